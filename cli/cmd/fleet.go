@@ -0,0 +1,149 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// aggregatorTimeout limits each request to the aggregation server
+const aggregatorTimeout = 30 * time.Second
+
+// FleetFilters collects repeated -filter tag:KEY=VALUE flags
+type FleetFilters []string
+
+func (f *FleetFilters) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *FleetFilters) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// Tags returns the tags the filters select instances by
+func (f FleetFilters) Tags() (map[string]string, error) {
+	tags := make(map[string]string, len(f))
+	for _, filter := range f {
+		key, value, found := strings.Cut(filter, "=")
+		if !found || !strings.HasPrefix(key, "tag:") || key == "tag:" {
+			return nil, fmt.Errorf("invalid filter %q, expected tag:KEY=VALUE", filter)
+		}
+		tags[strings.TrimPrefix(key, "tag:")] = value
+	}
+	return tags, nil
+}
+
+// tagQuery returns tags as "tag=KEY=VALUE" query parameters, in key order
+func tagQuery(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	query := url.Values{}
+	for _, key := range keys {
+		query.Add("tag", key+"="+tags[key])
+	}
+	return query.Encode()
+}
+
+// AggregatorClient runs fleet commands through an aggregation server, which
+// hands them to each daemon with the reply to its next report
+type AggregatorClient struct {
+	url   string
+	token string
+	http  *http.Client
+}
+
+// NewAggregatorClient creates a client for the server at serverURL, reading
+// its dashboard token and, if given, the CA that signed its certificate
+func NewAggregatorClient(serverURL, tokenFile, caFile string) (*AggregatorClient, error) {
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aggregator token: %v", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("token file %s is empty", tokenFile)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA: %v", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	}
+	return &AggregatorClient{
+		url:   strings.TrimSuffix(serverURL, "/"),
+		token: token,
+		http:  &http.Client{Timeout: aggregatorTimeout, Transport: transport},
+	}, nil
+}
+
+// Fleet returns the state of the instances with all of tags
+func (c *AggregatorClient) Fleet(tags map[string]string) (map[string]interface{}, error) {
+	var status map[string]interface{}
+	err := c.do(http.MethodGet, "/v1/fleet?"+tagQuery(tags), nil, &status)
+	return status, err
+}
+
+// Queue queues a command for the instances with all of tags that are still
+// reporting, returning the queued command of each instance
+func (c *AggregatorClient) Queue(command string, params map[string]interface{}, tags map[string]string) (map[string]interface{}, error) {
+	body := map[string]interface{}{"command": command, "params": params, "tags": tags}
+	var reply struct {
+		Queued map[string]interface{} `json:"queued"`
+	}
+	if err := c.do(http.MethodPost, "/v1/commands", body, &reply); err != nil {
+		return nil, err
+	}
+	return reply.Queued, nil
+}
+
+// do sends a request to the server and decodes its JSON reply into result
+func (c *AggregatorClient) do(method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("aggregation server returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
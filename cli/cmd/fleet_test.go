@@ -0,0 +1,75 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFleetFilters(t *testing.T) {
+	filters := FleetFilters{"tag:team=研", "tag:env=ci"}
+	tags, err := filters.Tags()
+	if err != nil || len(tags) != 2 || tags["team"] != "研" || tags["env"] != "ci" {
+		t.Errorf("Expected both tags, got %v and %v", tags, err)
+	}
+	for _, filter := range []string{"team=研", "tag:team", "tag:=研"} {
+		if _, err := (FleetFilters{filter}).Tags(); err == nil {
+			t.Errorf("Expected filter %q to be refused", filter)
+		}
+	}
+}
+
+func TestAggregatorClient(t *testing.T) {
+	var queued map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer s3cret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Path {
+		case "/v1/fleet":
+			if req.URL.Query().Get("tag") != "team=研" {
+				http.Error(w, "unexpected filter", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"instances": [{"instance_id": "i-a", "state": "paused"}]}`))
+		case "/v1/commands":
+			json.NewDecoder(req.Body).Decode(&queued)
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"queued": {"i-a": {"id": "1"}, "i-b": {"id": "2"}}}`))
+		}
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(tokenFile, []byte("s3cret\n"), 0600)
+	client, err := NewAggregatorClient(server.URL+"/", tokenFile, "")
+	if err != nil {
+		t.Fatalf("NewAggregatorClient failed: %v", err)
+	}
+
+	tags := map[string]string{"team": "研"}
+	status, err := client.Fleet(tags)
+	if instances, _ := status["instances"].([]interface{}); err != nil || len(instances) != 1 {
+		t.Errorf("Expected the team's instance, got %v and %v", status, err)
+	}
+	result, err := client.Queue("LEASE", map[string]interface{}{"hours": 72.0, "reason": "Release weekend"}, tags)
+	if err != nil || len(result) != 2 {
+		t.Errorf("Expected the lease queued for 2 instances, got %v and %v", result, err)
+	}
+	if queued["command"] != "LEASE" || queued["tags"].(map[string]interface{})["team"] != "研" {
+		t.Errorf("Expected the lease to be queued by tag, got %v", queued)
+	}
+
+	os.WriteFile(tokenFile, []byte("wrong"), 0600)
+	client, _ = NewAggregatorClient(server.URL, tokenFile, "")
+	if _, err := client.Fleet(nil); err == nil {
+		t.Error("Expected a wrong token to be refused")
+	}
+}
@@ -44,11 +44,12 @@ func CreateIssue(issueType IssueType, title, description string, browser bool) e
 		fmt.Printf("Warning: Could not collect all environment information: %v\n", err)
 	}
 
-	// Get log data
+	// Get log data. Issues are public, so secrets are always redacted.
 	logs, err := collectLogData()
 	if err != nil {
 		fmt.Printf("Warning: Could not collect log data: %v\n", err)
 	}
+	logs = RedactLogs(logs)
 
 	issueData := IssueData{
 		Type:        issueType,
@@ -415,8 +416,13 @@ func ReportIssue(issueType, title, description string, browser bool) error {
 	return CreateIssue(reportType, title, description, browser)
 }
 
-// SubmitDebugInfo collects and submits debug information to assist with troubleshooting
-func SubmitDebugInfo(outputFile string) error {
+// SubmitDebugInfo collects and submits debug information to assist with
+// troubleshooting. Secrets in the configuration and logs are redacted
+// unless showSecrets is set, which needs root.
+func SubmitDebugInfo(outputFile string, showSecrets bool) error {
+	if showSecrets && os.Geteuid() != 0 {
+		return fmt.Errorf("--show-secrets can only be used by root")
+	}
 	debugInfo := make(map[string]interface{})
 
 	// Get environment information
@@ -442,11 +448,18 @@ func SubmitDebugInfo(outputFile string) error {
 	}
 
 	// Get configuration
-	configCmd := exec.Command("snooze", "config", "list", "--json")
+	configArgs := []string{"config", "list", "--json"}
+	if showSecrets {
+		configArgs = append(configArgs, "--show-secrets")
+	}
+	configCmd := exec.Command("snooze", configArgs...)
 	configOutput, err := configCmd.Output()
 	if err == nil {
 		var configData interface{}
 		if err := json.Unmarshal(configOutput, &configData); err == nil {
+			if !showSecrets {
+				configData = RedactSecrets(configData)
+			}
 			debugInfo["config"] = configData
 		} else {
 			debugInfo["config"] = string(configOutput)
@@ -461,6 +474,9 @@ func SubmitDebugInfo(outputFile string) error {
 	if err != nil {
 		fmt.Printf("Warning: Could not collect log data: %v\n", err)
 	}
+	if !showSecrets {
+		logs = RedactLogs(logs)
+	}
 	debugInfo["logs"] = logs
 
 	// Get service status
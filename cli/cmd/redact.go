@@ -0,0 +1,66 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"regexp"
+	"strings"
+)
+
+// redactedValue replaces secrets in redacted output
+const redactedValue = "[REDACTED]"
+
+// sensitiveWords mark settings whose values are secret
+var sensitiveWords = []string{"token", "password", "secret", "webhook", "credential", "api_key", "apikey", "authorization", "routing_key"}
+
+// secretPatterns match secrets in log lines: values of sensitive settings
+// and webhook URLs, which carry their own credentials
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)((?:token|password|secret|api_?key|routing_key|authorization)["']?\s*[:=]\s*["']?)[^\s"',}]+`), "${1}" + redactedValue},
+	{regexp.MustCompile(`https://hooks\.slack\.com/\S+`), redactedValue},
+	{regexp.MustCompile(`https://[\w.-]*(?:webhook\.office|logic\.azure)\.com/\S+`), redactedValue},
+}
+
+// isSensitive returns true if a setting name suggests a secret value
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range sensitiveWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactSecrets replaces the values of settings whose names suggest a
+// secret in decoded JSON. The daemon already redacts CONFIG_GET; this
+// covers older daemons and free-form settings.
+func RedactSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if s, ok := nested.(string); ok && isSensitive(key) && s != "" {
+				v[key] = redactedValue
+			} else {
+				v[key] = RedactSecrets(nested)
+			}
+		}
+	case []interface{}:
+		for i, nested := range v {
+			v[i] = RedactSecrets(nested)
+		}
+	}
+	return value
+}
+
+// RedactLogs replaces secrets that appear in log text
+func RedactLogs(text string) string {
+	for _, secret := range secretPatterns {
+		text = secret.pattern.ReplaceAllString(text, secret.replacement)
+	}
+	return text
+}
@@ -0,0 +1,44 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedactSecrets(t *testing.T) {
+	config := map[string]interface{}{
+		"naptime_minutes": 30.0,
+		"notifications": map[string]interface{}{
+			"slack": map[string]interface{}{"bot_token": "xoxb-123", "channel": "#ops"},
+			"email": map[string]interface{}{"password": "", "to": []interface{}{"ops@example.com"}},
+		},
+	}
+	RedactSecrets(config)
+
+	notifications := config["notifications"].(map[string]interface{})
+	slack := notifications["slack"].(map[string]interface{})
+	if slack["bot_token"] != redactedValue || slack["channel"] != "#ops" {
+		t.Errorf("Expected only the token to be redacted, got %v", slack)
+	}
+	if email := notifications["email"].(map[string]interface{}); email["password"] != "" {
+		t.Errorf("Expected an empty password to stay empty, got %v", email["password"])
+	}
+}
+
+func TestRedactLogs(t *testing.T) {
+	logs := `level=INFO msg="Sent notification" url=https://hooks.slack.com/services/T0/B0/abc
+level=DEBUG msg="Config" password=hunter2 api_key: "k-123" channel=#ops`
+	redacted := RedactLogs(logs)
+
+	for _, secret := range []string{"hooks.slack.com", "hunter2", "k-123"} {
+		if strings.Contains(redacted, secret) {
+			t.Errorf("Expected %q to be redacted from %q", secret, redacted)
+		}
+	}
+	if !strings.Contains(redacted, "channel=#ops") {
+		t.Errorf("Expected other values to be kept, got %q", redacted)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tunnelTimeout is how long a tunnel may take to open, including SSH
+// authentication
+const tunnelTimeout = 30 * time.Second
+
+// Tunnel makes the API of a daemon on another machine available on a local
+// Unix socket, so the CLI talks to it as it would to a local daemon
+type Tunnel struct {
+	dir      string
+	ssh      *exec.Cmd
+	listener net.Listener
+}
+
+// IsTLSHost reports whether host names a daemon's TCP API, as
+// tls://HOST:PORT, rather than a host to reach over SSH
+func IsTLSHost(host string) bool {
+	return strings.HasPrefix(host, "tls://")
+}
+
+// OpenSSHTunnel forwards a local socket to remoteSocket on host, such as
+// user@host, with the ssh command. ssh's own configuration applies, so a
+// port or key can be set there, and it may prompt for a password. Remote
+// commands run as the SSH user, whose privileges on the daemon are checked
+// as for a local user.
+func OpenSSHTunnel(host, remoteSocket string) (*Tunnel, error) {
+	// ssh would take a host starting with - as an option
+	if host == "" || strings.HasPrefix(host, "-") {
+		return nil, fmt.Errorf("invalid host %q", host)
+	}
+	t, err := newTunnel()
+	if err != nil {
+		return nil, err
+	}
+	local := t.SocketPath()
+	t.ssh = exec.Command("ssh", sshTunnelArgs(local, remoteSocket, host)...)
+	t.ssh.Stdin = os.Stdin
+	t.ssh.Stderr = os.Stderr
+	if err := t.ssh.Start(); err != nil {
+		t.Close()
+		return nil, fmt.Errorf("failed to run ssh: %v", err)
+	}
+	exited := make(chan error, 1)
+	go func() {
+		exited <- t.ssh.Wait()
+	}()
+
+	// ssh creates the socket once it has connected
+	deadline := time.After(tunnelTimeout)
+	for {
+		if _, err := os.Stat(local); err == nil {
+			return t, nil
+		}
+		select {
+		case err := <-exited:
+			t.ssh = nil
+			t.Close()
+			return nil, fmt.Errorf("ssh to %s failed: %v", host, err)
+		case <-deadline:
+			t.Close()
+			return nil, fmt.Errorf("timed out connecting to %s", host)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// sshTunnelArgs returns the ssh arguments forwarding local to remoteSocket
+// on host
+func sshTunnelArgs(local, remoteSocket, host string) []string {
+	return []string{"-N", "-T",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "StreamLocalBindUnlink=yes",
+		"-L", local + ":" + remoteSocket,
+		"--", host}
+}
+
+// OpenTLSTunnel forwards a local socket to a daemon's TCP API at addr, as
+// tls://HOST:PORT or HOST:PORT. config holds the client certificate and
+// the CA that signed the daemon's certificate.
+func OpenTLSTunnel(addr string, config *tls.Config) (*Tunnel, error) {
+	addr = strings.TrimPrefix(addr, "tls://")
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid address %q, expected tls://HOST:PORT", addr)
+	}
+	t, err := newTunnel()
+	if err != nil {
+		return nil, err
+	}
+	t.listener, err = net.Listen("unix", t.SocketPath())
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	go func() {
+		for {
+			conn, err := t.listener.Accept()
+			if err != nil {
+				return
+			}
+			go forwardTLS(conn, addr, config)
+		}
+	}()
+	return t, nil
+}
+
+// forwardTLS copies a local connection to and from a new TLS connection
+func forwardTLS(conn net.Conn, addr string, config *tls.Config) {
+	defer conn.Close()
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	remote, err := tls.DialWithDialer(dialer, "tcp", addr, config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error connecting to %s: %v\n", addr, err)
+		return
+	}
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, conn)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+// LoadClientTLS loads a client certificate and key, and the CA that signed
+// the daemon's certificate (empty for the system's CAs)
+func LoadClientTLS(certFile, keyFile, caFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("a client certificate and key are required for the TCP API")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		data, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA: %v", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	return config, nil
+}
+
+// newTunnel creates a tunnel with a private directory for its socket
+func newTunnel() (*Tunnel, error) {
+	dir, err := os.MkdirTemp("", "snooze-remote-")
+	if err != nil {
+		return nil, err
+	}
+	return &Tunnel{dir: dir}, nil
+}
+
+// SocketPath returns the local socket the remote daemon is reached on
+func (t *Tunnel) SocketPath() string {
+	return filepath.Join(t.dir, "daemon.sock")
+}
+
+// Close closes the tunnel and removes its socket
+func (t *Tunnel) Close() error {
+	if t.ssh != nil && t.ssh.Process != nil {
+		t.ssh.Process.Kill()
+	}
+	if t.listener != nil {
+		t.listener.Close()
+	}
+	return os.RemoveAll(t.dir)
+}
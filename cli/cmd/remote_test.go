@@ -0,0 +1,154 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate for 127.0.0.1,
+// which serves as the CA, the daemon's certificate and the client's
+func writeTestCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ops-admin"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestIsTLSHost(t *testing.T) {
+	for host, want := range map[string]bool{
+		"tls://10.0.1.5:7443": true,
+		"ec2-user@10.0.1.5":   false,
+		"research-gpu":        false,
+	} {
+		if got := IsTLSHost(host); got != want {
+			t.Errorf("IsTLSHost(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestSSHTunnelArgs(t *testing.T) {
+	args := sshTunnelArgs("/tmp/snooze.sock", "/var/run/cloudsnooze.sock", "ec2-user@10.0.1.5")
+	if len(args) < 2 || args[len(args)-2] != "--" || args[len(args)-1] != "ec2-user@10.0.1.5" {
+		t.Errorf("Expected the host last, after --, got %v", args)
+	}
+}
+
+func TestSSHTunnelErrors(t *testing.T) {
+	for _, host := range []string{"", "-oProxyCommand=touch /tmp/pwned", "-F/dev/null"} {
+		if _, err := OpenSSHTunnel(host, "/var/run/cloudsnooze.sock"); err == nil {
+			t.Errorf("Expected host %q to be refused", host)
+		}
+	}
+}
+
+func TestTLSTunnel(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+	config, err := LoadClientTLS(certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatalf("LoadClientTLS failed: %v", err)
+	}
+
+	// The daemon echoes a line back with the client's certificate name
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: config.Certificates,
+		ClientCAs:    config.RootCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn *tls.Conn) {
+				defer conn.Close()
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				if err := conn.Handshake(); err != nil {
+					return
+				}
+				name := conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+				conn.Write([]byte(name + " " + line))
+			}(conn.(*tls.Conn))
+		}
+	}()
+
+	tunnel, err := OpenTLSTunnel("tls://"+listener.Addr().String(), config)
+	if err != nil {
+		t.Fatalf("OpenTLSTunnel failed: %v", err)
+	}
+	conn, err := net.Dial("unix", tunnel.SocketPath())
+	if err != nil {
+		t.Fatalf("Failed to connect to the tunnel: %v", err)
+	}
+	conn.Write([]byte("STATUS\n"))
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	conn.Close()
+	if err != nil || reply != "ops-admin STATUS\n" {
+		t.Errorf("Expected the request to reach the daemon as ops-admin, got %q and %v", reply, err)
+	}
+
+	if err := tunnel.Close(); err != nil {
+		t.Errorf("Close failed: %v", err)
+	}
+	if _, err := os.Stat(tunnel.SocketPath()); !os.IsNotExist(err) {
+		t.Error("Expected the tunnel socket to be removed")
+	}
+}
+
+func TestTLSTunnelErrors(t *testing.T) {
+	if _, err := OpenTLSTunnel("tls://10.0.1.5", &tls.Config{}); err == nil {
+		t.Error("Expected an address without a port to be refused")
+	}
+	if _, err := LoadClientTLS("", "", ""); err == nil {
+		t.Error("Expected a client certificate to be required")
+	}
+}
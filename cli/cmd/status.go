@@ -150,8 +150,26 @@ func FormatStatusOutput(client *api.SocketClient) (string, error) {
 		output += "System is active\n"
 	}
 	
-	// Display should snooze
-	if shouldSnooze, ok := data["should_snooze"].(bool); ok {
+	// Display how long the instance has been running
+	if uptime, ok := data["uptime_secs"].(float64); ok && uptime > 0 {
+		output += fmt.Sprintf("Uptime: %s (launched %s)\n", (time.Duration(uptime) * time.Second).String(), data["launch_time"])
+	}
+	
+	// Display the daemon's own memory use
+	if memory, ok := data["memory"].(map[string]interface{}); ok {
+		if rss, ok := memory["rss_bytes"].(float64); ok && rss > 0 {
+			output += fmt.Sprintf("Daemon memory: %.1f MB\n", rss/1024/1024)
+		}
+	}
+	
+	// Display should snooze, or the pending stop if a countdown is running
+	if breaker, ok := data["stop_breaker"].(map[string]interface{}); ok && breaker["open"] == true {
+		output += fmt.Sprintf("Status: SNOOZING SUSPENDED after %d failed stops - %s\n", int(breaker["failures"].(float64)), breaker["last_error"])
+		output += "Snoozing resumes once permissions are verified again\n"
+	} else if countdown, ok := data["countdown"].(map[string]interface{}); ok {
+		output += fmt.Sprintf("Status: SNOOZING IN %ds - %s\n", int(countdown["remaining_secs"].(float64)), countdown["reason"])
+		output += "Run 'snooze cancel' to keep the instance running\n"
+	} else if shouldSnooze, ok := data["should_snooze"].(bool); ok {
 		if shouldSnooze {
 			output += fmt.Sprintf("Status: WILL SNOOZE - %s\n", data["snooze_reason"])
 		} else {
@@ -190,5 +208,66 @@ func FormatStatusOutput(client *api.SocketClient) (string, error) {
 		output += fmt.Sprintf("  - Provider: %s\n", instanceInfo["Provider"])
 	}
 	
+	// Display the stop action, and why it falls back to stop
+	if stopAction, ok := data["stop_action"].(map[string]interface{}); ok {
+		if stopAction["effective"] == stopAction["requested"] {
+			output += fmt.Sprintf("  - Stop action: %s\n", stopAction["effective"])
+		} else {
+			output += fmt.Sprintf("  - Stop action: %s (%s unsupported, will fall back to %s: %s)\n",
+				stopAction["effective"], stopAction["requested"], stopAction["effective"], stopAction["reason"])
+		}
+	}
+	
+	// Display scheduled maintenance, during which the instance isn't snoozed
+	if events, ok := data["maintenance_events"].([]interface{}); ok && len(events) > 0 {
+		output += "\nScheduled Maintenance:\n"
+		for _, e := range events {
+			event, _ := e.(map[string]interface{})
+			output += fmt.Sprintf("  - %s from %s: %s\n", event["code"], event["not_before"], event["description"])
+		}
+	}
+	
+	// Display the instances depending on this one, which keep it running
+	// while active, and its peers, enough of which must keep running
+	output += formatInstanceStates("Dependent Instances", data["dependents"])
+	output += formatInstanceStates("Peers", data["peers"])
+	
+	// Display the organization's policy and the local settings it changed
+	if policy, ok := data["policy"].(map[string]interface{}); ok {
+		output += fmt.Sprintf("\nOrganization Policy: %s (%s)\n", policy["url"], policy["source"])
+		adjusted, _ := policy["adjusted"].([]interface{})
+		for _, change := range adjusted {
+			output += fmt.Sprintf("  - %s\n", change)
+		}
+	}
+	
+	// Display leases, which keep the instance running until they expire
+	if leases, ok := data["leases"].([]interface{}); ok && len(leases) > 0 {
+		output += "\nLeases:\n"
+		for _, l := range leases {
+			lease, _ := l.(map[string]interface{})
+			output += fmt.Sprintf("  - %s held by %s until %s: %s\n", lease["id"], lease["owner"], lease["expires_at"], lease["reason"])
+		}
+	}
+	
 	return output, nil
-}
\ No newline at end of file
+}
+
+// formatInstanceStates formats the states of other instances under a
+// heading, or returns "" if there are none
+func formatInstanceStates(heading string, value interface{}) string {
+	states, ok := value.([]interface{})
+	if !ok || len(states) == 0 {
+		return ""
+	}
+	output := "\n" + heading + ":\n"
+	for _, s := range states {
+		state, _ := s.(map[string]interface{})
+		output += fmt.Sprintf("  - %s: %s", state["instance_id"], state["state"])
+		if reason, _ := state["reason"].(string); reason != "" {
+			output += " - " + reason
+		}
+		output += "\n"
+	}
+	return output
+}
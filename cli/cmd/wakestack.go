@@ -0,0 +1,113 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// Formats GenerateWakeStack can write
+const (
+	FormatCloudFormation = "cloudformation"
+	FormatTerraform      = "terraform"
+)
+
+//go:embed templates/*.tmpl
+var templates embed.FS
+
+var (
+	// tagPrefixPattern matches prefixes safe to put in the templates and
+	// the Lambda source unquoted
+	tagPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_.:/=+@-]+$`)
+	// stackNamePattern matches names valid for the Lambda function, the
+	// role and the rule
+	stackNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	// schedulePattern matches EventBridge schedule expressions
+	schedulePattern = regexp.MustCompile(`^(rate|cron)\([A-Za-z0-9 ,*?/#LW-]+\)$`)
+)
+
+// WakeStackOptions configures the generated wake stack
+type WakeStackOptions struct {
+	Format   string // cloudformation or terraform
+	Prefix   string // The daemons' tagging_prefix
+	Name     string // Name of the function, role and rule
+	Schedule string // How often snoozed instances are checked
+}
+
+// DefaultWakeStackOptions returns the options matching the daemon's
+// default tagging prefix
+func DefaultWakeStackOptions() WakeStackOptions {
+	return WakeStackOptions{
+		Format:   FormatCloudFormation,
+		Prefix:   "CloudSnooze",
+		Name:     "cloudsnooze-wake",
+		Schedule: "rate(5 minutes)",
+	}
+}
+
+// GenerateWakeStack returns a CloudFormation or Terraform template for an
+// EventBridge rule and Lambda function that start instances snoozed by
+// CloudSnooze once their max_snooze_hours deadline has passed
+func GenerateWakeStack(options WakeStackOptions) (string, error) {
+	var name string
+	switch options.Format {
+	case FormatCloudFormation:
+		name = "wake_stack.yaml.tmpl"
+	case FormatTerraform:
+		name = "wake_stack.tf.tmpl"
+	default:
+		return "", fmt.Errorf("unknown format %q, expected %s or %s", options.Format, FormatCloudFormation, FormatTerraform)
+	}
+	if !tagPrefixPattern.MatchString(options.Prefix) {
+		return "", fmt.Errorf("invalid tagging prefix %q", options.Prefix)
+	}
+	if !stackNamePattern.MatchString(options.Name) {
+		return "", fmt.Errorf("invalid name %q: use up to 64 letters, digits, - and _", options.Name)
+	}
+	if !schedulePattern.MatchString(options.Schedule) {
+		return "", fmt.Errorf("invalid schedule %q, expected rate(...) or cron(...)", options.Schedule)
+	}
+
+	tmpl, err := template.New("").Funcs(template.FuncMap{"indent": indent}).ParseFS(templates, "templates/*.tmpl")
+	if err != nil {
+		return "", err
+	}
+	data := map[string]string{
+		"Prefix":   options.Prefix,
+		"Name":     options.Name,
+		"Resource": strings.ReplaceAll(options.Name, "-", "_"),
+		"Schedule": options.Schedule,
+	}
+
+	// The Lambda source goes inline in either template
+	var lambda bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&lambda, "wake_lambda.py.tmpl", data); err != nil {
+		return "", err
+	}
+	data["Lambda"] = lambda.String()
+
+	var out bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&out, name, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// indent indents the non-empty lines of text by spaces, without a trailing
+// newline
+func indent(spaces int, text string) string {
+	pad := strings.Repeat(" ", spaces)
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
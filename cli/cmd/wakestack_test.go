@@ -0,0 +1,55 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateWakeStack(t *testing.T) {
+	for _, format := range []string{FormatCloudFormation, FormatTerraform} {
+		options := DefaultWakeStackOptions()
+		options.Format = format
+		options.Prefix = "Snooze"
+		options.Name = "research-wake"
+
+		stack, err := GenerateWakeStack(options)
+		if err != nil {
+			t.Fatalf("%s: GenerateWakeStack failed: %v", format, err)
+		}
+		for _, want := range []string{
+			`PREFIX = "Snooze"`,
+			"aws:ResourceTag/Snooze:stopped_at",
+			"rate(5 minutes)",
+			"research-wake",
+		} {
+			if !strings.Contains(stack, want) {
+				t.Errorf("%s: expected the template to contain %q", format, want)
+			}
+		}
+		if strings.Contains(stack, "{{") {
+			t.Errorf("%s: expected every template action to be expanded", format)
+		}
+	}
+}
+
+func TestGenerateWakeStackInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*WakeStackOptions)
+	}{
+		{"format", func(o *WakeStackOptions) { o.Format = "pulumi" }},
+		{"prefix", func(o *WakeStackOptions) { o.Prefix = `Cloud"Snooze` }},
+		{"name", func(o *WakeStackOptions) { o.Name = "cloud snooze" }},
+		{"schedule", func(o *WakeStackOptions) { o.Schedule = "every 5 minutes" }},
+	}
+	for _, tt := range tests {
+		options := DefaultWakeStackOptions()
+		tt.modify(&options)
+		if _, err := GenerateWakeStack(options); err == nil {
+			t.Errorf("Expected an invalid %s to be refused", tt.name)
+		}
+	}
+}
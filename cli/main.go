@@ -5,8 +5,12 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/scttfrdmn/cloudsnooze/cli/cmd"
@@ -14,11 +18,18 @@ import (
 )
 
 var (
-	socketPath  = flag.String("socket", api.DefaultSocketPath, "Path to Unix socket")
+	socketPath  = flag.String("socket", api.DefaultSocketPath, "Path to Unix socket (on the remote host with -host user@host)")
 	showVersion = flag.Bool("version", false, "Show version and exit")
 	configFile  = flag.String("config", "/etc/snooze/snooze.json", "Path to configuration file")
+	remoteHost  = flag.String("host", "", "Daemon on another machine: user@host over SSH, or tls://host:port for its TCP API")
+	tlsCert     = flag.String("tls-cert", "", "Client certificate for -host tls://host:port")
+	tlsKey      = flag.String("tls-key", "", "Client private key for -host tls://host:port")
+	tlsCA       = flag.String("tls-ca", "", "CA that signed the daemon's certificate, for -host tls://host:port (empty for the system's)")
 )
 
+// tunnel reaches the daemon given by -host, if any
+var tunnel *cmd.Tunnel
+
 const version = "0.1.0"
 
 func main() {
@@ -33,14 +44,25 @@ func main() {
 	args := flag.Args()
 	if len(args) < 1 {
 		printUsage()
-		os.Exit(1)
+		exit(1)
 	}
 
-	// Create socket client
-	client := api.NewSocketClient(*socketPath)
-
 	// Process command
 	command := args[0]
+
+	// Reach a daemon on another machine through a local socket
+	path := *socketPath
+	if *remoteHost != "" && !localCommands[command] {
+		if err := openTunnel(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(3)
+		}
+		path = tunnel.SocketPath()
+	}
+
+	// Create socket client
+	client := api.NewSocketClient(path)
+
 	switch command {
 	case "status":
 		showStatus(client, args[1:])
@@ -48,21 +70,83 @@ func main() {
 		handleConfig(client, args[1:])
 	case "history":
 		showHistory(client, args[1:])
+	case "decisions":
+		showDecisions(client, args[1:])
+	case "audit":
+		showAudit(client, args[1:])
+	case "log-level":
+		logLevel(client, args[1:])
+	case "runtime":
+		showRuntime(client, args[1:])
+	case "cancel":
+		cancelSnooze(client)
+	case "lease":
+		handleLease(client, args[1:])
+	case "instances":
+		listInstances(client, args[1:])
+	case "wake":
+		wakeInstance(client, args[1:])
+	case "fleet":
+		handleFleet(client, args[1:])
+	case "start-instance":
+		startInstance(client, args[1:])
 	case "start", "stop", "restart":
 		controlDaemon(client, command)
 	case "issue":
 		handleIssue(args[1:])
 	case "debug":
 		handleDebug(args[1:])
+	case "generate":
+		handleGenerate(args[1:])
 	case "plugins":
 		listPlugins(client, args[1:])
+	case "plugin":
+		handlePlugin(client, args[1:])
 	case "help":
 		printUsage()
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		printUsage()
-		os.Exit(1)
+		exit(1)
 	}
+	exit(0)
+}
+
+// localCommands don't talk to the daemon, so don't need -host
+var localCommands = map[string]bool{"issue": true, "debug": true, "generate": true, "help": true}
+
+// openTunnel connects to the daemon given by -host, closing the tunnel if
+// the CLI is interrupted
+func openTunnel() error {
+	var err error
+	if cmd.IsTLSHost(*remoteHost) {
+		config, configErr := cmd.LoadClientTLS(*tlsCert, *tlsKey, *tlsCA)
+		if configErr != nil {
+			return configErr
+		}
+		tunnel, err = cmd.OpenTLSTunnel(*remoteHost, config)
+	} else {
+		tunnel, err = cmd.OpenSSHTunnel(*remoteHost, *socketPath)
+	}
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-signals
+		exit(130)
+	}()
+	return nil
+}
+
+// exit closes the tunnel to a remote daemon, if any, and exits
+func exit(code int) {
+	if tunnel != nil {
+		tunnel.Close()
+	}
+	os.Exit(code)
 }
 
 func printUsage() {
@@ -73,12 +157,24 @@ func printUsage() {
 	fmt.Println("  status       Show current system status")
 	fmt.Println("  config       View or modify configuration")
 	fmt.Println("  history      View snooze history")
+	fmt.Println("  decisions    Explain recent idle checks")
+	fmt.Println("  audit        View commands sent to the daemon")
+	fmt.Println("  log-level    Show or change the log level until the daemon restarts")
+	fmt.Println("  runtime      Show daemon goroutine, heap and GC statistics")
+	fmt.Println("  cancel       Cancel a pending snooze")
+	fmt.Println("  lease        Keep the instance running for a while (take, list, release)")
+	fmt.Println("  instances    List snoozed instances (restarter only)")
+	fmt.Println("  wake         Start a snoozed instance (restarter only)")
+	fmt.Println("  fleet        List, pause, resume or snooze fleet instances by tag (controller or aggregator)")
+	fmt.Println("  start-instance  Start another instance through the daemon's cloud provider")
 	fmt.Println("  start        Start the daemon")
 	fmt.Println("  stop         Stop the daemon")
 	fmt.Println("  restart      Restart the daemon")
 	fmt.Println("  issue        Create a GitHub issue")
 	fmt.Println("  debug        Generate debug information")
+	fmt.Println("  generate     Generate deployment templates (wake-stack)")
 	fmt.Println("  plugins      List available plugins")
+	fmt.Println("  plugin       Manage plugins (list, info, enable, disable, install)")
 	fmt.Println("  help         Show this help message")
 	fmt.Println("\nRun 'snooze help command' for more information on a command")
 }
@@ -96,7 +192,7 @@ func showStatus(client *api.SocketClient, args []string) {
 		jsonData, err := cmd.GetStatusJson(client)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println(string(jsonData))
 		return
@@ -105,7 +201,7 @@ func showStatus(client *api.SocketClient, args []string) {
 	formatted, err := cmd.FormatStatusOutput(client)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	
 	fmt.Println(formatted)
@@ -114,55 +210,63 @@ func showStatus(client *api.SocketClient, args []string) {
 func handleConfig(client *api.SocketClient, args []string) {
 	if len(args) < 1 {
 		fmt.Println("Usage: snooze config [list|get|set|reset|import|export]")
-		os.Exit(1)
+		exit(1)
 	}
 
+	// Secrets are redacted unless root asks for them
+	getCommand := "CONFIG_GET"
+	for _, arg := range args[1:] {
+		if arg == "--show-secrets" || arg == "-show-secrets" {
+			getCommand = "CONFIG_GET_SECRETS"
+		}
+	}
+	
 	action := args[0]
 	switch action {
 	case "list":
 		// Get all configuration
-		result, err := client.SendCommand("CONFIG_GET", nil)
+		result, err := client.SendCommand(getCommand, nil)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		
 		// Pretty print configuration
 		jsonData, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error formatting config: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		
 		fmt.Println(string(jsonData))
 		
 	case "get":
 		if len(args) < 2 {
-			fmt.Println("Usage: snooze config get <parameter>")
-			os.Exit(1)
+			fmt.Println("Usage: snooze config get <parameter> [--show-secrets]")
+			exit(1)
 		}
 		
 		paramName := args[1]
 		
 		// Get all configuration
-		result, err := client.SendCommand("CONFIG_GET", nil)
+		result, err := client.SendCommand(getCommand, nil)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		
 		// Extract the requested parameter
 		config, ok := result.(map[string]interface{})
 		if !ok {
 			fmt.Fprintf(os.Stderr, "Error: unexpected response format\n")
-			os.Exit(1)
+			exit(1)
 		}
 		
 		// Try to find the parameter
 		value, found := config[paramName]
 		if !found {
 			fmt.Fprintf(os.Stderr, "Error: parameter '%s' not found\n", paramName)
-			os.Exit(1)
+			exit(1)
 		}
 		
 		fmt.Printf("%v\n", value)
@@ -170,7 +274,7 @@ func handleConfig(client *api.SocketClient, args []string) {
 	case "set":
 		if len(args) < 3 {
 			fmt.Println("Usage: snooze config set <parameter> <value>")
-			os.Exit(1)
+			exit(1)
 		}
 		
 		paramName := args[1]
@@ -184,7 +288,7 @@ func handleConfig(client *api.SocketClient, args []string) {
 		_, err := client.SendCommand("CONFIG_SET", params)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		
 		fmt.Printf("Parameter '%s' updated to '%s'\n", paramName, paramValue)
@@ -192,7 +296,7 @@ func handleConfig(client *api.SocketClient, args []string) {
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown config action: %s\n", action)
 		fmt.Println("Usage: snooze config [list|get|set|reset|import|export]")
-		os.Exit(1)
+		exit(1)
 	}
 }
 
@@ -206,7 +310,7 @@ func showHistory(client *api.SocketClient, args []string) {
 	
 	if err := historyCmd.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	
 	params := map[string]interface{}{
@@ -221,14 +325,14 @@ func showHistory(client *api.SocketClient, args []string) {
 	result, err := client.SendCommand("HISTORY", params)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	
 	// Process results
 	events, ok := result.([]interface{})
 	if !ok {
 		fmt.Fprintf(os.Stderr, "Error: unexpected response format\n")
-		os.Exit(1)
+		exit(1)
 	}
 	
 	// Output results
@@ -241,7 +345,7 @@ func showHistory(client *api.SocketClient, args []string) {
 	case "csv":
 		// TODO: Implement CSV output
 		fmt.Fprintf(os.Stderr, "CSV output not implemented yet\n")
-		os.Exit(1)
+		exit(1)
 	case "text":
 		fallthrough
 	default:
@@ -251,6 +355,7 @@ func showHistory(client *api.SocketClient, args []string) {
 		if len(events) == 0 {
 			fmt.Println("No snooze events found")
 		} else {
+			var stoppedMins float64
 			for i, event := range events {
 				e, ok := event.(map[string]interface{})
 				if !ok {
@@ -265,14 +370,34 @@ func showHistory(client *api.SocketClient, args []string) {
 					t = time.Time{}
 				}
 				
+				// Start events say how long the instance was stopped, and
+				// snooze events how it was stopped
+				if kind, _ := e["event"].(string); kind == "start" {
+					mins, _ := e["stopped_mins"].(float64)
+					stoppedMins += mins
+					reason = fmt.Sprintf("%s after %s stopped", reason, time.Duration(mins*float64(time.Minute)).Round(time.Minute))
+				} else if action, _ := e["action"].(string); action != "" {
+					reason = fmt.Sprintf("%s [%s]", reason, action)
+				}
+				
 				fmt.Printf("%d. %s - %s\n", i+1, t.Format("2006-01-02 15:04:05"), reason)
+				steps, _ := e["steps"].([]interface{})
+				for _, s := range steps {
+					step, _ := s.(map[string]interface{})
+					if status, _ := step["status"].(string); status == "failed" {
+						fmt.Printf("   %s step failed: %v\n", step["step"], step["error"])
+					}
+				}
+			}
+			if stoppedMins > 0 {
+				fmt.Printf("\nStopped for %.1f hours in total\n", stoppedMins/60)
 			}
 		}
 	}
 	
 	if output_err != nil {
 		fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", output_err)
-		os.Exit(1)
+		exit(1)
 	}
 	
 	// Write to file if specified
@@ -280,12 +405,12 @@ func showHistory(client *api.SocketClient, args []string) {
 		outputDir := filepath.Dir(*output)
 		if err := os.MkdirAll(outputDir, 0755); err != nil {
 			fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		
 		if err := os.WriteFile(*output, output_data, 0644); err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing to output file: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		
 		fmt.Printf("Output written to %s\n", *output)
@@ -294,6 +419,356 @@ func showHistory(client *api.SocketClient, args []string) {
 	}
 }
 
+func cancelSnooze(client *api.SocketClient) {
+	result, err := client.SendCommand("CANCEL", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	
+	data, ok := result.(map[string]interface{})
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unexpected response format\n")
+		exit(1)
+	}
+	
+	if cancelled, _ := data["cancelled"].(bool); cancelled {
+		fmt.Printf("Pending snooze cancelled (%s)\n", data["reason"])
+	} else {
+		fmt.Println("No snooze is pending")
+	}
+}
+
+func handleLease(client *api.SocketClient, args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: snooze lease take HOURS REASON | list | release ID")
+		exit(1)
+	}
+	
+	switch args[0] {
+	case "take":
+		if len(args) < 3 {
+			fmt.Println("Usage: snooze lease take HOURS REASON")
+			exit(1)
+		}
+		hours, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid hours %q\n", args[1])
+			exit(1)
+		}
+		result, err := client.SendCommand("LEASE", map[string]interface{}{
+			"hours":  hours,
+			"reason": strings.Join(args[2:], " "),
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		lease, _ := result.(map[string]interface{})
+		fmt.Printf("Lease %s keeps the instance running until %s\n", lease["id"], lease["expires_at"])
+	case "list":
+		result, err := client.SendCommand("STATUS", nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		status, _ := result.(map[string]interface{})
+		leases, _ := status["leases"].([]interface{})
+		if len(leases) == 0 {
+			fmt.Println("No leases are held")
+			return
+		}
+		fmt.Printf("%-10s %-16s %-20s %s\n", "ID", "OWNER", "EXPIRES", "REASON")
+		for _, item := range leases {
+			lease, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			expires, _ := lease["expires_at"].(string)
+			if t, err := time.Parse(time.RFC3339Nano, expires); err == nil {
+				expires = t.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%-10v %-16v %-20s %v\n", lease["id"], lease["owner"], expires, lease["reason"])
+		}
+	case "release":
+		if len(args) != 2 {
+			fmt.Println("Usage: snooze lease release ID")
+			exit(1)
+		}
+		if _, err := client.SendCommand("RELEASE", map[string]interface{}{"id": args[1]}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("Released lease %s\n", args[1])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown lease command: %s\n", args[0])
+		exit(1)
+	}
+}
+
+func listInstances(client *api.SocketClient, args []string) {
+	instancesCmd := flag.NewFlagSet("instances", flag.ExitOnError)
+	jsonOutput := instancesCmd.Bool("json", false, "Output as JSON")
+	
+	if err := instancesCmd.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		exit(1)
+	}
+	
+	result, err := client.SendCommand("INSTANCES", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	
+	instances, _ := result.([]interface{})
+	if len(instances) == 0 {
+		fmt.Println("No instances are snoozed")
+		return
+	}
+	
+	fmt.Printf("%-20s %-24s %-20s %-20s %s\n", "INSTANCE", "NAME", "STOPPED", "WAKES", "REASON")
+	for _, item := range instances {
+		instance, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		localTime := func(key string) string {
+			value, _ := instance[key].(string)
+			if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				return t.Local().Format("2006-01-02 15:04")
+			}
+			return value
+		}
+		name, _ := instance["name"].(string)
+		reason, _ := instance["reason"].(string)
+		fmt.Printf("%-20v %-24s %-20s %-20s %s\n", instance["id"], name, localTime("stopped_at"), localTime("wake_at"), reason)
+	}
+}
+
+// handleFleet lists, pauses, resumes or snoozes the instances a controller
+// manages, or with -aggregator those reporting to an aggregation server.
+// Without a subcommand it lists them.
+func handleFleet(client *api.SocketClient, args []string) {
+	subcommand := "list"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		subcommand, args = args[0], args[1:]
+	}
+	
+	fleetCmd := flag.NewFlagSet("fleet "+subcommand, flag.ExitOnError)
+	var filters cmd.FleetFilters
+	fleetCmd.Var(&filters, "filter", "Only instances with a tag, as tag:KEY=VALUE (repeatable)")
+	jsonOutput := fleetCmd.Bool("json", false, "Output as JSON")
+	hours := fleetCmd.Float64("hours", 72, "How long a pause lasts")
+	reason := fleetCmd.String("reason", "", "Why snoozing is paused, or why the instances are snoozed")
+	all := fleetCmd.Bool("all", false, "Snooze every instance, when no -filter is given")
+	aggregatorURL := fleetCmd.String("aggregator", "", "Aggregation server URL, instead of the controller on -socket or -host")
+	tokenFile := fleetCmd.String("token-file", "", "File holding the aggregation server's dashboard token")
+	caFile := fleetCmd.String("ca", "", "CA that signed the aggregation server's certificate (empty for the system's)")
+	
+	if err := fleetCmd.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		exit(1)
+	}
+	if fleetCmd.NArg() > 0 {
+		fmt.Println("Usage: snooze fleet [list|pause|resume|snooze] [-filter tag:KEY=VALUE]... [options]")
+		exit(1)
+	}
+	tags, err := filters.Tags()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	if subcommand == "pause" && *reason == "" {
+		fmt.Fprintln(os.Stderr, "Error: -reason is required to pause snoozing")
+		exit(1)
+	}
+	// Stopping a whole fleet by mistake is costly to undo
+	if subcommand == "snooze" && len(tags) == 0 && !*all {
+		fmt.Fprintln(os.Stderr, "Error: snoozing needs -filter, or -all for every instance")
+		exit(1)
+	}
+	
+	if *aggregatorURL != "" {
+		aggregatorFleet(subcommand, *aggregatorURL, *tokenFile, *caFile, tags, *hours, *reason, *jsonOutput)
+		return
+	}
+	
+	var result interface{}
+	params := map[string]interface{}{"tags": tags}
+	switch subcommand {
+	case "list":
+		result, err = client.SendCommand("FLEET", params)
+	case "pause":
+		params["hours"] = *hours
+		params["reason"] = *reason
+		result, err = client.SendCommand("FLEET_PAUSE", params)
+	case "resume":
+		result, err = client.SendCommand("FLEET_RESUME", params)
+	case "snooze":
+		params["reason"] = *reason
+		result, err = client.SendCommand("FLEET_SNOOZE", params)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown fleet command: %s\n", subcommand)
+		exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	
+	switch subcommand {
+	case "pause":
+		pause, _ := result.(map[string]interface{})
+		fmt.Printf("Snoozing of %s paused until %v\n", describeTags(tags), pause["until"])
+	case "resume":
+		reply, _ := result.(map[string]interface{})
+		resumed, _ := reply["resumed"].([]interface{})
+		fmt.Printf("Ended %d pauses of %s\n", len(resumed), describeTags(tags))
+	default:
+		decisions, _ := result.([]interface{})
+		if len(decisions) == 0 {
+			fmt.Println("No instances have been checked")
+			return
+		}
+		printDecisions(decisions)
+	}
+}
+
+// printDecisions prints the controller's checks of instances
+func printDecisions(decisions []interface{}) {
+	fmt.Printf("%-20s %-24s %-12s %-8s %s\n", "INSTANCE", "NAME", "GROUP", "STATE", "REASON")
+	for _, item := range decisions {
+		decision, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := decision["name"].(string)
+		group, _ := decision["group"].(string)
+		reason, _ := decision["reason"].(string)
+		state := "busy"
+		if stopped, _ := decision["stopped"].(bool); stopped {
+			state = "stopped"
+		} else if paused, _ := decision["paused"].(bool); paused {
+			state = "paused"
+		} else if idle, _ := decision["idle"].(bool); idle {
+			state = "idle"
+		}
+		if message, _ := decision["error"].(string); message != "" {
+			reason = fmt.Sprintf("%s (%s)", reason, message)
+		}
+		fmt.Printf("%-20v %-24s %-12s %-8s %s\n", decision["instance_id"], name, group, state, reason)
+	}
+}
+
+// aggregatorFleet runs a fleet command through an aggregation server.
+// Pausing takes a lease on each instance, as the "aggregator" client, and
+// resuming releases the leases that client holds.
+func aggregatorFleet(subcommand, serverURL, tokenFile, caFile string, tags map[string]string, hours float64, reason string, jsonOutput bool) {
+	aggregator, err := cmd.NewAggregatorClient(serverURL, tokenFile, caFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	
+	var result interface{}
+	switch subcommand {
+	case "list":
+		result, err = aggregator.Fleet(tags)
+	case "pause":
+		result, err = aggregator.Queue("LEASE", map[string]interface{}{"hours": hours, "reason": reason}, tags)
+	case "resume":
+		result, err = aggregator.Queue("RELEASE", map[string]interface{}{"all": true}, tags)
+	case "snooze":
+		err = fmt.Errorf("the aggregation server can't snooze instances; use the controller")
+	default:
+		err = fmt.Errorf("unknown fleet command: %s", subcommand)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	
+	if jsonOutput {
+		data, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	if subcommand != "list" {
+		queued, _ := result.(map[string]interface{})
+		fmt.Printf("Queued %s of %s for %d instances, sent with their next reports\n", subcommand, describeTags(tags), len(queued))
+		return
+	}
+	
+	status, _ := result.(map[string]interface{})
+	instances, _ := status["instances"].([]interface{})
+	if len(instances) == 0 {
+		fmt.Println("No instances have reported")
+		return
+	}
+	fmt.Printf("%-20s %-24s %-10s %s\n", "INSTANCE", "NAME", "STATE", "REASON")
+	for _, item := range instances {
+		instance, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := instance["name"].(string)
+		reason, _ := instance["reason"].(string)
+		fmt.Printf("%-20v %-24s %-10v %s\n", instance["instance_id"], name, instance["state"], reason)
+	}
+}
+
+// describeTags names the instances a filter selects
+func describeTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return "every instance"
+	}
+	filters := make([]string, 0, len(tags))
+	for key, value := range tags {
+		filters = append(filters, key+"="+value)
+	}
+	sort.Strings(filters)
+	return "instances tagged " + strings.Join(filters, ", ")
+}
+
+func wakeInstance(client *api.SocketClient, args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: snooze wake INSTANCE_ID")
+		exit(1)
+	}
+	
+	if _, err := client.SendCommand("WAKE", map[string]interface{}{"instance_id": args[0]}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("Starting %s\n", args[0])
+}
+
+func startInstance(client *api.SocketClient, args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: snooze start-instance INSTANCE_ID")
+		exit(1)
+	}
+	
+	if _, err := client.SendCommand("START_INSTANCE", map[string]interface{}{"instance_id": args[0]}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("Starting %s\n", args[0])
+}
+
 func controlDaemon(client *api.SocketClient, command string) {
 	// TODO: Implement daemon control
 	fmt.Printf("Command '%s' not implemented yet\n", command)
@@ -309,7 +784,7 @@ func handleIssue(args []string) {
 	
 	if err := issueCmd.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	
 	// If this is the help command or no arguments, show usage
@@ -326,7 +801,7 @@ func handleIssue(args []string) {
 	// Create the issue
 	if err := cmd.ReportIssue(*issueType, *issueTitle, *issueDesc, *issueBrowser); err != nil {
 		fmt.Fprintf(os.Stderr, "Error creating issue: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	
 	if *issueBrowser {
@@ -340,10 +815,11 @@ func handleDebug(args []string) {
 	// Parse flags for debug command
 	debugCmd := flag.NewFlagSet("debug", flag.ExitOnError)
 	outputFile := debugCmd.String("output", "", "Output file (if not specified, outputs to stdout)")
+	showSecrets := debugCmd.Bool("show-secrets", false, "Include secrets from the configuration and logs (root only)")
 	
 	if err := debugCmd.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	
 	// If this is the help command, show usage
@@ -360,10 +836,51 @@ func handleDebug(args []string) {
 	fmt.Println("Collecting debug information...")
 	
 	// Generate debug information
-	if err := cmd.SubmitDebugInfo(*outputFile); err != nil {
+	if err := cmd.SubmitDebugInfo(*outputFile, *showSecrets); err != nil {
 		fmt.Fprintf(os.Stderr, "Error generating debug information: %v\n", err)
-		os.Exit(1)
+		exit(1)
+	}
+}
+
+func handleGenerate(args []string) {
+	if len(args) < 1 || args[0] != "wake-stack" {
+		fmt.Println("Usage: snooze generate wake-stack [--format cloudformation|terraform] [--prefix PREFIX] [--name NAME] [--schedule EXPRESSION] [--output FILE]")
+		exit(1)
+	}
+	
+	defaults := cmd.DefaultWakeStackOptions()
+	generateCmd := flag.NewFlagSet("generate wake-stack", flag.ExitOnError)
+	format := generateCmd.String("format", defaults.Format, "Template format (cloudformation, terraform)")
+	prefix := generateCmd.String("prefix", defaults.Prefix, "The daemons' tagging_prefix")
+	name := generateCmd.String("name", defaults.Name, "Name of the Lambda function, role and rule")
+	schedule := generateCmd.String("schedule", defaults.Schedule, "EventBridge schedule for checking snoozed instances")
+	output := generateCmd.String("output", "", "Write the template to this file instead of stdout")
+	
+	if err := generateCmd.Parse(args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		exit(1)
+	}
+	
+	stack, err := cmd.GenerateWakeStack(cmd.WakeStackOptions{
+		Format:   *format,
+		Prefix:   *prefix,
+		Name:     *name,
+		Schedule: *schedule,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	
+	if *output == "" {
+		fmt.Print(stack)
+		return
+	}
+	if err := os.WriteFile(*output, []byte(stack), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing template: %v\n", err)
+		exit(1)
 	}
+	fmt.Printf("Wake stack written to %s\n", *output)
 }
 
 func listPlugins(client *api.SocketClient, args []string) {
@@ -373,7 +890,7 @@ func listPlugins(client *api.SocketClient, args []string) {
 	
 	if err := pluginsCmd.Parse(args); err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	
 	// If this is the help command, show usage
@@ -391,14 +908,14 @@ func listPlugins(client *api.SocketClient, args []string) {
 	result, err := client.SendCommand("PLUGINS_LIST", nil)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 	
 	// Process results
 	plugins, ok := result.([]interface{})
 	if !ok {
 		fmt.Fprintf(os.Stderr, "Error: unexpected response format\n")
-		os.Exit(1)
+		exit(1)
 	}
 	
 	// Output results
@@ -406,7 +923,7 @@ func listPlugins(client *api.SocketClient, args []string) {
 		jsonData, err := json.MarshalIndent(plugins, "", "  ")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error formatting output: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		fmt.Println(string(jsonData))
 		return
@@ -450,7 +967,357 @@ func listPlugins(client *api.SocketClient, args []string) {
 			status = "running"
 		}
 		fmt.Printf("   Status: %s\n", status)
+		if enabled, ok := p["enabled"].(bool); ok && !enabled {
+			fmt.Println("   Disabled: yes")
+		}
 		
 		fmt.Println()
 	}
-}
\ No newline at end of file
+}
+
+func showDecisions(client *api.SocketClient, args []string) {
+	decisionsCmd := flag.NewFlagSet("decisions", flag.ExitOnError)
+	limit := decisionsCmd.Int("limit", 10, "Limit to N entries")
+	since := decisionsCmd.String("since", "", "Show entries since DATE")
+	jsonOutput := decisionsCmd.Bool("json", false, "Output as JSON")
+	
+	if err := decisionsCmd.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		exit(1)
+	}
+	
+	params := map[string]interface{}{
+		"limit": *limit,
+	}
+	if *since != "" {
+		params["since"] = *since
+	}
+	
+	result, err := client.SendCommand("DECISIONS", params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	
+	decisions, ok := result.([]interface{})
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unexpected response format\n")
+		exit(1)
+	}
+	
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(decisions, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	
+	if len(decisions) == 0 {
+		fmt.Println("No decisions recorded")
+		return
+	}
+	
+	for _, decision := range decisions {
+		d, ok := decision.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		
+		timestamp, _ := d["timestamp"].(string)
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			t = time.Time{}
+		}
+		
+		fmt.Printf("%s  %-10v %v\n", t.Local().Format("2006-01-02 15:04:05"), d["outcome"], d["reason"])
+		checks, _ := d["checks"].([]interface{})
+		for _, check := range checks {
+			c, ok := check.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			
+			name := fmt.Sprintf("%v", c["metric"])
+			if device, _ := c["device"].(string); device != "" {
+				name += " " + device
+			}
+			state := "busy"
+			if idle, _ := c["idle"].(bool); idle {
+				state = "idle"
+			}
+			if failure, _ := c["error"].(string); failure != "" {
+				state = "busy, not collected: " + failure
+			}
+			fmt.Printf("    %-22s %10.2f  threshold %10.2f  %s\n", name, c["value"], c["threshold"], state)
+		}
+	}
+}
+
+func logLevel(client *api.SocketClient, args []string) {
+	params := map[string]interface{}{}
+	if len(args) > 0 {
+		params["level"] = args[0]
+	}
+	
+	result, err := client.SendCommand("LOG_LEVEL", params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	
+	r, ok := result.(map[string]interface{})
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unexpected response format\n")
+		exit(1)
+	}
+	if len(args) > 0 {
+		fmt.Printf("Log level changed from %v to %v (until the daemon restarts; use 'snooze config set logging.log_level' to keep it)\n", r["previous"], r["level"])
+	} else {
+		fmt.Printf("Log level: %v\n", r["level"])
+	}
+}
+
+func showRuntime(client *api.SocketClient, args []string) {
+	runtimeCmd := flag.NewFlagSet("runtime", flag.ExitOnError)
+	jsonOutput := runtimeCmd.Bool("json", false, "Output as JSON")
+	
+	if err := runtimeCmd.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		exit(1)
+	}
+	
+	result, err := client.SendCommand("RUNTIME_STATS", nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	
+	r, ok := result.(map[string]interface{})
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unexpected response format\n")
+		exit(1)
+	}
+	
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(r, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	
+	mb := func(key string) float64 {
+		value, _ := r[key].(float64)
+		return value / (1024 * 1024)
+	}
+	uptime, _ := r["uptime_secs"].(float64)
+	fraction, _ := r["gc_cpu_fraction"].(float64)
+	
+	fmt.Printf("Go version:  %v (GOMAXPROCS %v)\n", r["go_version"], r["gomaxprocs"])
+	fmt.Printf("Uptime:      %s\n", time.Duration(uptime)*time.Second)
+	fmt.Printf("Goroutines:  %v\n", r["goroutines"])
+	fmt.Printf("Heap:        %.1f MB in use, %.1f MB reserved, %v objects\n", mb("heap_alloc_bytes"), mb("heap_sys_bytes"), r["heap_objects"])
+	fmt.Printf("Runtime:     %.1f MB from the OS, %.1f MB allocated in total\n", mb("sys_bytes"), mb("total_alloc_bytes"))
+	fmt.Printf("GC:          %v cycles, %.1f ms paused, %.2f%% of CPU\n", r["num_gc"], r["gc_pause_total_ms"], fraction*100)
+	if last, _ := r["last_gc"].(string); last != "" {
+		if t, err := time.Parse(time.RFC3339Nano, last); err == nil && !t.IsZero() {
+			fmt.Printf("Last GC:     %s\n", t.Local().Format("2006-01-02 15:04:05"))
+		}
+	}
+}
+
+func showAudit(client *api.SocketClient, args []string) {
+	auditCmd := flag.NewFlagSet("audit", flag.ExitOnError)
+	limit := auditCmd.Int("limit", 20, "Limit to N entries")
+	command := auditCmd.String("command", "", "Only show this command")
+	jsonOutput := auditCmd.Bool("json", false, "Output as JSON")
+	
+	if err := auditCmd.Parse(args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+		exit(1)
+	}
+	
+	params := map[string]interface{}{
+		"limit": *limit,
+	}
+	if *command != "" {
+		params["command"] = strings.ToUpper(*command)
+	}
+	
+	result, err := client.SendCommand("AUDIT", params)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		exit(1)
+	}
+	
+	records, ok := result.([]interface{})
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unexpected response format\n")
+		exit(1)
+	}
+	
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(records, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	
+	if len(records) == 0 {
+		fmt.Println("No commands recorded")
+		return
+	}
+	
+	for _, record := range records {
+		r, ok := record.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		
+		timestamp, _ := r["time"].(string)
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			t = time.Time{}
+		}
+		
+		who := "unknown"
+		if peer, ok := r["peer"].(map[string]interface{}); ok {
+			if name, _ := peer["user"].(string); name != "" {
+				who = name
+			} else {
+				who = fmt.Sprintf("uid %v", peer["uid"])
+			}
+			if pid, ok := peer["pid"].(float64); ok {
+				who += fmt.Sprintf(" (pid %d)", int(pid))
+			}
+		} else if remote, ok := r["remote"].(map[string]interface{}); ok {
+			who = fmt.Sprintf("cert %v (%v)", remote["certificate"], remote["address"])
+		}
+		
+		result := "ok"
+		if success, _ := r["success"].(bool); !success {
+			result = fmt.Sprintf("failed: %v", r["error"])
+		}
+		
+		fmt.Printf("%s  %-14s %-24s %s, %.1fms\n", t.Local().Format("2006-01-02 15:04:05"), r["command"], who, result, r["latency_ms"])
+		if params, _ := r["params"].(string); params != "" {
+			fmt.Printf("    %s\n", params)
+		}
+	}
+}
+
+func handlePlugin(client *api.SocketClient, args []string) {
+	if len(args) < 1 {
+		fmt.Println("Usage: snooze plugin [list|info|enable|disable|install]")
+		exit(1)
+	}
+	
+	action := args[0]
+	switch action {
+	case "list":
+		listPlugins(client, args[1:])
+		
+	case "info":
+		if len(args) < 2 {
+			fmt.Println("Usage: snooze plugin info <id>")
+			exit(1)
+		}
+		
+		result, err := client.SendCommand("PLUGIN_INFO", map[string]interface{}{"id": args[1]})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		
+		p, ok := result.(map[string]interface{})
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: unexpected response format\n")
+			exit(1)
+		}
+		
+		fmt.Printf("%s (%s) v%s\n", p["name"], p["id"], p["version"])
+		fmt.Printf("Type: %s\n", p["type"])
+		fmt.Printf("Author: %s\n", p["author"])
+		if website, _ := p["website"].(string); website != "" {
+			fmt.Printf("Website: %s\n", website)
+		}
+		if exec, _ := p["exec"].(string); exec != "" {
+			fmt.Printf("Executable: %s\n", exec)
+		}
+		if deps, ok := p["dependencies"].([]interface{}); ok && len(deps) > 0 {
+			depList := []string{}
+			for _, d := range deps {
+				depList = append(depList, fmt.Sprintf("%v", d))
+			}
+			fmt.Printf("Dependencies: %s\n", strings.Join(depList, ", "))
+		}
+		if caps, ok := p["capabilities"].(map[string]interface{}); ok && len(caps) > 0 {
+			capList := []string{}
+			for k, v := range caps {
+				if vBool, ok := v.(bool); ok && vBool {
+					capList = append(capList, k)
+				}
+			}
+			sort.Strings(capList)
+			fmt.Printf("Capabilities: %s\n", strings.Join(capList, ", "))
+		}
+		
+		enabled, _ := p["enabled"].(bool)
+		isRunning, _ := p["is_running"].(bool)
+		active, _ := p["active"].(bool)
+		fmt.Printf("Enabled: %v\n", enabled)
+		fmt.Printf("Running: %v\n", isRunning)
+		if active {
+			fmt.Println("Active cloud provider: true")
+		}
+		
+	case "enable", "disable":
+		if len(args) < 2 {
+			fmt.Printf("Usage: snooze plugin %s <id>\n", action)
+			exit(1)
+		}
+		
+		command := "PLUGIN_ENABLE"
+		if action == "disable" {
+			command = "PLUGIN_DISABLE"
+		}
+		result, err := client.SendCommand(command, map[string]interface{}{"id": args[1]})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		
+		fmt.Printf("Plugin %s %sd\n", args[1], action)
+		if resp, ok := result.(map[string]interface{}); ok {
+			if restart, _ := resp["restart_required"].(bool); restart {
+				fmt.Println("Restart the daemon for the change to take full effect")
+			}
+		}
+		
+	case "install":
+		installCmd := flag.NewFlagSet("plugin install", flag.ExitOnError)
+		checksum := installCmd.String("sha256", "", "Expected SHA-256 checksum of the archive")
+		if err := installCmd.Parse(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing flags: %v\n", err)
+			exit(1)
+		}
+		if installCmd.NArg() < 1 {
+			fmt.Println("Usage: snooze plugin install [--sha256 <checksum>] <url>")
+			exit(1)
+		}
+		
+		result, err := client.SendCommand("PLUGIN_INSTALL", map[string]interface{}{
+			"url":    installCmd.Arg(0),
+			"sha256": *checksum,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			exit(1)
+		}
+		
+		if p, ok := result.(map[string]interface{}); ok {
+			fmt.Printf("Installed %s (%s) v%s\n", p["name"], p["id"], p["version"])
+		}
+		
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown plugin action: %s\n", action)
+		fmt.Println("Usage: snooze plugin [list|info|enable|disable|install]")
+		exit(1)
+	}
+}
@@ -5,14 +5,27 @@ package accelerator
 
 import (
 	"fmt"
+	"log/slog"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
 )
 
+// reprobeInterval is how often the GPU tools are looked for again, so GPUs
+// whose drivers are installed after the daemon starts are picked up
+const reprobeInterval = 30 * time.Minute
+
+// logger returns the accelerator component logger
+func logger() *slog.Logger {
+	return logging.Component("accelerator")
+}
+
 // GPUMonitor is the interface for GPU monitoring
 type GPUMonitor interface {
 	// GetMetrics returns metrics for all detected GPUs
@@ -36,12 +49,9 @@ func (m *NvidiaMonitor) IsAvailable() bool {
 	return err == nil
 }
 
-// GetMetrics returns metrics for all NVIDIA GPUs
+// GetMetrics returns metrics for all NVIDIA GPUs. Availability isn't checked
+// here; GPUService only calls monitors it found available.
 func (m *NvidiaMonitor) GetMetrics() ([]common.GPUMetrics, error) {
-	if !m.IsAvailable() {
-		return nil, fmt.Errorf("nvidia-smi not available")
-	}
-
 	// Run nvidia-smi to get GPU info
 	cmd := exec.Command("nvidia-smi", "--query-gpu=index,name,utilization.gpu,memory.used,memory.total,temperature.gpu", "--format=csv,noheader,nounits")
 	output, err := cmd.Output()
@@ -97,12 +107,9 @@ func (m *AMDMonitor) IsAvailable() bool {
 	return err == nil
 }
 
-// GetMetrics returns metrics for all AMD GPUs
+// GetMetrics returns metrics for all AMD GPUs. Availability isn't checked
+// here; GPUService only calls monitors it found available.
 func (m *AMDMonitor) GetMetrics() ([]common.GPUMetrics, error) {
-	if !m.IsAvailable() {
-		return nil, fmt.Errorf("rocm-smi not available")
-	}
-
 	// Run rocm-smi to get GPU info
 	cmd := exec.Command("rocm-smi", "--showuse", "--showmemuse", "--showtemp")
 	output, err := cmd.Output()
@@ -159,9 +166,14 @@ func (m *AMDMonitor) GetMetrics() ([]common.GPUMetrics, error) {
 	return metrics, nil
 }
 
-// GPUService coordinates monitoring of multiple GPU types
+// GPUService coordinates monitoring of multiple GPU types. Which monitors are
+// available is probed once and then only every reprobeInterval, or after a
+// monitor fails, rather than on every collection.
 type GPUService struct {
-	monitors []GPUMonitor
+	monitors  []GPUMonitor
+	available []GPUMonitor
+	probed    time.Time
+	lock      sync.Mutex
 }
 
 // NewGPUService creates a new GPU monitoring service
@@ -182,12 +194,47 @@ func CreateGPUService() common.AcceleratorInterface {
 	return NewGPUService()
 }
 
-// Initialize implements the AcceleratorInterface
+// Initialize implements the AcceleratorInterface by probing for GPU tools
 func (s *GPUService) Initialize() error {
-	// Nothing to initialize for now
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.probe(time.Now())
 	return nil
 }
 
+// probe finds the available monitors. The caller holds the lock.
+func (s *GPUService) probe(now time.Time) {
+	s.available = s.available[:0]
+	for _, monitor := range s.monitors {
+		if monitor.IsAvailable() {
+			s.available = append(s.available, monitor)
+		}
+	}
+	s.probed = now
+	logger().Debug("Probed for GPU tools", "available", len(s.available))
+}
+
+// availableMonitors returns the available monitors, probing again if the
+// last probe is too old
+func (s *GPUService) availableMonitors() []GPUMonitor {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	if s.probed.IsZero() || now.Sub(s.probed) >= reprobeInterval {
+		s.probe(now)
+	}
+	return append([]GPUMonitor(nil), s.available...)
+}
+
+// reprobe makes the next collection probe again, after a monitor failed
+// because its tool may have gone away
+func (s *GPUService) reprobe() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.probed = time.Time{}
+}
+
 // GetMetrics implements the AcceleratorInterface
 func (s *GPUService) GetMetrics() ([]common.GPUMetrics, error) {
 	return s.GetAllMetrics()
@@ -218,14 +265,11 @@ func (s *GPUService) GetAllMetrics() ([]common.GPUMetrics, error) {
 	var allMetrics []common.GPUMetrics
 	var errs []string
 
-	for _, monitor := range s.monitors {
-		if !monitor.IsAvailable() {
-			continue
-		}
-
+	for _, monitor := range s.availableMonitors() {
 		metrics, err := monitor.GetMetrics()
 		if err != nil {
 			errs = append(errs, err.Error())
+			s.reprobe()
 			continue
 		}
 
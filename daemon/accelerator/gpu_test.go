@@ -4,7 +4,9 @@
 package accelerator
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/scttfrdmn/cloudsnooze/daemon/common"
 )
@@ -14,9 +16,11 @@ type MockGPUMonitor struct {
 	available bool
 	metrics   []common.GPUMetrics
 	err       error
+	probes    int
 }
 
 func (m *MockGPUMonitor) IsAvailable() bool {
+	m.probes++
 	return m.available
 }
 
@@ -139,4 +143,36 @@ func TestGPUServiceGetUtilization(t *testing.T) {
 	if utilization != 70.0 {
 		t.Errorf("Expected average utilization 70.0, got %f", utilization)
 	}
-}
\ No newline at end of file
+}
+func TestGPUServiceCachesAvailability(t *testing.T) {
+	monitor := &MockGPUMonitor{available: true, metrics: []common.GPUMetrics{{ID: "0"}}}
+	service := &GPUService{monitors: []GPUMonitor{monitor}}
+	if err := service.Initialize(); err != nil {
+		t.Fatalf("Initialize() returned error: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := service.GetMetrics(); err != nil {
+			t.Fatalf("GetMetrics() returned error: %v", err)
+		}
+	}
+	if monitor.probes != 1 {
+		t.Errorf("Expected availability to be probed once, got %d probes", monitor.probes)
+	}
+
+	// A failing monitor is probed again on the next collection
+	monitor.err = fmt.Errorf("nvidia-smi failed")
+	service.GetMetrics()
+	service.GetMetrics()
+	if monitor.probes != 2 {
+		t.Errorf("Expected a probe after the failure, got %d probes", monitor.probes)
+	}
+
+	// So is a stale probe
+	monitor.err = nil
+	service.probed = time.Now().Add(-reprobeInterval)
+	service.GetMetrics()
+	if monitor.probes != 3 {
+		t.Errorf("Expected a probe after the reprobe interval, got %d probes", monitor.probes)
+	}
+}
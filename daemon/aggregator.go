@@ -0,0 +1,232 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/aggregator"
+	"github.com/scttfrdmn/cloudsnooze/daemon/api"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+var (
+	aggregatorMode = flag.Bool("aggregator", false, "Collect the reports of many daemons and serve them as one fleet-wide API, instead of watching this instance")
+	reportTokenFor = flag.String("report-token", "", "Print the token the daemon of this instance ID pushes reports to the aggregator with, and exit")
+)
+
+// aggregationClient is who commands queued by the aggregation server run
+// as, for allowlists and the audit log
+const aggregationClient = "aggregator"
+
+// aggregationPushes asks the push loop for a report straight away
+var aggregationPushes = make(chan struct{}, 1)
+
+// pushAggregationNow pushes a report without waiting for the next one, such
+// as when the instance is about to be snoozed
+func pushAggregationNow() {
+	select {
+	case aggregationPushes <- struct{}{}:
+	default:
+	}
+}
+
+// runAggregator serves the aggregation API until a signal and returns the
+// exit code
+func runAggregator(config Config) int {
+	token, err := readTokenFile(config.Aggregator.TokenFile)
+	if err != nil {
+		logger().Error("Failed to read aggregator token", "error", err)
+		return 1
+	}
+	reportSecret, err := readTokenFile(config.Aggregator.ReportSecretFile)
+	if err != nil {
+		logger().Error("Failed to read aggregator report secret", "error", err)
+		return 1
+	}
+	s := aggregator.NewServer(aggregator.Config{
+		Token:        token,
+		ReportSecret: reportSecret,
+		StaleAfter:   time.Duration(config.Aggregator.StaleSeconds) * time.Second,
+		Commands:     config.Aggregator.Commands,
+	})
+
+	// Listen before giving up root, in case the port needs it
+	listener, err := net.Listen("tcp", config.Aggregator.ListenAddr)
+	if err != nil {
+		logger().Error("Failed to listen for the aggregation API", "address", config.Aggregator.ListenAddr, "error", err)
+		return 1
+	}
+	if err := dropPrivileges(config.Privileges, writablePaths(config)); err != nil {
+		logger().Error("Failed to drop privileges", "user", config.Privileges.User, "error", err)
+		listener.Close()
+		return 1
+	}
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		if config.Aggregator.CertFile != "" {
+			serverErr <- server.ServeTLS(listener, config.Aggregator.CertFile, config.Aggregator.KeyFile)
+		} else {
+			serverErr <- server.Serve(listener)
+		}
+	}()
+	logger().Info("Aggregator running", "address", config.Aggregator.ListenAddr, "tls", config.Aggregator.CertFile != "")
+
+	sigChan := make(chan os.Signal, 1)
+	notifyShutdown(sigChan)
+	exitCode := 0
+	select {
+	case sig := <-sigChan:
+		logger().Info("Received signal, shutting down", "signal", sig.String())
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger().Error("Aggregation API unavailable, shutting down", "error", err)
+			exitCode = 1
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	server.Shutdown(shutdownCtx)
+	return exitCode
+}
+
+// readTokenFile reads a bearer token, which must not be empty
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", path)
+	}
+	return token, nil
+}
+
+// printReportToken prints the report token of an instance for -report-token,
+// from the aggregator's report secret
+func printReportToken(config AggregatorConfig, instanceID string) error {
+	secret, err := readTokenFile(config.ReportSecretFile)
+	if err != nil {
+		return err
+	}
+	fmt.Println(aggregator.ReportToken(secret, instanceID))
+	return nil
+}
+
+// newAggregationPusher returns the pusher to the aggregation server, reading
+// its token and CA
+func newAggregationPusher(config AggregationConfig) (*aggregator.Pusher, error) {
+	token, err := readTokenFile(config.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aggregation token: %v", err)
+	}
+	var tlsConfig *tls.Config
+	if config.CAFile != "" {
+		data, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read aggregation CA: %v", err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in aggregation CA %s", config.CAFile)
+		}
+		tlsConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	}
+	return aggregator.NewPusher(config.ServerURL, token, tlsConfig), nil
+}
+
+// pushReports pushes a report to the aggregation server every interval
+// until ctx is cancelled. The commands the server replies with are run on
+// relay, and their results sent with the next report.
+func pushReports(ctx context.Context, pusher *aggregator.Pusher, relay *api.SocketServer, cloudProvider common.CloudProvider, config AggregationConfig) {
+	ticker := time.NewTicker(time.Duration(config.PushSeconds) * time.Second)
+	defer ticker.Stop()
+
+	var results []aggregator.CommandResult
+	for {
+		report := newReport(relay, cloudProvider, config.HistoryEvents)
+		report.Results = results
+		commands, err := pusher.Push(ctx, report)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			// Keep the results for the next report
+			logger().Warn("Failed to push report to the aggregation server", "error", err)
+		} else {
+			results = runAggregatedCommands(relay, commands)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-aggregationPushes:
+		}
+	}
+}
+
+// newReport reports the daemon's STATUS and its recent snooze events
+func newReport(relay *api.SocketServer, cloudProvider common.CloudProvider, historyEvents int) aggregator.Report {
+	report := aggregator.Report{Version: version, Time: time.Now()}
+	report.Hostname, _ = os.Hostname()
+	report.InstanceID = report.Hostname
+	if cloudProvider != nil {
+		if info, err := cloudProvider.GetInstanceInfo(); err == nil && info.ID != "" {
+			report.InstanceID = info.ID
+		}
+	}
+
+	if status, err := relay.Query("STATUS", nil); err != nil {
+		logger().Warn("Failed to get status for report", "error", err)
+	} else {
+		report.Status, _ = status.(map[string]interface{})
+	}
+	if historyEvents > 0 {
+		history, err := relay.Query("HISTORY", map[string]interface{}{"limit": float64(historyEvents)})
+		if err != nil {
+			logger().Warn("Failed to get history for report", "error", err)
+		}
+		report.History, _ = history.([]monitor.SnoozeEvent)
+	}
+	return report
+}
+
+// runAggregatedCommands runs the commands queued by the aggregation server
+// and returns their results
+func runAggregatedCommands(relay *api.SocketServer, commands []aggregator.Command) []aggregator.CommandResult {
+	results := make([]aggregator.CommandResult, 0, len(commands))
+	for _, command := range commands {
+		response := relay.Dispatch(aggregationClient, api.Request{Command: command.Command, Params: command.Params})
+		logger().Info("Ran command from the aggregation server", "id", command.ID, "command", command.Command,
+			"success", response.Success, "error", response.Error)
+		results = append(results, aggregator.CommandResult{
+			ID:      command.ID,
+			Command: command.Command,
+			Time:    time.Now(),
+			Success: response.Success,
+			Data:    response.Data,
+			Error:   response.Error,
+		})
+	}
+	return results
+}
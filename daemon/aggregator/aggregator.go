@@ -0,0 +1,401 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package aggregator collects the status and snooze history that daemons
+// push from many instances, and serves them as one fleet-wide API for
+// dashboards. Commands queued for the fleet, such as cancelling pending
+// snoozes, are handed to each daemon in reply to its next report.
+package aggregator
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+// logger returns the aggregator component logger
+func logger() *slog.Logger {
+	return logging.Component("aggregator")
+}
+
+// maxResults is how many command results are kept for each instance
+const maxResults = 20
+
+// maxReportBytes limits the size of a report body
+const maxReportBytes = 4 << 20
+
+// DefaultMaxInstances is how many instances a server keeps reports of
+// unless configured otherwise
+const DefaultMaxInstances = 10000
+
+// errTooManyInstances means a new instance reported to a full server
+var errTooManyInstances = errors.New("too many instances reporting")
+
+// Report is what a daemon pushes about its instance
+type Report struct {
+	InstanceID string                 `json:"instance_id"`
+	Hostname   string                 `json:"hostname,omitempty"`
+	Version    string                 `json:"version,omitempty"`
+	Time       time.Time              `json:"time"`
+	Status     map[string]interface{} `json:"status"`            // The daemon's STATUS
+	History    []monitor.SnoozeEvent  `json:"history,omitempty"` // Recent snooze events, newest first
+	Results    []CommandResult        `json:"results,omitempty"` // Outcomes of the commands received with the last report
+}
+
+// Command is a command queued for a daemon
+type Command struct {
+	ID      string                 `json:"id"`
+	Command string                 `json:"command"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+	Queued  time.Time              `json:"queued"`
+}
+
+// CommandResult is the outcome of a command a daemon ran
+type CommandResult struct {
+	ID      string      `json:"id"`
+	Command string      `json:"command"`
+	Time    time.Time   `json:"time"`
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// Instance is the latest report of an instance and its commands
+type Instance struct {
+	Report
+	Received time.Time       `json:"received"`
+	Stale    bool            `json:"stale"`             // No report within the stale period
+	Pending  []Command       `json:"pending,omitempty"` // Commands not yet handed to the daemon
+	Done     []CommandResult `json:"done,omitempty"`    // Outcomes of recent commands, newest last
+}
+
+// Config defines who may use the server and when instances go stale
+type Config struct {
+	Token        string        // Bearer token for dashboards and bulk operations
+	ReportSecret string        // Secret the report token of each daemon is derived from (see ReportToken)
+	StaleAfter   time.Duration // How long without a report before an instance is stale
+	Commands     []string      // Commands that may be queued for daemons
+	MaxInstances int           // Most instances kept, 0 for DefaultMaxInstances
+}
+
+// Server holds the latest report of each instance
+type Server struct {
+	config    Config
+	lock      sync.Mutex
+	instances map[string]*Instance
+	now       func() time.Time
+}
+
+// NewServer creates a server with no instances
+func NewServer(config Config) *Server {
+	if config.MaxInstances <= 0 {
+		config.MaxInstances = DefaultMaxInstances
+	}
+	return &Server{config: config, instances: make(map[string]*Instance), now: time.Now}
+}
+
+// ReportToken returns the token the daemon of an instance pushes reports
+// with. It is derived from the server's report secret, so a daemon can
+// only report as its own instance.
+func ReportToken(secret, instanceID string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(instanceID))
+	return instanceID + ":" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Receive records a report and returns the commands queued for its
+// instance, which are then no longer pending. A new instance makes room
+// by replacing the one that has been stale longest, and is refused if
+// none is stale.
+func (s *Server) Receive(report Report) ([]Command, error) {
+	if report.InstanceID == "" {
+		return nil, fmt.Errorf("instance_id is required")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	instance, exists := s.instances[report.InstanceID]
+	if !exists {
+		if len(s.instances) >= s.config.MaxInstances && !s.forgetStalest() {
+			return nil, errTooManyInstances
+		}
+		instance = &Instance{}
+		s.instances[report.InstanceID] = instance
+		logger().Info("Instance reporting", "instance", report.InstanceID, "hostname", report.Hostname)
+	}
+	instance.Done = append(instance.Done, report.Results...)
+	if extra := len(instance.Done) - maxResults; extra > 0 {
+		instance.Done = slices.Delete(instance.Done, 0, extra)
+	}
+	report.Results = nil
+	instance.Report = report
+	instance.Received = s.now()
+
+	commands := append([]Command{}, instance.Pending...)
+	instance.Pending = nil
+	return commands, nil
+}
+
+// Instances returns the latest report of every instance, by instance ID
+func (s *Server) Instances() []Instance {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	instances := make([]Instance, 0, len(s.instances))
+	for _, instance := range s.instances {
+		instances = append(instances, s.snapshot(instance))
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].InstanceID < instances[j].InstanceID
+	})
+	return instances
+}
+
+// Instance returns the latest report of one instance
+func (s *Server) Instance(instanceID string) (Instance, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	instance, exists := s.instances[instanceID]
+	if !exists {
+		return Instance{}, false
+	}
+	return s.snapshot(instance), true
+}
+
+// forgetStalest removes the instance that has been stale longest, and
+// returns false if none is stale. Callers hold the lock.
+func (s *Server) forgetStalest() bool {
+	var stalest string
+	for id, instance := range s.instances {
+		if s.snapshot(instance).Stale && (stalest == "" || instance.Received.Before(s.instances[stalest].Received)) {
+			stalest = id
+		}
+	}
+	if stalest == "" {
+		return false
+	}
+	delete(s.instances, stalest)
+	logger().Info("Forgot stale instance to make room", "instance", stalest)
+	return true
+}
+
+// snapshot copies an instance, marking it stale if it stopped reporting.
+// Callers hold the lock.
+func (s *Server) snapshot(instance *Instance) Instance {
+	copied := *instance
+	copied.Pending = slices.Clone(instance.Pending)
+	copied.Done = slices.Clone(instance.Done)
+	copied.Stale = s.config.StaleAfter > 0 && s.now().Sub(instance.Received) > s.config.StaleAfter
+	return copied
+}
+
+// History returns up to limit snooze events of every instance, newest
+// first. A limit of 0 returns them all.
+func (s *Server) History(limit int) []monitor.SnoozeEvent {
+	s.lock.Lock()
+	events := make([]monitor.SnoozeEvent, 0)
+	for _, instance := range s.instances {
+		events = append(events, instance.History...)
+	}
+	s.lock.Unlock()
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Timestamp.After(events[j].Timestamp)
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
+
+// Queue queues a command for the instances, or for every instance that
+// isn't stale if none are given. It returns the queued command for each
+// instance ID.
+func (s *Server) Queue(command string, params map[string]interface{}, instanceIDs []string) (map[string]Command, error) {
+	if !slices.Contains(s.config.Commands, command) {
+		return nil, fmt.Errorf("command %s can't be queued", command)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if len(instanceIDs) == 0 {
+		for id, instance := range s.instances {
+			if !s.snapshot(instance).Stale {
+				instanceIDs = append(instanceIDs, id)
+			}
+		}
+	}
+	for _, id := range instanceIDs {
+		if _, exists := s.instances[id]; !exists {
+			return nil, fmt.Errorf("unknown instance %s", id)
+		}
+	}
+
+	queued := make(map[string]Command, len(instanceIDs))
+	for _, id := range instanceIDs {
+		c := Command{ID: newCommandID(), Command: command, Params: params, Queued: s.now()}
+		s.instances[id].Pending = append(s.instances[id].Pending, c)
+		queued[id] = c
+	}
+	logger().Info("Queued command", "command", command, "instances", len(queued))
+	return queued, nil
+}
+
+// newCommandID returns a random command ID
+func newCommandID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// queueRequest is the body of POST /v1/commands
+type queueRequest struct {
+	Command   string                 `json:"command"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+	Instances []string               `json:"instances,omitempty"` // Empty for every instance that isn't stale
+	Tags      map[string]string      `json:"tags,omitempty"`      // Only the instances with all of these tags
+}
+
+// Handler serves the API. Daemons POST /v1/reports about their own
+// instance and read the state of other instances with
+// GET /v1/states?instance=ID&instance=ID using their report token;
+// dashboards use GET /v1/fleet?tag=KEY=VALUE, GET /v1/instances,
+// GET /v1/instances/{id}, GET /v1/history?limit=N and POST /v1/commands with
+// the other token.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/reports", s.reporter(func(w http.ResponseWriter, req *http.Request, instanceID string) {
+		var report Report
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxReportBytes)).Decode(&report); err != nil {
+			http.Error(w, "invalid report: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if report.InstanceID != instanceID {
+			http.Error(w, "the report token is for instance "+instanceID, http.StatusForbidden)
+			return
+		}
+		commands, err := s.Receive(report)
+		if errors.Is(err, errTooManyInstances) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"commands": commands})
+	}))
+	mux.HandleFunc("GET /v1/states", s.reporter(func(w http.ResponseWriter, req *http.Request, instanceID string) {
+		writeJSON(w, http.StatusOK, s.Members(req.URL.Query()["instance"]))
+	}))
+	mux.HandleFunc("GET /v1/instances", s.authorized(s.config.Token, func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, s.Instances())
+	}))
+	mux.HandleFunc("GET /v1/instances/{id}", s.authorized(s.config.Token, func(w http.ResponseWriter, req *http.Request) {
+		instance, exists := s.Instance(req.PathValue("id"))
+		if !exists {
+			http.Error(w, "unknown instance", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, instance)
+	}))
+	mux.HandleFunc("GET /v1/fleet", s.authorized(s.config.Token, func(w http.ResponseWriter, req *http.Request) {
+		tags, err := common.ParseTagFilters(req.URL.Query()["tag"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Fleet().Tagged(tags))
+	}))
+	mux.HandleFunc("GET /v1/history", s.authorized(s.config.Token, func(w http.ResponseWriter, req *http.Request) {
+		limit := 50
+		if value := req.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				http.Error(w, "limit must be a non-negative number", http.StatusBadRequest)
+				return
+			}
+			limit = parsed
+		}
+		writeJSON(w, http.StatusOK, s.History(limit))
+	}))
+	mux.HandleFunc("POST /v1/commands", s.authorized(s.config.Token, func(w http.ResponseWriter, req *http.Request) {
+		var body queueRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, maxReportBytes)).Decode(&body); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		instances := body.Instances
+		if len(body.Tags) > 0 {
+			instances = s.Tagged(body.Tags, body.Instances)
+			if len(instances) == 0 {
+				http.Error(w, "no instances have the tags", http.StatusBadRequest)
+				return
+			}
+		}
+		queued, err := s.Queue(body.Command, body.Params, instances)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{"queued": queued})
+	}))
+	return mux
+}
+
+// authorized wraps handler so it needs "Authorization: Bearer TOKEN"
+func (s *Server) authorized(token string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, req)
+	}
+}
+
+// reporter wraps handler so it needs "Authorization: Bearer TOKEN" with a
+// daemon's report token, passing on the instance the token is for
+func (s *Server) reporter(handler func(w http.ResponseWriter, req *http.Request, instanceID string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		token, _ := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		separator := strings.LastIndex(token, ":")
+		if s.config.ReportSecret == "" || separator <= 0 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		instanceID := token[:separator]
+		if subtle.ConstantTimeCompare([]byte(token), []byte(ReportToken(s.config.ReportSecret, instanceID))) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, req, instanceID)
+	}
+}
+
+// writeJSON writes value as the JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logger().Debug("Failed to write response", "error", err)
+	}
+}
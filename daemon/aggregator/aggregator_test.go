@@ -0,0 +1,364 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aggregator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+// testConfig lets dashboards cancel pending snoozes
+var testConfig = Config{
+	Token:        "dashboard",
+	ReportSecret: "daemon",
+	StaleAfter:   5 * time.Minute,
+	Commands:     []string{"CANCEL", "LEASE"},
+}
+
+// reportToken returns the report token of an instance's daemon
+func reportToken(instanceID string) string {
+	return ReportToken(testConfig.ReportSecret, instanceID)
+}
+
+func TestReceive(t *testing.T) {
+	s := NewServer(testConfig)
+	start := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return start }
+
+	for _, id := range []string{"i-web", "i-gpu"} {
+		_, err := s.Receive(Report{
+			InstanceID: id,
+			Status:     map[string]interface{}{"should_snooze": false},
+			History:    []monitor.SnoozeEvent{{InstanceID: id, Timestamp: start.Add(-time.Hour), Reason: id + " idle"}},
+		})
+		if err != nil {
+			t.Fatalf("Receive failed: %v", err)
+		}
+	}
+	if _, err := s.Receive(Report{}); err == nil {
+		t.Error("Expected a report without an instance ID to be refused")
+	}
+
+	// i-gpu stops reporting
+	s.now = func() time.Time { return start.Add(10 * time.Minute) }
+	if _, err := s.Receive(Report{InstanceID: "i-web"}); err != nil {
+		t.Fatalf("Receive failed: %v", err)
+	}
+	instances := s.Instances()
+	if len(instances) != 2 || instances[0].InstanceID != "i-gpu" || !instances[0].Stale || instances[1].Stale {
+		t.Fatalf("Expected i-gpu to be stale and i-web not, got %+v", instances)
+	}
+
+	// Commands for every instance only go to those still reporting
+	queued, err := s.Queue("CANCEL", nil, nil)
+	if err != nil {
+		t.Fatalf("Queue failed: %v", err)
+	}
+	if len(queued) != 1 || queued["i-web"].ID == "" {
+		t.Fatalf("Expected CANCEL to be queued for i-web, got %v", queued)
+	}
+	if _, err := s.Queue("CONFIG_SET", nil, nil); err == nil {
+		t.Error("Expected a command that isn't allowed to be refused")
+	}
+	if _, err := s.Queue("CANCEL", nil, []string{"i-missing"}); err == nil {
+		t.Error("Expected a command for an unknown instance to be refused")
+	}
+
+	// The daemon receives the command with its next report, and reports
+	// the result with the one after
+	commands, _ := s.Receive(Report{InstanceID: "i-web"})
+	if len(commands) != 1 || commands[0].Command != "CANCEL" {
+		t.Fatalf("Expected CANCEL to be handed to i-web, got %+v", commands)
+	}
+	result := CommandResult{ID: commands[0].ID, Command: "CANCEL", Success: true}
+	if commands, _ := s.Receive(Report{InstanceID: "i-web", Results: []CommandResult{result}}); len(commands) != 0 {
+		t.Errorf("Expected the command to be handed over once, got %+v", commands)
+	}
+	if instance, _ := s.Instance("i-web"); len(instance.Done) != 1 || instance.Done[0].ID != result.ID || len(instance.Results) != 0 {
+		t.Errorf("Expected the command result to be kept, got %+v", instance)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s := NewServer(testConfig)
+	base := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.Receive(Report{InstanceID: "i-web", History: []monitor.SnoozeEvent{
+		{InstanceID: "i-web", Timestamp: base.Add(3 * time.Hour)},
+		{InstanceID: "i-web", Timestamp: base.Add(1 * time.Hour)},
+	}})
+	s.Receive(Report{InstanceID: "i-gpu", History: []monitor.SnoozeEvent{
+		{InstanceID: "i-gpu", Timestamp: base.Add(2 * time.Hour)},
+	}})
+
+	events := s.History(2)
+	if len(events) != 2 || events[0].InstanceID != "i-web" || events[1].InstanceID != "i-gpu" {
+		t.Errorf("Expected the two newest events of the fleet, got %+v", events)
+	}
+	if events := s.History(0); len(events) != 3 {
+		t.Errorf("Expected every event, got %d", len(events))
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s := NewServer(testConfig)
+	handler := s.Handler()
+	request := func(method, path, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	daemon := reportToken("i-web")
+	tests := []struct {
+		method, path, token, body string
+		status                    int
+	}{
+		{http.MethodPost, "/v1/reports", "", `{"instance_id":"i-web"}`, http.StatusUnauthorized},
+		{http.MethodPost, "/v1/reports", "dashboard", `{"instance_id":"i-web"}`, http.StatusUnauthorized},
+		{http.MethodPost, "/v1/reports", "daemon", `{"instance_id":"i-web"}`, http.StatusUnauthorized},
+		{http.MethodPost, "/v1/reports", daemon, `not json`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/reports", daemon, `{"instance_id":"i-web"}`, http.StatusOK},
+		{http.MethodGet, "/v1/instances", daemon, "", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/instances", "dashboard", "", http.StatusOK},
+		{http.MethodGet, "/v1/fleet", daemon, "", http.StatusUnauthorized},
+		{http.MethodGet, "/v1/fleet", "dashboard", "", http.StatusOK},
+		{http.MethodGet, "/v1/instances/i-web", "dashboard", "", http.StatusOK},
+		{http.MethodGet, "/v1/instances/i-missing", "dashboard", "", http.StatusNotFound},
+		{http.MethodGet, "/v1/history?limit=x", "dashboard", "", http.StatusBadRequest},
+		{http.MethodGet, "/v1/history?limit=5", "dashboard", "", http.StatusOK},
+		{http.MethodPost, "/v1/commands", daemon, `{"command":"CANCEL"}`, http.StatusUnauthorized},
+		{http.MethodPost, "/v1/commands", "dashboard", `{"command":"CONFIG_SET"}`, http.StatusBadRequest},
+		{http.MethodPost, "/v1/commands", "dashboard", `{"command":"LEASE","params":{"hours":2},"instances":["i-web"]}`, http.StatusAccepted},
+	}
+	for _, tt := range tests {
+		if rec := request(tt.method, tt.path, tt.token, tt.body); rec.Code != tt.status {
+			t.Errorf("%s %s with token %q: expected %d, got %d: %s", tt.method, tt.path, tt.token, tt.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := request(http.MethodPost, "/v1/reports", daemon, `{"instance_id":"i-web"}`)
+	var reply struct {
+		Commands []Command `json:"commands"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&reply); err != nil {
+		t.Fatalf("Invalid reply: %v", err)
+	}
+	if len(reply.Commands) != 1 || reply.Commands[0].Command != "LEASE" || reply.Commands[0].Params["hours"] != 2.0 {
+		t.Errorf("Expected the queued lease in the reply, got %+v", reply.Commands)
+	}
+}
+
+func TestPush(t *testing.T) {
+	s := NewServer(testConfig)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	if _, err := NewPusher(server.URL, "wrong", nil).Push(context.Background(), Report{InstanceID: "i-web"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected an unauthorized push to fail, got %v", err)
+	}
+
+	pusher := NewPusher(server.URL+"/", reportToken("i-web"), nil)
+	if _, err := pusher.Push(context.Background(), Report{InstanceID: "i-web", Version: "1.0.0"}); err != nil {
+		t.Fatalf("Push failed: %v", err)
+	}
+	if _, err := s.Queue("CANCEL", nil, []string{"i-web"}); err != nil {
+		t.Fatalf("Queue failed: %v", err)
+	}
+	commands, err := pusher.Push(context.Background(), Report{InstanceID: "i-web"})
+	if err != nil || len(commands) != 1 || commands[0].Command != "CANCEL" {
+		t.Errorf("Expected CANCEL in reply to the push, got %+v and %v", commands, err)
+	}
+}
+
+func TestReportsTiedToInstance(t *testing.T) {
+	s := NewServer(testConfig)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	s.Receive(Report{InstanceID: "i-gpu", Status: map[string]interface{}{"should_snooze": false}})
+	if _, err := s.Queue("CANCEL", nil, []string{"i-gpu"}); err != nil {
+		t.Fatalf("Queue failed: %v", err)
+	}
+
+	// i-web's daemon can't report as i-gpu, or take its commands
+	web := NewPusher(server.URL, reportToken("i-web"), nil)
+	if _, err := web.Push(context.Background(), Report{InstanceID: "i-gpu"}); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Expected a report for another instance to be forbidden, got %v", err)
+	}
+	if instance, _ := s.Instance("i-gpu"); len(instance.Pending) != 1 || instance.Status["should_snooze"] != false {
+		t.Errorf("Expected i-gpu to be left alone, got %+v", instance)
+	}
+
+	// Nor make up a token for it
+	forged := NewPusher(server.URL, "i-gpu:"+strings.Repeat("0", 64), nil)
+	if _, err := forged.Push(context.Background(), Report{InstanceID: "i-gpu"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected a forged token to be refused, got %v", err)
+	}
+
+	commands, err := NewPusher(server.URL, reportToken("i-gpu"), nil).Push(context.Background(), Report{InstanceID: "i-gpu"})
+	if err != nil || len(commands) != 1 {
+		t.Errorf("Expected i-gpu's own daemon to receive its command, got %+v and %v", commands, err)
+	}
+}
+
+func TestReceiveInstanceLimit(t *testing.T) {
+	config := testConfig
+	config.MaxInstances = 2
+	s := NewServer(config)
+	start := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return start }
+
+	s.Receive(Report{InstanceID: "i-old"})
+	s.now = func() time.Time { return start.Add(time.Minute) }
+	s.Receive(Report{InstanceID: "i-gpu"})
+	if _, err := s.Receive(Report{InstanceID: "i-web"}); err == nil {
+		t.Error("Expected a new instance to be refused while none is stale")
+	}
+	if _, err := s.Receive(Report{InstanceID: "i-gpu"}); err != nil {
+		t.Errorf("Expected known instances to keep reporting, got %v", err)
+	}
+
+	// Once both are stale, the one stale longest makes room
+	s.now = func() time.Time { return start.Add(time.Hour) }
+	if _, err := s.Receive(Report{InstanceID: "i-web"}); err != nil {
+		t.Fatalf("Expected a stale instance to make room, got %v", err)
+	}
+	if _, exists := s.Instance("i-old"); exists {
+		t.Error("Expected the instance stale longest to be forgotten")
+	}
+	if _, exists := s.Instance("i-gpu"); !exists {
+		t.Error("Expected the other instance to be kept")
+	}
+}
+
+func TestFleet(t *testing.T) {
+	s := NewServer(testConfig)
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now.Add(-10 * time.Minute) }
+
+	// i-old snoozed before it stopped reporting, and i-lost just stopped
+	s.Receive(Report{InstanceID: "i-old", History: []monitor.SnoozeEvent{
+		{InstanceID: "i-old", Timestamp: now.Add(-10 * time.Minute), Event: monitor.EventSnooze, Reason: "Idle for 30 minutes"},
+	}})
+	s.Receive(Report{InstanceID: "i-lost"})
+	s.now = func() time.Time { return now }
+	s.Receive(Report{InstanceID: "i-busy", Hostname: "build", Status: map[string]interface{}{"idle_since": ""}})
+	s.Receive(Report{InstanceID: "i-idle", Status: map[string]interface{}{
+		"idle_since":    now.Add(-5 * time.Minute).Format(time.RFC3339),
+		"snooze_reason": "Idle for 5 minutes",
+	}})
+	s.Receive(Report{InstanceID: "i-stopping", Status: map[string]interface{}{
+		"idle_since": now.Add(-30 * time.Minute).Format(time.RFC3339),
+		"countdown":  map[string]interface{}{"reason": "Idle", "deadline": now.Add(time.Minute).Format(time.RFC3339), "remaining_secs": 120},
+	}})
+
+	status := s.Fleet()
+	want := map[string]string{
+		"i-busy":     common.FleetStateActive,
+		"i-idle":     common.FleetStateIdle,
+		"i-lost":     common.FleetStateStale,
+		"i-old":      common.FleetStateStopped,
+		"i-stopping": common.FleetStateCountdown,
+	}
+	if len(status.Instances) != len(want) {
+		t.Fatalf("Expected %d instances, got %+v", len(want), status.Instances)
+	}
+	for _, member := range status.Instances {
+		if member.State != want[member.InstanceID] {
+			t.Errorf("Expected %s to be %s, got %+v", member.InstanceID, want[member.InstanceID], member)
+		}
+	}
+	if counts := status.Counts; counts[common.FleetStateStopped] != 1 || counts[common.FleetStateActive] != 1 {
+		t.Errorf("Expected each state to be counted, got %v", counts)
+	}
+	if c := status.Instances[4].Countdown; c == nil || c.RemainingSecs != 60 {
+		t.Errorf("Expected 60 seconds left of the countdown, got %+v", c)
+	}
+	if old := status.Instances[3]; old.LastEvent == nil || old.LastEvent.Reason != "Idle for 30 minutes" {
+		t.Errorf("Expected the snooze as i-old's last event, got %+v", old.LastEvent)
+	}
+}
+
+func TestStates(t *testing.T) {
+	s := NewServer(testConfig)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	s.Receive(Report{InstanceID: "i-scheduler", Status: map[string]interface{}{"idle_since": ""}})
+	s.Receive(Report{InstanceID: "i-worker"})
+
+	// Daemons read states with the report token, not the dashboard one
+	if _, err := NewPusher(server.URL, "dashboard", nil).States(context.Background(), []string{"i-scheduler"}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected states to need the report token, got %v", err)
+	}
+	states, err := NewPusher(server.URL, reportToken("i-worker"), nil).States(context.Background(), []string{"i-scheduler", "i-missing"})
+	if err != nil {
+		t.Fatalf("States failed: %v", err)
+	}
+	if len(states) != 1 || states[0].InstanceID != "i-scheduler" || states[0].State != common.FleetStateActive {
+		t.Errorf("Expected only i-scheduler, active, got %+v", states)
+	}
+}
+
+func TestTagged(t *testing.T) {
+	s := NewServer(testConfig)
+	server := httptest.NewServer(s.Handler())
+	defer server.Close()
+
+	for id, team := range map[string]string{"i-a": "研", "i-b": "研", "i-c": "web"} {
+		s.Receive(Report{InstanceID: id, Status: map[string]interface{}{
+			"instance_info": map[string]interface{}{"ID": id, "Tags": map[string]interface{}{"team": team}},
+		}})
+	}
+	if tagged := s.Tagged(map[string]string{"team": "研"}, nil); len(tagged) != 2 || tagged[0] != "i-a" {
+		t.Errorf("Expected the team's instances, got %v", tagged)
+	}
+
+	// A pause leases the team's instances, which are then reported paused
+	body := `{"command": "LEASE", "params": {"hours": 72, "reason": "Release weekend"}, "tags": {"team": "研"}}`
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/commands", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer dashboard")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var queued struct {
+		Queued map[string]Command `json:"queued"`
+	}
+	json.NewDecoder(resp.Body).Decode(&queued)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted || len(queued.Queued) != 2 || queued.Queued["i-c"].ID != "" {
+		t.Errorf("Expected the lease to be queued for the team only, got %d and %+v", resp.StatusCode, queued.Queued)
+	}
+	s.Receive(Report{InstanceID: "i-a", Status: map[string]interface{}{
+		"instance_info": map[string]interface{}{"Tags": map[string]interface{}{"team": "研"}},
+		"leases":        []interface{}{map[string]interface{}{"owner": "aggregator", "reason": "Release weekend"}},
+	}})
+
+	req, _ = http.NewRequest(http.MethodGet, server.URL+"/v1/fleet?tag="+url.QueryEscape("team=研"), nil)
+	req.Header.Set("Authorization", "Bearer dashboard")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	var status common.FleetStatus
+	json.NewDecoder(resp.Body).Decode(&status)
+	resp.Body.Close()
+	if len(status.Instances) != 2 || status.Counts[common.FleetStatePaused] != 1 || status.Instances[0].Tags["team"] != "研" {
+		t.Errorf("Expected the team's instances, one paused, got %+v", status)
+	}
+}
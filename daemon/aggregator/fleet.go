@@ -0,0 +1,121 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aggregator
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+// reportedStatus is the part of a daemon's STATUS the fleet status uses
+type reportedStatus struct {
+	IdleSince    string                 `json:"idle_since"`
+	SnoozeReason string                 `json:"snooze_reason"`
+	Countdown    *common.FleetCountdown `json:"countdown"`
+	Leases       []common.InstanceLease `json:"leases"`
+	InstanceInfo *struct {
+		Tags map[string]string `json:"Tags"`
+	} `json:"instance_info"`
+}
+
+// Fleet returns the state of every instance. An instance that stopped
+// reporting after a snooze is stopped rather than stale.
+func (s *Server) Fleet() common.FleetStatus {
+	now := s.now()
+	instances := s.Instances()
+	members := make([]common.FleetMemberStatus, 0, len(instances))
+	for _, instance := range instances {
+		members = append(members, fleetMember(instance, now))
+	}
+	return common.NewFleetStatus(members, now)
+}
+
+// Members returns the state of the instances that have reported, of those
+// given, so daemons can check the instances they depend on
+func (s *Server) Members(instanceIDs []string) []common.FleetMemberStatus {
+	now := s.now()
+	members := make([]common.FleetMemberStatus, 0, len(instanceIDs))
+	for _, id := range instanceIDs {
+		if instance, exists := s.Instance(id); exists {
+			members = append(members, fleetMember(instance, now))
+		}
+	}
+	return members
+}
+
+// Tagged returns the IDs of the instances that aren't stale and have all of
+// tags, of those given or of every instance if none are
+func (s *Server) Tagged(tags map[string]string, instanceIDs []string) []string {
+	now := s.now()
+	var tagged []string
+	for _, instance := range s.Instances() {
+		if instance.Stale || (len(instanceIDs) > 0 && !slices.Contains(instanceIDs, instance.InstanceID)) {
+			continue
+		}
+		if common.HasTags(fleetMember(instance, now).Tags, tags) {
+			tagged = append(tagged, instance.InstanceID)
+		}
+	}
+	return tagged
+}
+
+// fleetMember summarizes an instance's latest report
+func fleetMember(instance Instance, now time.Time) common.FleetMemberStatus {
+	member := common.FleetMemberStatus{
+		InstanceID: instance.InstanceID,
+		Name:       instance.Hostname,
+		State:      common.FleetStateActive,
+		LastSeen:   instance.Received,
+	}
+	var last *monitor.SnoozeEvent
+	if len(instance.History) > 0 {
+		last = &instance.History[0]
+		member.LastEvent = &common.FleetEvent{Time: last.Timestamp, Event: monitor.EventSnooze, Reason: last.Reason}
+		if last.IsStart() {
+			member.LastEvent.Event = monitor.EventStart
+		}
+	}
+
+	// The status was decoded from JSON, so decode the fields needed again
+	var status reportedStatus
+	if data, err := json.Marshal(instance.Status); err == nil {
+		json.Unmarshal(data, &status)
+	}
+	if idleSince, err := time.Parse(time.RFC3339, status.IdleSince); err == nil {
+		member.IdleSince = &idleSince
+	}
+	if status.InstanceInfo != nil {
+		member.Tags = status.InstanceInfo.Tags
+	}
+
+	switch {
+	case instance.Stale && last != nil && !last.IsStart():
+		// Its last report was sent as it snoozed
+		member.State = common.FleetStateStopped
+		member.Reason = last.Reason
+	case instance.Stale:
+		member.State = common.FleetStateStale
+		member.Reason = "No report since " + instance.Received.Format(time.RFC3339)
+	case status.Countdown != nil:
+		member.State = common.FleetStateCountdown
+		member.Countdown = status.Countdown
+		// Count down from now rather than from when the daemon reported
+		member.Countdown.RemainingSecs = max(0, int(status.Countdown.Deadline.Sub(now).Seconds()))
+		member.Reason = status.Countdown.Reason
+	case len(status.Leases) > 0:
+		// A lease, such as one taken to pause the fleet, keeps it running
+		lease := status.Leases[0]
+		member.State = common.FleetStatePaused
+		member.Reason = fmt.Sprintf("Leased by %s until %s: %s", lease.Owner, lease.ExpiresAt.Format(time.RFC3339), lease.Reason)
+	case member.IdleSince != nil:
+		member.State = common.FleetStateIdle
+		member.Reason = status.SnoozeReason
+	}
+	return member
+}
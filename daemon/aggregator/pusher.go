@@ -0,0 +1,102 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aggregator
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// pushTimeout limits each push to the server
+const pushTimeout = 30 * time.Second
+
+// Pusher pushes a daemon's reports to a server
+type Pusher struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewPusher creates a pusher to the server at url, such as
+// https://snooze.example.com:8443. tlsConfig may be nil for the system's
+// CAs.
+func NewPusher(url, token string, tlsConfig *tls.Config) *Pusher {
+	return &Pusher{
+		url:   strings.TrimSuffix(url, "/"),
+		token: token,
+		client: &http.Client{
+			Timeout:   pushTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		},
+	}
+}
+
+// Push sends a report and returns the commands queued for the instance
+func (p *Pusher) Push(ctx context.Context, report Report) ([]Command, error) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/v1/reports", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to push report: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("aggregation server returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var reply struct {
+		Commands []Command `json:"commands"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("invalid reply from aggregation server: %v", err)
+	}
+	return reply.Commands, nil
+}
+
+// States returns the state of the instances that have reported to the
+// server, of those given
+func (p *Pusher) States(ctx context.Context, instanceIDs []string) ([]common.FleetMemberStatus, error) {
+	query := url.Values{"instance": instanceIDs}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"/v1/states?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance states: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("aggregation server returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var states []common.FleetMemberStatus
+	if err := json.NewDecoder(resp.Body).Decode(&states); err != nil {
+		return nil, fmt.Errorf("invalid reply from aggregation server: %v", err)
+	}
+	return states, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+// ClientAllowlist limits a client to some commands. The client is matched
+// by one of its local user, the token it sends, or the common name of its
+// TLS certificate. A matched client may run only the listed commands, "*"
+// allowing every command, whatever its other privileges; root commands
+// still need root.
+type ClientAllowlist struct {
+	User        string   // User name or UID of a local client
+	Token       string   // Token sent in requests
+	Certificate string   // Common name of a TLS client certificate
+	Commands    []string // Commands the client may run
+}
+
+// allowlist is a resolved ClientAllowlist
+type allowlist struct {
+	uid         int // -1 to not match local users
+	token       string
+	certificate string
+	commands    map[string]bool
+}
+
+// allows reports whether the allowlist permits command
+func (a allowlist) allows(command string) bool {
+	return a.commands["*"] || a.commands[command]
+}
+
+// SetAllowlists sets the commands clients are limited to. Each allowlist
+// must identify its client in exactly one way.
+func (s *SocketServer) SetAllowlists(lists []ClientAllowlist) error {
+	resolved := make([]allowlist, 0, len(lists))
+	for i, list := range lists {
+		identities := 0
+		for _, identity := range []string{list.User, list.Token, list.Certificate} {
+			if identity != "" {
+				identities++
+			}
+		}
+		if identities != 1 {
+			return fmt.Errorf("allowlist %d must set exactly one of user, token or certificate", i)
+		}
+
+		a := allowlist{uid: -1, token: list.Token, certificate: list.Certificate, commands: make(map[string]bool, len(list.Commands))}
+		if list.User != "" {
+			uid, err := LookupUserID(list.User)
+			if err != nil {
+				return err
+			}
+			a.uid = uid
+		}
+		for _, command := range list.Commands {
+			a.commands[command] = true
+		}
+		resolved = append(resolved, a)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allowlists = resolved
+	return nil
+}
+
+// allowlistFor returns the allowlist matching the caller, preferring a
+// token, then a certificate, then a local user
+func (s *SocketServer) allowlistFor(from caller, request Request) (allowlist, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if request.Token != "" {
+		for _, list := range s.allowlists {
+			if list.token != "" && subtle.ConstantTimeCompare([]byte(request.Token), []byte(list.token)) == 1 {
+				return list, true
+			}
+		}
+	}
+	for _, list := range s.allowlists {
+		switch {
+		case from.remote != nil && list.certificate != "" && list.certificate == from.remote.Certificate:
+			return list, true
+		case from.remote == nil && from.peer != nil && list.uid >= 0 && list.uid == from.peer.UID:
+			return list, true
+		}
+	}
+	return allowlist{}, false
+}
+
+// LookupUserID returns the UID of a user name or numeric UID
+func LookupUserID(name string) (int, error) {
+	if uid, err := strconv.Atoi(name); err == nil {
+		return uid, nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown user %q: %v", name, err)
+	}
+	return strconv.Atoi(u.Uid)
+}
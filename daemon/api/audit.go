@@ -0,0 +1,208 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAuditBufferSize is how many audit records are kept in memory
+const DefaultAuditBufferSize = 1000
+
+// maxParamLength truncates long parameter values in audit records
+const maxParamLength = 64
+
+// sensitiveWords mark parameters whose values are never recorded
+var sensitiveWords = []string{"token", "password", "secret", "webhook", "credential", "api_key", "apikey", "authorization"}
+
+// Peer identifies the process on the other end of a connection
+type Peer struct {
+	UID  int    `json:"uid"`
+	GID  int    `json:"gid"`
+	PID  int    `json:"pid,omitempty"`
+	User string `json:"user,omitempty"`
+}
+
+// AuditRecord describes one command received on the socket
+type AuditRecord struct {
+	Time      time.Time     `json:"time"`
+	Command   string        `json:"command"`
+	Params    string        `json:"params,omitempty"` // Summary with sensitive values redacted
+	Peer      *Peer         `json:"peer,omitempty"`   // Nil where the platform can't identify peers
+	Remote    *RemoteClient `json:"remote,omitempty"` // TLS client of the TCP API
+	Success   bool          `json:"success"`
+	Error     string        `json:"error,omitempty"`
+	LatencyMS float64       `json:"latency_ms"`
+}
+
+// AuditLog keeps the most recent records in a ring buffer and appends every
+// record to an optional JSON lines file
+type AuditLog struct {
+	records []AuditRecord
+	next    int
+	full    bool
+	out     io.WriteCloser
+	lock    sync.Mutex
+}
+
+// NewAuditLog creates an audit log holding size records in memory. If out
+// is not nil, every record is also written to it.
+func NewAuditLog(size int, out io.WriteCloser) *AuditLog {
+	if size <= 0 {
+		size = DefaultAuditBufferSize
+	}
+	return &AuditLog{
+		records: make([]AuditRecord, size),
+		out:     out,
+	}
+}
+
+// Add records a command
+func (a *AuditLog) Add(record AuditRecord) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.records[a.next] = record
+	a.next = (a.next + 1) % len(a.records)
+	if a.next == 0 {
+		a.full = true
+	}
+
+	if a.out != nil {
+		data, err := json.Marshal(record)
+		if err == nil {
+			_, err = a.out.Write(append(data, '\n'))
+		}
+		if err != nil {
+			logger().Warn("Failed to write audit record", "error", err)
+		}
+	}
+}
+
+// Usage returns the number of records held and the most that are kept
+func (a *AuditLog) Usage() (int, int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.full {
+		return len(a.records), len(a.records)
+	}
+	return a.next, len(a.records)
+}
+
+// Recent returns up to limit records, newest first, optionally only those
+// for one command. A limit of 0 returns all buffered records.
+func (a *AuditLog) Recent(limit int, command string) []AuditRecord {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	count := a.next
+	if a.full {
+		count = len(a.records)
+	}
+
+	result := make([]AuditRecord, 0)
+	for i := 0; i < count; i++ {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		record := a.records[(a.next-1-i+len(a.records))%len(a.records)]
+		if command != "" && !strings.EqualFold(record.Command, command) {
+			continue
+		}
+		result = append(result, record)
+	}
+	return result
+}
+
+// Close closes the audit file
+func (a *AuditLog) Close() error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if a.out == nil {
+		return nil
+	}
+	err := a.out.Close()
+	a.out = nil
+	return err
+}
+
+// summarizeParams formats params as sorted key=value pairs, truncating long
+// values and redacting secrets
+func summarizeParams(params map[string]interface{}) string {
+	if len(params) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// CONFIG_SET passes the setting's name separately from its value
+	name, _ := params["name"].(string)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		var value string
+		if IsSensitive(key) || (key == "value" && IsSensitive(name)) {
+			value = "[redacted]"
+		} else {
+			value = formatParam(params[key])
+		}
+		parts = append(parts, key+"="+value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatParam formats a parameter value, truncated to maxParamLength
+func formatParam(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = strconv.Quote(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			s = fmt.Sprint(v)
+		} else {
+			s = string(data)
+		}
+	}
+	if len(s) > maxParamLength {
+		s = s[:maxParamLength] + "..."
+	}
+	return s
+}
+
+// IsSensitive returns true if a parameter or setting name suggests a
+// secret value
+func IsSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range sensitiveWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupUser fills in the peer's user name, if known
+func lookupUser(peer *Peer) {
+	if peer == nil {
+		return
+	}
+	if u, err := user.LookupId(strconv.Itoa(peer.UID)); err == nil {
+		peer.User = u.Username
+	}
+}
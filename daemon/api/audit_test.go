@@ -0,0 +1,133 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bufferCloser is an in-memory audit file
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error { return nil }
+
+func TestAuditLogRing(t *testing.T) {
+	audit := NewAuditLog(3, nil)
+	for i := 1; i <= 5; i++ {
+		audit.Add(AuditRecord{Command: fmt.Sprintf("CMD%d", i%2)})
+	}
+
+	// Only the last three records are kept, newest first
+	records := audit.Recent(0, "")
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	expected := []string{"CMD1", "CMD0", "CMD1"}
+	for i, record := range records {
+		if record.Command != expected[i] {
+			t.Errorf("Record %d: expected %s, got %s", i, expected[i], record.Command)
+		}
+	}
+
+	if records := audit.Recent(1, ""); len(records) != 1 {
+		t.Errorf("Expected limit to return 1 record, got %d", len(records))
+	}
+	if records := audit.Recent(0, "cmd0"); len(records) != 1 || records[0].Command != "CMD0" {
+		t.Errorf("Expected one CMD0 record, got %+v", records)
+	}
+}
+
+func TestAuditLogFile(t *testing.T) {
+	out := &bufferCloser{}
+	audit := NewAuditLog(10, out)
+	audit.Add(AuditRecord{Command: "STATUS", Success: true})
+
+	var record AuditRecord
+	if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", out.String(), err)
+	}
+	if record.Command != "STATUS" || !record.Success {
+		t.Errorf("Unexpected record: %+v", record)
+	}
+}
+
+func TestSummarizeParams(t *testing.T) {
+	summary := summarizeParams(map[string]interface{}{
+		"limit":     float64(10),
+		"bot_token": "xoxb-secret",
+		"url":       "https://example.com/" + strings.Repeat("a", 100),
+	})
+	if strings.Contains(summary, "xoxb") || !strings.Contains(summary, "bot_token=[redacted]") {
+		t.Errorf("Expected token to be redacted: %s", summary)
+	}
+	if !strings.HasPrefix(summary, "bot_token=") || !strings.Contains(summary, " limit=10 ") {
+		t.Errorf("Expected sorted key=value pairs: %s", summary)
+	}
+	if !strings.HasSuffix(summary, "...") {
+		t.Errorf("Expected long value to be truncated: %s", summary)
+	}
+
+	// CONFIG_SET values are redacted based on the setting's name
+	summary = summarizeParams(map[string]interface{}{"name": "notifications.slack.webhook_url", "value": "https://hooks"})
+	if strings.Contains(summary, "hooks\"") {
+		t.Errorf("Expected value of sensitive setting to be redacted: %s", summary)
+	}
+}
+
+func TestSocketServerAudit(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "socket-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	server, err := NewSocketServer(filepath.Join(tempDir, "test.sock"), SocketAccess{})
+	if err != nil {
+		t.Fatalf("Failed to create socket server: %v", err)
+	}
+	defer server.Stop()
+
+	audit := NewAuditLog(10, nil)
+	server.SetAuditLog(audit)
+	server.RegisterHandler("FAIL", func(params map[string]interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("failed on purpose")
+	})
+	go server.Start()
+	time.Sleep(100 * time.Millisecond)
+
+	client := NewSocketClient(filepath.Join(tempDir, "test.sock"))
+	client.SendCommand("FAIL", map[string]interface{}{"limit": 5})
+	client.SendCommand("MISSING", nil)
+
+	records := audit.Recent(0, "")
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 audit records, got %d", len(records))
+	}
+	if records[0].Command != "MISSING" || records[0].Success || records[0].Error != "unknown command" {
+		t.Errorf("Unexpected record for unknown command: %+v", records[0])
+	}
+	failed := records[1]
+	if failed.Command != "FAIL" || failed.Success || failed.Error != "failed on purpose" || failed.Params != "limit=5" {
+		t.Errorf("Unexpected record for failed command: %+v", failed)
+	}
+
+	if runtime.GOOS == "linux" {
+		if failed.Peer == nil {
+			t.Fatalf("Expected peer credentials on Linux")
+		}
+		if failed.Peer.UID != os.Getuid() || failed.Peer.PID != os.Getpid() {
+			t.Errorf("Expected peer uid %d pid %d, got %+v", os.Getuid(), os.Getpid(), failed.Peer)
+		}
+	}
+}
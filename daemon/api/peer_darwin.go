@@ -0,0 +1,41 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerCredentials returns the credentials of the process connected to a
+// Unix socket, using LOCAL_PEERCRED and LOCAL_PEERPID
+func peerCredentials(conn net.Conn) *Peer {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return nil
+	}
+
+	var cred *unix.Xucred
+	var pid int
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+		if credErr == nil {
+			pid, _ = unix.GetsockoptInt(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERPID)
+		}
+	}); err != nil || credErr != nil {
+		return nil
+	}
+
+	peer := &Peer{UID: int(cred.Uid), PID: pid}
+	if cred.Ngroups > 0 {
+		peer.GID = int(cred.Groups[0])
+	}
+	return peer
+}
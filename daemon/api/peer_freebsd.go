@@ -0,0 +1,37 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"net"
+
+	"golang.org/x/sys/unix"
+)
+
+// peerCredentials returns the credentials of the process connected to a
+// Unix socket, using LOCAL_PEERCRED. FreeBSD doesn't report the process.
+func peerCredentials(conn net.Conn) *Peer {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return nil
+	}
+
+	var cred *unix.Xucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = unix.GetsockoptXucred(int(fd), unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	}); err != nil || credErr != nil {
+		return nil
+	}
+
+	peer := &Peer{UID: int(cred.Uid)}
+	if cred.Ngroups > 0 {
+		peer.GID = int(cred.Groups[0])
+	}
+	return peer
+}
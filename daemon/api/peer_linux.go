@@ -0,0 +1,32 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCredentials returns the credentials of the process connected to a
+// Unix socket, using SO_PEERCRED
+func peerCredentials(conn net.Conn) *Peer {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return nil
+	}
+
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil || credErr != nil {
+		return nil
+	}
+
+	return &Peer{UID: int(cred.Uid), GID: int(cred.Gid), PID: int(cred.Pid)}
+}
@@ -0,0 +1,13 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux && !darwin && !freebsd
+
+package api
+
+import "net"
+
+// peerCredentials is not supported on this platform
+func peerCredentials(conn net.Conn) *Peer {
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+// roundTripBudget is how long a client may wait for a simple command. See
+// docs/testing/performance.md.
+const roundTripBudget = 10 * time.Millisecond
+
+func TestRoundTripBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping perf budget in short mode")
+	}
+	_, socketPath, cleanup := setupTestServer(t)
+	defer cleanup()
+	client := NewSocketClient(socketPath)
+	params := map[string]interface{}{"key": "value"}
+
+	var best time.Duration
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		if _, err := client.SendCommand("echo", params); err != nil {
+			t.Fatalf("Failed to send command: %v", err)
+		}
+		if took := time.Since(start); i == 0 || took < best {
+			best = took
+		}
+	}
+	if best > roundTripBudget {
+		t.Errorf("Round trip took %v, over the budget of %v", best, roundTripBudget)
+	}
+}
+
+func BenchmarkRoundTrip(b *testing.B) {
+	_, socketPath, cleanup := setupTestServer(b)
+	defer cleanup()
+	client := NewSocketClient(socketPath)
+	params := map[string]interface{}{"key": "value"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := client.SendCommand("echo", params); err != nil {
+			b.Fatalf("Failed to send command: %v", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+)
+
+// privilege is who may run a command
+type privilege int
+
+const (
+	adminPrivilege    privilege = iota // Admins, see AdminPolicy
+	readOnlyPrivilege                  // Anyone who can connect
+	rootPrivilege                      // Only root
+)
+
+// AdminPolicy decides who may run administrative commands. Root and the
+// daemon's own user always may; read-only commands are open to anyone who
+// can connect to the socket.
+type AdminPolicy struct {
+	Group string // Group whose members are admins, name or GID (empty for none)
+	Token string // Token that makes any client an admin (empty to disable)
+}
+
+// caller identifies who sent a request
+type caller struct {
+	peer   *Peer         // Local process, nil where the platform can't identify it
+	remote *RemoteClient // TLS client, nil for local connections
+}
+
+// adminAccess is a resolved AdminPolicy
+type adminAccess struct {
+	gid   int // -1 for no admin group
+	token string
+}
+
+// SetAdminPolicy sets who may run administrative commands
+func (s *SocketServer) SetAdminPolicy(policy AdminPolicy) error {
+	access := adminAccess{gid: -1, token: policy.Token}
+	if policy.Group != "" {
+		gid, err := LookupGroupID(policy.Group)
+		if err != nil {
+			return err
+		}
+		access.gid = gid
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.admins = access
+	return nil
+}
+
+// authorize checks that the client may run a command needing privilege.
+// For administrative commands where the platform can't identify the peer,
+// only a token is checked if one is set, and otherwise the socket's
+// permissions are relied on. Root commands need an identified root peer,
+// and clients with an allowlist are limited to its commands.
+func (s *SocketServer) authorize(from caller, request Request, needs privilege) error {
+	isRoot := from.remote == nil && from.peer != nil && from.peer.UID == 0
+	if needs == rootPrivilege && !isRoot {
+		return fmt.Errorf("permission denied: %s can only be run by root", request.Command)
+	}
+
+	// Clients with an allowlist may run only its commands
+	if list, ok := s.allowlistFor(from, request); ok {
+		if !list.allows(request.Command) {
+			return fmt.Errorf("permission denied: %s is not allowed for this client", request.Command)
+		}
+		return nil
+	}
+
+	if from.remote != nil {
+		return s.authorizeCertificate(from.remote, request, needs)
+	}
+
+	peer := from.peer
+	if needs == readOnlyPrivilege || needs == rootPrivilege {
+		return nil
+	}
+
+	s.mu.RLock()
+	access := s.admins
+	s.mu.RUnlock()
+
+	if access.token != "" && subtle.ConstantTimeCompare([]byte(request.Token), []byte(access.token)) == 1 {
+		return nil
+	}
+	if peer == nil {
+		if access.token == "" {
+			return nil
+		}
+	} else if peer.UID == 0 || peer.UID == os.Getuid() || inGroup(peer, access.gid) {
+		return nil
+	}
+	return fmt.Errorf("permission denied: %s is an administrative command", request.Command)
+}
+
+// inGroup reports whether the peer's user is a member of the group gid
+func inGroup(peer *Peer, gid int) bool {
+	if gid < 0 {
+		return false
+	}
+	if peer.GID == gid {
+		return true
+	}
+	u, err := user.LookupId(strconv.Itoa(peer.UID))
+	if err != nil {
+		return false
+	}
+	groups, err := u.GroupIds()
+	if err != nil {
+		return false
+	}
+	for _, group := range groups {
+		if group == strconv.Itoa(gid) {
+			return true
+		}
+	}
+	return false
+}
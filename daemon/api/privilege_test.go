@@ -0,0 +1,136 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	server, err := NewSocketServer(filepath.Join(t.TempDir(), "test.sock"), SocketAccess{})
+	if err != nil {
+		t.Fatalf("Failed to create socket server: %v", err)
+	}
+	defer server.Stop()
+
+	request := Request{Command: "CONFIG_SET"}
+	other := &Peer{UID: 54321, GID: 54321}
+
+	if err := server.authorize(caller{peer: other}, request, adminPrivilege); err == nil {
+		t.Error("Expected another user to be denied")
+	}
+	if err := server.authorize(caller{peer: &Peer{UID: os.Getuid(), GID: 54321}}, request, adminPrivilege); err != nil {
+		t.Errorf("Expected the daemon's user to be allowed, got %v", err)
+	}
+	if err := server.authorize(caller{peer: &Peer{UID: 0, GID: 0}}, request, adminPrivilege); err != nil {
+		t.Errorf("Expected root to be allowed, got %v", err)
+	}
+	if err := server.authorize(caller{}, request, adminPrivilege); err != nil {
+		t.Errorf("Expected an unidentified peer to rely on the socket permissions, got %v", err)
+	}
+
+	// Members of the admin group are allowed
+	if err := server.SetAdminPolicy(AdminPolicy{Group: strconv.Itoa(other.GID)}); err != nil {
+		t.Fatalf("SetAdminPolicy failed: %v", err)
+	}
+	if err := server.authorize(caller{peer: other}, request, adminPrivilege); err != nil {
+		t.Errorf("Expected a member of the admin group to be allowed, got %v", err)
+	}
+
+	// A token admits anyone who has it
+	if err := server.SetAdminPolicy(AdminPolicy{Token: "s3cret"}); err != nil {
+		t.Fatalf("SetAdminPolicy failed: %v", err)
+	}
+	if err := server.authorize(caller{peer: other}, request, adminPrivilege); err == nil {
+		t.Error("Expected another user without the token to be denied")
+	}
+	if err := server.authorize(caller{}, request, adminPrivilege); err == nil {
+		t.Error("Expected an unidentified peer without the token to be denied")
+	}
+	request.Token = "s3cret"
+	if err := server.authorize(caller{peer: other}, request, adminPrivilege); err != nil {
+		t.Errorf("Expected the token to be accepted, got %v", err)
+	}
+
+	// Root commands need root, whatever the token
+	if err := server.authorize(caller{peer: other}, request, rootPrivilege); err == nil {
+		t.Error("Expected a root command to be denied to another user")
+	}
+	if err := server.authorize(caller{peer: &Peer{UID: 0}}, request, rootPrivilege); err != nil {
+		t.Errorf("Expected root to run a root command, got %v", err)
+	}
+}
+
+func TestAllowlists(t *testing.T) {
+	server, err := NewSocketServer(filepath.Join(t.TempDir(), "test.sock"), SocketAccess{})
+	if err != nil {
+		t.Fatalf("Failed to create socket server: %v", err)
+	}
+	defer server.Stop()
+
+	agent := caller{peer: &Peer{UID: 54321, GID: 54321}}
+	self := caller{peer: &Peer{UID: os.Getuid(), GID: os.Getgid()}}
+	if err := server.SetAllowlists([]ClientAllowlist{
+		{User: "54321", Commands: []string{"STATUS", "CONFIG_SET"}},
+		{Token: "monitoring", Commands: []string{"STATUS"}},
+		{User: strconv.Itoa(os.Getuid()), Commands: []string{"*"}},
+	}); err != nil {
+		t.Fatalf("SetAllowlists failed: %v", err)
+	}
+
+	// An allowlist grants its commands and nothing else
+	if err := server.authorize(agent, Request{Command: "CONFIG_SET"}, adminPrivilege); err != nil {
+		t.Errorf("Expected an allowed admin command to succeed, got %v", err)
+	}
+	if err := server.authorize(agent, Request{Command: "HISTORY"}, readOnlyPrivilege); err == nil {
+		t.Error("Expected a read-only command missing from the allowlist to be denied")
+	}
+
+	// A token allowlist applies to whoever sends the token
+	if err := server.authorize(self, Request{Command: "STATUS", Token: "monitoring"}, readOnlyPrivilege); err != nil {
+		t.Errorf("Expected the token's command to succeed, got %v", err)
+	}
+	if err := server.authorize(self, Request{Command: "CONFIG_SET", Token: "monitoring"}, adminPrivilege); err == nil {
+		t.Error("Expected the token to limit the client")
+	}
+
+	// "*" doesn't give root commands to other users
+	if os.Getuid() != 0 {
+		if err := server.authorize(self, Request{Command: "CONFIG_GET_SECRETS"}, rootPrivilege); err == nil {
+			t.Error("Expected a root command to need root")
+		}
+	}
+
+	// Each allowlist identifies one client
+	if err := server.SetAllowlists([]ClientAllowlist{{User: "54321", Token: "x", Commands: []string{"STATUS"}}}); err == nil {
+		t.Error("Expected an allowlist with two identities to be rejected")
+	}
+}
+
+func TestReadOnlyCommands(t *testing.T) {
+	server, socketPath, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.RegisterReadOnlyHandler("peek", func(params map[string]interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	if _, needs, _ := server.handler("peek", caller{}); needs != readOnlyPrivilege {
+		t.Error("Expected peek to be read-only")
+	}
+	if _, needs, _ := server.handler("echo", caller{}); needs != adminPrivilege {
+		t.Error("Expected echo to be administrative")
+	}
+
+	// The daemon's own user is an admin without the token
+	server.SetAdminPolicy(AdminPolicy{Token: "s3cret"})
+	client := NewSocketClient(socketPath)
+	if _, err := client.SendCommand("peek", nil); err != nil {
+		t.Errorf("Expected read-only command to succeed, got %v", err)
+	}
+	if _, err := client.SendCommand("echo", nil); err != nil {
+		t.Errorf("Expected the daemon's user to run admin commands, got %v", err)
+	}
+}
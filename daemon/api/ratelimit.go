@@ -0,0 +1,142 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// CodeTooManyRequests is the response code of commands refused by the rate
+// limit, like HTTP 429
+const CodeTooManyRequests = 429
+
+// maxClientBuckets bounds the per-client limits kept before idle ones are
+// dropped
+const maxClientBuckets = 1024
+
+// RateLimit bounds how many commands the server handles per second, in
+// total and from each client. Each command has its own connection, so
+// clients are told apart by user, or by certificate for the TCP API.
+type RateLimit struct {
+	GlobalPerSecond float64 // Commands from all clients, 0 for no limit
+	ClientPerSecond float64 // Commands from one client, 0 for no limit
+	Burst           int     // Commands allowed at once above the rates
+}
+
+// bucket is a token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// take removes a token if one is available, refilling at rate up to burst.
+// Otherwise it returns how long until one is.
+func (b *bucket) take(now time.Time, rate float64, burst int) (bool, time.Duration) {
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// rateLimiter applies a RateLimit
+type rateLimiter struct {
+	limit   RateLimit
+	global  bucket
+	clients map[string]*bucket
+	lock    sync.Mutex
+}
+
+// SetRateLimit sets how many commands are handled per second
+func (s *SocketServer) SetRateLimit(limit RateLimit) {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	now := time.Now()
+	limiter := &rateLimiter{
+		limit:   limit,
+		global:  bucket{tokens: float64(limit.Burst), updated: now},
+		clients: make(map[string]*bucket),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limiter = limiter
+}
+
+// allow checks the rate limits for a command from client
+func (l *rateLimiter) allow(client string, now time.Time) error {
+	if l == nil {
+		return nil
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var charged *bucket
+	if l.limit.ClientPerSecond > 0 {
+		b, ok := l.clients[client]
+		if !ok {
+			l.prune(now)
+			b = &bucket{tokens: float64(l.limit.Burst), updated: now}
+			l.clients[client] = b
+		}
+		if ok, wait := b.take(now, l.limit.ClientPerSecond, l.limit.Burst); !ok {
+			return fmt.Errorf("rate limit exceeded for this client, retry in %v", wait.Round(time.Millisecond))
+		}
+		charged = b
+	}
+	if l.limit.GlobalPerSecond > 0 {
+		if ok, wait := l.global.take(now, l.limit.GlobalPerSecond, l.limit.Burst); !ok {
+			// Refused commands don't count against the client
+			if charged != nil {
+				charged.tokens++
+			}
+			return fmt.Errorf("rate limit exceeded, retry in %v", wait.Round(time.Millisecond))
+		}
+	}
+	return nil
+}
+
+// prune drops the limits of clients that have been idle long enough to
+// refill, once there are too many
+func (l *rateLimiter) prune(now time.Time) {
+	if len(l.clients) < maxClientBuckets {
+		return
+	}
+	refill := time.Duration(float64(l.limit.Burst) / l.limit.ClientPerSecond * float64(time.Second))
+	for client, b := range l.clients {
+		if now.Sub(b.updated) >= refill {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// name returns the key the caller is rate limited by
+func (c caller) name() string {
+	switch {
+	case c.remote != nil:
+		return "cert:" + c.remote.Certificate
+	case c.peer != nil:
+		return fmt.Sprintf("uid:%d", c.peer.UID)
+	}
+	return "unknown"
+}
+
+// user returns the client's user name where it is known, for recording who
+// did something
+func (c caller) user() string {
+	if c.remote == nil && c.peer != nil {
+		peer := *c.peer
+		lookupUser(&peer)
+		if peer.User != "" {
+			return peer.User
+		}
+	}
+	return c.name()
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test that commands over the rate limit are refused with a 429 code
+func TestRateLimit(t *testing.T) {
+	server, socketPath, cleanup := setupTestServer(t)
+	defer cleanup()
+	server.SetRateLimit(RateLimit{ClientPerSecond: 0.1, Burst: 2})
+
+	client := NewSocketClient(socketPath)
+	for i := 0; i < 2; i++ {
+		if _, err := client.SendCommand("echo", nil); err != nil {
+			t.Fatalf("Expected command %d within the burst to succeed, got %v", i+1, err)
+		}
+	}
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(Request{Command: "echo"}); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	var response Response
+	if err := json.NewDecoder(conn).Decode(&response); err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if response.Success || response.Code != CodeTooManyRequests || !strings.Contains(response.Error, "rate limit") {
+		t.Errorf("Expected a rate limited response, got %+v", response)
+	}
+}
+
+// Test the per-client and global limits and refilling
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := &rateLimiter{
+		limit:   RateLimit{GlobalPerSecond: 10, ClientPerSecond: 1, Burst: 3},
+		global:  bucket{tokens: 3, updated: now},
+		clients: make(map[string]*bucket),
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := limiter.allow("uid:1", now); err != nil {
+			t.Fatalf("Expected command %d to be allowed, got %v", i+1, err)
+		}
+	}
+	if err := limiter.allow("uid:2", now); err != nil {
+		t.Fatalf("Expected another client's command to be allowed, got %v", err)
+	}
+
+	// The global burst is spent, whoever asks
+	if err := limiter.allow("uid:3", now); err == nil {
+		t.Error("Expected the global limit to refuse a command")
+	}
+
+	// A client's tokens come back at its rate
+	later := now.Add(time.Second)
+	for i := 0; i < 2; i++ {
+		if err := limiter.allow("uid:1", later); err != nil {
+			t.Errorf("Expected a refilled client to be allowed, got %v", err)
+		}
+	}
+	if err := limiter.allow("uid:1", later); err == nil {
+		t.Error("Expected a client over its own limit to be refused")
+	}
+
+	// A nil limiter allows everything
+	var unlimited *rateLimiter
+	if err := unlimited.allow("uid:1", now); err != nil {
+		t.Errorf("Expected no limit, got %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"fmt"
+	"time"
+
+	snoozeerrors "github.com/scttfrdmn/cloudsnooze/pkg/errors"
+)
+
+// NewRelayServer creates a server with no listener, whose commands come
+// from elsewhere, such as an aggregation server, and are run with Dispatch
+func NewRelayServer() *SocketServer {
+	return &SocketServer{
+		handlers:   make(map[string]CommandHandler),
+		identified: make(map[string]IdentifiedHandler),
+		privileges: make(map[string]privilege),
+		admins:     adminAccess{gid: -1},
+	}
+}
+
+// Dispatch runs a command relayed on behalf of client. It is authorized
+// and audited as if client were the common name of a TLS client
+// certificate, so it may run read-only commands and those its allowlist
+// gives it.
+func (s *SocketServer) Dispatch(client string, request Request) (response Response) {
+	from := caller{remote: &RemoteClient{Certificate: client, Address: "relay"}}
+	defer snoozeerrors.Recover(func(err *snoozeerrors.CloudSnoozeError) {
+		logger().Error("Recovered from panic handling relayed command", "error", err, "stack", err.Stack)
+		response = Response{Success: false, Error: "Internal error"}
+	})
+
+	handler, needs, exists := s.handler(request.Command, from)
+	if !exists {
+		s.auditCommand(from, request, time.Now(), fmt.Errorf("unknown command"))
+		return Response{Success: false, Error: fmt.Sprintf("Unknown command: %s", request.Command)}
+	}
+	if err := s.authorize(from, request, needs); err != nil {
+		s.auditCommand(from, request, time.Now(), err)
+		return Response{Success: false, Error: err.Error()}
+	}
+
+	start := time.Now()
+	result, err := handler(request.Params)
+	s.auditCommand(from, request, start, err)
+	if err != nil {
+		return Response{Success: false, Error: err.Error()}
+	}
+	return Response{Success: true, Data: result}
+}
+
+// Query runs a read-only command for the daemon itself, such as STATUS for
+// a report, without auditing it
+func (s *SocketServer) Query(command string, params map[string]interface{}) (interface{}, error) {
+	handler, needs, exists := s.handler(command, caller{})
+	if !exists || needs != readOnlyPrivilege {
+		return nil, fmt.Errorf("unknown read-only command: %s", command)
+	}
+	return handler(params)
+}
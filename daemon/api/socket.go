@@ -4,24 +4,43 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"os"
-	"path/filepath"
+	"os/user"
+	"strconv"
 	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+	snoozeerrors "github.com/scttfrdmn/cloudsnooze/pkg/errors"
 )
 
 const (
-	// DefaultSocketPath is the default Unix socket path
-	DefaultSocketPath = "/var/run/snooze.sock"
+	// connectionTimeout bounds how long a client may take to send a request
+	// and read the response
+	connectionTimeout = 30 * time.Second
+
+	// drainTimeout bounds how long Stop waits for commands in progress
+	drainTimeout = 5 * time.Second
+
+	// DefaultSocketMode lets the owner and group use the socket
+	DefaultSocketMode os.FileMode = 0660
 )
 
+// logger returns the api component logger
+func logger() *slog.Logger {
+	return logging.Component("api")
+}
+
 // Request represents a command request sent to the daemon
 type Request struct {
 	Command string                 `json:"command"`
 	Params  map[string]interface{} `json:"params,omitempty"`
+	Token   string                 `json:"token,omitempty"` // Admin token, for clients that aren't admin users
 }
 
 // Response represents a response from the daemon
@@ -29,23 +48,88 @@ type Response struct {
 	Success bool        `json:"success"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Code    int         `json:"code,omitempty"` // Why the command failed, such as CodeTooManyRequests
 }
 
 // CommandHandler is a function that handles a command request
 type CommandHandler func(params map[string]interface{}) (interface{}, error)
 
-// SocketServer handles the API socket
+// IdentifiedHandler handles a command that records who sent it. client is
+// the local user's name, or "cert:" and the common name for TLS clients.
+type IdentifiedHandler func(client string, params map[string]interface{}) (interface{}, error)
+
+// SocketServer handles the API socket. Handlers may be registered while
+// it is serving. Once stopped it can't be started again.
 type SocketServer struct {
-	listener   net.Listener
-	socketPath string
-	handlers   map[string]CommandHandler
-	running    bool
-	mu         sync.RWMutex
+	listener     net.Listener
+	socketPath   string
+	handlers     map[string]CommandHandler
+	identified   map[string]IdentifiedHandler
+	privileges   map[string]privilege
+	admins       adminAccess
+	allowlists   []allowlist // Commands particular clients are limited to
+	limiter      *rateLimiter
+	audit        *AuditLog
+	running      bool
+	stopped      bool
+	active       sync.WaitGroup
+	mu           sync.RWMutex // Guards handlers, privileges, admins, allowlists, limiter, audit, running and stopped
+}
+
+// SocketAccess controls who may connect to the socket. The zero value keeps
+// the daemon's group with DefaultSocketMode.
+type SocketAccess struct {
+	Group string      // Group name or GID that owns the socket, empty for the daemon's group
+	Mode  os.FileMode // Permissions of the socket file, 0 for DefaultSocketMode
+}
+
+// ParseSocketMode parses octal permissions such as "0660"
+func ParseSocketMode(mode string) (os.FileMode, error) {
+	value, err := strconv.ParseUint(mode, 8, 32)
+	if err != nil || value > 0777 {
+		return 0, fmt.Errorf("invalid socket mode %q, expected octal permissions such as 0660", mode)
+	}
+	return os.FileMode(value), nil
+}
+
+// LookupGroupID returns the GID of a group name or numeric GID
+func LookupGroupID(group string) (int, error) {
+	if gid, err := strconv.Atoi(group); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(group)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %q: %v", group, err)
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// apply sets the socket file's group and permissions
+func (a SocketAccess) apply(path string) error {
+	if a.Group != "" {
+		gid, err := LookupGroupID(a.Group)
+		if err != nil {
+			return err
+		}
+		if err := os.Chown(path, -1, gid); err != nil {
+			return fmt.Errorf("failed to set socket group: %v", err)
+		}
+	}
+	mode := a.Mode
+	if mode == 0 {
+		mode = DefaultSocketMode
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		return fmt.Errorf("failed to set socket permissions: %v", err)
+	}
+	return nil
 }
 
 // SocketClient is a client for communicating with the socket server
 type SocketClient struct {
 	socketPath string
+	token      string
+	dial       func() (net.Conn, error) // Connects to the TCP API instead of the socket
 }
 
 // NewSocketClient creates a new socket client
@@ -55,116 +139,246 @@ func NewSocketClient(socketPath string) *SocketClient {
 	}
 }
 
-// NewSocketServer creates a new Unix socket server
-func NewSocketServer(socketPath string) (*SocketServer, error) {
-	// Create socket directory if it doesn't exist
-	dir := filepath.Dir(socketPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create socket directory: %v", err)
-	}
-
-	// Remove socket file if it already exists
-	if err := os.RemoveAll(socketPath); err != nil {
-		return nil, fmt.Errorf("failed to remove existing socket: %v", err)
-	}
-
-	// Create Unix socket listener
-	listener, err := net.Listen("unix", socketPath)
+// NewSocketServer creates a new Unix socket server whose socket file has
+// the group and permissions in access. A path starting with
+// AbstractSocketPrefix is a Linux abstract socket, which has no file or
+// permissions, and one starting with NamedPipePrefix is a Windows named
+// pipe that members of the access group may open.
+func NewSocketServer(socketPath string, access SocketAccess) (*SocketServer, error) {
+	listener, err := listen(socketPath, access)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create socket listener: %v", err)
-	}
-
-	// Set permissions on socket file
-	if err := os.Chmod(socketPath, 0660); err != nil {
-		closeErr := listener.Close()
-		if closeErr != nil {
-			return nil, fmt.Errorf("failed to set socket permissions: %v, and close listener: %v", err, closeErr)
-		}
-		return nil, fmt.Errorf("failed to set socket permissions: %v", err)
+		return nil, err
 	}
 
 	return &SocketServer{
 		listener:   listener,
 		socketPath: socketPath,
 		handlers:   make(map[string]CommandHandler),
-		mu:         sync.RWMutex{},
+		identified: make(map[string]IdentifiedHandler),
+		privileges: make(map[string]privilege),
+		admins:     adminAccess{gid: -1},
 	}, nil
 }
 
-// RegisterHandler registers a command handler
+// RegisterHandler registers an administrative command handler, which only
+// admins may run (see SetAdminPolicy)
 func (s *SocketServer) RegisterHandler(command string, handler CommandHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = handler
+	s.privileges[command] = adminPrivilege
+}
+
+// RegisterReadOnlyHandler registers a command handler that any user who
+// can connect to the socket may run
+func (s *SocketServer) RegisterReadOnlyHandler(command string, handler CommandHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.handlers[command] = handler
+	s.privileges[command] = readOnlyPrivilege
+}
+
+// RegisterRootHandler registers a command handler that only root may run,
+// such as one revealing secrets
+func (s *SocketServer) RegisterRootHandler(command string, handler CommandHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[command] = handler
+	s.privileges[command] = rootPrivilege
+}
+
+// RegisterIdentifiedHandler registers a command handler that any user who
+// can connect to the socket may run, and that is told who sent the request
+func (s *SocketServer) RegisterIdentifiedHandler(command string, handler IdentifiedHandler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identified[command] = handler
+	s.privileges[command] = readOnlyPrivilege
+}
+
+// handler returns the handler registered for command, bound to the client
+// that sent it, and the privilege it needs
+func (s *SocketServer) handler(command string, from caller) (CommandHandler, privilege, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if handler, exists := s.identified[command]; exists {
+		client := from.user()
+		return func(params map[string]interface{}) (interface{}, error) {
+			return handler(client, params)
+		}, s.privileges[command], true
+	}
+	handler, exists := s.handlers[command]
+	return handler, s.privileges[command], exists
+}
+
+// SetAuditLog records every command received in audit
+func (s *SocketServer) SetAuditLog(audit *AuditLog) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audit = audit
 }
 
 // Start starts the socket server
 func (s *SocketServer) Start() error {
+	return s.StartContext(context.Background())
+}
+
+// StartContext starts the socket server and stops it when ctx is cancelled.
+// It returns nil once the server is stopped.
+func (s *SocketServer) StartContext(ctx context.Context) error {
 	s.mu.Lock()
+	switch {
+	case s.stopped:
+		s.mu.Unlock()
+		return fmt.Errorf("socket server has been stopped")
+	case s.running:
+		s.mu.Unlock()
+		return fmt.Errorf("socket server is already running")
+	}
 	s.running = true
 	s.mu.Unlock()
 
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+			if err := s.Stop(); err != nil {
+				logger().Error("Failed to stop socket server", "error", err)
+			}
+		case <-stopped:
+		}
+	}()
+
 	for {
+		conn, err := s.listener.Accept()
+
+		// Count the connection while holding the lock, so Stop can't start
+		// waiting for connections in between
 		s.mu.RLock()
 		running := s.running
+		if running && err == nil {
+			s.active.Add(1)
+		}
 		s.mu.RUnlock()
 
 		if !running {
+			if err == nil {
+				conn.Close()
+			}
 			return nil
 		}
-
-		conn, err := s.listener.Accept()
 		if err != nil {
-			s.mu.RLock()
-			running = s.running
-			s.mu.RUnlock()
-
-			if !running {
-				return nil
-			}
+			s.mu.Lock()
+			s.running = false
+			s.mu.Unlock()
 			return fmt.Errorf("error accepting connection: %v", err)
 		}
 
 		// Handle connection in a goroutine
-		go s.handleConnection(conn)
+		go func() {
+			defer s.active.Done()
+			s.handleConnection(conn)
+		}()
 	}
 }
 
-// Stop stops the socket server
+// Stop stops the socket server, waiting briefly for commands in progress.
+// Stopping a server that is already stopped does nothing.
 func (s *SocketServer) Stop() error {
 	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return nil
+	}
+	s.stopped = true
 	s.running = false
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
 	s.mu.Unlock()
 
-	if s.listener != nil {
-		return s.listener.Close()
+	drained := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(drainTimeout):
+		logger().Warn("Commands still in progress at shutdown")
 	}
-	return nil
+	return err
 }
 
 // handleConnection processes a client connection
 func (s *SocketServer) handleConnection(conn net.Conn) {
 	defer func() {
 		if err := conn.Close(); err != nil {
-			log.Printf("Error closing connection: %v", err)
+			logger().Error("Failed to close connection", "error", err)
 		}
 	}()
 
+	// A bad request or a broken handler must not take down the daemon
+	defer snoozeerrors.Recover(func(err *snoozeerrors.CloudSnoozeError) {
+		logger().Error("Recovered from panic handling command", "error", err, "stack", err.Stack)
+		sendErrorResponse(conn, "Internal error")
+	})
+
+	// Don't let a stalled client hold up shutdown
+	if err := conn.SetDeadline(time.Now().Add(connectionTimeout)); err != nil {
+		logger().Debug("Failed to set connection deadline", "error", err)
+	}
+
+	// Identify the client, a local process or a TLS client
+	from := caller{peer: peerCredentials(conn)}
+	remote, err := remoteClient(conn)
+	if err != nil {
+		logger().Debug("TLS handshake failed", "remote", conn.RemoteAddr().String(), "error", err)
+		return
+	}
+	from.remote = remote
+
 	// Create a decoder for the incoming JSON
 	decoder := json.NewDecoder(conn)
 	var request Request
 	if err := decoder.Decode(&request); err != nil {
+		s.auditCommand(from, request, time.Now(), fmt.Errorf("failed to parse request"))
 		sendErrorResponse(conn, "Failed to parse request")
 		return
 	}
 
+	// Refuse clients sending commands too quickly before doing any work.
+	// These aren't audited so a flood can't fill the audit log.
+	s.mu.RLock()
+	limiter := s.limiter
+	s.mu.RUnlock()
+	if err := limiter.allow(from.name(), time.Now()); err != nil {
+		logger().Debug("Rate limited command", "client", from.name(), "command", request.Command, "error", err)
+		sendResponse(conn, Response{Success: false, Error: err.Error(), Code: CodeTooManyRequests})
+		return
+	}
+
 	// Find handler for the command
-	handler, exists := s.handlers[request.Command]
+	handler, needs, exists := s.handler(request.Command, from)
 	if !exists {
+		s.auditCommand(from, request, time.Now(), fmt.Errorf("unknown command"))
 		sendErrorResponse(conn, fmt.Sprintf("Unknown command: %s", request.Command))
 		return
 	}
 
+	// Only admins may run administrative commands
+	if err := s.authorize(from, request, needs); err != nil {
+		s.auditCommand(from, request, time.Now(), err)
+		sendErrorResponse(conn, err.Error())
+		return
+	}
+
 	// Execute handler
+	start := time.Now()
 	result, err := handler(request.Params)
+	s.auditCommand(from, request, start, err)
 	if err != nil {
 		sendErrorResponse(conn, err.Error())
 		return
@@ -183,30 +397,70 @@ func (s *SocketServer) handleConnection(conn net.Conn) {
 	}
 }
 
+// auditCommand records a command in the audit log, if there is one
+func (s *SocketServer) auditCommand(from caller, request Request, start time.Time, err error) {
+	s.mu.RLock()
+	audit := s.audit
+	s.mu.RUnlock()
+	if audit == nil {
+		return
+	}
+
+	record := AuditRecord{
+		Time:      start,
+		Command:   request.Command,
+		Params:    summarizeParams(request.Params),
+		Peer:      from.peer,
+		Remote:    from.remote,
+		Success:   err == nil,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	lookupUser(record.Peer)
+	audit.Add(record)
+}
+
 // sendErrorResponse sends an error response to the client
 func sendErrorResponse(conn net.Conn, errMsg string) {
-	response := Response{
+	sendResponse(conn, Response{
 		Success: false,
 		Error:   errMsg,
-	}
+	})
+}
 
+// sendResponse sends a failed response to the client
+func sendResponse(conn net.Conn, response Response) {
 	encoder := json.NewEncoder(conn)
 	if err := encoder.Encode(response); err != nil {
 		// We're already in an error state, so just log this
-		log.Printf("Error sending error response: %v", err)
+		logger().Error("Failed to send error response", "error", err)
 	}
 }
 
+// SetToken sets the admin token sent with every command, for clients that
+// don't run as an admin user
+func (c *SocketClient) SetToken(token string) {
+	c.token = token
+}
+
 // SendCommand sends a command to the daemon and returns the response
 func (c *SocketClient) SendCommand(command string, params map[string]interface{}) (interface{}, error) {
 	// Connect to socket
-	conn, err := net.Dial("unix", c.socketPath)
+	var conn net.Conn
+	var err error
+	if c.dial != nil {
+		conn, err = c.dial()
+	} else {
+		conn, err = dialSocket(c.socketPath)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to daemon: %v", err)
 	}
 	defer func() {
 		if err := conn.Close(); err != nil {
-			log.Printf("Error closing client connection: %v", err)
+			logger().Error("Failed to close client connection", "error", err)
 		}
 	}()
 	
@@ -214,6 +468,7 @@ func (c *SocketClient) SendCommand(command string, params map[string]interface{}
 	request := Request{
 		Command: command,
 		Params:  params,
+		Token:   c.token,
 	}
 	
 	// Send request
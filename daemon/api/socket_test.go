@@ -4,13 +4,17 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+	"os/user"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -29,7 +33,7 @@ func TestNewSocketServer(t *testing.T) {
 	socketPath := filepath.Join(tempDir, "test.sock")
 
 	// Create the server
-	server, err := NewSocketServer(socketPath)
+	server, err := NewSocketServer(socketPath, SocketAccess{})
 	if err != nil {
 		t.Fatalf("Failed to create socket server: %v", err)
 	}
@@ -50,6 +54,15 @@ func TestNewSocketServer(t *testing.T) {
 	}
 }
 
+// Test ParseSocketMode
+func TestParseSocketMode(t *testing.T) {
+	for mode, valid := range map[string]bool{"0660": true, "660": true, "0600": true, "0999": false, "1777": false, "rw": false} {
+		if _, err := ParseSocketMode(mode); (err == nil) != valid {
+			t.Errorf("ParseSocketMode(%q): expected valid=%v, got error %v", mode, valid, err)
+		}
+	}
+}
+
 // Test RegisterHandler and command handling
 func TestRegisterHandler(t *testing.T) {
 	// Create a temporary directory for the socket
@@ -62,7 +75,7 @@ func TestRegisterHandler(t *testing.T) {
 	socketPath := filepath.Join(tempDir, "test.sock")
 
 	// Create the server
-	server, err := NewSocketServer(socketPath)
+	server, err := NewSocketServer(socketPath, SocketAccess{})
 	if err != nil {
 		t.Fatalf("Failed to create socket server: %v", err)
 	}
@@ -90,7 +103,7 @@ func TestRegisterHandler(t *testing.T) {
 }
 
 // setupTestServer creates a test server and starts it
-func setupTestServer(t *testing.T) (*SocketServer, string, func()) {
+func setupTestServer(t testing.TB) (*SocketServer, string, func()) {
 	// Create a temporary directory for the socket
 	tempDir, err := os.MkdirTemp("", "socket-test")
 	if err != nil {
@@ -100,7 +113,7 @@ func setupTestServer(t *testing.T) (*SocketServer, string, func()) {
 	socketPath := filepath.Join(tempDir, "test.sock")
 
 	// Create the server
-	server, err := NewSocketServer(socketPath)
+	server, err := NewSocketServer(socketPath, SocketAccess{})
 	if err != nil {
 		os.RemoveAll(tempDir)
 		t.Fatalf("Failed to create socket server: %v", err)
@@ -184,6 +197,28 @@ func TestClientServerCommunication(t *testing.T) {
 	}
 }
 
+// Identified handlers are told who sent the request
+func TestIdentifiedHandler(t *testing.T) {
+	server, socketPath, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	server.RegisterIdentifiedHandler("whoami", func(client string, params map[string]interface{}) (interface{}, error) {
+		return client, nil
+	})
+
+	result, err := NewSocketClient(socketPath).SendCommand("whoami", nil)
+	if err != nil {
+		t.Fatalf("Failed to send command: %v", err)
+	}
+	client, _ := result.(string)
+	if u, err := user.Current(); err == nil && runtime.GOOS == "linux" && client != u.Username {
+		t.Errorf("Expected the client to be %s, got %q", u.Username, client)
+	}
+	if client == "" {
+		t.Error("Expected the client to be identified")
+	}
+}
+
 // Test the error handling for unknown commands
 func TestUnknownCommand(t *testing.T) {
 	_, socketPath, cleanup := setupTestServer(t)
@@ -246,7 +281,7 @@ func TestSocketPathCreation(t *testing.T) {
 	socketPath := filepath.Join(nestedDir, "test.sock")
 
 	// Create the server - this should create the directory path
-	server, err := NewSocketServer(socketPath)
+	server, err := NewSocketServer(socketPath, SocketAccess{})
 	if err != nil {
 		t.Fatalf("Failed to create socket server: %v", err)
 	}
@@ -424,6 +459,10 @@ func (m *mockConn) Write(b []byte) (int, error) {
 	return len(b), nil
 }
 
+func (m *mockConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
 func (m *mockConn) Close() error {
 	if m.closeErr {
 		return fmt.Errorf("mock close error")
@@ -440,7 +479,7 @@ func TestHandleConnectionWriteError(t *testing.T) {
 	defer os.RemoveAll(tempDir)
 
 	socketPath := filepath.Join(tempDir, "test.sock")
-	server, err := NewSocketServer(socketPath)
+	server, err := NewSocketServer(socketPath, SocketAccess{})
 	if err != nil {
 		t.Fatalf("Failed to create socket server: %v", err)
 	}
@@ -458,4 +497,156 @@ func TestHandleConnectionWriteError(t *testing.T) {
 	server.handleConnection(mock)
 
 	// No assertions needed - we're just testing that it doesn't panic
-}
\ No newline at end of file
+}
+// Test that cancelling the context stops the server
+func TestStartContextCancel(t *testing.T) {
+	tempDir := t.TempDir()
+	socketPath := filepath.Join(tempDir, "test.sock")
+
+	server, err := NewSocketServer(socketPath, SocketAccess{})
+	if err != nil {
+		t.Fatalf("Failed to create socket server: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.StartContext(ctx)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected server to stop cleanly, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Server didn't stop after the context was cancelled")
+	}
+
+	if _, err := net.Dial("unix", socketPath); err == nil {
+		t.Error("Expected connection to fail after the context was cancelled")
+	}
+}
+
+// Test that Stop can be called more than once and a stopped server can't restart
+func TestStopIdempotent(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		if err := server.Stop(); err != nil {
+			t.Errorf("Stop %d failed: %v", i+1, err)
+		}
+	}
+
+	if err := server.Start(); err == nil {
+		t.Error("Expected starting a stopped server to fail")
+	}
+}
+
+// Test that a running server can't be started again
+func TestStartTwice(t *testing.T) {
+	server, _, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	if err := server.Start(); err == nil {
+		t.Error("Expected starting a running server to fail")
+	}
+}
+
+// Test registering handlers while commands are being served
+func TestRegisterHandlerWhileServing(t *testing.T) {
+	server, socketPath, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			server.RegisterHandler(fmt.Sprintf("extra-%d", i), func(params map[string]interface{}) (interface{}, error) {
+				return "ok", nil
+			})
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		client := NewSocketClient(socketPath)
+		for i := 0; i < 20; i++ {
+			if _, err := client.SendCommand("echo", nil); err != nil {
+				t.Errorf("Command %d failed: %v", i, err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+
+	result, err := NewSocketClient(socketPath).SendCommand("extra-49", nil)
+	if err != nil || result != "ok" {
+		t.Errorf("Expected handler registered while serving to run, got %v, %v", result, err)
+	}
+}
+
+// Test that a panicking handler fails only its own command
+func TestHandlerPanic(t *testing.T) {
+	server, socketPath, cleanup := setupTestServer(t)
+	defer cleanup()
+
+	server.RegisterHandler("panic", func(params map[string]interface{}) (interface{}, error) {
+		var m map[string]int
+		m["boom"] = 1
+		return nil, nil
+	})
+
+	client := NewSocketClient(socketPath)
+	if _, err := client.SendCommand("panic", nil); err == nil || !strings.Contains(err.Error(), "Internal error") {
+		t.Errorf("Expected internal error, got %v", err)
+	}
+
+	// The server keeps serving
+	if _, err := client.SendCommand("echo", map[string]interface{}{"key": "value"}); err != nil {
+		t.Errorf("Expected server to keep working after a panic, got %v", err)
+	}
+}
+
+// Test running relayed commands without a listener
+func TestRelayServer(t *testing.T) {
+	server := NewRelayServer()
+	audit := NewAuditLog(10, nil)
+	server.SetAuditLog(audit)
+	server.RegisterReadOnlyHandler("status", func(params map[string]interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+	server.RegisterIdentifiedHandler("whoami", func(client string, params map[string]interface{}) (interface{}, error) {
+		return client, nil
+	})
+	server.RegisterHandler("shutdown", func(params map[string]interface{}) (interface{}, error) {
+		return nil, nil
+	})
+
+	if response := server.Dispatch("aggregator", Request{Command: "whoami"}); !response.Success || response.Data != "cert:aggregator" {
+		t.Errorf("Expected the relayed command to run as cert:aggregator, got %+v", response)
+	}
+	if response := server.Dispatch("aggregator", Request{Command: "shutdown"}); response.Success {
+		t.Error("Expected a relayed administrative command to be refused")
+	}
+	if response := server.Dispatch("aggregator", Request{Command: "missing"}); response.Success || !strings.Contains(response.Error, "Unknown command") {
+		t.Errorf("Expected an unknown command to fail, got %+v", response)
+	}
+	if records := audit.Recent(0, ""); len(records) != 3 {
+		t.Errorf("Expected 3 relayed commands to be audited, got %d", len(records))
+	}
+
+	// The daemon's own queries aren't audited, and can't run administrative commands
+	if result, err := server.Query("status", nil); err != nil || result != "ok" {
+		t.Errorf("Expected status to be queried, got %v and %v", result, err)
+	}
+	if _, err := server.Query("shutdown", nil); err == nil {
+		t.Error("Expected an administrative command not to be queried")
+	}
+	if records := audit.Recent(0, ""); len(records) != 3 {
+		t.Errorf("Expected queries not to be audited, got %d records", len(records))
+	}
+}
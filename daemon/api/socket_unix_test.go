@@ -0,0 +1,43 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+// Test the socket's group and permissions
+func TestSocketAccess(t *testing.T) {
+	tempDir := t.TempDir()
+	socketPath := filepath.Join(tempDir, "test.sock")
+	group := strconv.Itoa(os.Getgid())
+
+	server, err := NewSocketServer(socketPath, SocketAccess{Group: group, Mode: 0600})
+	if err != nil {
+		t.Fatalf("Failed to create socket server: %v", err)
+	}
+	defer server.Stop()
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("Socket file was not created: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("Expected mode 0600, got %#o", mode)
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && strconv.Itoa(int(stat.Gid)) != group {
+		t.Errorf("Expected group %s, got %d", group, stat.Gid)
+	}
+
+	// An unknown group is an error
+	if _, err := NewSocketServer(filepath.Join(tempDir, "other.sock"), SocketAccess{Group: "no-such-group-cloudsnooze"}); err == nil {
+		t.Error("Expected an unknown group to fail")
+	}
+}
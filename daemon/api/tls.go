@@ -0,0 +1,104 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+)
+
+// RemoteTLS defines the certificates of the TCP API listener
+type RemoteTLS struct {
+	CertFile     string // Server certificate
+	KeyFile      string // Server private key
+	ClientCAFile string // CA that client certificates must be signed by
+}
+
+// RemoteClient identifies a client of the TCP API
+type RemoteClient struct {
+	Certificate string `json:"certificate"` // Common name of the client certificate
+	Address     string `json:"address"`
+}
+
+// NewTLSServer creates a server for the API over TCP. Clients must present
+// a certificate signed by the client CA. Certificates not given commands
+// with SetAllowlists may only run read-only commands, and root commands
+// can't be run remotely.
+func NewTLSServer(addr string, remote RemoteTLS) (*SocketServer, error) {
+	cert, err := tls.LoadX509KeyPair(remote.CertFile, remote.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %v", err)
+	}
+	caData, err := os.ReadFile(remote.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("no certificates found in client CA %s", remote.ClientCAFile)
+	}
+
+	listener, err := tls.Listen("tcp", addr, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TLS listener: %v", err)
+	}
+
+	return &SocketServer{
+		listener:   listener,
+		handlers:   make(map[string]CommandHandler),
+		identified: make(map[string]IdentifiedHandler),
+		privileges: make(map[string]privilege),
+		admins:     adminAccess{gid: -1},
+	}, nil
+}
+
+// NewTLSClient creates a client of the TCP API at addr. config holds the
+// client certificate and the CA that signed the daemon's certificate.
+func NewTLSClient(addr string, config *tls.Config) *SocketClient {
+	return &SocketClient{
+		dial: func() (net.Conn, error) {
+			dialer := &net.Dialer{Timeout: connectionTimeout}
+			return tls.DialWithDialer(dialer, "tcp", addr, config)
+		},
+	}
+}
+
+// Addr returns the address the server listens on
+func (s *SocketServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// remoteClient completes the TLS handshake of conn and identifies the
+// client. It returns nil for connections that aren't TLS.
+func remoteClient(conn net.Conn) (*RemoteClient, error) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil, nil
+	}
+	if err := tlsConn.Handshake(); err != nil {
+		return nil, err
+	}
+	client := &RemoteClient{Address: conn.RemoteAddr().String()}
+	if certs := tlsConn.ConnectionState().PeerCertificates; len(certs) > 0 {
+		client.Certificate = certs[0].Subject.CommonName
+	}
+	return client, nil
+}
+
+// authorizeCertificate checks that a TLS client without an allowlist may
+// run a command
+func (s *SocketServer) authorizeCertificate(client *RemoteClient, request Request, needs privilege) error {
+	if needs == readOnlyPrivilege {
+		return nil
+	}
+	return fmt.Errorf("permission denied: certificate %q may not run %s", client.Certificate, request.Command)
+}
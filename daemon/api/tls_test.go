@@ -0,0 +1,152 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for TLS tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &testCA{cert: cert, key: key, pool: pool, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate for name, as PEM certificate and key
+func (ca *testCA) issue(t *testing.T, name string, serial int64) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to issue certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// client returns a TLS client presenting a certificate for name
+func (ca *testCA) client(t *testing.T, addr, name string, serial int64) *SocketClient {
+	certPEM, keyPEM := ca.issue(t, name, serial)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("Failed to load client certificate: %v", err)
+	}
+	return NewTLSClient(addr, &tls.Config{Certificates: []tls.Certificate{cert}, RootCAs: ca.pool})
+}
+
+func TestTLSServer(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certPEM, keyPEM := ca.issue(t, "daemon", 2)
+	remote := RemoteTLS{
+		CertFile:     filepath.Join(dir, "server.pem"),
+		KeyFile:      filepath.Join(dir, "server-key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+	}
+	os.WriteFile(remote.CertFile, certPEM, 0600)
+	os.WriteFile(remote.KeyFile, keyPEM, 0600)
+	os.WriteFile(remote.ClientCAFile, ca.pem, 0600)
+
+	server, err := NewTLSServer("127.0.0.1:0", remote)
+	if err != nil {
+		t.Fatalf("Failed to create TLS server: %v", err)
+	}
+	ok := func(params map[string]interface{}) (interface{}, error) { return "ok", nil }
+	server.RegisterReadOnlyHandler("STATUS", ok)
+	server.RegisterHandler("CANCEL_ALL", ok)
+	server.RegisterHandler("CONFIG_SET", ok)
+	server.RegisterRootHandler("SECRETS", ok)
+	server.SetAllowlists([]ClientAllowlist{{Certificate: "fleet", Commands: []string{"STATUS", "CANCEL_ALL"}}})
+	go server.Start()
+	defer server.Stop()
+	addr := server.Addr().String()
+
+	fleet := ca.client(t, addr, "fleet", 3)
+	if _, err := fleet.SendCommand("CANCEL_ALL", nil); err != nil {
+		t.Errorf("Expected an allowed command to succeed, got %v", err)
+	}
+	if _, err := fleet.SendCommand("CONFIG_SET", nil); err == nil {
+		t.Error("Expected a command the certificate isn't allowed to fail")
+	}
+
+	// Certificates that aren't listed may only run read-only commands
+	other := ca.client(t, addr, "monitoring", 4)
+	if _, err := other.SendCommand("STATUS", nil); err != nil {
+		t.Errorf("Expected a read-only command to succeed, got %v", err)
+	}
+	if _, err := other.SendCommand("CANCEL_ALL", nil); err == nil {
+		t.Error("Expected an admin command to fail for an unlisted certificate")
+	}
+
+	// Root commands can't be run remotely
+	server.SetAllowlists([]ClientAllowlist{{Certificate: "fleet", Commands: []string{"*"}}})
+	if _, err := fleet.SendCommand("CONFIG_SET", nil); err != nil {
+		t.Errorf("Expected * to allow admin commands, got %v", err)
+	}
+	if _, err := fleet.SendCommand("SECRETS", nil); err == nil {
+		t.Error("Expected a root command to fail remotely")
+	}
+
+	// Clients without a certificate from the CA can't connect
+	anonymous := NewTLSClient(addr, &tls.Config{RootCAs: ca.pool})
+	if _, err := anonymous.SendCommand("STATUS", nil); err == nil {
+		t.Error("Expected a client without a certificate to fail")
+	}
+	strangerPEM, strangerKey := newTestCA(t).issue(t, "fleet", 5)
+	strangerCert, _ := tls.X509KeyPair(strangerPEM, strangerKey)
+	stranger := NewTLSClient(addr, &tls.Config{Certificates: []tls.Certificate{strangerCert}, RootCAs: ca.pool})
+	if _, err := stranger.SendCommand("STATUS", nil); err == nil {
+		t.Error("Expected a certificate from another CA to fail")
+	}
+}
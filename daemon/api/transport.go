@@ -0,0 +1,87 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const (
+	// AbstractSocketPrefix starts the path of a Linux abstract namespace
+	// socket, which has no file
+	AbstractSocketPrefix = "@"
+
+	// NamedPipePrefix starts the path of a Windows named pipe
+	NamedPipePrefix = `\\.\pipe\`
+)
+
+// isAbstractSocket reports whether socketPath names an abstract socket
+func isAbstractSocket(socketPath string) bool {
+	return strings.HasPrefix(socketPath, AbstractSocketPrefix)
+}
+
+// isNamedPipe reports whether socketPath names a named pipe
+func isNamedPipe(socketPath string) bool {
+	return len(socketPath) >= len(NamedPipePrefix) && strings.EqualFold(socketPath[:len(NamedPipePrefix)], NamedPipePrefix)
+}
+
+// listen creates the listener for socketPath, chosen by its prefix: a
+// named pipe, an abstract socket, or a socket file with the group and
+// permissions in access
+func listen(socketPath string, access SocketAccess) (net.Listener, error) {
+	switch {
+	case isNamedPipe(socketPath):
+		return listenPipe(socketPath, access)
+	case isAbstractSocket(socketPath):
+		// Other platforms would create a file named "@..."
+		if runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("abstract sockets are only supported on Linux")
+		}
+		listener, err := net.Listen("unix", socketPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create socket listener: %v", err)
+		}
+		return listener, nil
+	}
+
+	// Create socket directory if it doesn't exist
+	dir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %v", err)
+	}
+
+	// Remove socket file if it already exists
+	if err := os.RemoveAll(socketPath); err != nil {
+		return nil, fmt.Errorf("failed to remove existing socket: %v", err)
+	}
+
+	// Create Unix socket listener
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create socket listener: %v", err)
+	}
+
+	// Set the owner group and permissions on socket file
+	if err := access.apply(socketPath); err != nil {
+		closeErr := listener.Close()
+		if closeErr != nil {
+			return nil, fmt.Errorf("%v, and close listener: %v", err, closeErr)
+		}
+		return nil, err
+	}
+	return listener, nil
+}
+
+// dialSocket connects to the daemon at socketPath
+func dialSocket(socketPath string) (net.Conn, error) {
+	if isNamedPipe(socketPath) {
+		return dialPipe(socketPath)
+	}
+	return net.Dial("unix", socketPath)
+}
@@ -0,0 +1,24 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package api
+
+import (
+	"fmt"
+	"net"
+)
+
+// DefaultSocketPath is the default Unix socket path
+const DefaultSocketPath = "/var/run/snooze.sock"
+
+// listenPipe isn't supported on this platform
+func listenPipe(path string, access SocketAccess) (net.Listener, error) {
+	return nil, fmt.Errorf("named pipes are only supported on Windows")
+}
+
+// dialPipe isn't supported on this platform
+func dialPipe(path string) (net.Conn, error) {
+	return nil, fmt.Errorf("named pipes are only supported on Windows")
+}
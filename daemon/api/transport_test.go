@@ -0,0 +1,65 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package api
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"testing"
+)
+
+// Test that socket paths select their transport by prefix
+func TestTransportPrefixes(t *testing.T) {
+	for path, pipe := range map[string]bool{
+		`\\.\pipe\snooze`:      true,
+		`\\.\PIPE\snooze`:      true,
+		`\\.\pip`:              false,
+		"/var/run/snooze.sock": false,
+		"@snooze":              false,
+	} {
+		if isNamedPipe(path) != pipe {
+			t.Errorf("Expected isNamedPipe(%q) to be %v", path, pipe)
+		}
+	}
+	if !isAbstractSocket("@snooze") || isAbstractSocket("/var/run/snooze.sock") {
+		t.Error("Expected only @ paths to be abstract sockets")
+	}
+
+	if runtime.GOOS != "windows" {
+		if _, err := NewSocketServer(`\\.\pipe\snooze-test`, SocketAccess{}); err == nil {
+			t.Error("Expected a named pipe to fail on this platform")
+		}
+	}
+}
+
+// Test serving commands over an abstract socket, which has no file
+func TestAbstractSocket(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("abstract sockets are Linux only")
+	}
+	socketPath := fmt.Sprintf("@cloudsnooze-test-%d", os.Getpid())
+
+	server, err := NewSocketServer(socketPath, SocketAccess{})
+	if err != nil {
+		t.Fatalf("Failed to create socket server: %v", err)
+	}
+	server.RegisterReadOnlyHandler("echo", func(params map[string]interface{}) (interface{}, error) {
+		return params, nil
+	})
+	go server.Start()
+	defer server.Stop()
+
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no socket file, got %v", err)
+	}
+
+	result, err := NewSocketClient(socketPath).SendCommand("echo", map[string]interface{}{"key": "value"})
+	if err != nil {
+		t.Fatalf("SendCommand failed: %v", err)
+	}
+	if data, ok := result.(map[string]interface{}); !ok || data["key"] != "value" {
+		t.Errorf("Expected the params back, got %v", result)
+	}
+}
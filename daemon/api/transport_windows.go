@@ -0,0 +1,293 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package api
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// DefaultSocketPath is the default named pipe path
+const DefaultSocketPath = `\\.\pipe\snooze`
+
+const (
+	// pipeBufferSize is the size of each pipe instance's buffers
+	pipeBufferSize = 64 * 1024
+
+	// pipeBusyTimeout bounds how long a client waits for a free instance
+	pipeBusyTimeout = 5 * time.Second
+)
+
+// pipeAddr is the address of a named pipe
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+// pipeListener accepts clients of a named pipe, one pipe instance per
+// client
+type pipeListener struct {
+	path      string
+	security  *windows.SecurityAttributes
+	next      windows.Handle // Instance waiting for the next client
+	accepting bool           // Accept is waiting for a client on next
+	closed    bool
+	mu        sync.Mutex // Guards next, accepting and closed
+}
+
+// listenPipe creates the named pipe at path, which SYSTEM, administrators,
+// the daemon's own user and members of the access group may open. The mode
+// doesn't apply to pipes.
+func listenPipe(path string, access SocketAccess) (net.Listener, error) {
+	security, err := pipeSecurity(access)
+	if err != nil {
+		return nil, err
+	}
+	l := &pipeListener{path: path, security: security}
+	if l.next, err = l.createInstance(true); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// pipeSecurity returns the security attributes giving access to the pipe
+func pipeSecurity(access SocketAccess) (*windows.SecurityAttributes, error) {
+	sddl := "D:P(A;;GA;;;SY)(A;;GA;;;BA)"
+	if tokenUser, err := windows.GetCurrentProcessToken().GetTokenUser(); err == nil {
+		sddl += "(A;;GA;;;" + tokenUser.User.Sid.String() + ")"
+	}
+	if access.Group != "" {
+		// Group IDs are SIDs on Windows
+		group, err := user.LookupGroup(access.Group)
+		if err != nil {
+			return nil, fmt.Errorf("unknown group %q: %v", access.Group, err)
+		}
+		sddl += "(A;;GRGW;;;" + group.Gid + ")"
+	}
+	descriptor, err := windows.SecurityDescriptorFromString(sddl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set pipe permissions: %v", err)
+	}
+	return &windows.SecurityAttributes{
+		Length:             uint32(unsafe.Sizeof(windows.SecurityAttributes{})),
+		SecurityDescriptor: descriptor,
+	}, nil
+}
+
+// createInstance creates a pipe instance for a client to connect to. The
+// first instance fails if another process is serving the pipe.
+func (l *pipeListener) createInstance(first bool) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(l.path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	flags := uint32(windows.PIPE_ACCESS_DUPLEX)
+	if first {
+		flags |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	mode := uint32(windows.PIPE_TYPE_BYTE | windows.PIPE_READMODE_BYTE | windows.PIPE_WAIT | windows.PIPE_REJECT_REMOTE_CLIENTS)
+	handle, err := windows.CreateNamedPipe(name, flags, mode, windows.PIPE_UNLIMITED_INSTANCES, pipeBufferSize, pipeBufferSize, 0, l.security)
+	if err != nil {
+		return windows.InvalidHandle, fmt.Errorf("failed to create pipe %s: %v", l.path, err)
+	}
+	return handle, nil
+}
+
+// Accept waits for a client to open the pipe
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	if l.next == windows.InvalidHandle {
+		next, err := l.createInstance(false)
+		if err != nil {
+			l.mu.Unlock()
+			return nil, err
+		}
+		l.next = next
+	}
+	handle := l.next
+	l.accepting = true
+	l.mu.Unlock()
+
+	err := windows.ConnectNamedPipe(handle, nil)
+	if err == windows.ERROR_PIPE_CONNECTED {
+		err = nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.accepting = false
+	if l.closed {
+		windows.CloseHandle(handle)
+		return nil, net.ErrClosed
+	}
+	l.next = windows.InvalidHandle
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, fmt.Errorf("failed to connect pipe client: %v", err)
+	}
+	return &pipeConn{handle: handle, path: l.path, server: true}, nil
+}
+
+// Close stops accepting clients, waking a waiting Accept by connecting to
+// the pipe
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	accepting, handle := l.accepting, l.next
+	l.next = windows.InvalidHandle
+	l.mu.Unlock()
+
+	if accepting {
+		if conn, err := dialPipe(l.path); err == nil {
+			conn.Close()
+		}
+		return nil
+	}
+	if handle != windows.InvalidHandle {
+		return windows.CloseHandle(handle)
+	}
+	return nil
+}
+
+// Addr returns the pipe path
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr(l.path)
+}
+
+// pipeConn is one end of a named pipe instance, using blocking I/O.
+// Deadlines cancel I/O in progress rather than bounding each call.
+type pipeConn struct {
+	handle  windows.Handle
+	path    string
+	server  bool
+	timer   *time.Timer
+	expired bool
+	closed  bool
+	mu      sync.Mutex // Guards timer, expired and closed
+}
+
+// dialPipe opens the named pipe at path, waiting while every instance is
+// busy
+func dialPipe(path string) (net.Conn, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(pipeBusyTimeout)
+	for {
+		handle, err := windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, 0, 0)
+		if err == nil {
+			return &pipeConn{handle: handle, path: path}, nil
+		}
+		if err != windows.ERROR_PIPE_BUSY || time.Now().After(deadline) {
+			return nil, fmt.Errorf("failed to open pipe %s: %v", path, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (c *pipeConn) Read(p []byte) (int, error) {
+	var n uint32
+	err := windows.ReadFile(c.handle, p, &n, nil)
+	return int(n), c.ioError(err)
+}
+
+func (c *pipeConn) Write(p []byte) (int, error) {
+	written := 0
+	for written < len(p) {
+		var n uint32
+		err := windows.WriteFile(c.handle, p[written:], &n, nil)
+		written += int(n)
+		if err != nil {
+			return written, c.ioError(err)
+		}
+	}
+	return written, nil
+}
+
+// ioError translates the errors of a closed pipe and an expired deadline
+func (c *pipeConn) ioError(err error) error {
+	if err == nil {
+		return nil
+	}
+	c.mu.Lock()
+	expired := c.expired
+	c.mu.Unlock()
+	switch {
+	case expired:
+		return os.ErrDeadlineExceeded
+	case err == windows.ERROR_BROKEN_PIPE, err == windows.ERROR_PIPE_NOT_CONNECTED:
+		return io.EOF
+	}
+	return err
+}
+
+// Close closes the pipe. Data already written stays readable by the other
+// end.
+func (c *pipeConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	return windows.CloseHandle(c.handle)
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return pipeAddr(c.path) }
+func (c *pipeConn) RemoteAddr() net.Addr { return pipeAddr(c.path) }
+
+// SetDeadline cancels reads and writes still in progress at t
+func (c *pipeConn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.expired = false
+	if !t.IsZero() {
+		c.timer = time.AfterFunc(time.Until(t), c.expire)
+	}
+	return nil
+}
+
+func (c *pipeConn) SetReadDeadline(t time.Time) error  { return c.SetDeadline(t) }
+func (c *pipeConn) SetWriteDeadline(t time.Time) error { return c.SetDeadline(t) }
+
+// expire cancels I/O in progress once the deadline passes, disconnecting
+// the client from the server's end
+func (c *pipeConn) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.expired = true
+	windows.CancelIoEx(c.handle, nil)
+	if c.server {
+		windows.DisconnectNamedPipe(c.handle)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/health"
+)
+
+const (
+	// stopHealthComponent is the health check updated by the stop breaker
+	stopHealthComponent = "cloud:stop"
+
+	// stopBreakerRecheck is the longest wait between permission checks while
+	// stops are suspended
+	stopBreakerRecheck = 15 * time.Minute
+)
+
+// StopBreakerStatus describes repeated stop failures for STATUS responses
+type StopBreakerStatus struct {
+	Open      bool       `json:"open"`
+	Failures  int        `json:"failures"`
+	LastError string     `json:"last_error,omitempty"`
+	OpenedAt  *time.Time `json:"opened_at,omitempty"`
+}
+
+// stopBreaker stops snoozing after the instance repeatedly failed to stop,
+// until the cloud permissions are verified again. Without it a daemon that
+// can't stop the instance would call the API on every check forever.
+type stopBreaker struct {
+	threshold int
+	failures  int
+	lastError string
+	openedAt  time.Time
+	lock      sync.Mutex
+}
+
+// newStopBreaker creates a breaker that opens after threshold consecutive
+// failures, or never if threshold is 0
+func newStopBreaker(threshold int) *stopBreaker {
+	return &stopBreaker{threshold: threshold}
+}
+
+// Open returns true if stops are suspended
+func (b *stopBreaker) Open() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return !b.openedAt.IsZero()
+}
+
+// Record counts the result of a stop. It returns true if the failure
+// opened the breaker.
+func (b *stopBreaker) Record(err error) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		b.lastError = ""
+		return false
+	}
+
+	b.failures++
+	b.lastError = err.Error()
+	if b.threshold <= 0 || b.failures < b.threshold || !b.openedAt.IsZero() {
+		return false
+	}
+
+	b.openedAt = time.Now()
+	health.Set(stopHealthComponent, health.Failed,
+		fmt.Sprintf("snoozing suspended after %d failed stops: %s", b.failures, b.lastError))
+	return true
+}
+
+// Reset closes the breaker once the instance is known to be able to stop
+// itself again. It returns true if the breaker was open.
+func (b *stopBreaker) Reset() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	wasOpen := !b.openedAt.IsZero()
+	b.failures = 0
+	b.lastError = ""
+	b.openedAt = time.Time{}
+	if wasOpen {
+		health.Set(stopHealthComponent, health.OK, "")
+	}
+	return wasOpen
+}
+
+// Status returns the failure count and whether stops are suspended
+func (b *stopBreaker) Status() StopBreakerStatus {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	status := StopBreakerStatus{
+		Failures:  b.failures,
+		LastError: b.lastError,
+	}
+	if !b.openedAt.IsZero() {
+		openedAt := b.openedAt
+		status.Open = true
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
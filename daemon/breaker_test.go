@@ -0,0 +1,88 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/health"
+)
+
+// stopHealth returns the state of the stop breaker's health check
+func stopHealth() health.State {
+	for _, check := range health.Default.Checks() {
+		if check.Component == stopHealthComponent {
+			return check.State
+		}
+	}
+	return ""
+}
+
+func TestStopBreakerTrips(t *testing.T) {
+	defer health.Default.Remove(stopHealthComponent)
+	breaker := newStopBreaker(3)
+	denied := errors.New("UnauthorizedOperation")
+
+	if breaker.Record(denied) || breaker.Record(denied) || breaker.Open() {
+		t.Fatal("Expected the breaker to stay closed below the threshold")
+	}
+	if !breaker.Record(denied) || !breaker.Open() {
+		t.Fatal("Expected the third failure to open the breaker")
+	}
+	if breaker.Record(denied) {
+		t.Error("Expected only the failure that opened the breaker to report it")
+	}
+	if stopHealth() != health.Failed {
+		t.Errorf("Expected the stop health check to fail, got %q", stopHealth())
+	}
+
+	status := breaker.Status()
+	if !status.Open || status.Failures != 4 || status.LastError != denied.Error() || status.OpenedAt == nil {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+func TestStopBreakerSuccessClearsFailures(t *testing.T) {
+	breaker := newStopBreaker(2)
+	breaker.Record(errors.New("throttled"))
+	breaker.Record(nil)
+	if breaker.Record(errors.New("throttled")) || breaker.Open() {
+		t.Error("Expected a successful stop to restart the count")
+	}
+	if status := breaker.Status(); status.Failures != 1 || status.Open {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+func TestStopBreakerReset(t *testing.T) {
+	defer health.Default.Remove(stopHealthComponent)
+	breaker := newStopBreaker(1)
+	if breaker.Reset() {
+		t.Error("Expected resetting a closed breaker to report it wasn't open")
+	}
+
+	breaker.Record(errors.New("denied"))
+	if !breaker.Reset() || breaker.Open() {
+		t.Fatal("Expected Reset to close the open breaker")
+	}
+	if stopHealth() != health.OK {
+		t.Errorf("Expected the stop health check to recover, got %q", stopHealth())
+	}
+	if status := breaker.Status(); status.Failures != 0 || status.LastError != "" || status.OpenedAt != nil {
+		t.Errorf("Expected a reset breaker to forget its failures, got %+v", status)
+	}
+}
+
+func TestStopBreakerDisabled(t *testing.T) {
+	breaker := newStopBreaker(0)
+	for i := 0; i < 10; i++ {
+		if breaker.Record(errors.New("denied")) {
+			t.Fatal("Expected a threshold of 0 to never open the breaker")
+		}
+	}
+	if breaker.Open() {
+		t.Error("Expected the breaker to stay closed")
+	}
+}
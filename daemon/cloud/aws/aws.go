@@ -5,25 +5,28 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
-	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
 	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+	"github.com/scttfrdmn/cloudsnooze/daemon/telemetry"
+	snoozeerrors "github.com/scttfrdmn/cloudsnooze/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const (
-	// How often to refresh the token
-	tokenTTL = "300"
-)
+// logger returns the provider component logger
+func logger() *slog.Logger {
+	return logging.Component("provider").With("provider", "aws")
+}
 
 // Config holds the AWS provider configuration
 type Config struct {
@@ -31,53 +34,142 @@ type Config struct {
 	EnableTags         bool
 	TaggingPrefix      string
 	DetailedTags       bool
+	MaxSnooze          time.Duration // Longest snooze, recorded in a wake_at tag for the restarter (0 for no limit)
 	TagPollingEnabled  bool
 	TagPollingInterval int
 	EnableCloudWatch   bool
 	CloudWatchLogGroup string
+	SNSTopicARN        string // Topic that snooze events are published to (empty to disable)
+	PublishMetrics     bool   // Publish idle state as CloudWatch custom metrics
+	MetricsNamespace   string // CloudWatch namespace of the metrics
+	Partition          string // Partition such as aws-cn or aws-us-gov (empty to detect)
+	EC2Endpoint        string // Custom EC2 API endpoint, e.g. for LocalStack (empty for the default)
+	MetadataEndpoint   string // Custom instance metadata endpoint (empty for the default)
+	AssumeRoleARN      string // Role assumed to stop and tag the instance, e.g. in another account (empty to use the instance's credentials)
+	ExternalID         string // External ID required by the assumed role's trust policy
+	Hibernate          bool   // Hibernate instead of stopping, if the instance supports it
+	WarmPool           bool   // Return the instance to its Auto Scaling group's warm pool instead of stopping it, if the group allows
+	PricingCachePath   string // File on-demand prices are cached in (empty to not cache)
+	DeregisterTargets  bool          // Leave load balancer target groups before stopping
+	TargetGroupARNs    []string      // Target groups to leave (empty to find them)
+	DrainTimeout       time.Duration // Longest wait for connection draining
+	RetryAttempts      int           // Tries of stop and tag calls that hit throttling or network errors (0 for the default)
+	DNSZoneID          string        // Route 53 hosted zone of a record that follows the instance (empty to disable)
+	DNSRecordName      string        // A record pointed at the instance when it starts
+	DNSParkedIP        string        // Address the record points at while stopped (empty to delete the record)
+	DNSTTL             int           // TTL of the record in seconds (0 for the default)
+	DNSPrivateIP       bool          // Point the record at the private address instead of the public one
+	StateTable         string        // DynamoDB table the daemon's state is recorded in (empty to disable)
+	StateTTL           time.Duration // How long a row outlives its last update, via the table's TTL (0 to keep rows)
 }
 
+// describeTTL is how long the launch time and tags from the EC2 API are reused
+const describeTTL = 5 * time.Minute
+
 // AWSProvider is an implementation of CloudProvider for AWS
 type AWSProvider struct {
 	config     Config
+	awsConfig  *aws.Config
+	configLock sync.Mutex
 	client     *ec2.Client
+	snsClient  snsAPI
+	metadata   *metadataClient
+	cwClient   cloudWatchAPI
+	stsClient  stsAPI
+	iamClient  iamAPI
+	pricingClient pricingAPI
+	elbClient  elbAPI
+	leftGroups map[string][]elbtypes.TargetDescription // Target groups the last stop left, rejoined if it fails
+	dnsParked  bool // Whether the last stop parked the DNS record
+	route53Client route53API
+	dynamoDBClient dynamoDBAPI
+	autoscalingClient autoscalingAPI
+	warmPoolGroup string // Auto Scaling group whose warm pool the instance returns to
+	tagClient  tagAPI
+	fleetClient fleetAPI
+	controllerClient controllerAPI
+	metricReader metricReaderAPI
+	tagCache   tagCache
+	drainPoll  time.Duration
+	retryDelay time.Duration
+	metricsFailing bool
+	ctx        context.Context
 	tagPoller  *time.Ticker
 	stopTagPoll chan struct{}
+	stopOnce   sync.Once
 	instanceID string
 	region     string
+	instancePartition string
 	instanceType string
+	stopAction common.StopActionStatus
+	launchTime time.Time
+	tags       map[string]string
+	described  time.Time
 	lock       sync.RWMutex
 }
 
 // NewProvider creates a new AWS provider instance
 func NewProvider(config Config) *AWSProvider {
+	metadata := defaultMetadata
+	if config.MetadataEndpoint != "" {
+		metadata = newMetadataClient(config.MetadataEndpoint)
+	}
 	return &AWSProvider{
 		config:     config,
+		metadata:   metadata,
+		drainPoll:  drainPollInterval,
+		ctx:        context.Background(),
 		stopTagPoll: make(chan struct{}),
 	}
 }
 
 // Initialize sets up the AWS provider
 func (p *AWSProvider) Initialize() error {
-	// Load default AWS configuration
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(p.config.Region))
-	if err != nil {
-		return fmt.Errorf("error loading AWS config: %v", err)
+	// Create the EC2 client, which is shared by every operation
+	if _, err := p.getEC2Client(); err != nil {
+		return err
 	}
 
-	// Create EC2 client
-	p.client = ec2.NewFromConfig(cfg)
+	// Create SNS client if events are published
+	if p.config.SNSTopicARN != "" {
+		if _, err := p.getSNSClient(); err != nil {
+			return err
+		}
+	}
 
 	// Get instance ID and region info
 	if err := p.loadInstanceInfo(); err != nil {
 		return fmt.Errorf("error loading instance info: %v", err)
 	}
 
+	// Point the record back at the instance, which may have a new address
+	if p.dnsEnabled() {
+		if err := p.restoreDNS(p.context()); err != nil {
+			logger().Warn("Failed to update DNS record", "record", p.config.DNSRecordName, "error", err)
+		}
+	}
+
+	// Find out early whether hibernation will work, instead of at stop time
+	if p.config.Hibernate {
+		p.setStopAction(p.checkHibernation(p.context(), p.client, p.instanceID))
+	}
+	if p.config.WarmPool {
+		if client, err := p.getAutoscalingClient(); err != nil {
+			p.setStopAction(common.StopActionStatus{
+				Requested: "warm_pool",
+				Effective: "stop",
+				Reason:    fmt.Sprintf("couldn't create Auto Scaling client: %v", err),
+			})
+		} else {
+			p.setStopAction(p.checkWarmPool(p.context(), client, p.instanceID))
+		}
+	}
+
 	// Start tag polling if enabled
 	if p.config.TagPollingEnabled && p.config.TagPollingInterval > 0 {
 		interval := time.Duration(p.config.TagPollingInterval) * time.Second
 		p.tagPoller = time.NewTicker(interval)
-		go p.pollTags()
+		go p.pollTags(p.tagPoller)
 	}
 
 	return nil
@@ -85,6 +177,32 @@ func (p *AWSProvider) Initialize() error {
 
 // StopInstance stops the EC2 instance
 func (p *AWSProvider) StopInstance(reason string, metrics common.SystemMetrics) error {
+	return p.StopInstanceContext(p.context(), reason, metrics)
+}
+
+// SetContext ties the provider's API calls and tag polling to ctx, so
+// cancelling it aborts calls in progress and stops polling
+func (p *AWSProvider) SetContext(ctx context.Context) {
+	p.lock.Lock()
+	p.ctx = ctx
+	p.lock.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		p.StopTagPolling()
+		p.flushTags()
+	}()
+}
+
+// context returns the context API calls are made with
+func (p *AWSProvider) context() context.Context {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.ctx
+}
+
+// StopInstanceContext stops the EC2 instance, tracing the tag and stop calls
+func (p *AWSProvider) StopInstanceContext(ctx context.Context, reason string, metrics common.SystemMetrics) error {
 	// Get the instance ID
 	instanceID, err := p.getInstanceID()
 	if err != nil {
@@ -93,110 +211,132 @@ func (p *AWSProvider) StopInstance(reason string, metrics common.SystemMetrics)
 
 	// Apply tags if enabled
 	if p.config.EnableTags {
+		tagCtx, span := telemetry.Tracer().Start(ctx, "tag_instance",
+			trace.WithAttributes(attribute.String("instance.id", instanceID)))
+
 		// Create basic tags
-		tags := []types.Tag{
-			{
-				Key:   aws.String(fmt.Sprintf("%s:stopped_at", p.config.TaggingPrefix)),
-				Value: aws.String(time.Now().Format(time.RFC3339)),
-			},
-			{
-				Key:   aws.String(fmt.Sprintf("%s:reason", p.config.TaggingPrefix)),
-				Value: aws.String(reason),
-			},
+		now := time.Now()
+		tags := map[string]string{
+			fmt.Sprintf("%s:stopped_at", p.config.TaggingPrefix): now.Format(time.RFC3339),
+			fmt.Sprintf("%s:reason", p.config.TaggingPrefix):     reason,
+		}
+
+		// Record when the restarter should start the instance again
+		if p.config.MaxSnooze > 0 {
+			tags[fmt.Sprintf("%s:wake_at", p.config.TaggingPrefix)] = now.Add(p.config.MaxSnooze).Format(time.RFC3339)
 		}
 
 		// Add detailed metrics tags if enabled
 		if p.config.DetailedTags {
-			tags = append(tags, 
-				types.Tag{
-					Key:   aws.String(fmt.Sprintf("%s:cpu_percent", p.config.TaggingPrefix)),
-					Value: aws.String(fmt.Sprintf("%.2f", metrics.CPUUsage)),
-				},
-				types.Tag{
-					Key:   aws.String(fmt.Sprintf("%s:memory_percent", p.config.TaggingPrefix)),
-					Value: aws.String(fmt.Sprintf("%.2f", metrics.MemoryUsage)),
-				},
-				types.Tag{
-					Key:   aws.String(fmt.Sprintf("%s:idle_time_mins", p.config.TaggingPrefix)),
-					Value: aws.String(fmt.Sprintf("%.1f", float64(metrics.IdleTime)/60.0)), // Convert from seconds to minutes
-				},
-			)
+			tags[fmt.Sprintf("%s:cpu_percent", p.config.TaggingPrefix)] = fmt.Sprintf("%.2f", metrics.CPUUsage)
+			tags[fmt.Sprintf("%s:memory_percent", p.config.TaggingPrefix)] = fmt.Sprintf("%.2f", metrics.MemoryUsage)
+			tags[fmt.Sprintf("%s:idle_time_mins", p.config.TaggingPrefix)] = fmt.Sprintf("%.1f", float64(metrics.IdleTime)/60.0) // Convert from seconds to minutes
 		}
 
-		// Apply the tags
-		_, err = p.client.CreateTags(context.TODO(), &ec2.CreateTagsInput{
-			Resources: []string{instanceID},
-			Tags:      tags,
-		})
+		// Apply the tags, along with any still queued, in one call
+		err = p.writeTags(tagCtx, tags)
+		telemetry.EndSpan(span, err)
+		if err != nil && p.config.MaxSnooze > 0 {
+			// Without its wake time the restarter would leave the instance stopped
+			return fmt.Errorf("error recording wake time: %v", err)
+		}
 		if err != nil {
 			// Log the error but don't fail
-			fmt.Printf("Warning: Failed to apply tags: %v\n", err)
+			logger().Warn("Failed to apply tags", "error", err)
 		}
 	}
 
-	// Stop the instance
-	_, err = p.client.StopInstances(context.TODO(), &ec2.StopInstancesInput{
-		InstanceIds: []string{instanceID},
-	})
-	return err
-}
-
-// VerifyPermissions checks if the current AWS credentials have the required permissions
-func (p *AWSProvider) VerifyPermissions() (bool, error) {
-	// Load AWS configuration
-	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(p.config.Region))
-	if err != nil {
-		return false, fmt.Errorf("error loading AWS config: %v", err)
+	// Stop load balancers from sending traffic to the instance
+	if p.config.DeregisterTargets {
+		drainCtx, span := telemetry.Tracer().Start(ctx, "deregister_targets",
+			trace.WithAttributes(attribute.String("instance.id", instanceID)))
+		err := p.deregisterTargets(drainCtx, instanceID)
+		telemetry.EndSpan(span, err)
+		if err != nil {
+			// Stopping matters more than a clean drain
+			logger().Warn("Failed to leave load balancer target groups", "error", err)
+		}
 	}
 
-	// Create EC2 client
-	client := ec2.NewFromConfig(cfg)
-
-	// Check if we can describe instances
-	_, err = client.DescribeInstances(context.TODO(), &ec2.DescribeInstancesInput{
-		MaxResults: aws.Int32(5),
-	})
-	if err != nil {
-		return false, fmt.Errorf("error checking EC2 permissions: %v", err)
+	// Tell clients the instance is going away, instead of letting them time out
+	if p.dnsEnabled() {
+		dnsCtx, span := telemetry.Tracer().Start(ctx, "park_dns",
+			trace.WithAttributes(attribute.String("dns.record", p.config.DNSRecordName)))
+		err := p.parkDNS(dnsCtx)
+		telemetry.EndSpan(span, err)
+		if err == nil {
+			p.lock.Lock()
+			p.dnsParked = true
+			p.lock.Unlock()
+		} else {
+			// Stopping matters more than the record
+			logger().Warn("Failed to update DNS record", "record", p.config.DNSRecordName, "error", err)
+		}
 	}
 
-	// If tags are enabled, verify tag permissions
-	if p.config.EnableTags {
-		instanceID, err := p.getInstanceID()
-		if err != nil {
-			return false, fmt.Errorf("error getting instance ID: %v", err)
+	// Stop the instance
+	stopCtx, span := telemetry.Tracer().Start(ctx, "stop_instance",
+		trace.WithAttributes(attribute.String("instance.id", instanceID)))
+	if p.shouldUseWarmPool() {
+		span.SetAttributes(attribute.Bool("warm_pool", true))
+		err = p.returnToWarmPool(stopCtx, instanceID)
+		if err == nil || ctx.Err() != nil {
+			telemetry.EndSpan(span, err)
+			return err
 		}
-
-		// Try to add a test tag
-		_, err = client.CreateTags(context.TODO(), &ec2.CreateTagsInput{
-			Resources: []string{instanceID},
-			Tags: []types.Tag{
-				{
-					Key:   aws.String("cloudsnooze:test"),
-					Value: aws.String("permission-check"),
-				},
-			},
+		logger().Warn("Failed to return instance to warm pool, stopping instead", "error", err)
+		p.setStopAction(common.StopActionStatus{
+			Requested: "warm_pool",
+			Effective: "stop",
+			Reason:    fmt.Sprintf("returning to warm pool failed: %v", err),
 		})
-		if err != nil {
-			return false, fmt.Errorf("error checking tag permissions: %v", err)
-		}
-
-		// Try to remove the test tag
-		_, err = client.DeleteTags(context.TODO(), &ec2.DeleteTagsInput{
-			Resources: []string{instanceID},
-			Tags: []types.Tag{
-				{
-					Key:   aws.String("cloudsnooze:test"),
-					Value: aws.String("permission-check"),
-				},
-			},
+	}
+	hibernate := p.shouldHibernate()
+	span.SetAttributes(attribute.Bool("hibernate", hibernate))
+	err = p.retry(stopCtx, "error stopping instance", func(ctx context.Context) error {
+		_, err := p.client.StopInstances(ctx, &ec2.StopInstancesInput{
+			InstanceIds: []string{instanceID},
+			Hibernate:   aws.Bool(hibernate),
+		})
+		return err
+	})
+	if err != nil && hibernate && ctx.Err() == nil {
+		logger().Warn("Failed to hibernate instance, stopping instead", "error", err)
+		p.setStopAction(common.StopActionStatus{
+			Requested: "hibernate",
+			Effective: "stop",
+			Reason:    fmt.Sprintf("hibernate failed: %v", err),
+		})
+		err = p.retry(stopCtx, "error stopping instance", func(ctx context.Context) error {
+			_, err := p.client.StopInstances(ctx, &ec2.StopInstancesInput{
+				InstanceIds: []string{instanceID},
+			})
+			return err
 		})
-		if err != nil {
-			return false, fmt.Errorf("error checking tag delete permissions: %v", err)
-		}
 	}
+	telemetry.EndSpan(span, err)
+	return err
+}
 
-	return true, nil
+// RollbackStop undoes what a failed stop did before stopping the instance,
+// which keeps running: it rejoins the load balancer target groups it left
+// and points the DNS record back at the instance
+func (p *AWSProvider) RollbackStop(ctx context.Context) error {
+	var errs []error
+	if err := p.rejoinTargets(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	p.lock.Lock()
+	parked := p.dnsParked
+	p.dnsParked = false
+	p.lock.Unlock()
+	if parked {
+		if err := p.restoreDNS(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error restoring DNS record: %v", err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // GetInstanceInfo returns information about the current instance
@@ -208,49 +348,80 @@ func (p *AWSProvider) GetInstanceInfo() (*common.InstanceInfo, error) {
 
 	// Check if we already have the instance type
 	p.lock.RLock()
-	if p.instanceType != "" {
-		info := &common.InstanceInfo{
-			ID:       instanceID,
-			Type:     p.instanceType,
-			Region:   p.region,
-			Provider: "aws",
-		}
-		p.lock.RUnlock()
-		return info, nil
-	}
+	instanceType, region := p.instanceType, p.region
 	p.lock.RUnlock()
 
-	// Get the instance type from the metadata service
-	instanceType, err := getMetadata("instance-type")
-	if err != nil {
-		return nil, fmt.Errorf("error getting instance type: %v", err)
-	}
+	if instanceType == "" {
+		// Get the instance type from the metadata service
+		instanceType, err = p.metadata.get("instance-type")
+		if err != nil {
+			return nil, fmt.Errorf("error getting instance type: %v", err)
+		}
 
-	// Get region from the metadata service if not already set
-	region := p.config.Region
-	if region == "" {
-		// Try to get region from instance metadata
-		az, err := getMetadata("placement/availability-zone")
-		if err == nil {
-			// Convert AZ to region by removing the last character (e.g., us-west-2a -> us-west-2)
-			if len(az) > 1 {
-				region = az[:len(az)-1]
+		// Get region from the metadata service if not already set
+		region = p.config.Region
+		if region == "" {
+			// Try to get region from instance metadata
+			if r, err := p.metadataRegion(); err == nil {
+				region = r
 			}
 		}
-	}
 
-	// Store the values
-	p.lock.Lock()
-	p.instanceType = instanceType
-	p.region = region
-	p.lock.Unlock()
+		// Store the values
+		p.lock.Lock()
+		p.instanceType = instanceType
+		p.region = region
+		p.lock.Unlock()
+	}
 
-	return &common.InstanceInfo{
+	info := &common.InstanceInfo{
 		ID:       instanceID,
 		Type:     instanceType,
 		Region:   region,
 		Provider: "aws",
-	}, nil
+	}
+
+	// Add the launch time and tags, which only the EC2 API knows
+	p.lock.RLock()
+	launchTime, tags, described := p.launchTime, p.tags, p.described
+	p.lock.RUnlock()
+	if p.client != nil && time.Since(described) > describeTTL {
+		launchTime, tags, err = describeInstance(p.context(), p.client, instanceID)
+		if err != nil {
+			logger().Debug("Failed to describe instance", "error", err)
+		} else {
+			p.lock.Lock()
+			p.launchTime, p.tags, p.described = launchTime, tags, time.Now()
+			p.lock.Unlock()
+			p.tagCache.store(tags, time.Now())
+		}
+	}
+	if !launchTime.IsZero() {
+		info.LaunchTime = launchTime.Format(time.RFC3339)
+	}
+	info.Tags = tags
+
+	return info, nil
+}
+
+// describeInstance looks up the launch time and tags of the instance
+func describeInstance(ctx context.Context, client ec2DescribeAPI, instanceID string) (time.Time, map[string]string, error) {
+	output, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("error describing instance: %v", err)
+	}
+	if len(output.Reservations) == 0 || len(output.Reservations[0].Instances) == 0 {
+		return time.Time{}, nil, fmt.Errorf("instance %s not found", instanceID)
+	}
+	instance := output.Reservations[0].Instances[0]
+
+	tags := make(map[string]string, len(instance.Tags))
+	for _, tag := range instance.Tags {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return aws.ToTime(instance.LaunchTime), tags, nil
 }
 
 // getInstanceID returns the EC2 instance ID, caching the result
@@ -265,7 +436,7 @@ func (p *AWSProvider) getInstanceID() (string, error) {
 	p.lock.RUnlock()
 
 	// Get instance ID from metadata service
-	instanceID, err := getMetadata("instance-id")
+	instanceID, err := p.metadata.get("instance-id")
 	if err != nil {
 		return "", fmt.Errorf("error getting instance ID: %v", err)
 	}
@@ -281,220 +452,104 @@ func (p *AWSProvider) getInstanceID() (string, error) {
 // loadInstanceInfo loads instance information from the AWS metadata service
 func (p *AWSProvider) loadInstanceInfo() error {
 	// Get instance ID
-	instanceID, err := getMetadata("instance-id")
+	instanceID, err := p.metadata.get("instance-id")
 	if err != nil {
 		return fmt.Errorf("error getting instance ID: %v", err)
 	}
 
 	// Get instance type
-	instanceType, err := getMetadata("instance-type")
+	instanceType, err := p.metadata.get("instance-type")
 	if err != nil {
 		return fmt.Errorf("error getting instance type: %v", err)
 	}
 
-	// Get availability zone and derive region
-	az, err := getMetadata("placement/availability-zone")
+	// Get region
+	region, err := p.metadataRegion()
 	if err != nil {
-		return fmt.Errorf("error getting availability zone: %v", err)
+		return err
 	}
 
-	// Convert AZ to region by removing the last character (e.g., us-west-2a -> us-west-2)
-	var region string
-	if len(az) > 1 {
-		region = az[:len(az)-1]
-	}
+	// The partition is only reported by newer metadata versions
+	partition, _ := p.metadata.get("services/partition")
 
 	// Store the values
 	p.lock.Lock()
 	p.instanceID = instanceID
 	p.instanceType = instanceType
 	p.region = region
+	p.instancePartition = partition
 	p.lock.Unlock()
 
 	return nil
 }
 
-// getIMDSToken gets a token for IMDSv2
-func getIMDSToken() (string, error) {
-	// Create a request to get the token
-	req, err := http.NewRequest("PUT", "http://169.254.169.254/latest/api/token", nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", tokenTTL)
+// pollTags periodically checks for tags that might control the behavior of the daemon
+func (p *AWSProvider) pollTags(ticker *time.Ticker) {
+	for {
+		select {
+		case <-ticker.C:
+			p.checkTags()
 
-	client := &http.Client{Timeout: 2 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Printf("Error closing response body: %v", err)
+		case <-p.stopTagPoll:
+			// Stop was requested
+			return
 		}
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get IMDSv2 token, status: %d", resp.StatusCode)
 	}
-
-	token, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	return string(token), nil
 }
 
-// getMetadata gets a value from the EC2 instance metadata service
-func getMetadata(path string) (string, error) {
-	// Get token for IMDSv2
-	token, err := getIMDSToken()
-	if err != nil {
-		return "", err
-	}
-
-	// Create a request with the token
-	req, err := http.NewRequest("GET", "http://169.254.169.254/latest/meta-data/"+path, nil)
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("X-aws-ec2-metadata-token", token)
-
-	client := &http.Client{Timeout: 2 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			log.Printf("Error closing response body: %v", err)
-		}
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to get metadata at path %s, status: %d", path, resp.StatusCode)
-	}
+// checkTags looks up the tags that control the daemon. A panic ends this
+// check only, so polling goes on.
+func (p *AWSProvider) checkTags() {
+	defer snoozeerrors.Recover(func(err *snoozeerrors.CloudSnoozeError) {
+		logger().Error("Recovered from panic in tag polling", "error", err, "stack", err.Stack)
+	})
 
-	data, err := io.ReadAll(resp.Body)
+	// Get the instance tags, shared with other readers
+	tags, err := p.readTags(p.context())
 	if err != nil {
-		return "", err
+		logger().Error("Tag polling failed to get tags", "error", err)
+		return
 	}
 
-	return strings.TrimSpace(string(data)), nil
-}
-
-// pollTags periodically checks for tags that might control the behavior of the daemon
-func (p *AWSProvider) pollTags() {
-	for {
-		select {
-		case <-p.tagPoller.C:
-			// Get instance ID
-			instanceID, err := p.getInstanceID()
-			if err != nil {
-				fmt.Printf("Error in tag polling: %v\n", err)
-				continue
-			}
-
-			// Filter for the tags we're interested in
-			tagFilter := fmt.Sprintf("%s:*", p.config.TaggingPrefix)
-
-			// Get the instance tags
-			result, err := p.client.DescribeTags(context.TODO(), &ec2.DescribeTagsInput{
-				Filters: []types.Filter{
-					{
-						Name:   aws.String("resource-id"),
-						Values: []string{instanceID},
-					},
-					{
-						Name:   aws.String("key"),
-						Values: []string{tagFilter},
-					},
-				},
-			})
-			if err != nil {
-				fmt.Printf("Error getting tags: %v\n", err)
-				continue
-			}
-
-			// Process tags - this is a placeholder, add real tag handling logic here
-			for _, tag := range result.Tags {
-				if tag.Key != nil && tag.Value != nil {
-					fmt.Printf("Found tag: %s = %s\n", *tag.Key, *tag.Value)
-					// TODO: Implement actual tag handling logic
-					// For example, if there's a tag like "cloudsnooze:disable", pause monitoring
-				}
-			}
-
-		case <-p.stopTagPoll:
-			// Stop was requested
-			if p.tagPoller != nil {
-				p.tagPoller.Stop()
-				p.tagPoller = nil
-			}
-			return
+	// Process tags - this is a placeholder, add real tag handling logic here
+	prefix := p.config.TaggingPrefix + ":"
+	for key, value := range tags {
+		if strings.HasPrefix(key, prefix) {
+			logger().Debug("Found tag", "key", key, "value", value)
+			// TODO: Implement actual tag handling logic
+			// For example, if there's a tag like "cloudsnooze:disable", pause monitoring
 		}
 	}
 }
 
-// StopTagPolling stops the tag polling goroutine
+// StopTagPolling stops the tag polling goroutine. It may be called more
+// than once, and whether or not polling was started.
 func (p *AWSProvider) StopTagPolling() {
+	p.lock.Lock()
 	if p.tagPoller != nil {
-		p.stopTagPoll <- struct{}{}
+		p.tagPoller.Stop()
+		p.tagPoller = nil
 	}
+	p.lock.Unlock()
+
+	p.stopOnce.Do(func() {
+		close(p.stopTagPoll)
+	})
 }
 
-// TagInstance adds tags to the current instance
+// TagInstance adds tags to the current instance. Tags from several callers
+// are collected for a few seconds and written in one call. If the previous
+// write failed, its error is returned by the next call and its tags are
+// written again with the new ones; FlushTags writes them at once. A stop
+// writes any queued tags along with its own.
 func (p *AWSProvider) TagInstance(tags map[string]string) error {
-	instanceID, err := p.getInstanceID()
-	if err != nil {
+	if _, err := p.getInstanceID(); err != nil {
 		return fmt.Errorf("error getting instance ID: %v", err)
 	}
-	
-	// Convert map to EC2 tag format
-	var ec2Tags []types.Tag
-	for k, v := range tags {
-		ec2Tags = append(ec2Tags, types.Tag{
-			Key:   aws.String(k),
-			Value: aws.String(v),
-		})
-	}
-	
-	// Apply the tags
-	_, err = p.client.CreateTags(context.TODO(), &ec2.CreateTagsInput{
-		Resources: []string{instanceID},
-		Tags:      ec2Tags,
-	})
-	return err
+	return p.queueTags(tags)
 }
 
 // GetExternalTags checks for tags from external systems that might control this instance
 func (p *AWSProvider) GetExternalTags() (map[string]string, error) {
-	instanceID, err := p.getInstanceID()
-	if err != nil {
-		return nil, fmt.Errorf("error getting instance ID: %v", err)
-	}
-	
-	// Get all tags for the instance
-	result, err := p.client.DescribeTags(context.TODO(), &ec2.DescribeTagsInput{
-		Filters: []types.Filter{
-			{
-				Name:   aws.String("resource-id"),
-				Values: []string{instanceID},
-			},
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("error getting tags: %v", err)
-	}
-	
-	// Convert to map
-	tags := make(map[string]string)
-	for _, tag := range result.Tags {
-		if tag.Key != nil && tag.Value != nil {
-			tags[*tag.Key] = *tag.Value
-		}
-	}
-	
-	return tags, nil
+	return p.readTags(p.context())
 }
\ No newline at end of file
@@ -0,0 +1,228 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// controllerMetricPeriod is the period of the EC2 metrics read by the
+// controller, which is all basic monitoring provides
+const controllerMetricPeriod = 5 * time.Minute
+
+// controllerAPI is the subset of the EC2 client the controller uses
+type controllerAPI interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error)
+	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+}
+
+// metricReaderAPI is the subset of the CloudWatch client the controller
+// reads metrics with
+type metricReaderAPI interface {
+	GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error)
+}
+
+// getControllerClient returns the client instances are found and stopped with
+func (p *AWSProvider) getControllerClient() (controllerAPI, error) {
+	p.lock.RLock()
+	client := p.controllerClient
+	p.lock.RUnlock()
+	if client != nil {
+		return client, nil
+	}
+	return p.getEC2Client()
+}
+
+// getMetricReader returns the client metrics are read with, creating it on
+// first use
+func (p *AWSProvider) getMetricReader() (metricReaderAPI, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.metricReader == nil {
+		cfg, err := p.loadAWSConfig(p.ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.metricReader = cloudwatch.NewFromConfig(cfg)
+	}
+	return p.metricReader, nil
+}
+
+// TaggedInstances returns the running instances that have all of tags
+func (p *AWSProvider) TaggedInstances(ctx context.Context, tags map[string]string) ([]common.ControlledInstance, error) {
+	client, err := p.getControllerClient()
+	if err != nil {
+		return nil, err
+	}
+	filters := []types.Filter{{Name: aws.String("instance-state-name"), Values: []string{"running"}}}
+	for key, value := range tags {
+		filters = append(filters, types.Filter{Name: aws.String("tag:" + key), Values: []string{value}})
+	}
+	input := &ec2.DescribeInstancesInput{Filters: filters}
+
+	var instances []common.ControlledInstance
+	for {
+		var output *ec2.DescribeInstancesOutput
+		err := p.retry(ctx, "error finding tagged instances", func(ctx context.Context) error {
+			var err error
+			output, err = client.DescribeInstances(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, reservation := range output.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := make(map[string]string, len(instance.Tags))
+				for _, tag := range instance.Tags {
+					if tag.Key != nil && tag.Value != nil {
+						tags[*tag.Key] = *tag.Value
+					}
+				}
+				instances = append(instances, common.ControlledInstance{
+					ID:         aws.ToString(instance.InstanceId),
+					Name:       tags["Name"],
+					Type:       string(instance.InstanceType),
+					LaunchTime: aws.ToTime(instance.LaunchTime),
+					Tags:       tags,
+				})
+			}
+		}
+
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].ID < instances[j].ID
+	})
+	return instances, nil
+}
+
+// InstanceUsage returns the instance's highest CPU utilization and network
+// traffic of the metric periods in the period ending now
+func (p *AWSProvider) InstanceUsage(ctx context.Context, instanceID string, period time.Duration) (common.InstanceUsage, error) {
+	client, err := p.getMetricReader()
+	if err != nil {
+		return common.InstanceUsage{}, err
+	}
+	query := func(id, metric, stat string) cwtypes.MetricDataQuery {
+		return cwtypes.MetricDataQuery{
+			Id: aws.String(id),
+			MetricStat: &cwtypes.MetricStat{
+				Metric: &cwtypes.Metric{
+					Namespace:  aws.String("AWS/EC2"),
+					MetricName: aws.String(metric),
+					Dimensions: []cwtypes.Dimension{{Name: aws.String("InstanceId"), Value: aws.String(instanceID)}},
+				},
+				Period: aws.Int32(int32(controllerMetricPeriod.Seconds())),
+				Stat:   aws.String(stat),
+			},
+		}
+	}
+	now := time.Now()
+	input := &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(now.Add(-period)),
+		EndTime:   aws.Time(now),
+		MetricDataQueries: []cwtypes.MetricDataQuery{
+			query("cpu", "CPUUtilization", "Average"),
+			query("network_in", "NetworkIn", "Sum"),
+			query("network_out", "NetworkOut", "Sum"),
+		},
+	}
+
+	// Traffic is summed per period, in and out together
+	var usage common.InstanceUsage
+	traffic := make(map[time.Time]float64)
+	for {
+		var output *cloudwatch.GetMetricDataOutput
+		err := p.retry(ctx, "error reading instance metrics", func(ctx context.Context) error {
+			var err error
+			output, err = client.GetMetricData(ctx, input)
+			return err
+		})
+		if err != nil {
+			return common.InstanceUsage{}, err
+		}
+
+		for _, result := range output.MetricDataResults {
+			for i, value := range result.Values {
+				if i >= len(result.Timestamps) {
+					break
+				}
+				switch aws.ToString(result.Id) {
+				case "cpu":
+					usage.Samples++
+					usage.CPUPercent = max(usage.CPUPercent, value)
+				default:
+					traffic[result.Timestamps[i]] += value
+				}
+			}
+		}
+
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+	for _, bytes := range traffic {
+		usage.NetworkKBps = max(usage.NetworkKBps, bytes/1024/controllerMetricPeriod.Seconds())
+	}
+	return usage, nil
+}
+
+// StopFleetInstance tags another instance as snoozed, as its own daemon
+// would, and stops it. An instance that can't be tagged isn't stopped,
+// since the restarter couldn't find it again.
+func (p *AWSProvider) StopFleetInstance(ctx context.Context, instanceID, reason string) error {
+	client, err := p.getControllerClient()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	tags := []types.Tag{
+		{Key: aws.String(p.config.TaggingPrefix + ":stopped_at"), Value: aws.String(now.Format(time.RFC3339))},
+		{Key: aws.String(p.config.TaggingPrefix + ":reason"), Value: aws.String(reason)},
+	}
+	if p.config.MaxSnooze > 0 {
+		tags = append(tags, types.Tag{Key: aws.String(p.config.TaggingPrefix + ":wake_at"), Value: aws.String(now.Add(p.config.MaxSnooze).Format(time.RFC3339))})
+	}
+	err = p.retry(ctx, "error tagging instance", func(ctx context.Context) error {
+		_, err := client.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: []string{instanceID},
+			Tags:      tags,
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = p.retry(ctx, "error stopping instance", func(ctx context.Context) error {
+		_, err := client.StopInstances(ctx, &ec2.StopInstancesInput{
+			InstanceIds: []string{instanceID},
+		})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("tagged instance but failed to stop it: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// fakeController has two running instances and records tags and stops
+type fakeController struct {
+	filters []types.Filter
+	tagged  []*ec2.CreateTagsInput
+	stopped []string
+	tagErr  error
+}
+
+func (f *fakeController) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	f.filters = params.Filters
+	launched := aws.Time(time.Date(2025, 5, 1, 8, 0, 0, 0, time.UTC))
+	web := instanceWithTags("i-web", map[string]string{"Name": "web", "team": "research"})
+	web.LaunchTime = launched
+	web.InstanceType = types.InstanceTypeT3Medium
+	if params.NextToken == nil {
+		return &ec2.DescribeInstancesOutput{
+			Reservations: []types.Reservation{{Instances: []types.Instance{web}}},
+			NextToken:    aws.String("page-2"),
+		}, nil
+	}
+	gpu := instanceWithTags("i-gpu", map[string]string{"team": "research"})
+	gpu.LaunchTime = launched
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []types.Reservation{{Instances: []types.Instance{gpu}}},
+	}, nil
+}
+
+func (f *fakeController) StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	f.stopped = append(f.stopped, params.InstanceIds...)
+	return &ec2.StopInstancesOutput{}, nil
+}
+
+func (f *fakeController) CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	if f.tagErr != nil {
+		return nil, f.tagErr
+	}
+	f.tagged = append(f.tagged, params)
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+// fakeMetricReader returns two periods of metrics
+type fakeMetricReader struct {
+	input *cloudwatch.GetMetricDataInput
+}
+
+func (f *fakeMetricReader) GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	f.input = params
+	first := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	second := first.Add(5 * time.Minute)
+	return &cloudwatch.GetMetricDataOutput{
+		MetricDataResults: []cwtypes.MetricDataResult{
+			{Id: aws.String("cpu"), Timestamps: []time.Time{second, first}, Values: []float64{2.5, 7.5}},
+			{Id: aws.String("network_in"), Timestamps: []time.Time{second, first}, Values: []float64{300 * 1024, 600 * 1024}},
+			{Id: aws.String("network_out"), Timestamps: []time.Time{second, first}, Values: []float64{1200 * 1024, 300 * 1024}},
+		},
+	}, nil
+}
+
+func TestTaggedInstances(t *testing.T) {
+	client := &fakeController{}
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze"})
+	provider.controllerClient = client
+
+	instances, err := provider.TaggedInstances(context.Background(), map[string]string{"team": "research"})
+	if err != nil {
+		t.Fatalf("TaggedInstances failed: %v", err)
+	}
+	if len(instances) != 2 || instances[0].ID != "i-gpu" || instances[1].ID != "i-web" {
+		t.Fatalf("Expected i-gpu and i-web, got %+v", instances)
+	}
+	if instances[1].Name != "web" || instances[1].Type != "t3.medium" || instances[1].LaunchTime.IsZero() {
+		t.Errorf("Expected the name, type and launch time of i-web, got %+v", instances[1])
+	}
+	if len(client.filters) != 2 || aws.ToString(client.filters[1].Name) != "tag:team" || client.filters[1].Values[0] != "research" {
+		t.Errorf("Expected running instances to be filtered by tag, got %+v", client.filters)
+	}
+}
+
+func TestInstanceUsage(t *testing.T) {
+	client := &fakeMetricReader{}
+	provider := NewProvider(Config{})
+	provider.metricReader = client
+
+	usage, err := provider.InstanceUsage(context.Background(), "i-web", 30*time.Minute)
+	if err != nil {
+		t.Fatalf("InstanceUsage failed: %v", err)
+	}
+	if usage.CPUPercent != 7.5 || usage.Samples != 2 {
+		t.Errorf("Expected a peak of 7.5%% CPU from 2 samples, got %+v", usage)
+	}
+	// The second period moved 1500 KB in 300 seconds
+	if math.Abs(usage.NetworkKBps-5) > 0.001 {
+		t.Errorf("Expected a peak of 5 KB/s, got %g", usage.NetworkKBps)
+	}
+	if window := aws.ToTime(client.input.EndTime).Sub(aws.ToTime(client.input.StartTime)); window != 30*time.Minute {
+		t.Errorf("Expected metrics for the last 30 minutes, got %s", window)
+	}
+}
+
+func TestStopFleetInstance(t *testing.T) {
+	client := &fakeController{}
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze", MaxSnooze: 12 * time.Hour})
+	provider.controllerClient = client
+
+	if err := provider.StopFleetInstance(context.Background(), "i-web", "idle"); err != nil {
+		t.Fatalf("StopFleetInstance failed: %v", err)
+	}
+	if len(client.stopped) != 1 || client.stopped[0] != "i-web" {
+		t.Errorf("Expected i-web to be stopped, got %v", client.stopped)
+	}
+	tags := map[string]string{}
+	for _, tag := range client.tagged[0].Tags {
+		tags[*tag.Key] = *tag.Value
+	}
+	if tags["CloudSnooze:reason"] != "idle" || tags["CloudSnooze:stopped_at"] == "" || tags["CloudSnooze:wake_at"] == "" {
+		t.Errorf("Expected the snooze tags, got %v", tags)
+	}
+
+	// An instance the restarter couldn't find is left running
+	client = &fakeController{tagErr: errors.New("UnauthorizedOperation")}
+	provider.controllerClient = client
+	provider.retryDelay = time.Millisecond
+	if err := provider.StopFleetInstance(context.Background(), "i-web", "idle"); err == nil || len(client.stopped) != 0 {
+		t.Errorf("Expected a tagging failure to leave the instance running, got %v and %v", err, client.stopped)
+	}
+}
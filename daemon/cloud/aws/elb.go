@@ -0,0 +1,200 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+const (
+	// DefaultDrainTimeout bounds how long a stop waits for connection draining
+	DefaultDrainTimeout = 5 * time.Minute
+
+	// drainPollInterval is how often draining targets are checked
+	drainPollInterval = 5 * time.Second
+)
+
+// elbAPI is the subset of the Elastic Load Balancing client used by the provider
+type elbAPI interface {
+	DescribeTargetGroups(ctx context.Context, params *elb.DescribeTargetGroupsInput, optFns ...func(*elb.Options)) (*elb.DescribeTargetGroupsOutput, error)
+	DescribeTargetHealth(ctx context.Context, params *elb.DescribeTargetHealthInput, optFns ...func(*elb.Options)) (*elb.DescribeTargetHealthOutput, error)
+	DeregisterTargets(ctx context.Context, params *elb.DeregisterTargetsInput, optFns ...func(*elb.Options)) (*elb.DeregisterTargetsOutput, error)
+	RegisterTargets(ctx context.Context, params *elb.RegisterTargetsInput, optFns ...func(*elb.Options)) (*elb.RegisterTargetsOutput, error)
+}
+
+// deregisterTargets removes the instance from its load balancer target
+// groups and waits until connections have drained, so load balancers stop
+// sending traffic before the instance goes away. The groups left are
+// remembered so rejoinTargets can register the instance again.
+func (p *AWSProvider) deregisterTargets(ctx context.Context, instanceID string) error {
+	client, err := p.getELBClient()
+	if err != nil {
+		return err
+	}
+
+	groups := p.config.TargetGroupARNs
+	if len(groups) == 0 {
+		if groups, err = instanceTargetGroups(ctx, client); err != nil {
+			return err
+		}
+	}
+
+	// Deregister from every group the instance is registered with
+	registered := make(map[string][]elbtypes.TargetDescription)
+	for _, group := range groups {
+		targets, err := instanceTargets(ctx, client, group, instanceID)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			continue
+		}
+		_, err = client.DeregisterTargets(ctx, &elb.DeregisterTargetsInput{
+			TargetGroupArn: aws.String(group),
+			Targets:        targets,
+		})
+		if err != nil {
+			return fmt.Errorf("error deregistering from target group %s: %v", group, err)
+		}
+		logger().Info("Deregistered instance from target group", "target_group", group)
+		registered[group] = targets
+
+		p.lock.Lock()
+		if p.leftGroups == nil {
+			p.leftGroups = make(map[string][]elbtypes.TargetDescription)
+		}
+		p.leftGroups[group] = targets
+		p.lock.Unlock()
+	}
+	if len(registered) == 0 {
+		return nil
+	}
+
+	// Wait for connection draining to finish
+	timeout := p.config.DrainTimeout
+	if timeout <= 0 {
+		timeout = DefaultDrainTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		for group := range registered {
+			targets, err := instanceTargets(ctx, client, group, instanceID)
+			if err != nil {
+				return err
+			}
+			if len(targets) == 0 {
+				delete(registered, group)
+			}
+		}
+		if len(registered) == 0 {
+			logger().Info("Connections drained from target groups")
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("connections still draining after %s", timeout)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.drainPoll):
+		}
+	}
+}
+
+// rejoinTargets registers the instance again with the target groups it
+// left, for a stop that failed afterwards
+func (p *AWSProvider) rejoinTargets(ctx context.Context) error {
+	p.lock.Lock()
+	left := p.leftGroups
+	p.leftGroups = nil
+	p.lock.Unlock()
+	if len(left) == 0 {
+		return nil
+	}
+
+	client, err := p.getELBClient()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for group, targets := range left {
+		_, err := client.RegisterTargets(ctx, &elb.RegisterTargetsInput{
+			TargetGroupArn: aws.String(group),
+			Targets:        targets,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error registering with target group %s: %v", group, err))
+			continue
+		}
+		logger().Info("Registered instance with target group again", "target_group", group)
+	}
+	return errors.Join(errs...)
+}
+
+// instanceTargetGroups lists the target groups that route to instances
+func instanceTargetGroups(ctx context.Context, client elbAPI) ([]string, error) {
+	var groups []string
+	paginator := elb.NewDescribeTargetGroupsPaginator(client, &elb.DescribeTargetGroupsInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error listing target groups: %v", err)
+		}
+		for _, group := range page.TargetGroups {
+			if group.TargetType == elbtypes.TargetTypeEnumInstance {
+				groups = append(groups, aws.ToString(group.TargetGroupArn))
+			}
+		}
+	}
+	return groups, nil
+}
+
+// instanceTargets returns the instance's targets in a group that are still
+// registered or draining
+func instanceTargets(ctx context.Context, client elbAPI, group, instanceID string) ([]elbtypes.TargetDescription, error) {
+	health, err := client.DescribeTargetHealth(ctx, &elb.DescribeTargetHealthInput{
+		TargetGroupArn: aws.String(group),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error describing targets of %s: %v", group, err)
+	}
+
+	var targets []elbtypes.TargetDescription
+	for _, description := range health.TargetHealthDescriptions {
+		if description.Target == nil || aws.ToString(description.Target.Id) != instanceID {
+			continue
+		}
+		if description.TargetHealth != nil && description.TargetHealth.State == elbtypes.TargetHealthStateEnumUnused {
+			continue
+		}
+		targets = append(targets, elbtypes.TargetDescription{
+			Id:   description.Target.Id,
+			Port: description.Target.Port,
+		})
+	}
+	return targets, nil
+}
+
+// getELBClient returns the Elastic Load Balancing client, creating it on first use
+func (p *AWSProvider) getELBClient() (elbAPI, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.elbClient == nil {
+		cfg, err := p.loadAWSConfig(p.ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.elbClient = elb.NewFromConfig(cfg)
+	}
+	return p.elbClient, nil
+}
@@ -0,0 +1,128 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	elb "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbtypes "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+// fakeELB has the instance registered in one of two target groups. Its
+// target drains for a number of health checks after deregistration.
+type fakeELB struct {
+	state        map[string]elbtypes.TargetHealthStateEnum
+	drainChecks  int
+	deregistered []string
+	registered   []string
+}
+
+func (f *fakeELB) DescribeTargetGroups(ctx context.Context, params *elb.DescribeTargetGroupsInput, optFns ...func(*elb.Options)) (*elb.DescribeTargetGroupsOutput, error) {
+	return &elb.DescribeTargetGroupsOutput{
+		TargetGroups: []elbtypes.TargetGroup{
+			{TargetGroupArn: aws.String("web"), TargetType: elbtypes.TargetTypeEnumInstance},
+			{TargetGroupArn: aws.String("api"), TargetType: elbtypes.TargetTypeEnumInstance},
+			{TargetGroupArn: aws.String("lambda"), TargetType: elbtypes.TargetTypeEnumLambda},
+		},
+	}, nil
+}
+
+func (f *fakeELB) DescribeTargetHealth(ctx context.Context, params *elb.DescribeTargetHealthInput, optFns ...func(*elb.Options)) (*elb.DescribeTargetHealthOutput, error) {
+	group := aws.ToString(params.TargetGroupArn)
+	if f.state[group] == elbtypes.TargetHealthStateEnumDraining {
+		if f.drainChecks == 0 {
+			delete(f.state, group)
+		}
+		f.drainChecks--
+	}
+
+	output := &elb.DescribeTargetHealthOutput{
+		TargetHealthDescriptions: []elbtypes.TargetHealthDescription{{
+			Target:       &elbtypes.TargetDescription{Id: aws.String("i-other"), Port: aws.Int32(80)},
+			TargetHealth: &elbtypes.TargetHealth{State: elbtypes.TargetHealthStateEnumHealthy},
+		}},
+	}
+	if state, ok := f.state[group]; ok {
+		output.TargetHealthDescriptions = append(output.TargetHealthDescriptions, elbtypes.TargetHealthDescription{
+			Target:       &elbtypes.TargetDescription{Id: aws.String("i-0abc"), Port: aws.Int32(8080)},
+			TargetHealth: &elbtypes.TargetHealth{State: state},
+		})
+	}
+	return output, nil
+}
+
+func (f *fakeELB) DeregisterTargets(ctx context.Context, params *elb.DeregisterTargetsInput, optFns ...func(*elb.Options)) (*elb.DeregisterTargetsOutput, error) {
+	group := aws.ToString(params.TargetGroupArn)
+	if len(params.Targets) != 1 || aws.ToInt32(params.Targets[0].Port) != 8080 {
+		return nil, &elbtypes.InvalidTargetException{}
+	}
+	f.deregistered = append(f.deregistered, group)
+	f.state[group] = elbtypes.TargetHealthStateEnumDraining
+	return &elb.DeregisterTargetsOutput{}, nil
+}
+
+func (f *fakeELB) RegisterTargets(ctx context.Context, params *elb.RegisterTargetsInput, optFns ...func(*elb.Options)) (*elb.RegisterTargetsOutput, error) {
+	group := aws.ToString(params.TargetGroupArn)
+	if len(params.Targets) != 1 || aws.ToInt32(params.Targets[0].Port) != 8080 {
+		return nil, &elbtypes.InvalidTargetException{}
+	}
+	f.registered = append(f.registered, group)
+	f.state[group] = elbtypes.TargetHealthStateEnumInitial
+	return &elb.RegisterTargetsOutput{}, nil
+}
+
+func TestDeregisterTargets(t *testing.T) {
+	client := &fakeELB{
+		state:       map[string]elbtypes.TargetHealthStateEnum{"api": elbtypes.TargetHealthStateEnumHealthy},
+		drainChecks: 2,
+	}
+	provider := NewProvider(Config{DeregisterTargets: true, DrainTimeout: time.Second})
+	provider.elbClient = client
+	provider.drainPoll = time.Millisecond
+
+	if err := provider.deregisterTargets(context.Background(), "i-0abc"); err != nil {
+		t.Fatalf("deregisterTargets failed: %v", err)
+	}
+	if len(client.deregistered) != 1 || client.deregistered[0] != "api" {
+		t.Errorf("Expected to leave only the api group, left %v", client.deregistered)
+	}
+	if _, ok := client.state["api"]; ok {
+		t.Error("Expected to wait until draining finished")
+	}
+
+	// Draining that outlasts the timeout is reported
+	client.state["web"] = elbtypes.TargetHealthStateEnumHealthy
+	client.drainChecks = 1000
+	provider.config.TargetGroupARNs = []string{"web"}
+	provider.config.DrainTimeout = 10 * time.Millisecond
+	if err := provider.deregisterTargets(context.Background(), "i-0abc"); err == nil {
+		t.Error("Expected a draining timeout")
+	}
+}
+
+func TestRollbackStopRejoinsTargets(t *testing.T) {
+	client := &fakeELB{state: map[string]elbtypes.TargetHealthStateEnum{"api": elbtypes.TargetHealthStateEnumHealthy}}
+	provider := NewProvider(Config{DeregisterTargets: true, DrainTimeout: time.Second})
+	provider.elbClient = client
+	provider.drainPoll = time.Millisecond
+
+	if err := provider.deregisterTargets(context.Background(), "i-0abc"); err != nil {
+		t.Fatalf("deregisterTargets failed: %v", err)
+	}
+	if err := provider.RollbackStop(context.Background()); err != nil {
+		t.Fatalf("RollbackStop failed: %v", err)
+	}
+	if len(client.registered) != 1 || client.registered[0] != "api" {
+		t.Errorf("Expected to rejoin the api group with its port, rejoined %v", client.registered)
+	}
+
+	// A second rollback has nothing left to undo
+	if err := provider.RollbackStop(context.Background()); err != nil || len(client.registered) != 1 {
+		t.Errorf("Expected nothing to be registered again, got %v and %v", err, client.registered)
+	}
+}
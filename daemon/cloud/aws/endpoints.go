@@ -0,0 +1,166 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// partitionPrefixes maps region name prefixes to their partitions; any
+// other region is in the commercial "aws" partition
+var partitionPrefixes = []struct {
+	prefix    string
+	partition string
+}{
+	{"cn-", "aws-cn"},
+	{"us-gov-", "aws-us-gov"},
+	{"us-isob-", "aws-iso-b"},
+	{"us-iso-", "aws-iso"},
+	{"eu-isoe-", "aws-iso-e"},
+	{"us-isof-", "aws-iso-f"},
+}
+
+// partitionForRegion returns the partition a region belongs to
+func partitionForRegion(region string) string {
+	for _, p := range partitionPrefixes {
+		if strings.HasPrefix(region, p.prefix) {
+			return p.partition
+		}
+	}
+	return "aws"
+}
+
+// Timeouts of the HTTP client shared by every AWS client, so a hung
+// connection can't block a check or a stop
+const (
+	httpDialTimeout     = 5 * time.Second
+	httpTLSTimeout      = 5 * time.Second
+	httpResponseTimeout = 15 * time.Second
+	httpRequestTimeout  = 30 * time.Second
+	httpIdleTimeout     = 90 * time.Second
+)
+
+// newHTTPClient creates the HTTP client used by the AWS clients
+func newHTTPClient() aws.HTTPClient {
+	return awshttp.NewBuildableClient().
+		WithTimeout(httpRequestTimeout).
+		WithDialerOptions(func(d *net.Dialer) {
+			d.Timeout = httpDialTimeout
+		}).
+		WithTransportOptions(func(t *http.Transport) {
+			t.TLSHandshakeTimeout = httpTLSTimeout
+			t.ResponseHeaderTimeout = httpResponseTimeout
+			t.IdleConnTimeout = httpIdleTimeout
+			t.MaxIdleConnsPerHost = 4
+		})
+}
+
+// loadAWSConfig loads the SDK configuration shared by every client of the
+// provider, once. If a role is configured, its credentials are assumed with
+// STS and refreshed before they expire.
+func (p *AWSProvider) loadAWSConfig(ctx context.Context) (aws.Config, error) {
+	p.configLock.Lock()
+	defer p.configLock.Unlock()
+
+	if p.awsConfig != nil {
+		return *p.awsConfig, nil
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(p.config.Region), config.WithHTTPClient(newHTTPClient()))
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("error loading AWS config: %v", err)
+	}
+
+	if p.config.AssumeRoleARN != "" {
+		sessionName := "cloudsnooze"
+		if instanceID, err := p.getInstanceID(); err == nil {
+			sessionName += "-" + instanceID
+		}
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), p.config.AssumeRoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = sessionName
+			if p.config.ExternalID != "" {
+				o.ExternalID = aws.String(p.config.ExternalID)
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+		logger().Info("Using assumed role credentials", "role", p.config.AssumeRoleARN, "session", sessionName)
+	}
+
+	p.awsConfig = &cfg
+	return cfg, nil
+}
+
+// getEC2Client returns the EC2 client, creating it on first use
+func (p *AWSProvider) getEC2Client() (*ec2.Client, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.client == nil {
+		cfg, err := p.loadAWSConfig(p.ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.client = p.newEC2Client(cfg)
+	}
+	return p.client, nil
+}
+
+// newEC2Client creates an EC2 client, using the custom endpoint if one is
+// configured
+func (p *AWSProvider) newEC2Client(cfg aws.Config) *ec2.Client {
+	return ec2.NewFromConfig(cfg, func(o *ec2.Options) {
+		if p.config.EC2Endpoint != "" {
+			o.BaseEndpoint = aws.String(p.config.EC2Endpoint)
+		}
+	})
+}
+
+// partition returns the partition of the instance: the configured one, the
+// one reported by the metadata service, or the one implied by the region
+func (p *AWSProvider) partition() string {
+	if p.config.Partition != "" {
+		return p.config.Partition
+	}
+
+	p.lock.RLock()
+	partition, region := p.instancePartition, p.region
+	p.lock.RUnlock()
+	if partition != "" {
+		return partition
+	}
+	if region == "" {
+		region = p.config.Region
+	}
+	return partitionForRegion(region)
+}
+
+// metadataRegion reads the instance's region from the metadata service,
+// deriving it from the availability zone on older metadata versions
+func (p *AWSProvider) metadataRegion() (string, error) {
+	if region, err := p.metadata.get("placement/region"); err == nil && region != "" {
+		return region, nil
+	}
+
+	az, err := p.metadata.get("placement/availability-zone")
+	if err != nil {
+		return "", fmt.Errorf("error getting availability zone: %v", err)
+	}
+	// Convert AZ to region by removing the last character (e.g., us-west-2a -> us-west-2)
+	if len(az) > 1 {
+		return az[:len(az)-1], nil
+	}
+	return "", nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+)
+
+func TestPartitionForRegion(t *testing.T) {
+	tests := map[string]string{
+		"us-east-1":      "aws",
+		"cn-north-1":     "aws-cn",
+		"us-gov-west-1":  "aws-us-gov",
+		"us-iso-east-1":  "aws-iso",
+		"us-isob-east-1": "aws-iso-b",
+	}
+	for region, expected := range tests {
+		if partition := partitionForRegion(region); partition != expected {
+			t.Errorf("Region %s: expected partition %s, got %s", region, expected, partition)
+		}
+	}
+
+	// A configured partition wins over the region
+	provider := NewProvider(Config{Region: "us-east-1", Partition: "aws-cn"})
+	if partition := provider.partition(); partition != "aws-cn" {
+		t.Errorf("Expected configured partition aws-cn, got %s", partition)
+	}
+}
+
+func TestCustomEndpoints(t *testing.T) {
+	provider := NewProvider(Config{
+		Region:           "us-east-1",
+		EC2Endpoint:      "http://localhost:4566",
+		MetadataEndpoint: "http://localhost:1338/",
+	})
+
+	client := provider.newEC2Client(aws.Config{Region: "us-east-1"})
+	if endpoint := aws.ToString(client.Options().BaseEndpoint); endpoint != "http://localhost:4566" {
+		t.Errorf("Expected custom EC2 endpoint, got %q", endpoint)
+	}
+	if provider.metadata == defaultMetadata || provider.metadata.endpoint != "http://localhost:1338" {
+		t.Errorf("Expected custom metadata endpoint, got %q", provider.metadata.endpoint)
+	}
+
+	if NewProvider(Config{}).metadata != defaultMetadata {
+		t.Error("Expected the default metadata client without a custom endpoint")
+	}
+}
+
+func TestAssumeRoleConfig(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	provider := NewProvider(Config{
+		Region:        "us-east-1",
+		AssumeRoleARN: "arn:aws:iam::123456789012:role/cloudsnooze-central",
+		ExternalID:    "example",
+	})
+	provider.instanceID = "i-0abc"
+
+	cfg, err := provider.loadAWSConfig(context.Background())
+	if err != nil {
+		t.Fatalf("loadAWSConfig failed: %v", err)
+	}
+	cache, ok := cfg.Credentials.(*aws.CredentialsCache)
+	if !ok || !cache.IsCredentialsProvider(&stscreds.AssumeRoleProvider{}) {
+		t.Errorf("Expected cached assume role credentials, got %T", cfg.Credentials)
+	}
+
+	// Every client shares the same credentials
+	again, _ := provider.loadAWSConfig(context.Background())
+	if again.Credentials != cfg.Credentials {
+		t.Error("Expected the configuration to be loaded once")
+	}
+
+	// And the same HTTP client with bounded timeouts
+	client, ok := cfg.HTTPClient.(*awshttp.BuildableClient)
+	if !ok || client.GetTimeout() != httpRequestTimeout || client.GetTransport().ResponseHeaderTimeout != httpResponseTimeout {
+		t.Errorf("Expected an HTTP client with timeouts, got %T", cfg.HTTPClient)
+	}
+	ec2Client, _ := provider.getEC2Client()
+	if again, _ := provider.getEC2Client(); again != ec2Client {
+		t.Error("Expected the EC2 client to be created once")
+	}
+}
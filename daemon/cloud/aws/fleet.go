@@ -0,0 +1,194 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// fleetAPI is the subset of the EC2 client the restarter uses
+type fleetAPI interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error)
+	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+}
+
+// InitializeFleet sets up the provider to start other instances from an
+// always-on host, instead of watching the instance it runs on. Without a
+// configured region, the host's region is used.
+func (p *AWSProvider) InitializeFleet() error {
+	if p.config.Region == "" {
+		if region, err := p.metadata.get("placement/region"); err == nil {
+			p.config.Region = region
+		}
+	}
+	_, err := p.getFleetClient()
+	return err
+}
+
+// getFleetClient returns the client instances are found and started with
+func (p *AWSProvider) getFleetClient() (fleetAPI, error) {
+	p.lock.RLock()
+	client := p.fleetClient
+	p.lock.RUnlock()
+	if client != nil {
+		return client, nil
+	}
+	return p.getEC2Client()
+}
+
+// SnoozedInstances returns the stopped instances with a stopped_at tag
+// newer than their started_at tag, most recently stopped first. Instances
+// stopped by other means after the restarter started them are left alone.
+func (p *AWSProvider) SnoozedInstances(ctx context.Context) ([]common.SnoozedInstance, error) {
+	client, err := p.getFleetClient()
+	if err != nil {
+		return nil, err
+	}
+	stoppedTag := p.config.TaggingPrefix + ":stopped_at"
+	startedTag := p.config.TaggingPrefix + ":started_at"
+	input := &ec2.DescribeInstancesInput{
+		Filters: []types.Filter{
+			{Name: aws.String("tag-key"), Values: []string{stoppedTag}},
+			{Name: aws.String("instance-state-name"), Values: []string{"stopped"}},
+		},
+	}
+
+	var instances []common.SnoozedInstance
+	for {
+		var output *ec2.DescribeInstancesOutput
+		err := p.retry(ctx, "error finding snoozed instances", func(ctx context.Context) error {
+			var err error
+			output, err = client.DescribeInstances(ctx, input)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, reservation := range output.Reservations {
+			for _, instance := range reservation.Instances {
+				tags := make(map[string]string, len(instance.Tags))
+				for _, tag := range instance.Tags {
+					if tag.Key != nil && tag.Value != nil {
+						tags[*tag.Key] = *tag.Value
+					}
+				}
+				stoppedAt, err := time.Parse(time.RFC3339, tags[stoppedTag])
+				if err != nil {
+					continue
+				}
+				if startedAt, err := time.Parse(time.RFC3339, tags[startedTag]); err == nil && !stoppedAt.After(startedAt) {
+					continue
+				}
+				snoozed := common.SnoozedInstance{
+					ID:        aws.ToString(instance.InstanceId),
+					Name:      tags["Name"],
+					StoppedAt: stoppedAt,
+					Reason:    tags[p.config.TaggingPrefix+":reason"],
+					Tags:      tags,
+				}
+				if wakeAt, err := time.Parse(time.RFC3339, tags[p.config.TaggingPrefix+":wake_at"]); err == nil {
+					snoozed.WakeAt = &wakeAt
+				}
+				instances = append(instances, snoozed)
+			}
+		}
+
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i].StoppedAt.After(instances[j].StoppedAt)
+	})
+	return instances, nil
+}
+
+// StartInstance starts another instance, recording that it was started
+// through the API
+func (p *AWSProvider) StartInstance(instanceID string) error {
+	return p.StartInstanceContext(p.context(), instanceID, "api")
+}
+
+// StartInstanceContext starts an instance and tags it with when and why,
+// which ends its snooze
+func (p *AWSProvider) StartInstanceContext(ctx context.Context, instanceID, trigger string) error {
+	client, err := p.getFleetClient()
+	if err != nil {
+		return err
+	}
+	err = p.retry(ctx, "error starting instance", func(ctx context.Context) error {
+		_, err := client.StartInstances(ctx, &ec2.StartInstancesInput{
+			InstanceIds: []string{instanceID},
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = p.retry(ctx, "error tagging started instance", func(ctx context.Context) error {
+		_, err := client.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: []string{instanceID},
+			Tags: []types.Tag{
+				{Key: aws.String(p.config.TaggingPrefix + ":started_at"), Value: aws.String(time.Now().Format(time.RFC3339))},
+				{Key: aws.String(p.config.TaggingPrefix + ":start_trigger"), Value: aws.String(trigger)},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		// The instance is starting; it would only be listed again
+		return fmt.Errorf("started instance but failed to tag it: %v", err)
+	}
+	return nil
+}
+
+// Instance returns an instance's state and addresses
+func (p *AWSProvider) Instance(ctx context.Context, instanceID string) (common.FleetInstance, error) {
+	client, err := p.getFleetClient()
+	if err != nil {
+		return common.FleetInstance{}, err
+	}
+	var output *ec2.DescribeInstancesOutput
+	err = p.retry(ctx, "error describing instance", func(ctx context.Context) error {
+		var err error
+		output, err = client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+			InstanceIds: []string{instanceID},
+		})
+		return err
+	})
+	if err != nil {
+		return common.FleetInstance{}, err
+	}
+
+	for _, reservation := range output.Reservations {
+		for _, instance := range reservation.Instances {
+			if aws.ToString(instance.InstanceId) != instanceID {
+				continue
+			}
+			result := common.FleetInstance{
+				ID:        instanceID,
+				PrivateIP: aws.ToString(instance.PrivateIpAddress),
+				PublicIP:  aws.ToString(instance.PublicIpAddress),
+			}
+			if instance.State != nil {
+				result.State = string(instance.State.Name)
+			}
+			return result, nil
+		}
+	}
+	return common.FleetInstance{}, fmt.Errorf("instance %s not found", instanceID)
+}
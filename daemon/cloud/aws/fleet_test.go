@@ -0,0 +1,119 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// fakeFleet lists stopped instances over two pages and records starts
+type fakeFleet struct {
+	started []string
+	tagged  []*ec2.CreateTagsInput
+}
+
+func instanceWithTags(id string, tags map[string]string) types.Instance {
+	instance := types.Instance{InstanceId: aws.String(id)}
+	for key, value := range tags {
+		instance.Tags = append(instance.Tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+	return instance
+}
+
+func (f *fakeFleet) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	if len(params.InstanceIds) > 0 {
+		instance := instanceWithTags(params.InstanceIds[0], nil)
+		instance.State = &types.InstanceState{Name: types.InstanceStateNameRunning}
+		instance.PrivateIpAddress = aws.String("10.0.1.5")
+		return &ec2.DescribeInstancesOutput{
+			Reservations: []types.Reservation{{Instances: []types.Instance{instance}}},
+		}, nil
+	}
+	if params.NextToken == nil {
+		return &ec2.DescribeInstancesOutput{
+			Reservations: []types.Reservation{{Instances: []types.Instance{
+				instanceWithTags("i-older", map[string]string{"Name": "build-box", "CloudSnooze:stopped_at": "2025-05-01T18:00:00Z", "CloudSnooze:reason": "idle"}),
+				// Started by the restarter, then stopped by hand
+				instanceWithTags("i-woken", map[string]string{"CloudSnooze:stopped_at": "2025-05-01T18:00:00Z", "CloudSnooze:started_at": "2025-05-02T08:00:00Z"}),
+			}}},
+			NextToken: aws.String("page-2"),
+		}, nil
+	}
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []types.Reservation{{Instances: []types.Instance{
+			instanceWithTags("i-newer", map[string]string{"CloudSnooze:stopped_at": "2025-05-03T18:00:00Z", "CloudSnooze:started_at": "2025-05-02T08:00:00Z", "CloudSnooze:wake_at": "2025-05-04T18:00:00Z"}),
+		}}},
+	}, nil
+}
+
+func (f *fakeFleet) StartInstances(ctx context.Context, params *ec2.StartInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StartInstancesOutput, error) {
+	f.started = append(f.started, params.InstanceIds...)
+	return &ec2.StartInstancesOutput{}, nil
+}
+
+func (f *fakeFleet) CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	f.tagged = append(f.tagged, params)
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func TestSnoozedInstances(t *testing.T) {
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze"})
+	provider.fleetClient = &fakeFleet{}
+
+	instances, err := provider.SnoozedInstances(context.Background())
+	if err != nil {
+		t.Fatalf("SnoozedInstances failed: %v", err)
+	}
+	if len(instances) != 2 || instances[0].ID != "i-newer" || instances[1].ID != "i-older" {
+		t.Fatalf("Expected i-newer then i-older, got %+v", instances)
+	}
+	if instances[1].Name != "build-box" || instances[1].Reason != "idle" {
+		t.Errorf("Expected the name and reason from tags, got %+v", instances[1])
+	}
+	if instances[0].WakeAt == nil || !instances[0].WakeAt.Equal(time.Date(2025, 5, 4, 18, 0, 0, 0, time.UTC)) || instances[1].WakeAt != nil {
+		t.Errorf("Expected only i-newer to have a wake deadline, got %v and %v", instances[0].WakeAt, instances[1].WakeAt)
+	}
+}
+
+func TestStartInstance(t *testing.T) {
+	client := &fakeFleet{}
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze"})
+	provider.fleetClient = client
+
+	if err := provider.StartInstanceContext(context.Background(), "i-older", "webhook"); err != nil {
+		t.Fatalf("StartInstanceContext failed: %v", err)
+	}
+	if len(client.started) != 1 || client.started[0] != "i-older" {
+		t.Errorf("Expected i-older to be started, got %v", client.started)
+	}
+	if len(client.tagged) != 1 {
+		t.Fatalf("Expected one CreateTags call, got %d", len(client.tagged))
+	}
+	tags := map[string]string{}
+	for _, tag := range client.tagged[0].Tags {
+		tags[*tag.Key] = *tag.Value
+	}
+	if tags["CloudSnooze:start_trigger"] != "webhook" || tags["CloudSnooze:started_at"] == "" {
+		t.Errorf("Expected started_at and start_trigger tags, got %v", tags)
+	}
+}
+
+func TestInstance(t *testing.T) {
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze"})
+	provider.fleetClient = &fakeFleet{}
+
+	instance, err := provider.Instance(context.Background(), "i-older")
+	if err != nil {
+		t.Fatalf("Instance failed: %v", err)
+	}
+	if instance.ID != "i-older" || instance.State != "running" || instance.PrivateIP != "10.0.1.5" {
+		t.Errorf("Expected i-older running at 10.0.1.5, got %+v", instance)
+	}
+}
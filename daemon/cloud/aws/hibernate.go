@@ -0,0 +1,108 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+const (
+	// Largest instance memory that can be hibernated, in MiB
+	maxHibernateMemoryLinux   = 150 * 1024
+	maxHibernateMemoryWindows = 16 * 1024
+)
+
+// ec2DescribeAPI is the subset of the EC2 client used to inspect the instance
+type ec2DescribeAPI interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error)
+}
+
+// StopAction reports how the instance will be stopped
+func (p *AWSProvider) StopAction() common.StopActionStatus {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.stopAction.Requested == "" {
+		return common.StopActionStatus{Requested: "stop", Effective: "stop"}
+	}
+	return p.stopAction
+}
+
+// shouldHibernate returns true if the next stop should hibernate
+func (p *AWSProvider) shouldHibernate() bool {
+	return p.config.Hibernate && p.StopAction().Effective == "hibernate"
+}
+
+// setStopAction records the stop action, logging when it falls back to stop
+func (p *AWSProvider) setStopAction(status common.StopActionStatus) {
+	if status.Effective != status.Requested {
+		logger().Warn("Stop action unsupported, will fall back to stop", "requested", status.Requested, "reason", status.Reason)
+	}
+
+	p.lock.Lock()
+	p.stopAction = status
+	p.lock.Unlock()
+}
+
+// checkHibernation finds out whether the instance can be hibernated: it must
+// have been launched with hibernation enabled, on an instance type that
+// supports it, with no more memory than hibernation allows
+func (p *AWSProvider) checkHibernation(ctx context.Context, client ec2DescribeAPI, instanceID string) common.StopActionStatus {
+	status := common.StopActionStatus{Requested: "hibernate", Effective: "stop"}
+
+	instances, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		// Try anyway; a failed hibernate still falls back to stop
+		status.Effective = "hibernate"
+		status.Reason = fmt.Sprintf("couldn't verify hibernation support: %v", err)
+		return status
+	}
+	if len(instances.Reservations) == 0 || len(instances.Reservations[0].Instances) == 0 {
+		status.Reason = fmt.Sprintf("instance %s not found", instanceID)
+		return status
+	}
+	instance := instances.Reservations[0].Instances[0]
+
+	if instance.HibernationOptions == nil || !aws.ToBool(instance.HibernationOptions.Configured) {
+		status.Reason = "hibernation was not enabled when the instance was launched"
+		return status
+	}
+
+	typeInfo, err := client.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{instance.InstanceType},
+	})
+	if err != nil || len(typeInfo.InstanceTypes) == 0 {
+		// The instance was launched with hibernation, so the type supports it
+		status.Effective = "hibernate"
+		return status
+	}
+	info := typeInfo.InstanceTypes[0]
+
+	if !aws.ToBool(info.HibernationSupported) {
+		status.Reason = fmt.Sprintf("instance type %s doesn't support hibernation", instance.InstanceType)
+		return status
+	}
+
+	limit := int64(maxHibernateMemoryLinux)
+	if instance.Platform == "windows" {
+		limit = maxHibernateMemoryWindows
+	}
+	if info.MemoryInfo != nil && aws.ToInt64(info.MemoryInfo.SizeInMiB) > limit {
+		status.Reason = fmt.Sprintf("instance memory of %d GiB exceeds the hibernation limit of %d GiB",
+			aws.ToInt64(info.MemoryInfo.SizeInMiB)/1024, limit/1024)
+		return status
+	}
+
+	status.Effective = "hibernate"
+	return status
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// fakeDescribe describes a single instance and its type
+type fakeDescribe struct {
+	configured bool
+	supported  bool
+	memoryMiB  int64
+}
+
+func (f *fakeDescribe) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []types.Reservation{{
+			Instances: []types.Instance{{
+				InstanceId:         aws.String(params.InstanceIds[0]),
+				InstanceType:       types.InstanceTypeR5Large,
+				HibernationOptions: &types.HibernationOptions{Configured: aws.Bool(f.configured)},
+				LaunchTime:         aws.Time(time.Date(2025, 5, 1, 8, 30, 0, 0, time.UTC)),
+				Tags:               []types.Tag{{Key: aws.String("Name"), Value: aws.String("build-box")}},
+			}},
+		}},
+	}, nil
+}
+
+func (f *fakeDescribe) DescribeInstanceTypes(ctx context.Context, params *ec2.DescribeInstanceTypesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstanceTypesOutput, error) {
+	return &ec2.DescribeInstanceTypesOutput{
+		InstanceTypes: []types.InstanceTypeInfo{{
+			InstanceType:         params.InstanceTypes[0],
+			HibernationSupported: aws.Bool(f.supported),
+			MemoryInfo:           &types.MemoryInfo{SizeInMiB: aws.Int64(f.memoryMiB)},
+		}},
+	}, nil
+}
+
+func TestCheckHibernation(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    *fakeDescribe
+		effective string
+		reason    string
+	}{
+		{"supported", &fakeDescribe{configured: true, supported: true, memoryMiB: 16384}, "hibernate", ""},
+		{"not configured", &fakeDescribe{supported: true, memoryMiB: 16384}, "stop", "not enabled"},
+		{"unsupported type", &fakeDescribe{configured: true, memoryMiB: 16384}, "stop", "doesn't support"},
+		{"too much memory", &fakeDescribe{configured: true, supported: true, memoryMiB: 256 * 1024}, "stop", "exceeds"},
+	}
+
+	provider := NewProvider(Config{Hibernate: true})
+	for _, test := range tests {
+		status := provider.checkHibernation(context.Background(), test.client, "i-0abc")
+		if status.Requested != "hibernate" || status.Effective != test.effective || !strings.Contains(status.Reason, test.reason) {
+			t.Errorf("%s: unexpected status %+v", test.name, status)
+		}
+	}
+
+	// Without hibernation the provider always stops
+	if action := NewProvider(Config{}).StopAction(); action.Requested != "stop" || action.Effective != "stop" {
+		t.Errorf("Expected a plain stop, got %+v", action)
+	}
+}
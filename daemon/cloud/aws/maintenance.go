@@ -0,0 +1,71 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// eventTimeLayout is the time format of scheduled events in instance metadata
+const eventTimeLayout = "2 Jan 2006 15:04:05 GMT"
+
+// scheduledEvent is a maintenance event as reported by instance metadata
+type scheduledEvent struct {
+	EventID     string `json:"EventId"`
+	Code        string `json:"Code"`
+	Description string `json:"Description"`
+	NotBefore   string `json:"NotBefore"`
+	NotAfter    string `json:"NotAfter"`
+	State       string `json:"State"`
+}
+
+// ScheduledEvents returns the active maintenance events scheduled for the
+// instance, such as reboots and retirements
+func (p *AWSProvider) ScheduledEvents() ([]common.MaintenanceEvent, error) {
+	data, err := p.metadata.get("events/maintenance/scheduled")
+	if err != nil {
+		return nil, fmt.Errorf("error getting scheduled events: %v", err)
+	}
+	return parseScheduledEvents(data)
+}
+
+// parseScheduledEvents parses the metadata event list, skipping completed
+// and canceled events
+func parseScheduledEvents(data string) ([]common.MaintenanceEvent, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+
+	var scheduled []scheduledEvent
+	if err := json.Unmarshal([]byte(data), &scheduled); err != nil {
+		return nil, fmt.Errorf("error parsing scheduled events: %v", err)
+	}
+
+	events := make([]common.MaintenanceEvent, 0, len(scheduled))
+	for _, e := range scheduled {
+		if e.State != "" && e.State != "active" {
+			continue
+		}
+		notBefore, err := time.Parse(eventTimeLayout, e.NotBefore)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing start of event %s: %v", e.EventID, err)
+		}
+		event := common.MaintenanceEvent{
+			ID:          e.EventID,
+			Code:        e.Code,
+			Description: e.Description,
+			NotBefore:   notBefore,
+		}
+		if notAfter, err := time.Parse(eventTimeLayout, e.NotAfter); err == nil {
+			event.NotAfter = notAfter
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
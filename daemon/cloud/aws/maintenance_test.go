@@ -0,0 +1,37 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduledEvents(t *testing.T) {
+	data := `[
+		{"NotBefore": "21 Jan 2019 09:00:43 GMT", "Code": "system-reboot", "Description": "scheduled reboot",
+		 "EventId": "instance-event-0d59937288b749b32", "NotAfter": "21 Jan 2019 09:17:23 GMT", "State": "active"},
+		{"NotBefore": "1 Jan 2019 09:00:00 GMT", "Code": "system-maintenance", "Description": "done",
+		 "EventId": "instance-event-1", "State": "completed"}
+	]`
+
+	events, err := parseScheduledEvents(data)
+	if err != nil {
+		t.Fatalf("parseScheduledEvents failed: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected only the active event, got %+v", events)
+	}
+	event := events[0]
+	if event.Code != "system-reboot" || event.ID != "instance-event-0d59937288b749b32" {
+		t.Errorf("Unexpected event %+v", event)
+	}
+	if !event.NotBefore.Equal(time.Date(2019, 1, 21, 9, 0, 43, 0, time.UTC)) || event.NotAfter.Sub(event.NotBefore) != 1000*time.Second {
+		t.Errorf("Unexpected event times %s - %s", event.NotBefore, event.NotAfter)
+	}
+
+	if events, err := parseScheduledEvents("[]"); err != nil || len(events) != 0 {
+		t.Errorf("Expected no events, got %v, %v", events, err)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMetadataEndpoint is the address of the instance metadata service
+	DefaultMetadataEndpoint = "http://169.254.169.254"
+
+	// tokenTTL is how long an IMDSv2 session token is requested for (the maximum)
+	tokenTTL = 6 * time.Hour
+
+	// tokenRefreshMargin renews a token this long before it expires, so a
+	// token is never used as it runs out
+	tokenRefreshMargin = time.Minute
+)
+
+// metadataClient reads the EC2 instance metadata service using IMDSv2,
+// reusing its session token until shortly before it expires
+type metadataClient struct {
+	endpoint string
+	client   *http.Client
+	token    string
+	expires  time.Time
+	now      func() time.Time
+	lock     sync.Mutex
+}
+
+// defaultMetadata is shared by providers using the standard endpoint
+var defaultMetadata = newMetadataClient(DefaultMetadataEndpoint)
+
+// newMetadataClient creates a client for the metadata service at endpoint
+func newMetadataClient(endpoint string) *metadataClient {
+	return &metadataClient{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   &http.Client{Timeout: 2 * time.Second},
+		now:      time.Now,
+	}
+}
+
+// getToken returns the cached session token, requesting a new one if it
+// is missing or about to expire
+func (c *metadataClient) getToken() (string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.token != "" && c.now().Before(c.expires.Add(-tokenRefreshMargin)) {
+		return c.token, nil
+	}
+
+	req, err := http.NewRequest("PUT", c.endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", strconv.Itoa(int(tokenTTL.Seconds())))
+
+	requested := c.now()
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// The token's response is dropped after one hop by default, which
+		// is too few to reach a container with a network of its own
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return "", fmt.Errorf("%v (from a container, use the host's network or raise the instance's metadata hop limit to 2)", err)
+		}
+		return "", err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger().Error("Failed to close response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get IMDSv2 token, status: %d", resp.StatusCode)
+	}
+
+	token, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	c.token = string(token)
+	c.expires = requested.Add(tokenTTL)
+	return c.token, nil
+}
+
+// invalidateToken forgets the cached token, e.g. after it was rejected
+func (c *metadataClient) invalidateToken() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.token = ""
+}
+
+// get reads a metadata value, such as "instance-id"
+func (c *metadataClient) get(path string) (string, error) {
+	data, status, err := c.request(path)
+	if err == nil && status == http.StatusUnauthorized {
+		// The token was revoked or the instance was stopped and started;
+		// try once more with a fresh one
+		c.invalidateToken()
+		data, status, err = c.request(path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("failed to get metadata at path %s, status: %d", path, status)
+	}
+	return strings.TrimSpace(data), nil
+}
+
+// request performs one authenticated metadata request
+func (c *metadataClient) request(path string) (string, int, error) {
+	token, err := c.getToken()
+	if err != nil {
+		return "", 0, err
+	}
+
+	req, err := http.NewRequest("GET", c.endpoint+"/latest/meta-data/"+path, nil)
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger().Error("Failed to close response body", "error", err)
+		}
+	}()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+	return string(data), resp.StatusCode, nil
+}
+
+// MetadataAvailable returns true if the instance metadata service answers,
+// which means the daemon is running on EC2
+func MetadataAvailable() bool {
+	_, err := defaultMetadata.get("instance-id")
+	return err == nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeIMDS serves tokens and an instance ID, counting token requests
+func fakeIMDS(t *testing.T, tokens *int32, valid *atomic.Value) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "PUT" && r.URL.Path == "/latest/api/token":
+			if r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") != "21600" {
+				t.Errorf("Unexpected token TTL %q", r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds"))
+			}
+			n := atomic.AddInt32(tokens, 1)
+			token := "token-" + string(rune('0'+n))
+			valid.Store(token)
+			w.Write([]byte(token))
+		case r.Method == "GET" && r.URL.Path == "/latest/meta-data/instance-id":
+			if r.Header.Get("X-aws-ec2-metadata-token") != valid.Load().(string) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("i-0123456789abcdef0\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMetadataTokenReuse(t *testing.T) {
+	var tokens int32
+	var valid atomic.Value
+	valid.Store("")
+	server := fakeIMDS(t, &tokens, &valid)
+
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	client := newMetadataClient(server.URL + "/")
+	client.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		id, err := client.get("instance-id")
+		if err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		if id != "i-0123456789abcdef0" {
+			t.Errorf("Unexpected instance ID %q", id)
+		}
+	}
+	if tokens != 1 {
+		t.Errorf("Expected the token to be reused, got %d token requests", tokens)
+	}
+
+	// A token close to expiry is renewed before use
+	now = now.Add(tokenTTL - 30*time.Second)
+	if _, err := client.get("instance-id"); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if tokens != 2 {
+		t.Errorf("Expected the token to be renewed, got %d token requests", tokens)
+	}
+}
+
+func TestMetadataRejectedToken(t *testing.T) {
+	var tokens int32
+	var valid atomic.Value
+	valid.Store("")
+	server := fakeIMDS(t, &tokens, &valid)
+
+	client := newMetadataClient(server.URL)
+	if _, err := client.get("instance-id"); err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+
+	// The service stops accepting the cached token
+	valid.Store("revoked")
+	id, err := client.get("instance-id")
+	if err != nil {
+		t.Fatalf("Expected a rejected token to be replaced, got %v", err)
+	}
+	if id != "i-0123456789abcdef0" || tokens != 2 {
+		t.Errorf("Expected a retry with a new token, got %q after %d token requests", id, tokens)
+	}
+
+	if _, err := client.get("missing"); err == nil {
+		t.Error("Expected an error for a missing path")
+	}
+}
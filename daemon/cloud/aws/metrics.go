@@ -0,0 +1,132 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/scttfrdmn/cloudsnooze/daemon/telemetry"
+)
+
+// DefaultMetricsNamespace is the CloudWatch namespace of the custom metrics
+const DefaultMetricsNamespace = "CloudSnooze"
+
+// metricsTimeout bounds a PutMetricData call so a slow API can't pile up calls
+const metricsTimeout = 15 * time.Second
+
+// cloudWatchAPI is the subset of the CloudWatch client used by the provider
+type cloudWatchAPI interface {
+	PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error)
+}
+
+// resourceMetrics maps check resources to CloudWatch metric names and units
+var resourceMetrics = map[string]struct {
+	name string
+	unit cwtypes.StandardUnit
+}{
+	"cpu":     {"CPUUtilization", cwtypes.StandardUnitPercent},
+	"memory":  {"MemoryUtilization", cwtypes.StandardUnitPercent},
+	"network": {"NetworkKBps", cwtypes.StandardUnitKilobytesSecond},
+	"disk_io": {"DiskIOKBps", cwtypes.StandardUnitKilobytesSecond},
+	"gpu":     {"GPUUtilization", cwtypes.StandardUnitPercent},
+}
+
+// Record publishes the outcome of a monitoring check as CloudWatch custom
+// metrics when enabled. The call is made in the background so the
+// monitoring loop is never held up by the API.
+func (p *AWSProvider) Record(_ context.Context, check telemetry.Check) {
+	if !p.config.PublishMetrics {
+		return
+	}
+	instanceID, err := p.getInstanceID()
+	if err != nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(p.context(), metricsTimeout)
+		defer cancel()
+
+		err := p.putMetrics(ctx, instanceID, check)
+
+		// Log only changes so a missing permission doesn't flood the log
+		p.lock.Lock()
+		failing := p.metricsFailing
+		p.metricsFailing = err != nil
+		p.lock.Unlock()
+		if err != nil && !failing {
+			logger().Warn("Failed to publish CloudWatch metrics", "error", err)
+		} else if err == nil && failing {
+			logger().Info("Publishing CloudWatch metrics again")
+		}
+	}()
+}
+
+// putMetrics sends IdleSeconds, WillSnooze and resource utilization for the
+// instance
+func (p *AWSProvider) putMetrics(ctx context.Context, instanceID string, check telemetry.Check) error {
+	client, err := p.getCloudWatchClient()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	dimensions := []cwtypes.Dimension{{Name: aws.String("InstanceId"), Value: aws.String(instanceID)}}
+	datum := func(name string, value float64, unit cwtypes.StandardUnit) cwtypes.MetricDatum {
+		return cwtypes.MetricDatum{
+			MetricName: aws.String(name),
+			Dimensions: dimensions,
+			Timestamp:  aws.Time(now),
+			Value:      aws.Float64(value),
+			Unit:       unit,
+		}
+	}
+
+	willSnooze := 0.0
+	if check.Outcome == telemetry.OutcomePending || check.Outcome == telemetry.OutcomeSnooze {
+		willSnooze = 1
+	}
+	data := []cwtypes.MetricDatum{
+		datum("IdleSeconds", check.Idle.Seconds(), cwtypes.StandardUnitSeconds),
+		datum("WillSnooze", willSnooze, cwtypes.StandardUnitCount),
+	}
+	for resource, value := range check.Usage {
+		if metric, ok := resourceMetrics[resource]; ok {
+			data = append(data, datum(metric.name, value, metric.unit))
+		}
+	}
+
+	namespace := p.config.MetricsNamespace
+	if namespace == "" {
+		namespace = DefaultMetricsNamespace
+	}
+	_, err = client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+		Namespace:  aws.String(namespace),
+		MetricData: data,
+	})
+	if err != nil {
+		return fmt.Errorf("error publishing CloudWatch metrics: %v", err)
+	}
+	return nil
+}
+
+// getCloudWatchClient returns the CloudWatch client, creating it on first use
+func (p *AWSProvider) getCloudWatchClient() (cloudWatchAPI, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.cwClient == nil {
+		cfg, err := p.loadAWSConfig(p.ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.cwClient = cloudwatch.NewFromConfig(cfg)
+	}
+	return p.cwClient, nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// tagReadBudget is how long a tag read may take once the tags are cached.
+// See docs/testing/performance.md.
+const tagReadBudget = time.Millisecond
+
+func newBenchTagProvider() (*AWSProvider, *fakeTags) {
+	client := &fakeTags{}
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze"})
+	provider.instanceID = "i-0abc"
+	provider.tagClient = client
+	return provider, client
+}
+
+func TestTagReadBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping perf budget in short mode")
+	}
+	provider, client := newBenchTagProvider()
+	provider.GetExternalTags()
+
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		provider.GetExternalTags()
+	}
+	if took := time.Since(start) / 100; took > tagReadBudget {
+		t.Errorf("Cached tag read took %v, over the budget of %v", took, tagReadBudget)
+	}
+	if client.describes != 1 {
+		t.Errorf("Expected cached reads not to call DescribeTags, got %d calls", client.describes)
+	}
+}
+
+func BenchmarkTagRead(b *testing.B) {
+	provider, _ := newBenchTagProvider()
+	provider.GetExternalTags()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		provider.GetExternalTags()
+	}
+}
+
+func BenchmarkStopTags(b *testing.B) {
+	provider, _ := newBenchTagProvider()
+	provider.GetExternalTags()
+	tags := map[string]string{
+		"CloudSnooze:reason": "idle",
+		"CloudSnooze:time":   "2025-05-01T18:42:10Z",
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		provider.queueTags(map[string]string{"CloudSnooze:owner": "ci"})
+		if err := provider.writeTags(context.Background(), tags); err != nil {
+			b.Fatalf("writeTags failed: %v", err)
+		}
+	}
+}
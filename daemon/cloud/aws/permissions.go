@@ -0,0 +1,240 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// stsAPI is the subset of the STS client used to identify the caller
+type stsAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// iamAPI is the subset of the IAM client used to simulate policies
+type iamAPI interface {
+	GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+	SimulatePrincipalPolicy(ctx context.Context, params *iam.SimulatePrincipalPolicyInput, optFns ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error)
+}
+
+// ec2ProbeAPI is the subset of the EC2 client used to probe permissions
+type ec2ProbeAPI interface {
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error)
+	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+}
+
+// requiredActions returns the EC2 actions the provider needs on the instance
+func (p *AWSProvider) requiredActions() []string {
+	actions := []string{"ec2:StopInstances"}
+	if p.config.EnableTags {
+		actions = append(actions, "ec2:CreateTags")
+	}
+	return actions
+}
+
+// VerifyPermissions checks if the current AWS credentials have the required
+// permissions on this instance. The IAM policy simulator is used if the
+// credentials may call it; otherwise the EC2 API is probed with dry runs.
+func (p *AWSProvider) VerifyPermissions() (bool, error) {
+	ctx := p.context()
+
+	instanceID, err := p.getInstanceID()
+	if err != nil {
+		return false, err
+	}
+
+	if err := p.initIdentityClients(); err != nil {
+		return false, err
+	}
+
+	missing, err := p.simulatePermissions(ctx, instanceID)
+	if err != nil {
+		logger().Debug("IAM policy simulation unavailable, probing EC2 permissions", "error", err)
+		client, err := p.getEC2Client()
+		if err != nil {
+			return false, err
+		}
+		missing, err = p.probePermissions(ctx, client, instanceID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if len(missing) > 0 {
+		return false, fmt.Errorf("missing permissions on instance %s: %s", instanceID, strings.Join(missing, ", "))
+	}
+	return true, nil
+}
+
+// initIdentityClients creates the STS and IAM clients on first use
+func (p *AWSProvider) initIdentityClients() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.stsClient != nil && p.iamClient != nil {
+		return nil
+	}
+	cfg, err := p.loadAWSConfig(p.ctx)
+	if err != nil {
+		return err
+	}
+	if p.stsClient == nil {
+		p.stsClient = sts.NewFromConfig(cfg)
+	}
+	if p.iamClient == nil {
+		p.iamClient = iam.NewFromConfig(cfg)
+	}
+	return nil
+}
+
+// simulatePermissions asks the IAM policy simulator which required actions
+// the caller is not allowed to perform on the instance
+func (p *AWSProvider) simulatePermissions(ctx context.Context, instanceID string) ([]string, error) {
+	identity, err := p.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("error getting caller identity: %v", err)
+	}
+	callerARN := aws.ToString(identity.Arn)
+
+	// An assumed role may belong to another account than the instance
+	account, err := p.instanceAccount()
+	if err != nil {
+		account = aws.ToString(identity.Account)
+	}
+
+	principalARN, roleName := principalFromCaller(callerARN)
+	if roleName != "" {
+		// The assumed-role ARN doesn't include the role's path, so look
+		// up its real ARN if allowed
+		if role, err := p.iamClient.GetRole(ctx, &iam.GetRoleInput{RoleName: aws.String(roleName)}); err == nil && role.Role != nil {
+			principalARN = aws.ToString(role.Role.Arn)
+		}
+	}
+
+	p.lock.RLock()
+	region := p.region
+	p.lock.RUnlock()
+	if region == "" {
+		region = p.config.Region
+	}
+	instanceARN := fmt.Sprintf("arn:%s:ec2:%s:%s:instance/%s", p.partition(), region, account, instanceID)
+
+	result, err := p.iamClient.SimulatePrincipalPolicy(ctx, &iam.SimulatePrincipalPolicyInput{
+		PolicySourceArn: aws.String(principalARN),
+		ActionNames:     p.requiredActions(),
+		ResourceArns:    []string{instanceARN},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error simulating policy for %s: %v", principalARN, err)
+	}
+
+	var missing []string
+	for _, evaluation := range result.EvaluationResults {
+		if evaluation.EvalDecision != "allowed" {
+			missing = append(missing, fmt.Sprintf("%s (%s)", aws.ToString(evaluation.EvalActionName), evaluation.EvalDecision))
+		}
+	}
+	return missing, nil
+}
+
+// probePermissions checks the required actions with dry-run EC2 calls,
+// which are authorized like real calls but don't change anything
+func (p *AWSProvider) probePermissions(ctx context.Context, client ec2ProbeAPI, instanceID string) ([]string, error) {
+	_, err := client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error checking EC2 permissions: %v", err)
+	}
+
+	var missing []string
+	_, err = client.StopInstances(ctx, &ec2.StopInstancesInput{
+		InstanceIds: []string{instanceID},
+		DryRun:      aws.Bool(true),
+	})
+	if allowed, err := dryRunAllowed(err); err != nil {
+		return nil, fmt.Errorf("error checking stop permissions: %v", err)
+	} else if !allowed {
+		missing = append(missing, "ec2:StopInstances")
+	}
+
+	if p.config.EnableTags {
+		_, err = client.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: []string{instanceID},
+			Tags: []types.Tag{
+				{
+					Key:   aws.String(p.config.TaggingPrefix + ":permission-check"),
+					Value: aws.String("dry-run"),
+				},
+			},
+			DryRun: aws.Bool(true),
+		})
+		if allowed, err := dryRunAllowed(err); err != nil {
+			return nil, fmt.Errorf("error checking tag permissions: %v", err)
+		} else if !allowed {
+			missing = append(missing, "ec2:CreateTags")
+		}
+	}
+	return missing, nil
+}
+
+// dryRunAllowed interprets the result of a dry-run call. A successful dry
+// run fails with DryRunOperation; a denied one with UnauthorizedOperation.
+func dryRunAllowed(err error) (bool, error) {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "DryRunOperation":
+			return true, nil
+		case "UnauthorizedOperation":
+			return false, nil
+		}
+	}
+	if err == nil {
+		return true, nil
+	}
+	return false, err
+}
+
+// instanceAccount returns the ID of the account the instance runs in
+func (p *AWSProvider) instanceAccount() (string, error) {
+	data, err := p.metadata.get("identity-credentials/ec2/info")
+	if err != nil {
+		return "", err
+	}
+	var info struct {
+		AccountId string
+	}
+	if err := json.Unmarshal([]byte(data), &info); err != nil || info.AccountId == "" {
+		return "", fmt.Errorf("error reading instance account: %v", err)
+	}
+	return info.AccountId, nil
+}
+
+// principalFromCaller converts a caller ARN into the IAM principal the
+// simulator expects. For assumed roles it also returns the role name.
+func principalFromCaller(callerARN string) (string, string) {
+	// arn:aws:sts::123456789012:assumed-role/RoleName/SessionName
+	parts := strings.SplitN(callerARN, ":", 6)
+	if len(parts) != 6 || parts[2] != "sts" {
+		return callerARN, ""
+	}
+	resource := strings.Split(parts[5], "/")
+	if len(resource) < 2 || resource[0] != "assumed-role" {
+		return callerARN, ""
+	}
+	return fmt.Sprintf("arn:%s:iam::%s:role/%s", parts[1], parts[4], resource[1]), resource[1]
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	iamtypes "github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// fakeSTS returns a fixed caller identity
+type fakeSTS struct {
+	arn string
+}
+
+func (f *fakeSTS) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{Arn: aws.String(f.arn), Account: aws.String("123456789012")}, nil
+}
+
+// fakeIAM denies the listed actions and records the simulation input
+type fakeIAM struct {
+	denied map[string]bool
+	input  *iam.SimulatePrincipalPolicyInput
+}
+
+func (f *fakeIAM) GetRole(ctx context.Context, params *iam.GetRoleInput, optFns ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	return nil, fmt.Errorf("AccessDenied")
+}
+
+func (f *fakeIAM) SimulatePrincipalPolicy(ctx context.Context, params *iam.SimulatePrincipalPolicyInput, optFns ...func(*iam.Options)) (*iam.SimulatePrincipalPolicyOutput, error) {
+	f.input = params
+	output := &iam.SimulatePrincipalPolicyOutput{}
+	for _, action := range params.ActionNames {
+		decision := iamtypes.PolicyEvaluationDecisionTypeAllowed
+		if f.denied[action] {
+			decision = iamtypes.PolicyEvaluationDecisionTypeImplicitDeny
+		}
+		output.EvaluationResults = append(output.EvaluationResults, iamtypes.EvaluationResult{
+			EvalActionName: aws.String(action),
+			EvalDecision:   decision,
+		})
+	}
+	return output, nil
+}
+
+// fakeEC2Probe answers dry runs, denying the listed actions
+type fakeEC2Probe struct {
+	denied map[string]bool
+}
+
+func (f *fakeEC2Probe) dryRun(action string) error {
+	if f.denied[action] {
+		return &smithy.GenericAPIError{Code: "UnauthorizedOperation"}
+	}
+	return &smithy.GenericAPIError{Code: "DryRunOperation"}
+}
+
+func (f *fakeEC2Probe) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	return &ec2.DescribeInstancesOutput{}, nil
+}
+
+func (f *fakeEC2Probe) StopInstances(ctx context.Context, params *ec2.StopInstancesInput, optFns ...func(*ec2.Options)) (*ec2.StopInstancesOutput, error) {
+	if !aws.ToBool(params.DryRun) {
+		return nil, fmt.Errorf("expected a dry run")
+	}
+	return nil, f.dryRun("ec2:StopInstances")
+}
+
+func (f *fakeEC2Probe) CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	if !aws.ToBool(params.DryRun) {
+		return nil, fmt.Errorf("expected a dry run")
+	}
+	return nil, f.dryRun("ec2:CreateTags")
+}
+
+func TestSimulatePermissionsUnit(t *testing.T) {
+	// The instance runs in a member account, the role is in a central one
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/api/token":
+			w.Write([]byte("token"))
+		case "/latest/meta-data/identity-credentials/ec2/info":
+			w.Write([]byte(`{"Code":"Success","AccountId":"210987654321"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	iamClient := &fakeIAM{denied: map[string]bool{"ec2:CreateTags": true}}
+	provider := NewProvider(Config{Region: "us-gov-west-1", EnableTags: true, MetadataEndpoint: server.URL})
+	provider.stsClient = &fakeSTS{arn: "arn:aws-us-gov:sts::123456789012:assumed-role/snooze-role/i-0abc"}
+	provider.iamClient = iamClient
+
+	missing, err := provider.simulatePermissions(context.Background(), "i-0abc")
+	if err != nil {
+		t.Fatalf("simulatePermissions failed: %v", err)
+	}
+	if len(missing) != 1 || !strings.HasPrefix(missing[0], "ec2:CreateTags") {
+		t.Errorf("Expected ec2:CreateTags to be missing, got %v", missing)
+	}
+
+	if arn := aws.ToString(iamClient.input.PolicySourceArn); arn != "arn:aws-us-gov:iam::123456789012:role/snooze-role" {
+		t.Errorf("Unexpected principal %s", arn)
+	}
+	if arns := iamClient.input.ResourceArns; len(arns) != 1 || arns[0] != "arn:aws-us-gov:ec2:us-gov-west-1:210987654321:instance/i-0abc" {
+		t.Errorf("Unexpected resources %v", arns)
+	}
+}
+
+func TestProbePermissionsUnit(t *testing.T) {
+	provider := NewProvider(Config{EnableTags: true, TaggingPrefix: "cloudsnooze"})
+
+	missing, err := provider.probePermissions(context.Background(), &fakeEC2Probe{}, "i-0abc")
+	if err != nil || len(missing) != 0 {
+		t.Errorf("Expected all permissions, got %v, %v", missing, err)
+	}
+
+	client := &fakeEC2Probe{denied: map[string]bool{"ec2:StopInstances": true}}
+	missing, err = provider.probePermissions(context.Background(), client, "i-0abc")
+	if err != nil || len(missing) != 1 || missing[0] != "ec2:StopInstances" {
+		t.Errorf("Expected ec2:StopInstances to be missing, got %v, %v", missing, err)
+	}
+}
+
+func TestPrincipalFromCaller(t *testing.T) {
+	arn, role := principalFromCaller("arn:aws:iam::123456789012:user/alice")
+	if arn != "arn:aws:iam::123456789012:user/alice" || role != "" {
+		t.Errorf("Expected user ARN unchanged, got %s %s", arn, role)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	pricingtypes "github.com/aws/aws-sdk-go-v2/service/pricing/types"
+)
+
+// priceTTL is how long a cached price is used before it is looked up again
+const priceTTL = 7 * 24 * time.Hour
+
+// pricingAPI is the subset of the Pricing client used by the provider
+type pricingAPI interface {
+	GetProducts(ctx context.Context, params *pricing.GetProductsInput, optFns ...func(*pricing.Options)) (*pricing.GetProductsOutput, error)
+}
+
+// priceEntry is a cached on-demand price
+type priceEntry struct {
+	USDPerHour float64   `json:"usd_per_hour"`
+	Fetched    time.Time `json:"fetched"`
+}
+
+// HourlyPrice returns the on-demand price per hour of the instance in USD,
+// from the disk cache if it was looked up recently
+func (p *AWSProvider) HourlyPrice() (float64, error) {
+	info, err := p.GetInstanceInfo()
+	if err != nil {
+		return 0, err
+	}
+	operatingSystem := "Linux"
+	if runtime.GOOS == "windows" {
+		operatingSystem = "Windows"
+	}
+	key := fmt.Sprintf("%s/%s/%s", info.Region, info.Type, operatingSystem)
+
+	cache := loadPriceCache(p.config.PricingCachePath)
+	if entry, ok := cache[key]; ok && time.Since(entry.Fetched) < priceTTL {
+		return entry.USDPerHour, nil
+	}
+
+	client, err := p.getPricingClient()
+	if err != nil {
+		return 0, err
+	}
+	ctx, cancel := context.WithTimeout(p.context(), 30*time.Second)
+	defer cancel()
+	price, err := lookupPrice(ctx, client, info.Region, info.Type, operatingSystem)
+	if err != nil {
+		return 0, err
+	}
+
+	cache[key] = priceEntry{USDPerHour: price, Fetched: time.Now()}
+	if err := savePriceCache(p.config.PricingCachePath, cache); err != nil {
+		logger().Warn("Failed to cache instance price", "error", err)
+	}
+	return price, nil
+}
+
+// lookupPrice finds the on-demand price of an instance type in a region
+func lookupPrice(ctx context.Context, client pricingAPI, region, instanceType, operatingSystem string) (float64, error) {
+	filter := func(field, value string) pricingtypes.Filter {
+		return pricingtypes.Filter{
+			Type:  pricingtypes.FilterTypeTermMatch,
+			Field: aws.String(field),
+			Value: aws.String(value),
+		}
+	}
+
+	output, err := client.GetProducts(ctx, &pricing.GetProductsInput{
+		ServiceCode: aws.String("AmazonEC2"),
+		Filters: []pricingtypes.Filter{
+			filter("instanceType", instanceType),
+			filter("regionCode", region),
+			filter("operatingSystem", operatingSystem),
+			filter("tenancy", "Shared"),
+			filter("preInstalledSw", "NA"),
+			filter("capacitystatus", "Used"),
+			filter("licenseModel", "No License required"),
+		},
+		MaxResults: aws.Int32(10),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("error getting price of %s in %s: %v", instanceType, region, err)
+	}
+
+	for _, product := range output.PriceList {
+		if price, err := onDemandPrice(product); err == nil {
+			return price, nil
+		}
+	}
+	return 0, fmt.Errorf("no on-demand price found for %s in %s", instanceType, region)
+}
+
+// onDemandPrice extracts the hourly USD price from a price list entry
+func onDemandPrice(product string) (float64, error) {
+	var entry struct {
+		Terms struct {
+			OnDemand map[string]struct {
+				PriceDimensions map[string]struct {
+					Unit         string            `json:"unit"`
+					PricePerUnit map[string]string `json:"pricePerUnit"`
+				} `json:"priceDimensions"`
+			} `json:"OnDemand"`
+		} `json:"terms"`
+	}
+	if err := json.Unmarshal([]byte(product), &entry); err != nil {
+		return 0, err
+	}
+
+	for _, term := range entry.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			if dimension.Unit != "Hrs" {
+				continue
+			}
+			price, err := strconv.ParseFloat(dimension.PricePerUnit["USD"], 64)
+			if err == nil && price > 0 {
+				return price, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no hourly price in price list entry")
+}
+
+// getPricingClient returns the Pricing client, creating it on first use. The
+// API is only served from a few regions.
+func (p *AWSProvider) getPricingClient() (pricingAPI, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.pricingClient == nil {
+		cfg, err := p.loadAWSConfig(p.ctx)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Region = "us-east-1"
+		if partitionForRegion(p.region) == "aws-cn" {
+			cfg.Region = "cn-northwest-1"
+		}
+		p.pricingClient = pricing.NewFromConfig(cfg)
+	}
+	return p.pricingClient, nil
+}
+
+// loadPriceCache reads cached prices, returning an empty cache if there are none
+func loadPriceCache(path string) map[string]priceEntry {
+	cache := make(map[string]priceEntry)
+	if path == "" {
+		return cache
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		logger().Warn("Ignoring unreadable price cache", "path", path, "error", err)
+		return make(map[string]priceEntry)
+	}
+	return cache
+}
+
+// savePriceCache writes the cached prices
+func savePriceCache(path string, cache map[string]priceEntry) error {
+	if path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,60 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+)
+
+// priceListEntry is a trimmed-down price list product for an m5.large
+const priceListEntry = `{
+	"product": {"attributes": {"instanceType": "m5.large", "regionCode": "us-east-1"}},
+	"terms": {"OnDemand": {"ABC.JRTCKXETXF": {"priceDimensions": {
+		"ABC.JRTCKXETXF.6YS6EN2CT7": {"unit": "Hrs", "pricePerUnit": {"USD": "0.0960000000"}}
+	}}}}
+}`
+
+// fakePricing returns a fixed price list, counting requests
+type fakePricing struct {
+	requests int
+}
+
+func (f *fakePricing) GetProducts(ctx context.Context, params *pricing.GetProductsInput, optFns ...func(*pricing.Options)) (*pricing.GetProductsOutput, error) {
+	f.requests++
+	return &pricing.GetProductsOutput{PriceList: []string{priceListEntry}}, nil
+}
+
+func TestHourlyPriceCached(t *testing.T) {
+	client := &fakePricing{}
+	path := filepath.Join(t.TempDir(), "pricing.json")
+
+	newProvider := func() *AWSProvider {
+		provider := NewProvider(Config{PricingCachePath: path})
+		provider.instanceID = "i-0abc"
+		provider.instanceType = "m5.large"
+		provider.region = "us-east-1"
+		provider.pricingClient = client
+		return provider
+	}
+
+	price, err := newProvider().HourlyPrice()
+	if err != nil {
+		t.Fatalf("HourlyPrice failed: %v", err)
+	}
+	if price != 0.096 {
+		t.Errorf("Expected $0.096/hour, got %v", price)
+	}
+
+	// A restarted daemon uses the price cached on disk
+	if price, err := newProvider().HourlyPrice(); err != nil || price != 0.096 {
+		t.Errorf("Expected the cached price, got %v, %v", price, err)
+	}
+	if client.requests != 1 {
+		t.Errorf("Expected one price lookup, got %d", client.requests)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/aws/smithy-go"
+	snoozeerrors "github.com/scttfrdmn/cloudsnooze/pkg/errors"
+)
+
+const (
+	// DefaultRetryAttempts is how many times an API call is tried by default
+	DefaultRetryAttempts = 4
+
+	// Bounds of the delay between attempts
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 20 * time.Second
+)
+
+// Error codes by how the failure is classified
+var (
+	throttlingCodes = map[string]bool{
+		"Throttling":                             true,
+		"ThrottlingException":                    true,
+		"RequestLimitExceeded":                   true,
+		"RequestThrottled":                       true,
+		"RequestThrottledException":              true,
+		"TooManyRequestsException":               true,
+		"EC2ThrottledException":                  true,
+		"ProvisionedThroughputExceededException": true,
+		"PriorRequestNotComplete":                true,
+	}
+	permissionCodes = map[string]bool{
+		"UnauthorizedOperation": true,
+		"AccessDenied":          true,
+		"AccessDeniedException": true,
+		"AuthFailure":           true,
+		"ExpiredToken":          true,
+		"InvalidClientTokenId":  true,
+	}
+	unavailableCodes = map[string]bool{
+		"InternalError":      true,
+		"InternalFailure":    true,
+		"ServiceUnavailable": true,
+		"Unavailable":        true,
+		"RequestTimeout":     true,
+	}
+)
+
+// classifyError wraps an API error in an error whose type tells whether it
+// is worth retrying
+func classifyError(err error, message string) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code := apiErr.ErrorCode()
+		switch {
+		case throttlingCodes[code]:
+			return snoozeerrors.Wrap(err, snoozeerrors.ErrorTypeThrottling, message)
+		case permissionCodes[code]:
+			return snoozeerrors.Wrap(err, snoozeerrors.ErrorTypePermission, message)
+		case unavailableCodes[code]:
+			return snoozeerrors.Wrap(err, snoozeerrors.ErrorTypeNetwork, message)
+		}
+		return snoozeerrors.Wrap(err, snoozeerrors.ErrorTypeCloud, message)
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return snoozeerrors.Wrap(err, snoozeerrors.ErrorTypeNetwork, message)
+	}
+	return snoozeerrors.Wrap(err, snoozeerrors.ErrorTypeCloud, message)
+}
+
+// retryable returns true if a classified error may succeed when tried again
+func retryable(err error) bool {
+	return snoozeerrors.IsType(err, snoozeerrors.ErrorTypeThrottling) ||
+		snoozeerrors.IsType(err, snoozeerrors.ErrorTypeNetwork)
+}
+
+// retry calls fn until it succeeds, fails with an error that isn't worth
+// retrying, or runs out of attempts. Attempts are spaced by an exponential
+// backoff with full jitter, so instances throttled together don't retry in
+// step. The returned error is classified; message describes the operation.
+func (p *AWSProvider) retry(ctx context.Context, message string, fn func(ctx context.Context) error) error {
+	attempts := p.config.RetryAttempts
+	if attempts <= 0 {
+		attempts = DefaultRetryAttempts
+	}
+	base := p.retryDelay
+	if base <= 0 {
+		base = retryBaseDelay
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		err = classifyError(err, message)
+		if !retryable(err) || attempt >= attempts || ctx.Err() != nil {
+			return err
+		}
+
+		backoff := base << (attempt - 1)
+		if backoff <= 0 || backoff > retryMaxDelay {
+			backoff = retryMaxDelay
+		}
+		delay := time.Duration(rand.Int63n(int64(backoff)) + 1)
+		logger().Warn("Retrying AWS call", "operation", message, "attempt", attempt, "delay", delay.String(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	snoozeerrors "github.com/scttfrdmn/cloudsnooze/pkg/errors"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		code     string
+		expected snoozeerrors.ErrorType
+	}{
+		{"RequestLimitExceeded", snoozeerrors.ErrorTypeThrottling},
+		{"UnauthorizedOperation", snoozeerrors.ErrorTypePermission},
+		{"ServiceUnavailable", snoozeerrors.ErrorTypeNetwork},
+		{"IncorrectInstanceState", snoozeerrors.ErrorTypeCloud},
+	}
+	for _, test := range tests {
+		err := classifyError(&smithy.GenericAPIError{Code: test.code}, "error stopping instance")
+		if !snoozeerrors.IsType(err, test.expected) {
+			t.Errorf("%s: expected error type %d, got %v", test.code, test.expected, err)
+		}
+	}
+}
+
+func TestRetry(t *testing.T) {
+	p := &AWSProvider{config: Config{RetryAttempts: 3}, retryDelay: time.Millisecond}
+	ctx := context.Background()
+
+	// Throttling is retried until the call succeeds
+	calls := 0
+	err := p.retry(ctx, "error stopping instance", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return &smithy.GenericAPIError{Code: "RequestLimitExceeded"}
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success on the third call, got %v after %d calls", err, calls)
+	}
+
+	// Attempts are limited
+	calls = 0
+	err = p.retry(ctx, "error stopping instance", func(ctx context.Context) error {
+		calls++
+		return &smithy.GenericAPIError{Code: "Throttling"}
+	})
+	if !snoozeerrors.IsType(err, snoozeerrors.ErrorTypeThrottling) || calls != 3 {
+		t.Errorf("Expected a throttling error after 3 calls, got %v after %d calls", err, calls)
+	}
+
+	// Permission errors fail at once, keeping the original error
+	calls = 0
+	denied := &smithy.GenericAPIError{Code: "UnauthorizedOperation"}
+	err = p.retry(ctx, "error stopping instance", func(ctx context.Context) error {
+		calls++
+		return denied
+	})
+	if !snoozeerrors.IsType(err, snoozeerrors.ErrorTypePermission) || !errors.Is(err, denied) || calls != 1 {
+		t.Errorf("Expected a permission error after 1 call, got %v after %d calls", err, calls)
+	}
+
+	// Cancelling the context stops retrying
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	calls = 0
+	p.retry(cancelled, "error stopping instance", func(ctx context.Context) error {
+		calls++
+		return &smithy.GenericAPIError{Code: "Throttling"}
+	})
+	if calls != 1 {
+		t.Errorf("Expected no retries after cancellation, got %d calls", calls)
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// DefaultDNSTTL is the TTL of the record when none is configured
+const DefaultDNSTTL = 60
+
+// route53API is the subset of the Route 53 client used by the provider
+type route53API interface {
+	ListResourceRecordSets(ctx context.Context, params *route53.ListResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error)
+	ChangeResourceRecordSets(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error)
+}
+
+// dnsEnabled reports whether a record follows the instance
+func (p *AWSProvider) dnsEnabled() bool {
+	return p.config.DNSZoneID != "" && p.config.DNSRecordName != ""
+}
+
+// restoreDNS points the record at the instance, which may have a new
+// public address after being stopped
+func (p *AWSProvider) restoreDNS(ctx context.Context) error {
+	path := "public-ipv4"
+	if p.config.DNSPrivateIP {
+		path = "local-ipv4"
+	}
+	address, err := p.metadata.get(path)
+	if err != nil {
+		return fmt.Errorf("error getting instance address: %v", err)
+	}
+	if err := p.changeRecord(ctx, r53types.ChangeActionUpsert, address); err != nil {
+		return err
+	}
+	logger().Info("Pointed DNS record at instance", "record", p.config.DNSRecordName, "address", address)
+	return nil
+}
+
+// parkDNS points the record at the parked address, or deletes it if there
+// is none, so clients get a clear answer while the instance is stopped
+func (p *AWSProvider) parkDNS(ctx context.Context) error {
+	if p.config.DNSParkedIP != "" {
+		if err := p.changeRecord(ctx, r53types.ChangeActionUpsert, p.config.DNSParkedIP); err != nil {
+			return err
+		}
+		logger().Info("Parked DNS record", "record", p.config.DNSRecordName, "address", p.config.DNSParkedIP)
+		return nil
+	}
+
+	// A delete must match the record exactly
+	record, err := p.findRecord(ctx)
+	if err != nil || record == nil {
+		return err
+	}
+	client, err := p.getRoute53Client()
+	if err != nil {
+		return err
+	}
+	err = p.retry(ctx, "error deleting DNS record", func(ctx context.Context) error {
+		_, err := client.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(p.config.DNSZoneID),
+			ChangeBatch: &r53types.ChangeBatch{
+				Comment: aws.String("CloudSnooze: instance stopped"),
+				Changes: []r53types.Change{{Action: r53types.ChangeActionDelete, ResourceRecordSet: record}},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logger().Info("Deleted DNS record", "record", p.config.DNSRecordName)
+	return nil
+}
+
+// changeRecord creates or updates the A record
+func (p *AWSProvider) changeRecord(ctx context.Context, action r53types.ChangeAction, address string) error {
+	client, err := p.getRoute53Client()
+	if err != nil {
+		return err
+	}
+	ttl := p.config.DNSTTL
+	if ttl <= 0 {
+		ttl = DefaultDNSTTL
+	}
+	return p.retry(ctx, "error updating DNS record", func(ctx context.Context) error {
+		_, err := client.ChangeResourceRecordSets(ctx, &route53.ChangeResourceRecordSetsInput{
+			HostedZoneId: aws.String(p.config.DNSZoneID),
+			ChangeBatch: &r53types.ChangeBatch{
+				Comment: aws.String("CloudSnooze"),
+				Changes: []r53types.Change{{
+					Action: action,
+					ResourceRecordSet: &r53types.ResourceRecordSet{
+						Name:            aws.String(p.config.DNSRecordName),
+						Type:            r53types.RRTypeA,
+						TTL:             aws.Int64(int64(ttl)),
+						ResourceRecords: []r53types.ResourceRecord{{Value: aws.String(address)}},
+					},
+				}},
+			},
+		})
+		return err
+	})
+}
+
+// findRecord returns the A record, or nil if it doesn't exist
+func (p *AWSProvider) findRecord(ctx context.Context) (*r53types.ResourceRecordSet, error) {
+	client, err := p.getRoute53Client()
+	if err != nil {
+		return nil, err
+	}
+	var output *route53.ListResourceRecordSetsOutput
+	err = p.retry(ctx, "error finding DNS record", func(ctx context.Context) error {
+		var err error
+		output, err = client.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+			HostedZoneId:    aws.String(p.config.DNSZoneID),
+			StartRecordName: aws.String(p.config.DNSRecordName),
+			StartRecordType: r53types.RRTypeA,
+			MaxItems:        aws.Int32(1),
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Listing starts at the name, so the first record may be a later one
+	want := strings.TrimSuffix(strings.ToLower(p.config.DNSRecordName), ".")
+	for _, record := range output.ResourceRecordSets {
+		name := strings.TrimSuffix(strings.ToLower(aws.ToString(record.Name)), ".")
+		if name == want && record.Type == r53types.RRTypeA {
+			return &record, nil
+		}
+	}
+	return nil, nil
+}
+
+// getRoute53Client returns the Route 53 client, creating it on first use
+func (p *AWSProvider) getRoute53Client() (route53API, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.route53Client == nil {
+		cfg, err := p.loadAWSConfig(p.ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.route53Client = route53.NewFromConfig(cfg)
+	}
+	return p.route53Client, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	r53types "github.com/aws/aws-sdk-go-v2/service/route53/types"
+)
+
+// fakeRoute53 holds one zone's records by name
+type fakeRoute53 struct {
+	records map[string]r53types.ResourceRecordSet
+}
+
+func (f *fakeRoute53) ListResourceRecordSets(ctx context.Context, params *route53.ListResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error) {
+	output := &route53.ListResourceRecordSetsOutput{}
+	if record, ok := f.records[aws.ToString(params.StartRecordName)+"."]; ok {
+		output.ResourceRecordSets = []r53types.ResourceRecordSet{record}
+	}
+	return output, nil
+}
+
+func (f *fakeRoute53) ChangeResourceRecordSets(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	for _, change := range params.ChangeBatch.Changes {
+		name := aws.ToString(change.ResourceRecordSet.Name) + "."
+		switch change.Action {
+		case r53types.ChangeActionUpsert:
+			f.records[name] = *change.ResourceRecordSet
+		case r53types.ChangeActionDelete:
+			delete(f.records, name)
+		}
+	}
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+// recordAddress returns the address a record points at
+func (f *fakeRoute53) recordAddress(name string) string {
+	record, ok := f.records[name+"."]
+	if !ok || len(record.ResourceRecords) == 0 {
+		return ""
+	}
+	return aws.ToString(record.ResourceRecords[0].Value)
+}
+
+// fakeAddressIMDS serves the instance's public and private addresses
+func fakeAddressIMDS(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest/api/token":
+			w.Write([]byte("token"))
+		case "/latest/meta-data/public-ipv4":
+			w.Write([]byte("203.0.113.10"))
+		case "/latest/meta-data/local-ipv4":
+			w.Write([]byte("10.0.1.5"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDNSParkAndRestore(t *testing.T) {
+	imds := fakeAddressIMDS(t)
+	tests := []struct {
+		name     string
+		parkedIP string
+		private  bool
+		restored string
+		parked   string
+	}{
+		{"parked page", "198.51.100.1", false, "203.0.113.10", "198.51.100.1"},
+		{"deleted while stopped", "", true, "10.0.1.5", ""},
+	}
+	for _, tt := range tests {
+		client := &fakeRoute53{records: map[string]r53types.ResourceRecordSet{}}
+		provider := NewProvider(Config{
+			MetadataEndpoint: imds.URL,
+			DNSZoneID:        "Z123",
+			DNSRecordName:    "dev.example.com",
+			DNSParkedIP:      tt.parkedIP,
+			DNSPrivateIP:     tt.private,
+		})
+		provider.route53Client = client
+
+		if err := provider.restoreDNS(context.Background()); err != nil {
+			t.Fatalf("%s: restoreDNS failed: %v", tt.name, err)
+		}
+		if address := client.recordAddress("dev.example.com"); address != tt.restored {
+			t.Errorf("%s: expected the record to point at %q, got %q", tt.name, tt.restored, address)
+		}
+		if ttl := aws.ToInt64(client.records["dev.example.com."].TTL); ttl != DefaultDNSTTL {
+			t.Errorf("%s: expected the default TTL, got %d", tt.name, ttl)
+		}
+
+		if err := provider.parkDNS(context.Background()); err != nil {
+			t.Fatalf("%s: parkDNS failed: %v", tt.name, err)
+		}
+		if address := client.recordAddress("dev.example.com"); address != tt.parked {
+			t.Errorf("%s: expected the parked record to point at %q, got %q", tt.name, tt.parked, address)
+		}
+	}
+}
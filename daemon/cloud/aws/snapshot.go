@@ -0,0 +1,119 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// ec2SnapshotAPI is the subset of the EC2 client used to snapshot volumes
+type ec2SnapshotAPI interface {
+	CreateSnapshots(ctx context.Context, params *ec2.CreateSnapshotsInput, optFns ...func(*ec2.Options)) (*ec2.CreateSnapshotsOutput, error)
+	DescribeSnapshots(ctx context.Context, params *ec2.DescribeSnapshotsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error)
+	DeleteSnapshot(ctx context.Context, params *ec2.DeleteSnapshotInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSnapshotOutput, error)
+}
+
+// SnapshotVolumes starts crash-consistent snapshots of the instance's EBS
+// volumes, which are taken at the moment they start, so the instance can
+// stop straight away. Only the newest keep snapshots of each volume taken
+// this way are kept (0 keeps them all). It returns the new snapshot IDs.
+func (p *AWSProvider) SnapshotVolumes(ctx context.Context, description string, keep int) ([]string, error) {
+	instanceID, err := p.getInstanceID()
+	if err != nil {
+		return nil, fmt.Errorf("error getting instance ID: %v", err)
+	}
+	client, err := p.getEC2Client()
+	if err != nil {
+		return nil, err
+	}
+	return p.snapshotVolumes(ctx, client, instanceID, description, keep)
+}
+
+// snapshotVolumes snapshots the volumes of instanceID with client
+func (p *AWSProvider) snapshotVolumes(ctx context.Context, client ec2SnapshotAPI, instanceID, description string, keep int) ([]string, error) {
+	tag := p.config.TaggingPrefix + ":snooze_snapshot"
+	var output *ec2.CreateSnapshotsOutput
+	err := p.retry(ctx, "error creating snapshots", func(ctx context.Context) error {
+		var err error
+		output, err = client.CreateSnapshots(ctx, &ec2.CreateSnapshotsInput{
+			InstanceSpecification: &ec2types.InstanceSpecification{InstanceId: aws.String(instanceID)},
+			Description:           aws.String(description),
+			CopyTagsFromSource:    ec2types.CopyTagsFromSourceVolume,
+			TagSpecifications: []ec2types.TagSpecification{{
+				ResourceType: ec2types.ResourceTypeSnapshot,
+				Tags:         []ec2types.Tag{{Key: aws.String(tag), Value: aws.String(instanceID)}},
+			}},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(output.Snapshots))
+	for _, snapshot := range output.Snapshots {
+		ids = append(ids, aws.ToString(snapshot.SnapshotId))
+	}
+	logger().Info("Started volume snapshots", "snapshots", ids)
+
+	if keep > 0 {
+		p.pruneSnapshots(ctx, client, tag, instanceID, keep)
+	}
+	return ids, nil
+}
+
+// pruneSnapshots deletes all but the newest keep snooze snapshots of each
+// of the instance's volumes. Failures are logged, since the new snapshots
+// have been taken either way.
+func (p *AWSProvider) pruneSnapshots(ctx context.Context, client ec2SnapshotAPI, tag, instanceID string, keep int) {
+	byVolume := make(map[string][]ec2types.Snapshot)
+	input := &ec2.DescribeSnapshotsInput{
+		OwnerIds: []string{"self"},
+		Filters:  []ec2types.Filter{{Name: aws.String("tag:" + tag), Values: []string{instanceID}}},
+	}
+	for {
+		var output *ec2.DescribeSnapshotsOutput
+		err := p.retry(ctx, "error listing snapshots", func(ctx context.Context) error {
+			var err error
+			output, err = client.DescribeSnapshots(ctx, input)
+			return err
+		})
+		if err != nil {
+			logger().Warn("Failed to list old snapshots", "error", err)
+			return
+		}
+		for _, snapshot := range output.Snapshots {
+			volume := aws.ToString(snapshot.VolumeId)
+			byVolume[volume] = append(byVolume[volume], snapshot)
+		}
+		if output.NextToken == nil {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	for volume, snapshots := range byVolume {
+		sort.Slice(snapshots, func(i, j int) bool {
+			return aws.ToTime(snapshots[i].StartTime).After(aws.ToTime(snapshots[j].StartTime))
+		})
+		for _, snapshot := range snapshots[min(keep, len(snapshots)):] {
+			id := aws.ToString(snapshot.SnapshotId)
+			err := p.retry(ctx, "error deleting snapshot", func(ctx context.Context) error {
+				_, err := client.DeleteSnapshot(ctx, &ec2.DeleteSnapshotInput{SnapshotId: aws.String(id)})
+				return err
+			})
+			if err != nil {
+				logger().Warn("Failed to delete old snapshot", "snapshot", id, "volume", volume, "error", err)
+				continue
+			}
+			logger().Info("Deleted old snapshot", "snapshot", id, "volume", volume)
+		}
+	}
+}
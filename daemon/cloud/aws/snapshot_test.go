@@ -0,0 +1,104 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// fakeSnapshots holds the snooze snapshots of an instance with two volumes
+type fakeSnapshots struct {
+	snapshots []ec2types.Snapshot
+	created   *ec2.CreateSnapshotsInput
+	filter    string
+	deleted   []string
+}
+
+func (f *fakeSnapshots) CreateSnapshots(ctx context.Context, params *ec2.CreateSnapshotsInput, optFns ...func(*ec2.Options)) (*ec2.CreateSnapshotsOutput, error) {
+	f.created = params
+	now := time.Now()
+	output := &ec2.CreateSnapshotsOutput{}
+	for _, volume := range []string{"vol-root", "vol-data"} {
+		snapshot := ec2types.Snapshot{SnapshotId: aws.String("snap-new-" + volume), VolumeId: aws.String(volume), StartTime: aws.Time(now)}
+		f.snapshots = append(f.snapshots, snapshot)
+		output.Snapshots = append(output.Snapshots, ec2types.SnapshotInfo{SnapshotId: snapshot.SnapshotId, VolumeId: snapshot.VolumeId})
+	}
+	return output, nil
+}
+
+func (f *fakeSnapshots) DescribeSnapshots(ctx context.Context, params *ec2.DescribeSnapshotsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSnapshotsOutput, error) {
+	f.filter = aws.ToString(params.Filters[0].Name) + "=" + params.Filters[0].Values[0]
+	// Return a page at a time to exercise pagination
+	if params.NextToken == nil {
+		return &ec2.DescribeSnapshotsOutput{Snapshots: f.snapshots[:2], NextToken: aws.String("page2")}, nil
+	}
+	return &ec2.DescribeSnapshotsOutput{Snapshots: f.snapshots[2:]}, nil
+}
+
+func (f *fakeSnapshots) DeleteSnapshot(ctx context.Context, params *ec2.DeleteSnapshotInput, optFns ...func(*ec2.Options)) (*ec2.DeleteSnapshotOutput, error) {
+	f.deleted = append(f.deleted, aws.ToString(params.SnapshotId))
+	return &ec2.DeleteSnapshotOutput{}, nil
+}
+
+// oldSnapshot returns a snapshot of volume taken age ago
+func oldSnapshot(id, volume string, age time.Duration) ec2types.Snapshot {
+	return ec2types.Snapshot{SnapshotId: aws.String(id), VolumeId: aws.String(volume), StartTime: aws.Time(time.Now().Add(-age))}
+}
+
+func TestSnapshotVolumes(t *testing.T) {
+	client := &fakeSnapshots{snapshots: []ec2types.Snapshot{
+		oldSnapshot("snap-root-1", "vol-root", 48*time.Hour),
+		oldSnapshot("snap-data-1", "vol-data", 48*time.Hour),
+		oldSnapshot("snap-root-2", "vol-root", 24*time.Hour),
+		oldSnapshot("snap-data-2", "vol-data", 24*time.Hour),
+	}}
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze"})
+
+	ids, err := provider.snapshotVolumes(context.Background(), client, "i-0abc", "Before snooze", 2)
+	if err != nil {
+		t.Fatalf("snapshotVolumes failed: %v", err)
+	}
+	if !slices.Equal(ids, []string{"snap-new-vol-root", "snap-new-vol-data"}) {
+		t.Errorf("Unexpected snapshot IDs %v", ids)
+	}
+
+	created := client.created
+	if aws.ToString(created.InstanceSpecification.InstanceId) != "i-0abc" || created.CopyTagsFromSource != ec2types.CopyTagsFromSourceVolume {
+		t.Errorf("Unexpected CreateSnapshots input %+v", created)
+	}
+	tag := created.TagSpecifications[0].Tags[0]
+	if aws.ToString(tag.Key) != "CloudSnooze:snooze_snapshot" || aws.ToString(tag.Value) != "i-0abc" {
+		t.Errorf("Unexpected snapshot tag %s=%s", aws.ToString(tag.Key), aws.ToString(tag.Value))
+	}
+	if client.filter != "tag:CloudSnooze:snooze_snapshot=i-0abc" {
+		t.Errorf("Expected old snapshots to be found by tag, filtered by %s", client.filter)
+	}
+
+	// The new snapshot and the newest old one of each volume are kept
+	slices.Sort(client.deleted)
+	if !slices.Equal(client.deleted, []string{"snap-data-1", "snap-root-1"}) {
+		t.Errorf("Expected the oldest snapshots to be deleted, got %v", client.deleted)
+	}
+}
+
+func TestSnapshotVolumesKeepAll(t *testing.T) {
+	client := &fakeSnapshots{snapshots: []ec2types.Snapshot{
+		oldSnapshot("snap-root-1", "vol-root", 48*time.Hour),
+	}}
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze"})
+
+	if _, err := provider.snapshotVolumes(context.Background(), client, "i-0abc", "Before snooze", 0); err != nil {
+		t.Fatalf("snapshotVolumes failed: %v", err)
+	}
+	if client.filter != "" || len(client.deleted) != 0 {
+		t.Errorf("Expected no snapshots to be listed or deleted, got filter %q and %v", client.filter, client.deleted)
+	}
+}
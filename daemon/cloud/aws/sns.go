@@ -0,0 +1,70 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// snsAPI is the subset of the SNS client used by the provider
+type snsAPI interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+}
+
+// PublishEvent publishes a snooze event to the configured SNS topic. The
+// event type and instance ID are set as message attributes so subscribers
+// can use SNS filter policies.
+func (p *AWSProvider) PublishEvent(eventType string, payload []byte) error {
+	if p.config.SNSTopicARN == "" {
+		return fmt.Errorf("no SNS topic configured")
+	}
+	client, err := p.getSNSClient()
+	if err != nil {
+		return err
+	}
+
+	attributes := map[string]snstypes.MessageAttributeValue{
+		"event_type": {
+			DataType:    aws.String("String"),
+			StringValue: aws.String(eventType),
+		},
+	}
+	if instanceID, err := p.getInstanceID(); err == nil {
+		attributes["instance_id"] = snstypes.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(instanceID),
+		}
+	}
+
+	_, err = client.Publish(p.context(), &sns.PublishInput{
+		TopicArn:          aws.String(p.config.SNSTopicARN),
+		Message:           aws.String(string(payload)),
+		Subject:           aws.String(fmt.Sprintf("CloudSnooze %s", eventType)),
+		MessageAttributes: attributes,
+	})
+	if err != nil {
+		return fmt.Errorf("error publishing to SNS topic: %v", err)
+	}
+	return nil
+}
+
+// getSNSClient returns the SNS client, creating it on first use
+func (p *AWSProvider) getSNSClient() (snsAPI, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.snsClient == nil {
+		cfg, err := p.loadAWSConfig(p.ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.snsClient = sns.NewFromConfig(cfg)
+	}
+	return p.snsClient, nil
+}
@@ -0,0 +1,105 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// dynamoDBAPI is the subset of the DynamoDB client used by the provider
+type dynamoDBAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+// RecordState writes the instance's state to the configured DynamoDB
+// table, keyed by instance_id. With a TTL, expires_at is set so rows of
+// instances that stopped recording are removed by DynamoDB.
+func (p *AWSProvider) RecordState(ctx context.Context, state common.InstanceState) error {
+	if p.config.StateTable == "" {
+		return fmt.Errorf("no DynamoDB state table configured")
+	}
+	client, err := p.getDynamoDBClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(p.config.StateTable),
+		Item:      stateItem(state, p.config.StateTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("error writing to DynamoDB table %s: %v", p.config.StateTable, err)
+	}
+	return nil
+}
+
+// stateItem returns the table row of state. Empty attributes are left out,
+// so they don't show up in queries.
+func stateItem(state common.InstanceState, ttl time.Duration) map[string]ddbtypes.AttributeValue {
+	item := map[string]ddbtypes.AttributeValue{
+		"instance_id": &ddbtypes.AttributeValueMemberS{Value: state.InstanceID},
+		"state":       &ddbtypes.AttributeValueMemberS{Value: state.State},
+		"updated":     &ddbtypes.AttributeValueMemberS{Value: state.Updated.UTC().Format(time.RFC3339)},
+		"lease_count": &ddbtypes.AttributeValueMemberN{Value: strconv.Itoa(len(state.Leases))},
+	}
+	setString := func(name, value string) {
+		if value != "" {
+			item[name] = &ddbtypes.AttributeValueMemberS{Value: value}
+		}
+	}
+	setString("hostname", state.Hostname)
+	setString("instance_type", state.InstanceType)
+	setString("region", state.Region)
+	setString("version", state.Version)
+	setString("reason", state.Reason)
+	if state.IdleSince != nil {
+		setString("idle_since", state.IdleSince.UTC().Format(time.RFC3339))
+	}
+	if state.LastSnooze != nil {
+		setString("last_snooze", state.LastSnooze.Time.UTC().Format(time.RFC3339))
+		setString("last_snooze_reason", state.LastSnooze.Reason)
+	}
+	if len(state.Leases) > 0 {
+		leases := make([]ddbtypes.AttributeValue, 0, len(state.Leases))
+		for _, lease := range state.Leases {
+			value := map[string]ddbtypes.AttributeValue{
+				"id":         &ddbtypes.AttributeValueMemberS{Value: lease.ID},
+				"owner":      &ddbtypes.AttributeValueMemberS{Value: lease.Owner},
+				"expires_at": &ddbtypes.AttributeValueMemberS{Value: lease.ExpiresAt.UTC().Format(time.RFC3339)},
+			}
+			if lease.Reason != "" {
+				value["reason"] = &ddbtypes.AttributeValueMemberS{Value: lease.Reason}
+			}
+			leases = append(leases, &ddbtypes.AttributeValueMemberM{Value: value})
+		}
+		item["leases"] = &ddbtypes.AttributeValueMemberL{Value: leases}
+	}
+	if ttl > 0 {
+		item["expires_at"] = &ddbtypes.AttributeValueMemberN{Value: strconv.FormatInt(state.Updated.Add(ttl).Unix(), 10)}
+	}
+	return item
+}
+
+// getDynamoDBClient returns the DynamoDB client, creating it on first use
+func (p *AWSProvider) getDynamoDBClient() (dynamoDBAPI, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.dynamoDBClient == nil {
+		cfg, err := p.loadAWSConfig(p.ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.dynamoDBClient = dynamodb.NewFromConfig(cfg)
+	}
+	return p.dynamoDBClient, nil
+}
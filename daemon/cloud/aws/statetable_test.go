@@ -0,0 +1,103 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// fakeDynamoDB keeps the last item written
+type fakeDynamoDB struct {
+	table string
+	item  map[string]ddbtypes.AttributeValue
+	err   error
+}
+
+func (f *fakeDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.table = aws.ToString(params.TableName)
+	f.item = params.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// attribute returns a string or number attribute of the item
+func (f *fakeDynamoDB) attribute(name string) string {
+	switch value := f.item[name].(type) {
+	case *ddbtypes.AttributeValueMemberS:
+		return value.Value
+	case *ddbtypes.AttributeValueMemberN:
+		return value.Value
+	}
+	return ""
+}
+
+func TestRecordState(t *testing.T) {
+	updated := time.Date(2025, 5, 6, 18, 42, 10, 0, time.UTC)
+	idleSince := updated.Add(-time.Hour)
+	client := &fakeDynamoDB{}
+	provider := NewProvider(Config{StateTable: "snooze-state", StateTTL: 24 * time.Hour})
+	provider.dynamoDBClient = client
+
+	err := provider.RecordState(context.Background(), common.InstanceState{
+		InstanceID: "i-0123456789abcdef0",
+		Hostname:   "research-gpu-1",
+		State:      common.FleetStateStopped,
+		Reason:     "Idle for 60 minutes",
+		IdleSince:  &idleSince,
+		LastSnooze: &common.FleetEvent{Time: updated, Event: "snooze", Reason: "Idle for 60 minutes"},
+		Leases:     []common.InstanceLease{{ID: "a1", Owner: "alice", ExpiresAt: updated.Add(time.Hour)}},
+		Updated:    updated,
+	})
+	if err != nil {
+		t.Fatalf("RecordState failed: %v", err)
+	}
+	if client.table != "snooze-state" {
+		t.Errorf("Expected the item in snooze-state, got %q", client.table)
+	}
+	for name, want := range map[string]string{
+		"instance_id": "i-0123456789abcdef0",
+		"state":       "stopped",
+		"idle_since":  "2025-05-06T17:42:10Z",
+		"last_snooze": "2025-05-06T18:42:10Z",
+		"lease_count": "1",
+		"expires_at":  strconv.FormatInt(updated.Add(24*time.Hour).Unix(), 10),
+	} {
+		if got := client.attribute(name); got != want {
+			t.Errorf("Expected %s to be %q, got %q", name, want, got)
+		}
+	}
+	if _, ok := client.item["version"]; ok {
+		t.Error("Expected empty attributes to be left out")
+	}
+	leases, ok := client.item["leases"].(*ddbtypes.AttributeValueMemberL)
+	if !ok || len(leases.Value) != 1 {
+		t.Fatalf("Expected one lease, got %#v", client.item["leases"])
+	}
+	if owner := leases.Value[0].(*ddbtypes.AttributeValueMemberM).Value["owner"]; owner.(*ddbtypes.AttributeValueMemberS).Value != "alice" {
+		t.Errorf("Expected the lease owner, got %#v", owner)
+	}
+}
+
+func TestRecordStateErrors(t *testing.T) {
+	if err := NewProvider(Config{}).RecordState(context.Background(), common.InstanceState{}); err == nil {
+		t.Error("Expected an error without a state table")
+	}
+
+	provider := NewProvider(Config{StateTable: "snooze-state"})
+	provider.dynamoDBClient = &fakeDynamoDB{err: errors.New("ResourceNotFoundException")}
+	if err := provider.RecordState(context.Background(), common.InstanceState{InstanceID: "i-1"}); err == nil {
+		t.Error("Expected the DynamoDB error to be returned")
+	}
+}
@@ -0,0 +1,260 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+const (
+	// tagReadTTL is how long tags read from the EC2 API are reused, so tag
+	// polling, instance info and other readers share one call
+	tagReadTTL = 30 * time.Second
+
+	// tagWriteDelay is how long tags are collected before they are written,
+	// so tags from several subsystems are applied in one call
+	tagWriteDelay = 5 * time.Second
+
+	// tagFlushTimeout bounds writing queued tags when the daemon shuts down
+	tagFlushTimeout = 10 * time.Second
+)
+
+// tagAPI is the subset of the EC2 client used to read and write tags
+type tagAPI interface {
+	DescribeTags(ctx context.Context, params *ec2.DescribeTagsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeTagsOutput, error)
+	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+}
+
+// tagCache holds the instance tags last read and the tags waiting to be
+// written
+type tagCache struct {
+	fetching sync.Mutex // Held while reading, so concurrent readers share one call
+	lock     sync.Mutex
+	tags     map[string]string
+	read     time.Time
+	pending  map[string]string
+	timer    *time.Timer
+	failed   error // Why the last queued write failed, until a caller is told
+}
+
+// fresh returns the cached tags, with the pending ones applied, if they were
+// read recently
+func (c *tagCache) fresh(now time.Time) (map[string]string, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.tags == nil || now.Sub(c.read) >= tagReadTTL {
+		return nil, false
+	}
+	tags := maps.Clone(c.tags)
+	maps.Copy(tags, c.pending)
+	return tags, true
+}
+
+// store caches tags read at the given time
+func (c *tagCache) store(tags map[string]string, read time.Time) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.tags = maps.Clone(tags)
+	c.read = read
+}
+
+// written records tags that were applied to the instance
+func (c *tagCache) written(tags map[string]string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.tags != nil {
+		maps.Copy(c.tags, tags)
+	}
+}
+
+// getTagClient returns the client tags are read and written with
+func (p *AWSProvider) getTagClient() (tagAPI, error) {
+	p.lock.RLock()
+	client := p.tagClient
+	p.lock.RUnlock()
+	if client != nil {
+		return client, nil
+	}
+	return p.getEC2Client()
+}
+
+// readTags returns all tags of the instance. Tags read within tagReadTTL
+// are reused, and concurrent callers share a single call.
+func (p *AWSProvider) readTags(ctx context.Context) (map[string]string, error) {
+	c := &p.tagCache
+	c.fetching.Lock()
+	defer c.fetching.Unlock()
+
+	if tags, ok := c.fresh(time.Now()); ok {
+		return tags, nil
+	}
+
+	instanceID, err := p.getInstanceID()
+	if err != nil {
+		return nil, fmt.Errorf("error getting instance ID: %v", err)
+	}
+	client, err := p.getTagClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var result *ec2.DescribeTagsOutput
+	err = p.retry(ctx, "error getting tags", func(ctx context.Context) error {
+		var err error
+		result, err = client.DescribeTags(ctx, &ec2.DescribeTagsInput{
+			Filters: []types.Filter{
+				{
+					Name:   aws.String("resource-id"),
+					Values: []string{instanceID},
+				},
+			},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(result.Tags))
+	for _, tag := range result.Tags {
+		if tag.Key != nil && tag.Value != nil {
+			tags[*tag.Key] = *tag.Value
+		}
+	}
+	c.store(tags, time.Now())
+
+	tags, _ = c.fresh(time.Now())
+	return tags, nil
+}
+
+// queueTags adds tags to the next write, which happens tagWriteDelay after
+// the first tags were queued. If the last queued write failed its error is
+// returned, once; its tags are written along with these.
+func (p *AWSProvider) queueTags(tags map[string]string) error {
+	c := &p.tagCache
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.pending == nil {
+		c.pending = make(map[string]string)
+	}
+	maps.Copy(c.pending, tags)
+	if c.timer == nil {
+		c.timer = time.AfterFunc(tagWriteDelay, func() {
+			if err := p.writeTags(p.context(), nil); err != nil {
+				logger().Warn("Failed to apply tags", "error", err)
+				c.lock.Lock()
+				c.failed = err
+				c.lock.Unlock()
+			}
+		})
+	}
+	failed := c.failed
+	c.failed = nil
+	return failed
+}
+
+// requeue puts back queued tags that failed to be written, without
+// replacing tags queued since
+func (c *tagCache) requeue(tags map[string]string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.pending == nil {
+		c.pending = make(map[string]string)
+	}
+	for key, value := range tags {
+		if _, ok := c.pending[key]; !ok {
+			c.pending[key] = value
+		}
+	}
+}
+
+// writeTags applies the queued tags together with extra in a single call.
+// Queued tags that fail to be written are queued again for the next write;
+// once they are written, an earlier failure no longer needs reporting.
+func (p *AWSProvider) writeTags(ctx context.Context, extra map[string]string) error {
+	c := &p.tagCache
+	c.lock.Lock()
+	queued := c.pending
+	c.pending = nil
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	c.lock.Unlock()
+
+	tags := maps.Clone(queued)
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	maps.Copy(tags, extra)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	err := p.createTags(ctx, tags)
+	c.lock.Lock()
+	if err == nil {
+		c.failed = nil
+	}
+	c.lock.Unlock()
+	if err != nil {
+		c.requeue(queued)
+		return err
+	}
+	c.written(tags)
+	return nil
+}
+
+// createTags applies tags to the instance in a single call
+func (p *AWSProvider) createTags(ctx context.Context, tags map[string]string) error {
+	instanceID, err := p.getInstanceID()
+	if err != nil {
+		return fmt.Errorf("error getting instance ID: %v", err)
+	}
+	client, err := p.getTagClient()
+	if err != nil {
+		return err
+	}
+
+	ec2Tags := make([]types.Tag, 0, len(tags))
+	for _, key := range slices.Sorted(maps.Keys(tags)) {
+		ec2Tags = append(ec2Tags, types.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(tags[key]),
+		})
+	}
+	return p.retry(ctx, "error tagging instance", func(ctx context.Context) error {
+		_, err := client.CreateTags(ctx, &ec2.CreateTagsInput{
+			Resources: []string{instanceID},
+			Tags:      ec2Tags,
+		})
+		return err
+	})
+}
+
+// FlushTags writes any queued tags now rather than after tagWriteDelay, for
+// callers that need to know their tags were applied
+func (p *AWSProvider) FlushTags(ctx context.Context) error {
+	return p.writeTags(ctx, nil)
+}
+
+// flushTags writes any queued tags before the daemon exits
+func (p *AWSProvider) flushTags() {
+	ctx, cancel := context.WithTimeout(context.Background(), tagFlushTimeout)
+	defer cancel()
+	if err := p.FlushTags(ctx); err != nil {
+		logger().Warn("Failed to apply queued tags", "error", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// fakeTags records tag calls
+type fakeTags struct {
+	lock      sync.Mutex
+	describes int
+	creates   []*ec2.CreateTagsInput
+	fail      error // Returned by CreateTags when set
+}
+
+func (f *fakeTags) DescribeTags(ctx context.Context, params *ec2.DescribeTagsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeTagsOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.describes++
+	return &ec2.DescribeTagsOutput{
+		Tags: []types.TagDescription{{Key: aws.String("Name"), Value: aws.String("build-box")}},
+	}, nil
+}
+
+func (f *fakeTags) CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.creates = append(f.creates, params)
+	if f.fail != nil {
+		return nil, f.fail
+	}
+	return &ec2.CreateTagsOutput{}, nil
+}
+
+func TestTagReadsCoalesce(t *testing.T) {
+	client := &fakeTags{}
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze"})
+	provider.instanceID = "i-0abc"
+	provider.tagClient = client
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if tags, err := provider.GetExternalTags(); err != nil || tags["Name"] != "build-box" {
+				t.Errorf("Expected the Name tag, got %v (error %v)", tags, err)
+			}
+		}()
+	}
+	wg.Wait()
+	provider.checkTags()
+
+	if client.describes != 1 {
+		t.Errorf("Expected one DescribeTags call, got %d", client.describes)
+	}
+}
+
+func TestTagWritesBatch(t *testing.T) {
+	client := &fakeTags{}
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze"})
+	provider.instanceID = "i-0abc"
+	provider.tagClient = client
+	provider.GetExternalTags()
+
+	provider.TagInstance(map[string]string{"CloudSnooze:owner": "ci"})
+	provider.TagInstance(map[string]string{"CloudSnooze:project": "build"})
+	if len(client.creates) != 0 {
+		t.Fatalf("Expected tags to be queued, got %d writes", len(client.creates))
+	}
+
+	// A stop writes the queued tags with its own
+	if err := provider.writeTags(context.Background(), map[string]string{"CloudSnooze:reason": "idle"}); err != nil {
+		t.Fatalf("writeTags failed: %v", err)
+	}
+	if len(client.creates) != 1 || len(client.creates[0].Tags) != 3 {
+		t.Fatalf("Expected one write of 3 tags, got %+v", client.creates)
+	}
+
+	// Nothing is left to write
+	provider.flushTags()
+	if len(client.creates) != 1 {
+		t.Errorf("Expected no further writes, got %d", len(client.creates))
+	}
+
+	// Reads include what was written
+	provider.TagInstance(map[string]string{"CloudSnooze:owner": "ops"})
+	tags, _ := provider.GetExternalTags()
+	if tags["CloudSnooze:reason"] != "idle" || tags["CloudSnooze:owner"] != "ops" || client.describes != 1 {
+		t.Errorf("Expected written and queued tags to be read back, got %v", tags)
+	}
+	provider.flushTags()
+}
+
+func TestTagWriteFailureReported(t *testing.T) {
+	client := &fakeTags{fail: errors.New("UnauthorizedOperation")}
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze"})
+	provider.instanceID = "i-0abc"
+	provider.tagClient = client
+
+	if err := provider.TagInstance(map[string]string{"CloudSnooze:owner": "ci"}); err != nil {
+		t.Fatalf("Expected queueing to succeed, got %v", err)
+	}
+	if err := provider.FlushTags(context.Background()); err == nil {
+		t.Fatal("Expected FlushTags to return the write error")
+	}
+
+	// A failed write from the timer is returned by the next caller, once
+	provider.tagCache.failed = client.fail
+	if err := provider.TagInstance(map[string]string{"CloudSnooze:project": "build"}); err == nil {
+		t.Error("Expected TagInstance to report the failed write")
+	}
+	if err := provider.TagInstance(map[string]string{"CloudSnooze:project": "test"}); err != nil {
+		t.Errorf("Expected the failure to be reported once, got %v", err)
+	}
+
+	// The failed tags are written with the next ones, without replacing them
+	client.fail = nil
+	if err := provider.FlushTags(context.Background()); err != nil {
+		t.Fatalf("FlushTags failed: %v", err)
+	}
+	written := make(map[string]string)
+	for _, tag := range client.creates[len(client.creates)-1].Tags {
+		written[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	if written["CloudSnooze:owner"] != "ci" || written["CloudSnooze:project"] != "test" {
+		t.Errorf("Expected the failed and newer tags to be written, got %v", written)
+	}
+}
+
+func TestStopFailsWithoutWakeTime(t *testing.T) {
+	client := &fakeTags{fail: errors.New("UnauthorizedOperation")}
+	provider := NewProvider(Config{TaggingPrefix: "CloudSnooze", EnableTags: true, MaxSnooze: 8 * time.Hour})
+	provider.instanceID = "i-0abc"
+	provider.tagClient = client
+
+	err := provider.StopInstanceContext(context.Background(), "idle", common.SystemMetrics{})
+	if err == nil || !strings.Contains(err.Error(), "wake time") {
+		t.Errorf("Expected the stop to fail without its wake time, got %v", err)
+	}
+}
@@ -4,8 +4,13 @@
 package aws
 
 import (
+	"context"
 	"testing"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/scttfrdmn/cloudsnooze/daemon/telemetry"
 )
 
 // TestNewProviderUnit tests the NewProvider function without external dependencies
@@ -60,4 +65,115 @@ func TestStopTagPollingUnit(t *testing.T) {
 	if provider.tagPoller != nil {
 		t.Errorf("Expected tagPoller to be nil after stopping")
 	}
-}
\ No newline at end of file
+}
+// fakeSNS records published messages
+type fakeSNS struct {
+	inputs []*sns.PublishInput
+}
+
+func (f *fakeSNS) Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.inputs = append(f.inputs, params)
+	return &sns.PublishOutput{}, nil
+}
+
+// TestPublishEventUnit tests SNS publishing without external dependencies
+func TestPublishEventUnit(t *testing.T) {
+	provider := NewProvider(Config{Region: "us-west-2"})
+	if err := provider.PublishEvent("snoozed", []byte("{}")); err == nil {
+		t.Errorf("Expected error when no topic is configured")
+	}
+
+	fake := &fakeSNS{}
+	provider = NewProvider(Config{
+		Region:      "us-west-2",
+		SNSTopicARN: "arn:aws:sns:us-west-2:123456789012:cloudsnooze",
+	})
+	provider.snsClient = fake
+	provider.instanceID = "i-0123456789abcdef0"
+
+	if err := provider.PublishEvent("snoozed", []byte(`{"type":"snoozed"}`)); err != nil {
+		t.Fatalf("PublishEvent returned error: %v", err)
+	}
+
+	if len(fake.inputs) != 1 {
+		t.Fatalf("Expected 1 published message, got %d", len(fake.inputs))
+	}
+	input := fake.inputs[0]
+	if *input.TopicArn != "arn:aws:sns:us-west-2:123456789012:cloudsnooze" {
+		t.Errorf("Unexpected topic ARN: %s", *input.TopicArn)
+	}
+	if *input.Message != `{"type":"snoozed"}` {
+		t.Errorf("Unexpected message: %s", *input.Message)
+	}
+	if *input.MessageAttributes["event_type"].StringValue != "snoozed" {
+		t.Errorf("Expected event_type attribute to be snoozed")
+	}
+	if *input.MessageAttributes["instance_id"].StringValue != "i-0123456789abcdef0" {
+		t.Errorf("Expected instance_id attribute to be set")
+	}
+}
+
+// fakeCloudWatch records metric data
+type fakeCloudWatch struct {
+	inputs []*cloudwatch.PutMetricDataInput
+}
+
+func (f *fakeCloudWatch) PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error) {
+	f.inputs = append(f.inputs, params)
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+// TestPutMetricsUnit tests CloudWatch metric publishing without external dependencies
+func TestPutMetricsUnit(t *testing.T) {
+	fake := &fakeCloudWatch{}
+	provider := NewProvider(Config{Region: "us-west-2", PublishMetrics: true})
+	provider.cwClient = fake
+
+	check := telemetry.Check{
+		Idle:    5 * time.Minute,
+		Usage:   map[string]float64{"cpu": 2.5, "unknown": 1},
+		Outcome: telemetry.OutcomePending,
+	}
+	if err := provider.putMetrics(context.Background(), "i-0123456789abcdef0", check); err != nil {
+		t.Fatalf("putMetrics returned error: %v", err)
+	}
+
+	if len(fake.inputs) != 1 {
+		t.Fatalf("Expected 1 PutMetricData call, got %d", len(fake.inputs))
+	}
+	input := fake.inputs[0]
+	if *input.Namespace != DefaultMetricsNamespace {
+		t.Errorf("Expected namespace %s, got %s", DefaultMetricsNamespace, *input.Namespace)
+	}
+
+	values := map[string]float64{}
+	for _, datum := range input.MetricData {
+		values[*datum.MetricName] = *datum.Value
+		if len(datum.Dimensions) != 1 || *datum.Dimensions[0].Value != "i-0123456789abcdef0" {
+			t.Errorf("Expected InstanceId dimension on %s", *datum.MetricName)
+		}
+	}
+	expected := map[string]float64{"IdleSeconds": 300, "WillSnooze": 1, "CPUUtilization": 2.5}
+	if len(values) != len(expected) {
+		t.Errorf("Expected metrics %v, got %v", expected, values)
+	}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("Expected %s to be %v, got %v", name, value, values[name])
+		}
+	}
+}
+
+// TestDescribeInstanceUnit tests reading the launch time and tags
+func TestDescribeInstanceUnit(t *testing.T) {
+	launched, tags, err := describeInstance(context.Background(), &fakeDescribe{}, "i-0abc")
+	if err != nil {
+		t.Fatalf("describeInstance failed: %v", err)
+	}
+	if !launched.Equal(time.Date(2025, 5, 1, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected launch time %s", launched)
+	}
+	if tags["Name"] != "build-box" {
+		t.Errorf("Expected the Name tag, got %v", tags)
+	}
+}
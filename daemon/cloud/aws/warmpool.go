@@ -0,0 +1,113 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// autoscalingAPI is the subset of the Auto Scaling client used by the provider
+type autoscalingAPI interface {
+	DescribeAutoScalingInstances(ctx context.Context, params *autoscaling.DescribeAutoScalingInstancesInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingInstancesOutput, error)
+	DescribeWarmPool(ctx context.Context, params *autoscaling.DescribeWarmPoolInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeWarmPoolOutput, error)
+	TerminateInstanceInAutoScalingGroup(ctx context.Context, params *autoscaling.TerminateInstanceInAutoScalingGroupInput, optFns ...func(*autoscaling.Options)) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error)
+}
+
+// shouldUseWarmPool returns true if the next stop should return the
+// instance to its group's warm pool
+func (p *AWSProvider) shouldUseWarmPool() bool {
+	return p.config.WarmPool && p.StopAction().Effective == "warm_pool"
+}
+
+// checkWarmPool finds out whether the instance can go back to a warm pool:
+// it must be in service in an Auto Scaling group whose warm pool keeps
+// instances stopped or hibernated and reuses them on scale in. Otherwise
+// scaling in would terminate it.
+func (p *AWSProvider) checkWarmPool(ctx context.Context, client autoscalingAPI, instanceID string) common.StopActionStatus {
+	status := common.StopActionStatus{Requested: "warm_pool", Effective: "stop"}
+
+	instances, err := client.DescribeAutoScalingInstances(ctx, &autoscaling.DescribeAutoScalingInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		status.Reason = fmt.Sprintf("couldn't find the instance's Auto Scaling group: %v", err)
+		return status
+	}
+	if len(instances.AutoScalingInstances) == 0 {
+		status.Reason = "the instance isn't in an Auto Scaling group"
+		return status
+	}
+	instance := instances.AutoScalingInstances[0]
+	group := aws.ToString(instance.AutoScalingGroupName)
+	if state := aws.ToString(instance.LifecycleState); state != string(astypes.LifecycleStateInService) {
+		status.Reason = fmt.Sprintf("the instance is %s in Auto Scaling group %s", state, group)
+		return status
+	}
+
+	pool, err := client.DescribeWarmPool(ctx, &autoscaling.DescribeWarmPoolInput{
+		AutoScalingGroupName: aws.String(group),
+		MaxRecords:           aws.Int32(1),
+	})
+	if err != nil {
+		status.Reason = fmt.Sprintf("couldn't describe the warm pool of %s: %v", group, err)
+		return status
+	}
+	poolConfig := pool.WarmPoolConfiguration
+	if poolConfig == nil {
+		status.Reason = fmt.Sprintf("Auto Scaling group %s has no warm pool", group)
+		return status
+	}
+	if poolConfig.PoolState != astypes.WarmPoolStateStopped && poolConfig.PoolState != astypes.WarmPoolStateHibernated {
+		status.Reason = fmt.Sprintf("the warm pool of %s keeps instances %s", group, poolConfig.PoolState)
+		return status
+	}
+	if poolConfig.InstanceReusePolicy == nil || !aws.ToBool(poolConfig.InstanceReusePolicy.ReuseOnScaleIn) {
+		status.Reason = fmt.Sprintf("the warm pool of %s doesn't reuse instances on scale in", group)
+		return status
+	}
+
+	p.lock.Lock()
+	p.warmPoolGroup = group
+	p.lock.Unlock()
+	status.Effective = "warm_pool"
+	return status
+}
+
+// returnToWarmPool scales the instance's group in by one, which runs its
+// lifecycle hooks and moves the instance into the warm pool instead of
+// terminating it
+func (p *AWSProvider) returnToWarmPool(ctx context.Context, instanceID string) error {
+	client, err := p.getAutoscalingClient()
+	if err != nil {
+		return err
+	}
+	return p.retry(ctx, "error returning instance to warm pool", func(ctx context.Context) error {
+		_, err := client.TerminateInstanceInAutoScalingGroup(ctx, &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+			InstanceId:                     aws.String(instanceID),
+			ShouldDecrementDesiredCapacity: aws.Bool(true),
+		})
+		return err
+	})
+}
+
+// getAutoscalingClient returns the Auto Scaling client, creating it on first use
+func (p *AWSProvider) getAutoscalingClient() (autoscalingAPI, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.autoscalingClient == nil {
+		cfg, err := p.loadAWSConfig(p.ctx)
+		if err != nil {
+			return nil, err
+		}
+		p.autoscalingClient = autoscaling.NewFromConfig(cfg)
+	}
+	return p.autoscalingClient, nil
+}
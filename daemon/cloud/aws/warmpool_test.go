@@ -0,0 +1,100 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package aws
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/autoscaling"
+	astypes "github.com/aws/aws-sdk-go-v2/service/autoscaling/types"
+)
+
+// fakeAutoscaling puts a single instance in a group with an optional warm pool
+type fakeAutoscaling struct {
+	group      string
+	lifecycle  string
+	pool       *astypes.WarmPoolConfiguration
+	terminated []string
+	decrement  bool
+}
+
+func (f *fakeAutoscaling) DescribeAutoScalingInstances(ctx context.Context, params *autoscaling.DescribeAutoScalingInstancesInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeAutoScalingInstancesOutput, error) {
+	if f.group == "" {
+		return &autoscaling.DescribeAutoScalingInstancesOutput{}, nil
+	}
+	return &autoscaling.DescribeAutoScalingInstancesOutput{
+		AutoScalingInstances: []astypes.AutoScalingInstanceDetails{{
+			InstanceId:           aws.String(params.InstanceIds[0]),
+			AutoScalingGroupName: aws.String(f.group),
+			LifecycleState:       aws.String(f.lifecycle),
+		}},
+	}, nil
+}
+
+func (f *fakeAutoscaling) DescribeWarmPool(ctx context.Context, params *autoscaling.DescribeWarmPoolInput, optFns ...func(*autoscaling.Options)) (*autoscaling.DescribeWarmPoolOutput, error) {
+	return &autoscaling.DescribeWarmPoolOutput{WarmPoolConfiguration: f.pool}, nil
+}
+
+func (f *fakeAutoscaling) TerminateInstanceInAutoScalingGroup(ctx context.Context, params *autoscaling.TerminateInstanceInAutoScalingGroupInput, optFns ...func(*autoscaling.Options)) (*autoscaling.TerminateInstanceInAutoScalingGroupOutput, error) {
+	f.terminated = append(f.terminated, aws.ToString(params.InstanceId))
+	f.decrement = aws.ToBool(params.ShouldDecrementDesiredCapacity)
+	return &autoscaling.TerminateInstanceInAutoScalingGroupOutput{}, nil
+}
+
+// warmPool returns a warm pool configuration
+func warmPool(state astypes.WarmPoolState, reuse bool) *astypes.WarmPoolConfiguration {
+	return &astypes.WarmPoolConfiguration{
+		PoolState:           state,
+		InstanceReusePolicy: &astypes.InstanceReusePolicy{ReuseOnScaleIn: aws.Bool(reuse)},
+	}
+}
+
+func TestCheckWarmPool(t *testing.T) {
+	tests := []struct {
+		name      string
+		client    *fakeAutoscaling
+		effective string
+		reason    string
+	}{
+		{"stopped pool", &fakeAutoscaling{group: "web", lifecycle: "InService", pool: warmPool(astypes.WarmPoolStateStopped, true)}, "warm_pool", ""},
+		{"hibernated pool", &fakeAutoscaling{group: "web", lifecycle: "InService", pool: warmPool(astypes.WarmPoolStateHibernated, true)}, "warm_pool", ""},
+		{"no group", &fakeAutoscaling{}, "stop", "isn't in an Auto Scaling group"},
+		{"not in service", &fakeAutoscaling{group: "web", lifecycle: "Standby", pool: warmPool(astypes.WarmPoolStateStopped, true)}, "stop", "Standby"},
+		{"no pool", &fakeAutoscaling{group: "web", lifecycle: "InService"}, "stop", "no warm pool"},
+		{"running pool", &fakeAutoscaling{group: "web", lifecycle: "InService", pool: warmPool(astypes.WarmPoolStateRunning, true)}, "stop", "Running"},
+		{"no reuse", &fakeAutoscaling{group: "web", lifecycle: "InService", pool: warmPool(astypes.WarmPoolStateStopped, false)}, "stop", "reuse"},
+	}
+
+	for _, test := range tests {
+		provider := NewProvider(Config{WarmPool: true})
+		status := provider.checkWarmPool(context.Background(), test.client, "i-0abc")
+		if status.Requested != "warm_pool" || status.Effective != test.effective || !strings.Contains(status.Reason, test.reason) {
+			t.Errorf("%s: unexpected status %+v", test.name, status)
+		}
+	}
+}
+
+func TestReturnToWarmPool(t *testing.T) {
+	client := &fakeAutoscaling{group: "web", lifecycle: "InService", pool: warmPool(astypes.WarmPoolStateStopped, true)}
+	provider := NewProvider(Config{WarmPool: true})
+	provider.autoscalingClient = client
+	provider.setStopAction(provider.checkWarmPool(context.Background(), client, "i-0abc"))
+	if !provider.shouldUseWarmPool() {
+		t.Fatalf("Expected to use the warm pool, stop action is %+v", provider.StopAction())
+	}
+
+	if err := provider.returnToWarmPool(context.Background(), "i-0abc"); err != nil {
+		t.Fatalf("returnToWarmPool failed: %v", err)
+	}
+	if len(client.terminated) != 1 || client.terminated[0] != "i-0abc" {
+		t.Errorf("Expected i-0abc to leave the group, got %v", client.terminated)
+	}
+	// Without the decrement the group would launch a replacement
+	if !client.decrement {
+		t.Error("Expected the desired capacity to be decremented")
+	}
+}
@@ -3,6 +3,14 @@
 
 package common
 
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+)
+
 // SystemMetrics contains all metrics collected from the system
 type SystemMetrics struct {
     CPUUsage        float64
@@ -37,6 +45,10 @@ type CloudProvider interface {
     // StopInstance stops the current instance
     StopInstance(reason string, metrics SystemMetrics) error
     
+    // StartInstance starts another instance, so a fleet controller can wake
+    // the peers it snoozed
+    StartInstance(instanceID string) error
+    
     // TagInstance adds tags to the current instance
     TagInstance(tags map[string]string) error
     
@@ -44,6 +56,277 @@ type CloudProvider interface {
     GetExternalTags() (map[string]string, error)
 }
 
+// ContextStopper is implemented by cloud providers that can trace or cancel
+// a stop through a context
+type ContextStopper interface {
+    // StopInstanceContext stops the current instance
+    StopInstanceContext(ctx context.Context, reason string, metrics SystemMetrics) error
+}
+
+// ContextBinder is implemented by cloud providers whose API calls and
+// background work should end when the daemon shuts down
+type ContextBinder interface {
+    // SetContext sets the context the provider's calls are made with
+    SetContext(ctx context.Context)
+}
+
+// StopActionStatus describes how the instance will be stopped
+type StopActionStatus struct {
+    Requested string `json:"requested"`        // Configured action, e.g. "hibernate"
+    Effective string `json:"effective"`        // Action that will be used
+    Reason    string `json:"reason,omitempty"` // Why the effective action differs, if it does
+}
+
+// StopActionReporter is implemented by cloud providers that may not be able
+// to stop the instance the way it was configured
+type StopActionReporter interface {
+    // StopAction reports the configured and effective stop actions
+    StopAction() StopActionStatus
+}
+
+// VolumeSnapshotter is implemented by cloud providers that can snapshot the
+// instance's volumes before it stops
+type VolumeSnapshotter interface {
+    // SnapshotVolumes starts snapshots of the instance's volumes, keeping
+    // only the newest keep taken this way (0 keeps all), and returns their IDs
+    SnapshotVolumes(ctx context.Context, description string, keep int) ([]string, error)
+}
+
+// StopRollbacker is implemented by cloud providers whose stop does more
+// than stop the instance, such as leaving load balancers, and can undo it
+// when the stop fails
+type StopRollbacker interface {
+    // RollbackStop undoes what a failed stop did before it failed
+    RollbackStop(ctx context.Context) error
+}
+
+// MaintenanceEvent is maintenance the cloud provider scheduled for the instance
+type MaintenanceEvent struct {
+    ID          string    `json:"id,omitempty"`
+    Code        string    `json:"code"`        // e.g. "system-reboot" or "instance-retirement"
+    Description string    `json:"description"`
+    NotBefore   time.Time `json:"not_before"`
+    NotAfter    time.Time `json:"not_after,omitempty"`
+}
+
+// MaintenanceReporter is implemented by cloud providers that report
+// scheduled maintenance of the instance
+type MaintenanceReporter interface {
+    // ScheduledEvents returns upcoming maintenance events
+    ScheduledEvents() ([]MaintenanceEvent, error)
+}
+
+// PriceReporter is implemented by cloud providers that can look up what the
+// instance costs to run
+type PriceReporter interface {
+    // HourlyPrice returns the on-demand price per hour in USD
+    HourlyPrice() (float64, error)
+}
+
+// EventPublisher is implemented by cloud providers that can publish snooze
+// events to a messaging service (e.g. an SNS topic)
+type EventPublisher interface {
+    // PublishEvent publishes a JSON-encoded event of the given type
+    PublishEvent(eventType string, payload []byte) error
+}
+
+// StateRecorder is implemented by cloud providers that can record the
+// daemon's state in a table shared by the fleet (e.g. in DynamoDB)
+type StateRecorder interface {
+    // RecordState replaces the instance's row with state
+    RecordState(ctx context.Context, state InstanceState) error
+}
+
+// Fleet is implemented by cloud providers that can find instances snoozed
+// by CloudSnooze daemons and start them again, for the restarter
+type Fleet interface {
+    // SnoozedInstances returns the instances that are stopped because they
+    // were snoozed, and haven't been started since
+    SnoozedInstances(ctx context.Context) ([]SnoozedInstance, error)
+    
+    // StartInstanceContext starts an instance, recording what triggered
+    // the start
+    StartInstanceContext(ctx context.Context, instanceID, trigger string) error
+    
+    // Instance returns an instance's state and addresses
+    Instance(ctx context.Context, instanceID string) (FleetInstance, error)
+}
+
+// FleetController is implemented by cloud providers that can judge other
+// instances idle from the provider's own metrics and stop them, for the
+// controller, which snoozes a fleet without a daemon on each instance
+type FleetController interface {
+    // TaggedInstances returns the running instances that have all of tags
+    TaggedInstances(ctx context.Context, tags map[string]string) ([]ControlledInstance, error)
+    
+    // InstanceUsage returns an instance's peak use over the period ending now
+    InstanceUsage(ctx context.Context, instanceID string, period time.Duration) (InstanceUsage, error)
+    
+    // StopFleetInstance stops another instance, tagging it as snoozed so
+    // the restarter can start it again
+    StopFleetInstance(ctx context.Context, instanceID, reason string) error
+}
+
+// ControlledInstance is a running instance watched by the controller
+type ControlledInstance struct {
+    ID         string            `json:"id"`
+    Name       string            `json:"name,omitempty"`
+    Type       string            `json:"type,omitempty"`
+    LaunchTime time.Time         `json:"launch_time"` // When the instance last started
+    Tags       map[string]string `json:"tags,omitempty"`
+}
+
+// InstanceUsage is an instance's peak use over a period, from the cloud
+// provider's metrics
+type InstanceUsage struct {
+    CPUPercent  float64 `json:"cpu_percent"`  // Highest average CPU utilization of a metric period
+    NetworkKBps float64 `json:"network_kbps"` // Highest traffic in and out of a metric period
+    Samples     int     `json:"samples"`      // Metric periods with data
+}
+
+// States of the instances in a FleetStatus
+const (
+    FleetStateActive    = "active"    // Running and in use
+    FleetStateIdle      = "idle"      // Running and idle, but not yet being stopped
+    FleetStateCountdown = "countdown" // A stop is pending
+    FleetStatePaused    = "paused"    // Running, with snoozing paused or leased
+    FleetStateStopped   = "stopped"   // Snoozed
+    FleetStateStale     = "stale"     // Not heard from recently
+    FleetStateError     = "error"     // Couldn't be checked
+)
+
+// FleetStatus is every instance a controller or aggregation server tracks,
+// in one response that can back a fleet dashboard
+type FleetStatus struct {
+    Time      time.Time           `json:"time"`
+    Counts    map[string]int      `json:"counts"` // Number of instances in each state
+    Instances []FleetMemberStatus `json:"instances"`
+}
+
+// FleetMemberStatus is the state of one instance of a FleetStatus
+type FleetMemberStatus struct {
+    InstanceID string          `json:"instance_id"`
+    Name       string          `json:"name,omitempty"`
+    Group      string          `json:"group,omitempty"`      // The controller group whose policy applies
+    State      string          `json:"state"`                // One of FleetStateActive, FleetStateIdle, ...
+    Reason     string          `json:"reason,omitempty"`     // Why it is in that state
+    IdleSince  *time.Time      `json:"idle_since,omitempty"`
+    Countdown  *FleetCountdown `json:"countdown,omitempty"`  // The pending stop
+    LastEvent  *FleetEvent     `json:"last_event,omitempty"` // The latest snooze or start
+    LastSeen   time.Time       `json:"last_seen"`            // When it was last checked or reported
+    Tags       map[string]string `json:"tags,omitempty"`
+}
+
+// HasTags reports whether tags include every key and value of want
+func HasTags(tags, want map[string]string) bool {
+    for key, value := range want {
+        if tags[key] != value {
+            return false
+        }
+    }
+    return true
+}
+
+// FleetCountdown is a pending stop of a fleet instance
+type FleetCountdown struct {
+    Reason        string    `json:"reason"`
+    Deadline      time.Time `json:"deadline"`
+    RemainingSecs int       `json:"remaining_secs"`
+}
+
+// FleetEvent is a snooze or start of a fleet instance
+type FleetEvent struct {
+    Time   time.Time `json:"time"`
+    Event  string    `json:"event"` // "snooze" or "start"
+    Reason string    `json:"reason,omitempty"`
+}
+
+// InstanceState is a daemon's own state, as recorded in a shared state
+// table
+type InstanceState struct {
+    InstanceID   string          `json:"instance_id"`
+    Hostname     string          `json:"hostname,omitempty"`
+    InstanceType string          `json:"instance_type,omitempty"`
+    Region       string          `json:"region,omitempty"`
+    Version      string          `json:"version,omitempty"`
+    State        string          `json:"state"` // FleetStateActive, FleetStateIdle, FleetStateCountdown or FleetStateStopped
+    Reason       string          `json:"reason,omitempty"`
+    IdleSince    *time.Time      `json:"idle_since,omitempty"`
+    LastSnooze   *FleetEvent     `json:"last_snooze,omitempty"`
+    Leases       []InstanceLease `json:"leases,omitempty"`
+    Updated      time.Time       `json:"updated"`
+}
+
+// InstanceLease is a lease keeping an instance running
+type InstanceLease struct {
+    ID        string    `json:"id"`
+    Owner     string    `json:"owner"`
+    Reason    string    `json:"reason,omitempty"`
+    ExpiresAt time.Time `json:"expires_at"`
+}
+
+// NewFleetStatus returns the status of instances, ordered by instance ID,
+// counting those in each state
+func NewFleetStatus(instances []FleetMemberStatus, now time.Time) FleetStatus {
+    sort.Slice(instances, func(i, j int) bool {
+        return instances[i].InstanceID < instances[j].InstanceID
+    })
+    status := FleetStatus{Time: now, Counts: make(map[string]int), Instances: instances}
+    if status.Instances == nil {
+        status.Instances = []FleetMemberStatus{}
+    }
+    for _, instance := range instances {
+        status.Counts[instance.State]++
+    }
+    return status
+}
+
+// Tagged returns the status of the instances that have all of tags
+func (s FleetStatus) Tagged(tags map[string]string) FleetStatus {
+    if len(tags) == 0 {
+        return s
+    }
+    var instances []FleetMemberStatus
+    for _, instance := range s.Instances {
+        if HasTags(instance.Tags, tags) {
+            instances = append(instances, instance)
+        }
+    }
+    return NewFleetStatus(instances, s.Time)
+}
+
+// ParseTagFilters parses filters of the form "tag:KEY=VALUE", or just
+// "KEY=VALUE", into the tags instances must have
+func ParseTagFilters(filters []string) (map[string]string, error) {
+    tags := make(map[string]string, len(filters))
+    for _, filter := range filters {
+        key, value, found := strings.Cut(strings.TrimPrefix(filter, "tag:"), "=")
+        if !found || key == "" {
+            return nil, fmt.Errorf("invalid filter %q, expected tag:KEY=VALUE", filter)
+        }
+        tags[key] = value
+    }
+    return tags, nil
+}
+
+// FleetInstance is the state and addresses of an instance
+type FleetInstance struct {
+    ID        string `json:"id"`
+    State     string `json:"state"` // pending, running, stopping, stopped, ...
+    PrivateIP string `json:"private_ip,omitempty"`
+    PublicIP  string `json:"public_ip,omitempty"`
+}
+
+// SnoozedInstance is an instance stopped by a CloudSnooze daemon
+type SnoozedInstance struct {
+    ID        string            `json:"id"`
+    Name      string            `json:"name,omitempty"`
+    StoppedAt time.Time         `json:"stopped_at"`
+    Reason    string            `json:"reason,omitempty"`
+    WakeAt    *time.Time        `json:"wake_at,omitempty"` // When it must be started again, if its daemon limits snoozes
+    Tags      map[string]string `json:"tags,omitempty"`
+}
+
 // InstanceInfo contains information about the current cloud instance
 type InstanceInfo struct {
     ID         string
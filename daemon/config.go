@@ -3,11 +3,17 @@
 
 package main
 
+import "path/filepath"
+
 // Config represents the complete configuration
 type Config struct {
 	// General settings
 	CheckIntervalSeconds int     `json:"check_interval_seconds"`
 	NaptimeMinutes       int     `json:"naptime_minutes"`
+	CountdownSeconds     int     `json:"countdown_seconds"` // Grace period before stopping during which the stop can be cancelled
+	BootGraceMinutes     int     `json:"boot_grace_minutes"` // Don't snooze for idleness this soon after launch
+	MaxSnoozeHours       int     `json:"max_snooze_hours"`   // Have the restarter start the instance again this long after snoozing (0 for no limit)
+	StatePath            string  `json:"state_path"`         // Where the idle timer is kept across daemon restarts (empty to disable)
 	
 	// Thresholds
 	CPUThresholdPercent    float64 `json:"cpu_threshold_percent"`
@@ -16,17 +22,49 @@ type Config struct {
 	DiskIOThresholdKBps    float64 `json:"disk_io_threshold_kbps"`
 	InputIdleThresholdSecs int     `json:"input_idle_threshold_secs"`
 	
+	// How a metric that fails to collect counts: "busy", or "last_value"
+	// until the last collected value is older than CollectionStaleSecs
+	CollectionFailurePolicy string `json:"collection_failure_policy"`
+	CollectionStaleSecs     int    `json:"collection_stale_secs"`
+	CollectionTimeoutSecs   int    `json:"collection_timeout_secs"` // How long each monitor may take to collect
+	
 	// GPU/Accelerator settings
 	GPUMonitoringEnabled bool    `json:"gpu_monitoring_enabled"`
 	GPUThresholdPercent  float64 `json:"gpu_threshold_percent"`
 	
 	// Cloud provider settings
 	ProviderType         string `json:"provider_type"`       // Which cloud provider to use (empty for auto-detection)
+	ProviderSettings     map[string]interface{} `json:"provider_settings"` // Configuration passed to plugin providers
 	
 	// AWS settings
 	AWSRegion          string `json:"aws_region"`
 	EnableInstanceTags bool   `json:"enable_instance_tags"`
 	TaggingPrefix      string `json:"tagging_prefix"`
+	SNSTopicARN        string `json:"sns_topic_arn"` // Publish snooze events to this SNS topic (empty to disable)
+	CloudWatchMetrics   bool   `json:"cloudwatch_metrics"`   // Publish idle state as CloudWatch custom metrics
+	CloudWatchNamespace string `json:"cloudwatch_namespace"` // Namespace of the custom metrics
+	AWSPartition        string `json:"aws_partition"`         // Partition such as aws-cn or aws-us-gov (empty to detect)
+	EC2Endpoint         string `json:"ec2_endpoint"`          // Custom EC2 API endpoint, e.g. LocalStack (empty for the default)
+	MetadataEndpoint    string `json:"metadata_endpoint"`     // Custom instance metadata endpoint (empty for the default)
+	AssumeRoleARN       string `json:"assume_role_arn"`       // Role assumed to stop the instance, e.g. from a central account (empty to disable)
+	AssumeRoleExternalID string `json:"assume_role_external_id" secret:"true"` // External ID required by the role's trust policy
+	StopAction          string `json:"stop_action"`           // "stop", "hibernate" or "warm_pool" (falls back to stop where unsupported), "suspend" or "script"
+	StopScript          string `json:"stop_script"`           // Executable run by the "script" stop action
+	StopScriptTimeoutSecs int  `json:"stop_script_timeout_secs"` // Longest the stop script may run
+	PricingLookup       bool   `json:"pricing_lookup"`        // Look up the on-demand price for savings estimates if hourly_cost_usd is 0
+	PricingCachePath    string `json:"pricing_cache_path"`    // Where looked up prices are cached
+	ELBDeregister       bool     `json:"elb_deregister"`          // Leave load balancer target groups before stopping
+	ELBTargetGroups     []string `json:"elb_target_groups"`       // Target group ARNs to leave (empty to find them)
+	ELBDrainTimeoutSecs int      `json:"elb_drain_timeout_secs"`  // Longest wait for connection draining
+	AWSRetryAttempts    int      `json:"aws_retry_attempts"`      // Tries of EC2 calls that are throttled or hit network errors
+	Route53ZoneID       string   `json:"route53_zone_id"`         // Hosted zone of a record that follows the instance (empty to disable)
+	Route53RecordName   string   `json:"route53_record_name"`     // A record pointed at the instance on start, e.g. dev.example.com
+	Route53ParkedIP     string   `json:"route53_parked_ip"`       // Address the record points at while snoozed (empty to delete the record)
+	Route53TTL          int      `json:"route53_ttl"`             // TTL of the record in seconds
+	Route53PrivateIP    bool     `json:"route53_private_ip"`      // Point the record at the private address instead of the public one
+	StateTable          string   `json:"state_table"`             // DynamoDB table the daemon records its state in (empty to disable)
+	StateTableSeconds   int      `json:"state_table_seconds"`     // How often the state is recorded, besides when the instance snoozes
+	StateTableTTLHours  int      `json:"state_table_ttl_hours"`   // Hours after its last update a row expires, for the table's TTL (0 to keep rows)
 	
 	// Tag-based monitoring for external tools
 	DetailedInstanceTags    bool `json:"detailed_instance_tags"`     // Whether to add detailed tags about the stop reason
@@ -42,47 +80,797 @@ type Config struct {
 	// Plugin settings
 	PluginsEnabled bool   `json:"plugins_enabled"`     // Whether to use the plugin system
 	PluginsDir     string `json:"plugins_dir"`         // Directory to load external plugins from
+	DisabledPlugins []string `json:"disabled_plugins"` // IDs of plugins that must not be used
+	PluginLimits   PluginLimitsConfig `json:"plugin_limits"` // Resource limits for out-of-process plugins
+	
+	// Schedule settings
+	Schedule ScheduleConfig `json:"schedule"`
+	
+	// History settings
+	HistoryFile      string `json:"history_file"`       // Where snooze events are recorded
+	HistoryMaxEvents int    `json:"history_max_events"` // Maximum number of events retained
+	DecisionLogSize  int    `json:"decision_log_size"`  // Number of check decisions kept for the DECISIONS command
+	
+	// Notification settings
+	Notifications NotificationsConfig `json:"notifications"`
+	
+	// Who may use the API socket
+	Socket SocketConfig `json:"socket"`
+	
+	// API over TCP with client certificates, for fleet tooling
+	RemoteAPI RemoteAPIConfig `json:"remote_api"`
+	
+	// Commands particular API clients are limited to
+	ClientAllowlists []ClientAllowlistConfig `json:"client_allowlists"`
+	
+	// The user the daemon runs as once started
+	Privileges PrivilegesConfig `json:"privileges"`
+	
+	// Sealed store that secret settings can refer to
+	Secrets SecretsConfig `json:"secrets"`
+	
+	// Starting snoozed instances again, when run with -restarter
+	Restarter RestarterConfig `json:"restarter"`
+	
+	// Snoozing a fleet from its metrics, when run with -controller
+	Controller ControllerConfig `json:"controller"`
+	
+	// Pushing status and history to an aggregation server
+	Aggregation AggregationConfig `json:"aggregation"`
+	
+	// Serving the fleet-wide API daemons push to, when run with -aggregator
+	Aggregator AggregatorConfig `json:"aggregator"`
+	
+	// Commands saving state before a snooze and restoring it after
+	Hooks HooksConfig `json:"hooks"`
+	
+	// Asking training jobs to checkpoint before the instance stops
+	Training TrainingConfig `json:"training"`
+	
+	// Checkpointing databases before the instance stops
+	Databases DatabasesConfig `json:"databases"`
+	
+	// Stopping containers before the instance stops
+	Docker DockerConfig `json:"docker"`
+	
+	// Watching the host when the daemon runs in a container
+	Container ContainerConfig `json:"container"`
+	
+	// Kubernetes node draining
+	Kubernetes KubernetesConfig `json:"kubernetes"`
+	
+	// Flushing and unmounting filesystems before the instance stops
+	Filesystems FilesystemsConfig `json:"filesystems"`
+	
+	// Steps of a snooze, in order (empty for the default pipeline)
+	Pipeline []PipelineStepConfig `json:"pipeline"`
+	
+	// Requests to keep the instance running for a while
+	Leases LeaseConfig `json:"leases"`
+	
+	// Instances that depend on this one, which keep it running while active
+	Dependencies DependenciesConfig `json:"dependencies"`
+	
+	// Replicas of this instance, enough of which must keep running
+	Peers PeersConfig `json:"peers"`
+	
+	// Audit log of API commands
+	Audit AuditConfig `json:"audit"`
+	
+	// Scheduled maintenance of the instance
+	Maintenance MaintenanceConfig `json:"maintenance"`
+	
+	// Checking more or less often depending on how close the system is to snoozing
+	AdaptiveInterval AdaptiveIntervalConfig `json:"adaptive_interval"`
+	
+	// Metrics export
+	Telemetry TelemetryConfig `json:"telemetry"` // OpenTelemetry metrics and traces
+	StatsD    StatsDConfig    `json:"statsd"`
+	
+	// Diagnosing the daemon itself
+	Debug DebugConfig `json:"debug"`
+}
+
+// NotificationsConfig defines where snooze notifications are sent
+type NotificationsConfig struct {
+	HourlyCostUSD float64        `json:"hourly_cost_usd"` // Instance cost used for savings estimates (0 to omit)
+	WarnUsers     bool           `json:"warn_users"`      // Warn logged-in sessions when the pre-stop countdown starts
+	TitleTemplate string         `json:"title_template"`  // Go template for notification titles (empty for the default)
+	BodyTemplate  string         `json:"body_template"`   // Go template for notification bodies (empty for the default)
+	QueuePath     string         `json:"queue_path"`      // Where undelivered notifications are kept for retry
+	QueueMaxEntries int          `json:"queue_max_entries"` // Most undelivered notifications kept; the oldest are dropped
+	Slack         SlackConfig    `json:"slack"`
+	Teams         TeamsConfig    `json:"teams"`
+	Email         EmailConfig    `json:"email"`
+	Alerting      AlertingConfig `json:"alerting"`
+
+	// Settings for notifier plugins by plugin ID; listed plugins are enabled
+	Plugins map[string]map[string]interface{} `json:"plugins"`
+}
+
+// AlertingConfig defines incident alerting for error conditions
+type AlertingConfig struct {
+	Enabled                    bool   `json:"enabled"`
+	Service                    string `json:"service"`                      // "pagerduty" or "opsgenie"
+	RoutingKey                 string `json:"routing_key" secret:"true"`    // PagerDuty Events API v2 integration key
+	APIKey                     string `json:"api_key" secret:"true"`        // Opsgenie API key
+	CollectionFailureThreshold int    `json:"collection_failure_threshold"` // Consecutive metric collection failures before alerting
+	PermissionCheckMinutes     int    `json:"permission_check_minutes"`     // How often cloud permissions are re-verified (0 for startup only)
+	StopFailureThreshold       int    `json:"stop_failure_threshold"`       // Consecutive failed stops before snoozing is suspended (0 to never suspend)
+}
+
+// SlackConfig defines the Slack notifier. Use either an incoming webhook
+// or a bot token with a channel.
+type SlackConfig struct {
+	Enabled    bool   `json:"enabled"`
+	WebhookURL string `json:"webhook_url" secret:"true"`
+	BotToken   string `json:"bot_token" secret:"true"`
+	Channel    string `json:"channel"`
+	Username   string `json:"username"`
+}
+
+// TeamsConfig defines the Microsoft Teams notifier
+type TeamsConfig struct {
+	Enabled    bool   `json:"enabled"`
+	WebhookURL string `json:"webhook_url" secret:"true"` // Incoming webhook or workflow URL
+}
+
+// EmailConfig defines the SMTP email notifier
+type EmailConfig struct {
+	Enabled         bool     `json:"enabled"`
+	SMTPHost        string   `json:"smtp_host"`
+	SMTPPort        int      `json:"smtp_port"`
+	Username        string   `json:"username"`
+	Password        string   `json:"password" secret:"true"`
+	UseTLS          bool     `json:"use_tls"` // Implicit TLS (port 465); STARTTLS is used automatically otherwise
+	From            string   `json:"from"`
+	To              []string `json:"to"`
+	SubjectTemplate string   `json:"subject_template"` // Go template, empty for the default
+	BodyTemplate    string   `json:"body_template"`    // Go template, empty for the default
+}
+
+// ScheduleConfig defines time-based behavior
+type ScheduleConfig struct {
+	Timezone                string         `json:"timezone"`                   // IANA time zone for schedules (empty for instance local time)
+	Calendar                CalendarConfig `json:"calendar"`
+	DailyRuntimeBudgetHours float64        `json:"daily_runtime_budget_hours"` // Maximum running hours per day (0 to disable)
+	BudgetWarningMinutes    int            `json:"budget_warning_minutes"`     // How long before the budget is used up to warn
+	BudgetStatePath         string         `json:"budget_state_path"`          // Where budget consumption is persisted
+	Weekend                 WeekendConfig  `json:"weekend"`
+}
+
+// WeekendConfig defines thresholds used on weekend days instead of the
+// top-level values. Zero values inherit the weekday setting.
+type WeekendConfig struct {
+	Enabled                bool     `json:"enabled"`
+	Days                   []string `json:"days"` // Days counted as the weekend
+	NaptimeMinutes         int      `json:"naptime_minutes"`
+	CPUThresholdPercent    float64  `json:"cpu_threshold_percent"`
+	MemoryThresholdPercent float64  `json:"memory_threshold_percent"`
+	NetworkThresholdKBps   float64  `json:"network_threshold_kbps"`
+	DiskIOThresholdKBps    float64  `json:"disk_io_threshold_kbps"`
+	InputIdleThresholdSecs int      `json:"input_idle_threshold_secs"`
+	GPUThresholdPercent    float64  `json:"gpu_threshold_percent"`
+}
+
+// CalendarConfig defines the iCal maintenance window integration
+type CalendarConfig struct {
+	Enabled             bool     `json:"enabled"`
+	URL                 string   `json:"url" secret:"true"`     // iCal feed URL, which may embed an access key
+	RefreshMinutes      int      `json:"refresh_minutes"`       // How often to re-fetch the feed
+	CachePath           string   `json:"cache_path"`            // Where the last fetched feed is cached for offline use
+	DefaultWindow       string   `json:"default_window"`        // "blackout" or "force-active" for events matching no keyword
+	BlackoutKeywords    []string `json:"blackout_keywords"`     // Events matching these keywords suppress stopping
+	ForceActiveKeywords []string `json:"force_active_keywords"` // Events matching these keywords keep the system active
 }
 
 // LoggingConfig defines logging behavior
 type LoggingConfig struct {
 	LogLevel           string `json:"log_level"` // "debug", "info", "warn", "error"
+	LogFormat          string `json:"log_format"` // "text" or "json"
 	EnableFileLogging  bool   `json:"enable_file_logging"`
 	LogFilePath        string `json:"log_file_path"`
+	MaxSizeMB          int    `json:"max_size_mb"`   // Rotate the log file at this size
+	MaxAgeHours        int    `json:"max_age_hours"` // Rotate the log file at least this often
+	MaxBackups         int    `json:"max_backups"`   // Rotated log files to keep
+	Compress           bool   `json:"compress"`      // Gzip rotated log files
 	EnableSyslog       bool   `json:"enable_syslog"`
 	EnableCloudWatch   bool   `json:"enable_cloudwatch"`
 	CloudWatchLogGroup string `json:"cloudwatch_log_group"`
 }
 
+// TelemetryConfig defines OTLP export of metrics and traces
+type TelemetryConfig struct {
+	Enabled            bool              `json:"enabled"`
+	OTLPEndpoint       string            `json:"otlp_endpoint"`        // Collector host:port (OTLP/HTTP); empty uses OTEL_EXPORTER_OTLP_ENDPOINT
+	Insecure           bool              `json:"insecure"`             // Use HTTP instead of HTTPS
+	Headers            map[string]string `json:"headers" secret:"true"` // Extra request headers, e.g. API keys
+	ServiceName        string            `json:"service_name"`
+	ExportIntervalSecs int               `json:"export_interval_secs"` // How often metrics are pushed
+}
+
+// DebugConfig defines the profiling listener
+type DebugConfig struct {
+	PprofAddr string `json:"pprof_addr"` // Loopback host:port serving net/http/pprof, empty to disable
+}
+
+// SocketConfig defines access to the API socket
+type SocketConfig struct {
+	Group          string          `json:"group"`            // Group that owns the socket, e.g. "snooze" (empty for the daemon's group)
+	Mode           string          `json:"mode"`             // Octal permissions of the socket file
+	AdminGroup     string          `json:"admin_group"`      // Members may run administrative commands, besides root
+	AdminTokenFile string          `json:"admin_token_file"` // File holding a token that allows administrative commands (empty to disable)
+	RateLimit      RateLimitConfig `json:"rate_limit"`       // Commands handled per second, by the socket and the TCP API each
+}
+
+// RateLimitConfig bounds how quickly clients may send commands, so a
+// script polling in a tight loop can't slow the daemon down
+type RateLimitConfig struct {
+	GlobalPerSecond float64 `json:"global_per_second"` // Commands from all clients (0 for no limit)
+	ClientPerSecond float64 `json:"client_per_second"` // Commands from one user or certificate (0 for no limit)
+	Burst           int     `json:"burst"`             // Commands allowed at once above the rates
+}
+
+// RemoteAPIConfig defines the TCP API. Clients must present a certificate
+// signed by the client CA.
+type RemoteAPIConfig struct {
+	ListenAddr   string `json:"listen_addr"`    // host:port to listen on, empty to disable
+	CertFile     string `json:"cert_file"`      // Server certificate
+	KeyFile      string `json:"key_file"`       // Server private key
+	ClientCAFile string `json:"client_ca_file"` // CA that signs client certificates
+}
+
+// ClientAllowlistConfig limits one API client, identified by exactly one of
+// its user, token or certificate, to some commands
+type ClientAllowlistConfig struct {
+	User        string   `json:"user,omitempty"`        // Local user name or UID
+	TokenFile   string   `json:"token_file,omitempty"`  // File holding a token the client sends
+	Certificate string   `json:"certificate,omitempty"` // Common name of a TCP API client certificate
+	Commands    []string `json:"commands"`              // Commands the client may run, "*" for all
+}
+
+// PrivilegesConfig defines the user the daemon switches to after starting
+// as root, once the socket is bound and the config is read
+type PrivilegesConfig struct {
+	User  string `json:"user"`  // User to run as, e.g. "snooze" (empty to keep running as root)
+	Group string `json:"group"` // Group to run as (empty for the user's primary group)
+}
+
+// SecretsConfig defines the sealed store of integration credentials.
+// Secret settings such as webhook URLs may be "secret:NAME" to use the
+// secret NAME from the store.
+type SecretsConfig struct {
+	StorePath string `json:"store_path"` // Secrets sealed with AES-256-GCM
+	KeyFile   string `json:"key_file"`   // Key of the store, readable only by root
+}
+
+// RestarterConfig defines the restarter (snoozed -restarter), which runs on
+// an always-on host and starts instances snoozed by other daemons
+type RestarterConfig struct {
+	PollSeconds      int                       `json:"poll_seconds"`       // How often schedules are checked
+	Schedules        []RestarterScheduleConfig `json:"schedules"`          // When to start snoozed instances
+	WebhookAddr      string                    `json:"webhook_addr"`       // host:port accepting POST /wake/INSTANCE_ID (empty to disable)
+	WebhookTokenFile string                    `json:"webhook_token_file"` // File holding the bearer token webhook requests must send
+	WebhookCertFile  string                    `json:"webhook_cert_file"`  // Certificate to serve the webhook over HTTPS (empty for HTTP)
+	WebhookKeyFile   string                    `json:"webhook_key_file"`   // Private key of the webhook certificate
+	WakeOnSSH        []WakeOnSSHConfig         `json:"wake_on_ssh"`        // Listeners forwarding SSH to instances, starting them first
+	WakeTimeoutSecs  int                       `json:"wake_timeout_secs"`  // How long an SSH connection waits for its instance to start
+}
+
+// ControllerConfig defines the controller (snoozed -controller), which
+// snoozes tagged instances from their CloudWatch metrics, without a daemon
+// on each. Instances are idle below cpu_threshold_percent and
+// network_threshold_kbps for naptime_minutes, unless a group sets its own.
+type ControllerConfig struct {
+	Tags        map[string]string       `json:"tags"`         // Tags instances must have to be snoozed
+	Groups      []ControllerGroupConfig `json:"groups"`       // Policies by tag; with groups, only instances in one are snoozed
+	PollSeconds int                     `json:"poll_seconds"` // How often instances are checked
+	DryRun      bool              `json:"dry_run"`      // Log the instances that would be stopped without stopping them
+	
+	StatusAddr      string `json:"status_addr"`       // host:port serving GET /v1/fleet (empty to disable)
+	StatusTokenFile string `json:"status_token_file"` // File holding the bearer token status requests must send
+	StatusCertFile  string `json:"status_cert_file"`  // Certificate to serve the status over HTTPS (empty for HTTP)
+	StatusKeyFile   string `json:"status_key_file"`   // Private key of the status certificate
+}
+
+// ControllerGroupConfig is the policy for instances with all of the
+// group's tags, read again at every check. An instance in several groups
+// follows the first listed. Zero thresholds and naptime are the top-level
+// ones.
+type ControllerGroupConfig struct {
+	Name                 string            `json:"name"`                   // Shown in decisions and the fleet status
+	Tags                 map[string]string `json:"tags"`                   // Tags instances of the group have, besides controller.tags
+	NaptimeMinutes       int               `json:"naptime_minutes"`        // How long instances must be idle
+	CPUThresholdPercent  float64           `json:"cpu_threshold_percent"`  // Peak CPU utilization below which instances are idle
+	NetworkThresholdKBps float64           `json:"network_threshold_kbps"` // Peak traffic below which instances are idle
+	Disabled             bool              `json:"disabled"`               // Never snooze the group's instances
+}
+
+// AggregationConfig defines pushing this daemon's STATUS and recent
+// history to an aggregation server. Commands the server queued for the
+// instance are run when it replies, as a TLS client named "aggregator".
+type AggregationConfig struct {
+	ServerURL     string `json:"server_url"`     // Server to push to, such as https://snooze.example.com:8443 (empty to disable)
+	TokenFile     string `json:"token_file"`     // File holding this daemon's report token (see -report-token)
+	CAFile        string `json:"ca_file"`        // CA that signed the server's certificate (empty for the system's)
+	PushSeconds   int    `json:"push_seconds"`   // How often reports are pushed
+	HistoryEvents int    `json:"history_events"` // Number of recent snooze events in each report
+}
+
+// AggregatorConfig defines the aggregation server (snoozed -aggregator),
+// which collects reports from many daemons and serves them to dashboards
+type AggregatorConfig struct {
+	ListenAddr       string   `json:"listen_addr"`        // host:port to serve the API on
+	TokenFile        string   `json:"token_file"`         // File holding the bearer token for dashboards and bulk operations
+	ReportSecretFile string   `json:"report_secret_file"` // File holding the secret each daemon's report token is derived from (see -report-token)
+	CertFile         string   `json:"cert_file"`          // Certificate to serve HTTPS (empty for HTTP)
+	KeyFile          string   `json:"key_file"`           // Private key of the certificate
+	StaleSeconds     int      `json:"stale_seconds"`      // How long without a report before an instance is stale
+	Commands         []string `json:"commands"`           // Commands that may be queued for daemons
+}
+
+// WakeOnSSHConfig forwards connections on an address to an instance's SSH
+// server, starting the instance if it is snoozed
+type WakeOnSSHConfig struct {
+	ListenAddr string `json:"listen_addr"`      // host:port the instance's DNS name points at
+	InstanceID string `json:"instance_id"`      // Instance to start and forward to
+	Target     string `json:"target,omitempty"` // host:port to forward to (empty for port 22 of the instance's private address)
+}
+
+// RestarterScheduleConfig starts snoozed instances at a time of day, in
+// schedule.timezone
+type RestarterScheduleConfig struct {
+	Time      string            `json:"time"`                // Time of day, e.g. "08:00"
+	Days      []string          `json:"days,omitempty"`      // Weekdays, e.g. ["mon", "tue"] (empty for every day)
+	Instances []string          `json:"instances,omitempty"` // Instance IDs (empty for every snoozed instance)
+	Tags      map[string]string `json:"tags,omitempty"`      // Tags the instances must have
+}
+
+// HooksConfig defines commands run around a snooze. Check commands can
+// veto or defer it. The output of each pre_stop command is kept until the
+// instance starts again and given to its post_start command. Event
+// commands run as the idle state changes and when snoozes happen.
+type HooksConfig struct {
+	Commands    []HookConfig `json:"commands"`     // Paired commands, run in order
+	Events      []EventHookConfig `json:"events"` // Commands run on lifecycle events, in order
+	ContextPath string       `json:"context_path"` // Where pre_stop output is kept until post_start
+	TimeoutSecs int          `json:"timeout_secs"` // Longest each command may run; a check that takes longer vetoes the snooze
+}
+
+// HookConfig is a set of commands; any may be empty
+type HookConfig struct {
+	Name      string `json:"name"`                 // Identifies the saved output
+	Check     string `json:"check,omitempty"`      // Executable run before snoozing; exit 75 defers the snooze and other failures veto it
+	PreStop   string `json:"pre_stop,omitempty"`   // Executable run before the instance stops, whose output is saved
+	PostStart string `json:"post_start,omitempty"` // Executable run after it starts, given the saved output on stdin
+}
+
+// TrainingConfig defines the training jobs asked to save a checkpoint
+// before the instance stops, so they can resume where they left off
+type TrainingConfig struct {
+	Checkpoints []TrainingCheckpointConfig `json:"checkpoints"`  // Training jobs to checkpoint, all at once
+	TimeoutSecs int                        `json:"timeout_secs"` // Longest wait for each job to confirm its checkpoint
+}
+
+// TrainingCheckpointConfig is a training job signalled, or called over
+// HTTP, to save a checkpoint
+type TrainingCheckpointConfig struct {
+	Name     string `json:"name"`              // Identifies the job in logs
+	PIDFile  string `json:"pid_file"`          // File holding the process ID to signal
+	Process  string `json:"process"`           // Text in the command lines of the processes to signal
+	Signal   string `json:"signal"`            // Signal sent (empty for SIGUSR1)
+	URL      string `json:"url" secret:"true"` // Endpoint POSTed to instead of sending a signal
+	DoneFile string `json:"done_file"`         // File the job writes once its checkpoint is saved
+	Required bool   `json:"required"`          // Abandon the snooze if the checkpoint isn't confirmed
+}
+
+// DatabasesConfig defines the databases checkpointed before the instance
+// stops, so they start again without crash recovery
+type DatabasesConfig struct {
+	Checkpoints []DatabaseCheckpointConfig `json:"checkpoints"`  // Databases to checkpoint, all at once
+	TimeoutSecs int                        `json:"timeout_secs"` // Longest each checkpoint may take
+}
+
+// DatabaseCheckpointConfig is a database checkpointed with its client
+type DatabaseCheckpointConfig struct {
+	Name      string `json:"name"`                   // Identifies the database in logs
+	Engine    string `json:"engine"`                 // postgres or mysql
+	Host      string `json:"host"`                   // Server host (empty for the local socket)
+	Port      int    `json:"port"`                   // Server port (0 for the engine's default)
+	User      string `json:"user"`                   // User allowed to checkpoint (empty for the client's default)
+	Password  string `json:"password" secret:"true"` // Password, usually "secret:NAME"
+	Database  string `json:"database"`               // Database to connect to
+	Statement string `json:"statement"`              // SQL to run (empty for CHECKPOINT or FLUSH TABLES)
+	Client    string `json:"client"`                 // Client binary (empty for psql or mysql)
+}
+
+// DockerConfig defines how running containers are stopped before the
+// instance stops, so they can shut down gracefully
+type DockerConfig struct {
+	StopContainers  bool     `json:"stop_containers"`   // Stop running containers through the Docker API before stopping
+	SocketPath      string   `json:"socket_path"`       // Docker API socket
+	StopTimeoutSecs int      `json:"stop_timeout_secs"` // How long containers get to exit after SIGTERM before they're killed
+	Exclude         []string `json:"exclude"`           // Names of containers left running
+	StatePath       string   `json:"state_path"`        // Where stopped containers are listed so they're started again (empty to leave them stopped)
+}
+
+// ContainerConfig defines where the host's filesystems are mounted when the
+// daemon runs in a container, so it watches the host rather than the
+// container
+type ContainerConfig struct {
+	Enabled  bool   `json:"enabled"`   // Read host metrics from the mounts below
+	HostProc string `json:"host_proc"` // Where the host's /proc is mounted
+	HostSys  string `json:"host_sys"`  // Where the host's /sys is mounted
+	HostDev  string `json:"host_dev"`  // Where the host's /dev is mounted, for input (empty to not watch input)
+}
+
+// KubernetesConfig defines how the local Kubernetes node is drained before
+// the instance stops, so its pods move elsewhere within their disruption
+// budgets
+type KubernetesConfig struct {
+	DrainNode          bool   `json:"drain_node"`           // Cordon and drain the node with kubectl before stopping
+	NodeName           string `json:"node_name"`            // Node to drain (empty for the hostname)
+	Kubectl            string `json:"kubectl"`              // kubectl binary
+	Kubeconfig         string `json:"kubeconfig"`           // kubeconfig with rights to drain the node (empty for kubectl's default)
+	DrainTimeoutSecs   int    `json:"drain_timeout_secs"`   // How long evictions may take before the snooze is abandoned
+	DeleteEmptyDirData bool   `json:"delete_emptydir_data"` // Evict pods using emptyDir volumes, losing their data
+	Force              bool   `json:"force"`                // Delete pods not managed by a controller, which won't be recreated
+	StatePath          string `json:"state_path"`           // Where the drained node is recorded so it's uncordoned on start (empty to leave it cordoned)
+}
+
+// FilesystemsConfig defines how filesystems are prepared for a stop, which
+// the instance may not shut down cleanly enough to do itself
+type FilesystemsConfig struct {
+	SyncBeforeStop   bool     `json:"sync_before_stop"`   // Flush cached writes and wait for dirty pages to be written
+	Unmount          []string `json:"unmount"`            // Mount points to unmount, such as network or scratch mounts
+	FlushTimeoutSecs int      `json:"flush_timeout_secs"` // Longest the stop waits for syncing and unmounting
+}
+
+// PipelineStepConfig is a step of the snooze pipeline
+type PipelineStepConfig struct {
+	Step        string `json:"step"`         // notify, hooks, training, databases, drain, containers, snapshot, filesystems, command or stop
+	TimeoutSecs int    `json:"timeout_secs"` // Longest the step may take (0 for no limit of its own)
+	OnFailure   string `json:"on_failure"`   // "abort" to abandon the snooze or "continue" with the next step (empty for abort)
+	Command     string `json:"command"`      // Executable run by a command step
+	Keep        int    `json:"keep"`         // Snapshots kept per volume by a snapshot step (0 keeps them all)
+}
+
+// LeaseConfig defines how clients may keep the instance running with the
+// LEASE command
+type LeaseConfig struct {
+	MaxHours  float64 `json:"max_hours"`  // Longest lease a client may take (0 for no limit)
+	StatePath string  `json:"state_path"` // Where leases are kept across restarts (empty to keep them in memory)
+}
+
+// DependenciesConfig declares the instances that depend on this one, such
+// as the scheduler a worker serves. The instance isn't snoozed while any of
+// them is active. The aggregation server knows whether a dependent is idle;
+// to the cloud provider, a running dependent is active.
+type DependenciesConfig struct {
+	Instances     []string          `json:"instances"`      // IDs of instances that depend on this one
+	Tags          map[string]string `json:"tags"`           // Tags of running instances that depend on this one
+	Source        string            `json:"source"`         // "aggregator" or "cloud" (empty for aggregator when aggregation.server_url is set)
+	CheckSeconds  int               `json:"check_seconds"`  // How long the states read are reused
+	IgnoreUnknown bool              `json:"ignore_unknown"` // Snooze when a dependent's state can't be read, instead of staying up
+}
+
+// PeersConfig declares the instances that are replicas of this one, such
+// as the other member of a pair. The instance isn't snoozed if it would
+// leave fewer than min_running peers running. Peers that are idle at the
+// same time take turns: the one with the lowest instance ID snoozes first.
+type PeersConfig struct {
+	Instances    []string          `json:"instances"`     // IDs of peers; this instance's own ID is skipped, so peers can share the list
+	Tags         map[string]string `json:"tags"`          // Tags of running instances that are peers
+	Source       string            `json:"source"`        // "aggregator" or "cloud" (empty for aggregator when aggregation.server_url is set)
+	MinRunning   int               `json:"min_running"`   // Peers that must keep running after this instance snoozes
+	CheckSeconds int               `json:"check_seconds"` // How long the states read are reused
+}
+
+// EventHookConfig is a command run on lifecycle events
+type EventHookConfig struct {
+	Name    string   `json:"name"`    // Identifies the command in logs
+	Events  []string `json:"events"`  // idle_start, idle_end, snooze_executed or snooze_failed
+	Command string   `json:"command"` // Executable run on each of the events
+}
+
+// AuditConfig defines where API commands are recorded
+type AuditConfig struct {
+	LogPath    string `json:"log_path"`    // JSON lines file, empty to keep records in memory only
+	BufferSize int    `json:"buffer_size"` // Records kept in memory for the AUDIT command
+	MaxSizeMB  int    `json:"max_size_mb"` // Rotate the file at this size
+	MaxBackups int    `json:"max_backups"` // Rotated files to keep
+}
+
+// MaintenanceConfig defines how scheduled maintenance events are handled
+type MaintenanceConfig struct {
+	Enabled      bool `json:"enabled"`       // Poll the provider for scheduled events
+	PollMinutes  int  `json:"poll_minutes"`  // How often events are polled
+	GuardMinutes int  `json:"guard_minutes"` // Don't snooze this long before an event starts, 0 to only notify
+	Notify       bool `json:"notify"`        // Send a notification when an event is scheduled
+}
+
+// AdaptiveIntervalConfig varies the check interval between MinSeconds, close
+// to a snooze decision, and MaxSeconds, while the system is clearly busy
+type AdaptiveIntervalConfig struct {
+	Enabled    bool `json:"enabled"`
+	MinSeconds int  `json:"min_seconds"` // Interval while a stop is near
+	MaxSeconds int  `json:"max_seconds"` // Longest interval while busy
+}
+
+// StatsDConfig defines where StatsD metrics are sent
+type StatsDConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Host      string   `json:"host"`
+	Port      int      `json:"port"`
+	Prefix    string   `json:"prefix"`    // Prepended to every metric name
+	Tags      []string `json:"tags"`      // Tags added to every metric, e.g. "env:prod"
+	DogStatsD bool     `json:"dogstatsd"` // Use DogStatsD tags instead of folding tag values into names
+}
+
+// PluginLimitsConfig bounds the resources each out-of-process plugin may use
+type PluginLimitsConfig struct {
+	CPUPercent float64 `json:"cpu_percent"` // Share of one CPU, 0 for no limit
+	MemoryMB   int     `json:"memory_mb"`   // Resident memory, 0 for no limit
+}
+
 // DefaultConfig returns the default configuration
 func DefaultConfig() Config {
 	return Config{
 		CheckIntervalSeconds:    60,
 		NaptimeMinutes:          30,
+		CountdownSeconds:        300,
+		BootGraceMinutes:        15,
+		MaxSnoozeHours:          0,
+		StatePath:               filepath.Join(dataDir, "state.json"),
 		CPUThresholdPercent:     10.0,
 		MemoryThresholdPercent:  30.0,
 		NetworkThresholdKBps:    50.0,
 		DiskIOThresholdKBps:     100.0,
 		InputIdleThresholdSecs:  900,
+		CollectionFailurePolicy: "busy",
+		CollectionStaleSecs:     300,
+		CollectionTimeoutSecs:   10,
 		GPUMonitoringEnabled:    true,
 		GPUThresholdPercent:     5.0,
 		ProviderType:            "",  // Empty for auto-detection
 		AWSRegion:               "us-east-1",
 		EnableInstanceTags:      true,
 		TaggingPrefix:           "CloudSnooze",
+		CloudWatchMetrics:       false,
+		CloudWatchNamespace:     "CloudSnooze",
+		StopAction:              "stop",
+		StopScript:              "",
+		StopScriptTimeoutSecs:   300,
+		PricingLookup:           true,
+		PricingCachePath:        filepath.Join(dataDir, "pricing.json"),
+		ELBDeregister:           false,
+		ELBDrainTimeoutSecs:     300,
+		AWSRetryAttempts:        4,
+		Route53ZoneID:           "",
+		Route53RecordName:       "",
+		Route53ParkedIP:         "",
+		Route53TTL:              60,
+		Route53PrivateIP:        false,
+		StateTable:              "",
+		StateTableSeconds:       300,
+		StateTableTTLHours:      0,
 		DetailedInstanceTags:    true,
 		TagPollingEnabled:       true,
 		TagPollingIntervalSecs:  60,  // 1 minute by default
 		Logging: LoggingConfig{
 			LogLevel:           "info",
+			LogFormat:          "text",
 			EnableFileLogging:  true,
-			LogFilePath:        "/var/log/cloudsnooze.log",
+			LogFilePath:        filepath.Join(logDir, "cloudsnooze.log"),
+			MaxSizeMB:          100,
+			MaxAgeHours:        24,
+			MaxBackups:         7,
+			Compress:           true,
 			EnableSyslog:       false,
 			EnableCloudWatch:   false,
 			CloudWatchLogGroup: "CloudSnooze",
 		},
 		MonitoringMode: "basic",
 		PluginsEnabled: true,
-		PluginsDir:     "/etc/cloudsnooze/plugins",
+		PluginsDir:     filepath.Join(installDir, "plugins"),
+		PluginLimits: PluginLimitsConfig{
+			CPUPercent: 50,
+			MemoryMB:   256,
+		},
+		Schedule: ScheduleConfig{
+			Timezone: "", // Empty for instance local time
+			Calendar: CalendarConfig{
+				Enabled:             false,
+				RefreshMinutes:      15,
+				CachePath:           filepath.Join(dataDir, "calendar.ics"),
+				DefaultWindow:       "blackout",
+				BlackoutKeywords:    []string{"blackout", "release", "freeze"},
+				ForceActiveKeywords: []string{"keep-alive", "force-active"},
+			},
+			DailyRuntimeBudgetHours: 0, // Disabled by default
+			BudgetWarningMinutes:    10,
+			BudgetStatePath:         filepath.Join(dataDir, "budget.json"),
+			Weekend: WeekendConfig{
+				Enabled:        false,
+				Days:           []string{"saturday", "sunday"},
+				NaptimeMinutes: 10, // Snooze sooner when nobody should be working
+			},
+		},
+		HistoryFile:      filepath.Join(dataDir, "history.json"),
+		HistoryMaxEvents: 1000,
+		DecisionLogSize:  1440, // A day of checks at the default interval
+		Notifications: NotificationsConfig{
+			WarnUsers: true,
+			QueuePath: filepath.Join(dataDir, "notification-queue.json"),
+			QueueMaxEntries: 500,
+			Slack: SlackConfig{
+				Enabled:  false,
+				Username: "CloudSnooze",
+			},
+			Email: EmailConfig{
+				Enabled:  false,
+				SMTPPort: 587,
+			},
+			Alerting: AlertingConfig{
+				Enabled:                    false,
+				Service:                    "pagerduty",
+				CollectionFailureThreshold: 3,
+				PermissionCheckMinutes:     60,
+				StopFailureThreshold:       3,
+			},
+		},
+		Socket: SocketConfig{
+			Group:          "",
+			Mode:           "0660",
+			AdminGroup:     "",
+			AdminTokenFile: "",
+			RateLimit: RateLimitConfig{
+				GlobalPerSecond: 50,
+				ClientPerSecond: 10,
+				Burst:           20,
+			},
+		},
+		RemoteAPI: RemoteAPIConfig{
+			ListenAddr:   "",
+			CertFile:     filepath.Join(installDir, "tls", "server.pem"),
+			KeyFile:      filepath.Join(installDir, "tls", "server-key.pem"),
+			ClientCAFile: filepath.Join(installDir, "tls", "client-ca.pem"),
+		},
+		ClientAllowlists: []ClientAllowlistConfig{},
+		Privileges: PrivilegesConfig{
+			User:  "",
+			Group: "",
+		},
+		Secrets: SecretsConfig{
+			StorePath: filepath.Join(configDir, "secrets.sealed"),
+			KeyFile:   filepath.Join(configDir, "secrets.key"),
+		},
+		Restarter: RestarterConfig{
+			PollSeconds:      60,
+			Schedules:        []RestarterScheduleConfig{},
+			WebhookAddr:      "",
+			WebhookTokenFile: filepath.Join(configDir, "restarter.token"),
+			WebhookCertFile:  "",
+			WebhookKeyFile:   "",
+			WakeOnSSH:        []WakeOnSSHConfig{},
+			WakeTimeoutSecs:  300,
+		},
+		Controller: ControllerConfig{
+			Tags:            map[string]string{},
+			Groups:          []ControllerGroupConfig{},
+			PollSeconds:     300,
+			DryRun:          false,
+			StatusAddr:      "",
+			StatusTokenFile: filepath.Join(configDir, "controller.token"),
+			StatusCertFile:  "",
+			StatusKeyFile:   "",
+		},
+		Aggregation: AggregationConfig{
+			ServerURL:     "",
+			TokenFile:     filepath.Join(configDir, "aggregation.token"),
+			CAFile:        "",
+			PushSeconds:   60,
+			HistoryEvents: 20,
+		},
+		Aggregator: AggregatorConfig{
+			ListenAddr:       ":8443",
+			TokenFile:        filepath.Join(configDir, "aggregator.token"),
+			ReportSecretFile: filepath.Join(configDir, "aggregator-report.secret"),
+			CertFile:         "",
+			KeyFile:          "",
+			StaleSeconds:     300,
+			Commands:         []string{"CANCEL", "LEASE", "RELEASE"},
+		},
+		Hooks: HooksConfig{
+			Commands:    []HookConfig{},
+			Events:      []EventHookConfig{},
+			ContextPath: filepath.Join(dataDir, "hook-context.json"),
+			TimeoutSecs: 60,
+		},
+		Training: TrainingConfig{
+			Checkpoints: []TrainingCheckpointConfig{},
+			TimeoutSecs: 600,
+		},
+		Databases: DatabasesConfig{
+			Checkpoints: []DatabaseCheckpointConfig{},
+			TimeoutSecs: 60,
+		},
+		Docker: DockerConfig{
+			StopContainers:  false,
+			SocketPath:      "/var/run/docker.sock",
+			StopTimeoutSecs: 10,
+			Exclude:         []string{},
+			StatePath:       filepath.Join(dataDir, "containers.json"),
+		},
+		Container: ContainerConfig{
+			Enabled:  false,
+			HostProc: "/host/proc",
+			HostSys:  "/host/sys",
+			HostDev:  "/host/dev",
+		},
+		Kubernetes: KubernetesConfig{
+			DrainNode:          false,
+			NodeName:           "",
+			Kubectl:            "kubectl",
+			Kubeconfig:         "",
+			DrainTimeoutSecs:   300,
+			DeleteEmptyDirData: false,
+			Force:              false,
+			StatePath:          filepath.Join(dataDir, "kubernetes.json"),
+		},
+		Filesystems: FilesystemsConfig{
+			SyncBeforeStop:   true,
+			Unmount:          []string{},
+			FlushTimeoutSecs: 30,
+		},
+		Pipeline: []PipelineStepConfig{},
+		Leases: LeaseConfig{
+			MaxHours:  24,
+			StatePath: filepath.Join(dataDir, "leases.json"),
+		},
+		Dependencies: DependenciesConfig{
+			Instances:     []string{},
+			Tags:          map[string]string{},
+			Source:        "",
+			CheckSeconds:  60,
+			IgnoreUnknown: false,
+		},
+		Peers: PeersConfig{
+			Instances:    []string{},
+			Tags:         map[string]string{},
+			Source:       "",
+			MinRunning:   1,
+			CheckSeconds: 60,
+		},
+		Audit: AuditConfig{
+			LogPath:    filepath.Join(logDir, "cloudsnooze-audit.log"),
+			BufferSize: 1000,
+			MaxSizeMB:  10,
+			MaxBackups: 10,
+		},
+		Telemetry: TelemetryConfig{
+			Enabled:            false,
+			OTLPEndpoint:       "localhost:4318",
+			Insecure:           true,
+			ServiceName:        "cloudsnooze",
+			ExportIntervalSecs: 60,
+		},
+		Maintenance: MaintenanceConfig{
+			Enabled:      true,
+			PollMinutes:  15,
+			GuardMinutes: 120,
+			Notify:       true,
+		},
+		AdaptiveInterval: AdaptiveIntervalConfig{
+			MinSeconds: 15,
+			MaxSeconds: 300,
+		},
+		StatsD: StatsDConfig{
+			Enabled:   false,
+			Host:      "127.0.0.1",
+			Port:      8125,
+			Prefix:    "cloudsnooze",
+			DogStatsD: true,
+		},
 	}
 }
\ No newline at end of file
@@ -0,0 +1,215 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/api"
+	"github.com/scttfrdmn/cloudsnooze/daemon/cloud"
+	"github.com/scttfrdmn/cloudsnooze/daemon/cloud/aws"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/controller"
+)
+
+var controllerMode = flag.Bool("controller", false, "Snooze tagged instances from their CloudWatch metrics, from one host, instead of watching this instance")
+
+// runController runs the controller until a signal and returns the exit
+// code. The socket API reports the latest check of each instance.
+func runController(config Config) int {
+	// Only AWS metrics are read
+	if config.ProviderType != "" && cloud.ProviderType(config.ProviderType) != cloud.AWS {
+		logger().Error("The controller only supports AWS", "provider", config.ProviderType)
+		return 1
+	}
+	// Without tags every instance in the region would be snoozed
+	if len(config.Controller.Tags) == 0 && len(config.Controller.Groups) == 0 {
+		logger().Error("The controller needs controller.tags or controller.groups to choose the instances it snoozes")
+		return 1
+	}
+	provider := aws.NewProvider(awsProviderConfig(config))
+	if err := provider.InitializeFleet(); err != nil {
+		logger().Error("Failed to set up the AWS provider", "error", err)
+		return 1
+	}
+
+	// Never snooze the host the controller runs on
+	var exclude []string
+	if info, err := provider.GetInstanceInfo(); err == nil {
+		exclude = append(exclude, info.ID)
+	}
+	c := controller.New(provider, controller.Config{
+		Tags:        config.Controller.Tags,
+		Groups:      controllerGroups(config),
+		Exclude:     exclude,
+		CPUPercent:  config.CPUThresholdPercent,
+		NetworkKBps: config.NetworkThresholdKBps,
+		Naptime:     time.Duration(config.NaptimeMinutes) * time.Minute,
+		DryRun:      config.Controller.DryRun,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	provider.SetContext(ctx)
+
+	// Serve the API, with only the controller's commands
+	admins, err := adminPolicy(config.Socket)
+	if err != nil {
+		logger().Error("Failed to read socket admin token", "error", err)
+		return 1
+	}
+	allowlists, err := clientAllowlists(config.ClientAllowlists)
+	if err != nil {
+		logger().Error("Failed to read client allowlist token", "error", err)
+		return 1
+	}
+	auditLog := newAuditLog(config)
+	defer auditLog.Close()
+	newServer := func() (*api.SocketServer, error) {
+		server, err := api.NewSocketServer(*socketPath, socketAccess(config.Socket))
+		if err != nil {
+			return nil, err
+		}
+		if err := server.SetAdminPolicy(admins); err != nil {
+			server.Stop()
+			return nil, err
+		}
+		if err := server.SetAllowlists(allowlists); err != nil {
+			server.Stop()
+			return nil, err
+		}
+		server.SetAuditLog(auditLog)
+		server.SetRateLimit(rateLimit(config.Socket.RateLimit))
+
+		// FLEET reports the latest check of each instance, or of those with
+		// the "tags" given
+		server.RegisterReadOnlyHandler("FLEET", func(params map[string]interface{}) (interface{}, error) {
+			tags := tagsParam(params)
+			return slices.DeleteFunc(c.Decisions(), func(decision controller.Decision) bool {
+				return !common.HasTags(decision.Tags, tags)
+			}), nil
+		})
+
+		// FLEET_PAUSE keeps the instances with the tags running for a
+		// number of hours, FLEET_RESUME ends the pauses of instances with
+		// the tags, and FLEET_SNOOZE stops the instances with the tags now
+		server.RegisterHandler("FLEET_PAUSE", func(params map[string]interface{}) (interface{}, error) {
+			hours, _ := params["hours"].(float64)
+			reason, _ := params["reason"].(string)
+			if hours <= 0 {
+				return nil, fmt.Errorf("hours must be greater than 0")
+			}
+			if reason == "" {
+				return nil, fmt.Errorf("reason is required")
+			}
+			return c.Pause(tagsParam(params), reason, time.Now().Add(time.Duration(hours*float64(time.Hour)))), nil
+		})
+		server.RegisterHandler("FLEET_RESUME", func(params map[string]interface{}) (interface{}, error) {
+			return map[string]interface{}{"resumed": c.Resume(tagsParam(params))}, nil
+		})
+		server.RegisterHandler("FLEET_SNOOZE", func(params map[string]interface{}) (interface{}, error) {
+			reason, _ := params["reason"].(string)
+			if reason == "" {
+				reason = "Snoozed from the controller"
+			}
+			return c.Snooze(ctx, tagsParam(params), reason)
+		})
+		return server, nil
+	}
+	server, err := newServer()
+	if err != nil {
+		logger().Error("Failed to create socket server", "error", err)
+		return 1
+	}
+
+	// Serve the fleet's status to dashboards, listening before giving up root
+	var status *http.Server
+	if config.Controller.StatusAddr != "" {
+		token, err := readTokenFile(config.Controller.StatusTokenFile)
+		if err != nil {
+			logger().Error("Failed to read controller status token", "error", err)
+			return 1
+		}
+		status, err = startHTTPServer("Controller status", config.Controller.StatusAddr, c.StatusHandler(token),
+			config.Controller.StatusCertFile, config.Controller.StatusKeyFile)
+		if err != nil {
+			logger().Error("Failed to serve the controller status", "address", config.Controller.StatusAddr, "error", err)
+			return 1
+		}
+	}
+	if err := dropPrivileges(config.Privileges, writablePaths(config)); err != nil {
+		logger().Error("Failed to drop privileges", "user", config.Privileges.User, "error", err)
+		return 1
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- serveAPI(ctx, server, newServer)
+	}()
+	go c.Run(ctx, time.Duration(config.Controller.PollSeconds)*time.Second)
+	logger().Info("Controller running", "tags", config.Controller.Tags, "groups", len(config.Controller.Groups),
+		"naptime_minutes", config.NaptimeMinutes, "dry_run", config.Controller.DryRun, "status", config.Controller.StatusAddr)
+
+	sigChan := make(chan os.Signal, 1)
+	notifyShutdown(sigChan)
+	exitCode := 0
+	select {
+	case sig := <-sigChan:
+		logger().Info("Received signal, shutting down", "signal", sig.String())
+	case err := <-serverErr:
+		logger().Error("API socket unavailable, shutting down", "error", err)
+		exitCode = 1
+	}
+	cancel()
+	if status != nil {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+		status.Shutdown(shutdownCtx)
+		cancelShutdown()
+	}
+	return exitCode
+}
+
+// controllerGroups returns the controller's groups, with the top-level
+// thresholds and naptime where a group doesn't set its own
+func controllerGroups(config Config) []controller.Group {
+	groups := make([]controller.Group, 0, len(config.Controller.Groups))
+	for _, g := range config.Controller.Groups {
+		group := controller.Group{
+			Name:        g.Name,
+			Tags:        g.Tags,
+			CPUPercent:  g.CPUThresholdPercent,
+			NetworkKBps: g.NetworkThresholdKBps,
+			Naptime:     time.Duration(g.NaptimeMinutes) * time.Minute,
+			Disabled:    g.Disabled,
+		}
+		if group.CPUPercent == 0 {
+			group.CPUPercent = config.CPUThresholdPercent
+		}
+		if group.NetworkKBps == 0 {
+			group.NetworkKBps = config.NetworkThresholdKBps
+		}
+		if group.Naptime == 0 {
+			group.Naptime = time.Duration(config.NaptimeMinutes) * time.Minute
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// tagsParam returns the "tags" parameter of a command, which selects the
+// instances with all of them
+func tagsParam(params map[string]interface{}) map[string]string {
+	values, _ := params["tags"].(map[string]interface{})
+	tags := make(map[string]string, len(values))
+	for key, value := range values {
+		tags[key] = fmt.Sprint(value)
+	}
+	return tags
+}
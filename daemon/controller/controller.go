@@ -0,0 +1,338 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package controller snoozes a fleet of instances from one host, judging
+// them idle from the cloud provider's metrics rather than a daemon on each
+// instance. Instances it stops are tagged like those a daemon snoozes, so
+// the restarter can start them again.
+package controller
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+)
+
+// logger returns the controller component logger
+func logger() *slog.Logger {
+	return logging.Component("controller")
+}
+
+// Config defines which instances are snoozed and when they count as idle
+type Config struct {
+	Tags        map[string]string // Tags instances must have
+	Groups      []Group           // Policies for groups of instances, the first an instance is in applying
+	Exclude     []string          // Instance IDs never snoozed, such as the controller's own
+	CPUPercent  float64           // Peak CPU utilization below which an instance is idle
+	NetworkKBps float64           // Peak traffic below which an instance is idle
+	Naptime     time.Duration     // How long an instance must be idle
+	DryRun      bool              // Log the instances that would be stopped without stopping them
+}
+
+// Group is the policy for the instances that have all of its tags. With
+// groups, only instances in one of them are snoozed.
+type Group struct {
+	Name        string
+	Tags        map[string]string
+	CPUPercent  float64
+	NetworkKBps float64
+	Naptime     time.Duration
+	Disabled    bool // Never snooze the group's instances
+}
+
+// matches reports whether an instance with tags is in the group
+func (g Group) matches(tags map[string]string) bool {
+	for key, value := range g.Tags {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Decision is the outcome of checking an instance
+type Decision struct {
+	InstanceID string               `json:"instance_id"`
+	Name       string               `json:"name,omitempty"`
+	Group      string               `json:"group,omitempty"` // The group whose policy applied
+	Tags       map[string]string    `json:"tags,omitempty"`
+	Time       time.Time            `json:"time"`
+	LaunchTime time.Time            `json:"launch_time"`
+	Usage      common.InstanceUsage `json:"usage"`
+	Idle       bool                 `json:"idle"`
+	Paused     bool                 `json:"paused,omitempty"` // Left running by a pause
+	Reason     string               `json:"reason"`
+	Stopped    bool                 `json:"stopped"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// Controller snoozes idle instances of a fleet
+type Controller struct {
+	fleet     common.FleetController
+	config    Config
+	lock      sync.Mutex
+	decisions []Decision
+	stops     map[string]Decision // The latest stop of each instance
+	pauses    []Pause
+}
+
+// New creates a controller for the instances of fleet
+func New(fleet common.FleetController, config Config) *Controller {
+	return &Controller{fleet: fleet, config: config, stops: make(map[string]Decision)}
+}
+
+// Decisions returns the outcome of the latest check of each instance
+func (c *Controller) Decisions() []Decision {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return slices.Clone(c.decisions)
+}
+
+// Run checks the fleet every interval until ctx is cancelled
+func (c *Controller) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := c.Check(ctx); err != nil {
+			logger().Warn("Failed to check instances", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check checks every instance of the fleet, stopping the idle ones. An
+// instance whose metrics can't be read, or that is paused, is left running. Group membership
+// is read from the instances' current tags.
+func (c *Controller) Check(ctx context.Context) error {
+	instances, err := c.instances(ctx)
+	if err != nil {
+		return err
+	}
+
+	pauses := c.Pauses()
+	now := time.Now()
+	decisions := make([]Decision, 0, len(instances))
+	for _, instance := range instances {
+		if slices.Contains(c.config.Exclude, instance.ID) {
+			continue
+		}
+		policy, ok := c.policy(instance.Tags)
+		if !ok {
+			continue
+		}
+		if pause, paused := pausedBy(pauses, instance.Tags); paused {
+			decisions = append(decisions, Decision{InstanceID: instance.ID, Name: instance.Name, Group: policy.Name,
+				Tags: instance.Tags, Time: now, LaunchTime: instance.LaunchTime, Paused: true, Reason: pause.reason()})
+			continue
+		}
+		decision := c.evaluate(ctx, instance, policy, now)
+		if decision.Idle && decision.Error == "" {
+			c.stop(ctx, &decision)
+		}
+		decisions = append(decisions, decision)
+	}
+
+	c.lock.Lock()
+	c.decisions = decisions
+	for _, decision := range decisions {
+		if decision.Stopped {
+			c.stops[decision.InstanceID] = decision
+		}
+	}
+	c.lock.Unlock()
+	logger().Debug("Checked instances", "instances", len(decisions))
+	return nil
+}
+
+// instances returns the running instances with the controller's tags,
+// and with the tags of a group if there are groups, by instance ID
+func (c *Controller) instances(ctx context.Context) ([]common.ControlledInstance, error) {
+	if len(c.config.Groups) == 0 {
+		return c.fleet.TaggedInstances(ctx, c.config.Tags)
+	}
+
+	// An instance in several groups is found once
+	found := make(map[string]common.ControlledInstance)
+	for _, group := range c.config.Groups {
+		tags := maps.Clone(c.config.Tags)
+		if tags == nil {
+			tags = make(map[string]string)
+		}
+		maps.Copy(tags, group.Tags)
+		instances, err := c.fleet.TaggedInstances(ctx, tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find instances of group %s: %v", group.Name, err)
+		}
+		for _, instance := range instances {
+			found[instance.ID] = instance
+		}
+	}
+	instances := slices.Collect(maps.Values(found))
+	slices.SortFunc(instances, func(a, b common.ControlledInstance) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return instances, nil
+}
+
+// policy returns the policy for an instance with tags: that of the first
+// group it is in, or the controller's own if there are no groups. It
+// returns false for an instance in no group.
+func (c *Controller) policy(tags map[string]string) (Group, bool) {
+	if len(c.config.Groups) == 0 {
+		return Group{CPUPercent: c.config.CPUPercent, NetworkKBps: c.config.NetworkKBps, Naptime: c.config.Naptime}, true
+	}
+	for _, group := range c.config.Groups {
+		if group.matches(tags) {
+			return group, true
+		}
+	}
+	return Group{}, false
+}
+
+// evaluate decides whether an instance has been idle for its policy's
+// naptime
+func (c *Controller) evaluate(ctx context.Context, instance common.ControlledInstance, policy Group, now time.Time) Decision {
+	decision := Decision{InstanceID: instance.ID, Name: instance.Name, Group: policy.Name, Tags: instance.Tags,
+		Time: now, LaunchTime: instance.LaunchTime}
+	if policy.Disabled {
+		decision.Reason = fmt.Sprintf("Group %s is never snoozed", policy.Name)
+		return decision
+	}
+
+	// Metrics from before the instance last started don't count
+	if up := now.Sub(instance.LaunchTime); up < policy.Naptime {
+		decision.Reason = fmt.Sprintf("Started %s ago, within the naptime", up.Round(time.Minute))
+		return decision
+	}
+	usage, err := c.fleet.InstanceUsage(ctx, instance.ID, policy.Naptime)
+	if err != nil {
+		logger().Warn("Failed to read instance metrics", "instance", instance.ID, "error", err)
+		decision.Reason = "Metrics unavailable"
+		decision.Error = err.Error()
+		return decision
+	}
+	decision.Usage = usage
+
+	switch {
+	case usage.Samples == 0:
+		decision.Reason = "No metrics for the naptime"
+	case usage.CPUPercent >= policy.CPUPercent:
+		decision.Reason = fmt.Sprintf("CPU peaked at %.1f%%, threshold %.1f%%", usage.CPUPercent, policy.CPUPercent)
+	case usage.NetworkKBps >= policy.NetworkKBps:
+		decision.Reason = fmt.Sprintf("Network peaked at %.1f KB/s, threshold %.1f KB/s", usage.NetworkKBps, policy.NetworkKBps)
+	default:
+		decision.Idle = true
+		decision.Reason = fmt.Sprintf("Idle for %d minutes: CPU peaked at %.1f%%, network at %.1f KB/s",
+			int(policy.Naptime.Minutes()), usage.CPUPercent, usage.NetworkKBps)
+	}
+	return decision
+}
+
+// stop stops an idle instance, unless this is a dry run
+func (c *Controller) stop(ctx context.Context, decision *Decision) {
+	if c.config.DryRun {
+		logger().Info("Would stop idle instance", "instance", decision.InstanceID, "name", decision.Name, "reason", decision.Reason)
+		return
+	}
+	if err := c.fleet.StopFleetInstance(ctx, decision.InstanceID, decision.Reason); err != nil {
+		logger().Error("Failed to stop idle instance", "instance", decision.InstanceID, "error", err)
+		decision.Error = err.Error()
+		return
+	}
+	decision.Stopped = true
+	logger().Info("Stopped idle instance", "instance", decision.InstanceID, "name", decision.Name, "reason", decision.Reason)
+}
+
+// Fleet returns the state of every instance from its latest check.
+// Instances the controller stopped are included until they run again.
+func (c *Controller) Fleet() common.FleetStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	members := make([]common.FleetMemberStatus, 0, len(c.decisions))
+	checked := make(map[string]bool, len(c.decisions))
+	for _, decision := range c.decisions {
+		checked[decision.InstanceID] = true
+		member := common.FleetMemberStatus{
+			InstanceID: decision.InstanceID,
+			Name:       decision.Name,
+			Group:      decision.Group,
+			State:      common.FleetStateActive,
+			Reason:     decision.Reason,
+			LastSeen:   decision.Time,
+			Tags:       decision.Tags,
+		}
+		switch {
+		case decision.Stopped:
+			member.State = common.FleetStateStopped
+		case decision.Error != "":
+			member.State = common.FleetStateError
+			member.Reason = fmt.Sprintf("%s: %s", decision.Reason, decision.Error)
+		case decision.Paused:
+			member.State = common.FleetStatePaused
+		case decision.Idle:
+			member.State = common.FleetStateIdle
+		}
+		if stop, ok := c.stops[decision.InstanceID]; ok {
+			member.LastEvent = &common.FleetEvent{Time: stop.Time, Event: "snooze", Reason: stop.Reason}
+			if decision.LaunchTime.After(stop.Time) {
+				member.LastEvent = &common.FleetEvent{Time: decision.LaunchTime, Event: "start"}
+			}
+		}
+		members = append(members, member)
+	}
+
+	// Stopped instances aren't checked again until they are started
+	for id, stop := range c.stops {
+		if checked[id] {
+			continue
+		}
+		members = append(members, common.FleetMemberStatus{
+			InstanceID: id,
+			Name:       stop.Name,
+			Group:      stop.Group,
+			State:      common.FleetStateStopped,
+			Reason:     stop.Reason,
+			LastEvent:  &common.FleetEvent{Time: stop.Time, Event: "snooze", Reason: stop.Reason},
+			LastSeen:   stop.Time,
+			Tags:       stop.Tags,
+		})
+	}
+	return common.NewFleetStatus(members, time.Now())
+}
+
+// StatusHandler serves GET /v1/fleet?tag=KEY=VALUE, the status of the
+// fleet or of the instances with the tags, to requests authorized with
+// "Authorization: Bearer TOKEN"
+func (c *Controller) StatusHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/fleet", func(w http.ResponseWriter, req *http.Request) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		tags, err := common.ParseTagFilters(req.URL.Query()["tag"])
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(c.Fleet().Tagged(tags))
+	})
+	return mux
+}
@@ -0,0 +1,306 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// fakeFleet has an instance for each way a check can turn out
+type fakeFleet struct {
+	stopped map[string]string
+	stopErr error
+}
+
+func (f *fakeFleet) TaggedInstances(ctx context.Context, tags map[string]string) ([]common.ControlledInstance, error) {
+	if tags["team"] != "research" {
+		return nil, errors.New("unexpected tags")
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	return []common.ControlledInstance{
+		{ID: "i-idle", Name: "notebook", LaunchTime: old},
+		{ID: "i-busy-cpu", LaunchTime: old},
+		{ID: "i-busy-network", LaunchTime: old},
+		{ID: "i-new", LaunchTime: time.Now().Add(-10 * time.Minute)},
+		{ID: "i-no-metrics", LaunchTime: old},
+		{ID: "i-broken", LaunchTime: old},
+		{ID: "i-controller", LaunchTime: old},
+	}, nil
+}
+
+func (f *fakeFleet) InstanceUsage(ctx context.Context, instanceID string, period time.Duration) (common.InstanceUsage, error) {
+	switch instanceID {
+	case "i-busy-cpu":
+		return common.InstanceUsage{CPUPercent: 45, Samples: 6}, nil
+	case "i-busy-network":
+		return common.InstanceUsage{CPUPercent: 1, NetworkKBps: 250, Samples: 6}, nil
+	case "i-no-metrics":
+		return common.InstanceUsage{}, nil
+	case "i-broken":
+		return common.InstanceUsage{}, errors.New("AccessDenied")
+	}
+	return common.InstanceUsage{CPUPercent: 1.5, NetworkKBps: 2, Samples: 6}, nil
+}
+
+func (f *fakeFleet) StopFleetInstance(ctx context.Context, instanceID, reason string) error {
+	if f.stopErr != nil {
+		return f.stopErr
+	}
+	if f.stopped == nil {
+		f.stopped = make(map[string]string)
+	}
+	f.stopped[instanceID] = reason
+	return nil
+}
+
+// testConfig snoozes the research fleet after 30 idle minutes
+var testConfig = Config{
+	Tags:        map[string]string{"team": "research"},
+	Exclude:     []string{"i-controller"},
+	CPUPercent:  10,
+	NetworkKBps: 50,
+	Naptime:     30 * time.Minute,
+}
+
+func TestCheck(t *testing.T) {
+	fleet := &fakeFleet{}
+	c := New(fleet, testConfig)
+
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(fleet.stopped) != 1 || !strings.HasPrefix(fleet.stopped["i-idle"], "Idle for 30 minutes") {
+		t.Fatalf("Expected only i-idle to be stopped, stopped %v", fleet.stopped)
+	}
+
+	decisions := map[string]Decision{}
+	for _, d := range c.Decisions() {
+		decisions[d.InstanceID] = d
+	}
+	if _, ok := decisions["i-controller"]; ok || len(decisions) != 6 {
+		t.Errorf("Expected excluded instances not to be checked, got %d decisions", len(decisions))
+	}
+	if d := decisions["i-idle"]; !d.Idle || !d.Stopped || d.Name != "notebook" {
+		t.Errorf("Expected i-idle to be stopped, got %+v", d)
+	}
+	for id, reason := range map[string]string{
+		"i-busy-cpu":     "CPU peaked at 45.0%",
+		"i-busy-network": "Network peaked at 250.0 KB/s",
+		"i-new":          "within the naptime",
+		"i-no-metrics":   "No metrics",
+		"i-broken":       "Metrics unavailable",
+	} {
+		if d := decisions[id]; d.Idle || d.Stopped || !strings.Contains(d.Reason, reason) {
+			t.Errorf("Expected %s to be left running because %q, got %+v", id, reason, d)
+		}
+	}
+	if decisions["i-broken"].Error != "AccessDenied" {
+		t.Errorf("Expected the metrics error to be recorded, got %q", decisions["i-broken"].Error)
+	}
+}
+
+func TestCheckDryRun(t *testing.T) {
+	fleet := &fakeFleet{}
+	config := testConfig
+	config.DryRun = true
+	c := New(fleet, config)
+
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(fleet.stopped) != 0 {
+		t.Errorf("Expected a dry run not to stop anything, stopped %v", fleet.stopped)
+	}
+	for _, d := range c.Decisions() {
+		if d.InstanceID == "i-idle" && (!d.Idle || d.Stopped) {
+			t.Errorf("Expected i-idle to be idle but running, got %+v", d)
+		}
+	}
+}
+
+func TestCheckStopFailure(t *testing.T) {
+	fleet := &fakeFleet{stopErr: errors.New("UnauthorizedOperation")}
+	c := New(fleet, testConfig)
+
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	for _, d := range c.Decisions() {
+		if d.InstanceID == "i-idle" && (d.Stopped || d.Error != "UnauthorizedOperation") {
+			t.Errorf("Expected the failed stop to be recorded, got %+v", d)
+		}
+	}
+}
+
+func TestFleet(t *testing.T) {
+	fleet := &fakeFleet{}
+	c := New(fleet, testConfig)
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	status := c.Fleet()
+	states := map[string]common.FleetMemberStatus{}
+	for _, member := range status.Instances {
+		states[member.InstanceID] = member
+	}
+	if len(status.Instances) != 6 || status.Counts[common.FleetStateActive] != 4 {
+		t.Fatalf("Expected 6 instances, 4 of them active, got %+v", status.Counts)
+	}
+	if idle := states["i-idle"]; idle.State != common.FleetStateStopped || idle.LastEvent == nil || idle.LastEvent.Event != "snooze" {
+		t.Errorf("Expected i-idle to be stopped, got %+v", idle)
+	}
+	if broken := states["i-broken"]; broken.State != common.FleetStateError || !strings.Contains(broken.Reason, "AccessDenied") {
+		t.Errorf("Expected i-broken to be in error, got %+v", broken)
+	}
+
+	// Once stopped, the instance is no longer found but is still reported
+	c.decisions = nil
+	status = c.Fleet()
+	if len(status.Instances) != 1 || status.Instances[0].InstanceID != "i-idle" || status.Instances[0].State != common.FleetStateStopped {
+		t.Errorf("Expected the stopped instance to be kept, got %+v", status.Instances)
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	handler := New(&fakeFleet{}, testConfig).StatusHandler("s3cret")
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "wrong": http.StatusUnauthorized, "s3cret": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/v1/fleet", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("Expected %d with token %q, got %d", want, token, rec.Code)
+		}
+	}
+}
+
+// groupFleet has idle instances of several teams, found by their tags
+type groupFleet struct {
+	fakeFleet
+	queries   int
+	instances []common.ControlledInstance
+}
+
+func (f *groupFleet) TaggedInstances(ctx context.Context, tags map[string]string) ([]common.ControlledInstance, error) {
+	f.queries++
+	var found []common.ControlledInstance
+	for _, instance := range f.instances {
+		if (Group{Tags: tags}).matches(instance.Tags) {
+			found = append(found, instance)
+		}
+	}
+	return found, nil
+}
+
+func TestCheckGroups(t *testing.T) {
+	idleSince := time.Now().Add(-time.Hour)
+	fleet := &groupFleet{instances: []common.ControlledInstance{
+		{ID: "i-ci", Tags: map[string]string{"snooze": "yes", "env": "ci"}, LaunchTime: idleSince},
+		{ID: "i-research", Tags: map[string]string{"snooze": "yes", "team": "research"}, LaunchTime: idleSince},
+		{ID: "i-research-ci", Tags: map[string]string{"snooze": "yes", "team": "research", "env": "ci"}, LaunchTime: idleSince},
+		{ID: "i-prod", Tags: map[string]string{"snooze": "yes", "env": "prod"}, LaunchTime: idleSince},
+		{ID: "i-prod-ci", Tags: map[string]string{"snooze": "yes", "env": "prod", "team": "research"}, LaunchTime: idleSince},
+		{ID: "i-untagged", Tags: map[string]string{"team": "research"}, LaunchTime: idleSince},
+		{ID: "i-other", Tags: map[string]string{"snooze": "yes", "team": "web"}, LaunchTime: idleSince},
+	}}
+	config := testConfig
+	config.Tags = map[string]string{"snooze": "yes"}
+	config.Groups = []Group{
+		{Name: "prod", Tags: map[string]string{"env": "prod"}, Disabled: true},
+		{Name: "ci", Tags: map[string]string{"env": "ci"}, CPUPercent: 10, NetworkKBps: 50, Naptime: 10 * time.Minute},
+		{Name: "research", Tags: map[string]string{"team": "research"}, CPUPercent: 10, NetworkKBps: 50, Naptime: 2 * time.Hour},
+	}
+	c := New(fleet, config)
+
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if fleet.queries != len(config.Groups) {
+		t.Errorf("Expected a query per group, got %d", fleet.queries)
+	}
+
+	decisions := c.Decisions()
+	want := map[string]string{
+		"i-ci":          "ci",
+		"i-prod":        "prod",
+		"i-prod-ci":     "prod",
+		"i-research":    "research",
+		"i-research-ci": "ci",
+	}
+	if len(decisions) != len(want) {
+		t.Fatalf("Expected only instances in a group to be checked, got %+v", decisions)
+	}
+	for i, d := range decisions {
+		if i > 0 && decisions[i-1].InstanceID >= d.InstanceID {
+			t.Errorf("Expected decisions in instance order, got %s after %s", d.InstanceID, decisions[i-1].InstanceID)
+		}
+		if d.Group != want[d.InstanceID] {
+			t.Errorf("Expected %s to follow group %s, got %q", d.InstanceID, want[d.InstanceID], d.Group)
+		}
+	}
+
+	// Up for an hour, CI instances have been idle long enough but research
+	// ones haven't, and prod ones are never snoozed
+	if len(fleet.stopped) != 2 || fleet.stopped["i-ci"] == "" || fleet.stopped["i-research-ci"] == "" {
+		t.Errorf("Expected only the CI instances to be stopped, stopped %v", fleet.stopped)
+	}
+}
+
+func TestPause(t *testing.T) {
+	idleSince := time.Now().Add(-time.Hour)
+	fleet := &groupFleet{instances: []common.ControlledInstance{
+		{ID: "i-research", Tags: map[string]string{"team": "research"}, LaunchTime: idleSince},
+		{ID: "i-research-gpu", Tags: map[string]string{"team": "research", "gpu": "yes"}, LaunchTime: idleSince},
+		{ID: "i-web", Tags: map[string]string{"team": "web"}, LaunchTime: idleSince},
+	}}
+	config := testConfig
+	config.Tags = nil
+	config.Groups = []Group{{Name: "all", CPUPercent: 10, NetworkKBps: 50, Naptime: 30 * time.Minute}}
+	c := New(fleet, config)
+
+	c.Pause(map[string]string{"team": "research"}, "Release weekend", time.Now().Add(72*time.Hour))
+	c.Pause(map[string]string{"team": "web"}, "Expired", time.Now().Add(-time.Minute))
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(fleet.stopped) != 1 || fleet.stopped["i-web"] == "" {
+		t.Errorf("Expected only the instance without a pause to be stopped, stopped %v", fleet.stopped)
+	}
+	status := c.Fleet().Tagged(map[string]string{"team": "research"})
+	if len(status.Instances) != 2 || status.Counts[common.FleetStatePaused] != 2 {
+		t.Errorf("Expected the research instances to be paused, got %+v", status.Instances)
+	}
+	if pauses := c.Pauses(); len(pauses) != 1 {
+		t.Errorf("Expected the expired pause to be dropped, got %+v", pauses)
+	}
+
+	// Snoozing on demand leaves paused instances running
+	decisions, err := c.Snooze(context.Background(), map[string]string{"gpu": "yes"}, "Snoozed for the weekend")
+	if err != nil || len(decisions) != 1 || decisions[0].Stopped || !decisions[0].Paused {
+		t.Errorf("Expected the paused GPU instance to be left running, got %+v and %v", decisions, err)
+	}
+
+	// Resuming the team ends its pause, and the next check stops it
+	if resumed := c.Resume(map[string]string{"team": "research"}); len(resumed) != 1 {
+		t.Errorf("Expected the research pause to end, got %+v", resumed)
+	}
+	decisions, err = c.Snooze(context.Background(), map[string]string{"gpu": "yes"}, "Snoozed for the weekend")
+	if err != nil || len(decisions) != 1 || !decisions[0].Stopped || fleet.stopped["i-research-gpu"] != "Snoozed for the weekend" {
+		t.Errorf("Expected the GPU instance to be stopped on demand, got %+v and %v", decisions, err)
+	}
+	if member := c.Fleet().Tagged(map[string]string{"gpu": "yes"}).Instances; len(member) != 1 || member[0].State != common.FleetStateStopped {
+		t.Errorf("Expected the snoozed instance to be reported stopped, got %+v", member)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// Pause keeps the instances with all of its tags running until it expires,
+// such as a team's instances over a release weekend. Pauses are kept until
+// the controller restarts.
+type Pause struct {
+	Tags   map[string]string `json:"tags,omitempty"` // Empty pauses every instance
+	Reason string            `json:"reason"`
+	Until  time.Time         `json:"until"`
+}
+
+// reason explains why a paused instance is left running
+func (p Pause) reason() string {
+	return fmt.Sprintf("Paused until %s: %s", p.Until.Format(time.RFC3339), p.Reason)
+}
+
+// Pause keeps the instances with all of tags running until until
+func (c *Controller) Pause(tags map[string]string, reason string, until time.Time) Pause {
+	pause := Pause{Tags: tags, Reason: reason, Until: until}
+	c.lock.Lock()
+	c.pauses = append(c.pauses, pause)
+	c.lock.Unlock()
+	logger().Info("Paused snoozing", "tags", tags, "reason", reason, "until", until.Format(time.RFC3339))
+	return pause
+}
+
+// Resume ends the pauses of instances with all of tags, including those of
+// narrower pauses, returning them. Without tags it ends every pause.
+func (c *Controller) Resume(tags map[string]string) []Pause {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var resumed []Pause
+	c.pauses = slices.DeleteFunc(c.pauses, func(pause Pause) bool {
+		if common.HasTags(pause.Tags, tags) {
+			resumed = append(resumed, pause)
+			return true
+		}
+		return false
+	})
+	logger().Info("Resumed snoozing", "tags", tags, "pauses", len(resumed))
+	return resumed
+}
+
+// Pauses returns the pauses that haven't expired, dropping the rest
+func (c *Controller) Pauses() []Pause {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	now := time.Now()
+	c.pauses = slices.DeleteFunc(c.pauses, func(pause Pause) bool {
+		return !pause.Until.After(now)
+	})
+	return slices.Clone(c.pauses)
+}
+
+// pausedBy returns the pause, of pauses, that covers an instance with tags
+func pausedBy(pauses []Pause, tags map[string]string) (Pause, bool) {
+	for _, pause := range pauses {
+		if common.HasTags(tags, pause.Tags) {
+			return pause, true
+		}
+	}
+	return Pause{}, false
+}
+
+// Snooze stops the running instances with all of tags now, whether or not
+// they are idle. Paused instances and those of disabled groups are left
+// running.
+func (c *Controller) Snooze(ctx context.Context, tags map[string]string, reason string) ([]Decision, error) {
+	instances, err := c.instances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pauses := c.Pauses()
+
+	now := time.Now()
+	decisions := make([]Decision, 0)
+	for _, instance := range instances {
+		if slices.Contains(c.config.Exclude, instance.ID) || !common.HasTags(instance.Tags, tags) {
+			continue
+		}
+		policy, ok := c.policy(instance.Tags)
+		if !ok {
+			continue
+		}
+		decision := Decision{InstanceID: instance.ID, Name: instance.Name, Group: policy.Name, Tags: instance.Tags,
+			Time: now, LaunchTime: instance.LaunchTime, Reason: reason}
+		pause, paused := pausedBy(pauses, instance.Tags)
+		switch {
+		case policy.Disabled:
+			decision.Reason = fmt.Sprintf("Group %s is never snoozed", policy.Name)
+		case paused:
+			decision.Paused = true
+			decision.Reason = pause.reason()
+		default:
+			c.stop(ctx, &decision)
+		}
+		decisions = append(decisions, decision)
+	}
+
+	// Until the next check, report the instances as they are now
+	c.lock.Lock()
+	for _, decision := range decisions {
+		if !decision.Stopped {
+			continue
+		}
+		c.stops[decision.InstanceID] = decision
+		if i := slices.IndexFunc(c.decisions, func(d Decision) bool { return d.InstanceID == decision.InstanceID }); i >= 0 {
+			c.decisions[i] = decision
+		}
+	}
+	c.lock.Unlock()
+	return decisions, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// CountdownStatus describes a pending stop for STATUS responses
+type CountdownStatus struct {
+	Reason        string    `json:"reason"`
+	Trigger       string    `json:"trigger"`
+	Deadline      time.Time `json:"deadline"`
+	RemainingSecs int       `json:"remaining_secs"`
+}
+
+// countdown tracks the pre-stop grace period during which a stop can be aborted
+type countdown struct {
+	duration time.Duration
+	deadline time.Time
+	reason   string
+	trigger  string
+	lock     sync.Mutex
+}
+
+// newCountdown creates a countdown of the given duration
+func newCountdown(duration time.Duration) *countdown {
+	return &countdown{duration: duration}
+}
+
+// Enabled returns true if stops should be preceded by a countdown
+func (c *countdown) Enabled() bool {
+	return c.duration > 0
+}
+
+// Start begins the countdown if it is not already running. It returns true
+// if a new countdown was started.
+func (c *countdown) Start(reason, trigger string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.deadline.IsZero() {
+		return false
+	}
+
+	c.deadline = time.Now().Add(c.duration)
+	c.reason = reason
+	c.trigger = trigger
+	return true
+}
+
+// Expired returns true if a countdown is in progress and has run out
+func (c *countdown) Expired() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return !c.deadline.IsZero() && !time.Now().Before(c.deadline)
+}
+
+// Cancel aborts the countdown, returning false if none was in progress
+func (c *countdown) Cancel() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.deadline.IsZero() {
+		return false
+	}
+
+	c.deadline = time.Time{}
+	c.reason = ""
+	c.trigger = ""
+	return true
+}
+
+// Status returns the pending stop, or nil if no countdown is in progress
+func (c *countdown) Status() *CountdownStatus {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.deadline.IsZero() {
+		return nil
+	}
+
+	remaining := time.Until(c.deadline)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return &CountdownStatus{
+		Reason:        c.reason,
+		Trigger:       c.trigger,
+		Deadline:      c.deadline,
+		RemainingSecs: int(remaining.Seconds()),
+	}
+}
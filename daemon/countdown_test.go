@@ -0,0 +1,70 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCountdownEnabled(t *testing.T) {
+	if newCountdown(0).Enabled() {
+		t.Error("Expected a countdown of 0 to be disabled")
+	}
+	if !newCountdown(time.Minute).Enabled() {
+		t.Error("Expected a countdown of a minute to be enabled")
+	}
+}
+
+func TestCountdownStartCancel(t *testing.T) {
+	countdown := newCountdown(time.Hour)
+	if countdown.Status() != nil || countdown.Expired() {
+		t.Fatal("Expected no countdown before Start")
+	}
+
+	if !countdown.Start("idle", "idle_timeout") {
+		t.Fatal("Expected Start to begin a countdown")
+	}
+	if countdown.Start("schedule", "schedule") {
+		t.Error("Expected a second Start to leave the running countdown alone")
+	}
+	status := countdown.Status()
+	if status == nil || status.Reason != "idle" || status.Trigger != "idle_timeout" {
+		t.Fatalf("Expected the first countdown's status, got %+v", status)
+	}
+	if status.RemainingSecs <= 3590 || status.RemainingSecs > 3600 {
+		t.Errorf("Expected about an hour remaining, got %d seconds", status.RemainingSecs)
+	}
+	if countdown.Expired() {
+		t.Error("Expected the countdown not to have expired")
+	}
+
+	if !countdown.Cancel() {
+		t.Fatal("Expected Cancel to abort the countdown")
+	}
+	if countdown.Cancel() {
+		t.Error("Expected a second Cancel to find no countdown")
+	}
+	if countdown.Status() != nil || countdown.Expired() {
+		t.Error("Expected no countdown after Cancel")
+	}
+
+	// A cancelled countdown can be started again
+	if !countdown.Start("idle", "idle_timeout") {
+		t.Error("Expected Start after Cancel to begin a new countdown")
+	}
+}
+
+func TestCountdownExpires(t *testing.T) {
+	countdown := newCountdown(20 * time.Millisecond)
+	countdown.Start("idle", "idle_timeout")
+	time.Sleep(30 * time.Millisecond)
+
+	if !countdown.Expired() {
+		t.Fatal("Expected the countdown to have expired")
+	}
+	if status := countdown.Status(); status == nil || status.RemainingSecs != 0 {
+		t.Errorf("Expected an expired countdown with nothing remaining, got %+v", status)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package database checkpoints self-hosted databases before the instance
+// stops, using their command-line clients, so their data files are up to
+// date and they start without lengthy crash recovery.
+package database
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+)
+
+// Database engines
+const (
+	EnginePostgres = "postgres"
+	EngineMySQL    = "mysql"
+)
+
+// Engines lists the supported engines
+var Engines = []string{EnginePostgres, EngineMySQL}
+
+// Checkpoint is a database to checkpoint
+type Checkpoint struct {
+	Name      string // Label used in logs
+	Engine    string // postgres or mysql
+	Host      string // Empty for the local socket
+	Port      int    // 0 for the engine's default
+	User      string // Empty for the client's default
+	Password  string // Passed in the environment, never on the command line
+	Database  string // Database to connect to
+	Statement string // SQL to run; empty for the engine's checkpoint
+	Client    string // Client binary; empty for psql or mysql
+}
+
+// logger returns the database component logger
+func logger() *slog.Logger {
+	return logging.Component("database")
+}
+
+// DefaultStatement returns the SQL that checkpoints engine: CHECKPOINT
+// writes Postgres' dirty buffers out, and FLUSH TABLES closes MySQL's
+// tables and flushes their caches
+func DefaultStatement(engine string) string {
+	switch engine {
+	case EnginePostgres:
+		return "CHECKPOINT"
+	case EngineMySQL:
+		return "FLUSH TABLES"
+	}
+	return ""
+}
+
+// Run runs the checkpoint statement with the engine's client
+func (c Checkpoint) Run(ctx context.Context) error {
+	name, args, env, err := c.command()
+	if err != nil {
+		return err
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return fmt.Errorf("%v: %s", err, firstLine(message))
+		}
+		return err
+	}
+	return nil
+}
+
+// command returns the client, its arguments and its environment
+func (c Checkpoint) command() (string, []string, []string, error) {
+	statement := c.Statement
+	if statement == "" {
+		statement = DefaultStatement(c.Engine)
+	}
+
+	var args, env []string
+	client := c.Client
+	switch c.Engine {
+	case EnginePostgres:
+		if client == "" {
+			client = "psql"
+		}
+		args = []string{"--no-psqlrc", "--set=ON_ERROR_STOP=1", "--command=" + statement}
+		if c.Host != "" {
+			args = append(args, "--host="+c.Host)
+		}
+		if c.Port != 0 {
+			args = append(args, "--port="+strconv.Itoa(c.Port))
+		}
+		if c.User != "" {
+			args = append(args, "--username="+c.User)
+		}
+		if c.Database != "" {
+			args = append(args, "--dbname="+c.Database)
+		}
+		// Fail rather than prompt for a missing password
+		args = append(args, "--no-password")
+		if c.Password != "" {
+			env = append(env, "PGPASSWORD="+c.Password)
+		}
+	case EngineMySQL:
+		if client == "" {
+			client = "mysql"
+		}
+		args = []string{"--no-defaults", "--batch", "--execute=" + statement}
+		if c.Host != "" {
+			args = append(args, "--host="+c.Host)
+		}
+		if c.Port != 0 {
+			args = append(args, "--port="+strconv.Itoa(c.Port))
+		}
+		if c.User != "" {
+			args = append(args, "--user="+c.User)
+		}
+		if c.Database != "" {
+			args = append(args, c.Database)
+		}
+		if c.Password != "" {
+			env = append(env, "MYSQL_PWD="+c.Password)
+		}
+	default:
+		return "", nil, nil, fmt.Errorf("unknown database engine %q", c.Engine)
+	}
+	return client, args, env, nil
+}
+
+// RunAll runs the checkpoints at the same time, giving each up to timeout,
+// and returns the errors of those that failed
+func RunAll(ctx context.Context, checkpoints []Checkpoint, timeout time.Duration) error {
+	var (
+		errs []error
+		lock sync.Mutex
+		wg   sync.WaitGroup
+	)
+	for _, checkpoint := range checkpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := checkpoint.Run(ctx)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", checkpoint.Name, err))
+				return
+			}
+			logger().Info("Checkpointed database", "name", checkpoint.Name, "engine", checkpoint.Engine, "duration", time.Since(start).Round(time.Millisecond))
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// firstLine returns the first line of a client's error output
+func firstLine(output string) string {
+	line, _, _ := strings.Cut(output, "\n")
+	return strings.TrimSpace(line)
+}
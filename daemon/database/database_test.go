@@ -0,0 +1,87 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin
+
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClient writes a client that logs its arguments and the password it
+// was given, returning its path and the log path
+func fakeClient(t *testing.T, script string) (string, string) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "client.log")
+	client := filepath.Join(dir, "client")
+	content := "#!/bin/sh\necho \"$@|$PGPASSWORD|$MYSQL_PWD\" >> " + logPath + "\n" + script
+	if err := os.WriteFile(client, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return client, logPath
+}
+
+func TestRunAll(t *testing.T) {
+	client, logPath := fakeClient(t, "")
+	checkpoints := []Checkpoint{
+		{Name: "pg", Engine: EnginePostgres, Host: "localhost", Port: 5433, User: "postgres", Password: "pg-secret", Database: "app", Client: client},
+		{Name: "mysql", Engine: EngineMySQL, User: "root", Password: "my-secret", Statement: "FLUSH LOGS", Client: client},
+	}
+	if err := RunAll(context.Background(), checkpoints, time.Minute); err != nil {
+		t.Fatalf("RunAll failed: %v", err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	for _, want := range []string{
+		"--no-psqlrc --set=ON_ERROR_STOP=1 --command=CHECKPOINT --host=localhost --port=5433 --username=postgres --dbname=app --no-password|pg-secret|\n",
+		"--no-defaults --batch --execute=FLUSH LOGS --user=root||my-secret\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected a call %q, got %q", want, got)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(got), "\n") {
+		if args, _, _ := strings.Cut(line, "|"); strings.Contains(args, "secret") {
+			t.Errorf("Expected passwords to stay off the command line, got %q", args)
+		}
+	}
+}
+
+func TestRunAllFailure(t *testing.T) {
+	client, _ := fakeClient(t, "echo 'psql: error: FATAL:  must be superuser to do CHECKPOINT' >&2\necho 'details' >&2\nexit 2\n")
+	checkpoints := []Checkpoint{
+		{Name: "pg", Engine: EnginePostgres, Client: client},
+		{Name: "unknown", Engine: "oracle"},
+	}
+	err := RunAll(context.Background(), checkpoints, time.Minute)
+	if err == nil {
+		t.Fatal("Expected RunAll to fail")
+	}
+	for _, want := range []string{"pg: exit status 2: psql: error: FATAL:  must be superuser to do CHECKPOINT", `unknown: unknown database engine "oracle"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %q", want, err)
+		}
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	client, _ := fakeClient(t, "exec sleep 10\n")
+	start := time.Now()
+	err := RunAll(context.Background(), []Checkpoint{{Name: "slow", Engine: EngineMySQL, Client: client}}, 100*time.Millisecond)
+	if err == nil {
+		t.Fatal("Expected a timeout")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected the checkpoint to be killed at the timeout, took %v", elapsed)
+	}
+}
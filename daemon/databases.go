@@ -0,0 +1,39 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/database"
+)
+
+// checkpointDatabases checkpoints the configured databases, returning the
+// errors of those that failed. The default pipeline carries on regardless,
+// since a database still recovers from its log when the instance starts.
+func checkpointDatabases(ctx context.Context, config DatabasesConfig) error {
+	if len(config.Checkpoints) == 0 {
+		return nil
+	}
+	checkpoints := make([]database.Checkpoint, len(config.Checkpoints))
+	for i, c := range config.Checkpoints {
+		checkpoints[i] = database.Checkpoint{
+			Name:      c.Name,
+			Engine:    c.Engine,
+			Host:      c.Host,
+			Port:      c.Port,
+			User:      c.User,
+			Password:  c.Password,
+			Database:  c.Database,
+			Statement: c.Statement,
+			Client:    c.Client,
+		}
+	}
+	if err := database.RunAll(ctx, checkpoints, time.Duration(config.TimeoutSecs)*time.Second); err != nil {
+		return fmt.Errorf("failed to checkpoint databases: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+)
+
+// processStart is when the daemon started, for its uptime
+var processStart = time.Now()
+
+// RuntimeStats describes the Go runtime of the daemon
+type RuntimeStats struct {
+	GoVersion     string    `json:"go_version"`
+	UptimeSecs    int64     `json:"uptime_secs"`
+	Goroutines    int       `json:"goroutines"`
+	GOMAXPROCS    int       `json:"gomaxprocs"`
+	HeapAlloc     uint64    `json:"heap_alloc_bytes"`
+	HeapSys       uint64    `json:"heap_sys_bytes"`
+	HeapObjects   uint64    `json:"heap_objects"`
+	Sys           uint64    `json:"sys_bytes"`
+	TotalAlloc    uint64    `json:"total_alloc_bytes"`
+	NumGC         uint32    `json:"num_gc"`
+	LastGC        time.Time `json:"last_gc,omitempty"`
+	PauseTotalMS  float64   `json:"gc_pause_total_ms"`
+	GCCPUFraction float64   `json:"gc_cpu_fraction"`
+}
+
+// runtimeStats reads the current runtime statistics
+func runtimeStats() RuntimeStats {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := RuntimeStats{
+		GoVersion:     runtime.Version(),
+		UptimeSecs:    int64(time.Since(processStart).Seconds()),
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		HeapAlloc:     mem.HeapAlloc,
+		HeapSys:       mem.HeapSys,
+		HeapObjects:   mem.HeapObjects,
+		Sys:           mem.Sys,
+		TotalAlloc:    mem.TotalAlloc,
+		NumGC:         mem.NumGC,
+		PauseTotalMS:  float64(mem.PauseTotalNs) / float64(time.Millisecond),
+		GCCPUFraction: mem.GCCPUFraction,
+	}
+	if mem.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(mem.LastGC))
+	}
+	return stats
+}
+
+// startDebugServer serves net/http/pprof on addr until ctx is cancelled.
+// Profiles expose memory contents, so the address should be loopback only.
+func startDebugServer(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger().Error("Debug server failed", "error", err)
+		}
+	}()
+	logger().Warn("Serving pprof debug endpoints", "address", listener.Addr().String())
+	return nil
+}
@@ -0,0 +1,73 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// dependencyChecker reads the states of the instances that depend on this
+// one, such as the scheduler a worker serves, and keeps the instance
+// running while any of them is active
+type dependencyChecker struct {
+	*instanceStates
+	ignoreUnknown bool
+}
+
+// newDependencyChecker creates a checker, or returns nil if no dependents
+// are declared or their states can't be read
+func newDependencyChecker(cloudProvider common.CloudProvider, config Config) *dependencyChecker {
+	dependencies := config.Dependencies
+	if len(dependencies.Instances) == 0 && len(dependencies.Tags) == 0 {
+		return nil
+	}
+	read, err := newInstanceStateReader(cloudProvider, config, dependencies.Source, dependencies.Instances, dependencies.Tags)
+	if err != nil {
+		logger().Error("Dependencies disabled", "error", err)
+		return nil
+	}
+	return &dependencyChecker{
+		instanceStates: &instanceStates{read: read, interval: time.Duration(dependencies.CheckSeconds) * time.Second},
+		ignoreUnknown:  dependencies.IgnoreUnknown,
+	}
+}
+
+// Check reads the states of the dependents again if the check interval has
+// passed. It returns why the instance must keep running, or "" if no
+// dependent needs it.
+func (c *dependencyChecker) Check(ctx context.Context, now time.Time) string {
+	if c == nil {
+		return ""
+	}
+	states, err := c.Read(ctx, now, "dependent instances")
+	if err != nil {
+		if c.ignoreUnknown {
+			return ""
+		}
+		return fmt.Sprintf("Snooze suppressed as the states of dependent instances can't be read: %v", err)
+	}
+	for _, state := range states {
+		switch state.State {
+		case common.FleetStateActive:
+			return fmt.Sprintf("Snooze suppressed while dependent instance %s is active: %s", state.InstanceID, state.Reason)
+		case common.FleetStateStale, common.FleetStateError:
+			if !c.ignoreUnknown {
+				return fmt.Sprintf("Snooze suppressed as the state of dependent instance %s is unknown: %s", state.InstanceID, state.Reason)
+			}
+		}
+	}
+	return ""
+}
+
+// States returns the states of the dependents last read
+func (c *dependencyChecker) States() []common.FleetMemberStatus {
+	if c == nil {
+		return nil
+	}
+	return c.instanceStates.States()
+}
@@ -0,0 +1,19 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/docker"
+)
+
+// newContainerStopper returns the stopper of the running containers, or nil
+// if containers are left to the operating system's shutdown
+func newContainerStopper(config DockerConfig) *docker.Stopper {
+	if !config.StopContainers {
+		return nil
+	}
+	return docker.NewStopper(config.SocketPath, time.Duration(config.StopTimeoutSecs)*time.Second, config.Exclude, config.StatePath)
+}
@@ -0,0 +1,250 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package docker stops running containers through the Docker Engine API
+// before the instance stops, so they get SIGTERM and time to run their
+// shutdown handlers, and starts them again once the instance is back.
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+)
+
+// apiGrace is how much longer than the stop timeout a stop request may take
+const apiGrace = 30 * time.Second
+
+// Container is a running container
+type Container struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	Image string   `json:"Image"`
+}
+
+// Name returns the container's name without the leading slash
+func (c Container) Name() string {
+	if len(c.Names) == 0 {
+		return c.ID
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
+
+// Stopper stops containers before a snooze and starts them after
+type Stopper struct {
+	client    *http.Client
+	timeout   time.Duration
+	exclude   []string
+	statePath string
+}
+
+// logger returns the docker component logger
+func logger() *slog.Logger {
+	return logging.Component("docker")
+}
+
+// NewStopper creates a stopper talking to the Docker daemon at socketPath.
+// Containers get timeout to exit before they're killed, and those named in
+// exclude are left running. The stopped containers are listed in
+// statePath until they're started again; an empty path leaves them stopped.
+func NewStopper(socketPath string, timeout time.Duration, exclude []string, statePath string) *Stopper {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &Stopper{
+		client:    &http.Client{Transport: transport},
+		timeout:   timeout,
+		exclude:   exclude,
+		statePath: statePath,
+	}
+}
+
+// StopAll stops the running containers at the same time and waits for
+// them to exit. Containers that stopped are recorded even if others failed.
+func (s *Stopper) StopAll(ctx context.Context) error {
+	running, err := s.running(ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		stopped []string
+		failed  []string
+		lock    sync.Mutex
+		wg      sync.WaitGroup
+	)
+	for _, container := range running {
+		if slices.Contains(s.exclude, container.Name()) {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.stop(ctx, container.ID)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				logger().Warn("Failed to stop container", "container", container.Name(), "error", err)
+				failed = append(failed, container.Name())
+				return
+			}
+			logger().Info("Stopped container", "container", container.Name(), "image", container.Image)
+			stopped = append(stopped, container.ID)
+		}()
+	}
+	wg.Wait()
+
+	if err := s.save(stopped); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to stop containers %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// StartStopped starts the containers the last StopAll stopped, then
+// forgets them so they're only started once
+func (s *Stopper) StartStopped(ctx context.Context) error {
+	if s == nil || s.statePath == "" {
+		return nil
+	}
+	ids, err := s.load()
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := s.post(ctx, "/containers/"+url.PathEscape(id)+"/start", nil); err != nil {
+			logger().Warn("Failed to start container", "container", id, "error", err)
+			continue
+		}
+		logger().Info("Started container", "container", id)
+	}
+	if err := os.Remove(s.statePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove stopped containers: %v", err)
+	}
+	return nil
+}
+
+// running lists the running containers
+func (s *Stopper) running(ctx context.Context) ([]Container, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://docker/containers/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to list containers: %s", apiError(response))
+	}
+
+	var containers []Container
+	if err := json.NewDecoder(response.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("failed to parse containers: %v", err)
+	}
+	return containers, nil
+}
+
+// stop sends the container SIGTERM, or its configured stop signal, and
+// waits for it to exit, killing it after the timeout
+func (s *Stopper) stop(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout+apiGrace)
+	defer cancel()
+	query := url.Values{"t": {fmt.Sprint(int(s.timeout.Seconds()))}}
+	return s.post(ctx, "/containers/"+url.PathEscape(id)+"/stop", query)
+}
+
+// post sends a request without a body; already being in the requested
+// state isn't an error
+func (s *Stopper) post(ctx context.Context, path string, query url.Values) error {
+	target := "http://docker" + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, target, nil)
+	if err != nil {
+		return err
+	}
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusNotModified {
+		return fmt.Errorf("%s", apiError(response))
+	}
+	return nil
+}
+
+// load returns the containers recorded as stopped
+func (s *Stopper) load() ([]string, error) {
+	data, err := os.ReadFile(s.statePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stopped containers: %v", err)
+	}
+	var ids []string
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return nil, fmt.Errorf("failed to parse stopped containers: %v", err)
+	}
+	return ids, nil
+}
+
+// save records the stopped containers, if they're to be started again,
+// along with any from an earlier stop that weren't started yet
+func (s *Stopper) save(ids []string) error {
+	if s.statePath == "" || len(ids) == 0 {
+		return nil
+	}
+	earlier, _ := s.load()
+	for _, id := range earlier {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(s.statePath), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("failed to serialize stopped containers: %v", err)
+	}
+	if err := os.WriteFile(s.statePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write stopped containers: %v", err)
+	}
+	return nil
+}
+
+// apiError returns the message of a failed API response
+func apiError(response *http.Response) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		return body.Message
+	}
+	return response.Status
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDocker serves the container endpoints on a Unix socket
+type fakeDocker struct {
+	running map[string]string // Name by ID
+	broken  string            // ID whose stop fails
+	stopped []string
+	started []string
+	timeout string
+	lock    sync.Mutex
+}
+
+func (f *fakeDocker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/containers/json":
+		var containers []Container
+		for id, name := range f.running {
+			containers = append(containers, Container{ID: id, Names: []string{"/" + name}, Image: "nginx"})
+		}
+		json.NewEncoder(w).Encode(containers)
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "stop":
+		if parts[1] == f.broken {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message": "cannot stop container"}`))
+			return
+		}
+		f.timeout = r.URL.Query().Get("t")
+		f.stopped = append(f.stopped, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[2] == "start":
+		f.started = append(f.started, parts[1])
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serve starts the fake on a socket, returning its path
+func serve(t *testing.T, handler http.Handler) string {
+	dir, err := os.MkdirTemp("", "docker")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "docker.sock")
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return path
+}
+
+func TestStopAndStart(t *testing.T) {
+	fake := &fakeDocker{running: map[string]string{"a1": "web", "b2": "db", "c3": "agent"}}
+	statePath := filepath.Join(t.TempDir(), "containers.json")
+	stopper := NewStopper(serve(t, fake), 20*time.Second, []string{"agent"}, statePath)
+
+	if err := stopper.StopAll(context.Background()); err != nil {
+		t.Fatalf("StopAll failed: %v", err)
+	}
+	if len(fake.stopped) != 2 || fake.timeout != "20" {
+		t.Errorf("Expected web and db to be stopped with a 20s timeout, stopped %v with %q", fake.stopped, fake.timeout)
+	}
+
+	if err := stopper.StartStopped(context.Background()); err != nil {
+		t.Fatalf("StartStopped failed: %v", err)
+	}
+	if len(fake.started) != 2 {
+		t.Errorf("Expected the stopped containers to be started, started %v", fake.started)
+	}
+	if _, err := os.Stat(statePath); !os.IsNotExist(err) {
+		t.Error("Expected the stopped containers to be forgotten once started")
+	}
+}
+
+func TestStopFailure(t *testing.T) {
+	fake := &fakeDocker{running: map[string]string{"a1": "web", "b2": "db"}, broken: "b2"}
+	statePath := filepath.Join(t.TempDir(), "containers.json")
+	stopper := NewStopper(serve(t, fake), time.Second, nil, statePath)
+
+	err := stopper.StopAll(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "db") {
+		t.Errorf("Expected db to fail to stop, got %v", err)
+	}
+	// The container that did stop is still started again
+	if data, _ := os.ReadFile(statePath); string(data) != `["a1"]` {
+		t.Errorf("Expected only web to be recorded, got %s", data)
+	}
+}
+
+func TestDockerUnavailable(t *testing.T) {
+	stopper := NewStopper(filepath.Join(t.TempDir(), "missing.sock"), time.Second, nil, "")
+	if err := stopper.StopAll(context.Background()); err == nil {
+		t.Error("Expected an error without a Docker daemon")
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// envPrefix starts the environment variables that override settings
+const envPrefix = "SNOOZED_"
+
+// applyEnvironment overrides settings with SNOOZED_ environment variables,
+// so a container can be configured without a config file of its own. A
+// variable is named for the setting's key in upper case, with the keys of
+// nested settings joined by a double underscore, as in
+// SNOOZED_NAPTIME_MINUTES or SNOOZED_DOCKER__STOP_CONTAINERS. Values are read
+// as JSON, so lists and objects are given whole, or else as strings.
+func applyEnvironment(config *Config, environ []string) error {
+	variables := make(map[string]string)
+	for _, variable := range environ {
+		name, value, _ := strings.Cut(variable, "=")
+		if strings.HasPrefix(name, envPrefix) && name != envPrefix {
+			variables[name] = value
+		}
+	}
+	if len(variables) == 0 {
+		return nil
+	}
+	if *lockedConfig {
+		return fmt.Errorf("settings can't be overridden from the environment when the config is locked")
+	}
+
+	// Whole sections come before the settings within them
+	names := make([]string, 0, len(variables))
+	for name := range variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		key := strings.Split(strings.ToLower(strings.TrimPrefix(name, envPrefix)), "__")
+		if err := overrideSetting(config, key, variables[name]); err != nil {
+			return fmt.Errorf("invalid %s: %v", name, err)
+		}
+		keys = append(keys, strings.Join(key, "."))
+	}
+	logger().Info("Settings overridden from the environment", "settings", keys)
+	return nil
+}
+
+// overrideSetting sets the setting at key, decoding value as JSON if it
+// fits the setting and as a string otherwise
+func overrideSetting(config *Config, key []string, value string) error {
+	var parsed interface{}
+	if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+		parsed = value
+	}
+	err := decodeSetting(config, key, parsed)
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && parsed != value {
+		// Such as a number given for a setting that takes a string
+		err = decodeSetting(config, key, value)
+	}
+	return err
+}
+
+// decodeSetting decodes value into the setting at key, leaving the other
+// settings as they are. Keys that aren't settings are refused.
+func decodeSetting(config *Config, key []string, value interface{}) error {
+	for i := len(key) - 1; i >= 0; i-- {
+		value = map[string]interface{}{key[i]: value}
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(config)
+}
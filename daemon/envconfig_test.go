@@ -0,0 +1,81 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestApplyEnvironment(t *testing.T) {
+	lockConfig(t, false)
+	config := DefaultConfig()
+	environ := []string{
+		"PATH=/usr/bin",
+		"SNOOZE_REASON=idle",
+		"SNOOZED_NAPTIME_MINUTES=60",
+		"SNOOZED_CPU_THRESHOLD_PERCENT=12.5",
+		"SNOOZED_AWS_REGION=eu-west-1",
+		"SNOOZED_TAGGING_PREFIX=1234",
+		"SNOOZED_DOCKER__STOP_CONTAINERS=true",
+		`SNOOZED_DOCKER__EXCLUDE=["db","cache"]`,
+		"SNOOZED_LOGGING__LOG_LEVEL=debug",
+	}
+	if err := applyEnvironment(&config, environ); err != nil {
+		t.Fatalf("applyEnvironment returned error: %v", err)
+	}
+
+	if config.NaptimeMinutes != 60 || config.CPUThresholdPercent != 12.5 {
+		t.Errorf("Expected numbers to be read as JSON, got %d and %g", config.NaptimeMinutes, config.CPUThresholdPercent)
+	}
+	if config.AWSRegion != "eu-west-1" || config.TaggingPrefix != "1234" {
+		t.Errorf("Expected plain and numeric strings, got %q and %q", config.AWSRegion, config.TaggingPrefix)
+	}
+	if !config.Docker.StopContainers || !slices.Equal(config.Docker.Exclude, []string{"db", "cache"}) {
+		t.Errorf("Expected nested settings, got %+v", config.Docker)
+	}
+
+	// Settings that weren't given keep their values
+	defaults := DefaultConfig()
+	if config.Logging.LogLevel != "debug" || config.Logging.LogFormat != defaults.Logging.LogFormat || config.Docker.SocketPath != defaults.Docker.SocketPath {
+		t.Errorf("Expected the rest of each section to be left alone, got %+v and %+v", config.Logging, config.Docker)
+	}
+}
+
+func TestApplyEnvironmentRefuses(t *testing.T) {
+	lockConfig(t, false)
+	tests := []struct {
+		name     string
+		variable string
+		want     string
+	}{
+		{"unknown setting", "SNOOZED_NAPTIME=60", "SNOOZED_NAPTIME"},
+		{"unknown nested setting", "SNOOZED_DOCKER__STOP=true", "SNOOZED_DOCKER__STOP"},
+		{"wrong type", "SNOOZED_NAPTIME_MINUTES=soon", "SNOOZED_NAPTIME_MINUTES"},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		err := applyEnvironment(&config, []string{tt.variable})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error naming %s, got %v", tt.name, tt.want, err)
+		}
+	}
+}
+
+func TestApplyEnvironmentLockedConfig(t *testing.T) {
+	lockConfig(t, true)
+	config := DefaultConfig()
+	if err := applyEnvironment(&config, []string{"SNOOZED_NAPTIME_MINUTES=600"}); err == nil {
+		t.Error("Expected overrides to be refused with -locked-config")
+	}
+	if config.NaptimeMinutes != DefaultConfig().NaptimeMinutes {
+		t.Errorf("Expected the locked config to be left alone, got naptime %d", config.NaptimeMinutes)
+	}
+
+	// Without overrides a locked config starts as usual
+	if err := applyEnvironment(&config, []string{"PATH=/usr/bin", "SNOOZE_REASON=idle"}); err != nil {
+		t.Errorf("Expected no error without overrides, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// flushedKB is how little dirty and writeback memory counts as flushed
+const flushedKB = 1024
+
+// meminfoPath is where the kernel reports dirty page counts
+const meminfoPath = "/proc/meminfo"
+
+// flushPollInterval is how often dirty page counts are checked while
+// waiting for them to be written
+var flushPollInterval = 250 * time.Millisecond
+
+// The platform's sync, unmount and mount, and the reader of meminfoPath
+var (
+	syncAll     = syncAllFilesystems
+	unmount     = unmountFilesystem
+	mount       = mountFilesystem
+	openMeminfo = func() (io.ReadCloser, error) { return os.Open(meminfoPath) }
+)
+
+// prepareFilesystems gets the filesystems ready for the instance to stop:
+// it flushes cached writes, unmounts the configured mounts and waits for
+// dirty pages to reach the disks. Every part is tried, and the problems
+// are returned together; the default pipeline carries on with the stop.
+// It returns the mounts it unmounted, for remountFilesystems.
+func prepareFilesystems(ctx context.Context, config FilesystemsConfig) ([]string, error) {
+	if !config.SyncBeforeStop && len(config.Unmount) == 0 {
+		return nil, nil
+	}
+	start := time.Now()
+	timeout := time.Duration(config.FlushTimeoutSecs) * time.Second
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Unmounting flushes the mounts too, but not the rest
+	var errs []error
+	var unmounted []string
+	if config.SyncBeforeStop {
+		if err := syncFilesystems(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to sync filesystems: %v", err))
+		}
+	}
+	for _, mount := range config.Unmount {
+		if err := unmount(mount); err != nil {
+			errs = append(errs, fmt.Errorf("failed to unmount %s: %v", mount, err))
+			continue
+		}
+		unmounted = append(unmounted, mount)
+		logger().Info("Unmounted filesystem", "mount", mount)
+	}
+	if config.SyncBeforeStop {
+		if err := waitForFlush(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("dirty pages not flushed: %v", err))
+		}
+	}
+	logger().Info("Prepared filesystems for stop", "took", time.Since(start).Round(time.Millisecond).String())
+	return unmounted, errors.Join(errs...)
+}
+
+// remountFilesystems mounts what prepareFilesystems unmounted again, newest
+// first, for an instance that keeps running. Every mount is tried, and the
+// problems are returned together.
+func remountFilesystems(ctx context.Context, mounts []string) error {
+	var errs []error
+	for i := len(mounts) - 1; i >= 0; i-- {
+		if err := mount(ctx, mounts[i]); err != nil {
+			errs = append(errs, fmt.Errorf("failed to mount %s: %v", mounts[i], err))
+			continue
+		}
+		logger().Info("Mounted filesystem again", "mount", mounts[i])
+	}
+	return errors.Join(errs...)
+}
+
+// syncFilesystems asks the kernel to write out cached data, giving up when
+// ctx is done since a hung network mount can block it indefinitely
+func syncFilesystems(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		syncAll()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("sync still running: %v", ctx.Err())
+	}
+}
+
+// waitForFlush waits until little dirty or writeback memory is left. It
+// returns straight away where the kernel doesn't report it.
+func waitForFlush(ctx context.Context) error {
+	for {
+		pending, err := dirtyKB()
+		if err != nil {
+			return nil
+		}
+		if pending <= flushedKB {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d kB still to be written", pending)
+		case <-time.After(flushPollInterval):
+		}
+	}
+}
+
+// dirtyKB returns the memory waiting to be written to disk, in kB
+func dirtyKB() (int64, error) {
+	file, err := openMeminfo()
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var total int64
+	found := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || (fields[0] != "Dirty:" && fields[0] != "Writeback:") {
+			continue
+		}
+		value, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", fields[0], fields[1])
+		}
+		total += value
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("no dirty page counts in %s", meminfoPath)
+	}
+	return total, nil
+}
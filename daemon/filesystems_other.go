@@ -0,0 +1,25 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux && !darwin && !freebsd && !openbsd
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// syncAllFilesystems isn't supported on this platform; the operating system flushes
+// filesystems as it shuts down
+func syncAllFilesystems() {}
+
+// unmountFilesystem isn't supported on this platform
+func unmountFilesystem(path string) error {
+	return fmt.Errorf("unmounting %s is not supported on this platform", path)
+}
+
+// mountFilesystem isn't supported on this platform
+func mountFilesystem(ctx context.Context, path string) error {
+	return fmt.Errorf("mounting %s is not supported on this platform", path)
+}
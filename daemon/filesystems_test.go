@@ -0,0 +1,188 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeMeminfo makes each read of meminfo return the next of reports, then
+// the last one again. No reports means there's no meminfo.
+func fakeMeminfo(t *testing.T, reports ...string) {
+	previous, previousInterval := openMeminfo, flushPollInterval
+	flushPollInterval = time.Millisecond
+	t.Cleanup(func() { openMeminfo, flushPollInterval = previous, previousInterval })
+
+	reads := 0
+	openMeminfo = func() (io.ReadCloser, error) {
+		if len(reports) == 0 {
+			return nil, os.ErrNotExist
+		}
+		report := reports[min(reads, len(reports)-1)]
+		reads++
+		return io.NopCloser(strings.NewReader(report)), nil
+	}
+}
+
+// fakeFilesystems records the paths unmounted and mounted
+type fakeFilesystems struct {
+	unmounted []string
+	mounted   []string
+}
+
+// fakeFilesystemCalls replaces sync, unmount and mount, failing for the
+// paths in busy
+func fakeFilesystemCalls(t *testing.T, busy ...string) *fakeFilesystems {
+	previousSync, previousUnmount, previousMount := syncAll, unmount, mount
+	t.Cleanup(func() { syncAll, unmount, mount = previousSync, previousUnmount, previousMount })
+
+	calls := &fakeFilesystems{}
+	syncAll = func() {}
+	unmount = func(path string) error {
+		calls.unmounted = append(calls.unmounted, path)
+		if slices.Contains(busy, path) {
+			return errors.New("device or resource busy")
+		}
+		return nil
+	}
+	mount = func(ctx context.Context, path string) error {
+		calls.mounted = append(calls.mounted, path)
+		if slices.Contains(busy, path) {
+			return errors.New("mount point busy")
+		}
+		return nil
+	}
+	return calls
+}
+
+func TestDirtyKB(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []string
+		want    int64
+		wantErr bool
+	}{
+		{"dirty and writeback", []string{"MemTotal:       16318440 kB\nDirty:               100 kB\nWriteback:            20 kB\n"}, 120, false},
+		{"dirty only", []string{"Dirty:               512 kB\n"}, 512, false},
+		{"no counts", []string{"MemTotal:       16318440 kB\n"}, 0, true},
+		{"invalid count", []string{"Dirty:               lots kB\n"}, 0, true},
+		{"no meminfo", nil, 0, true},
+	}
+	for _, tt := range tests {
+		fakeMeminfo(t, tt.reports...)
+		got, err := dirtyKB()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %d kB, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestWaitForFlush(t *testing.T) {
+	tests := []struct {
+		name    string
+		reports []string
+		wantErr string
+	}{
+		{"already flushed", []string{"Dirty: 12 kB\nWriteback: 0 kB\n"}, ""},
+		{"flushed while waiting", []string{"Dirty: 90000 kB\n", "Dirty: 40000 kB\n", "Dirty: 0 kB\n"}, ""},
+		{"never flushed", []string{"Dirty: 90000 kB\nWriteback: 10000 kB\n"}, "100000 kB still to be written"},
+		{"no meminfo", nil, ""},
+	}
+	for _, tt := range tests {
+		fakeMeminfo(t, tt.reports...)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		err := waitForFlush(ctx)
+		cancel()
+
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestPrepareFilesystems(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    FilesystemsConfig
+		busy      []string
+		unmounted []string
+		wantErrs  []string
+	}{
+		{"nothing to do", FilesystemsConfig{FlushTimeoutSecs: 1}, nil, nil, nil},
+		{"unmounts", FilesystemsConfig{Unmount: []string{"/scratch", "/data"}, FlushTimeoutSecs: 1},
+			nil, []string{"/scratch", "/data"}, nil},
+		{"unmount errors gathered", FilesystemsConfig{Unmount: []string{"/scratch", "/data", "/shared"}, FlushTimeoutSecs: 1},
+			[]string{"/scratch", "/shared"}, []string{"/data"},
+			[]string{"failed to unmount /scratch", "failed to unmount /shared"}},
+	}
+	for _, tt := range tests {
+		fakeMeminfo(t, "Dirty: 0 kB\n")
+		calls := fakeFilesystemCalls(t, tt.busy...)
+
+		unmounted, err := prepareFilesystems(context.Background(), tt.config)
+		if !slices.Equal(calls.unmounted, tt.config.Unmount) {
+			t.Errorf("%s: expected every mount to be tried, got %v", tt.name, calls.unmounted)
+		}
+		if !slices.Equal(unmounted, tt.unmounted) {
+			t.Errorf("%s: expected %v to be reported unmounted, got %v", tt.name, tt.unmounted, unmounted)
+		}
+		if len(tt.wantErrs) == 0 && err != nil {
+			t.Errorf("%s: expected no error, got %v", tt.name, err)
+		}
+		for _, want := range tt.wantErrs {
+			if err == nil || !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: expected an error containing %q, got %v", tt.name, want, err)
+			}
+		}
+	}
+}
+
+func TestPrepareFilesystemsFlushTimeout(t *testing.T) {
+	fakeMeminfo(t, "Dirty: 90000 kB\n")
+	calls := fakeFilesystemCalls(t, "/scratch")
+
+	start := time.Now()
+	_, err := prepareFilesystems(context.Background(), FilesystemsConfig{
+		SyncBeforeStop:   true,
+		Unmount:          []string{"/scratch"},
+		FlushTimeoutSecs: 1,
+	})
+	if took := time.Since(start); took > 3*time.Second {
+		t.Errorf("Expected the flush to be given up after its timeout, took %s", took)
+	}
+	if err == nil || !strings.Contains(err.Error(), "dirty pages not flushed") || !strings.Contains(err.Error(), "failed to unmount /scratch") {
+		t.Errorf("Expected the unmount and flush errors together, got %v", err)
+	}
+	if len(calls.unmounted) != 1 {
+		t.Errorf("Expected the unmount to be tried, got %v", calls.unmounted)
+	}
+}
+
+func TestRemountFilesystems(t *testing.T) {
+	calls := fakeFilesystemCalls(t, "/data")
+
+	err := remountFilesystems(context.Background(), []string{"/scratch", "/data", "/shared"})
+	if !slices.Equal(calls.mounted, []string{"/shared", "/data", "/scratch"}) {
+		t.Errorf("Expected every mount to be tried newest first, got %v", calls.mounted)
+	}
+	if err == nil || !strings.Contains(err.Error(), "failed to mount /data") {
+		t.Errorf("Expected the failed mount to be reported, got %v", err)
+	}
+	if err := remountFilesystems(context.Background(), nil); err != nil {
+		t.Errorf("Expected nothing to mount without unmounts, got %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin || freebsd || openbsd
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"syscall"
+)
+
+// syncAllFilesystems writes out the data cached for every filesystem
+func syncAllFilesystems() {
+	syscall.Sync()
+}
+
+// unmountFilesystem unmounts the filesystem at path, which fails if it is in use or
+// the daemon has dropped root
+func unmountFilesystem(path string) error {
+	return syscall.Unmount(path, 0)
+}
+
+// mountFilesystem mounts the filesystem at path again from its fstab entry
+func mountFilesystem(ctx context.Context, path string) error {
+	if output, err := exec.CommandContext(ctx, "mount", path).CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("%v: %s", err, message)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package health tracks the state of daemon components so problems that
+// don't stop the daemon are still visible to operators.
+package health
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// State is the health of a component
+type State string
+
+// Component states, from best to worst
+const (
+	OK       State = "ok"
+	Degraded State = "degraded"
+	Failed   State = "failed"
+)
+
+// Check is the last reported state of a component
+type Check struct {
+	Component string    `json:"component"`
+	State     State     `json:"state"`
+	Message   string    `json:"message,omitempty"`
+	Updated   time.Time `json:"updated"`
+}
+
+// Tracker records component health
+type Tracker struct {
+	checks map[string]Check
+	lock   sync.RWMutex
+}
+
+// NewTracker creates an empty health tracker
+func NewTracker() *Tracker {
+	return &Tracker{
+		checks: make(map[string]Check),
+	}
+}
+
+// Set records the state of a component
+func (t *Tracker) Set(component string, state State, message string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.checks[component] = Check{
+		Component: component,
+		State:     state,
+		Message:   message,
+		Updated:   time.Now(),
+	}
+}
+
+// Remove forgets a component
+func (t *Tracker) Remove(component string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	delete(t.checks, component)
+}
+
+// Checks returns all component checks ordered by component name
+func (t *Tracker) Checks() []Check {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	result := make([]Check, 0, len(t.checks))
+	for _, check := range t.checks {
+		result = append(result, check)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Component < result[j].Component
+	})
+	return result
+}
+
+// Overall returns the worst state of any component
+func (t *Tracker) Overall() State {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	overall := OK
+	for _, check := range t.checks {
+		if check.State == Failed {
+			return Failed
+		}
+		if check.State == Degraded {
+			overall = Degraded
+		}
+	}
+	return overall
+}
+
+// Global tracker instance
+var Default = NewTracker()
+
+// Set records the state of a component in the global tracker
+func Set(component string, state State, message string) {
+	Default.Set(component, state, message)
+}
@@ -0,0 +1,34 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package health
+
+import "testing"
+
+func TestTrackerOverall(t *testing.T) {
+	tracker := NewTracker()
+	if tracker.Overall() != OK {
+		t.Errorf("Expected empty tracker to be ok, got %s", tracker.Overall())
+	}
+
+	tracker.Set("plugin:b", Degraded, "slow")
+	tracker.Set("plugin:a", OK, "")
+	if tracker.Overall() != Degraded {
+		t.Errorf("Expected degraded, got %s", tracker.Overall())
+	}
+
+	tracker.Set("plugin:a", Failed, "killed")
+	if tracker.Overall() != Failed {
+		t.Errorf("Expected failed, got %s", tracker.Overall())
+	}
+
+	checks := tracker.Checks()
+	if len(checks) != 2 || checks[0].Component != "plugin:a" || checks[0].Message != "killed" {
+		t.Errorf("Unexpected checks %+v", checks)
+	}
+
+	tracker.Remove("plugin:a")
+	if tracker.Overall() != Degraded {
+		t.Errorf("Expected degraded after removing failed component, got %s", tracker.Overall())
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+const (
+	// DefaultHistoryPath is the default location of the history file
+	DefaultHistoryPath = "/var/lib/cloudsnooze/history.json"
+
+	// DefaultMaxEvents is the default number of events retained
+	DefaultMaxEvents = 1000
+)
+
+// historyFile is the on-disk format of the history file
+type historyFile struct {
+	Events []monitor.SnoozeEvent `json:"events"`
+}
+
+// Store keeps the most recent snooze events in a ring buffer and persists
+// them to a JSON file
+type Store struct {
+	path   string
+	events []monitor.SnoozeEvent
+	next   int
+	full   bool
+	lock   sync.RWMutex
+}
+
+// NewStore creates a history store backed by the given file, loading any
+// existing events. An empty path keeps history in memory only.
+func NewStore(path string, maxEvents int) (*Store, error) {
+	if maxEvents <= 0 {
+		maxEvents = DefaultMaxEvents
+	}
+
+	store := &Store{
+		path:   path,
+		events: make([]monitor.SnoozeEvent, maxEvents),
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return store, fmt.Errorf("failed to read history file: %v", err)
+	}
+
+	var file historyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return store, fmt.Errorf("failed to parse history file: %v", err)
+	}
+	for _, event := range file.Events {
+		store.push(event)
+	}
+
+	return store, nil
+}
+
+// Add records an event and persists the history
+func (s *Store) Add(event monitor.SnoozeEvent) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.push(event)
+	return s.save()
+}
+
+// Update replaces the most recent event with event if they were recorded
+// at the same time, as when a snooze fills in its results as it goes, and
+// persists the history. Other events are left alone.
+func (s *Store) Update(event monitor.SnoozeEvent) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.count() == 0 {
+		return nil
+	}
+	last := (s.next - 1 + len(s.events)) % len(s.events)
+	if !s.events[last].Timestamp.Equal(event.Timestamp) {
+		return nil
+	}
+	s.events[last] = event
+	return s.save()
+}
+
+// RecordStart records a start event if the instance started after the most
+// recent snooze and the start hasn't been recorded yet, filling in how long
+// it was stopped. It returns the recorded event, or nil if there was none.
+func (s *Store) RecordStart(event monitor.SnoozeEvent) (*monitor.SnoozeEvent, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.count() == 0 {
+		return nil, nil
+	}
+	last := s.events[(s.next-1+len(s.events))%len(s.events)]
+	if last.IsStart() || !event.Timestamp.After(last.Timestamp) {
+		return nil, nil
+	}
+
+	event.Event = monitor.EventStart
+	event.StoppedMins = event.Timestamp.Sub(last.Timestamp).Minutes()
+	s.push(event)
+	return &event, s.save()
+}
+
+// List returns up to limit events recorded at or after since, newest first.
+// A limit of 0 returns all matching events.
+func (s *Store) List(limit int, since time.Time) []monitor.SnoozeEvent {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	result := make([]monitor.SnoozeEvent, 0)
+	count := s.count()
+	for i := 0; i < count; i++ {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		event := s.events[(s.next-1-i+len(s.events))%len(s.events)]
+		if !since.IsZero() && event.Timestamp.Before(since) {
+			continue
+		}
+		result = append(result, event)
+	}
+
+	return result
+}
+
+// Usage returns the number of events held and the most that are kept
+func (s *Store) Usage() (int, int) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.count(), len(s.events)
+}
+
+// push adds an event, overwriting the oldest if the buffer is full
+func (s *Store) push(event monitor.SnoozeEvent) {
+	s.events[s.next] = event
+	s.next = (s.next + 1) % len(s.events)
+	if s.next == 0 {
+		s.full = true
+	}
+}
+
+// count returns the number of events held
+func (s *Store) count() int {
+	if s.full {
+		return len(s.events)
+	}
+	return s.next
+}
+
+// ordered returns the events held, oldest first
+func (s *Store) ordered() []monitor.SnoozeEvent {
+	if !s.full {
+		return s.events[:s.next]
+	}
+	return append(append([]monitor.SnoozeEvent(nil), s.events[s.next:]...), s.events[:s.next]...)
+}
+
+// save writes the history file atomically
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(historyFile{Events: s.ordered()}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize history: %v", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write history file: %v", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace history file: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package history
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+func TestStoreAddAndList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+
+	store, err := NewStore(path, 0)
+	if err != nil {
+		t.Fatalf("NewStore returned error: %v", err)
+	}
+
+	base := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		event := monitor.SnoozeEvent{
+			Timestamp: base.Add(time.Duration(i) * time.Hour),
+			Reason:    "idle",
+		}
+		if err := store.Add(event); err != nil {
+			t.Fatalf("Add returned error: %v", err)
+		}
+	}
+
+	events := store.List(2, time.Time{})
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if !events[0].Timestamp.Equal(base.Add(2 * time.Hour)) {
+		t.Errorf("Expected newest event first, got %v", events[0].Timestamp)
+	}
+
+	events = store.List(0, base.Add(90*time.Minute))
+	if len(events) != 1 {
+		t.Errorf("Expected 1 event since 13:30, got %d", len(events))
+	}
+
+	// Reload from disk
+	reloaded, err := NewStore(path, 0)
+	if err != nil {
+		t.Fatalf("NewStore returned error on reload: %v", err)
+	}
+	if len(reloaded.List(0, time.Time{})) != 3 {
+		t.Errorf("Expected 3 events after reload")
+	}
+}
+
+func TestStoreRetentionLimit(t *testing.T) {
+	store, err := NewStore("", 2)
+	if err != nil {
+		t.Fatalf("NewStore returned error: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := store.Add(monitor.SnoozeEvent{Timestamp: time.Unix(int64(i), 0)}); err != nil {
+			t.Fatalf("Add returned error: %v", err)
+		}
+	}
+
+	events := store.List(0, time.Time{})
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 retained events, got %d", len(events))
+	}
+	if events[1].Timestamp.Unix() != 3 {
+		t.Errorf("Expected oldest retained event to be #3, got %d", events[1].Timestamp.Unix())
+	}
+	if entries, capacity := store.Usage(); entries != 2 || capacity != 2 {
+		t.Errorf("Expected 2 of 2 entries used, got %d of %d", entries, capacity)
+	}
+}
+
+func TestStoreWrapsInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	store, _ := NewStore(path, 3)
+	for i := 0; i < 5; i++ {
+		store.Add(monitor.SnoozeEvent{Timestamp: time.Unix(int64(i), 0)})
+	}
+
+	// The wrapped buffer is saved oldest first, so a smaller limit on
+	// reload keeps the newest events
+	restored, err := NewStore(path, 2)
+	if err != nil {
+		t.Fatalf("NewStore returned error: %v", err)
+	}
+	events := restored.List(0, time.Time{})
+	if len(events) != 2 || events[0].Timestamp.Unix() != 4 || events[1].Timestamp.Unix() != 3 {
+		t.Errorf("Expected events #4 and #3, got %+v", events)
+	}
+}
+
+func TestStoreUpdate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "history.json")
+	store, err := NewStore(path, 0)
+	if err != nil {
+		t.Fatalf("NewStore returned error: %v", err)
+	}
+	earlier := time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC)
+	snoozed := earlier.Add(12 * time.Hour)
+	store.Add(monitor.SnoozeEvent{Timestamp: earlier, Reason: "earlier"})
+	store.Add(monitor.SnoozeEvent{Timestamp: snoozed, Reason: "idle"})
+
+	steps := []monitor.StepResult{{Step: "stop", Status: monitor.StepOK}}
+	if err := store.Update(monitor.SnoozeEvent{Timestamp: snoozed, Reason: "idle", Steps: steps}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+	// Only the most recent event may be updated
+	if err := store.Update(monitor.SnoozeEvent{Timestamp: earlier, Reason: "changed"}); err != nil {
+		t.Fatalf("Update returned error: %v", err)
+	}
+
+	reloaded, err := NewStore(path, 0)
+	if err != nil {
+		t.Fatalf("NewStore returned error: %v", err)
+	}
+	events := reloaded.List(0, time.Time{})
+	if len(events) != 2 || len(events[0].Steps) != 1 || events[1].Reason != "earlier" {
+		t.Errorf("Expected only the latest event to be updated, got %+v", events)
+	}
+}
+
+func TestStoreRecordStart(t *testing.T) {
+	store, err := NewStore("", 0)
+	if err != nil {
+		t.Fatalf("NewStore returned error: %v", err)
+	}
+	stopped := time.Date(2025, 5, 1, 22, 0, 0, 0, time.UTC)
+	started := stopped.Add(9*time.Hour + 30*time.Minute)
+
+	// Nothing to follow yet
+	if recorded, _ := store.RecordStart(monitor.SnoozeEvent{Timestamp: started}); recorded != nil {
+		t.Error("Expected no start without a snooze")
+	}
+
+	store.Add(monitor.SnoozeEvent{Timestamp: stopped, Event: monitor.EventSnooze, Reason: "idle"})
+	// A launch before the snooze means the stop failed
+	if recorded, _ := store.RecordStart(monitor.SnoozeEvent{Timestamp: stopped.Add(-time.Hour)}); recorded != nil {
+		t.Error("Expected no start before the snooze")
+	}
+	if recorded, err := store.RecordStart(monitor.SnoozeEvent{Timestamp: started}); recorded == nil || err != nil {
+		t.Fatalf("Expected the start to be recorded, got %v, %v", recorded, err)
+	}
+	// The daemon restarting doesn't record the start again
+	if recorded, _ := store.RecordStart(monitor.SnoozeEvent{Timestamp: started}); recorded != nil {
+		t.Error("Expected the start to be recorded once")
+	}
+
+	events := store.List(0, time.Time{})
+	if len(events) != 2 || !events[0].IsStart() || events[0].StoppedMins != 570 {
+		t.Errorf("Expected a start after 570 stopped minutes, got %+v", events)
+	}
+}
@@ -0,0 +1,36 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/hooks"
+)
+
+// newHookRunner returns the runner of the configured check, pre_stop,
+// post_start and event commands
+func newHookRunner(config HooksConfig) *hooks.Runner {
+	commands := make([]hooks.Hook, 0, len(config.Commands))
+	for _, c := range config.Commands {
+		commands = append(commands, hooks.Hook{
+			Name:      c.Name,
+			Check:     c.Check,
+			PreStop:   c.PreStop,
+			PostStart: c.PostStart,
+		})
+	}
+	runner := hooks.NewRunner(commands, config.ContextPath, time.Duration(config.TimeoutSecs)*time.Second)
+
+	events := make([]hooks.EventHook, 0, len(config.Events))
+	for _, e := range config.Events {
+		events = append(events, hooks.EventHook{
+			Name:    e.Name,
+			Events:  e.Events,
+			Command: e.Command,
+		})
+	}
+	runner.SetEventHooks(events)
+	return runner
+}
@@ -0,0 +1,380 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package hooks runs commands around a snooze: check commands can veto or
+// defer it, pre_stop commands save state before the instance stops, and
+// their output is kept in a context file that is handed to the matching
+// post_start commands once the instance is running again. Event commands
+// follow the idle state and the outcome of each snooze.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+)
+
+// maxOutput bounds how much of a pre_stop command's output is kept
+const maxOutput = 1 << 20
+
+// DeferExitCode is the exit status (EX_TEMPFAIL) with which a check defers
+// a snooze to the next check instead of vetoing it
+const DeferExitCode = 75
+
+// Hook is a set of commands; any may be empty
+type Hook struct {
+	Name      string
+	Check     string // Command run before snoozing; a non-zero exit vetoes or defers the snooze
+	PreStop   string // Command run before stopping; its output is saved
+	PostStart string // Command run after starting, given the saved output
+}
+
+// Decisions a check can make
+const (
+	Allow = "allow"
+	Defer = "defer" // Try again at the next check
+	Veto  = "veto"  // Wait for the system to be idle for the naptime again
+)
+
+// Verdict is what the checks decided about a snooze
+type Verdict struct {
+	Decision string
+	Hook     string // Hook whose check refused the snooze
+	Message  string // First line the check wrote, or why it failed
+}
+
+// Lifecycle events that event hooks can run on
+const (
+	EventIdleStart    = "idle_start"      // The system became idle
+	EventIdleEnd      = "idle_end"        // The system became busy again
+	EventSnoozed      = "snooze_executed" // The instance is being stopped
+	EventSnoozeFailed = "snooze_failed"   // Stopping the instance failed
+)
+
+// Events lists the lifecycle events, in the order they happen
+var Events = []string{EventIdleStart, EventIdleEnd, EventSnoozed, EventSnoozeFailed}
+
+// EventHook is a command run when any of its events happen
+type EventHook struct {
+	Name    string
+	Events  []string
+	Command string
+}
+
+// EventDetails describes an event to the commands run on it
+type EventDetails struct {
+	Reason     string
+	Trigger    string
+	InstanceID string
+	Metrics    interface{}
+	Error      string // Why the stop failed, for EventSnoozeFailed
+}
+
+// Runner runs hooks and keeps their context between a stop and a start
+type Runner struct {
+	hooks       []Hook
+	events      []EventHook
+	contextPath string
+	timeout     time.Duration
+}
+
+// Context is what the context file holds between a stop and a start
+type Context struct {
+	StoppedAt  time.Time         `json:"stopped_at"`
+	Reason     string            `json:"reason"`
+	Trigger    string            `json:"trigger"`
+	InstanceID string            `json:"instance_id,omitempty"`
+	Outputs    map[string]string `json:"outputs"` // pre_stop output by hook name
+}
+
+// logger returns the hooks component logger
+func logger() *slog.Logger {
+	return logging.Component("hooks")
+}
+
+// NewRunner creates a runner that keeps the context at contextPath and
+// gives each command up to timeout
+func NewRunner(hooks []Hook, contextPath string, timeout time.Duration) *Runner {
+	return &Runner{
+		hooks:       hooks,
+		contextPath: contextPath,
+		timeout:     timeout,
+	}
+}
+
+// SetEventHooks sets the commands run on lifecycle events
+func (r *Runner) SetEventHooks(events []EventHook) {
+	r.events = events
+}
+
+// Fire runs the commands listening for event, in order. A failed command
+// is logged and doesn't prevent the others from running.
+func (r *Runner) Fire(ctx context.Context, event string, details EventDetails) {
+	if r == nil {
+		return
+	}
+	var env []string
+	for _, hook := range r.events {
+		if !slices.Contains(hook.Events, event) {
+			continue
+		}
+		if env == nil {
+			env = []string{
+				"SNOOZE_HOOK=" + event,
+				"SNOOZE_REASON=" + details.Reason,
+				"SNOOZE_TRIGGER=" + details.Trigger,
+				"SNOOZE_INSTANCE_ID=" + details.InstanceID,
+				"SNOOZE_METRICS=" + encodeMetrics(details.Metrics),
+				"SNOOZE_ERROR=" + details.Error,
+			}
+		}
+		if _, err := r.run(ctx, Hook{Name: hook.Name}, hook.Command, env, nil); err != nil {
+			logger().Warn("Event hook failed", "hook", hook.Name, "event", event, "error", err)
+			continue
+		}
+		logger().Debug("Ran event hook", "hook", hook.Name, "event", event)
+	}
+}
+
+// Enabled reports whether there are any hooks
+func (r *Runner) Enabled() bool {
+	return r != nil && len(r.hooks) > 0
+}
+
+// Check runs the check commands in order, with the snooze's reason and the
+// metrics that led to it, until one refuses the snooze. A check that exits
+// with DeferExitCode defers it; any other failure, including a timeout,
+// vetoes it, so a broken check errs on the side of keeping the instance.
+func (r *Runner) Check(ctx context.Context, reason, trigger, instanceID string, metrics interface{}) Verdict {
+	if !r.Enabled() {
+		return Verdict{Decision: Allow}
+	}
+	env := []string{
+		"SNOOZE_HOOK=check",
+		"SNOOZE_REASON=" + reason,
+		"SNOOZE_TRIGGER=" + trigger,
+		"SNOOZE_INSTANCE_ID=" + instanceID,
+		"SNOOZE_METRICS=" + encodeMetrics(metrics),
+	}
+	for _, hook := range r.hooks {
+		if hook.Check == "" {
+			continue
+		}
+		output, err := r.run(ctx, hook, hook.Check, env, nil)
+		if err == nil {
+			continue
+		}
+
+		verdict := Verdict{Decision: Veto, Hook: hook.Name, Message: firstLine(output)}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == DeferExitCode {
+			verdict.Decision = Defer
+		}
+		if verdict.Message == "" {
+			verdict.Message = err.Error()
+		}
+		return verdict
+	}
+	return Verdict{Decision: Allow}
+}
+
+// PreStop runs the pre_stop commands and saves their output. A failed
+// command is logged and doesn't prevent the others from running or the
+// instance from stopping.
+func (r *Runner) PreStop(ctx context.Context, reason, trigger, instanceID string) error {
+	if !r.Enabled() {
+		return nil
+	}
+	saved := Context{
+		StoppedAt:  time.Now(),
+		Reason:     reason,
+		Trigger:    trigger,
+		InstanceID: instanceID,
+		Outputs:    make(map[string]string),
+	}
+	env := []string{
+		"SNOOZE_HOOK=pre_stop",
+		"SNOOZE_REASON=" + reason,
+		"SNOOZE_TRIGGER=" + trigger,
+		"SNOOZE_INSTANCE_ID=" + instanceID,
+	}
+	for _, hook := range r.hooks {
+		if hook.PreStop == "" {
+			continue
+		}
+		output, err := r.run(ctx, hook, hook.PreStop, env, nil)
+		if err != nil {
+			logger().Warn("pre_stop hook failed", "hook", hook.Name, "error", err)
+			continue
+		}
+		saved.Outputs[hook.Name] = output
+		logger().Info("Ran pre_stop hook", "hook", hook.Name, "saved_bytes", len(output))
+	}
+	return r.save(saved)
+}
+
+// PostStart runs the post_start commands if a stop saved a context, giving
+// each the output of its pre_stop command on standard input, then removes
+// the context so they only run once per stop
+func (r *Runner) PostStart(ctx context.Context) error {
+	if r == nil || r.contextPath == "" {
+		return nil
+	}
+	saved, err := r.load()
+	if err != nil || saved == nil {
+		return err
+	}
+
+	env := []string{
+		"SNOOZE_HOOK=post_start",
+		"SNOOZE_REASON=" + saved.Reason,
+		"SNOOZE_TRIGGER=" + saved.Trigger,
+		"SNOOZE_INSTANCE_ID=" + saved.InstanceID,
+		"SNOOZE_STOPPED_AT=" + saved.StoppedAt.Format(time.RFC3339),
+	}
+	for _, hook := range r.hooks {
+		if hook.PostStart == "" {
+			continue
+		}
+		output, ok := saved.Outputs[hook.Name]
+		if hook.PreStop != "" && !ok {
+			// Its pre_stop command failed, so there is nothing to restore
+			logger().Warn("Skipping post_start hook without saved state", "hook", hook.Name)
+			continue
+		}
+		if _, err := r.run(ctx, hook, hook.PostStart, env, strings.NewReader(output)); err != nil {
+			logger().Warn("post_start hook failed", "hook", hook.Name, "error", err)
+			continue
+		}
+		logger().Info("Ran post_start hook", "hook", hook.Name)
+	}
+
+	if err := os.Remove(r.contextPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove hook context: %v", err)
+	}
+	return nil
+}
+
+// run runs a command, returning its standard output, which is also
+// returned with an error when the command ran but failed
+func (r *Runner) run(ctx context.Context, hook Hook, command string, env []string, stdin *strings.Reader) (string, error) {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, command)
+	cmd.Env = append(os.Environ(), append(env, "SNOOZE_HOOK_NAME="+hook.Name)...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	stdout := &limitedBuffer{limit: maxOutput}
+	stderr := &limitedBuffer{limit: 4096}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// Don't wait for children of a killed command that hold its output open
+	cmd.WaitDelay = time.Second
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("%s timed out after %s", command, r.timeout)
+		}
+		return stdout.String(), fmt.Errorf("%s: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+	// Part of the state would restore the wrong thing
+	if stdout.overflowed {
+		return "", fmt.Errorf("%s wrote more than %d bytes", command, maxOutput)
+	}
+	return stdout.String(), nil
+}
+
+// save writes the context file atomically, readable only by its owner
+// since it may hold whatever the hooks saved
+func (r *Runner) save(saved Context) error {
+	if r.contextPath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.contextPath), 0755); err != nil {
+		return fmt.Errorf("failed to create hook context directory: %v", err)
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize hook context: %v", err)
+	}
+
+	tmpPath := r.contextPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write hook context: %v", err)
+	}
+	if err := os.Rename(tmpPath, r.contextPath); err != nil {
+		return fmt.Errorf("failed to replace hook context: %v", err)
+	}
+	return nil
+}
+
+// load reads the context file, returning nil if there is none
+func (r *Runner) load() (*Context, error) {
+	data, err := os.ReadFile(r.contextPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook context: %v", err)
+	}
+	var saved Context
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse hook context: %v", err)
+	}
+	return &saved, nil
+}
+
+// encodeMetrics returns metrics as JSON for the environment
+func encodeMetrics(metrics interface{}) string {
+	encoded, err := json.Marshal(metrics)
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
+}
+
+// firstLine returns the first non-empty line of output, shortened for logs
+func firstLine(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if len(line) > 200 {
+				line = line[:200]
+			}
+			return line
+		}
+	}
+	return ""
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest, so a noisy command can't exhaust memory
+type limitedBuffer struct {
+	bytes.Buffer
+	limit      int
+	overflowed bool
+}
+
+// Write implements io.Writer
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if room := b.limit - b.Len(); n > room {
+		b.overflowed = true
+		p = p[:max(room, 0)]
+	}
+	b.Buffer.Write(p)
+	return n, nil
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin
+
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeScript writes an executable shell script
+func writeScript(t *testing.T, dir, name, body string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestPreStopPostStart(t *testing.T) {
+	dir := t.TempDir()
+	restored := filepath.Join(dir, "restored")
+	hooks := []Hook{
+		{
+			Name:      "layout",
+			PreStop:   writeScript(t, dir, "save", `echo "layout for $SNOOZE_REASON"`),
+			PostStart: writeScript(t, dir, "restore", `cat > `+restored+`; echo "$SNOOZE_TRIGGER" >> `+restored),
+		},
+		{
+			Name:      "broken",
+			PreStop:   writeScript(t, dir, "fail", `echo oops >&2; exit 1`),
+			PostStart: writeScript(t, dir, "never", `touch `+filepath.Join(dir, "ran")),
+		},
+	}
+	contextPath := filepath.Join(dir, "state", "hooks.json")
+	runner := NewRunner(hooks, contextPath, 5*time.Second)
+
+	if err := runner.PreStop(context.Background(), "idle", "idle_timeout", "i-0abc"); err != nil {
+		t.Fatalf("PreStop failed: %v", err)
+	}
+	if info, err := os.Stat(contextPath); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("Expected a context file readable only by its owner, got %v, %v", info, err)
+	}
+
+	// A new runner, as after the instance starts again
+	runner = NewRunner(hooks, contextPath, 5*time.Second)
+	if err := runner.PostStart(context.Background()); err != nil {
+		t.Fatalf("PostStart failed: %v", err)
+	}
+	data, err := os.ReadFile(restored)
+	if err != nil || string(data) != "layout for idle\nidle_timeout\n" {
+		t.Errorf("Expected the saved layout and trigger to be restored, got %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ran")); err == nil {
+		t.Error("Expected the post_start command of a failed pre_stop command to be skipped")
+	}
+	if _, err := os.Stat(contextPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the context to be removed after restoring, got %v", err)
+	}
+
+	// Nothing to restore without a stop
+	os.Remove(restored)
+	if err := runner.PostStart(context.Background()); err != nil {
+		t.Fatalf("PostStart failed: %v", err)
+	}
+	if _, err := os.Stat(restored); err == nil {
+		t.Error("Expected post_start commands to only run after a stop")
+	}
+}
+
+func TestHookTimeout(t *testing.T) {
+	dir := t.TempDir()
+	runner := NewRunner(nil, "", 50*time.Millisecond)
+	hook := Hook{Name: "slow", PreStop: writeScript(t, dir, "slow", "sleep 5")}
+
+	start := time.Now()
+	if _, err := runner.run(context.Background(), hook, hook.PreStop, nil, nil); err == nil {
+		t.Fatal("Expected a slow command to time out")
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the command to be killed at the timeout, took %s", elapsed)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	env := filepath.Join(dir, "env")
+	allow := Hook{Name: "allow", Check: writeScript(t, dir, "allow", `echo "$SNOOZE_REASON $SNOOZE_METRICS" > `+env)}
+	deferring := Hook{Name: "jobs", Check: writeScript(t, dir, "defer", `echo "backup running"; echo; exit 75`)}
+	veto := Hook{Name: "users", Check: writeScript(t, dir, "veto", `echo "alice is logged in" >&2; exit 1`)}
+	slow := Hook{Name: "slow", Check: writeScript(t, dir, "slow", `sleep 5`)}
+	metrics := map[string]float64{"cpu_percent": 1.5}
+
+	runner := NewRunner([]Hook{allow}, "", time.Second)
+	if verdict := runner.Check(context.Background(), "idle", "idle", "i-0abc", metrics); verdict.Decision != Allow {
+		t.Errorf("Expected the snooze to be allowed, got %+v", verdict)
+	}
+	if data, _ := os.ReadFile(env); string(data) != "idle {\"cpu_percent\":1.5}\n" {
+		t.Errorf("Expected the reason and metrics in the environment, got %q", data)
+	}
+
+	// The first check to refuse decides, and its output says why
+	runner = NewRunner([]Hook{allow, deferring, veto}, "", time.Second)
+	verdict := runner.Check(context.Background(), "idle", "idle", "i-0abc", metrics)
+	if verdict.Decision != Defer || verdict.Hook != "jobs" || verdict.Message != "backup running" {
+		t.Errorf("Expected jobs to defer the snooze, got %+v", verdict)
+	}
+
+	runner = NewRunner([]Hook{veto}, "", time.Second)
+	verdict = runner.Check(context.Background(), "idle", "idle", "i-0abc", metrics)
+	if verdict.Decision != Veto || !strings.Contains(verdict.Message, "alice is logged in") {
+		t.Errorf("Expected users to veto the snooze, got %+v", verdict)
+	}
+
+	// A check that doesn't answer in time vetoes the snooze
+	runner = NewRunner([]Hook{slow}, "", 100*time.Millisecond)
+	if verdict := runner.Check(context.Background(), "idle", "idle", "i-0abc", metrics); verdict.Decision != Veto {
+		t.Errorf("Expected a slow check to veto the snooze, got %+v", verdict)
+	}
+}
+
+func TestFire(t *testing.T) {
+	dir := t.TempDir()
+	log := filepath.Join(dir, "events")
+	runner := NewRunner(nil, "", time.Second)
+	runner.SetEventHooks([]EventHook{
+		{Name: "broken", Events: []string{EventSnoozeFailed}, Command: writeScript(t, dir, "broken", `exit 1`)},
+		{Name: "log", Events: []string{EventIdleStart, EventSnoozeFailed}, Command: writeScript(t, dir, "log", `echo "$SNOOZE_HOOK_NAME $SNOOZE_HOOK $SNOOZE_ERROR" >> `+log)},
+	})
+
+	runner.Fire(context.Background(), EventIdleStart, EventDetails{})
+	runner.Fire(context.Background(), EventIdleEnd, EventDetails{})
+	// A failed command doesn't stop the rest
+	runner.Fire(context.Background(), EventSnoozeFailed, EventDetails{Reason: "idle", Error: "access denied"})
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("Expected the event hook to run: %v", err)
+	}
+	if want := "log idle_start \nlog snooze_failed access denied\n"; string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+}
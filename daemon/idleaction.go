@@ -0,0 +1,211 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+// Idle actions, chosen with stop_action
+const (
+	actionStop      = "stop"
+	actionHibernate = "hibernate"
+	actionWarmPool  = "warm_pool"
+	actionSuspend   = "suspend"
+	actionScript    = "script"
+)
+
+// idleActions lists the valid stop_action settings
+var idleActions = []string{actionStop, actionHibernate, actionWarmPool, actionSuspend, actionScript}
+
+var (
+	// suspendTimeout is how long the system has to go to sleep after
+	// being asked to suspend
+	suspendTimeout = 2 * time.Minute
+	// resumeJump is how far the wall clock must get ahead of the
+	// monotonic clock, which stops while the system sleeps, to count as
+	// a resume
+	resumeJump = 5 * time.Second
+)
+
+// idleAction is what is done to an idle instance once it's been prepared
+type idleAction interface {
+	// Name is the action recorded in the history, e.g. "hibernate"
+	Name() string
+	// Run carries out the action
+	Run(ctx context.Context, event monitor.SnoozeEvent) error
+	// WaitRunning returns once the instance is running again after Run,
+	// as it is when a suspended system resumes, or reports false if it
+	// won't be
+	WaitRunning(ctx context.Context) (bool, error)
+}
+
+// newIdleAction returns the configured action, or nil if it needs a cloud
+// provider and there is none
+func newIdleAction(config Config, cloudProvider common.CloudProvider) idleAction {
+	switch config.StopAction {
+	case actionSuspend:
+		return &suspendAction{}
+	case actionScript:
+		return scriptAction{command: config.StopScript, timeout: time.Duration(config.StopScriptTimeoutSecs) * time.Second}
+	}
+	if cloudProvider == nil {
+		return nil
+	}
+	return cloudStopAction{provider: cloudProvider}
+}
+
+// localAction reports whether the action leaves the instance to the
+// operating system rather than the cloud provider
+func localAction(stopAction string) bool {
+	return stopAction == actionSuspend || stopAction == actionScript
+}
+
+// cloudStopAction stops the instance through the cloud provider, which
+// may hibernate it or return it to a warm pool instead
+type cloudStopAction struct {
+	provider common.CloudProvider
+}
+
+// Name returns the stop action the provider will use
+func (a cloudStopAction) Name() string {
+	if reporter, ok := a.provider.(common.StopActionReporter); ok {
+		return reporter.StopAction().Effective
+	}
+	return actionStop
+}
+
+// Run stops the instance, tracing the provider calls when it supports a
+// context
+func (a cloudStopAction) Run(ctx context.Context, event monitor.SnoozeEvent) error {
+	if stopper, ok := a.provider.(common.ContextStopper); ok {
+		return stopper.StopInstanceContext(ctx, event.Reason, event.Metrics)
+	}
+	return a.provider.StopInstance(event.Reason, event.Metrics)
+}
+
+// WaitRunning reports false, since the instance is shutting down
+func (cloudStopAction) WaitRunning(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+// suspendAction suspends the system to RAM, leaving the instance running
+// for the cloud provider
+type suspendAction struct {
+	requested time.Time
+}
+
+// Name returns "suspend"
+func (*suspendAction) Name() string {
+	return actionSuspend
+}
+
+// Run asks the system to suspend, which happens in the background
+func (a *suspendAction) Run(ctx context.Context, event monitor.SnoozeEvent) error {
+	var command []string
+	switch runtime.GOOS {
+	case "linux":
+		command = []string{"systemctl", "suspend"}
+	case "darwin":
+		command = []string{"pmset", "sleepnow"}
+	case "freebsd":
+		command = []string{"acpiconf", "-s", "3"}
+	case "openbsd":
+		command = []string{"zzz"}
+	default:
+		return fmt.Errorf("suspending is not supported on %s", runtime.GOOS)
+	}
+
+	a.requested = time.Now()
+	if output, err := exec.CommandContext(ctx, command[0], command[1:]...).CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("failed to suspend: %v: %s", err, message)
+		}
+		return fmt.Errorf("failed to suspend: %v", err)
+	}
+	return nil
+}
+
+// WaitRunning waits for the clocks to show the system slept and resumed
+func (a *suspendAction) WaitRunning(ctx context.Context) (bool, error) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return true, ctx.Err()
+		case <-ticker.C:
+		}
+		now := time.Now()
+		slept := now.Round(0).Sub(a.requested.Round(0)) - now.Sub(a.requested)
+		if slept > resumeJump {
+			logger().Info("System resumed", "suspended_for", slept.Round(time.Second).String())
+			return true, nil
+		}
+		if now.Sub(a.requested) > suspendTimeout {
+			return true, fmt.Errorf("system didn't suspend within %s", suspendTimeout)
+		}
+	}
+}
+
+// scriptAction runs a site-specific executable, which might power the
+// machine off or hand it to another system
+type scriptAction struct {
+	command string
+	timeout time.Duration
+}
+
+// Name returns "script"
+func (scriptAction) Name() string {
+	return actionScript
+}
+
+// Run runs the script with the snooze details in its environment
+func (a scriptAction) Run(ctx context.Context, event monitor.SnoozeEvent) error {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	if err := runSnoozeCommand(ctx, a.command, event); err != nil {
+		return fmt.Errorf("stop script failed: %v", err)
+	}
+	logger().Info("Ran stop script", "command", a.command)
+	return nil
+}
+
+// runSnoozeCommand runs command with the snooze details in its
+// environment, returning an error with its output if it fails
+func runSnoozeCommand(ctx context.Context, command string, event monitor.SnoozeEvent) error {
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, command)
+	cmd.Env = append(os.Environ(),
+		"SNOOZE_REASON="+event.Reason,
+		"SNOOZE_TRIGGER="+event.Trigger,
+		"SNOOZE_INSTANCE_ID="+event.InstanceID,
+	)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(output.String()); message != "" {
+			return fmt.Errorf("%v: %s", err, message)
+		}
+		return err
+	}
+	return nil
+}
+
+// WaitRunning reports true: if the script has returned and the daemon is
+// still running, so is the instance
+func (scriptAction) WaitRunning(ctx context.Context) (bool, error) {
+	return true, nil
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+// fakeProvider records the stops it's asked for
+type fakeProvider struct {
+	stops      []string
+	stopErr    error
+	rollbacks  int
+	effective  string
+	contextual bool
+}
+
+func (f *fakeProvider) VerifyPermissions() (bool, error) {
+	return true, nil
+}
+
+func (f *fakeProvider) GetInstanceInfo() (*common.InstanceInfo, error) {
+	return &common.InstanceInfo{}, nil
+}
+
+func (f *fakeProvider) StartInstance(instanceID string) error {
+	return nil
+}
+
+func (f *fakeProvider) TagInstance(tags map[string]string) error {
+	return nil
+}
+
+func (f *fakeProvider) GetExternalTags() (map[string]string, error) {
+	return nil, nil
+}
+
+func (f *fakeProvider) StopInstance(reason string, metrics common.SystemMetrics) error {
+	f.stops = append(f.stops, reason)
+	return f.stopErr
+}
+
+func (f *fakeProvider) StopInstanceContext(ctx context.Context, reason string, metrics common.SystemMetrics) error {
+	f.contextual = true
+	return f.StopInstance(reason, metrics)
+}
+
+func (f *fakeProvider) StopAction() common.StopActionStatus {
+	return common.StopActionStatus{Requested: f.effective, Effective: f.effective}
+}
+
+func (f *fakeProvider) RollbackStop(ctx context.Context) error {
+	f.rollbacks++
+	return nil
+}
+
+// writeScript writes an executable shell script
+func writeScript(t *testing.T, dir, name, body string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestNewIdleAction(t *testing.T) {
+	provider := &fakeProvider{effective: actionHibernate}
+	tests := []struct {
+		stopAction string
+		provider   common.CloudProvider
+		want       string
+		local      bool
+	}{
+		{actionStop, provider, actionHibernate, false},
+		{actionHibernate, provider, actionHibernate, false},
+		{actionSuspend, nil, actionSuspend, true},
+		{actionScript, nil, actionScript, true},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		config.StopAction = tt.stopAction
+		action := newIdleAction(config, tt.provider)
+		if action == nil || action.Name() != tt.want {
+			t.Errorf("%s: expected the %s action, got %v", tt.stopAction, tt.want, action)
+		}
+		if localAction(tt.stopAction) != tt.local {
+			t.Errorf("%s: expected local=%v", tt.stopAction, tt.local)
+		}
+	}
+
+	if action := newIdleAction(DefaultConfig(), nil); action != nil {
+		t.Errorf("Expected no stop action without a cloud provider, got %v", action)
+	}
+}
+
+func TestCloudStopAction(t *testing.T) {
+	provider := &fakeProvider{effective: actionStop}
+	action := cloudStopAction{provider: provider}
+	if action.Name() != actionStop {
+		t.Errorf("Expected the provider's stop action, got %q", action.Name())
+	}
+
+	if err := action.Run(context.Background(), monitor.SnoozeEvent{Reason: "idle"}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(provider.stops) != 1 || provider.stops[0] != "idle" || !provider.contextual {
+		t.Errorf("Expected one traced stop, got %v", provider.stops)
+	}
+	if running, err := action.WaitRunning(context.Background()); running || err != nil {
+		t.Errorf("Expected a stopped instance not to be running again, got %v, %v", running, err)
+	}
+
+	provider.stopErr = errors.New("UnauthorizedOperation")
+	if err := action.Run(context.Background(), monitor.SnoozeEvent{Reason: "idle"}); err == nil {
+		t.Error("Expected the provider's error to be returned")
+	}
+}
+
+func TestScriptAction(t *testing.T) {
+	dir := t.TempDir()
+	env := filepath.Join(dir, "env")
+	action := scriptAction{
+		command: writeScript(t, dir, "park", `echo "$SNOOZE_REASON $SNOOZE_TRIGGER $SNOOZE_INSTANCE_ID" > `+env),
+		timeout: 5 * time.Second,
+	}
+	event := monitor.SnoozeEvent{Reason: "idle", Trigger: "idle_timeout", InstanceID: "i-0abc"}
+	if err := action.Run(context.Background(), event); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if data, err := os.ReadFile(env); err != nil || string(data) != "idle idle_timeout i-0abc\n" {
+		t.Errorf("Expected the snooze details in the script's environment, got %q, %v", data, err)
+	}
+	if running, _ := action.WaitRunning(context.Background()); !running {
+		t.Error("Expected the instance to still be running after the script returns")
+	}
+
+	failing := scriptAction{command: writeScript(t, dir, "fail", `echo "no power controller" >&2; exit 1`), timeout: 5 * time.Second}
+	if err := failing.Run(context.Background(), event); err == nil || !strings.Contains(err.Error(), "no power controller") {
+		t.Errorf("Expected the script's output in its error, got %v", err)
+	}
+
+	slow := scriptAction{command: writeScript(t, dir, "slow", "exec sleep 5"), timeout: 100 * time.Millisecond}
+	if err := slow.Run(context.Background(), event); err == nil {
+		t.Error("Expected a script that outlasts its timeout to fail")
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/aggregator"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// Where the states of other instances, such as dependents and peers, are
+// read from
+const (
+	instanceSourceAggregator = "aggregator"
+	instanceSourceCloud      = "cloud"
+)
+
+// instanceStateReadTimeout bounds reading the states of other instances,
+// so a slow API can't hold up a check
+const instanceStateReadTimeout = 30 * time.Second
+
+// instanceSource returns where the states of other instances are read
+// from, defaulting to the aggregation server if there is one
+func instanceSource(config Config, source string) string {
+	if source != "" {
+		return source
+	}
+	if config.Aggregation.ServerURL != "" {
+		return instanceSourceAggregator
+	}
+	return instanceSourceCloud
+}
+
+// newInstanceStateReader returns a function reading the states of other
+// instances, given by ID or by the tags of running instances, from the
+// aggregation server or the cloud provider. This instance is skipped, so
+// instances can share a list.
+func newInstanceStateReader(cloudProvider common.CloudProvider, config Config, source string, instanceIDs []string, tags map[string]string) (func(ctx context.Context) ([]common.FleetMemberStatus, error), error) {
+	controller, tagged := cloudProvider.(common.FleetController)
+	if len(tags) > 0 && !tagged {
+		return nil, fmt.Errorf("the cloud provider cannot find instances by tag")
+	}
+	var fleet common.Fleet
+	var pusher *aggregator.Pusher
+	if instanceSource(config, source) == instanceSourceAggregator {
+		var err error
+		if pusher, err = newAggregationPusher(config.Aggregation); err != nil {
+			return nil, err
+		}
+	} else if f, ok := cloudProvider.(common.Fleet); ok {
+		fleet = f
+	} else {
+		return nil, fmt.Errorf("the cloud provider cannot read other instances")
+	}
+
+	return func(ctx context.Context) ([]common.FleetMemberStatus, error) {
+		self := currentInstanceID(cloudProvider)
+		ids := slices.DeleteFunc(slices.Clone(instanceIDs), func(id string) bool { return id == self })
+		var running []common.ControlledInstance
+		if len(tags) > 0 {
+			instances, err := controller.TaggedInstances(ctx, tags)
+			if err != nil {
+				return nil, err
+			}
+			for _, instance := range instances {
+				if instance.ID != self && !slices.Contains(ids, instance.ID) {
+					running = append(running, instance)
+				}
+			}
+		}
+
+		if pusher != nil {
+			for _, instance := range running {
+				ids = append(ids, instance.ID)
+			}
+			reported, err := pusher.States(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			return reportedStates(ids, reported), nil
+		}
+		states := cloudStates(ctx, fleet, ids)
+		for _, instance := range running {
+			states = append(states, common.FleetMemberStatus{
+				InstanceID: instance.ID,
+				Name:       instance.Name,
+				State:      common.FleetStateActive,
+				Reason:     "Instance is running",
+				LastSeen:   time.Now(),
+			})
+		}
+		return states, nil
+	}, nil
+}
+
+// reportedStates returns the states the aggregation server reported,
+// adding the instances it hasn't heard from
+func reportedStates(instanceIDs []string, reported []common.FleetMemberStatus) []common.FleetMemberStatus {
+	states := make([]common.FleetMemberStatus, 0, len(instanceIDs))
+	for _, id := range instanceIDs {
+		state := common.FleetMemberStatus{
+			InstanceID: id,
+			State:      common.FleetStateError,
+			Reason:     "Not reported to the aggregation server",
+		}
+		for _, member := range reported {
+			if member.InstanceID == id {
+				state = member
+				break
+			}
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// cloudStates returns the states of the instances from the cloud provider,
+// to which a running or starting instance is active
+func cloudStates(ctx context.Context, fleet common.Fleet, instanceIDs []string) []common.FleetMemberStatus {
+	states := make([]common.FleetMemberStatus, 0, len(instanceIDs))
+	for _, id := range instanceIDs {
+		state := common.FleetMemberStatus{InstanceID: id, LastSeen: time.Now()}
+		instance, err := fleet.Instance(ctx, id)
+		switch {
+		case err != nil:
+			state.State = common.FleetStateError
+			state.Reason = err.Error()
+		case instance.State == "running" || instance.State == "pending":
+			state.State = common.FleetStateActive
+			state.Reason = "Instance is " + instance.State
+		default:
+			state.State = common.FleetStateStopped
+			state.Reason = "Instance is " + instance.State
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// instanceStates holds the states of other instances last read, reading
+// them again once they are older than the interval
+type instanceStates struct {
+	read     func(ctx context.Context) ([]common.FleetMemberStatus, error)
+	interval time.Duration
+	states   []common.FleetMemberStatus
+	err      error
+	lastRead time.Time
+	reading  sync.Mutex // Held while checking and reading, so concurrent callers share one read
+	lock     sync.RWMutex
+}
+
+// Read returns the states, reading them again if the interval has passed
+func (s *instanceStates) Read(ctx context.Context, now time.Time, what string) ([]common.FleetMemberStatus, error) {
+	s.reading.Lock()
+	defer s.reading.Unlock()
+
+	s.lock.RLock()
+	stale := now.Sub(s.lastRead) >= s.interval
+	s.lock.RUnlock()
+	if stale {
+		ctx, cancel := context.WithTimeout(ctx, instanceStateReadTimeout)
+		states, err := s.read(ctx)
+		cancel()
+		if err != nil {
+			logger().Warn("Failed to read the states of "+what, "error", err)
+		}
+		s.lock.Lock()
+		s.states, s.err, s.lastRead = states, err, now
+		s.lock.Unlock()
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.states, s.err
+}
+
+// States returns the states last read
+func (s *instanceStates) States() []common.FleetMemberStatus {
+	if s == nil {
+		return nil
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.states
+}
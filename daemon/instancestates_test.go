@@ -0,0 +1,68 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+func TestInstanceStatesReadsShared(t *testing.T) {
+	var reads atomic.Int32
+	states := &instanceStates{
+		read: func(ctx context.Context) ([]common.FleetMemberStatus, error) {
+			reads.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			return []common.FleetMemberStatus{{InstanceID: "i-0peer", State: common.FleetStateActive}}, nil
+		},
+		interval: time.Minute,
+	}
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if read, err := states.Read(context.Background(), now, "peers"); err != nil || len(read) != 1 {
+				t.Errorf("Expected the peer's state, got %v (error %v)", read, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if reads.Load() != 1 {
+		t.Errorf("Expected concurrent callers to share one read, got %d", reads.Load())
+	}
+
+	states.Read(context.Background(), now.Add(30*time.Second), "peers")
+	if reads.Load() != 1 {
+		t.Errorf("Expected the states to be reused within the interval, got %d reads", reads.Load())
+	}
+	states.Read(context.Background(), now.Add(time.Minute), "peers")
+	if reads.Load() != 2 {
+		t.Errorf("Expected the states to be read again after the interval, got %d reads", reads.Load())
+	}
+	if len(states.States()) != 1 {
+		t.Errorf("Expected States to return the states last read, got %v", states.States())
+	}
+}
+
+func TestInstanceStatesReadError(t *testing.T) {
+	failure := errors.New("aggregation server unavailable")
+	states := &instanceStates{
+		read: func(ctx context.Context) ([]common.FleetMemberStatus, error) {
+			return nil, failure
+		},
+		interval: time.Minute,
+	}
+	if _, err := states.Read(context.Background(), time.Now(), "peers"); !errors.Is(err, failure) {
+		t.Errorf("Expected the read error, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// The key and mode of config verification are flags rather than settings,
+// so editing the config can't turn verification off
+var (
+	configKeyFile = flag.String("config-key", "", "File holding the HMAC key the config file is signed with (empty to not verify)")
+	lockedConfig  = flag.Bool("locked-config", false, "Refuse to start with a config file that isn't signed with -config-key")
+	signConfig    = flag.Bool("sign-config", false, "Sign the config file with -config-key and exit")
+)
+
+// signaturePath returns where the signature of a config file is kept
+func signaturePath(configPath string) string {
+	return configPath + ".sig"
+}
+
+// configKey returns the config signing key, or nil if configs aren't signed
+func configKey() ([]byte, error) {
+	if *configKeyFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(*configKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config key: %v", err)
+	}
+	key := []byte(strings.TrimSpace(string(data)))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("config key %s is empty", *configKeyFile)
+	}
+	return key, nil
+}
+
+// configSignature returns the HMAC-SHA256 of a config file's contents
+func configSignature(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// writeConfigSignature signs the config file at path, if configs are signed
+func writeConfigSignature(path string, data []byte) error {
+	key, err := configKey()
+	if err != nil || key == nil {
+		return err
+	}
+	return os.WriteFile(signaturePath(path), []byte(configSignature(key, data)+"\n"), 0644)
+}
+
+// verifyConfig checks the signature of a config file's contents. A missing
+// or wrong signature is an error with -locked-config, and a warning
+// otherwise.
+func verifyConfig(path string, data []byte) error {
+	problem, err := configProblem(path, data)
+	if err != nil || problem == "" {
+		return err
+	}
+
+	if *lockedConfig {
+		return fmt.Errorf("%s (run snoozed -sign-config to sign an intended change)", problem)
+	}
+	logger().Warn("Config file failed verification", "path", path, "problem", problem)
+	return nil
+}
+
+// configProblem returns why a config file's contents don't match its
+// signature, or "" if they do or configs aren't signed
+func configProblem(path string, data []byte) (string, error) {
+	key, err := configKey()
+	if err != nil || key == nil {
+		return "", err
+	}
+
+	signature, err := os.ReadFile(signaturePath(path))
+	switch {
+	case os.IsNotExist(err):
+		return "config file is not signed", nil
+	case err != nil:
+		return fmt.Sprintf("failed to read config signature: %v", err), nil
+	case !hmac.Equal([]byte(strings.TrimSpace(string(signature))), []byte(configSignature(key, data))):
+		return "config file does not match its signature", nil
+	}
+	return "", nil
+}
+
+// signConfigFile signs the config file at path for -sign-config
+func signConfigFile(path string) error {
+	if *configKeyFile == "" {
+		return fmt.Errorf("-sign-config needs -config-key")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return writeConfigSignature(path, data)
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// lockConfig sets -locked-config for the rest of a test
+func lockConfig(t *testing.T, locked bool) {
+	previous := *lockedConfig
+	*lockedConfig = locked
+	t.Cleanup(func() { *lockedConfig = previous })
+}
+
+// signWith sets -config-key to a file holding key for the rest of a test
+func signWith(t *testing.T, key string) {
+	path := filepath.Join(t.TempDir(), "config.key")
+	if err := os.WriteFile(path, []byte(key+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	previous := *configKeyFile
+	*configKeyFile = path
+	t.Cleanup(func() { *configKeyFile = previous })
+}
+
+func TestVerifyConfigSigned(t *testing.T) {
+	signWith(t, "s3cret")
+	lockConfig(t, true)
+	path := filepath.Join(t.TempDir(), "snooze.json")
+	data := []byte(`{"naptime_minutes": 30}`)
+	os.WriteFile(path, data, 0644)
+
+	if err := verifyConfig(path, data); err == nil {
+		t.Error("Expected an unsigned config to be refused")
+	}
+	if err := signConfigFile(path); err != nil {
+		t.Fatalf("signConfigFile returned error: %v", err)
+	}
+	if err := verifyConfig(path, data); err != nil {
+		t.Errorf("Expected the signed config to verify, got %v", err)
+	}
+}
+
+func TestVerifyConfigTampered(t *testing.T) {
+	signWith(t, "s3cret")
+	path := filepath.Join(t.TempDir(), "snooze.json")
+	data := []byte(`{"naptime_minutes": 30}`)
+	if err := writeConfigSignature(path, data); err != nil {
+		t.Fatalf("writeConfigSignature returned error: %v", err)
+	}
+	tampered := []byte(`{"naptime_minutes": 3000}`)
+
+	lockConfig(t, true)
+	if err := verifyConfig(path, tampered); err == nil {
+		t.Error("Expected a changed config to be refused when locked")
+	}
+
+	// Signed with another key
+	signWith(t, "other")
+	if err := verifyConfig(path, data); err == nil {
+		t.Error("Expected a signature from another key to be refused")
+	}
+
+	// Unlocked, a mismatch is only a warning
+	lockConfig(t, false)
+	if err := verifyConfig(path, tampered); err != nil {
+		t.Errorf("Expected a warning rather than an error when not locked, got %v", err)
+	}
+}
+
+func TestVerifyConfigWithoutKey(t *testing.T) {
+	lockConfig(t, true)
+	if err := verifyConfig(filepath.Join(t.TempDir(), "snooze.json"), nil); err != nil {
+		t.Errorf("Expected no verification without -config-key, got %v", err)
+	}
+	if err := signConfigFile(filepath.Join(t.TempDir(), "snooze.json")); err == nil {
+		t.Error("Expected -sign-config without -config-key to fail")
+	}
+
+	signWith(t, "")
+	if _, err := configKey(); err == nil {
+		t.Error("Expected an empty key to be refused")
+	}
+}
+
+func TestUpdateConfigFileSigned(t *testing.T) {
+	signWith(t, "s3cret")
+	lockConfig(t, true)
+	path := filepath.Join(t.TempDir(), "snooze.json")
+	os.WriteFile(path, []byte(`{"naptime_minutes": 30}`), 0644)
+	signConfigFile(path)
+
+	if err := updateConfigFile(path, "logging.log_level", "debug"); err != nil {
+		t.Fatalf("updateConfigFile returned error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if err := verifyConfig(path, data); err != nil {
+		t.Errorf("Expected the changed config to be signed, got %v", err)
+	}
+}
+
+func TestUpdateConfigFileTampered(t *testing.T) {
+	signWith(t, "s3cret")
+	path := filepath.Join(t.TempDir(), "snooze.json")
+	os.WriteFile(path, []byte(`{"naptime_minutes": 30}`), 0644)
+	signConfigFile(path)
+	tampered := []byte(`{"naptime_minutes": 3000}`)
+	os.WriteFile(path, tampered, 0644)
+	signature, _ := os.ReadFile(signaturePath(path))
+
+	lockConfig(t, true)
+	if err := updateConfigFile(path, "logging.log_level", "debug"); err == nil {
+		t.Error("Expected a tampered config to be refused when locked")
+	}
+	if data, _ := os.ReadFile(path); string(data) != string(tampered) {
+		t.Errorf("Expected the tampered config to be left alone, got %s", data)
+	}
+	if data, _ := os.ReadFile(signaturePath(path)); string(data) != string(signature) {
+		t.Error("Expected the tampered config not to be signed")
+	}
+
+	// Unlocked, the change is made but the file stays unverified
+	lockConfig(t, false)
+	if err := updateConfigFile(path, "logging.log_level", "debug"); err != nil {
+		t.Fatalf("updateConfigFile returned error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	lockConfig(t, true)
+	if err := verifyConfig(path, data); err == nil {
+		t.Error("Expected the tampered config not to be signed")
+	}
+}
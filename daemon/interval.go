@@ -0,0 +1,66 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"time"
+)
+
+// adaptiveInterval picks the time until the next check. When enabled it
+// checks often while the system is close to being snoozed and backs off
+// while it stays busy, so the daemon costs little on busy hosts.
+type adaptiveInterval struct {
+	enabled  bool
+	base     time.Duration
+	shortest time.Duration
+	longest  time.Duration
+	current  time.Duration
+}
+
+// newAdaptiveInterval creates the interval, which stays at base if adaptive
+// checking is disabled
+func newAdaptiveInterval(base time.Duration, config AdaptiveIntervalConfig) *adaptiveInterval {
+	a := &adaptiveInterval{
+		enabled:  config.Enabled,
+		base:     base,
+		shortest: time.Duration(config.MinSeconds) * time.Second,
+		longest:  time.Duration(config.MaxSeconds) * time.Second,
+		current:  base,
+	}
+	if a.shortest <= 0 || a.shortest > base {
+		a.shortest = min(15*time.Second, base)
+	}
+	if a.longest < base {
+		a.longest = base
+	}
+	return a
+}
+
+// Current returns the interval until the next check
+func (a *adaptiveInterval) Current() time.Duration {
+	return a.current
+}
+
+// Next works out the interval after a check. The shortest interval is used
+// while a stop is pending or the naptime is about to pass, the base interval
+// while the system is idle, and while it is busy the interval doubles up to
+// the longest.
+func (a *adaptiveInterval) Next(now time.Time, idleSince *time.Time, naptime time.Duration, pending bool) time.Duration {
+	if !a.enabled {
+		return a.base
+	}
+
+	switch {
+	case pending:
+		a.current = a.shortest
+	case idleSince != nil:
+		a.current = a.base
+		if naptime-now.Sub(*idleSince) <= a.longest {
+			a.current = a.shortest
+		}
+	default:
+		a.current = min(max(a.current, a.base)*2, a.longest)
+	}
+	return a.current
+}
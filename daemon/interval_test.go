@@ -0,0 +1,80 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdaptiveIntervalDisabled(t *testing.T) {
+	interval := newAdaptiveInterval(time.Minute, AdaptiveIntervalConfig{MinSeconds: 10, MaxSeconds: 600})
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		if got := interval.Next(now, nil, 30*time.Minute, true); got != time.Minute {
+			t.Errorf("Expected the base interval while disabled, got %v", got)
+		}
+	}
+}
+
+func TestAdaptiveIntervalBacksOffWhileBusy(t *testing.T) {
+	interval := newAdaptiveInterval(time.Minute, AdaptiveIntervalConfig{Enabled: true, MinSeconds: 10, MaxSeconds: 300})
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute} {
+		if got := interval.Next(now, nil, 30*time.Minute, false); got != want {
+			t.Errorf("Expected %v while busy, got %v", want, got)
+		}
+	}
+	if interval.Current() != 5*time.Minute {
+		t.Errorf("Expected Current to return the last interval, got %v", interval.Current())
+	}
+}
+
+func TestAdaptiveIntervalNearSnooze(t *testing.T) {
+	interval := newAdaptiveInterval(time.Minute, AdaptiveIntervalConfig{Enabled: true, MinSeconds: 10, MaxSeconds: 300})
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+	naptime := 30 * time.Minute
+
+	interval.Next(now, nil, naptime, false)
+	justIdle := now.Add(-time.Minute)
+	if got := interval.Next(now, &justIdle, naptime, false); got != time.Minute {
+		t.Errorf("Expected the base interval early in the naptime, got %v", got)
+	}
+
+	// Within the longest interval of the naptime passing
+	nearlyDue := now.Add(-26 * time.Minute)
+	if got := interval.Next(now, &nearlyDue, naptime, false); got != 10*time.Second {
+		t.Errorf("Expected the shortest interval as the naptime ends, got %v", got)
+	}
+
+	interval.Next(now, nil, naptime, false)
+	if got := interval.Next(now, nil, naptime, true); got != 10*time.Second {
+		t.Errorf("Expected the shortest interval while a stop is pending, got %v", got)
+	}
+}
+
+func TestAdaptiveIntervalBounds(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		config   AdaptiveIntervalConfig
+		shortest time.Duration
+		longest  time.Duration
+	}{
+		{"configured", time.Minute, AdaptiveIntervalConfig{Enabled: true, MinSeconds: 20, MaxSeconds: 600}, 20 * time.Second, 10 * time.Minute},
+		{"unset", time.Minute, AdaptiveIntervalConfig{Enabled: true}, 15 * time.Second, time.Minute},
+		{"minimum above the base", 10 * time.Second, AdaptiveIntervalConfig{Enabled: true, MinSeconds: 30, MaxSeconds: 5}, 10 * time.Second, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		interval := newAdaptiveInterval(tt.base, tt.config)
+		if interval.Current() != tt.base {
+			t.Errorf("%s: expected to start at the base interval, got %v", tt.name, interval.Current())
+		}
+		if interval.shortest != tt.shortest || interval.longest != tt.longest {
+			t.Errorf("%s: expected bounds %v to %v, got %v to %v", tt.name, tt.shortest, tt.longest, interval.shortest, interval.longest)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/kubernetes"
+)
+
+// newNodeDrainer returns the drainer of the local Kubernetes node, or nil
+// if the node isn't drained before stopping
+func newNodeDrainer(config KubernetesConfig) *kubernetes.Drainer {
+	if !config.DrainNode {
+		return nil
+	}
+	node := config.NodeName
+	if node == "" {
+		// The kubelet registers the node under its hostname by default
+		hostname, err := os.Hostname()
+		if err != nil {
+			logger().Warn("Failed to get hostname for the node name", "error", err)
+		}
+		node = strings.ToLower(hostname)
+	}
+	return kubernetes.NewDrainer(kubernetes.Options{
+		Kubectl:            config.Kubectl,
+		Kubeconfig:         config.Kubeconfig,
+		Node:               node,
+		Timeout:            time.Duration(config.DrainTimeoutSecs) * time.Second,
+		DeleteEmptyDirData: config.DeleteEmptyDirData,
+		Force:              config.Force,
+		StatePath:          config.StatePath,
+	})
+}
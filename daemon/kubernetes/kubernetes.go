@@ -0,0 +1,203 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package kubernetes cordons and drains the local node with kubectl before
+// the instance stops, so its pods are evicted and rescheduled elsewhere
+// within their PodDisruptionBudgets, and uncordons it once the instance is
+// back.
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+)
+
+var (
+	// uncordonAttempts is how many times uncordoning is tried, since the
+	// API server may not be reachable as soon as the instance starts
+	uncordonAttempts = 5
+	// uncordonDelay is the wait between attempts
+	uncordonDelay = 10 * time.Second
+)
+
+// Options configures a Drainer
+type Options struct {
+	Kubectl            string        // kubectl binary
+	Kubeconfig         string        // kubeconfig file; empty uses kubectl's default
+	Node               string        // Node to drain
+	Timeout            time.Duration // Longest the drain may take
+	DeleteEmptyDirData bool          // Evict pods using emptyDir volumes, losing their data
+	Force              bool          // Delete pods not managed by a controller
+	StatePath          string        // Where the cordoned node is recorded; empty leaves it cordoned
+}
+
+// Drainer drains the node before a snooze and uncordons it after
+type Drainer struct {
+	options Options
+}
+
+// state records a node cordoned by Drain
+type state struct {
+	Node string `json:"node"`
+}
+
+// logger returns the kubernetes component logger
+func logger() *slog.Logger {
+	return logging.Component("kubernetes")
+}
+
+// NewDrainer creates a drainer for the node in options
+func NewDrainer(options Options) *Drainer {
+	if options.Kubectl == "" {
+		options.Kubectl = "kubectl"
+	}
+	return &Drainer{options: options}
+}
+
+// Drain cordons the node and evicts its pods, waiting for them to go.
+// Evictions that would break a PodDisruptionBudget are retried until the
+// timeout, when Drain gives up; the node stays cordoned, so the caller
+// should Uncordon it if the instance keeps running.
+func (d *Drainer) Drain(ctx context.Context) error {
+	// A node cordoned by someone else is left cordoned afterwards
+	cordoned, err := d.cordoned(ctx)
+	if err != nil {
+		return err
+	}
+	if !cordoned {
+		if err := d.save(); err != nil {
+			return err
+		}
+	}
+
+	args := []string{"drain", d.options.Node,
+		"--ignore-daemonsets",
+		"--timeout=" + d.options.Timeout.String(),
+	}
+	if d.options.DeleteEmptyDirData {
+		args = append(args, "--delete-emptydir-data")
+	}
+	if d.options.Force {
+		args = append(args, "--force")
+	}
+	ctx, cancel := context.WithTimeout(ctx, d.options.Timeout+time.Minute)
+	defer cancel()
+	if _, err := d.kubectl(ctx, args...); err != nil {
+		return fmt.Errorf("failed to drain node %s: %v", d.options.Node, err)
+	}
+	logger().Info("Drained node", "node", d.options.Node)
+	return nil
+}
+
+// Uncordon makes the node Drain cordoned schedulable again, then forgets
+// it so it's only uncordoned once
+func (d *Drainer) Uncordon(ctx context.Context) error {
+	if d == nil || d.options.StatePath == "" {
+		return nil
+	}
+	node, err := d.load()
+	if err != nil || node == "" {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		_, err = d.kubectl(ctx, "uncordon", node)
+		if err == nil || attempt == uncordonAttempts {
+			break
+		}
+		logger().Debug("Retrying uncordon", "node", node, "attempt", attempt, "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(uncordonDelay):
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to uncordon node %s: %v", node, err)
+	}
+	logger().Info("Uncordoned node", "node", node)
+
+	if err := os.Remove(d.options.StatePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove drained node: %v", err)
+	}
+	return nil
+}
+
+// cordoned reports whether the node is already unschedulable
+func (d *Drainer) cordoned(ctx context.Context) (bool, error) {
+	output, err := d.kubectl(ctx, "get", "node", d.options.Node, "-o", "jsonpath={.spec.unschedulable}")
+	if err != nil {
+		return false, fmt.Errorf("failed to get node %s: %v", d.options.Node, err)
+	}
+	return strings.TrimSpace(output) == "true", nil
+}
+
+// kubectl runs kubectl, returning its output or an error with its last
+// line of stderr
+func (d *Drainer) kubectl(ctx context.Context, args ...string) (string, error) {
+	if d.options.Kubeconfig != "" {
+		args = append([]string{"--kubeconfig", d.options.Kubeconfig}, args...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, d.options.Kubectl, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if message := lastLine(stderr.String()); message != "" {
+			return "", fmt.Errorf("%v: %s", err, message)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// load returns the node recorded as cordoned
+func (d *Drainer) load() (string, error) {
+	data, err := os.ReadFile(d.options.StatePath)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read drained node: %v", err)
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", fmt.Errorf("failed to parse drained node: %v", err)
+	}
+	return s.Node, nil
+}
+
+// save records the node as cordoned by the drain
+func (d *Drainer) save() error {
+	if d.options.StatePath == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(d.options.StatePath), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+	data, err := json.Marshal(state{Node: d.options.Node})
+	if err != nil {
+		return fmt.Errorf("failed to serialize drained node: %v", err)
+	}
+	if err := os.WriteFile(d.options.StatePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write drained node: %v", err)
+	}
+	return nil
+}
+
+// lastLine returns the last non-empty line of output, which is where
+// kubectl puts its error
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin
+
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeKubectl writes a kubectl that logs its arguments, reports the node
+// as cordoned or not and fails drains when told to, returning the drainer
+// options and the log path
+func fakeKubectl(t *testing.T, cordoned, drainFails bool) (Options, string) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "kubectl.log")
+	unschedulable := ""
+	if cordoned {
+		unschedulable = "true"
+	}
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %s
+case "$3" in
+get) printf '%s' ;;
+drain)
+	if %t; then
+		echo "evicting pod default/web-1" >&2
+		echo "error: Cannot evict pod as it would violate the pod's disruption budget." >&2
+		exit 1
+	fi ;;
+esac
+`, logPath, unschedulable, drainFails)
+	kubectl := filepath.Join(dir, "kubectl")
+	if err := os.WriteFile(kubectl, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return Options{
+		Kubectl:    kubectl,
+		Kubeconfig: "/etc/kubernetes/kubelet.conf",
+		Node:       "ip-10-0-0-1.ec2.internal",
+		Timeout:    2 * time.Minute,
+		Force:      true,
+		StatePath:  filepath.Join(dir, "state", "kubernetes.json"),
+	}, logPath
+}
+
+// calls returns the logged kubectl invocations
+func calls(t *testing.T, logPath string) []string {
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestDrainAndUncordon(t *testing.T) {
+	options, logPath := fakeKubectl(t, false, false)
+	drainer := NewDrainer(options)
+
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if err := drainer.Uncordon(context.Background()); err != nil {
+		t.Fatalf("Uncordon failed: %v", err)
+	}
+
+	want := []string{
+		"--kubeconfig /etc/kubernetes/kubelet.conf get node ip-10-0-0-1.ec2.internal -o jsonpath={.spec.unschedulable}",
+		"--kubeconfig /etc/kubernetes/kubelet.conf drain ip-10-0-0-1.ec2.internal --ignore-daemonsets --timeout=2m0s --force",
+		"--kubeconfig /etc/kubernetes/kubelet.conf uncordon ip-10-0-0-1.ec2.internal",
+	}
+	got := calls(t, logPath)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("kubectl calls = %q, want %q", got, want)
+	}
+	if _, err := os.Stat(options.StatePath); !os.IsNotExist(err) {
+		t.Errorf("Expected the state to be removed, got %v", err)
+	}
+
+	// Only the drained node is uncordoned, and only once
+	if err := drainer.Uncordon(context.Background()); err != nil {
+		t.Fatalf("Second Uncordon failed: %v", err)
+	}
+	if got := calls(t, logPath); len(got) != len(want) {
+		t.Errorf("Expected no more kubectl calls, got %q", got[len(want):])
+	}
+}
+
+func TestDrainLeavesCordonedNode(t *testing.T) {
+	options, logPath := fakeKubectl(t, true, false)
+	drainer := NewDrainer(options)
+
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if err := drainer.Uncordon(context.Background()); err != nil {
+		t.Fatalf("Uncordon failed: %v", err)
+	}
+	for _, call := range calls(t, logPath) {
+		if strings.Contains(call, "uncordon") {
+			t.Errorf("Expected a node cordoned beforehand to stay cordoned, got %q", call)
+		}
+	}
+}
+
+func TestDrainFailure(t *testing.T) {
+	options, _ := fakeKubectl(t, false, true)
+	drainer := NewDrainer(options)
+
+	err := drainer.Drain(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "disruption budget") {
+		t.Fatalf("Expected the eviction error, got %v", err)
+	}
+	// The drain cordoned the node, so it must be uncordoned
+	if _, err := os.Stat(options.StatePath); err != nil {
+		t.Errorf("Expected the node to be recorded, got %v", err)
+	}
+}
+
+func TestUncordonRetries(t *testing.T) {
+	uncordonAttempts, uncordonDelay = 2, time.Millisecond
+	defer func() { uncordonAttempts, uncordonDelay = 5, 10*time.Second }()
+
+	options, logPath := fakeKubectl(t, false, false)
+	drainer := NewDrainer(options)
+	if err := drainer.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+
+	// The API server isn't reachable yet
+	drainer.options.Kubectl = "/bin/false"
+	if err := drainer.Uncordon(context.Background()); err == nil {
+		t.Fatal("Expected Uncordon to fail")
+	}
+	if _, err := os.Stat(options.StatePath); err != nil {
+		t.Errorf("Expected the node to stay recorded, got %v", err)
+	}
+
+	drainer.options.Kubectl = options.Kubectl
+	if err := drainer.Uncordon(context.Background()); err != nil {
+		t.Fatalf("Uncordon failed: %v", err)
+	}
+	if got := calls(t, logPath); got[len(got)-1] != "--kubeconfig /etc/kubernetes/kubelet.conf uncordon ip-10-0-0-1.ec2.internal" {
+		t.Errorf("Expected the node to be uncordoned, got %q", got)
+	}
+}
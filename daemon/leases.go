@@ -0,0 +1,175 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Lease keeps the instance running until it expires, whatever its load
+type Lease struct {
+	ID        string    `json:"id"`
+	Owner     string    `json:"owner"` // Who took the lease, as identified by the socket
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// leaseManager holds the active leases, keeping them in a file so they
+// survive the daemon restarting
+type leaseManager struct {
+	path     string
+	maxHours float64
+	leases   []Lease
+	lock     sync.Mutex
+}
+
+// newLeaseManager creates a lease manager, loading any leases saved at path.
+// An empty path keeps leases in memory only.
+func newLeaseManager(config LeaseConfig) *leaseManager {
+	m := &leaseManager{path: config.StatePath, maxHours: config.MaxHours}
+	if m.path == "" {
+		return m
+	}
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger().Warn("Failed to read leases", "error", err)
+		}
+		return m
+	}
+	if err := json.Unmarshal(data, &m.leases); err != nil {
+		logger().Warn("Failed to parse leases", "error", err)
+	}
+	return m
+}
+
+// Take adds a lease keeping the instance running for hours
+func (m *leaseManager) Take(owner, reason string, hours float64) (Lease, error) {
+	if hours <= 0 {
+		return Lease{}, fmt.Errorf("hours must be greater than 0")
+	}
+	if m.maxHours > 0 && hours > m.maxHours {
+		return Lease{}, fmt.Errorf("leases may last at most %g hours", m.maxHours)
+	}
+	if reason == "" {
+		return Lease{}, fmt.Errorf("reason is required")
+	}
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return Lease{}, fmt.Errorf("failed to generate lease ID: %v", err)
+	}
+	now := time.Now()
+	lease := Lease{
+		ID:        hex.EncodeToString(id),
+		Owner:     owner,
+		Reason:    reason,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(hours * float64(time.Hour))),
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.expire(now)
+	m.leases = append(m.leases, lease)
+	return lease, m.save()
+}
+
+// Release ends a lease early. Only its owner or root may release it.
+func (m *leaseManager) Release(client, id string) (Lease, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, lease := range m.leases {
+		if lease.ID == id {
+			if client != lease.Owner && client != "root" {
+				return Lease{}, fmt.Errorf("lease %s belongs to %s", id, lease.Owner)
+			}
+			m.leases = append(m.leases[:i], m.leases[i+1:]...)
+			return lease, m.save()
+		}
+	}
+	return Lease{}, fmt.Errorf("no lease %s", id)
+}
+
+// ReleaseOwned ends every lease client took, such as those the aggregation
+// server took to pause the fleet, returning them
+func (m *leaseManager) ReleaseOwned(client string) ([]Lease, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var released, kept []Lease
+	for _, lease := range m.leases {
+		if lease.Owner == client {
+			released = append(released, lease)
+		} else {
+			kept = append(kept, lease)
+		}
+	}
+	if len(released) == 0 {
+		return nil, nil
+	}
+	m.leases = kept
+	return released, m.save()
+}
+
+// Active returns the leases that haven't expired, dropping the rest
+func (m *leaseManager) Active(now time.Time) []Lease {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.expire(now) {
+		if err := m.save(); err != nil {
+			logger().Warn("Failed to save leases", "error", err)
+		}
+	}
+	return append([]Lease{}, m.leases...)
+}
+
+// expire drops expired leases, reporting whether there were any
+func (m *leaseManager) expire(now time.Time) bool {
+	active := m.leases[:0]
+	for _, lease := range m.leases {
+		if now.Before(lease.ExpiresAt) {
+			active = append(active, lease)
+			continue
+		}
+		logger().Info("Lease expired", "id", lease.ID, "owner", lease.Owner, "reason", lease.Reason)
+	}
+	expired := len(active) != len(m.leases)
+	m.leases = active
+	return expired
+}
+
+// save writes the leases atomically
+func (m *leaseManager) save() error {
+	if m.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(m.path), 0755); err != nil {
+		return fmt.Errorf("failed to create lease directory: %v", err)
+	}
+	data, err := json.MarshalIndent(m.leases, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize leases: %v", err)
+	}
+
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write leases: %v", err)
+	}
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		return fmt.Errorf("failed to replace leases: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLeaseCaps(t *testing.T) {
+	leases := newLeaseManager(LeaseConfig{MaxHours: 8})
+
+	tests := []struct {
+		name   string
+		hours  float64
+		reason string
+		valid  bool
+	}{
+		{"within the cap", 2, "training run", true},
+		{"at the cap", 8, "training run", true},
+		{"over the cap", 8.5, "training run", false},
+		{"no time", 0, "training run", false},
+		{"negative time", -1, "training run", false},
+		{"no reason", 1, "", false},
+	}
+	for _, tt := range tests {
+		if _, err := leases.Take("alice", tt.reason, tt.hours); (err == nil) != tt.valid {
+			t.Errorf("%s: expected valid=%v, got error %v", tt.name, tt.valid, err)
+		}
+	}
+
+	uncapped := newLeaseManager(LeaseConfig{})
+	if _, err := uncapped.Take("alice", "long job", 72); err != nil {
+		t.Errorf("Expected no cap with max_hours 0, got %v", err)
+	}
+}
+
+func TestLeaseExpiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leases.json")
+	leases := newLeaseManager(LeaseConfig{StatePath: path})
+	short, err := leases.Take("alice", "quick fix", 1)
+	if err != nil {
+		t.Fatalf("Take returned error: %v", err)
+	}
+	long, err := leases.Take("bob", "training run", 4)
+	if err != nil {
+		t.Fatalf("Take returned error: %v", err)
+	}
+
+	if active := leases.Active(time.Now()); len(active) != 2 {
+		t.Fatalf("Expected 2 active leases, got %d", len(active))
+	}
+	later := short.ExpiresAt.Add(time.Minute)
+	active := leases.Active(later)
+	if len(active) != 1 || active[0].ID != long.ID {
+		t.Fatalf("Expected only the longer lease after the first expires, got %+v", active)
+	}
+
+	// The expired lease is gone from the saved leases too
+	if active := newLeaseManager(LeaseConfig{StatePath: path}).Active(later); len(active) != 1 || active[0].ID != long.ID {
+		t.Errorf("Expected the saved leases to survive a restart without the expired one, got %+v", active)
+	}
+	if active := leases.Active(long.ExpiresAt); len(active) != 0 {
+		t.Errorf("Expected no leases once the last expires, got %+v", active)
+	}
+}
+
+func TestLeaseRelease(t *testing.T) {
+	leases := newLeaseManager(LeaseConfig{})
+	lease, _ := leases.Take("alice", "debugging", 1)
+	leases.Take("aggregator", "fleet pause", 1)
+	leases.Take("aggregator", "fleet pause", 1)
+
+	if _, err := leases.Release("bob", lease.ID); err == nil {
+		t.Error("Expected another user's release to be refused")
+	}
+	if _, err := leases.Release("root", lease.ID); err != nil {
+		t.Errorf("Expected root to release any lease, got %v", err)
+	}
+	if _, err := leases.Release("alice", lease.ID); err == nil {
+		t.Error("Expected releasing a released lease to fail")
+	}
+
+	released, err := leases.ReleaseOwned("aggregator")
+	if err != nil || len(released) != 2 {
+		t.Errorf("Expected the aggregator's 2 leases to be released, got %d (error %v)", len(released), err)
+	}
+	if active := leases.Active(time.Now()); len(active) != 0 {
+		t.Errorf("Expected no leases left, got %+v", active)
+	}
+}
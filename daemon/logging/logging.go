@@ -0,0 +1,107 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package logging configures the daemon's structured logger. Packages log
+// through Component so every record carries the component that emitted it.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config defines how the daemon logs
+type Config struct {
+	Level  string    // "debug", "info", "warn", or "error"
+	Format string    // "text" or "json"
+	Output io.Writer // Defaults to stderr
+	Syslog bool      // Also send records to journald or the local syslog
+}
+
+// level is shared by all handlers so it can be changed at runtime
+var level = new(slog.LevelVar)
+
+// ParseLevel converts a configured level name to a slog level
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", name)
+	}
+}
+
+// Setup installs the configured logger as the default. Output from the
+// standard log package is routed through it as well. If syslog can't be
+// reached the logger is still installed without it and an error returned.
+func Setup(config Config) error {
+	parsed, err := ParseLevel(config.Level)
+	if err != nil {
+		return err
+	}
+	level.Set(parsed)
+
+	output := config.Output
+	if output == nil {
+		output = os.Stderr
+	}
+
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(config.Format) {
+	case "", FormatText:
+		handler = slog.NewTextHandler(output, options)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(output, options)
+	default:
+		return fmt.Errorf("invalid log format %q", config.Format)
+	}
+
+	var syslogErr error
+	if config.Syslog {
+		syslog, err := newSyslogHandler(DefaultSyslogTag, level)
+		if err != nil {
+			syslogErr = fmt.Errorf("syslog logging disabled: %v", err)
+		} else {
+			handler = multiHandler{handler, syslog}
+		}
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return syslogErr
+}
+
+// SetLevel changes the minimum level of the running logger
+func SetLevel(name string) error {
+	parsed, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(parsed)
+	return nil
+}
+
+// Level returns the name of the current minimum level
+func Level() string {
+	return strings.ToLower(level.Level().String())
+}
+
+// Component returns a logger that tags records with the component name
+func Component(name string) *slog.Logger {
+	return slog.Default().With("component", name)
+}
@@ -0,0 +1,65 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestSetupJSON(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	if err := Setup(Config{Level: "warn", Format: FormatJSON, Output: &buf}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	Component("monitor").Info("hidden")
+	Component("monitor").Warn("Failed to get GPU metrics", "error", "nvidia-smi not found")
+
+	var record map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON record, got %q: %v", buf.String(), err)
+	}
+	if record["component"] != "monitor" || record["level"] != "WARN" || record["error"] != "nvidia-smi not found" {
+		t.Errorf("Unexpected record %v", record)
+	}
+}
+
+func TestSetupRejectsInvalidConfig(t *testing.T) {
+	if err := Setup(Config{Level: "verbose"}); err == nil {
+		t.Error("Expected invalid level to be rejected")
+	}
+	if err := Setup(Config{Format: "xml"}); err == nil {
+		t.Error("Expected invalid format to be rejected")
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var buf bytes.Buffer
+	if err := Setup(Config{Level: "info", Output: &buf}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	Component("monitor").Debug("before")
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	Component("monitor").Debug("after")
+
+	if bytes.Contains(buf.Bytes(), []byte("before")) || !bytes.Contains(buf.Bytes(), []byte("after")) {
+		t.Errorf("Expected only records after the change to be logged, got %q", buf.String())
+	}
+	if Level() != "debug" {
+		t.Errorf("Expected level debug, got %s", Level())
+	}
+	if err := SetLevel("loud"); err == nil || Level() != "debug" {
+		t.Error("Expected invalid level to be rejected without changing the level")
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp added to rotated log file names
+const backupTimeFormat = "20060102-150405"
+
+// RotateConfig defines when a log file is rotated and how many old files are kept
+type RotateConfig struct {
+	MaxSizeMB  int           // Rotate when the file reaches this size, 0 to disable
+	MaxAge     time.Duration // Rotate when the file has been written for this long, 0 to disable
+	MaxBackups int           // Number of rotated files to keep, 0 to keep all
+	Compress   bool          // Gzip rotated files
+}
+
+// RotatingFile is a log file that rotates itself by size and age
+type RotatingFile struct {
+	path    string
+	config  RotateConfig
+	file    *os.File
+	size    int64
+	opened  time.Time
+	now     func() time.Time
+	pending sync.WaitGroup
+	cleanup sync.Mutex // Serializes compression and removal of backups
+	lock    sync.Mutex
+}
+
+// NewRotatingFile opens the log file at path for appending
+func NewRotatingFile(path string, config RotateConfig) (*RotatingFile, error) {
+	r := &RotatingFile{
+		path:   path,
+		config: config,
+		now:    time.Now,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %v", err)
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens or creates the current log file
+func (r *RotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.opened = r.now()
+	return nil
+}
+
+// Write appends to the log file, rotating it first if it is due
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.due(len(p)) {
+		if err := r.rotate(); err != nil {
+			// Keep logging to the current file rather than losing records
+			fmt.Fprintf(os.Stderr, "Failed to rotate log file: %v\n", err)
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// due returns true if writing n more bytes requires a rotation
+func (r *RotatingFile) due(n int) bool {
+	if r.size == 0 {
+		return false
+	}
+	if r.config.MaxSizeMB > 0 && r.size+int64(n) > int64(r.config.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	return r.config.MaxAge > 0 && r.now().Sub(r.opened) >= r.config.MaxAge
+}
+
+// rotate renames the current file aside and starts a new one
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	backup := r.path + "." + r.now().Format(backupTimeFormat)
+	for i := 1; fileExists(backup) || fileExists(backup+".gz"); i++ {
+		backup = fmt.Sprintf("%s.%s.%d", r.path, r.now().Format(backupTimeFormat), i)
+	}
+	renameErr := os.Rename(r.path, backup)
+
+	if err := r.open(); err != nil {
+		return err
+	}
+	if renameErr != nil {
+		return renameErr
+	}
+
+	// Compression and cleanup happen in the background so logging isn't blocked
+	r.pending.Add(1)
+	go func() {
+		defer r.pending.Done()
+		r.cleanup.Lock()
+		defer r.cleanup.Unlock()
+		if r.config.Compress {
+			if err := compressFile(backup); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to compress log file: %v\n", err)
+			}
+		}
+		r.removeOldBackups()
+	}()
+	return nil
+}
+
+// removeOldBackups deletes the oldest rotated files beyond MaxBackups
+func (r *RotatingFile) removeOldBackups() {
+	if r.config.MaxBackups <= 0 {
+		return
+	}
+
+	backups := r.Backups()
+	for len(backups) > r.config.MaxBackups {
+		os.Remove(backups[0])
+		backups = backups[1:]
+	}
+}
+
+// Backups returns the rotated log files, oldest first
+func (r *RotatingFile) Backups() []string {
+	matches, _ := filepath.Glob(r.path + ".*")
+
+	var backups []string
+	for _, match := range matches {
+		// Skip files still being compressed
+		if strings.HasSuffix(match, ".gz.tmp") {
+			continue
+		}
+		backups = append(backups, match)
+	}
+	// Timestamps sort chronologically; compare without the .gz suffix so a
+	// compressed file sorts the same as it did before compression
+	sort.Slice(backups, func(i, j int) bool {
+		return strings.TrimSuffix(backups[i], ".gz") < strings.TrimSuffix(backups[j], ".gz")
+	})
+	return backups
+}
+
+// Close waits for background compression and closes the file
+func (r *RotatingFile) Close() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.pending.Wait()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// compressFile gzips path to path.gz and removes the original
+func compressFile(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := path + ".gz.tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(out)
+	_, err = io.Copy(gz, in)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(path)
+}
+
+// fileExists returns true if path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
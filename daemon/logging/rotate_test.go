@@ -0,0 +1,99 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFileSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snooze.log")
+	r, err := NewRotatingFile(path, RotateConfig{MaxSizeMB: 1, MaxBackups: 2, Compress: true})
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+
+	// Each rotation needs a distinct timestamp
+	clock := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return clock }
+
+	line := []byte(strings.Repeat("x", 1023) + "\n")
+	for i := 0; i < 4*1024; i++ {
+		if _, err := r.Write(line); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		clock = clock.Add(time.Second)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	backups := r.Backups()
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups to be kept, got %v", backups)
+	}
+	for _, backup := range backups {
+		if !strings.HasSuffix(backup, ".gz") {
+			t.Errorf("Expected %s to be compressed", backup)
+			continue
+		}
+		file, _ := os.Open(backup)
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatalf("Invalid gzip file %s: %v", backup, err)
+		}
+		data, _ := io.ReadAll(gz)
+		file.Close()
+		if len(data) != 1024*1024 {
+			t.Errorf("Expected 1 MB in %s, got %d bytes", backup, len(data))
+		}
+	}
+
+	info, _ := os.Stat(path)
+	if info.Size() != 1024*1024 {
+		t.Errorf("Expected current file to hold 1 MB, got %d bytes", info.Size())
+	}
+}
+
+func TestRotatingFileAge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snooze.log")
+	r, err := NewRotatingFile(path, RotateConfig{MaxAge: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("NewRotatingFile failed: %v", err)
+	}
+	defer r.Close()
+
+	clock := time.Now()
+	r.now = func() time.Time { return clock }
+	r.opened = clock
+
+	r.Write([]byte("first\n"))
+	clock = clock.Add(23 * time.Hour)
+	r.Write([]byte("second\n"))
+	if len(r.Backups()) != 0 {
+		t.Fatal("Expected no rotation before the maximum age")
+	}
+
+	clock = clock.Add(time.Hour)
+	r.Write([]byte("third\n"))
+	backups := r.Backups()
+	if len(backups) != 1 {
+		t.Fatalf("Expected one rotation after the maximum age, got %v", backups)
+	}
+
+	data, _ := os.ReadFile(backups[0])
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("Unexpected rotated content %q", data)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != "third\n" {
+		t.Errorf("Unexpected current content %q", data)
+	}
+}
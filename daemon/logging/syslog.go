@@ -0,0 +1,267 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSyslogTag identifies the daemon's records in syslog and journald
+const DefaultSyslogTag = "snoozed"
+
+// Sockets tried in order; journald is preferred because it keeps fields
+var (
+	journalSocket = "/run/systemd/journal/socket"
+	syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+)
+
+// syslogDaemonFacility is the syslog facility used for all records
+const syslogDaemonFacility = 3
+
+// syslogHandler sends records to journald with each attribute as a field,
+// or to the local syslog daemon with attributes appended to the message
+type syslogHandler struct {
+	conn    net.Conn
+	journal bool
+	tag     string
+	level   slog.Leveler
+	attrs   []slog.Attr
+	prefix  string
+	lock    *sync.Mutex
+}
+
+// newSyslogHandler connects to journald or, failing that, syslog
+func newSyslogHandler(tag string, level slog.Leveler) (*syslogHandler, error) {
+	handler := &syslogHandler{tag: tag, level: level, lock: &sync.Mutex{}}
+
+	if conn, err := net.Dial("unixgram", journalSocket); err == nil {
+		handler.conn = conn
+		handler.journal = true
+		return handler, nil
+	}
+	for _, path := range syslogSockets {
+		if conn, err := net.Dial("unixgram", path); err == nil {
+			handler.conn = conn
+			return handler, nil
+		}
+	}
+	return nil, fmt.Errorf("no journald or syslog socket found")
+}
+
+// Enabled reports whether the level is logged
+func (h *syslogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// WithAttrs returns a handler that adds attrs to every record
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), h.qualify(attrs)...)
+	return &clone
+}
+
+// WithGroup returns a handler that prefixes later attribute names
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// qualify applies the current group prefix to attribute names
+func (h *syslogHandler) qualify(attrs []slog.Attr) []slog.Attr {
+	if h.prefix == "" {
+		return attrs
+	}
+	result := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		result[i] = slog.Attr{Key: h.prefix + attr.Key, Value: attr.Value}
+	}
+	return result
+}
+
+// Handle sends one record
+func (h *syslogHandler) Handle(_ context.Context, record slog.Record) error {
+	attrs := append([]slog.Attr{}, h.attrs...)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, h.qualify([]slog.Attr{attr})...)
+		return true
+	})
+
+	var message []byte
+	if h.journal {
+		message = h.journalMessage(record, attrs)
+	} else {
+		message = h.syslogMessage(record, attrs)
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	_, err := h.conn.Write(message)
+	return err
+}
+
+// journalMessage encodes a record in the journald native protocol
+func (h *syslogHandler) journalMessage(record slog.Record, attrs []slog.Attr) []byte {
+	var buf bytes.Buffer
+	writeJournalField(&buf, "MESSAGE", record.Message)
+	writeJournalField(&buf, "PRIORITY", fmt.Sprint(syslogSeverity(record.Level)))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", h.tag)
+	for _, attr := range flattenAttrs("", attrs) {
+		writeJournalField(&buf, journalFieldName(attr.Key), attrString(attr.Value))
+	}
+	return buf.Bytes()
+}
+
+// writeJournalField appends a field, using the binary form for values that
+// contain newlines
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	if name == "" {
+		return
+	}
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName converts an attribute key to a valid journald field
+// name: uppercase letters, digits and underscores, not starting with an
+// underscore (those are reserved for trusted fields)
+func journalFieldName(key string) string {
+	var name strings.Builder
+	for _, c := range strings.ToUpper(key) {
+		if (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			name.WriteRune(c)
+		} else {
+			name.WriteByte('_')
+		}
+	}
+	return strings.TrimLeft(name.String(), "_")
+}
+
+// syslogMessage formats a record as an RFC 3164 message
+func (h *syslogHandler) syslogMessage(record slog.Record, attrs []slog.Attr) []byte {
+	timestamp := record.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>%s %s[%d]: %s", syslogDaemonFacility*8+syslogSeverity(record.Level),
+		timestamp.Format(time.Stamp), h.tag, os.Getpid(), record.Message)
+	for _, attr := range flattenAttrs("", attrs) {
+		fmt.Fprintf(&buf, " %s=%q", attr.Key, attrString(attr.Value))
+	}
+	return buf.Bytes()
+}
+
+// syslogSeverity maps slog levels to syslog severities
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3 // err
+	case level >= slog.LevelWarn:
+		return 4 // warning
+	case level >= slog.LevelInfo:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
+
+// flattenAttrs expands groups into dotted attribute names
+func flattenAttrs(prefix string, attrs []slog.Attr) []slog.Attr {
+	var result []slog.Attr
+	for _, attr := range attrs {
+		value := attr.Value.Resolve()
+		if value.Kind() == slog.KindGroup {
+			result = append(result, flattenAttrs(prefix+attr.Key+".", value.Group())...)
+			continue
+		}
+		if attr.Key == "" {
+			continue
+		}
+		result = append(result, slog.Attr{Key: prefix + attr.Key, Value: value})
+	}
+	return result
+}
+
+// attrString formats an attribute value, encoding structs as JSON
+func attrString(value slog.Value) string {
+	if value.Kind() != slog.KindAny {
+		return value.String()
+	}
+	switch v := value.Any().(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if data, err := json.Marshal(value.Any()); err == nil {
+		return string(data)
+	}
+	return value.String()
+}
+
+// multiHandler sends records to several handlers
+type multiHandler []slog.Handler
+
+// Enabled reports whether any handler logs the level
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to every handler that logs its level
+func (m multiHandler) Handle(ctx context.Context, record slog.Record) error {
+	var firstErr error
+	for _, h := range m {
+		if !h.Enabled(ctx, record.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, record.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// WithAttrs applies attrs to every handler
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	result := make(multiHandler, len(m))
+	for i, h := range m {
+		result[i] = h.WithAttrs(attrs)
+	}
+	return result
+}
+
+// WithGroup applies the group to every handler
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	result := make(multiHandler, len(m))
+	for i, h := range m {
+		result[i] = h.WithGroup(name)
+	}
+	return result
+}
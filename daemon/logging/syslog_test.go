@@ -0,0 +1,93 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package logging
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenUnixgram creates a datagram socket standing in for journald or syslog
+func listenUnixgram(t *testing.T) (string, *net.UnixConn) {
+	// Socket paths are limited in length, so avoid the long test temp dir
+	dir, err := os.MkdirTemp("", "log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return path, conn
+}
+
+func readDatagram(t *testing.T, conn *net.UnixConn) string {
+	buf := make([]byte, 65536)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("No message received: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestJournalHandler(t *testing.T) {
+	path, conn := listenUnixgram(t)
+	original := journalSocket
+	journalSocket = path
+	defer func() { journalSocket = original }()
+
+	handler, err := newSyslogHandler(DefaultSyslogTag, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("newSyslogHandler failed: %v", err)
+	}
+	logger := slog.New(handler).With("component", "daemon")
+
+	logger.Debug("hidden")
+	logger.Warn("Snooze event", "reason", "System idle", "instance-id", "i-123", "detail", "line one\nline two")
+
+	message := readDatagram(t, conn)
+	for _, field := range []string{"MESSAGE=Snooze event\n", "PRIORITY=4\n", "SYSLOG_IDENTIFIER=snoozed\n",
+		"COMPONENT=daemon\n", "REASON=System idle\n", "INSTANCE_ID=i-123\n"} {
+		if !strings.Contains(message, field) {
+			t.Errorf("Expected %q in journal message %q", field, message)
+		}
+	}
+
+	// Multi-line values use the length-prefixed binary form
+	if !strings.Contains(message, "DETAIL\n\x11\x00\x00\x00\x00\x00\x00\x00line one\nline two\n") {
+		t.Errorf("Expected binary DETAIL field in %q", message)
+	}
+}
+
+func TestSyslogHandlerFallback(t *testing.T) {
+	path, conn := listenUnixgram(t)
+	originalJournal, originalSyslog := journalSocket, syslogSockets
+	journalSocket = filepath.Join(filepath.Dir(path), "missing")
+	syslogSockets = []string{path}
+	defer func() { journalSocket, syslogSockets = originalJournal, originalSyslog }()
+
+	handler, err := newSyslogHandler(DefaultSyslogTag, slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("newSyslogHandler failed: %v", err)
+	}
+	slog.New(handler).Error("Failed to stop instance", "error", "access denied")
+
+	message := readDatagram(t, conn)
+	if !strings.HasPrefix(message, "<27>") {
+		t.Errorf("Expected daemon.err priority, got %q", message)
+	}
+	if !strings.Contains(message, "snoozed[") || !strings.HasSuffix(message, `: Failed to stop instance error="access denied"`) {
+		t.Errorf("Unexpected syslog message %q", message)
+	}
+}
@@ -4,13 +4,15 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/scttfrdmn/cloudsnooze/daemon/accelerator"
@@ -18,43 +20,95 @@ import (
 	"github.com/scttfrdmn/cloudsnooze/daemon/cloud"
 	"github.com/scttfrdmn/cloudsnooze/daemon/cloud/aws"
 	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/health"
+	"github.com/scttfrdmn/cloudsnooze/daemon/history"
+	"github.com/scttfrdmn/cloudsnooze/daemon/hooks"
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
 	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+	"github.com/scttfrdmn/cloudsnooze/daemon/notify"
 	"github.com/scttfrdmn/cloudsnooze/daemon/plugin"
+	"github.com/scttfrdmn/cloudsnooze/daemon/schedule"
+	"github.com/scttfrdmn/cloudsnooze/daemon/telemetry"
 	cloudplugin "github.com/scttfrdmn/cloudsnooze/daemon/plugin/cloud"
+	notifierplugin "github.com/scttfrdmn/cloudsnooze/daemon/plugin/notifier"
+	snoozeerrors "github.com/scttfrdmn/cloudsnooze/pkg/errors"
+	"github.com/shirou/gopsutil/v3/host"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	
 	// Import all provider plugins to ensure they register themselves
 	_ "github.com/scttfrdmn/cloudsnooze/daemon/plugin/cloud/aws"
 )
 
 var (
-	configFile  = flag.String("config", "/etc/snooze/snooze.json", "Path to configuration file")
-	socketPath  = flag.String("socket", api.DefaultSocketPath, "Path to Unix socket")
+	configFile  = flag.String("config", filepath.Join(configDir, "snooze.json"), "Path to configuration file")
+	socketPath  = flag.String("socket", api.DefaultSocketPath, `Path to Unix socket, @name for an abstract socket, or \\.\pipe\name for a named pipe`)
 	showVersion = flag.Bool("version", false, "Show version and exit")
 )
 
 const version = "0.1.0"
 
+// shutdownTimeout bounds how long shutdown waits for subsystems to finish
+const shutdownTimeout = 20 * time.Second
+
+// Restarting the socket server after it fails
+const (
+	maxServerRestarts   = 3
+	serverRestartWindow = time.Minute
+)
+
+// serverRestartDelay is how long to wait before listening again
+var serverRestartDelay = 2 * time.Second
+
+// remoteAPIHealthComponent reports the TCP API in HEALTH
+const remoteAPIHealthComponent = "api:remote"
+
+// logger returns the daemon component logger
+func logger() *slog.Logger {
+	return logging.Component("daemon")
+}
+
 // initializePlugins initializes and logs information about loaded plugins
 func initializePlugins(config *Config) {
 	// Built-in plugins are self-registered via their init() functions
 	
+	// Disabled plugins stay registered but are skipped by lookups
+	if config != nil {
+		for _, id := range config.DisabledPlugins {
+			plugin.Registry.SetEnabled(id, false)
+		}
+	}
+	
 	// Load external plugins if enabled
 	if config != nil && config.PluginsEnabled && config.PluginsDir != "" {
-		log.Printf("Loading external plugins from %s...", config.PluginsDir)
+		logger().Info("Loading external plugins", "dir", config.PluginsDir)
 		if err := plugin.LoadExternalPlugins(config.PluginsDir); err != nil {
-			log.Printf("Warning: Failed to load external plugins: %v", err)
+			logger().Warn("Failed to load external plugins", "error", err)
 		}
+		applyPluginLimits(config.PluginLimits)
 	}
 	
 	// List all available cloud provider plugins
 	providers := cloudplugin.Registry.GetAllProviders()
 	if len(providers) == 0 {
-		log.Printf("Warning: No cloud provider plugins loaded")
+		logger().Warn("No cloud provider plugins loaded")
 	} else {
-		log.Printf("Loaded %d cloud provider plugins:", len(providers))
+		logger().Info("Loaded cloud provider plugins", "count", len(providers))
 		for _, p := range providers {
 			info := p.Info()
-			log.Printf("  - %s (%s) v%s", info.Name, info.ID, info.Version)
+			logger().Info("Cloud provider plugin", "plugin", info.ID, "name", info.Name, "version", info.Version)
+		}
+	}
+}
+
+// applyPluginLimits sets resource limits on all out-of-process plugins
+func applyPluginLimits(limits PluginLimitsConfig) {
+	for _, p := range plugin.Registry.GetAll() {
+		if limited, ok := p.(interface{ SetLimits(plugin.ResourceLimits) }); ok {
+			limited.SetLimits(plugin.ResourceLimits{
+				CPUPercent: limits.CPUPercent,
+				MemoryMB:   limits.MemoryMB,
+			})
 		}
 	}
 }
@@ -62,20 +116,141 @@ func initializePlugins(config *Config) {
 func main() {
 	flag.Parse()
 
+	// On Windows, manage the service, or run as one
+	if handleService(runDaemon) {
+		return
+	}
+	runDaemon()
+}
+
+// runDaemon runs the daemon, or the mode its flags choose, until it is
+// shut down
+func runDaemon() {
+
 	if *showVersion {
 		fmt.Printf("CloudSnooze daemon v%s\n", version)
 		return
 	}
+	if *signConfig {
+		if err := signConfigFile(*configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to sign config: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Signed %s\n", *configFile)
+		return
+	}
+	if *setSecret != "" || *deleteSecret != "" || *listSecrets {
+		if err := manageSecrets(*configFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to manage secrets: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	
 	// Load configuration
 	config, err := loadConfig(*configFile)
 	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+		logger().Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+	if *reportTokenFor != "" {
+		if err := printReportToken(config.Aggregator, *reportTokenFor); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to derive report token: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	
+	// Configure logging before anything else logs, writing to the log file
+	// as well as stderr when file logging is enabled
+	logOutput := io.Writer(os.Stderr)
+	var logFile *logging.RotatingFile
+	if config.Logging.EnableFileLogging && config.Logging.LogFilePath != "" {
+		logFile, err = logging.NewRotatingFile(config.Logging.LogFilePath, logging.RotateConfig{
+			MaxSizeMB:  config.Logging.MaxSizeMB,
+			MaxAge:     time.Duration(config.Logging.MaxAgeHours) * time.Hour,
+			MaxBackups: config.Logging.MaxBackups,
+			Compress:   config.Logging.Compress,
+		})
+		if err != nil {
+			logger().Warn("File logging disabled", "error", err)
+		} else {
+			logOutput = io.MultiWriter(os.Stderr, logFile)
+		}
+	}
+	if err := logging.Setup(logging.Config{
+		Level:  config.Logging.LogLevel,
+		Format: config.Logging.LogFormat,
+		Output: logOutput,
+		Syslog: config.Logging.EnableSyslog,
+	}); err != nil {
+		logger().Warn("Logging is not fully configured", "error", err)
+	}
+
+	// The restarter watches other instances rather than this one
+	if *restarterMode {
+		code := runRestarter(config)
+		if logFile != nil {
+			logFile.Close()
+		}
+		if code != 0 {
+			os.Exit(code)
+		}
+		return
+	}
+	
+	// So does the controller, judging them from their metrics
+	if *controllerMode {
+		code := runController(config)
+		if logFile != nil {
+			logFile.Close()
+		}
+		if code != 0 {
+			os.Exit(code)
+		}
+		return
+	}
+	
+	// The aggregator collects what the daemons of other instances report
+	if *aggregatorMode {
+		code := runAggregator(config)
+		if logFile != nil {
+			logFile.Close()
+		}
+		if code != 0 {
+			os.Exit(code)
+		}
+		return
+	}
+	
+	// The organization's policy bounds the local settings
+	activePolicy, err = loadOrgPolicy(context.Background())
+	if err != nil {
+		logger().Error("Organization policy not applied", "error", err)
+	} else if activePolicy != nil {
+		activePolicy.Adjusted = applyOrgPolicy(&config, activePolicy.Policy)
+		for _, change := range activePolicy.Adjusted {
+			logger().Warn("Setting overridden by organization policy", "change", change)
+		}
+		logger().Info("Organization policy applied", "url", activePolicy.URL, "source", activePolicy.Source)
 	}
 	
 	// Initialize plugins with loaded config
 	initializePlugins(&config)
 
+	// In a container, watch the host rather than the container
+	if config.Container.Enabled {
+		if err := monitor.WatchHost(monitor.HostPaths{
+			Proc: config.Container.HostProc,
+			Sys:  config.Container.HostSys,
+			Dev:  config.Container.HostDev,
+		}); err != nil {
+			logger().Error("Failed to watch the host from the container", "error", err)
+			os.Exit(1)
+		}
+		logger().Info("Watching the host from a container", "proc", config.Container.HostProc, "sys", config.Container.HostSys)
+	}
+
 	// Set up system monitor
 	systemMonitor := monitor.NewSystemMonitor(
 		config.CPUThresholdPercent,
@@ -89,18 +264,34 @@ func main() {
 		config.GPUMonitoringEnabled,
 	)
 	
+	// Keep recent decisions so users can see why the system did or didn't snooze
+	decisions := monitor.NewDecisionLog(config.DecisionLogSize)
+	systemMonitor.SetDecisionLog(decisions)
+
+	// Carry the idle timer over daemon restarts
+	systemMonitor.SetStatePath(config.StatePath)
+
+	// Never let a metric that fails to collect count as idle
+	systemMonitor.SetFailurePolicy(monitor.FailurePolicy(config.CollectionFailurePolicy),
+		time.Duration(config.CollectionStaleSecs)*time.Second)
+	systemMonitor.SetCollectTimeout(time.Duration(config.CollectionTimeoutSecs) * time.Second)
+	
 	// Initialize GPU service and inject it into the system monitor
 	if config.GPUMonitoringEnabled {
 		// Use the factory function to create a GPU service
 		gpuService := accelerator.CreateGPUService()
 		// Initialize the service
 		if err := gpuService.Initialize(); err != nil {
-			log.Printf("Warning: Failed to initialize GPU service: %v", err)
+			logger().Warn("Failed to initialize GPU service", "error", err)
 		}
 		// Inject the service into the system monitor
 		systemMonitor.SetGPUService(gpuService)
 	}
 	
+	// Cancelled on shutdown to abort cloud calls and stop background work
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
 	// Set up cloud provider
 	var cloudProvider common.CloudProvider
 	var providerType cloud.ProviderType
@@ -108,18 +299,18 @@ func main() {
 	// Determine provider type from config or auto-detect
 	if config.ProviderType == "" {
 		// Auto-detect provider
-		log.Printf("No provider type specified, attempting auto-detection...")
+		logger().Info("No provider type specified, attempting auto-detection")
 		detectedType, detectErr := cloud.DetectProvider()
 		if detectErr != nil {
-			log.Printf("Warning: Failed to auto-detect cloud provider: %v", detectErr)
+			logger().Warn("Failed to auto-detect cloud provider", "error", detectErr)
 		} else {
 			providerType = detectedType
-			log.Printf("Detected cloud provider: %s", providerType)
+			logger().Info("Detected cloud provider", "provider", providerType)
 		}
 	} else {
 		// Use configured provider
 		providerType = cloud.ProviderType(config.ProviderType)
-		log.Printf("Using configured cloud provider: %s", providerType)
+		logger().Info("Using configured cloud provider", "provider", providerType)
 	}
 	
 	// Create provider instance based on type
@@ -127,85 +318,492 @@ func main() {
 		switch providerType {
 		case cloud.AWS:
 			// Set up AWS cloud provider
-			awsConfig := aws.Config{
-				Region:             config.AWSRegion,
-				EnableTags:         config.EnableInstanceTags,
-				TaggingPrefix:      config.TaggingPrefix,
-				DetailedTags:       config.DetailedInstanceTags,
-				TagPollingEnabled:  config.TagPollingEnabled,
-				TagPollingInterval: config.TagPollingIntervalSecs,
-				EnableCloudWatch:   config.Logging.EnableCloudWatch,
-				CloudWatchLogGroup: config.Logging.CloudWatchLogGroup,
-			}
+			awsConfig := awsProviderConfig(config)
 			cloudProvider, err = cloud.CreateProvider(providerType, awsConfig)
 			if err != nil {
-				log.Printf("Warning: Failed to create AWS cloud provider: %v", err)
+				logger().Warn("Failed to create AWS cloud provider", "error", err)
 			}
 		default:
-			log.Printf("Warning: Unsupported cloud provider type: %s", providerType)
+			// Other providers come from plugins and receive their settings as-is
+			cloudProvider, err = cloud.CreateProvider(providerType, config.ProviderSettings)
+			if err != nil {
+				logger().Warn("Unsupported cloud provider type", "provider", providerType, "error", err)
+			}
 		}
 	} else {
-		log.Printf("No cloud provider available, running in local mode")
+		logger().Info("No cloud provider available, running in local mode")
 	}
 
-	// Set up API socket server
-	socketServer, err := api.NewSocketServer(*socketPath)
+	// Export metrics and traces to monitoring backends
+	shutdownTelemetry := setupTelemetry(config, cloudProvider)
+
+	// Profiling endpoints for diagnosing performance in the field
+	if config.Debug.PprofAddr != "" {
+		if err := startDebugServer(ctx, config.Debug.PprofAddr); err != nil {
+			logger().Warn("Failed to start debug server", "address", config.Debug.PprofAddr, "error", err)
+		}
+	}
+
+	// Look up the instance price for savings estimates unless one is configured
+	if config.Notifications.HourlyCostUSD <= 0 && config.PricingLookup {
+		config.Notifications.HourlyCostUSD = lookupHourlyCost(cloudProvider)
+	}
+
+	// Set up the scheduler, falling back to local time if the zone is invalid
+	scheduler, err := schedule.NewScheduler(config.Schedule.Timezone)
+	if err != nil {
+		logger().Warn("Invalid time zone, falling back to local time", "error", err)
+		scheduler, _ = schedule.NewScheduler("")
+	}
+	logger().Info("Using time zone for schedules", "timezone", scheduler.ZoneName())
+
+	// Set up weekend thresholds
+	if config.Schedule.Weekend.Enabled {
+		days, err := schedule.ParseWeekdays(config.Schedule.Weekend.Days)
+		if err != nil {
+			logger().Warn("Invalid weekend days, using Saturday and Sunday", "error", err)
+		} else {
+			scheduler.SetWeekendDays(days)
+		}
+	}
+	
+	// Set up the maintenance window calendar
+	if config.Schedule.Calendar.Enabled {
+		calendar := schedule.NewCalendar(schedule.CalendarConfig{
+			URL:                 config.Schedule.Calendar.URL,
+			RefreshMinutes:      config.Schedule.Calendar.RefreshMinutes,
+			CachePath:           config.Schedule.Calendar.CachePath,
+			DefaultKind:         schedule.WindowKind(config.Schedule.Calendar.DefaultWindow),
+			BlackoutKeywords:    config.Schedule.Calendar.BlackoutKeywords,
+			ForceActiveKeywords: config.Schedule.Calendar.ForceActiveKeywords,
+		})
+		scheduler.SetCalendar(calendar)
+		if err := calendar.Initialize(); err != nil {
+			logger().Warn("Failed to refresh calendar, using cached events", "error", err)
+		}
+	}
+
+	// Set up the daily runtime budget
+	if config.Schedule.DailyRuntimeBudgetHours > 0 {
+		budget := schedule.NewBudget(
+			time.Duration(config.Schedule.DailyRuntimeBudgetHours*float64(time.Hour)),
+			time.Duration(config.Schedule.BudgetWarningMinutes)*time.Minute,
+			scheduler.Location(),
+			config.Schedule.BudgetStatePath,
+		)
+		scheduler.SetBudget(budget)
+		logger().Info("Daily runtime budget configured", "hours", config.Schedule.DailyRuntimeBudgetHours)
+	}
+	
+	// Set up snooze history
+	historyStore, err := history.NewStore(config.HistoryFile, config.HistoryMaxEvents)
+	if err != nil {
+		logger().Warn("Failed to load snooze history", "error", err)
+	}
+	recordStart(historyStore, cloudProvider, config)
+
+	if binder, ok := cloudProvider.(common.ContextBinder); ok {
+		binder.SetContext(ctx)
+	}
+
+	// Set up notifications
+	notifier := newNotifier(config, cloudProvider)
+
+	// Set up the pre-stop countdown
+	stopCountdown := newCountdown(time.Duration(config.CountdownSeconds) * time.Second)
+
+	// Watch for maintenance scheduled by the cloud provider
+	maintenance := newMaintenanceWatcher(cloudProvider, config.Maintenance)
+
+	// Keep the instance running while clients hold leases
+	leases := newLeaseManager(config.Leases)
+
+	// and while instances that depend on it are active
+	dependencies := newDependencyChecker(cloudProvider, config)
+
+	// and when snoozing it would leave too few of its replicas running
+	peers := newPeerChecker(cloudProvider, config)
+
+	// Stop trying to snooze after repeated stop failures
+	breaker := newStopBreaker(config.Notifications.Alerting.StopFailureThreshold)
+
+	// Record every command for change tracking
+	auditLog := newAuditLog(config)
+
+	// Every in-memory buffer has a fixed size, reported by STATUS
+	buffers := map[string]bufferReporter{
+		"decisions":          decisions,
+		"history":            historyStore,
+		"audit":              auditLog,
+		"notification_queue": notifier,
+	}
+
+	// Set up API socket server, registering the command handlers
+	var activeProvider string
+	if cloudProvider != nil {
+		activeProvider = string(providerType)
+	}
+	admins, err := adminPolicy(config.Socket)
+	if err != nil {
+		logger().Error("Failed to read socket admin token", "error", err)
+		os.Exit(1)
+	}
+	allowlists, err := clientAllowlists(config.ClientAllowlists)
+	if err != nil {
+		logger().Error("Failed to read client allowlist token", "error", err)
+		os.Exit(1)
+	}
+	registerHandlers := func(server *api.SocketServer) error {
+		if err := server.SetAllowlists(allowlists); err != nil {
+			server.Stop()
+			return err
+		}
+		server.SetAuditLog(auditLog)
+		server.SetRateLimit(rateLimit(config.Socket.RateLimit))
+		registerCommandHandlers(server, *configFile, systemMonitor, config, cloudProvider, scheduler, historyStore, stopCountdown, maintenance, leases, dependencies, peers, breaker, auditLog, buffers)
+		registerPluginHandlers(server, *configFile, config, activeProvider)
+		return nil
+	}
+	newServer := func() (*api.SocketServer, error) {
+		server, err := api.NewSocketServer(*socketPath, socketAccess(config.Socket))
+		if err != nil {
+			return nil, err
+		}
+		if err := server.SetAdminPolicy(admins); err != nil {
+			server.Stop()
+			return nil, err
+		}
+		if err := registerHandlers(server); err != nil {
+			return nil, err
+		}
+		return server, nil
+	}
+	socketServer, err := newServer()
 	if err != nil {
-		log.Fatalf("Failed to create socket server: %v", err)
+		logger().Error("Failed to create socket server", "error", err)
+		os.Exit(1)
+	}
+
+	// Serve the API over TCP for fleet tooling; clients need a certificate
+	var serveRemote func()
+	if remote := config.RemoteAPI; remote.ListenAddr != "" {
+		newRemoteServer := func() (*api.SocketServer, error) {
+			server, err := api.NewTLSServer(remote.ListenAddr, api.RemoteTLS{
+				CertFile:     remote.CertFile,
+				KeyFile:      remote.KeyFile,
+				ClientCAFile: remote.ClientCAFile,
+			})
+			if err != nil {
+				return nil, err
+			}
+			if err := registerHandlers(server); err != nil {
+				return nil, err
+			}
+			return server, nil
+		}
+		// Listen before giving up root, in case the port needs it
+		remoteServer, err := newRemoteServer()
+		serveRemote = func() {
+			serveRemoteAPI(ctx, remote.ListenAddr, remoteServer, err, newRemoteServer)
+		}
+	}
+
+	// Push status to the aggregation server, which may queue commands
+	var pushAggregation func()
+	if config.Aggregation.ServerURL != "" {
+		pusher, err := newAggregationPusher(config.Aggregation)
+		if err != nil {
+			logger().Error("Aggregation disabled", "error", err)
+		} else {
+			relay := api.NewRelayServer()
+			if err := registerHandlers(relay); err != nil {
+				logger().Error("Aggregation disabled", "error", err)
+			} else {
+				pushAggregation = func() {
+					pushReports(ctx, pusher, relay, cloudProvider, config.Aggregation)
+				}
+			}
+		}
+	}
+	
+	// Record the daemon's state in the fleet's state table
+	var recordState func()
+	if config.StateTable != "" {
+		recorder, ok := cloudProvider.(common.StateRecorder)
+		relay := api.NewRelayServer()
+		if !ok {
+			logger().Warn("State table configured but the cloud provider cannot record state")
+		} else if err := registerHandlers(relay); err != nil {
+			logger().Error("State table disabled", "error", err)
+		} else {
+			recordState = func() {
+				recordStates(ctx, recorder, relay, time.Duration(config.StateTableSeconds)*time.Second)
+			}
+		}
 	}
 
-	// Register command handlers
-	registerCommandHandlers(socketServer, systemMonitor, config, cloudProvider)
+	// Everything that needs root has been set up
+	if err := dropPrivileges(config.Privileges, writablePaths(config)); err != nil {
+		logger().Error("Failed to drop privileges", "user", config.Privileges.User, "error", err)
+		os.Exit(1)
+	}
+	if config.Privileges.User != "" {
+		logger().Info("Running as an unprivileged user", "user", config.Privileges.User, "uid", os.Geteuid())
+	}
 
-	// Start socket server in a goroutine
+	// Restore what was saved before the last snooze
 	go func() {
-		if err := socketServer.Start(); err != nil {
-			log.Fatalf("Socket server error: %v", err)
+		if err := newContainerStopper(config.Docker).StartStopped(ctx); err != nil {
+			logger().Warn("Failed to start stopped containers", "error", err)
+		}
+		if err := newNodeDrainer(config.Kubernetes).Uncordon(ctx); err != nil {
+			logger().Warn("Failed to uncordon node", "error", err)
 		}
+		if err := newHookRunner(config.Hooks).PostStart(ctx); err != nil {
+			logger().Warn("Failed to run post_start hooks", "error", err)
+		}
+	}()
+
+	// Serve the API until ctx is cancelled; an error means it can't be served
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- serveAPI(ctx, socketServer, newServer)
 	}()
+	if serveRemote != nil {
+		go serveRemote()
+	}
+	if pushAggregation != nil {
+		go pushAggregation()
+	}
+	if recordState != nil {
+		go recordState()
+	}
 
 	// Set up signal handling for graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	sigChan := make(chan os.Signal, 2)
+	notifyShutdown(sigChan)
 
 	// Start monitoring loop
-	done := make(chan bool)
-	go monitorLoop(systemMonitor, cloudProvider, config, done)
+	monitorDone := make(chan struct{})
+	go func() {
+		defer close(monitorDone)
+		monitorLoop(ctx, systemMonitor, cloudProvider, config, scheduler, historyStore, stopCountdown, maintenance, leases, dependencies, peers, breaker, notifier)
+	}()
+
+	// Wait for a signal, or for the API to fail for good
+	exitCode := 0
+	serverStopped := false
+	select {
+	case sig := <-sigChan:
+		logger().Info("Received signal, shutting down", "signal", sig.String())
+	case err := <-serverErr:
+		logger().Error("API socket unavailable, shutting down", "error", err)
+		exitCode = 1
+		serverStopped = true
+	}
 
-	// Wait for signal
-	sig := <-sigChan
-	log.Printf("Received signal %v, shutting down...", sig)
+	// Cancel in-flight work, and don't let a stuck subsystem hold up exit
+	cancel()
+	forceExit := time.AfterFunc(shutdownTimeout, func() {
+		logger().Error("Shutdown timed out, exiting", "timeout", shutdownTimeout.String())
+		os.Exit(1)
+	})
+	defer forceExit.Stop()
+	go func() {
+		sig := <-sigChan
+		logger().Warn("Received second signal, exiting immediately", "signal", sig.String())
+		os.Exit(1)
+	}()
 
-	// Stop the monitoring loop
-	done <- true
+	// Wait for the monitoring loop and in-progress commands to finish
+	<-monitorDone
+	if !serverStopped {
+		<-serverErr
+	}
 
 	// Clean up
-	if err := socketServer.Stop(); err != nil {
-		log.Printf("Error stopping socket server: %v", err)
+	if err := auditLog.Close(); err != nil {
+		logger().Warn("Failed to close audit log", "error", err)
 	}
 	
-	// Stop tag polling if the provider supports it
-	// This is a type assertion to check if our provider is specifically an AWS provider
-	if cloudProvider != nil {
-		if provider, ok := cloudProvider.(interface{ StopTagPolling() }); ok {
-			provider.StopTagPolling()
-		}
-	}
+	// Stop scheduler background activity
+	scheduler.Stop()
+	
+	// Stop retrying notifications; undelivered ones stay queued for the next start
+	notifier.Stop()
 	
 	// Stop all running plugins
 	if config.PluginsEnabled {
-		log.Println("Stopping all plugins...")
-		providers := cloudplugin.Registry.GetAllProviders()
-		for _, p := range providers {
+		logger().Info("Stopping all plugins")
+		for _, p := range plugin.Registry.GetAll() {
 			if p.IsRunning() {
 				info := p.Info()
-				log.Printf("Stopping plugin: %s (%s)", info.Name, info.ID)
+				logger().Info("Stopping plugin", "plugin", info.ID, "name", info.Name)
 				if err := p.Stop(); err != nil {
-					log.Printf("Error stopping plugin %s: %v", info.ID, err)
+					logger().Error("Failed to stop plugin", "plugin", info.ID, "error", err)
 				}
 			}
 		}
 	}
+	
+	// Flush buffered metrics and traces
+	if shutdownTelemetry != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := shutdownTelemetry(ctx); err != nil {
+			logger().Warn("Failed to flush telemetry", "error", err)
+		}
+		cancel()
+	}
+	
+	// Flush the log file last so shutdown is recorded
+	if logFile != nil {
+		logger().Info("Shutdown complete")
+		logFile.Close()
+	}
+	if exitCode != 0 {
+		os.Exit(exitCode)
+	}
+}
+
+// awsProviderConfig returns the AWS provider settings from config
+func awsProviderConfig(config Config) aws.Config {
+	return aws.Config{
+		Region:             config.AWSRegion,
+		EnableTags:         config.EnableInstanceTags,
+		TaggingPrefix:      config.TaggingPrefix,
+		DetailedTags:       config.DetailedInstanceTags,
+		MaxSnooze:          time.Duration(config.MaxSnoozeHours) * time.Hour,
+		TagPollingEnabled:  config.TagPollingEnabled,
+		TagPollingInterval: config.TagPollingIntervalSecs,
+		EnableCloudWatch:   config.Logging.EnableCloudWatch,
+		CloudWatchLogGroup: config.Logging.CloudWatchLogGroup,
+		SNSTopicARN:        config.SNSTopicARN,
+		PublishMetrics:     config.CloudWatchMetrics,
+		MetricsNamespace:   config.CloudWatchNamespace,
+		Partition:          config.AWSPartition,
+		EC2Endpoint:        config.EC2Endpoint,
+		MetadataEndpoint:   config.MetadataEndpoint,
+		AssumeRoleARN:      config.AssumeRoleARN,
+		ExternalID:         config.AssumeRoleExternalID,
+		Hibernate:          config.StopAction == "hibernate",
+		WarmPool:           config.StopAction == "warm_pool",
+		PricingCachePath:   config.PricingCachePath,
+		DeregisterTargets:  config.ELBDeregister,
+		TargetGroupARNs:    config.ELBTargetGroups,
+		DrainTimeout:       time.Duration(config.ELBDrainTimeoutSecs) * time.Second,
+		RetryAttempts:      config.AWSRetryAttempts,
+		DNSZoneID:          config.Route53ZoneID,
+		DNSRecordName:      config.Route53RecordName,
+		DNSParkedIP:        config.Route53ParkedIP,
+		DNSTTL:             config.Route53TTL,
+		DNSPrivateIP:       config.Route53PrivateIP,
+		StateTable:         config.StateTable,
+		StateTTL:           time.Duration(config.StateTableTTLHours) * time.Hour,
+	}
+}
+
+// socketAccess returns the socket group and permissions to use. The mode
+// is validated when the config is loaded.
+func socketAccess(config SocketConfig) api.SocketAccess {
+	access := api.SocketAccess{Group: config.Group}
+	if config.Mode != "" {
+		access.Mode, _ = api.ParseSocketMode(config.Mode)
+	}
+	return access
+}
+
+// rateLimit returns how many commands the API servers handle per second
+func rateLimit(config RateLimitConfig) api.RateLimit {
+	return api.RateLimit{
+		GlobalPerSecond: config.GlobalPerSecond,
+		ClientPerSecond: config.ClientPerSecond,
+		Burst:           config.Burst,
+	}
+}
+
+// adminPolicy returns who may run administrative commands, reading the
+// admin token from its file
+func adminPolicy(config SocketConfig) (api.AdminPolicy, error) {
+	policy := api.AdminPolicy{Group: config.AdminGroup}
+	if config.AdminTokenFile != "" {
+		data, err := os.ReadFile(config.AdminTokenFile)
+		if err != nil {
+			return policy, err
+		}
+		policy.Token = strings.TrimSpace(string(data))
+	}
+	return policy, nil
+}
+
+// clientAllowlists returns the commands API clients are limited to, reading
+// the tokens from their files
+func clientAllowlists(configs []ClientAllowlistConfig) ([]api.ClientAllowlist, error) {
+	lists := make([]api.ClientAllowlist, 0, len(configs))
+	for _, config := range configs {
+		list := api.ClientAllowlist{User: config.User, Certificate: config.Certificate, Commands: config.Commands}
+		if config.TokenFile != "" {
+			data, err := os.ReadFile(config.TokenFile)
+			if err != nil {
+				return nil, err
+			}
+			list.Token = strings.TrimSpace(string(data))
+		}
+		lists = append(lists, list)
+	}
+	return lists, nil
+}
+
+// serveAPI runs the API socket server until ctx is cancelled. If the server
+// fails, for example because its socket was removed, a new one is created
+// in its place. It gives up and returns the error after repeated failures.
+func serveAPI(ctx context.Context, server *api.SocketServer, newServer func() (*api.SocketServer, error)) error {
+	failures := 0
+	for {
+		started := time.Now()
+		err := server.StartContext(ctx)
+		if stopErr := server.Stop(); stopErr != nil {
+			logger().Debug("Failed to close socket server", "error", stopErr)
+		}
+		if err == nil || ctx.Err() != nil {
+			return nil
+		}
+
+		// Only count failures in quick succession
+		if time.Since(started) > serverRestartWindow {
+			failures = 0
+		}
+		for {
+			failures++
+			if failures > maxServerRestarts {
+				return err
+			}
+			logger().Error("Socket server failed, listening again", "error", err, "attempt", failures)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(serverRestartDelay):
+			}
+
+			if server, err = newServer(); err == nil {
+				break
+			}
+		}
+	}
+}
+
+// serveRemoteAPI runs the TCP API until ctx is cancelled, given the result
+// of the first newServer call. Unlike the socket, the daemon keeps running
+// without it.
+func serveRemoteAPI(ctx context.Context, addr string, server *api.SocketServer, err error, newServer func() (*api.SocketServer, error)) {
+	if err == nil {
+		logger().Info("Serving the TCP API", "address", addr)
+		health.Set(remoteAPIHealthComponent, health.OK, "listening on "+addr)
+		err = serveAPI(ctx, server, newServer)
+	}
+	if err != nil {
+		logger().Error("TCP API unavailable", "address", addr, "error", err)
+		health.Set(remoteAPIHealthComponent, health.Failed, err.Error())
+	}
 }
 
 func loadConfig(path string) (Config, error) {
@@ -214,9 +812,13 @@ func loadConfig(path string) (Config, error) {
 
 	// Check if config file exists
 	if _, err := os.Stat(path); os.IsNotExist(err) {
+		// A locked config can't be reset by removing it
+		if *lockedConfig {
+			return config, fmt.Errorf("config file %s is missing", path)
+		}
+
 		// Create config directory if it doesn't exist
-		configDir := "/etc/snooze"
-		if err := os.MkdirAll(configDir, 0755); err != nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 			return config, fmt.Errorf("failed to create config directory: %v", err)
 		}
 
@@ -229,9 +831,15 @@ func loadConfig(path string) (Config, error) {
 		if err := os.WriteFile(path, defaultConfig, 0644); err != nil {
 			return config, fmt.Errorf("failed to write default config: %v", err)
 		}
+		if err := writeConfigSignature(path, defaultConfig); err != nil {
+			return config, fmt.Errorf("failed to sign default config: %v", err)
+		}
 
-		log.Printf("Created default configuration at %s", path)
-		return config, nil
+		logger().Info("Created default configuration", "path", path)
+		if err := applyEnvironment(&config, os.Environ()); err != nil {
+			return config, err
+		}
+		return config, validateConfig(config)
 	}
 
 	// Read and parse config file
@@ -239,139 +847,1150 @@ func loadConfig(path string) (Config, error) {
 	if err != nil {
 		return config, fmt.Errorf("failed to read config file: %v", err)
 	}
+	if err := verifyConfig(path, data); err != nil {
+		return config, err
+	}
 
 	if err := json.Unmarshal(data, &config); err != nil {
 		return config, fmt.Errorf("failed to parse config file: %v", err)
 	}
+	if err := applyEnvironment(&config, os.Environ()); err != nil {
+		return config, err
+	}
+	if err := resolveSecrets(&config); err != nil {
+		return config, err
+	}
+
+	return config, validateConfig(config)
+}
+
+// updateConfigFile sets one setting in the configuration file, leaving the
+// rest of the file as written. Nested settings use dotted keys such as
+// "logging.log_level".
+func updateConfigFile(path, key string, value interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// Signing the change would also sign edits made to the file since it
+	// was last signed, so a file that doesn't verify is left alone when
+	// locked, and left unsigned otherwise
+	problem, err := configProblem(path, data)
+	if err != nil {
+		return err
+	}
+	if problem != "" && *lockedConfig {
+		return fmt.Errorf("%s, not changing it (run snoozed -sign-config to sign an intended change)", problem)
+	}
+
+	settings := make(map[string]interface{})
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return fmt.Errorf("failed to parse config file: %v", err)
+	}
+	
+	section := settings
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := section[part].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			section[part] = child
+		}
+		section = child
+	}
+	section[parts[len(parts)-1]] = value
+
+	data, err = json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize config: %v", err)
+	}
 
-	return config, nil
+	// Write to a temporary file first so a failure can't truncate the config
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	// Changes made through the API are signed like the rest of the file
+	if problem != "" {
+		logger().Warn("Leaving changed config file unsigned", "path", path, "problem", problem)
+		return nil
+	}
+	return writeConfigSignature(path, data)
 }
 
-func monitorLoop(systemMonitor *monitor.SystemMonitor, cloudProvider common.CloudProvider, config Config, done chan bool) {
-	ticker := time.NewTicker(time.Duration(config.CheckIntervalSeconds) * time.Second)
-	defer ticker.Stop()
+
+func monitorLoop(ctx context.Context, systemMonitor *monitor.SystemMonitor, cloudProvider common.CloudProvider, config Config, scheduler *schedule.Scheduler, historyStore *history.Store, stopCountdown *countdown, maintenance *maintenanceWatcher, leases *leaseManager, dependencies *dependencyChecker, peers *peerChecker, breaker *stopBreaker, notifier *notify.Dispatcher) {
+	checkInterval := newAdaptiveInterval(time.Duration(config.CheckIntervalSeconds)*time.Second, config.AdaptiveInterval)
+	timer := time.NewTimer(checkInterval.Current())
+	defer timer.Stop()
 
 	// Try to verify permissions at startup
+	permissionsOK := true
+	var lastPermissionCheck time.Time
 	if cloudProvider != nil {
-		log.Printf("Verifying cloud provider permissions...")
-		if hasPerms, err := cloudProvider.VerifyPermissions(); err != nil {
-			log.Printf("Warning: Failed to verify cloud provider permissions: %v", err)
-		} else if !hasPerms {
-			log.Printf("Warning: Insufficient permissions to stop instances")
-		} else {
-			log.Printf("Cloud provider permissions verified successfully")
-		}
+		logger().Info("Verifying cloud provider permissions")
+		permissionsOK = checkPermissions(cloudProvider, config, notifier, permissionsOK)
+		lastPermissionCheck = time.Now()
 	}
+	permissionInterval := time.Duration(config.Notifications.Alerting.PermissionCheckMinutes) * time.Minute
 
-	for {
-		select {
-		case <-done:
-			return
-		case <-ticker.C:
-			metrics, err := systemMonitor.CollectMetrics()
-			if err != nil {
-				log.Printf("Error collecting metrics: %v", err)
-				continue
-			}
-
-			shouldSnooze, reason := systemMonitor.ShouldSnooze()
-			if shouldSnooze {
-				log.Printf("Instance should be snoozed: %s", reason)
-				
-				// Actually stop the instance via cloud provider
-				if cloudProvider != nil {
-					// Create a snooze event for logging
-					event := &monitor.SnoozeEvent{
-						Timestamp:   time.Now(),
-						Reason:      reason,
-						Metrics:     metrics,
-						NaptimeMins: config.NaptimeMinutes,
-					}
-					
-					// Get instance info if possible
-					instanceInfo, err := cloudProvider.GetInstanceInfo()
-					if err != nil {
-						log.Printf("Warning: Failed to get instance info: %v", err)
-					} else {
-						event.InstanceID = instanceInfo.ID
-						event.InstanceType = instanceInfo.Type
-						event.Region = instanceInfo.Region
-					}
-					
-					// Log the snooze event (ideally this would go to a proper logging system)
-					eventJSON, _ := json.MarshalIndent(event, "", "  ")
-					log.Printf("Snooze event: %s", string(eventJSON))
-					
-					// Stop the instance
-					err = cloudProvider.StopInstance(reason, metrics)
-					if err != nil {
-						log.Printf("Failed to stop instance: %v", err)
-					} else {
-						log.Printf("Successfully initiated instance stop")
-					}
-				} else {
-					log.Printf("No cloud provider available, would stop instance with reason: %s", reason)
-				}
-				
-				// Reset idle state after stopping instance
-				systemMonitor.ResetIdleState()
+	activeProfile := ""
+	collectionFailures := 0
+	launched := launchTime(cloudProvider)
+	bootGrace := time.Duration(config.BootGraceMinutes) * time.Minute
+	hookRunner := newHookRunner(config.Hooks)
+	wasIdle := false
+
+	// check evaluates the system once and snoozes the instance if it should be
+	check := func() {
+		// Switch between weekday and weekend thresholds
+		if profile, thresholds := thresholdProfile(config, scheduler, time.Now()); profile != activeProfile {
+			if activeProfile != "" {
+				logger().Info("Switching threshold profile", "profile", profile, "naptime_minutes", thresholds.NaptimeMinutes)
 			}
+			systemMonitor.SetThresholds(thresholds)
+			activeProfile = profile
 		}
-	}
-}
 
-func registerCommandHandlers(server *api.SocketServer, systemMonitor *monitor.SystemMonitor, config Config, cloudProvider common.CloudProvider) {
-	
-	// STATUS command
-	server.RegisterHandler("STATUS", func(params map[string]interface{}) (interface{}, error) {
-		metrics := systemMonitor.GetLastMetrics()
-		
-		var idleSinceStr string
-		if idleSince := systemMonitor.GetIdleSince(); idleSince != nil {
-			idleSinceStr = idleSince.Format(time.RFC3339)
+		// Periodically re-verify that the instance can still stop itself,
+		// more often while stops are suspended
+		interval := permissionInterval
+		if breaker.Open() && (interval <= 0 || interval > stopBreakerRecheck) {
+			interval = stopBreakerRecheck
 		}
-		
-		shouldSnooze, reason := systemMonitor.ShouldSnooze()
-		
-		// Get instance info if available
-		var instanceInfo *common.InstanceInfo
-		if cloudProvider != nil {
-			instanceInfo, _ = cloudProvider.GetInstanceInfo()
+		if cloudProvider != nil && interval > 0 && time.Since(lastPermissionCheck) >= interval {
+			permissionsOK = checkPermissions(cloudProvider, config, notifier, permissionsOK)
+			lastPermissionCheck = time.Now()
+			if permissionsOK && breaker.Reset() {
+				logger().Info("Cloud provider permissions verified, resuming snoozing")
+			}
 		}
-		
-		return map[string]interface{}{
-			"metrics":       metrics,
-			"idle_since":    idleSinceStr,
-			"should_snooze": shouldSnooze,
-			"snooze_reason": reason,
-			"version":       version,
-			"instance_info": instanceInfo,
+
+		metrics, err := systemMonitor.CollectMetrics()
+		if err != nil {
+			logger().Error("Failed to collect metrics", "error", err)
+			collectionFailures++
+			if collectionFailures == config.Notifications.Alerting.CollectionFailureThreshold {
+				event := newSnoozeEvent(cloudProvider, config, "Metric collection is failing", "", metrics, nil)
+				notifier.Notify(notify.Notification{
+					Type:  notify.NotificationCollectionFailed,
+					Event: *event,
+					Error: fmt.Sprintf("%d consecutive failures, last error: %v", collectionFailures, err),
+				})
+			}
+			return
+		}
+		if collectionFailures > 0 {
+			logger().Info("Metric collection recovered", "failures", collectionFailures)
+		}
+		collectionFailures = 0
+
+		// Let event hooks follow the idle state
+		if idle := systemMonitor.GetIdleSince() != nil; idle != wasIdle {
+			wasIdle = idle
+			event := hooks.EventIdleEnd
+			if idle {
+				event = hooks.EventIdleStart
+			}
+			hookRunner.Fire(ctx, event, hooks.EventDetails{InstanceID: currentInstanceID(cloudProvider), Metrics: metrics})
+		}
+
+		// Notify about newly scheduled maintenance
+		for _, event := range maintenance.Poll(time.Now()) {
+			if config.Maintenance.Notify {
+				reason := fmt.Sprintf("Scheduled %s at %s: %s", event.Code, event.NotBefore.Format(time.RFC3339), event.Description)
+				notifier.Notify(notify.Notification{
+					Type:  notify.NotificationMaintenance,
+					Event: *newSnoozeEvent(cloudProvider, config, reason, "", metrics, nil),
+				})
+			}
+		}
+
+		// Track daily runtime budget consumption
+		budget := scheduler.Budget()
+		if budget != nil {
+			if err := budget.Record(time.Now()); err != nil {
+				logger().Warn("Failed to record runtime budget", "error", err)
+			}
+		}
+
+		// Apply maintenance windows from the calendar
+		window := scheduler.ActiveWindow()
+		if window != nil && window.Kind == schedule.WindowForceActive {
+			// Calendar says the system must be considered active
+			systemMonitor.ResetIdleState()
+			if stopCountdown.Cancel() {
+				logger().Info("Pending snooze aborted by calendar window", "window", window.Summary)
+			}
+			recordCheck(systemMonitor, metrics, telemetry.OutcomeSuppressed,
+				fmt.Sprintf("Calendar window %q keeps the system active", window.Summary))
+			return
+		}
+		blackout := window != nil && window.Kind == schedule.WindowBlackout
+
+		// Decide whether the instance should be stopped and why
+		var reason, trigger string
+		var budgetStatus *schedule.BudgetStatus
+		if budget != nil {
+			status := budget.Status()
+			budgetStatus = &status
+		}
+
+		shouldSnooze, idleReason := systemMonitor.ShouldSnooze()
+
+		// Enforce the daily runtime budget even if the system is not idle
+		if budget != nil && !blackout {
+			if budget.NeedsWarning() {
+				status := budget.Status()
+				logger().Warn("Daily runtime budget nearly used",
+					"used_minutes", status.UsedMinutes, "budget_minutes", status.BudgetMinutes, "remaining_minutes", status.RemainingMinutes)
+			}
+			if budget.Exceeded() {
+				reason = fmt.Sprintf("Daily runtime budget of %.1f hours exceeded", budgetStatus.BudgetMinutes/60)
+				trigger = monitor.TriggerRuntimeBudget
+			}
+		}
+
+		if trigger == "" && shouldSnooze {
+			if blackout {
+				logger().Info("Snooze suppressed by calendar blackout window",
+					"window", window.Summary, "until", window.End.Format(time.RFC3339))
+			} else if time.Since(launched) < bootGrace {
+				logger().Info("Snooze suppressed during boot grace period",
+					"launched", launched.Format(time.RFC3339), "grace", bootGrace.String())
+			} else {
+				reason = idleReason
+				trigger = monitor.TriggerIdle
+			}
+		}
+
+		// Don't stop the instance shortly before scheduled maintenance
+		if event := maintenance.Blocking(time.Now()); trigger != "" && event != nil {
+			why := fmt.Sprintf("Snooze suppressed by scheduled %s at %s", event.Code, event.NotBefore.Format(time.RFC3339))
+			if stopCountdown.Cancel() {
+				logger().Info("Pending snooze aborted by scheduled maintenance", "code", event.Code)
+			}
+			logger().Info("Snooze suppressed by scheduled maintenance",
+				"code", event.Code, "not_before", event.NotBefore.Format(time.RFC3339), "reason", reason)
+			recordCheck(systemMonitor, metrics, telemetry.OutcomeSuppressed, why)
+			return
+		}
+
+		// Leases keep the instance running whatever its load
+		if active := leases.Active(time.Now()); trigger != "" && len(active) > 0 {
+			lease := active[0]
+			if stopCountdown.Cancel() {
+				logger().Info("Pending snooze aborted by lease", "id", lease.ID, "owner", lease.Owner)
+			}
+			recordCheck(systemMonitor, metrics, telemetry.OutcomeSuppressed,
+				fmt.Sprintf("Snooze suppressed by lease %s held by %s until %s: %s",
+					lease.ID, lease.Owner, lease.ExpiresAt.Format(time.RFC3339), lease.Reason))
+			return
+		}
+
+		// So do active instances that depend on this one, and peers that
+		// need this replica
+		if trigger != "" {
+			if why := dependencies.Check(ctx, time.Now()); why != "" {
+				if stopCountdown.Cancel() {
+					logger().Info("Pending snooze aborted by dependent instances", "reason", why)
+				}
+				recordCheck(systemMonitor, metrics, telemetry.OutcomeSuppressed, why)
+				return
+			}
+			if why := peers.Check(ctx, time.Now()); why != "" {
+				if stopCountdown.Cancel() {
+					logger().Info("Pending snooze aborted by peers", "reason", why)
+				}
+				recordCheck(systemMonitor, metrics, telemetry.OutcomeSuppressed, why)
+				return
+			}
+		}
+
+		// Don't keep calling the API when the instance can't be stopped
+		if trigger != "" && breaker.Open() {
+			stopCountdown.Cancel()
+			recordCheck(systemMonitor, metrics, telemetry.OutcomeSuppressed,
+				"Snoozing suspended after repeated stop failures until permissions are verified")
+			return
+		}
+
+		if trigger == "" {
+			// Any activity aborts a pending stop
+			if stopCountdown.Cancel() {
+				logger().Info("Pending snooze aborted", "reason", idleReason)
+			}
+			outcome, why := telemetry.OutcomeActive, idleReason
+			if shouldSnooze && blackout {
+				outcome = telemetry.OutcomeSuppressed
+				why = fmt.Sprintf("Snooze suppressed by calendar blackout window %q", window.Summary)
+			} else if shouldSnooze {
+				outcome = telemetry.OutcomeSuppressed
+				why = fmt.Sprintf("Snooze suppressed for %s after launch", bootGrace)
+			} else if systemMonitor.GetIdleSince() != nil {
+				outcome = telemetry.OutcomeIdle
+			}
+			recordCheck(systemMonitor, metrics, outcome, why)
+			return
+		}
+
+		// Site-specific checks may veto or defer the snooze, before a
+		// countdown starts and again when it runs out
+		if stopCountdown.Status() == nil || stopCountdown.Expired() {
+			instanceID := currentInstanceID(cloudProvider)
+			if verdict := hookRunner.Check(ctx, reason, trigger, instanceID, metrics); verdict.Decision != hooks.Allow {
+				why := fmt.Sprintf("Snooze deferred by %s check: %s", verdict.Hook, verdict.Message)
+				if verdict.Decision == hooks.Veto {
+					// Wait for the system to be idle for the naptime again
+					why = fmt.Sprintf("Snooze vetoed by %s check: %s", verdict.Hook, verdict.Message)
+					stopCountdown.Cancel()
+					systemMonitor.ResetIdleState()
+				}
+				logger().Info("Snooze refused by check", "decision", verdict.Decision, "hook", verdict.Hook,
+					"message", verdict.Message, "reason", reason)
+				recordCheck(systemMonitor, metrics, telemetry.OutcomeSuppressed, why)
+				return
+			}
+		}
+
+		// Give users a chance to cancel before stopping
+		if stopCountdown.Enabled() {
+			if stopCountdown.Start(reason, trigger) {
+				logger().Info("Instance will be snoozed unless cancelled (run 'snooze cancel')",
+					"countdown", stopCountdown.duration.String(), "reason", reason)
+				notifier.Notify(notify.Notification{
+					Type:         notify.NotificationPending,
+					Event:        *newSnoozeEvent(cloudProvider, config, reason, trigger, metrics, budgetStatus),
+					IdleDuration: idleDuration(systemMonitor),
+					Countdown:    stopCountdown.duration,
+					HourlyCost:   config.Notifications.HourlyCostUSD,
+				})
+				recordCheck(systemMonitor, metrics, telemetry.OutcomePending, reason)
+				return
+			}
+			if !stopCountdown.Expired() {
+				recordCheck(systemMonitor, metrics, telemetry.OutcomePending, reason)
+				return
+			}
+			stopCountdown.Cancel()
+		}
+
+		logger().Info("Instance should be snoozed", "reason", reason, "trigger", trigger)
+		recordCheck(systemMonitor, metrics, telemetry.OutcomeSnooze, reason)
+		err = snoozeInstance(ctx, cloudProvider, config, historyStore, notifier, reason, trigger, metrics, budgetStatus, idleDuration(systemMonitor))
+		if breaker.Record(err) {
+			status := breaker.Status()
+			logger().Error("Snoozing suspended after repeated stop failures", "failures", status.Failures, "error", status.LastError)
+			notifier.Notify(notify.Notification{
+				Type:  notify.NotificationSuspended,
+				Event: *newSnoozeEvent(cloudProvider, config, reason, trigger, metrics, budgetStatus),
+				Error: fmt.Sprintf("%d consecutive failures, last error: %s", status.Failures, status.LastError),
+			})
+		}
+
+		// Reset idle state after stopping instance
+		systemMonitor.ResetIdleState()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			// A panic in a collector ends this check only
+			func() {
+				defer snoozeerrors.Recover(func(err *snoozeerrors.CloudSnoozeError) {
+					logger().Error("Recovered from panic in monitor check", "error", err, "stack", err.Stack)
+				})
+				check()
+			}()
+
+			previous := checkInterval.Current()
+			naptime := time.Duration(systemMonitor.GetThresholds().NaptimeMinutes) * time.Minute
+			next := checkInterval.Next(time.Now(), systemMonitor.GetIdleSince(), naptime, stopCountdown.Status() != nil)
+			if next != previous {
+				logger().Debug("Changing check interval", "interval", next.String())
+			}
+			timer.Reset(next)
+		}
+	}
+}
+
+// recordCheck records the decision made by a check and exports its
+// utilization, thresholds and outcome
+func recordCheck(systemMonitor *monitor.SystemMonitor, metrics common.SystemMetrics, outcome, reason string) {
+	decision := systemMonitor.RecordDecision(outcome, reason)
+	logger().Debug("Decision",
+		"outcome", decision.Outcome,
+		"idle", decision.Idle,
+		"idle_seconds", decision.IdleSeconds,
+		"reason", decision.Reason,
+		"checks", decision.Checks)
+	
+	thresholds := systemMonitor.GetThresholds()
+	check := telemetry.Check{
+		Idle: idleDuration(systemMonitor),
+		Usage: map[string]float64{
+			"cpu":     metrics.CPUUsage,
+			"memory":  metrics.MemoryUsage,
+			"network": metrics.NetworkRate,
+			"disk_io": metrics.DiskIORate,
+		},
+		Thresholds: map[string]float64{
+			"cpu":     thresholds.CPUPercent,
+			"memory":  thresholds.MemoryPercent,
+			"network": thresholds.NetworkKBps,
+			"disk_io": thresholds.DiskIOKBps,
+		},
+		Outcome: outcome,
+	}
+	
+	// The busiest GPU decides whether the GPUs are idle
+	if len(metrics.GPUMetrics) > 0 {
+		var busiest float64
+		for _, gpu := range metrics.GPUMetrics {
+			if gpu.Utilization > busiest {
+				busiest = gpu.Utilization
+			}
+		}
+		check.Usage["gpu"] = busiest
+		check.Thresholds["gpu"] = thresholds.GPUPercent
+	}
+	
+	telemetry.Record(context.Background(), check)
+}
+
+// checkPermissions verifies the cloud provider permissions, sending a
+// notification when they stop being sufficient. It returns whether the
+// permissions are currently sufficient.
+func checkPermissions(cloudProvider common.CloudProvider, config Config, notifier *notify.Dispatcher, previouslyOK bool) bool {
+	hasPerms, err := cloudProvider.VerifyPermissions()
+	if err == nil && hasPerms {
+		if !previouslyOK {
+			logger().Info("Cloud provider permissions restored")
+		} else {
+			logger().Info("Cloud provider permissions verified successfully")
+		}
+		return true
+	}
+	
+	if err != nil {
+		logger().Warn("Failed to verify cloud provider permissions", "error", err)
+	} else {
+		logger().Warn("Insufficient permissions to stop instances")
+		err = fmt.Errorf("insufficient permissions to stop instances")
+	}
+	
+	// Only notify when permissions change from sufficient to insufficient
+	if previouslyOK {
+		event := newSnoozeEvent(cloudProvider, config, "Cloud permission check failed", "", common.SystemMetrics{}, nil)
+		notifier.Notify(notify.Notification{
+			Type:  notify.NotificationPermissionsLost,
+			Event: *event,
+			Error: err.Error(),
+		})
+	}
+	return false
+}
+
+// launchTime returns when the instance was launched, or when the system
+// booted if the cloud provider doesn't know
+func launchTime(cloudProvider common.CloudProvider) time.Time {
+	if cloudProvider != nil {
+		if info, err := cloudProvider.GetInstanceInfo(); err == nil && info.LaunchTime != "" {
+			if launched, err := time.Parse(time.RFC3339, info.LaunchTime); err == nil {
+				return launched
+			}
+		}
+	}
+	if boot, err := host.BootTime(); err == nil {
+		return time.Unix(int64(boot), 0)
+	}
+	return time.Time{}
+}
+
+// currentInstanceID returns the ID of the instance, or an empty string if
+// there is no cloud provider or it doesn't know
+func currentInstanceID(cloudProvider common.CloudProvider) string {
+	if cloudProvider == nil {
+		return ""
+	}
+	if info, err := cloudProvider.GetInstanceInfo(); err == nil {
+		return info.ID
+	}
+	return ""
+}
+
+// recordStart adds a start event to the history if the instance launched
+// since the last snooze, so reports use the time it was actually stopped
+func recordStart(historyStore *history.Store, cloudProvider common.CloudProvider, config Config) {
+	launched := launchTime(cloudProvider)
+	if launched.IsZero() {
+		return
+	}
+	event := monitor.SnoozeEvent{
+		Timestamp: launched,
+		Reason:    "Instance started",
+	}
+	if cloudProvider != nil {
+		if info, err := cloudProvider.GetInstanceInfo(); err == nil {
+			event.InstanceID = info.ID
+			event.InstanceType = info.Type
+			event.Region = info.Region
+			// The restarter tags the instances it starts
+			event.Trigger = info.Tags[config.TaggingPrefix+":start_trigger"]
+		}
+	}
+
+	recorded, err := historyStore.RecordStart(event)
+	if err != nil {
+		logger().Warn("Failed to record instance start", "error", err)
+	}
+	if recorded != nil {
+		stopped := time.Duration(recorded.StoppedMins * float64(time.Minute))
+		logger().Info("Recorded instance start", "launched", launched.Format(time.RFC3339),
+			"stopped_for", stopped.Round(time.Minute).String())
+	}
+}
+
+// lookupHourlyCost returns the on-demand price of the instance, or 0 if the
+// provider can't look it up
+func lookupHourlyCost(cloudProvider common.CloudProvider) float64 {
+	reporter, ok := cloudProvider.(common.PriceReporter)
+	if !ok {
+		return 0
+	}
+	price, err := reporter.HourlyPrice()
+	if err != nil {
+		logger().Warn("Failed to look up instance price, savings estimates are disabled", "error", err)
+		return 0
+	}
+	logger().Info("Using on-demand price for savings estimates", "usd_per_hour", price)
+	return price
+}
+
+// setupTelemetry starts exporting check metrics to the configured StatsD,
+// CloudWatch and OTLP backends, returning the function that flushes OTLP
+// export on shutdown
+func setupTelemetry(config Config, cloudProvider common.CloudProvider) func(context.Context) error {
+	if config.StatsD.Enabled {
+		statsd, err := telemetry.NewStatsD(telemetry.StatsDConfig{
+			Host:      config.StatsD.Host,
+			Port:      config.StatsD.Port,
+			Prefix:    config.StatsD.Prefix,
+			Tags:      config.StatsD.Tags,
+			DogStatsD: config.StatsD.DogStatsD,
+		})
+		if err != nil {
+			logger().Warn("StatsD metrics disabled", "error", err)
+		} else {
+			telemetry.AddRecorder(statsd)
+			logger().Info("Sending metrics to StatsD", "host", config.StatsD.Host, "port", config.StatsD.Port)
+		}
+	}
+	
+	// Providers that publish metrics to their own monitoring service
+	if recorder, ok := cloudProvider.(telemetry.Recorder); ok {
+		telemetry.AddRecorder(recorder)
+	}
+	
+	if !config.Telemetry.Enabled {
+		return nil
+	}
+	
+	attributes := map[string]string{"service.version": version}
+	if cloudProvider != nil {
+		if info, err := cloudProvider.GetInstanceInfo(); err == nil {
+			attributes["host.id"] = info.ID
+			attributes["host.type"] = info.Type
+			attributes["cloud.provider"] = info.Provider
+			attributes["cloud.region"] = info.Region
+		}
+	}
+	
+	shutdown, err := telemetry.Setup(context.Background(), telemetry.Config{
+		Endpoint:       config.Telemetry.OTLPEndpoint,
+		Insecure:       config.Telemetry.Insecure,
+		Headers:        config.Telemetry.Headers,
+		ServiceName:    config.Telemetry.ServiceName,
+		ExportInterval: time.Duration(config.Telemetry.ExportIntervalSecs) * time.Second,
+		Attributes:     attributes,
+	})
+	if err != nil {
+		logger().Warn("Telemetry export disabled", "error", err)
+		return nil
+	}
+	logger().Info("Exporting telemetry over OTLP", "endpoint", config.Telemetry.OTLPEndpoint)
+	return shutdown
+}
+
+// changeLogLevel changes the level of the running logger
+func changeLogLevel(name string) error {
+	if _, err := logging.ParseLevel(name); err != nil {
+		return err
+	}
+	logger().Info("Changing log level", "from", logging.Level(), "to", strings.ToLower(name))
+	return logging.SetLevel(name)
+}
+
+// newAuditLog creates the audit log of socket commands, also writing it to
+// a rotated file when a path is configured
+func newAuditLog(config Config) *api.AuditLog {
+	var out io.WriteCloser
+	if config.Audit.LogPath != "" {
+		file, err := logging.NewRotatingFile(config.Audit.LogPath, logging.RotateConfig{
+			MaxSizeMB:  config.Audit.MaxSizeMB,
+			MaxBackups: config.Audit.MaxBackups,
+			Compress:   config.Logging.Compress,
+		})
+		if err != nil {
+			logger().Warn("Audit log file disabled", "error", err)
+		} else {
+			out = file
+		}
+	}
+	return api.NewAuditLog(config.Audit.BufferSize, out)
+}
+
+// notificationRetryInterval is how often queued notifications are checked for redelivery
+const notificationRetryInterval = 30 * time.Second
+
+// newNotifier creates the notification dispatcher from the configuration
+func newNotifier(config Config, cloudProvider common.CloudProvider) *notify.Dispatcher {
+	dispatcher := notify.NewDispatcher()
+	
+	if config.Notifications.TitleTemplate != "" || config.Notifications.BodyTemplate != "" {
+		templates, err := notify.NewTemplates(config.Notifications.TitleTemplate, config.Notifications.BodyTemplate)
+		if err != nil {
+			logger().Warn("Invalid notification template, using default notification text", "error", err)
+		} else {
+			dispatcher.SetTemplates(templates)
+		}
+	}
+	
+	if config.Notifications.WarnUsers && config.CountdownSeconds > 0 {
+		dispatcher.Add(notify.NewSessionNotifier())
+	}
+	
+	if config.SNSTopicARN != "" {
+		if publisher, ok := cloudProvider.(common.EventPublisher); ok {
+			dispatcher.Add(notify.NewPublisherNotifier("sns", publisher))
+		} else {
+			logger().Warn("SNS topic configured but the cloud provider cannot publish events")
+		}
+	}
+	
+	if slack := config.Notifications.Slack; slack.Enabled {
+		notifier, err := notify.NewSlackNotifier(notify.SlackConfig{
+			WebhookURL: slack.WebhookURL,
+			BotToken:   slack.BotToken,
+			Channel:    slack.Channel,
+			Username:   slack.Username,
+		})
+		if err != nil {
+			logger().Warn("Slack notifications disabled", "error", err)
+		} else {
+			dispatcher.Add(notifier)
+		}
+	}
+	
+	if teams := config.Notifications.Teams; teams.Enabled {
+		notifier, err := notify.NewTeamsNotifier(teams.WebhookURL)
+		if err != nil {
+			logger().Warn("Teams notifications disabled", "error", err)
+		} else {
+			dispatcher.Add(notifier)
+		}
+	}
+	
+	if email := config.Notifications.Email; email.Enabled {
+		notifier, err := notify.NewEmailNotifier(notify.EmailConfig{
+			Host:            email.SMTPHost,
+			Port:            email.SMTPPort,
+			Username:        email.Username,
+			Password:        email.Password,
+			From:            email.From,
+			To:              email.To,
+			UseTLS:          email.UseTLS,
+			SubjectTemplate: email.SubjectTemplate,
+			BodyTemplate:    email.BodyTemplate,
+		})
+		if err != nil {
+			logger().Warn("Email notifications disabled", "error", err)
+		} else {
+			dispatcher.Add(notifier)
+		}
+	}
+	
+	if alerting := config.Notifications.Alerting; alerting.Enabled {
+		notifier, err := notify.NewAlertNotifier(notify.AlertConfig{
+			Service:    alerting.Service,
+			RoutingKey: alerting.RoutingKey,
+			APIKey:     alerting.APIKey,
+		})
+		if err != nil {
+			logger().Warn("Alerting disabled", "error", err)
+		} else {
+			dispatcher.Add(notifier)
+		}
+	}
+	
+	// Dispatch to notifier plugins that have been configured
+	for id, err := range notifierplugin.Registry.AddTo(dispatcher, config.Notifications.Plugins) {
+		logger().Warn("Notifier plugin not used", "plugin", id, "error", err)
+	}
+	
+	if dispatcher.Count() > 0 {
+		logger().Info("Sending snooze notifications", "destinations", dispatcher.Count())
+		
+		// Persist deliveries so they are retried after outages and restarts
+		queue, err := notify.NewQueue(config.Notifications.QueuePath, config.Notifications.QueueMaxEntries)
+		if err != nil {
+			logger().Warn("Failed to load notification queue", "error", err)
+		}
+		if pending := queue.Len(); pending > 0 {
+			logger().Info("Retrying undelivered notifications from a previous run", "count", pending)
+		}
+		dispatcher.SetQueue(queue)
+		dispatcher.StartRetry(notificationRetryInterval)
+	}
+	return dispatcher
+}
+
+// idleDuration returns how long the system has been idle
+func idleDuration(systemMonitor *monitor.SystemMonitor) time.Duration {
+	if idleSince := systemMonitor.GetIdleSince(); idleSince != nil {
+		return time.Since(*idleSince)
+	}
+	return 0
+}
+
+// newSnoozeEvent builds a snooze event, including instance details when a
+// cloud provider is available
+func newSnoozeEvent(cloudProvider common.CloudProvider, config Config, reason, trigger string, metrics common.SystemMetrics, budgetStatus *schedule.BudgetStatus) *monitor.SnoozeEvent {
+	event := &monitor.SnoozeEvent{
+		Timestamp:   time.Now(),
+		Event:       monitor.EventSnooze,
+		Reason:      reason,
+		Trigger:     trigger,
+		Metrics:     metrics,
+		NaptimeMins: config.NaptimeMinutes,
+	}
+	if action := newIdleAction(config, cloudProvider); action != nil {
+		event.Action = action.Name()
+	}
+	if budgetStatus != nil {
+		event.BudgetUsedMins = budgetStatus.UsedMinutes
+	}
+	
+	if cloudProvider != nil {
+		instanceInfo, err := cloudProvider.GetInstanceInfo()
+		if err != nil {
+			logger().Warn("Failed to get instance info", "error", err)
+		} else {
+			event.InstanceID = instanceInfo.ID
+			event.InstanceType = instanceInfo.Type
+			event.Region = instanceInfo.Region
+		}
+	}
+	
+	return event
+}
+
+// snoozeInstance records a snooze event and runs the snooze pipeline,
+// which ends in the idle action: stopping the instance via the cloud
+// provider unless another is configured
+func snoozeInstance(ctx context.Context, cloudProvider common.CloudProvider, config Config, historyStore *history.Store, notifier *notify.Dispatcher, reason, trigger string, metrics common.SystemMetrics, budgetStatus *schedule.BudgetStatus, idle time.Duration) error {
+	action := newIdleAction(config, cloudProvider)
+	if action == nil {
+		logger().Info("No cloud provider available, would stop instance", "reason", reason)
+		return nil
+	}
+	
+	// Create a snooze event for logging
+	event := newSnoozeEvent(cloudProvider, config, reason, trigger, metrics, budgetStatus)
+	
+	// Log the snooze event; the top-level fields become journald fields
+	// so events can be filtered with e.g. journalctl -t snoozed TRIGGER=idle
+	logger().Info("Snooze event",
+		"reason", event.Reason,
+		"trigger", event.Trigger,
+		"instance_id", event.InstanceID,
+		"instance_type", event.InstanceType,
+		"region", event.Region,
+		"action", event.Action,
+		"event", event)
+	
+	// Record the event in the history before the instance goes away
+	if historyStore != nil {
+		if err := historyStore.Add(*event); err != nil {
+			logger().Warn("Failed to record snooze event", "error", err)
+		}
+	}
+	// and the aggregation server and state table, if there are any
+	pushAggregationNow()
+	recordSnoozeState(ctx, cloudProvider, config, *event)
+	
+	// Trace the snooze, and the provider calls when they take a context
+	ctx, span := telemetry.Tracer().Start(ctx, "snooze", trace.WithAttributes(
+		attribute.String("snooze.reason", reason),
+		attribute.String("snooze.trigger", trigger),
+		attribute.String("instance.id", event.InstanceID),
+	))
+	
+	// Take the instance down step by step, keeping the recorded event up
+	// to date in case a step doesn't return
+	pipeline := newSnoozePipeline(config, cloudProvider, notifier, action, idle)
+	hookRunner := pipeline.hookRunner
+	err := pipeline.run(ctx, event, func() {
+		if historyStore != nil {
+			if err := historyStore.Update(*event); err != nil {
+				logger().Warn("Failed to record snooze steps", "error", err)
+			}
+		}
+	})
+	telemetry.EndSpan(span, err)
+	notification := notify.Notification{
+		Type:         notify.NotificationSnoozed,
+		Event:        *event,
+		IdleDuration: idle,
+		HourlyCost:   config.Notifications.HourlyCostUSD,
+	}
+	if err != nil {
+		logger().Error("Failed to stop instance", "action", event.Action, "error", err)
+		notification.Type = notify.NotificationFailed
+		notification.Error = err.Error()
+		
+		// The instance keeps running, so undo the steps that were run
+		// rather than leave it half taken down
+		pipeline.rollback(ctx)
+		recordStateNow()
+		hookRunner.Fire(ctx, hooks.EventSnoozeFailed, hooks.EventDetails{
+			Reason:     reason,
+			Trigger:    trigger,
+			InstanceID: event.InstanceID,
+			Metrics:    metrics,
+			Error:      err.Error(),
+		})
+	} else {
+		logger().Info("Successfully initiated instance stop", "action", event.Action)
+		hookRunner.Fire(ctx, hooks.EventSnoozed, hooks.EventDetails{
+			Reason:     reason,
+			Trigger:    trigger,
+			InstanceID: event.InstanceID,
+			Metrics:    metrics,
+		})
+	}
+	notifier.Notify(notification)
+	if err != nil {
+		return err
+	}
+	
+	// Actions such as suspend leave the instance running afterwards
+	running, waitErr := action.WaitRunning(ctx)
+	if waitErr != nil {
+		logger().Warn("Failed waiting for the instance to resume", "action", event.Action, "error", waitErr)
+	}
+	if running {
+		pipeline.rollback(ctx)
+		if historyStore != nil && waitErr == nil {
+			recordResume(historyStore, *event)
+		}
+		recordStateNow()
+	}
+	return nil
+}
+
+// recordResume records the instance running again after an action that
+// didn't stop it, such as a suspend
+func recordResume(historyStore *history.Store, snooze monitor.SnoozeEvent) {
+	recorded, err := historyStore.RecordStart(monitor.SnoozeEvent{
+		Timestamp:    time.Now(),
+		InstanceID:   snooze.InstanceID,
+		InstanceType: snooze.InstanceType,
+		Region:       snooze.Region,
+		Reason:       "Instance resumed",
+		Action:       snooze.Action,
+	})
+	if err != nil {
+		logger().Warn("Failed to record instance resume", "error", err)
+	}
+	if recorded != nil {
+		stopped := time.Duration(recorded.StoppedMins * float64(time.Minute))
+		logger().Info("Recorded instance resume", "action", snooze.Action,
+			"stopped_for", stopped.Round(time.Minute).String())
+	}
+}
+
+func registerCommandHandlers(server *api.SocketServer, configPath string, systemMonitor *monitor.SystemMonitor, config Config, cloudProvider common.CloudProvider, scheduler *schedule.Scheduler, historyStore *history.Store, stopCountdown *countdown, maintenance *maintenanceWatcher, leases *leaseManager, dependencies *dependencyChecker, peers *peerChecker, breaker *stopBreaker, auditLog *api.AuditLog, buffers map[string]bufferReporter) {
+	
+	// STATUS command
+	server.RegisterReadOnlyHandler("STATUS", func(params map[string]interface{}) (interface{}, error) {
+		metrics := systemMonitor.GetLastMetrics()
+		
+		var idleSinceStr string
+		if idleSince := systemMonitor.GetIdleSince(); idleSince != nil {
+			idleSinceStr = idleSince.Format(time.RFC3339)
+		}
+		
+		shouldSnooze, reason := systemMonitor.ShouldSnooze()
+		profile, _ := thresholdProfile(config, scheduler, time.Now())
+		
+		// Get runtime budget consumption if a budget is configured
+		var budgetStatus *schedule.BudgetStatus
+		if budget := scheduler.Budget(); budget != nil {
+			status := budget.Status()
+			budgetStatus = &status
+		}
+		
+		// Get instance info if available
+		var instanceInfo *common.InstanceInfo
+		if cloudProvider != nil {
+			instanceInfo, _ = cloudProvider.GetInstanceInfo()
+		}
+		
+		// Uptime since launch
+		var launchStr string
+		var uptime int64
+		if launched := launchTime(cloudProvider); !launched.IsZero() {
+			launchStr = launched.Format(time.RFC3339)
+			uptime = int64(time.Since(launched).Seconds())
+		}
+		
+		// Report whether the configured stop action can be used
+		var stopAction *common.StopActionStatus
+		if localAction(config.StopAction) {
+			stopAction = &common.StopActionStatus{Requested: config.StopAction, Effective: config.StopAction}
+		} else if reporter, ok := cloudProvider.(common.StopActionReporter); ok {
+			status := reporter.StopAction()
+			stopAction = &status
+		}
+		
+		return map[string]interface{}{
+			"metrics":           metrics,
+			"idle_since":        idleSinceStr,
+			"should_snooze":     shouldSnooze,
+			"snooze_reason":     reason,
+			"version":           version,
+			"instance_info":     instanceInfo,
+			"timezone":          scheduler.ZoneName(),
+			"calendar_window":   scheduler.ActiveWindow(),
+			"runtime_budget":    budgetStatus,
+			"countdown":         stopCountdown.Status(),
+			"threshold_profile": profile,
+			"stop_action":       stopAction,
+			"launch_time":       launchStr,
+			"uptime_secs":       uptime,
+			"maintenance_events": maintenance.Events(),
+			"dependents":         dependencies.States(),
+			"peers":              peers.States(),
+			"policy":             activePolicy,
+			"leases":            leases.Active(time.Now()),
+			"stop_breaker":      breaker.Status(),
+			"memory":            memoryStatus(buffers),
+		}, nil
+	})
+	
+	// CANCEL command aborts a pending stop. Any user may cancel, since it
+	// only keeps the instance running.
+	server.RegisterReadOnlyHandler("CANCEL", func(params map[string]interface{}) (interface{}, error) {
+		pending := stopCountdown.Status()
+		if !stopCountdown.Cancel() {
+			return map[string]interface{}{"cancelled": false, "message": "No snooze is pending"}, nil
+		}
+		
+		// Restart the idle timer so the countdown doesn't begin again on the next check
+		systemMonitor.ResetIdleState()
+		logger().Info("Pending snooze cancelled via API", "reason", pending.Reason)
+		
+		return map[string]interface{}{"cancelled": true, "reason": pending.Reason}, nil
+	})
+	
+	// LEASE command keeps the instance running for a number of hours. Like
+	// CANCEL, any user may take one, and the lease records who did.
+	server.RegisterIdentifiedHandler("LEASE", func(client string, params map[string]interface{}) (interface{}, error) {
+		hours, _ := params["hours"].(float64)
+		reason, _ := params["reason"].(string)
+		lease, err := leases.Take(client, reason, hours)
+		if err != nil {
+			return nil, err
+		}
+		// Don't stop on the next check because of idle time before the lease
+		stopCountdown.Cancel()
+		systemMonitor.ResetIdleState()
+		logger().Info("Lease taken", "id", lease.ID, "owner", lease.Owner, "reason", lease.Reason,
+			"expires", lease.ExpiresAt.Format(time.RFC3339))
+		return lease, nil
+	})
+	
+	// RELEASE command ends a lease early, or with "all" every lease the
+	// client took
+	server.RegisterIdentifiedHandler("RELEASE", func(client string, params map[string]interface{}) (interface{}, error) {
+		if all, _ := params["all"].(bool); all {
+			released, err := leases.ReleaseOwned(client)
+			if err != nil {
+				return nil, err
+			}
+			logger().Info("Leases released", "owner", client, "count", len(released))
+			return map[string]interface{}{"released": released}, nil
+		}
+		id, _ := params["id"].(string)
+		if id == "" {
+			return nil, fmt.Errorf("id is required")
+		}
+		lease, err := leases.Release(client, id)
+		if err != nil {
+			return nil, err
+		}
+		logger().Info("Lease released", "id", lease.ID, "owner", lease.Owner, "by", client)
+		return lease, nil
+	})
+	
+	// START_INSTANCE command starts another instance, for fleet controllers
+	// waking the peers they snoozed
+	server.RegisterHandler("START_INSTANCE", func(params map[string]interface{}) (interface{}, error) {
+		instanceID, _ := params["instance_id"].(string)
+		if instanceID == "" {
+			return nil, fmt.Errorf("instance_id is required")
+		}
+		if cloudProvider == nil {
+			return nil, fmt.Errorf("no cloud provider is configured")
+		}
+		if err := cloudProvider.StartInstance(instanceID); err != nil {
+			return nil, fmt.Errorf("failed to start instance: %v", err)
+		}
+		logger().Info("Started instance via API", "instance", instanceID)
+		
+		return map[string]interface{}{"instance_id": instanceID, "starting": true}, nil
+	})
+	
+	// HEALTH command reports problems in daemon components
+	server.RegisterReadOnlyHandler("HEALTH", func(params map[string]interface{}) (interface{}, error) {
+		return map[string]interface{}{
+			"status": health.Default.Overall(),
+			"checks": health.Default.Checks(),
 		}, nil
 	})
 	
-	// CONFIG_GET command
+	// RUNTIME_STATS command reports goroutines, heap and GC statistics
+	server.RegisterReadOnlyHandler("RUNTIME_STATS", func(params map[string]interface{}) (interface{}, error) {
+		return runtimeStats(), nil
+	})
+	
+	// CONFIG_GET command, with secrets redacted
 	server.RegisterHandler("CONFIG_GET", func(params map[string]interface{}) (interface{}, error) {
+		return redactedConfig(config)
+	})
+	
+	// CONFIG_GET_SECRETS command returns the configuration with its secrets
+	server.RegisterRootHandler("CONFIG_GET_SECRETS", func(params map[string]interface{}) (interface{}, error) {
 		return config, nil
 	})
 	
 	// CONFIG_SET command - placeholder
 	server.RegisterHandler("CONFIG_SET", func(params map[string]interface{}) (interface{}, error) {
-		// TODO: Implement configuration updates
-		return map[string]interface{}{"updated": false, "message": "Not implemented yet"}, nil
+		name, _ := params["name"].(string)
+		value, _ := params["value"].(string)
+		
+		// Settings that can be applied without a restart
+		switch name {
+		case "logging.log_level":
+			if err := changeLogLevel(value); err != nil {
+				return nil, err
+			}
+			config.Logging.LogLevel = logging.Level()
+			if err := updateConfigFile(configPath, name, config.Logging.LogLevel); err != nil {
+				return nil, fmt.Errorf("log level changed but not saved: %v", err)
+			}
+			return map[string]interface{}{"updated": true, "applied": true}, nil
+		default:
+			return nil, fmt.Errorf("setting %s can't be changed at runtime", name)
+		}
+	})
+	
+	// LOG_LEVEL command reports or changes the log level until the next restart
+	server.RegisterHandler("LOG_LEVEL", func(params map[string]interface{}) (interface{}, error) {
+		previous := logging.Level()
+		if value, ok := params["level"].(string); ok && value != "" {
+			if err := changeLogLevel(value); err != nil {
+				return nil, err
+			}
+		}
+		return map[string]interface{}{
+			"level":    logging.Level(),
+			"previous": previous,
+		}, nil
 	})
 	
-	// HISTORY command - placeholder
-	server.RegisterHandler("HISTORY", func(params map[string]interface{}) (interface{}, error) {
-		// TODO: Implement history retrieval
-		return []interface{}{}, nil
+	// HISTORY command
+	server.RegisterReadOnlyHandler("HISTORY", func(params map[string]interface{}) (interface{}, error) {
+		limit := 10
+		if value, ok := params["limit"].(float64); ok {
+			limit = int(value)
+		}
+		
+		var since time.Time
+		if value, ok := params["since"].(string); ok && value != "" {
+			parsed, err := parseSince(value, scheduler.Location())
+			if err != nil {
+				return nil, err
+			}
+			since = parsed
+		}
+		
+		return historyStore.List(limit, since), nil
 	})
 	
+	// DECISIONS command
+	server.RegisterReadOnlyHandler("DECISIONS", func(params map[string]interface{}) (interface{}, error) {
+		decisions := systemMonitor.Decisions()
+		if decisions == nil {
+			return []monitor.Decision{}, nil
+		}
+		
+		limit := 20
+		if value, ok := params["limit"].(float64); ok {
+			limit = int(value)
+		}
+		
+		var since time.Time
+		if value, ok := params["since"].(string); ok && value != "" {
+			parsed, err := parseSince(value, scheduler.Location())
+			if err != nil {
+				return nil, err
+			}
+			since = parsed
+		}
+		
+		return decisions.List(limit, since), nil
+	})
+	
+	// AUDIT command
+	server.RegisterHandler("AUDIT", func(params map[string]interface{}) (interface{}, error) {
+		limit := 50
+		if value, ok := params["limit"].(float64); ok {
+			limit = int(value)
+		}
+		command, _ := params["command"].(string)
+		return auditLog.Recent(limit, command), nil
+	})
+}
+
+// registerPluginHandlers registers the plugin management commands. Enabled
+// state is saved to the configuration file at configPath.
+func registerPluginHandlers(server *api.SocketServer, configPath string, config Config, activeProvider string) {
+	
 	// PLUGINS_LIST command
-	server.RegisterHandler("PLUGINS_LIST", func(params map[string]interface{}) (interface{}, error) {
-		providers := cloudplugin.Registry.GetAllProviders()
+	server.RegisterReadOnlyHandler("PLUGINS_LIST", func(params map[string]interface{}) (interface{}, error) {
+		plugins := plugin.Registry.GetAll()
 		
 		var result []map[string]interface{}
-		for _, p := range providers {
+		for _, p := range plugins {
 			info := p.Info()
 			result = append(result, map[string]interface{}{
 				"id":           info.ID,
@@ -382,9 +2001,131 @@ func registerCommandHandlers(server *api.SocketServer, systemMonitor *monitor.Sy
 				"author":       info.Author,
 				"website":      info.Website,
 				"is_running":   p.IsRunning(),
+				"enabled":      plugin.Registry.IsEnabled(info.ID),
 			})
 		}
 		
 		return result, nil
 	})
-}
\ No newline at end of file
+	
+	// PLUGIN_INFO command
+	server.RegisterReadOnlyHandler("PLUGIN_INFO", func(params map[string]interface{}) (interface{}, error) {
+		p, err := findPlugin(params)
+		if err != nil {
+			return nil, err
+		}
+		
+		info := p.Info()
+		return map[string]interface{}{
+			"id":           info.ID,
+			"name":         info.Name,
+			"type":         info.Type,
+			"version":      info.Version,
+			"capabilities": info.Capabilities,
+			"author":       info.Author,
+			"website":      info.Website,
+			"dependencies": info.Dependencies,
+			"exec":         info.Exec,
+			"is_running":   p.IsRunning(),
+			"enabled":      plugin.Registry.IsEnabled(info.ID),
+			"active":       info.ID == activeProvider,
+		}, nil
+	})
+	
+	// PLUGIN_ENABLE and PLUGIN_DISABLE commands
+	setEnabled := func(params map[string]interface{}, enabled bool) (interface{}, error) {
+		p, err := findPlugin(params)
+		if err != nil {
+			return nil, err
+		}
+		
+		id := p.Info().ID
+		if !enabled && id == activeProvider {
+			return nil, fmt.Errorf("plugin %s is the active cloud provider and cannot be disabled", id)
+		}
+		
+		previous := plugin.Registry.IsEnabled(id)
+		plugin.Registry.SetEnabled(id, enabled)
+		if err := updateConfigFile(configPath, "disabled_plugins", plugin.Registry.Disabled()); err != nil {
+			plugin.Registry.SetEnabled(id, previous)
+			return nil, fmt.Errorf("failed to save configuration: %v", err)
+		}
+		logger().Info("Plugin enabled state changed via API", "plugin", id, "enabled", enabled)
+		
+		// Plugins already in use keep running until the daemon restarts
+		return map[string]interface{}{
+			"id":               id,
+			"enabled":          enabled,
+			"restart_required": p.IsRunning() || enabled,
+		}, nil
+	}
+	server.RegisterHandler("PLUGIN_ENABLE", func(params map[string]interface{}) (interface{}, error) {
+		return setEnabled(params, true)
+	})
+	server.RegisterHandler("PLUGIN_DISABLE", func(params map[string]interface{}) (interface{}, error) {
+		return setEnabled(params, false)
+	})
+	
+	// PLUGIN_INSTALL command downloads a plugin into the plugins directory
+	server.RegisterHandler("PLUGIN_INSTALL", func(params map[string]interface{}) (interface{}, error) {
+		url, _ := params["url"].(string)
+		if url == "" {
+			return nil, fmt.Errorf("missing plugin url")
+		}
+		if !config.PluginsEnabled || config.PluginsDir == "" {
+			return nil, fmt.Errorf("external plugins are disabled in the configuration")
+		}
+		checksum, _ := params["sha256"].(string)
+		
+		manifestPath, err := plugin.Install(url, config.PluginsDir, checksum)
+		if err != nil {
+			return nil, err
+		}
+		
+		p, err := plugin.LoadPluginFromManifest(manifestPath)
+		if err == nil {
+			err = plugin.Registry.Register(p)
+		}
+		if err != nil {
+			os.RemoveAll(filepath.Dir(manifestPath))
+			return nil, fmt.Errorf("failed to load installed plugin: %v", err)
+		}
+		applyPluginLimits(config.PluginLimits)
+		
+		info := p.Info()
+		logger().Info("Installed plugin", "plugin", info.ID, "version", info.Version, "url", url)
+		return map[string]interface{}{
+			"id":      info.ID,
+			"name":    info.Name,
+			"type":    info.Type,
+			"version": info.Version,
+		}, nil
+	})
+}
+
+// findPlugin returns the registered plugin named by the "id" parameter,
+// whether or not it is enabled
+func findPlugin(params map[string]interface{}) (plugin.Plugin, error) {
+	id, _ := params["id"].(string)
+	if id == "" {
+		return nil, fmt.Errorf("missing plugin id")
+	}
+	
+	for _, p := range plugin.Registry.GetAll() {
+		if p.Info().ID == id {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("plugin %s not found", id)
+}
+
+// parseSince parses a HISTORY "since" parameter given as RFC 3339 or a plain date
+func parseSince(value string, location *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid since value %q, expected RFC 3339 or YYYY-MM-DD", value)
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// maintenanceWatcher polls the cloud provider for scheduled maintenance of
+// the instance. A stop shortly before a scheduled reboot or retirement can
+// interfere with it, so snoozing is held off during the guard period.
+type maintenanceWatcher struct {
+	reporter common.MaintenanceReporter
+	interval time.Duration
+	guard    time.Duration
+	events   []common.MaintenanceEvent
+	seen     map[string]bool
+	lastPoll time.Time
+	lock     sync.RWMutex
+}
+
+// newMaintenanceWatcher creates a watcher, or returns nil if it is disabled
+// or the provider doesn't report maintenance
+func newMaintenanceWatcher(cloudProvider common.CloudProvider, config MaintenanceConfig) *maintenanceWatcher {
+	reporter, ok := cloudProvider.(common.MaintenanceReporter)
+	if !ok || !config.Enabled {
+		return nil
+	}
+	interval := time.Duration(config.PollMinutes) * time.Minute
+	if interval <= 0 {
+		interval = 15 * time.Minute
+	}
+	return &maintenanceWatcher{
+		reporter: reporter,
+		interval: interval,
+		guard:    time.Duration(config.GuardMinutes) * time.Minute,
+		seen:     make(map[string]bool),
+	}
+}
+
+// Poll refreshes the scheduled events if the poll interval has passed. It
+// returns the events that weren't scheduled at the previous poll.
+func (w *maintenanceWatcher) Poll(now time.Time) []common.MaintenanceEvent {
+	if w == nil || now.Sub(w.lastPoll) < w.interval {
+		return nil
+	}
+	w.lastPoll = now
+
+	events, err := w.reporter.ScheduledEvents()
+	if err != nil {
+		logger().Warn("Failed to get scheduled maintenance events", "error", err)
+		return nil
+	}
+
+	var scheduled []common.MaintenanceEvent
+	w.lock.Lock()
+	w.events = events
+	for _, event := range events {
+		key := event.ID + event.Code + event.NotBefore.String()
+		if !w.seen[key] {
+			w.seen[key] = true
+			scheduled = append(scheduled, event)
+		}
+	}
+	w.lock.Unlock()
+
+	for _, event := range scheduled {
+		logger().Warn("Maintenance scheduled for instance",
+			"code", event.Code, "description", event.Description, "not_before", event.NotBefore.Format(time.RFC3339))
+	}
+	return scheduled
+}
+
+// Events returns the scheduled maintenance events
+func (w *maintenanceWatcher) Events() []common.MaintenanceEvent {
+	if w == nil {
+		return nil
+	}
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	return w.events
+}
+
+// Blocking returns the event whose guard period or window includes now,
+// during which the instance shouldn't be snoozed
+func (w *maintenanceWatcher) Blocking(now time.Time) *common.MaintenanceEvent {
+	if w == nil || w.guard <= 0 {
+		return nil
+	}
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+
+	for i, event := range w.events {
+		end := event.NotAfter
+		if end.IsZero() {
+			end = event.NotBefore
+		}
+		if !now.Before(event.NotBefore.Add(-w.guard)) && now.Before(end) {
+			return &w.events[i]
+		}
+	}
+	return nil
+}
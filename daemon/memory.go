@@ -0,0 +1,54 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"runtime"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// bufferReporter is an in-memory buffer with a fixed capacity
+type bufferReporter interface {
+	Usage() (int, int)
+}
+
+// BufferStatus is how full an in-memory buffer is
+type BufferStatus struct {
+	Entries  int `json:"entries"`
+	Capacity int `json:"capacity"`
+}
+
+// MemoryStatus reports the daemon's own memory use
+type MemoryStatus struct {
+	RSSBytes   uint64                  `json:"rss_bytes,omitempty"` // Omitted where the platform doesn't report it
+	HeapBytes  uint64                  `json:"heap_bytes"`
+	SysBytes   uint64                  `json:"sys_bytes"`
+	Goroutines int                     `json:"goroutines"`
+	Buffers    map[string]BufferStatus `json:"buffers"`
+}
+
+// memoryStatus collects the daemon's memory use and how full its buffers are
+func memoryStatus(buffers map[string]bufferReporter) MemoryStatus {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	status := MemoryStatus{
+		HeapBytes:  stats.HeapAlloc,
+		SysBytes:   stats.Sys,
+		Goroutines: runtime.NumGoroutine(),
+		Buffers:    make(map[string]BufferStatus, len(buffers)),
+	}
+	if proc, err := process.NewProcess(int32(os.Getpid())); err == nil {
+		if info, err := proc.MemoryInfo(); err == nil {
+			status.RSSBytes = info.RSS
+		}
+	}
+	for name, buffer := range buffers {
+		entries, capacity := buffer.Usage()
+		status.Buffers[name] = BufferStatus{Entries: entries, Capacity: capacity}
+	}
+	return status
+}
@@ -0,0 +1,35 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import "time"
+
+// clockJumpThreshold is how far the wall clock may drift from the monotonic
+// clock between checks before it is reported
+const clockJumpThreshold = time.Minute
+
+// checkClock accounts for time the daemon couldn't observe since the last
+// check. Idle time is measured with the monotonic clock, so NTP steps don't
+// affect it, but a suspend or a stalled daemon leaves a gap in which the
+// system may have been used. Gaps longer than two check intervals are not
+// counted as idle.
+func (m *SystemMonitor) checkClock(now time.Time) {
+	if m.lastCheck.IsZero() {
+		return
+	}
+
+	elapsed := now.Sub(m.lastCheck)
+	wallElapsed := now.Round(0).Sub(m.lastCheck.Round(0))
+	if jump := wallElapsed - elapsed; jump > clockJumpThreshold || jump < -clockJumpThreshold {
+		logger().Info("System clock jumped or the system was suspended", "wall_clock_change", jump.Round(time.Second).String())
+	}
+
+	maxGap := 2 * time.Duration(m.checkIntervalMs) * time.Millisecond
+	if m.idleSince == nil || maxGap <= 0 || elapsed <= maxGap {
+		return
+	}
+	since := m.idleSince.Add(elapsed - maxGap)
+	m.idleSince = &since
+	logger().Info("Not counting gap between checks as idle time", "gap", elapsed.Round(time.Second).String())
+}
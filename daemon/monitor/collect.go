@@ -0,0 +1,111 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/health"
+)
+
+// DefaultCollectTimeout bounds how long a single monitor may take to collect
+const DefaultCollectTimeout = 10 * time.Second
+
+// pending is a collection running in its own goroutine
+type pending[T any] struct {
+	name  string
+	done  chan struct{}
+	value T
+	err   error
+}
+
+// collectAsync starts a monitor's collection. A monitor whose collection from
+// an earlier check is still running isn't started again, so a hung tool
+// doesn't pile up goroutines; it fails until the stuck call returns.
+func collectAsync[T any](m *SystemMonitor, name string, collect func() (T, error)) *pending[T] {
+	p := &pending[T]{name: name, done: make(chan struct{})}
+
+	m.collectLock.Lock()
+	if m.collecting == nil {
+		m.collecting = make(map[string]time.Time)
+	}
+	if started, ok := m.collecting[name]; ok {
+		m.collectLock.Unlock()
+		p.err = fmt.Errorf("still collecting after %s", time.Since(started).Round(time.Second))
+		close(p.done)
+		return p
+	}
+	started := time.Now()
+	m.collecting[name] = started
+	m.collectLock.Unlock()
+
+	go func() {
+		defer close(p.done)
+		p.value, p.err = collect()
+
+		m.collectLock.Lock()
+		defer m.collectLock.Unlock()
+		delete(m.collecting, name)
+		if m.slow[name] {
+			delete(m.slow, name)
+			took := time.Since(started).Round(time.Second)
+			logger().Info("Slow monitor finished collecting", "monitor", name, "took", took)
+			health.Set(healthComponent(name), health.OK, fmt.Sprintf("recovered, last collection took %s", took))
+		}
+	}()
+	return p
+}
+
+// wait returns the result of the collection, or an error if it doesn't
+// finish by the deadline, in which case the monitor is reported as degraded
+func (p *pending[T]) wait(m *SystemMonitor, deadline time.Time) (T, error) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case <-p.done:
+		return p.value, p.err
+	case <-timer.C:
+	}
+
+	var zero T
+	m.collectLock.Lock()
+	defer m.collectLock.Unlock()
+	if _, running := m.collecting[p.name]; !running {
+		// Finished just as the deadline passed
+		<-p.done
+		return p.value, p.err
+	}
+	if m.slow == nil {
+		m.slow = make(map[string]bool)
+	}
+	if !m.slow[p.name] {
+		m.slow[p.name] = true
+		logger().Warn("Monitor timed out collecting metrics", "monitor", p.name, "timeout", m.collectTimeout())
+		health.Set(healthComponent(p.name), health.Degraded,
+			fmt.Sprintf("collection timed out after %s", m.collectTimeout()))
+	}
+	return zero, fmt.Errorf("timed out after %s", m.collectTimeout())
+}
+
+// SetCollectTimeout sets how long each monitor may take to collect
+func (m *SystemMonitor) SetCollectTimeout(timeout time.Duration) {
+	m.collectLock.Lock()
+	defer m.collectLock.Unlock()
+	m.timeout = timeout
+}
+
+// collectTimeout returns the per-monitor timeout. The caller holds collectLock.
+func (m *SystemMonitor) collectTimeout() time.Duration {
+	if m.timeout <= 0 {
+		return DefaultCollectTimeout
+	}
+	return m.timeout
+}
+
+// healthComponent names a monitor in the health checks
+func healthComponent(name string) string {
+	return "monitor:" + name
+}
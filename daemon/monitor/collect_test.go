@@ -0,0 +1,63 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/health"
+)
+
+func healthState(component string) health.State {
+	for _, check := range health.Default.Checks() {
+		if check.Component == component {
+			return check.State
+		}
+	}
+	return ""
+}
+
+func TestCollectTimeout(t *testing.T) {
+	m := &SystemMonitor{}
+	m.SetCollectTimeout(20 * time.Millisecond)
+	defer health.Default.Remove("monitor:test")
+
+	release := make(chan struct{})
+	hung := func() (float64, error) {
+		<-release
+		return 1, nil
+	}
+
+	// A hung monitor times out and is reported as degraded
+	if _, err := collectAsync(m, "test", hung).wait(m, time.Now().Add(20*time.Millisecond)); err == nil {
+		t.Fatal("Expected a hung monitor to time out")
+	}
+	if state := healthState("monitor:test"); state != health.Degraded {
+		t.Errorf("Expected monitor to be degraded, got %q", state)
+	}
+
+	// It isn't started again while the earlier call is stuck
+	started := false
+	again := func() (float64, error) {
+		started = true
+		return 2, nil
+	}
+	if _, err := collectAsync(m, "test", again).wait(m, time.Now().Add(time.Second)); err == nil || started {
+		t.Error("Expected a stuck monitor not to be started again")
+	}
+
+	// Once the stuck call returns the monitor recovers
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for healthState("monitor:test") != health.OK && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if state := healthState("monitor:test"); state != health.OK {
+		t.Errorf("Expected monitor to recover, got %q", state)
+	}
+	if value, err := collectAsync(m, "test", again).wait(m, time.Now().Add(time.Second)); err != nil || value != 2 {
+		t.Errorf("Expected 2, got %v (error %v)", value, err)
+	}
+}
@@ -12,34 +12,62 @@ import (
 // CPUMonitor handles CPU usage monitoring
 type CPUMonitor struct {
 	lastCheckTime time.Time
+	lastBusy      float64
+	lastTotal     float64
 	lastUsage     float64
 }
 
 // NewCPUMonitor creates a new CPU monitor
 func NewCPUMonitor() *CPUMonitor {
-	return &CPUMonitor{
+	m := &CPUMonitor{
 		lastCheckTime: time.Now(),
 	}
+	// Get initial times, starting from the average since boot
+	if times, err := cpu.Times(false); err == nil && len(times) > 0 {
+		m.lastBusy, m.lastTotal = cpuBusy(times[0])
+		if m.lastTotal > 0 {
+			m.lastUsage = m.lastBusy / m.lastTotal * 100
+		}
+	}
+	return m
+}
+
+// cpuBusy returns the busy and total CPU seconds in times
+func cpuBusy(times cpu.TimesStat) (busy, total float64) {
+	total = times.User + times.System + times.Idle + times.Nice + times.Iowait +
+		times.Irq + times.Softirq + times.Steal
+	return total - times.Idle - times.Iowait, total
 }
 
-// GetUsage returns the current CPU usage percentage
+// GetUsage returns the CPU usage percentage since the last check. Usage is
+// worked out from the CPU times rather than by sampling, so checks don't
+// block.
 func (m *CPUMonitor) GetUsage() (float64, error) {
-	// Get CPU usage over a short interval (100ms)
-	percentages, err := cpu.Percent(100*time.Millisecond, false)
+	times, err := cpu.Times(false)
 	if err != nil {
 		return 0, err
 	}
+	if len(times) == 0 {
+		return m.lastUsage, nil
+	}
 
-	// Get the average across all CPUs
-	var total float64
-	for _, p := range percentages {
-		total += p
+	busy, total := cpuBusy(times[0])
+	if total-m.lastTotal <= 0 {
+		return m.lastUsage, nil // Return last value if no time has been counted
+	}
+
+	usage := (busy - m.lastBusy) / (total - m.lastTotal) * 100
+	if usage < 0 {
+		usage = 0
+	} else if usage > 100 {
+		usage = 100
 	}
-	avgUsage := total / float64(len(percentages))
 
 	// Update last check data
 	m.lastCheckTime = time.Now()
-	m.lastUsage = avgUsage
+	m.lastBusy = busy
+	m.lastTotal = total
+	m.lastUsage = usage
 
-	return avgUsage, nil
-}
\ No newline at end of file
+	return usage, nil
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultDecisionLogSize keeps a day of decisions at the default check interval
+const DefaultDecisionLogSize = 1440
+
+// MetricCheck is the comparison of one metric with its threshold
+type MetricCheck struct {
+	Metric    string  `json:"metric"`
+	Device    string  `json:"device,omitempty"` // GPU ID for per-device metrics
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Idle      bool    `json:"idle"`            // True if the metric allows the system to be idle
+	Error     string  `json:"error,omitempty"` // Why the metric couldn't be collected
+}
+
+// Decision explains one evaluation of the system: every metric compared
+// with its threshold, the resulting idle state, and what the daemon did
+type Decision struct {
+	Timestamp      time.Time     `json:"timestamp"`
+	Checks         []MetricCheck `json:"checks"`
+	Idle           bool          `json:"idle"`
+	IdleSeconds    int64         `json:"idle_seconds"`
+	NaptimeMinutes int           `json:"naptime_minutes"`
+	Outcome        string        `json:"outcome"`
+	Reason         string        `json:"reason,omitempty"`
+}
+
+// DecisionLog keeps the most recent decisions in memory
+type DecisionLog struct {
+	decisions []Decision
+	next      int
+	full      bool
+	lock      sync.RWMutex
+}
+
+// NewDecisionLog creates a log holding the last size decisions
+func NewDecisionLog(size int) *DecisionLog {
+	if size <= 0 {
+		size = DefaultDecisionLogSize
+	}
+	return &DecisionLog{
+		decisions: make([]Decision, size),
+	}
+}
+
+// Add records a decision, dropping the oldest if the log is full
+func (l *DecisionLog) Add(decision Decision) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.decisions[l.next] = decision
+	l.next = (l.next + 1) % len(l.decisions)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Usage returns the number of decisions held and the most that are kept
+func (l *DecisionLog) Usage() (int, int) {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	if l.full {
+		return len(l.decisions), len(l.decisions)
+	}
+	return l.next, len(l.decisions)
+}
+
+// List returns up to limit decisions made at or after since, newest first.
+// A limit of 0 returns all of them.
+func (l *DecisionLog) List(limit int, since time.Time) []Decision {
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.decisions)
+	}
+
+	result := make([]Decision, 0)
+	for i := 0; i < count; i++ {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		decision := l.decisions[(l.next-1-i+len(l.decisions))%len(l.decisions)]
+		if !since.IsZero() && decision.Timestamp.Before(since) {
+			break
+		}
+		result = append(result, decision)
+	}
+	return result
+}
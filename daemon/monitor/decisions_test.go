@@ -0,0 +1,60 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecisionLog(t *testing.T) {
+	log := NewDecisionLog(3)
+	start := time.Date(2025, 5, 1, 22, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		log.Add(Decision{Timestamp: start.Add(time.Duration(i) * time.Minute), IdleSeconds: int64(i)})
+	}
+
+	// Only the last three decisions are kept, newest first
+	decisions := log.List(0, time.Time{})
+	if len(decisions) != 3 {
+		t.Fatalf("Expected 3 decisions, got %d", len(decisions))
+	}
+	for i, expected := range []int64{4, 3, 2} {
+		if decisions[i].IdleSeconds != expected {
+			t.Errorf("Decision %d: expected %d, got %d", i, expected, decisions[i].IdleSeconds)
+		}
+	}
+
+	if decisions := log.List(1, time.Time{}); len(decisions) != 1 {
+		t.Errorf("Expected limit to return 1 decision, got %d", len(decisions))
+	}
+	if decisions := log.List(0, start.Add(3*time.Minute)); len(decisions) != 2 {
+		t.Errorf("Expected 2 decisions since minute 3, got %d", len(decisions))
+	}
+}
+
+func TestRecordDecision(t *testing.T) {
+	m := NewSystemMonitor(10, 30, 50, 100, 5, 0, 30, 60000, false)
+	m.SetDecisionLog(NewDecisionLog(10))
+	m.lastChecks = []MetricCheck{
+		{Metric: "cpu_percent", Value: 42, Threshold: 10, Idle: false},
+		{Metric: "memory_percent", Value: 12, Threshold: 30, Idle: true},
+	}
+
+	decision := m.RecordDecision("active", "System is not idle")
+	if decision.Idle || decision.Outcome != "active" || len(decision.Checks) != 2 {
+		t.Errorf("Unexpected decision: %+v", decision)
+	}
+
+	since := time.Now().Add(-10 * time.Minute)
+	m.idleSince = &since
+	decision = m.RecordDecision("idle", "")
+	if !decision.Idle || decision.IdleSeconds < 599 || decision.NaptimeMinutes != 30 {
+		t.Errorf("Unexpected idle decision: %+v", decision)
+	}
+
+	if decisions := m.Decisions().List(0, time.Time{}); len(decisions) != 2 {
+		t.Errorf("Expected 2 recorded decisions, got %d", len(decisions))
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// FailurePolicy decides how a metric that couldn't be collected is counted,
+// so that broken monitoring never makes the system look idle
+type FailurePolicy string
+
+const (
+	// FailureBusy counts a metric that couldn't be collected as busy
+	FailureBusy FailurePolicy = "busy"
+
+	// FailureLastValue keeps using the last collected value until it is
+	// older than the staleness bound, then counts the metric as busy
+	FailureLastValue FailurePolicy = "last_value"
+
+	// DefaultStaleAfter is how long a last collected value stays usable
+	DefaultStaleAfter = 5 * time.Minute
+)
+
+// reading is the last successfully collected value of a metric
+type reading struct {
+	value float64
+	at    time.Time
+}
+
+// gpuReading is the last successfully collected set of GPU metrics
+type gpuReading struct {
+	metrics []common.GPUMetrics
+	at      time.Time
+}
+
+// SetFailurePolicy sets how metrics that fail to collect are counted. With
+// FailureLastValue, staleAfter bounds how old a reused value may be.
+func (m *SystemMonitor) SetFailurePolicy(policy FailurePolicy, staleAfter time.Duration) {
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+	m.failurePolicy = policy
+	m.staleAfter = staleAfter
+}
+
+// sample remembers a collected value, or resolves a failed collection by the
+// failure policy. It returns the collection error if there is no usable
+// value, in which case the metric counts as busy.
+func (m *SystemMonitor) sample(metric string, value float64, err error, now time.Time) (float64, error) {
+	if err == nil {
+		if m.lastGood == nil {
+			m.lastGood = make(map[string]reading)
+		}
+		m.lastGood[metric] = reading{value: value, at: now}
+		return value, nil
+	}
+
+	last, ok := m.lastGood[metric]
+	if !m.reuse(last.at, ok, now) {
+		logger().Warn("Metric collection failed, counting it as busy", "metric", metric, "error", err)
+		return 0, err
+	}
+	logger().Warn("Metric collection failed, using last value", "metric", metric,
+		"age", now.Sub(last.at).Round(time.Second), "error", err)
+	return last.value, nil
+}
+
+// sampleGPU is sample for the per-device GPU metrics
+func (m *SystemMonitor) sampleGPU(metrics []common.GPUMetrics, err error, now time.Time) ([]common.GPUMetrics, error) {
+	if err == nil {
+		m.lastGPU = gpuReading{metrics: metrics, at: now}
+		return metrics, nil
+	}
+
+	if !m.reuse(m.lastGPU.at, m.lastGPU.metrics != nil, now) {
+		logger().Warn("GPU metric collection failed, counting GPUs as busy", "error", err)
+		return nil, err
+	}
+	logger().Warn("GPU metric collection failed, using last values",
+		"age", now.Sub(m.lastGPU.at).Round(time.Second), "error", err)
+	return m.lastGPU.metrics, nil
+}
+
+// reuse returns true if a value collected at the given time may stand in
+// for one that failed to collect
+func (m *SystemMonitor) reuse(at time.Time, ok bool, now time.Time) bool {
+	return ok && m.failurePolicy == FailureLastValue && now.Sub(at) <= m.staleAfter
+}
+
+// failedCheck is the check of a metric that couldn't be collected, which
+// keeps the system from counting as idle
+func failedCheck(metric string, threshold float64, err error) MetricCheck {
+	return MetricCheck{Metric: metric, Threshold: threshold, Idle: false, Error: err.Error()}
+}
@@ -0,0 +1,52 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+func TestFailurePolicy(t *testing.T) {
+	failure := errors.New("query failed")
+	now := time.Now()
+
+	// By default a failed metric has no value, so it counts as busy
+	m := &SystemMonitor{}
+	m.sample("cpu_percent", 3, nil, now)
+	if _, err := m.sample("cpu_percent", 0, failure, now.Add(time.Minute)); err == nil {
+		t.Error("Expected a failed metric to have no value by default")
+	}
+	if _, err := m.sampleGPU(nil, failure, now); err == nil {
+		t.Error("Expected failed GPU metrics to have no value by default")
+	}
+
+	// The last value stands in until it is stale
+	m.SetFailurePolicy(FailureLastValue, 5*time.Minute)
+	if value, err := m.sample("cpu_percent", 0, failure, now.Add(time.Minute)); err != nil || value != 3 {
+		t.Errorf("Expected the last value 3, got %v (error %v)", value, err)
+	}
+	if _, err := m.sample("cpu_percent", 0, failure, now.Add(6*time.Minute)); err == nil {
+		t.Error("Expected a stale value not to be used")
+	}
+	if _, err := m.sample("memory_percent", 0, failure, now); err == nil {
+		t.Error("Expected a metric never collected to have no value")
+	}
+
+	gpus := []common.GPUMetrics{{ID: "0", Utilization: 1}}
+	m.sampleGPU(gpus, nil, now)
+	if metrics, err := m.sampleGPU(nil, failure, now.Add(time.Minute)); err != nil || len(metrics) != 1 {
+		t.Errorf("Expected the last GPU metrics, got %v (error %v)", metrics, err)
+	}
+	if _, err := m.sampleGPU(nil, failure, now.Add(6*time.Minute)); err == nil {
+		t.Error("Expected stale GPU metrics not to be used")
+	}
+
+	if check := failedCheck("gpu_percent", 5, failure); check.Idle || check.Error != "query failed" {
+		t.Errorf("Expected a busy check with the error, got %+v", check)
+	}
+}
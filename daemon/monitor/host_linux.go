@@ -0,0 +1,63 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// HostPaths are where the host's filesystems are mounted in a container
+type HostPaths struct {
+	Proc string
+	Sys  string
+	Dev  string // Empty to not watch input
+}
+
+// WatchHost makes the monitors watch the host the daemon's container runs
+// on rather than the container. It must be called before any monitor is
+// created.
+func WatchHost(paths HostPaths) error {
+	if _, err := os.Stat(filepath.Join(paths.Proc, "stat")); err != nil {
+		return fmt.Errorf("host /proc isn't mounted at %s: %v", paths.Proc, err)
+	}
+	if _, err := os.Stat(filepath.Join(paths.Sys, "block")); err != nil {
+		return fmt.Errorf("host /sys isn't mounted at %s: %v", paths.Sys, err)
+	}
+
+	// CPU, memory and disk usage are read through gopsutil, which looks here
+	os.Setenv("HOST_PROC", paths.Proc)
+	os.Setenv("HOST_SYS", paths.Sys)
+
+	// /proc/net belongs to the reader's network namespace, so the host's
+	// traffic is read from that of its init process
+	netDev := filepath.Join(paths.Proc, "1", "net", "dev")
+	netCounters = func() ([]net.IOCountersStat, error) {
+		return net.IOCountersByFile(false, netDev)
+	}
+
+	// Desktop sessions' buses can't be reached from a container
+	sessionBusPattern = ""
+	if paths.Dev == "" {
+		terminalPatterns = nil
+		eventDevicePattern = ""
+		return nil
+	}
+	patterns := make([]string, len(terminalPatterns))
+	for i, pattern := range terminalPatterns {
+		patterns[i] = hostDevice(paths.Dev, pattern)
+	}
+	terminalPatterns = patterns
+	eventDevicePattern = hostDevice(paths.Dev, eventDevicePattern)
+	return nil
+}
+
+// hostDevice returns where a path under /dev is in the host's /dev at dev
+func hostDevice(dev, path string) string {
+	return filepath.Join(dev, strings.TrimPrefix(path, "/dev/"))
+}
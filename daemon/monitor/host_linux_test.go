@@ -0,0 +1,51 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+func TestWatchHost(t *testing.T) {
+	defer func(terminals []string, devices, buses string, counters func() ([]net.IOCountersStat, error)) {
+		terminalPatterns, eventDevicePattern, sessionBusPattern, netCounters = terminals, devices, buses, counters
+	}(terminalPatterns, eventDevicePattern, sessionBusPattern, netCounters)
+	t.Setenv("HOST_PROC", "")
+	t.Setenv("HOST_SYS", "")
+
+	dir := t.TempDir()
+	paths := HostPaths{Proc: filepath.Join(dir, "proc"), Sys: filepath.Join(dir, "sys"), Dev: filepath.Join(dir, "dev")}
+	if err := WatchHost(paths); err == nil {
+		t.Fatal("Expected an error without the host's /proc")
+	}
+
+	os.MkdirAll(filepath.Join(paths.Proc, "1", "net"), 0755)
+	os.MkdirAll(filepath.Join(paths.Sys, "block"), 0755)
+	os.WriteFile(filepath.Join(paths.Proc, "stat"), nil, 0644)
+	os.WriteFile(filepath.Join(paths.Proc, "1", "net", "dev"), []byte(
+		"Inter-|   Receive                                                |  Transmit\n"+
+			" face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed\n"+
+			"  eth0: 1000 10 0 0 0 0 0 0 2000 20 0 0 0 0 0 0\n"), 0644)
+	if err := WatchHost(paths); err != nil {
+		t.Fatalf("WatchHost returned error: %v", err)
+	}
+
+	if os.Getenv("HOST_PROC") != paths.Proc || os.Getenv("HOST_SYS") != paths.Sys {
+		t.Error("Expected gopsutil to read the host's /proc and /sys")
+	}
+	counters, err := netCounters()
+	if err != nil || len(counters) != 1 || counters[0].BytesRecv != 1000 || counters[0].BytesSent != 2000 {
+		t.Errorf("Expected the host's network traffic, got %v, %v", counters, err)
+	}
+	if terminalPatterns[0] != filepath.Join(paths.Dev, "pts", "[0-9]*") {
+		t.Errorf("Expected terminals in the host's /dev, got %v", terminalPatterns)
+	}
+	if eventDevicePattern != filepath.Join(paths.Dev, "input", "event*") || sessionBusPattern != "" {
+		t.Errorf("Expected input devices in the host's /dev and no desktops, got %q and %q", eventDevicePattern, sessionBusPattern)
+	}
+}
@@ -0,0 +1,23 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux
+
+package monitor
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// HostPaths are where the host's filesystems are mounted in a container
+type HostPaths struct {
+	Proc string
+	Sys  string
+	Dev  string // Empty to not watch input
+}
+
+// WatchHost is only supported on Linux
+func WatchHost(paths HostPaths) error {
+	return fmt.Errorf("watching the host from a container is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,51 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build freebsd || openbsd
+
+package monitor
+
+import (
+	"sync"
+	"time"
+)
+
+// terminalPatterns match the terminals whose access time the kernel updates
+// when a user types: pseudo-terminals, as SSH sessions use, the console and
+// the serial ports a cloud provider's serial console arrives on. FreeBSD
+// names them pts/N, ttyvN and ttyuN; OpenBSD ttypN and the like, ttyCN and
+// ttyNN.
+var terminalPatterns = []string{
+	"/dev/pts/[0-9]*", "/dev/ttyv[0-9a-f]", "/dev/ttyu[0-9]",
+	"/dev/tty[p-s][0-9a-f]", "/dev/ttyC[0-9a-f]", "/dev/tty0[0-9]",
+}
+
+// platformInput finds the idle time from the access times of terminals.
+// Cloud BSD instances are headless, so there are no input devices or
+// desktops to ask.
+type platformInput struct {
+	started   time.Time // When the monitor was created
+	unwatched sync.Once // Logs that there is nothing to watch
+}
+
+// newPlatformInput creates the BSD input monitor
+func newPlatformInput() *platformInput {
+	return &platformInput{started: time.Now()}
+}
+
+// idle returns the time since the last input on any terminal. A host with no
+// terminals can't be given input, so it counts as idle since the monitor
+// started.
+func (p *platformInput) idle(now time.Time) (time.Duration, error) {
+	last, found := latestAccess(terminalPatterns)
+	if !found {
+		p.unwatched.Do(func() {
+			logger().Info("No terminals to watch for input; counting input as idle since startup")
+		})
+		last = p.started
+	}
+	if last.After(now) {
+		return 0, nil
+	}
+	return now.Sub(last), nil
+}
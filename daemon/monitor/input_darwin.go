@@ -0,0 +1,60 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build darwin && cgo
+
+package monitor
+
+/*
+#cgo LDFLAGS: -framework IOKit -framework CoreFoundation
+#include <IOKit/IOKitLib.h>
+#include <CoreFoundation/CoreFoundation.h>
+
+// hidIdleTime returns the HIDIdleTime of IOHIDSystem in nanoseconds, or -1
+static int64_t hidIdleTime(void) {
+	io_iterator_t iter;
+	if (IOServiceGetMatchingServices(MACH_PORT_NULL, IOServiceMatching("IOHIDSystem"), &iter) != KERN_SUCCESS) {
+		return -1;
+	}
+	io_registry_entry_t entry = IOIteratorNext(iter);
+	IOObjectRelease(iter);
+	if (entry == 0) {
+		return -1;
+	}
+
+	int64_t idle = -1;
+	CFMutableDictionaryRef props = NULL;
+	if (IORegistryEntryCreateCFProperties(entry, &props, kCFAllocatorDefault, 0) == KERN_SUCCESS) {
+		CFNumberRef value = (CFNumberRef)CFDictionaryGetValue(props, CFSTR("HIDIdleTime"));
+		if (value != NULL) {
+			CFNumberGetValue(value, kCFNumberSInt64Type, &idle);
+		}
+		CFRelease(props);
+	}
+	IOObjectRelease(entry);
+	return idle;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+)
+
+// platformInput reads the HID idle time from IOKit
+type platformInput struct{}
+
+// newPlatformInput creates the macOS input monitor
+func newPlatformInput() *platformInput {
+	return &platformInput{}
+}
+
+// idle returns the time since the last keyboard or mouse input
+func (p *platformInput) idle(now time.Time) (time.Duration, error) {
+	idle := int64(C.hidIdleTime())
+	if idle < 0 {
+		return 0, fmt.Errorf("HIDIdleTime not available from IOKit")
+	}
+	return time.Duration(idle), nil
+}
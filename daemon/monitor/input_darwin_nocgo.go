@@ -0,0 +1,49 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build darwin && !cgo
+
+package monitor
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// platformInput reads the HID idle time with ioreg. Builds with cgo read it
+// from IOKit directly instead.
+type platformInput struct{}
+
+// newPlatformInput creates the macOS input monitor
+func newPlatformInput() *platformInput {
+	return &platformInput{}
+}
+
+// idle returns the time since the last keyboard or mouse input
+func (p *platformInput) idle(now time.Time) (time.Duration, error) {
+	output, err := exec.Command("ioreg", "-c", "IOHIDSystem").Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to run ioreg: %v", err)
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if !strings.Contains(line, "HIDIdleTime") {
+			continue
+		}
+		parts := strings.Split(line, " = ")
+		if len(parts) != 2 {
+			continue
+		}
+
+		// Value is in nanoseconds
+		idleNs, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse idle time: %v", err)
+		}
+		return time.Duration(idleNs), nil
+	}
+	return 0, fmt.Errorf("HIDIdleTime not found in ioreg output")
+}
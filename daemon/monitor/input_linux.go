@@ -0,0 +1,154 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// terminalPatterns match the terminals whose access time the kernel
+	// updates when a user types, as used by w(1). Serial and hypervisor
+	// consoles are where input from a cloud provider's serial console
+	// arrives.
+	terminalPatterns = []string{"/dev/pts/[0-9]*", "/dev/tty[0-9]*", "/dev/ttyS[0-9]*", "/dev/hvc[0-9]*"}
+
+	// eventDevicePattern matches the input event devices of keyboards and mice
+	eventDevicePattern = "/dev/input/event*"
+)
+
+const (
+	// inputEventSize is the size of struct input_event: a timeval of two
+	// longs, then the type, code and value
+	inputEventSize = 2*strconv.IntSize/8 + 8
+
+	// Event types that mean a user did something
+	evKey = 1
+	evRel = 2
+	evAbs = 3
+)
+
+// platformInput finds the idle time from whichever sources of input the
+// host has: the access times of terminals, events read from the input
+// devices, and the idle time desktop sessions report over D-Bus, which is
+// all there is to go on under Wayland. The most recent input of any of them
+// counts. Devices are read in the background, so a check only has to look
+// at timestamps.
+type platformInput struct {
+	lock      sync.Mutex
+	devices   map[string]bool // Event devices being read
+	watched   bool            // Whether any event device was ever read
+	lastEvent time.Time
+
+	// desktop returns the time of the last input in any desktop session
+	desktop func(now time.Time) (time.Time, bool)
+
+	started   time.Time // When the monitor was created
+	unwatched sync.Once // Logs that there is nothing to watch
+}
+
+// newPlatformInput creates the Linux input monitor
+func newPlatformInput() *platformInput {
+	return &platformInput{
+		devices:   make(map[string]bool),
+		lastEvent: time.Now(),
+		desktop:   newDesktopIdle().lastInput,
+		started:   time.Now(),
+	}
+}
+
+// idle returns the time since the last input on any terminal, input device
+// or desktop session. A headless host with none of them can't be given
+// input, so it counts as idle since the monitor started.
+func (p *platformInput) idle(now time.Time) (time.Duration, error) {
+	p.watchDevices()
+
+	last, found := p.lastInput()
+	for _, input := range []func() (time.Time, bool){
+		func() (time.Time, bool) { return latestAccess(terminalPatterns) },
+		func() (time.Time, bool) { return p.desktop(now) },
+	} {
+		if t, ok := input(); ok {
+			if !found || t.After(last) {
+				last = t
+			}
+			found = true
+		}
+	}
+	if !found {
+		p.unwatched.Do(func() {
+			logger().Info("No terminals, input devices or desktop sessions to watch for input; counting input as idle since startup")
+		})
+		last = p.started
+	}
+	if last.After(now) {
+		return 0, nil
+	}
+	return now.Sub(last), nil
+}
+
+// lastInput returns the time of the last event read from an input device
+func (p *platformInput) lastInput() (time.Time, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.lastEvent, p.watched
+}
+
+// watchDevices starts reading input devices that aren't read yet, so
+// devices plugged in later are picked up
+func (p *platformInput) watchDevices() {
+	paths, _ := filepath.Glob(eventDevicePattern)
+	for _, path := range paths {
+		p.lock.Lock()
+		watching := p.devices[path]
+		p.lock.Unlock()
+		if watching {
+			continue
+		}
+
+		device, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		p.lock.Lock()
+		p.devices[path] = true
+		if !p.watched {
+			// Input from before the first device was read is unknown
+			p.watched = true
+			p.lastEvent = time.Now()
+		}
+		p.lock.Unlock()
+		go p.watch(path, device)
+	}
+}
+
+// watch reads events from an input device until it fails, such as when the
+// device is unplugged. Reading doesn't take events away from other readers.
+func (p *platformInput) watch(path string, device io.ReadCloser) {
+	defer func() {
+		device.Close()
+		p.lock.Lock()
+		delete(p.devices, path)
+		p.lock.Unlock()
+	}()
+
+	event := make([]byte, inputEventSize)
+	for {
+		if _, err := io.ReadFull(device, event); err != nil {
+			return
+		}
+		switch binary.NativeEndian.Uint16(event[inputEventSize-8:]) {
+		case evKey, evRel, evAbs:
+			p.lock.Lock()
+			p.lastEvent = time.Now()
+			p.lock.Unlock()
+		}
+	}
+}
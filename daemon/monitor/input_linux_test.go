@@ -0,0 +1,111 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestInputIdleFromTerminals(t *testing.T) {
+	dir := t.TempDir()
+	typed := time.Now().Add(-10 * time.Minute)
+	for i, access := range []time.Time{typed.Add(-time.Hour), typed} {
+		path := filepath.Join(dir, string(rune('0'+i)))
+		os.WriteFile(path, nil, 0600)
+		os.Chtimes(path, access, access)
+	}
+
+	defer func(terminals []string, devices, buses string) {
+		terminalPatterns, eventDevicePattern, sessionBusPattern = terminals, devices, buses
+	}(terminalPatterns, eventDevicePattern, sessionBusPattern)
+	terminalPatterns = []string{filepath.Join(dir, "*")}
+	eventDevicePattern = filepath.Join(dir, "none*")
+	sessionBusPattern = filepath.Join(dir, "none*")
+
+	idle, err := newPlatformInput().idle(time.Now())
+	if err != nil {
+		t.Fatalf("idle returned error: %v", err)
+	}
+	if idle < 10*time.Minute || idle > 11*time.Minute {
+		t.Errorf("Expected 10 minutes idle, got %s", idle)
+	}
+
+	// With nothing to watch, input is idle since the monitor started
+	terminalPatterns = []string{filepath.Join(dir, "none*")}
+	p := newPlatformInput()
+	p.started = time.Now().Add(-time.Hour)
+	idle, err = p.idle(time.Now())
+	if err != nil {
+		t.Fatalf("idle returned error with nothing to watch: %v", err)
+	}
+	if idle < time.Hour {
+		t.Errorf("Expected idle since startup with nothing to watch, got %s", idle)
+	}
+}
+
+func TestInputIdleFromDesktop(t *testing.T) {
+	defer func(terminals []string, devices string) {
+		terminalPatterns, eventDevicePattern = terminals, devices
+	}(terminalPatterns, eventDevicePattern)
+	dir := t.TempDir()
+	terminalPatterns = []string{filepath.Join(dir, "none*")}
+	eventDevicePattern = filepath.Join(dir, "none*")
+
+	p := newPlatformInput()
+	p.desktop = func(now time.Time) (time.Time, bool) { return now.Add(-5 * time.Minute), true }
+	now := time.Now()
+	idle, err := p.idle(now)
+	if err != nil {
+		t.Fatalf("idle returned error: %v", err)
+	}
+	if idle != 5*time.Minute {
+		t.Errorf("Expected 5 minutes idle from the desktop, got %s", idle)
+	}
+
+	// An input device read more recently wins
+	p.watched, p.lastEvent = true, now.Add(-time.Minute)
+	if idle, _ := p.idle(now); idle != time.Minute {
+		t.Errorf("Expected 1 minute idle from the device, got %s", idle)
+	}
+}
+
+func TestInputEvents(t *testing.T) {
+	p := newPlatformInput()
+	before := time.Now().Add(-time.Hour)
+	p.watched, p.lastEvent = true, before
+	p.devices["event0"] = true
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		p.watch("event0", reader)
+		close(done)
+	}()
+
+	event := func(kind uint16) []byte {
+		data := make([]byte, inputEventSize)
+		binary.NativeEndian.PutUint16(data[inputEventSize-8:], kind)
+		return data
+	}
+
+	// A synchronization event followed by a key press
+	writer.Write(event(0))
+	writer.Write(event(evKey))
+	writer.Close()
+	<-done
+
+	if last, _ := p.lastInput(); !last.After(before) {
+		t.Error("Expected a key event to count as input")
+	}
+	if p.devices["event0"] {
+		t.Error("Expected a closed device to be forgotten")
+	}
+}
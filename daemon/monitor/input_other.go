@@ -0,0 +1,25 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux && !darwin && !windows && !freebsd && !openbsd
+
+package monitor
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+// platformInput is not supported on this platform
+type platformInput struct{}
+
+// newPlatformInput creates the unsupported input monitor
+func newPlatformInput() *platformInput {
+	return &platformInput{}
+}
+
+// idle always fails on this platform
+func (p *platformInput) idle(now time.Time) (time.Duration, error) {
+	return 0, fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+}
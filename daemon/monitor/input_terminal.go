@@ -0,0 +1,35 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || freebsd || openbsd
+
+package monitor
+
+import (
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// latestAccess returns the most recent access time of the files matching
+// the patterns
+func latestAccess(patterns []string) (time.Time, bool) {
+	var latest time.Time
+	found := false
+	for _, pattern := range patterns {
+		paths, _ := filepath.Glob(pattern)
+		for _, path := range paths {
+			var stat unix.Stat_t
+			if err := unix.Stat(path, &stat); err != nil {
+				continue
+			}
+			access := time.Unix(stat.Atim.Unix())
+			if !found || access.After(latest) {
+				latest = access
+			}
+			found = true
+		}
+	}
+	return latest, found
+}
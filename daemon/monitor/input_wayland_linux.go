@@ -0,0 +1,160 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// sessionBusPattern matches the D-Bus session buses of logged-in users
+var sessionBusPattern = "/run/user/*/bus"
+
+// desktopCallTimeout limits each D-Bus call, so a hung session doesn't hold
+// up the check
+const desktopCallTimeout = 2 * time.Second
+
+// idleMethod is a D-Bus method that reports how long a desktop session has
+// been idle
+type idleMethod struct {
+	name   string
+	dest   string
+	path   dbus.ObjectPath
+	method string
+	unit   time.Duration
+}
+
+// idleMethods are tried in order on each session bus, until one answers.
+// Wayland compositors don't let clients see input the way X servers do, so
+// the idle time is asked of the desktop itself.
+var idleMethods = []idleMethod{
+	{"mutter", "org.gnome.Mutter.IdleMonitor", "/org/gnome/Mutter/IdleMonitor/Core",
+		"org.gnome.Mutter.IdleMonitor.GetIdletime", time.Millisecond},
+	// KDE Plasma answers in milliseconds, rather than the seconds the
+	// interface was first specified with
+	{"screensaver", "org.freedesktop.ScreenSaver", "/org/freedesktop/ScreenSaver",
+		"org.freedesktop.ScreenSaver.GetSessionIdleTime", time.Millisecond},
+}
+
+// desktopBus is a connection to a user's session bus, and the method that
+// last answered on it
+type desktopBus struct {
+	conn   *dbus.Conn
+	method int
+}
+
+// desktopIdle reads the idle time of graphical sessions, Wayland or X, from
+// their desktops over each user's session bus. Connections are kept open
+// between checks; a bus the daemon's user may not connect to is skipped.
+type desktopIdle struct {
+	lock  sync.Mutex
+	buses map[string]*desktopBus
+}
+
+// newDesktopIdle creates a reader with no connections yet
+func newDesktopIdle() *desktopIdle {
+	return &desktopIdle{buses: make(map[string]*desktopBus)}
+}
+
+// lastInput returns the time of the latest input in any desktop session
+func (d *desktopIdle) lastInput(now time.Time) (time.Time, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	paths, _ := filepath.Glob(sessionBusPattern)
+	var latest time.Time
+	found := false
+	for _, path := range paths {
+		idle, err := d.query(path)
+		if err != nil {
+			logger().Debug("Desktop idle time unavailable", "bus", path, "error", err)
+			continue
+		}
+		if input := now.Add(-idle); !found || input.After(latest) {
+			latest = input
+		}
+		found = true
+	}
+
+	// Forget the buses of users who logged out
+	for path, bus := range d.buses {
+		if !slices.Contains(paths, path) {
+			bus.conn.Close()
+			delete(d.buses, path)
+		}
+	}
+	return latest, found
+}
+
+// query asks the desktop on a session bus for its idle time, trying the
+// method that answered last first
+func (d *desktopIdle) query(path string) (time.Duration, error) {
+	bus, err := d.connect(path)
+	if err != nil {
+		return 0, err
+	}
+	for i := range idleMethods {
+		index := (bus.method + i) % len(idleMethods)
+		idle, err := callIdleMethod(bus.conn, idleMethods[index])
+		if err == nil {
+			bus.method = index
+			return idle, nil
+		}
+		if !bus.conn.Connected() {
+			delete(d.buses, path)
+			return 0, err
+		}
+	}
+	return 0, fmt.Errorf("no desktop on the bus reports its idle time")
+}
+
+// connect returns the connection to a session bus, connecting if needed
+func (d *desktopIdle) connect(path string) (*desktopBus, error) {
+	if bus, ok := d.buses[path]; ok && bus.conn.Connected() {
+		return bus, nil
+	}
+	conn, err := dbus.Dial("unix:path=" + path)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Auth(nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := conn.Hello(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	bus := &desktopBus{conn: conn}
+	d.buses[path] = bus
+	return bus, nil
+}
+
+// callIdleMethod calls an idle time method, which answers with an unsigned
+// integer in its unit
+func callIdleMethod(conn *dbus.Conn, method idleMethod) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), desktopCallTimeout)
+	defer cancel()
+	call := conn.Object(method.dest, method.path).CallWithContext(ctx, method.method, 0)
+	if call.Err != nil {
+		return 0, call.Err
+	}
+	if len(call.Body) != 1 {
+		return 0, fmt.Errorf("unexpected reply from %s", method.name)
+	}
+	switch value := call.Body[0].(type) {
+	case uint32:
+		return time.Duration(value) * method.unit, nil
+	case uint64:
+		return time.Duration(value) * method.unit, nil
+	default:
+		return 0, fmt.Errorf("unexpected reply of type %T from %s", value, method.name)
+	}
+}
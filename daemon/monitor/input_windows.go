@@ -0,0 +1,153 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows
+
+package monitor
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	user32                         = windows.NewLazySystemDLL("user32.dll")
+	wtsapi32                       = windows.NewLazySystemDLL("wtsapi32.dll")
+	procGetLastInputInfo           = user32.NewProc("GetLastInputInfo")
+	procWTSQuerySessionInformation = wtsapi32.NewProc("WTSQuerySessionInformationW")
+)
+
+const (
+	// wtsSessionInfo is WTSSessionInfo of WTS_INFO_CLASS
+	wtsSessionInfo = 24
+
+	// wtsActive is WTSActive of WTS_CONNECTSTATE_CLASS: a user is logged
+	// on and connected
+	wtsActive = 0
+)
+
+// lastInputInfo is LASTINPUTINFO
+type lastInputInfo struct {
+	size uint32
+	time uint32 // Tick count of the last input
+}
+
+// wtsInfo is WTSINFOW, the state of a Remote Desktop Services session
+type wtsInfo struct {
+	State                   int32
+	SessionID               uint32
+	IncomingBytes           uint32
+	OutgoingBytes           uint32
+	IncomingFrames          uint32
+	OutgoingFrames          uint32
+	IncomingCompressedBytes uint32
+	OutgoingCompressedBytes uint32
+	WinStationName          [32]uint16
+	Domain                  [17]uint16
+	UserName                [21]uint16
+	_                       [4]byte // LARGE_INTEGER is aligned to 8 bytes, on 386 as well
+	ConnectTime             int64
+	DisconnectTime          int64
+	LastInputTime           int64 // FILETIME of the last input, 0 if unknown
+	LogonTime               int64
+	CurrentTime             int64
+}
+
+// platformInput finds the idle time from the last input of each session a
+// user is connected to, console or Remote Desktop. The daemon runs as a
+// service in session 0, where GetLastInputInfo only sees its own session,
+// so that is only used when it runs in a user's session.
+type platformInput struct {
+	session uint32 // The daemon's own session
+}
+
+// newPlatformInput creates the Windows input monitor
+func newPlatformInput() *platformInput {
+	p := &platformInput{}
+	windows.ProcessIdToSessionId(windows.GetCurrentProcessId(), &p.session)
+	return p
+}
+
+// idle returns the time since the last input in any connected session
+func (p *platformInput) idle(now time.Time) (time.Duration, error) {
+	last, found, err := p.lastSessionInput()
+	if err != nil {
+		return 0, err
+	}
+	if p.session != 0 {
+		if input, err := lastInput(now); err == nil {
+			if !found || input.After(last) {
+				last = input
+			}
+			found = true
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("no user sessions to watch for input")
+	}
+	if last.After(now) {
+		return 0, nil
+	}
+	return now.Sub(last), nil
+}
+
+// lastSessionInput returns the latest input of the sessions users are
+// connected to. A session that doesn't report its last input, as the
+// console may not, is skipped.
+func (p *platformInput) lastSessionInput() (time.Time, bool, error) {
+	var sessions *windows.WTS_SESSION_INFO
+	var count uint32
+	if err := windows.WTSEnumerateSessions(0, 0, 1, &sessions, &count); err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to enumerate sessions: %v", err)
+	}
+	defer windows.WTSFreeMemory(uintptr(unsafe.Pointer(sessions)))
+
+	var latest time.Time
+	found := false
+	for _, session := range unsafe.Slice(sessions, count) {
+		if session.State != wtsActive {
+			continue
+		}
+		info, err := querySessionInfo(session.SessionID)
+		if err != nil || info.UserName[0] == 0 || info.LastInputTime == 0 {
+			continue
+		}
+		filetime := windows.Filetime{LowDateTime: uint32(info.LastInputTime), HighDateTime: uint32(info.LastInputTime >> 32)}
+		input := time.Unix(0, filetime.Nanoseconds())
+		if !found || input.After(latest) {
+			latest = input
+		}
+		found = true
+	}
+	return latest, found, nil
+}
+
+// querySessionInfo returns the WTSINFO of a session
+func querySessionInfo(session uint32) (wtsInfo, error) {
+	var buffer *wtsInfo
+	var size uint32
+	ok, _, err := procWTSQuerySessionInformation.Call(0, uintptr(session), wtsSessionInfo,
+		uintptr(unsafe.Pointer(&buffer)), uintptr(unsafe.Pointer(&size)))
+	if ok == 0 {
+		return wtsInfo{}, err
+	}
+	defer windows.WTSFreeMemory(uintptr(unsafe.Pointer(buffer)))
+	if size < uint32(unsafe.Sizeof(wtsInfo{})) {
+		return wtsInfo{}, fmt.Errorf("session information of %d bytes is too short", size)
+	}
+	return *buffer, nil
+}
+
+// lastInput returns the time of the last input in the daemon's own session
+func lastInput(now time.Time) (time.Time, error) {
+	info := lastInputInfo{size: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if ok, _, err := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); ok == 0 {
+		return time.Time{}, fmt.Errorf("GetLastInputInfo failed: %v", err)
+	}
+	// Tick counts wrap every 49.7 days, which the subtraction allows for
+	idle := uint32(windows.DurationSinceBoot().Milliseconds()) - info.time
+	return now.Add(-time.Duration(idle) * time.Millisecond), nil
+}
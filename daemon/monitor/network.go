@@ -9,6 +9,11 @@ import (
 	"github.com/shirou/gopsutil/v3/net"
 )
 
+// netCounters returns the total traffic of every network interface
+var netCounters = func() ([]net.IOCountersStat, error) {
+	return net.IOCounters(false)
+}
+
 // NetworkMonitor handles network usage monitoring
 type NetworkMonitor struct {
 	lastCheckTime   time.Time
@@ -21,7 +26,7 @@ type NetworkMonitor struct {
 // NewNetworkMonitor creates a new network monitor
 func NewNetworkMonitor(checkIntervalMs int) *NetworkMonitor {
 	// Get initial stats
-	ioStats, _ := net.IOCounters(false)
+	ioStats, _ := netCounters()
 	var initialBytesRecv, initialBytesSent uint64
 	if len(ioStats) > 0 {
 		initialBytesRecv = ioStats[0].BytesRecv
@@ -39,7 +44,7 @@ func NewNetworkMonitor(checkIntervalMs int) *NetworkMonitor {
 // GetUsage returns the current network I/O in KB/s
 func (m *NetworkMonitor) GetUsage() (float64, error) {
 	// Get current stats
-	ioStats, err := net.IOCounters(false)
+	ioStats, err := netCounters()
 	if err != nil {
 		return 0, err
 	}
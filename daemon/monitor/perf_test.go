@@ -0,0 +1,54 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"testing"
+	"time"
+)
+
+// collectBudget is how long one check may take to collect metrics without a
+// GPU. See docs/testing/performance.md.
+const collectBudget = 50 * time.Millisecond
+
+// fastest returns the shortest of n runs of fn, so the budget isn't failed
+// by a busy test machine
+func fastest(n int, fn func()) time.Duration {
+	var best time.Duration
+	for i := 0; i < n; i++ {
+		start := time.Now()
+		fn()
+		if took := time.Since(start); i == 0 || took < best {
+			best = took
+		}
+	}
+	return best
+}
+
+func newBenchMonitor() *SystemMonitor {
+	return NewSystemMonitor(10, 30, 50, 100, 5, 900, 30, 60000, false)
+}
+
+func TestCollectMetricsBudget(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping perf budget in short mode")
+	}
+	m := newBenchMonitor()
+	m.CollectMetrics()
+
+	if took := fastest(5, func() { m.CollectMetrics() }); took > collectBudget {
+		t.Errorf("Collecting metrics took %v, over the budget of %v", took, collectBudget)
+	}
+}
+
+func BenchmarkCollectMetrics(b *testing.B) {
+	m := newBenchMonitor()
+	m.CollectMetrics()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.CollectMetrics()
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// bootTime returns when the system booted, in seconds since the epoch
+var bootTime = host.BootTime
+
+// stateSaveInterval is how often the idle state is saved while idle, which
+// bounds the idle time lost when the daemon restarts
+const stateSaveInterval = time.Minute
+
+// idleState is the idle tracking kept across daemon restarts
+type idleState struct {
+	IdleSince *time.Time `json:"idle_since,omitempty"`
+	BootTime  uint64     `json:"boot_time"`
+	SavedAt   time.Time  `json:"saved_at"`
+}
+
+// SetStatePath keeps the idle state in the file at path, so restarting the
+// daemon doesn't restart the idle timer. State saved before the system last
+// booted is ignored, since an instance that was stopped and started again
+// has not been idle in the meantime. Time while the daemon wasn't running
+// isn't counted as idle either.
+func (m *SystemMonitor) SetStatePath(path string) {
+	m.statePath = path
+	if path == "" {
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger().Warn("Failed to read idle state", "path", path, "error", err)
+		}
+		return
+	}
+
+	var state idleState
+	if err := json.Unmarshal(data, &state); err != nil {
+		logger().Warn("Ignoring unreadable idle state", "path", path, "error", err)
+		return
+	}
+	booted, err := bootTime()
+	if err != nil || state.BootTime != booted || state.IdleSince == nil {
+		return
+	}
+
+	// Re-anchor the idle time on the monotonic clock
+	idle := state.SavedAt.Sub(*state.IdleSince)
+	if idle < 0 {
+		idle = 0
+	}
+	since := time.Now().Add(-idle)
+	m.idleSince = &since
+	logger().Info("Restored idle state", "idle", idle.Round(time.Second).String())
+}
+
+// setIdleSince updates the idle start time, saving it if it changed
+func (m *SystemMonitor) setIdleSince(since *time.Time) {
+	changed := (m.idleSince == nil) != (since == nil)
+	m.idleSince = since
+	if changed {
+		m.writeState(time.Now())
+	}
+}
+
+// refreshState saves the idle state periodically while the system stays idle
+func (m *SystemMonitor) refreshState(now time.Time) {
+	if now.Sub(m.stateSaved) >= stateSaveInterval {
+		m.writeState(now)
+	}
+}
+
+// writeState saves the idle state, logging failures
+func (m *SystemMonitor) writeState(now time.Time) {
+	m.stateSaved = now
+	if err := m.saveState(); err != nil {
+		logger().Warn("Failed to save idle state", "error", err)
+	}
+}
+
+// saveState writes the idle state to the state file, if there is one
+func (m *SystemMonitor) saveState() error {
+	if m.statePath == "" {
+		return nil
+	}
+
+	booted, err := bootTime()
+	if err != nil {
+		return fmt.Errorf("failed to get boot time: %v", err)
+	}
+	data, err := json.Marshal(idleState{
+		IdleSince: m.idleSince,
+		BootTime:  booted,
+		SavedAt:   time.Now(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize idle state: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.statePath), 0755); err != nil {
+		return fmt.Errorf("failed to create idle state directory: %v", err)
+	}
+	tmp := m.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write idle state: %v", err)
+	}
+	return os.Rename(tmp, m.statePath)
+}
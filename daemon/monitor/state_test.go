@@ -0,0 +1,68 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package monitor
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIdleStateRestore(t *testing.T) {
+	defer func(original func() (uint64, error)) { bootTime = original }(bootTime)
+	booted := uint64(1746086400)
+	bootTime = func() (uint64, error) { return booted, nil }
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	since := time.Now().Add(-25 * time.Minute)
+
+	m := &SystemMonitor{}
+	m.SetStatePath(path)
+	m.setIdleSince(&since)
+
+	// A restart in the same boot resumes the idle timer
+	restarted := &SystemMonitor{}
+	restarted.SetStatePath(path)
+	if idle := restarted.GetIdleSince(); idle == nil || time.Since(*idle) < 25*time.Minute || time.Since(*idle) > 26*time.Minute {
+		t.Errorf("Expected to be idle for 25 minutes, idle since %v", idle)
+	}
+
+	// After a reboot the saved state is stale
+	booted += 3600
+	rebooted := &SystemMonitor{}
+	rebooted.SetStatePath(path)
+	if idle := rebooted.GetIdleSince(); idle != nil {
+		t.Errorf("Expected no idle state after reboot, got %v", idle)
+	}
+
+	// Becoming busy clears the saved state
+	rebooted.setIdleSince(&since)
+	rebooted.ResetIdleState()
+	again := &SystemMonitor{}
+	again.SetStatePath(path)
+	if idle := again.GetIdleSince(); idle != nil {
+		t.Errorf("Expected no idle state after reset, got %v", idle)
+	}
+}
+
+func TestCheckClockGap(t *testing.T) {
+	m := &SystemMonitor{checkIntervalMs: 60000}
+	now := time.Now()
+	since := now.Add(-10 * time.Minute)
+	m.idleSince = &since
+
+	// Regular checks count as idle time
+	m.lastCheck = now.Add(-time.Minute)
+	m.checkClock(now)
+	if !m.idleSince.Equal(since) {
+		t.Errorf("Expected idle start unchanged, got %v", m.idleSince)
+	}
+
+	// Only two intervals of a 30 minute gap count as idle time
+	m.lastCheck = now.Add(-30 * time.Minute)
+	m.checkClock(now)
+	if expected := since.Add(28 * time.Minute); !m.idleSince.Equal(expected) {
+		t.Errorf("Expected idle since %v after gap, got %v", expected, m.idleSince)
+	}
+}
@@ -5,11 +5,19 @@ package monitor
 
 import (
 	"fmt"
+	"log/slog"
+	"sync"
 	"time"
 	
 	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
 )
 
+// logger returns the monitor component logger
+func logger() *slog.Logger {
+	return logging.Component("monitor")
+}
+
 // SystemMonitor coordinates all monitoring activities
 type SystemMonitor struct {
 	cpuMonitor     *CPUMonitor
@@ -27,10 +35,27 @@ type SystemMonitor struct {
 	gpuThreshold    float64
 	
 	// Tracking data
-	idleSince          *time.Time
+	idleSince          *time.Time // Carries a monotonic reading, so wall clock changes don't affect it
+	lastCheck          time.Time
 	napTimeMinutes     int
 	lastMetrics        common.SystemMetrics
+	lastChecks         []MetricCheck
 	checkIntervalMs    int
+	decisions          *DecisionLog
+	statePath          string
+	stateSaved         time.Time
+	
+	// Handling of metrics that fail to collect
+	failurePolicy      FailurePolicy
+	staleAfter         time.Duration
+	lastGood           map[string]reading
+	lastGPU            gpuReading
+	
+	// Concurrent collection
+	timeout            time.Duration
+	collecting         map[string]time.Time // Start of each monitor's running collection
+	slow               map[string]bool      // Monitors reported as timed out
+	collectLock        sync.Mutex
 	
 	// GPU monitoring
 	gpuMonitoringEnabled bool
@@ -70,117 +95,157 @@ func NewSystemMonitor(cpuThreshold, memoryThreshold, networkThreshold, diskThres
 	}
 }
 
+// Thresholds holds the settings that decide when the system is idle
+type Thresholds struct {
+	CPUPercent     float64
+	MemoryPercent  float64
+	NetworkKBps    float64
+	DiskIOKBps     float64
+	GPUPercent     float64
+	InputIdleSecs  int
+	NaptimeMinutes int
+}
+
+// SetThresholds replaces the idle thresholds and naptime. The current idle
+// period is kept, so a longer naptime simply extends the wait.
+func (m *SystemMonitor) SetThresholds(t Thresholds) {
+	m.cpuThreshold = t.CPUPercent
+	m.memoryThreshold = t.MemoryPercent
+	m.networkThreshold = t.NetworkKBps
+	m.diskThreshold = t.DiskIOKBps
+	m.gpuThreshold = t.GPUPercent
+	m.inputThreshold = t.InputIdleSecs
+	m.napTimeMinutes = t.NaptimeMinutes
+}
+
+// GetThresholds returns the idle thresholds currently in effect
+func (m *SystemMonitor) GetThresholds() Thresholds {
+	return Thresholds{
+		CPUPercent:     m.cpuThreshold,
+		MemoryPercent:  m.memoryThreshold,
+		NetworkKBps:    m.networkThreshold,
+		DiskIOKBps:     m.diskThreshold,
+		GPUPercent:     m.gpuThreshold,
+		InputIdleSecs:  m.inputThreshold,
+		NaptimeMinutes: m.napTimeMinutes,
+	}
+}
+
 // SetGPUService sets the GPU monitoring service
 // This is used to break circular dependencies
 func (m *SystemMonitor) SetGPUService(service common.AcceleratorInterface) {
 	m.gpuService = service
 }
 
-// CollectMetrics gathers all system metrics and evaluates idle status
+// CollectMetrics gathers all system metrics and evaluates idle status. The
+// monitors collect concurrently, each with its own timeout. A metric that
+// fails to collect is handled by the failure policy, and an error is
+// returned if a core metric had no usable value.
 func (m *SystemMonitor) CollectMetrics() (common.SystemMetrics, error) {
+	start := time.Now()
 	metrics := common.SystemMetrics{
-		CollectionTime: time.Now().Unix(),
+		CollectionTime: start.Unix(),
 	}
 	
-	// Collect CPU metrics
-	cpuUsage, err := m.cpuMonitor.GetUsage()
-	if err != nil {
-		return metrics, fmt.Errorf("error collecting CPU metrics: %v", err)
-	}
-	metrics.CPUUsage = cpuUsage
-	
-	// Collect memory metrics
-	memoryUsage, err := m.memoryMonitor.GetUsage()
-	if err != nil {
-		return metrics, fmt.Errorf("error collecting memory metrics: %v", err)
-	}
-	metrics.MemoryUsage = memoryUsage
-	
-	// Collect network metrics
-	networkUsage, err := m.networkMonitor.GetUsage()
-	if err != nil {
-		return metrics, fmt.Errorf("error collecting network metrics: %v", err)
-	}
-	metrics.NetworkRate = networkUsage
+	m.collectLock.Lock()
+	deadline := start.Add(m.collectTimeout())
+	m.collectLock.Unlock()
 	
-	// Collect disk metrics
-	diskUsage, err := m.diskMonitor.GetUsage()
-	if err != nil {
-		return metrics, fmt.Errorf("error collecting disk metrics: %v", err)
-	}
-	metrics.DiskIORate = diskUsage
-	
-	// Collect input activity metrics
-	inputIdleSecs, err := m.inputMonitor.GetIdleSeconds()
-	if err != nil {
-		// Just log and continue, don't fail the entire collection
-		fmt.Printf("Warning: Failed to get input metrics: %v\n", err)
-		inputIdleSecs = 0
+	cpu := collectAsync(m, "cpu", m.cpuMonitor.GetUsage)
+	memory := collectAsync(m, "memory", m.memoryMonitor.GetUsage)
+	network := collectAsync(m, "network", m.networkMonitor.GetUsage)
+	disk := collectAsync(m, "disk", m.diskMonitor.GetUsage)
+	input := collectAsync(m, "input", m.inputMonitor.GetIdleSeconds)
+	var gpu *pending[[]common.GPUMetrics]
+	if m.gpuMonitoringEnabled && m.gpuService != nil {
+		gpu = collectAsync(m, "gpu", m.gpuService.GetMetrics)
 	}
-	metrics.LastInputTime = time.Now().Unix() - int64(inputIdleSecs)
 	
-	// Collect GPU metrics if enabled
-	if m.gpuMonitoringEnabled && m.gpuService != nil {
-		gpuMetrics, err := m.gpuService.GetMetrics()
+	// Collect every metric, even if one fails, so the idle state is always
+	// evaluated and a failed metric counts as busy
+	var checks []MetricCheck
+	var failed error
+	core := func(metric, name string, value, threshold float64, err error) float64 {
+		value, err = m.sample(metric, value, err, start)
 		if err != nil {
-			// Just log and continue
-			fmt.Printf("Warning: Failed to get GPU metrics: %v\n", err)
-		} else {
-			metrics.GPUMetrics = gpuMetrics
+			if failed == nil {
+				failed = fmt.Errorf("error collecting %s metrics: %v", name, err)
+			}
+			checks = append(checks, failedCheck(metric, threshold, err))
+			return 0
 		}
+		checks = append(checks, MetricCheck{Metric: metric, Value: value, Threshold: threshold, Idle: value < threshold})
+		return value
 	}
 	
-	// Check CPU usage - if above threshold, system is not idle
-	if cpuUsage >= m.cpuThreshold {
-		m.idleSince = nil
-		m.lastMetrics = metrics
-		return metrics, nil
-	}
+	cpuUsage, err := cpu.wait(m, deadline)
+	metrics.CPUUsage = core("cpu_percent", "CPU", cpuUsage, m.cpuThreshold, err)
 	
-	// Check memory usage
-	if memoryUsage >= m.memoryThreshold {
-		m.idleSince = nil
-		m.lastMetrics = metrics
-		return metrics, nil
-	}
+	memoryUsage, err := memory.wait(m, deadline)
+	metrics.MemoryUsage = core("memory_percent", "memory", memoryUsage, m.memoryThreshold, err)
 	
-	// Check network usage
-	if networkUsage >= m.networkThreshold {
-		m.idleSince = nil
-		m.lastMetrics = metrics
-		return metrics, nil
-	}
+	networkUsage, err := network.wait(m, deadline)
+	metrics.NetworkRate = core("network_kbps", "network", networkUsage, m.networkThreshold, err)
 	
-	// Check disk usage
-	if diskUsage >= m.diskThreshold {
-		m.idleSince = nil
-		m.lastMetrics = metrics
-		return metrics, nil
+	diskUsage, err := disk.wait(m, deadline)
+	metrics.DiskIORate = core("disk_io_kbps", "disk", diskUsage, m.diskThreshold, err)
+	
+	// Collect input activity metrics. A failure doesn't fail the collection,
+	// but the input check counts as busy.
+	inputIdle, err := input.wait(m, deadline)
+	inputIdleSecs, err := m.sample("input_idle_secs", float64(inputIdle), err, start)
+	metrics.LastInputTime = start.Unix() - int64(inputIdleSecs)
+	if m.inputThreshold > 0 {
+		if err != nil {
+			checks = append(checks, failedCheck("input_idle_secs", float64(m.inputThreshold), err))
+		} else {
+			checks = append(checks, MetricCheck{
+				Metric:    "input_idle_secs",
+				Value:     inputIdleSecs,
+				Threshold: float64(m.inputThreshold),
+				Idle:      int(inputIdleSecs) >= m.inputThreshold,
+			})
+		}
 	}
 	
-	// Check input idle time if threshold is set
-	if m.inputThreshold > 0 && inputIdleSecs < m.inputThreshold {
-		m.idleSince = nil
-		m.lastMetrics = metrics
-		return metrics, nil
+	// Collect GPU metrics if enabled. As with input, a failure counts as busy.
+	if gpu != nil {
+		gpuMetrics, err := gpu.wait(m, deadline)
+		gpuMetrics, err = m.sampleGPU(gpuMetrics, err, start)
+		if err != nil {
+			checks = append(checks, failedCheck("gpu_percent", m.gpuThreshold, err))
+		}
+		metrics.GPUMetrics = gpuMetrics
+		for _, gpu := range gpuMetrics {
+			checks = append(checks, MetricCheck{
+				Metric:    "gpu_percent",
+				Device:    gpu.ID,
+				Value:     gpu.Utilization,
+				Threshold: m.gpuThreshold,
+				Idle:      gpu.Utilization <= m.gpuThreshold,
+			})
+		}
 	}
+	m.lastChecks = checks
+	now := time.Now()
+	m.checkClock(now)
+	m.lastCheck = now
 	
-	// Check GPU usage if enabled
-	if m.gpuMonitoringEnabled && len(metrics.GPUMetrics) > 0 {
-		for _, gpu := range metrics.GPUMetrics {
-			if gpu.Utilization > m.gpuThreshold {
-				m.idleSince = nil
-				m.lastMetrics = metrics
-				return metrics, nil
-			}
+	// Any busy metric means the system is not idle
+	for _, check := range checks {
+		if !check.Idle {
+			m.setIdleSince(nil)
+			m.lastMetrics = metrics
+			return metrics, failed
 		}
 	}
 	
 	// At this point, the system is idle (all metrics below thresholds)
 	// Update idle state tracking
 	if m.idleSince == nil {
-		now := time.Now()
-		m.idleSince = &now
+		m.setIdleSince(&now)
+	} else {
+		m.refreshState(now)
 	}
 	
 	// Set idle time in metrics
@@ -209,6 +274,36 @@ func (m *SystemMonitor) ShouldSnooze() (bool, string) {
 		idleMinutes, m.napTimeMinutes)
 }
 
+// SetDecisionLog keeps a record of each evaluation in log
+func (m *SystemMonitor) SetDecisionLog(log *DecisionLog) {
+	m.decisions = log
+}
+
+// Decisions returns the decision log, or nil if decisions aren't recorded
+func (m *SystemMonitor) Decisions() *DecisionLog {
+	return m.decisions
+}
+
+// RecordDecision records the last evaluation together with what the daemon
+// decided to do about it, and returns the record
+func (m *SystemMonitor) RecordDecision(outcome, reason string) Decision {
+	decision := Decision{
+		Timestamp:      time.Now(),
+		Checks:         m.lastChecks,
+		Idle:           m.idleSince != nil,
+		NaptimeMinutes: m.napTimeMinutes,
+		Outcome:        outcome,
+		Reason:         reason,
+	}
+	if m.idleSince != nil {
+		decision.IdleSeconds = int64(time.Since(*m.idleSince).Seconds())
+	}
+	if m.decisions != nil {
+		m.decisions.Add(decision)
+	}
+	return decision
+}
+
 // GetLastMetrics returns the most recently collected metrics
 func (m *SystemMonitor) GetLastMetrics() common.SystemMetrics {
 	return m.lastMetrics
@@ -221,5 +316,5 @@ func (m *SystemMonitor) GetIdleSince() *time.Time {
 
 // ResetIdleState resets the idle state tracking
 func (m *SystemMonitor) ResetIdleState() {
-	m.idleSince = nil
+	m.setIdleSince(nil)
 }
\ No newline at end of file
@@ -29,14 +29,56 @@ type SystemMetrics struct {
 // Old implementations can convert between types as needed
 type GPUMetric = common.GPUMetrics
 
-// SnoozeEvent represents a stopping action
+// Snooze triggers
+const (
+	// TriggerIdle is used when the system was idle for the naptime
+	TriggerIdle = "idle"
+	// TriggerRuntimeBudget is used when the daily runtime budget was exceeded
+	TriggerRuntimeBudget = "runtime_budget"
+)
+
+// History event kinds
+const (
+	// EventSnooze is a stop; events recorded before kinds existed have none
+	EventSnooze = "snooze"
+	// EventStart is the instance starting again after a snooze
+	EventStart = "start"
+)
+
+// Outcomes of the steps of a snooze
+const (
+	StepOK      = "ok"
+	StepFailed  = "failed"
+	StepSkipped = "skipped" // Not run because an earlier step failed
+)
+
+// StepResult is the outcome of a step of the snooze pipeline
+type StepResult struct {
+	Step         string  `json:"step"`
+	Status       string  `json:"status"` // StepOK, StepFailed or StepSkipped
+	DurationSecs float64 `json:"duration_secs"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// SnoozeEvent represents a stopping action, or the start that followed one
 type SnoozeEvent struct {
-	Timestamp    time.Time                `json:"timestamp"`
-	InstanceID   string                   `json:"instance_id"`
-	InstanceType string                   `json:"instance_type"`
-	Region       string                   `json:"region"`
-	Reason       string                   `json:"reason"`
-	Metrics      common.SystemMetrics     `json:"metrics"`
-	Tags         map[string]string        `json:"tags,omitempty"`
-	NaptimeMins  int                      `json:"naptime_mins"`
+	Timestamp      time.Time                `json:"timestamp"`
+	Event          string                   `json:"event,omitempty"` // EventSnooze or EventStart
+	Action         string                   `json:"action,omitempty"` // How the instance was snoozed, e.g. "stop", "hibernate" or "suspend"
+	InstanceID     string                   `json:"instance_id"`
+	InstanceType   string                   `json:"instance_type"`
+	Region         string                   `json:"region"`
+	Reason         string                   `json:"reason"`
+	Trigger        string                   `json:"trigger,omitempty"`
+	Metrics        common.SystemMetrics     `json:"metrics"`
+	Tags           map[string]string        `json:"tags,omitempty"`
+	NaptimeMins    int                      `json:"naptime_mins"`
+	BudgetUsedMins float64                  `json:"budget_used_mins,omitempty"`
+	StoppedMins    float64                  `json:"stopped_mins,omitempty"` // How long the instance was stopped, on start events
+	Steps          []StepResult             `json:"steps,omitempty"` // Outcome of each step of the snooze
+}
+
+// IsStart reports whether the event is the instance starting
+func (e SnoozeEvent) IsStart() bool {
+	return e.Event == EventStart
 }
\ No newline at end of file
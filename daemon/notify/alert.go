@@ -0,0 +1,169 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Supported alerting services
+const (
+	AlertServicePagerDuty = "pagerduty"
+	AlertServiceOpsgenie  = "opsgenie"
+)
+
+const (
+	pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+	opsgenieAlertsURL  = "https://api.opsgenie.com/v2/alerts"
+)
+
+// AlertConfig holds the incident alerting settings
+type AlertConfig struct {
+	Service    string // "pagerduty" or "opsgenie"
+	RoutingKey string // PagerDuty Events API v2 integration key
+	APIKey     string // Opsgenie API key
+}
+
+// AlertNotifier opens incidents for error conditions only. Incidents are
+// deduplicated per instance and condition, so a repeating failure updates
+// a single incident instead of paging again.
+type AlertNotifier struct {
+	config AlertConfig
+	apiURL string
+	client *http.Client
+}
+
+// NewAlertNotifier creates an alerting notifier for PagerDuty or Opsgenie
+func NewAlertNotifier(config AlertConfig) (*AlertNotifier, error) {
+	var apiURL string
+	switch config.Service {
+	case AlertServicePagerDuty:
+		if config.RoutingKey == "" {
+			return nil, fmt.Errorf("pagerduty requires a routing key")
+		}
+		apiURL = pagerDutyEventsURL
+	case AlertServiceOpsgenie:
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("opsgenie requires an API key")
+		}
+		apiURL = opsgenieAlertsURL
+	default:
+		return nil, fmt.Errorf("unsupported alerting service %q", config.Service)
+	}
+
+	return &AlertNotifier{
+		config: config,
+		apiURL: apiURL,
+		client: &http.Client{Timeout: DefaultTimeout},
+	}, nil
+}
+
+// Name returns the notifier name
+func (a *AlertNotifier) Name() string {
+	return a.config.Service
+}
+
+// Notify opens an incident if the notification describes an error
+func (a *AlertNotifier) Notify(n Notification) error {
+	if !n.IsError() {
+		return nil
+	}
+
+	var payload interface{}
+	headers := map[string]string{}
+
+	switch a.config.Service {
+	case AlertServicePagerDuty:
+		payload = map[string]interface{}{
+			"routing_key":  a.config.RoutingKey,
+			"event_action": "trigger",
+			"dedup_key":    dedupKey(n),
+			"payload": map[string]interface{}{
+				"summary":        n.Title(),
+				"source":         alertSource(n),
+				"severity":       "error",
+				"component":      "cloudsnooze",
+				"class":          string(n.Type),
+				"custom_details": alertDetails(n),
+			},
+		}
+	case AlertServiceOpsgenie:
+		payload = map[string]interface{}{
+			"message":     n.Title(),
+			"alias":       dedupKey(n),
+			"description": n.Error,
+			"source":      alertSource(n),
+			"priority":    "P2",
+			"tags":        []string{"cloudsnooze", string(n.Type)},
+			"details":     alertDetails(n),
+		}
+		headers["Authorization"] = "GenieKey " + a.config.APIKey
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to serialize alert: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, a.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s returned status %d: %s", a.config.Service, resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// dedupKey identifies an incident by instance and condition
+func dedupKey(n Notification) string {
+	return fmt.Sprintf("cloudsnooze/%s/%s", alertSource(n), n.Type)
+}
+
+// alertSource returns the instance ID, falling back to the hostname
+func alertSource(n Notification) string {
+	if n.Event.InstanceID != "" {
+		return n.Event.InstanceID
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		return hostname
+	}
+	return "unknown"
+}
+
+// alertDetails returns the fields attached to an incident
+func alertDetails(n Notification) map[string]string {
+	details := map[string]string{
+		"reason": n.Event.Reason,
+		"error":  n.Error,
+	}
+	if n.CustomBody != "" {
+		details["message"] = n.CustomBody
+	}
+	if n.Event.InstanceType != "" {
+		details["instance_type"] = n.Event.InstanceType
+	}
+	if n.Event.Region != "" {
+		details["region"] = n.Event.Region
+	}
+	return details
+}
@@ -0,0 +1,192 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Default email templates. Templates receive the Notification, so fields
+// such as .Event.InstanceID and methods such as .Title are available.
+const (
+	DefaultEmailSubject = "[CloudSnooze] {{.Title}}"
+	DefaultEmailBody    = `{{.Title}}
+
+Instance:      {{with .Event.InstanceID}}{{.}}{{else}}unknown{{end}}{{with .Event.InstanceType}} ({{.}}){{end}}
+Region:        {{with .Event.Region}}{{.}}{{else}}unknown{{end}}
+Time:          {{.Event.Timestamp.Format "2006-01-02 15:04:05 MST"}}
+Reason:        {{.Event.Reason}}
+{{- with .Event.Trigger}}
+Trigger:       {{.}}{{end}}
+{{- with .Idle}}
+Idle for:      {{.}}{{end}}
+{{- with .Error}}
+Error:         {{.}}{{end}}
+{{- with .Savings}}
+Est. savings:  {{.}}{{end}}
+
+Metrics at decision time:
+  CPU:         {{printf "%.1f" .Event.Metrics.CPUUsage}}%
+  Memory:      {{printf "%.1f" .Event.Metrics.MemoryUsage}}%
+  Network:     {{printf "%.1f" .Event.Metrics.NetworkRate}} KB/s
+  Disk I/O:    {{printf "%.1f" .Event.Metrics.DiskIORate}} KB/s
+{{- if eq .Type "pending"}}
+
+Run 'snooze cancel' on the instance to keep it running.{{end}}
+`
+)
+
+// EmailConfig holds the SMTP notifier settings
+type EmailConfig struct {
+	Host            string
+	Port            int
+	Username        string
+	Password        string
+	From            string
+	To              []string
+	UseTLS          bool // Connect with implicit TLS (e.g. port 465) instead of STARTTLS
+	SubjectTemplate string
+	BodyTemplate    string
+}
+
+// EmailNotifier sends notifications by email
+type EmailNotifier struct {
+	config      EmailConfig
+	subject     *template.Template
+	body        *template.Template
+	defaultBody bool // Use the dispatcher's body template when one is set
+}
+
+// NewEmailNotifier creates an email notifier, parsing its templates
+func NewEmailNotifier(config EmailConfig) (*EmailNotifier, error) {
+	if config.Host == "" {
+		return nil, fmt.Errorf("email requires an SMTP host")
+	}
+	if config.From == "" || len(config.To) == 0 {
+		return nil, fmt.Errorf("email requires from and to addresses")
+	}
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	if config.SubjectTemplate == "" {
+		config.SubjectTemplate = DefaultEmailSubject
+	}
+	defaultBody := config.BodyTemplate == ""
+	if defaultBody {
+		config.BodyTemplate = DefaultEmailBody
+	}
+
+	subject, err := template.New("subject").Parse(config.SubjectTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email subject template: %v", err)
+	}
+	body, err := template.New("body").Parse(config.BodyTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email body template: %v", err)
+	}
+
+	return &EmailNotifier{
+		config:      config,
+		subject:     subject,
+		body:        body,
+		defaultBody: defaultBody,
+	}, nil
+}
+
+// Name returns the notifier name
+func (e *EmailNotifier) Name() string {
+	return "email"
+}
+
+// Notify sends the notification to all recipients
+func (e *EmailNotifier) Notify(n Notification) error {
+	message, err := e.render(n)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(e.config.Host, strconv.Itoa(e.config.Port))
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+	}
+
+	if !e.config.UseTLS {
+		// SendMail upgrades the connection with STARTTLS when the server offers it
+		if err := smtp.SendMail(addr, auth, e.config.From, e.config.To, message); err != nil {
+			return fmt.Errorf("failed to send email: %v", err)
+		}
+		return nil
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: DefaultTimeout}, "tcp", addr,
+		&tls.Config{ServerName: e.config.Host})
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %v", err)
+	}
+	client, err := smtp.NewClient(conn, e.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %v", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %v", err)
+		}
+	}
+	if err := client.Mail(e.config.From); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	for _, to := range e.config.To {
+		if err := client.Rcpt(to); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %v", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+	return client.Quit()
+}
+
+// render builds the RFC 5322 message for a notification
+func (e *EmailNotifier) render(n Notification) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := e.subject.Execute(&subject, n); err != nil {
+		return nil, fmt.Errorf("failed to render email subject: %v", err)
+	}
+	if e.defaultBody && n.CustomBody != "" {
+		body.WriteString(n.CustomBody + "\n")
+	} else if err := e.body.Execute(&body, n); err != nil {
+		return nil, fmt.Errorf("failed to render email body: %v", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", strings.TrimSpace(strings.ReplaceAll(subject.String(), "\n", " ")))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(body.String(), "\n", "\r\n"))
+
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,385 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notify delivers snooze notifications to chat and alerting services
+package notify
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+// NotificationType identifies what happened to the instance
+type NotificationType string
+
+const (
+	// NotificationPending is sent when the instance is about to be snoozed
+	NotificationPending NotificationType = "pending"
+	// NotificationSnoozing is sent by a pipeline's notify step as the
+	// snooze gets under way
+	NotificationSnoozing NotificationType = "snoozing"
+	// NotificationSnoozed is sent once the instance stop has been initiated
+	NotificationSnoozed NotificationType = "snoozed"
+	// NotificationFailed is sent when stopping the instance failed
+	NotificationFailed NotificationType = "failed"
+	// NotificationPermissionsLost is sent when the cloud permission check fails
+	NotificationPermissionsLost NotificationType = "permissions_lost"
+	// NotificationCollectionFailed is sent after repeated metric collection failures
+	NotificationCollectionFailed NotificationType = "collection_failed"
+	// NotificationMaintenance is sent when maintenance is scheduled for the instance
+	NotificationMaintenance NotificationType = "maintenance_scheduled"
+	// NotificationSuspended is sent when snoozing stops after repeated stop failures
+	NotificationSuspended NotificationType = "snooze_suspended"
+)
+
+// DefaultTimeout bounds how long a single notifier may take
+const DefaultTimeout = 10 * time.Second
+
+// logger returns the notify component logger
+func logger() *slog.Logger {
+	return logging.Component("notify")
+}
+
+// Notification describes a snooze for notifiers
+type Notification struct {
+	Type         NotificationType    `json:"type"`
+	Event        monitor.SnoozeEvent `json:"event"`
+	IdleDuration time.Duration       `json:"idle_duration"`
+	Countdown    time.Duration       `json:"countdown,omitempty"`   // Time left before the stop for pending notifications
+	HourlyCost   float64             `json:"hourly_cost,omitempty"` // Estimated cost per hour saved while stopped
+	Error        string              `json:"error,omitempty"`       // What went wrong for failure notifications
+
+	// Text rendered from user templates, replacing the default title and details
+	CustomTitle string `json:"-"`
+	CustomBody  string `json:"-"`
+}
+
+// Title returns a one-line summary of the notification
+func (n Notification) Title() string {
+	if n.CustomTitle != "" {
+		return n.CustomTitle
+	}
+
+	instance := n.Event.InstanceID
+	if instance == "" {
+		instance = "Instance"
+	}
+
+	switch n.Type {
+	case NotificationPending:
+		return fmt.Sprintf("%s will be snoozed in %s", instance, n.Countdown.Round(time.Second))
+	case NotificationSnoozing:
+		return fmt.Sprintf("%s is being snoozed", instance)
+	case NotificationSnoozed:
+		return fmt.Sprintf("%s has been snoozed", instance)
+	case NotificationFailed:
+		return fmt.Sprintf("%s could not be snoozed", instance)
+	case NotificationPermissionsLost:
+		return fmt.Sprintf("%s lost permission to stop itself", instance)
+	case NotificationCollectionFailed:
+		return fmt.Sprintf("%s cannot collect idle metrics", instance)
+	case NotificationMaintenance:
+		return fmt.Sprintf("%s has scheduled maintenance", instance)
+	case NotificationSuspended:
+		return fmt.Sprintf("%s stopped trying to snooze", instance)
+	default:
+		return fmt.Sprintf("%s: %s", instance, n.Type)
+	}
+}
+
+// IsError returns true for notifications about error conditions
+func (n Notification) IsError() bool {
+	switch n.Type {
+	case NotificationFailed, NotificationPermissionsLost, NotificationCollectionFailed, NotificationSuspended:
+		return true
+	}
+	return false
+}
+
+// Idle returns the idle duration rounded to minutes, or an empty string if
+// the system was not idle
+func (n Notification) Idle() string {
+	if n.IdleDuration <= 0 {
+		return ""
+	}
+	return n.IdleDuration.Round(time.Minute).String()
+}
+
+// Savings returns a human-readable savings estimate, or an empty string if
+// the hourly cost is unknown or the instance was not stopped
+func (n Notification) Savings() string {
+	if n.HourlyCost <= 0 || n.IsError() {
+		return ""
+	}
+	return fmt.Sprintf("$%.2f/hour ($%.2f/day) while stopped", n.HourlyCost, n.HourlyCost*24)
+}
+
+// Field is a labelled detail shown by chat notifiers
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields returns the details shown in chat messages, omitting empty values
+func (n Notification) Fields() []Field {
+	var fields []Field
+
+	if n.Event.InstanceID != "" {
+		instance := n.Event.InstanceID
+		if n.Event.InstanceType != "" {
+			instance += fmt.Sprintf(" (%s)", n.Event.InstanceType)
+		}
+		if n.Event.Region != "" {
+			instance += " in " + n.Event.Region
+		}
+		fields = append(fields, Field{Name: "Instance", Value: instance})
+	}
+	fields = append(fields, Field{Name: "Reason", Value: n.Event.Reason})
+	if idle := n.Idle(); idle != "" {
+		fields = append(fields, Field{Name: "Idle for", Value: idle})
+	}
+	if n.Error != "" {
+		fields = append(fields, Field{Name: "Error", Value: n.Error})
+	}
+	if savings := n.Savings(); savings != "" {
+		fields = append(fields, Field{Name: "Estimated savings", Value: savings})
+	}
+
+	return fields
+}
+
+// Hint returns advice for the reader, such as how to cancel a pending stop
+func (n Notification) Hint() string {
+	switch n.Type {
+	case NotificationPending:
+		return "Run 'snooze cancel' on the instance to keep it running."
+	case NotificationMaintenance:
+		return "The instance won't be snoozed shortly before the maintenance starts."
+	case NotificationSuspended:
+		return "Snoozing resumes once the instance's permissions are verified again."
+	}
+	return ""
+}
+
+// Notifier sends notifications to a single destination
+type Notifier interface {
+	// Name identifies the notifier in logs
+	Name() string
+
+	// Notify delivers a notification
+	Notify(n Notification) error
+}
+
+// Dispatcher fans notifications out to all configured notifiers
+type Dispatcher struct {
+	notifiers []Notifier
+	templates *Templates
+	queue     *Queue
+	timeout   time.Duration
+	stopRetry chan struct{}
+	lock      sync.RWMutex
+}
+
+// NewDispatcher creates an empty dispatcher
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{timeout: DefaultTimeout}
+}
+
+// Add registers a notifier
+func (d *Dispatcher) Add(notifier Notifier) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.notifiers = append(d.notifiers, notifier)
+}
+
+// SetTemplates customizes the text of all notifications
+func (d *Dispatcher) SetTemplates(templates *Templates) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.templates = templates
+}
+
+// SetQueue makes deliveries durable: each delivery is persisted before it
+// is attempted and retried with backoff until it succeeds
+func (d *Dispatcher) SetQueue(queue *Queue) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.queue = queue
+}
+
+// Usage returns the number of queued notifications and the most that are
+// kept, or zeros if deliveries aren't queued
+func (d *Dispatcher) Usage() (int, int) {
+	d.lock.RLock()
+	queue := d.queue
+	d.lock.RUnlock()
+	if queue == nil {
+		return 0, 0
+	}
+	return queue.Usage()
+}
+
+// Count returns the number of registered notifiers
+func (d *Dispatcher) Count() int {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	return len(d.notifiers)
+}
+
+// Notify sends the notification to every notifier in parallel and waits
+// for them to finish, so messages go out before the instance powers off.
+// Failures are logged and, if a queue is set, retried later.
+func (d *Dispatcher) Notify(n Notification) {
+	d.lock.RLock()
+	notifiers := append([]Notifier(nil), d.notifiers...)
+	queue := d.queue
+	d.lock.RUnlock()
+
+	if len(notifiers) == 0 {
+		return
+	}
+
+	rendered := d.render(n)
+
+	var wg sync.WaitGroup
+	for _, notifier := range notifiers {
+		// Notices of stops to come are time-sensitive and not worth
+		// delivering late
+		var id string
+		if queue != nil && n.Type != NotificationPending && n.Type != NotificationSnoozing {
+			var err error
+			if id, err = queue.Add(notifier.Name(), n); err != nil {
+				logger().Warn("Failed to queue notification", "notifier", notifier.Name(), "error", err)
+			}
+		}
+
+		wg.Add(1)
+		go func(notifier Notifier, id string) {
+			defer wg.Done()
+			d.deliver(queue, notifier, rendered, id)
+		}(notifier, id)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(d.timeout):
+		logger().Warn("Timed out waiting for notifications to be delivered")
+	}
+}
+
+// Retry redelivers queued notifications that are due
+func (d *Dispatcher) Retry() {
+	d.lock.RLock()
+	queue := d.queue
+	notifiers := make(map[string]Notifier, len(d.notifiers))
+	for _, notifier := range d.notifiers {
+		notifiers[notifier.Name()] = notifier
+	}
+	d.lock.RUnlock()
+
+	if queue == nil {
+		return
+	}
+
+	for _, entry := range queue.Due(time.Now()) {
+		notifier, ok := notifiers[entry.Notifier]
+		if !ok {
+			// The notifier was removed from the configuration
+			logger().Warn("Dropping queued notification for unknown notifier", "notifier", entry.Notifier)
+			if err := queue.Done(entry.ID); err != nil {
+				logger().Warn("Failed to update notification queue", "error", err)
+			}
+			continue
+		}
+		d.deliver(queue, notifier, d.render(entry.Notification), entry.ID)
+	}
+}
+
+// StartRetry retries queued notifications in the background at the given interval
+func (d *Dispatcher) StartRetry(interval time.Duration) {
+	d.lock.Lock()
+	if d.queue == nil || d.stopRetry != nil {
+		d.lock.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	d.stopRetry = stop
+	d.lock.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		d.Retry()
+		for {
+			select {
+			case <-ticker.C:
+				d.Retry()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops background retries
+func (d *Dispatcher) Stop() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.stopRetry != nil {
+		close(d.stopRetry)
+		d.stopRetry = nil
+	}
+}
+
+// render applies the user templates to a notification
+func (d *Dispatcher) render(n Notification) Notification {
+	d.lock.RLock()
+	templates := d.templates
+	d.lock.RUnlock()
+
+	if templates != nil {
+		if err := templates.Apply(&n); err != nil {
+			logger().Warn("Using default notification text", "error", err)
+		}
+	}
+	return n
+}
+
+// deliver sends a notification to one notifier, updating its queue entry
+func (d *Dispatcher) deliver(queue *Queue, notifier Notifier, n Notification, id string) {
+	err := notifier.Notify(n)
+	if err != nil {
+		logger().Warn("Notification failed", "notifier", notifier.Name(), "error", err)
+	}
+	if id == "" {
+		return
+	}
+
+	if err == nil {
+		if err := queue.Done(id); err != nil {
+			logger().Warn("Failed to update notification queue", "error", err)
+		}
+		return
+	}
+
+	retrying, queueErr := queue.Failed(id, err)
+	if queueErr != nil {
+		logger().Warn("Failed to update notification queue", "error", queueErr)
+	}
+	if !retrying {
+		logger().Warn("Giving up on notification", "notifier", notifier.Name(), "max_age", retryMaxAge)
+	}
+}
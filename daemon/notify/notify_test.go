@@ -0,0 +1,529 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+func testNotification() Notification {
+	return Notification{
+		Type: NotificationSnoozed,
+		Event: monitor.SnoozeEvent{
+			InstanceID:   "i-0123456789abcdef0",
+			InstanceType: "g4dn.xlarge",
+			Region:       "us-east-1",
+			Reason:       "System idle for 30 minutes",
+		},
+		IdleDuration: 30 * time.Minute,
+		HourlyCost:   0.526,
+	}
+}
+
+func TestSlackWebhook(t *testing.T) {
+	var received slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("Expected no authorization header for webhooks")
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	notifier, err := NewSlackNotifier(SlackConfig{WebhookURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewSlackNotifier returned error: %v", err)
+	}
+
+	if err := notifier.Notify(testNotification()); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+
+	if received.Text != "i-0123456789abcdef0 has been snoozed" {
+		t.Errorf("Unexpected message text: %q", received.Text)
+	}
+	if len(received.Blocks) != 2 {
+		t.Fatalf("Expected 2 blocks, got %d", len(received.Blocks))
+	}
+	details := received.Blocks[1].Text.Text
+	for _, want := range []string{"i-0123456789abcdef0", "30m0s", "$0.53/hour"} {
+		if !strings.Contains(details, want) {
+			t.Errorf("Expected details to contain %q, got %q", want, details)
+		}
+	}
+}
+
+func TestSlackBotToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-test" {
+			t.Errorf("Unexpected authorization header: %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+	}))
+	defer server.Close()
+
+	if _, err := NewSlackNotifier(SlackConfig{BotToken: "xoxb-test"}); err == nil {
+		t.Errorf("Expected error when bot token has no channel")
+	}
+
+	notifier, err := NewSlackNotifier(SlackConfig{BotToken: "xoxb-test", Channel: "#ops"})
+	if err != nil {
+		t.Fatalf("NewSlackNotifier returned error: %v", err)
+	}
+	notifier.apiURL = server.URL
+
+	err = notifier.Notify(testNotification())
+	if err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("Expected API error to be reported, got %v", err)
+	}
+}
+
+func TestEmailRender(t *testing.T) {
+	notifier, err := NewEmailNotifier(EmailConfig{
+		Host: "smtp.example.com",
+		From: "snooze@example.com",
+		To:   []string{"ops@example.com", "dev@example.com"},
+	})
+	if err != nil {
+		t.Fatalf("NewEmailNotifier returned error: %v", err)
+	}
+
+	n := testNotification()
+	n.Type = NotificationFailed
+	n.Error = "UnauthorizedOperation"
+
+	message, err := notifier.render(n)
+	if err != nil {
+		t.Fatalf("render returned error: %v", err)
+	}
+
+	text := string(message)
+	for _, want := range []string{
+		"To: ops@example.com, dev@example.com\r\n",
+		"Subject: [CloudSnooze] i-0123456789abcdef0 could not be snoozed\r\n",
+		"Error:         UnauthorizedOperation\r\n",
+		"Idle for:      30m0s\r\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected message to contain %q, got:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "savings") {
+		t.Errorf("Expected no savings estimate for failed stops")
+	}
+}
+
+func TestEmailCustomTemplate(t *testing.T) {
+	if _, err := NewEmailNotifier(EmailConfig{
+		Host:            "smtp.example.com",
+		From:            "snooze@example.com",
+		To:              []string{"ops@example.com"},
+		SubjectTemplate: "{{.Event.InstanceID",
+	}); err == nil {
+		t.Errorf("Expected error for invalid template")
+	}
+
+	notifier, err := NewEmailNotifier(EmailConfig{
+		Host:            "smtp.example.com",
+		From:            "snooze@example.com",
+		To:              []string{"ops@example.com"},
+		SubjectTemplate: "{{.Event.Region}}/{{.Event.InstanceID}} {{.Type}}",
+		BodyTemplate:    "See https://runbooks.example.com/snooze?id={{.Event.InstanceID}}",
+	})
+	if err != nil {
+		t.Fatalf("NewEmailNotifier returned error: %v", err)
+	}
+
+	message, err := notifier.render(testNotification())
+	if err != nil {
+		t.Fatalf("render returned error: %v", err)
+	}
+	if !strings.Contains(string(message), "Subject: us-east-1/i-0123456789abcdef0 snoozed\r\n") {
+		t.Errorf("Expected custom subject, got:\n%s", message)
+	}
+	if !strings.HasSuffix(string(message), "?id=i-0123456789abcdef0") {
+		t.Errorf("Expected custom body, got:\n%s", message)
+	}
+}
+
+// fakePublisher records published events
+type fakePublisher struct {
+	eventType string
+	payload   []byte
+}
+
+func (f *fakePublisher) PublishEvent(eventType string, payload []byte) error {
+	f.eventType = eventType
+	f.payload = payload
+	return nil
+}
+
+func TestPublisherNotifier(t *testing.T) {
+	publisher := &fakePublisher{}
+	notifier := NewPublisherNotifier("sns", publisher)
+
+	if err := notifier.Notify(testNotification()); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+
+	if publisher.eventType != "snoozed" {
+		t.Errorf("Expected event type snoozed, got %s", publisher.eventType)
+	}
+
+	var message map[string]interface{}
+	if err := json.Unmarshal(publisher.payload, &message); err != nil {
+		t.Fatalf("Payload is not valid JSON: %v", err)
+	}
+	if message["idle_seconds"] != float64(1800) {
+		t.Errorf("Expected idle_seconds 1800, got %v", message["idle_seconds"])
+	}
+	event := message["event"].(map[string]interface{})
+	if event["instance_id"] != "i-0123456789abcdef0" {
+		t.Errorf("Expected instance ID in event, got %v", event["instance_id"])
+	}
+}
+
+func TestAlertOnlyForErrors(t *testing.T) {
+	var requests []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, body)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	notifier, err := NewAlertNotifier(AlertConfig{Service: AlertServicePagerDuty, RoutingKey: "R0UT1NG"})
+	if err != nil {
+		t.Fatalf("NewAlertNotifier returned error: %v", err)
+	}
+	notifier.apiURL = server.URL
+
+	// Successful snoozes are not incidents
+	if err := notifier.Notify(testNotification()); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+	if len(requests) != 0 {
+		t.Fatalf("Expected no alert for a successful snooze")
+	}
+
+	failed := testNotification()
+	failed.Type = NotificationFailed
+	failed.Error = "UnauthorizedOperation"
+	for i := 0; i < 2; i++ {
+		if err := notifier.Notify(failed); err != nil {
+			t.Fatalf("Notify returned error: %v", err)
+		}
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected 2 alerts, got %d", len(requests))
+	}
+	if requests[0]["dedup_key"] != "cloudsnooze/i-0123456789abcdef0/failed" {
+		t.Errorf("Unexpected dedup key: %v", requests[0]["dedup_key"])
+	}
+	if requests[0]["dedup_key"] != requests[1]["dedup_key"] {
+		t.Errorf("Expected repeated failures to share a dedup key")
+	}
+}
+
+func TestAlertOpsgenie(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey key" {
+			t.Errorf("Unexpected authorization header: %q", r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"message":"Request body is not processable"}`))
+	}))
+	defer server.Close()
+
+	if _, err := NewAlertNotifier(AlertConfig{Service: AlertServiceOpsgenie}); err == nil {
+		t.Errorf("Expected error without an API key")
+	}
+
+	notifier, err := NewAlertNotifier(AlertConfig{Service: AlertServiceOpsgenie, APIKey: "key"})
+	if err != nil {
+		t.Fatalf("NewAlertNotifier returned error: %v", err)
+	}
+	notifier.apiURL = server.URL
+
+	n := testNotification()
+	n.Type = NotificationCollectionFailed
+	if err := notifier.Notify(n); err == nil || !strings.Contains(err.Error(), "422") {
+		t.Errorf("Expected status error, got %v", err)
+	}
+}
+
+func TestSessionNotifier(t *testing.T) {
+	var calls []string
+	notifier := NewSessionNotifier()
+	notifier.run = func(stdin string, name string, args ...string) error {
+		calls = append(calls, name+"|"+strings.Join(args, " ")+"|"+stdin)
+		return nil
+	}
+
+	// Only pending stops are broadcast
+	notifier.platform = "linux"
+	notifier.Notify(testNotification())
+	if len(calls) != 0 {
+		t.Fatalf("Expected no broadcast for completed snoozes")
+	}
+
+	pending := testNotification()
+	pending.Type = NotificationPending
+	pending.Countdown = 5 * time.Minute
+	pending.Event.Reason = `Idle "forever"`
+
+	if err := notifier.Notify(pending); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+	if !strings.HasPrefix(calls[0], "wall||CloudSnooze: This instance will be stopped in 5m0s") ||
+		!strings.Contains(calls[0], "snooze cancel") {
+		t.Errorf("Unexpected wall call: %q", calls[0])
+	}
+
+	notifier.platform = "darwin"
+	if err := notifier.Notify(pending); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+	if !strings.Contains(calls[1], `osascript|-e display notification "This instance will be stopped in 5m0s: Idle \"forever\"`) {
+		t.Errorf("Unexpected osascript call: %q", calls[1])
+	}
+}
+
+func TestTeamsAdaptiveCard(t *testing.T) {
+	var received struct {
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Type string `json:"type"`
+				Body []struct {
+					Type  string              `json:"type"`
+					Text  string              `json:"text"`
+					Facts []map[string]string `json:"facts"`
+				} `json:"body"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	notifier, err := NewTeamsNotifier(server.URL)
+	if err != nil {
+		t.Fatalf("NewTeamsNotifier returned error: %v", err)
+	}
+
+	pending := testNotification()
+	pending.Type = NotificationPending
+	pending.Countdown = 5 * time.Minute
+	if err := notifier.Notify(pending); err != nil {
+		t.Fatalf("Notify returned error: %v", err)
+	}
+
+	if len(received.Attachments) != 1 || received.Attachments[0].Content.Type != "AdaptiveCard" {
+		t.Fatalf("Expected a single Adaptive Card attachment, got %+v", received)
+	}
+	body := received.Attachments[0].Content.Body
+	if len(body) != 3 {
+		t.Fatalf("Expected title, facts and hint, got %d elements", len(body))
+	}
+	if body[0].Text != "i-0123456789abcdef0 will be snoozed in 5m0s" {
+		t.Errorf("Unexpected title: %q", body[0].Text)
+	}
+	if body[1].Facts[0]["value"] != "i-0123456789abcdef0 (g4dn.xlarge) in us-east-1" {
+		t.Errorf("Unexpected instance fact: %v", body[1].Facts[0])
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	if _, err := NewTemplates("{{.Instance", ""); err == nil {
+		t.Errorf("Expected error for invalid template")
+	}
+
+	templates, err := NewTemplates(
+		"[{{upper .Region}}] {{.Title}}",
+		"{{.Instance}} idle {{.Idle}}, CPU {{printf \"%.0f\" .Metrics.CPUUsage}}%{{with .Savings}}, saving {{.}}{{end}}\nRunbook: https://wiki.example.com/snooze/{{.Instance}}",
+	)
+	if err != nil {
+		t.Fatalf("NewTemplates returned error: %v", err)
+	}
+
+	n := testNotification()
+	n.Event.Metrics.CPUUsage = 2.4
+	if err := templates.Apply(&n); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+
+	if n.Title() != "[US-EAST-1] i-0123456789abcdef0 has been snoozed" {
+		t.Errorf("Unexpected title: %q", n.Title())
+	}
+	want := "i-0123456789abcdef0 idle 30m0s, CPU 2%, saving $0.53/hour ($12.62/day) while stopped\nRunbook: https://wiki.example.com/snooze/i-0123456789abcdef0"
+	if n.CustomBody != want {
+		t.Errorf("Unexpected body:\n%s\nwant:\n%s", n.CustomBody, want)
+	}
+	if slackDetails(n) != want {
+		t.Errorf("Expected Slack to use the custom body")
+	}
+
+	// Email uses the custom body unless it has its own template
+	email, _ := NewEmailNotifier(EmailConfig{Host: "smtp.example.com", From: "a@example.com", To: []string{"b@example.com"}})
+	message, err := email.render(n)
+	if err != nil {
+		t.Fatalf("render returned error: %v", err)
+	}
+	if !strings.Contains(string(message), "Subject: [CloudSnooze] [US-EAST-1] i-0123456789abcdef0 has been snoozed\r\n") ||
+		!strings.Contains(string(message), "Runbook: https://wiki.example.com/snooze/i-0123456789abcdef0") {
+		t.Errorf("Expected email to use the custom templates, got:\n%s", message)
+	}
+}
+
+// flakyNotifier fails until told to succeed
+type flakyNotifier struct {
+	fail      bool
+	delivered []Notification
+}
+
+func (f *flakyNotifier) Name() string { return "flaky" }
+
+func (f *flakyNotifier) Notify(n Notification) error {
+	if f.fail {
+		return errors.New("service unavailable")
+	}
+	f.delivered = append(f.delivered, n)
+	return nil
+}
+
+func TestQueueDropsOldest(t *testing.T) {
+	queue, _ := NewQueue("", 2)
+	first, _ := queue.Add("slack", testNotification())
+	queue.Add("slack", testNotification())
+	queue.Add("slack", testNotification())
+
+	if entries, capacity := queue.Usage(); entries != 2 || capacity != 2 {
+		t.Fatalf("Expected 2 of 2 entries used, got %d of %d", entries, capacity)
+	}
+	for _, entry := range queue.Due(time.Now()) {
+		if entry.ID == first {
+			t.Error("Expected the oldest entry to be dropped")
+		}
+	}
+}
+
+func TestQueueRetriesAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+
+	queue, err := NewQueue(path, 0)
+	if err != nil {
+		t.Fatalf("NewQueue returned error: %v", err)
+	}
+	notifier := &flakyNotifier{fail: true}
+	dispatcher := NewDispatcher()
+	dispatcher.Add(notifier)
+	dispatcher.SetQueue(queue)
+
+	dispatcher.Notify(testNotification())
+
+	// Pending notifications are not queued
+	pending := testNotification()
+	pending.Type = NotificationPending
+	dispatcher.Notify(pending)
+
+	if queue.Len() != 1 {
+		t.Fatalf("Expected 1 queued notification, got %d", queue.Len())
+	}
+
+	// Simulate a restart with the service back up
+	restored, err := NewQueue(path, 0)
+	if err != nil {
+		t.Fatalf("NewQueue returned error: %v", err)
+	}
+	entry := restored.Due(time.Now().Add(time.Minute))[0]
+	if entry.Attempts != 1 || entry.LastError != "service unavailable" {
+		t.Errorf("Unexpected queue entry: %+v", entry)
+	}
+	if len(restored.Due(time.Now())) != 0 {
+		t.Errorf("Expected retry to be delayed by backoff")
+	}
+
+	notifier = &flakyNotifier{}
+	dispatcher = NewDispatcher()
+	dispatcher.Add(notifier)
+	dispatcher.SetQueue(restored)
+	restored.entries[0].NextAttempt = time.Now()
+	dispatcher.Retry()
+
+	if len(notifier.delivered) != 1 || notifier.delivered[0].Event.InstanceID != "i-0123456789abcdef0" {
+		t.Fatalf("Expected queued notification to be delivered, got %+v", notifier.delivered)
+	}
+	if restored.Len() != 0 {
+		t.Errorf("Expected queue to be empty after delivery")
+	}
+}
+
+// slowNotifier counts deliveries, the first taking until release is closed
+type slowNotifier struct {
+	release chan struct{}
+	sent    atomic.Int32
+}
+
+func (s *slowNotifier) Name() string { return "slow" }
+
+func (s *slowNotifier) Notify(n Notification) error {
+	if s.sent.Add(1) == 1 {
+		<-s.release
+	}
+	return nil
+}
+
+func TestRetrySkipsDeliveriesInProgress(t *testing.T) {
+	queue, _ := NewQueue("", 0)
+	notifier := &slowNotifier{release: make(chan struct{})}
+	dispatcher := NewDispatcher()
+	dispatcher.timeout = 10 * time.Millisecond
+	dispatcher.Add(notifier)
+	dispatcher.SetQueue(queue)
+
+	// Notify gives up waiting while the delivery is still going
+	dispatcher.Notify(testNotification())
+	dispatcher.Retry()
+	if sent := notifier.sent.Load(); sent != 1 {
+		t.Errorf("Expected the delivery in progress not to be retried, got %d sends", sent)
+	}
+
+	close(notifier.release)
+	deadline := time.Now().Add(time.Second)
+	for queue.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if queue.Len() != 0 {
+		t.Fatal("Expected the delivery to finish and leave the queue")
+	}
+	dispatcher.Retry()
+	if sent := notifier.sent.Load(); sent != 1 {
+		t.Errorf("Expected a single send, got %d", sent)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if retryDelay(1) != 30*time.Second || retryDelay(3) != 2*time.Minute {
+		t.Errorf("Unexpected backoff: %s, %s", retryDelay(1), retryDelay(3))
+	}
+	if retryDelay(20) != time.Hour {
+		t.Errorf("Expected backoff to be capped at an hour, got %s", retryDelay(20))
+	}
+}
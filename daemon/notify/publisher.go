@@ -0,0 +1,69 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+// EventMessage is the machine-readable form of a notification sent to
+// event buses and notifier plugins
+type EventMessage struct {
+	Type             NotificationType    `json:"type"`
+	Title            string              `json:"title"`
+	Message          string              `json:"message,omitempty"`
+	Event            monitor.SnoozeEvent `json:"event"`
+	IdleSeconds      int                 `json:"idle_seconds,omitempty"`
+	CountdownSeconds int                 `json:"countdown_seconds,omitempty"`
+	HourlyCost       float64             `json:"hourly_cost,omitempty"`
+	Error            string              `json:"error,omitempty"`
+}
+
+// NewEventMessage converts a notification to its machine-readable form
+func NewEventMessage(n Notification) EventMessage {
+	return EventMessage{
+		Type:             n.Type,
+		Title:            n.Title(),
+		Message:          n.CustomBody,
+		Event:            n.Event,
+		IdleSeconds:      int(n.IdleDuration.Seconds()),
+		CountdownSeconds: int(n.Countdown.Seconds()),
+		HourlyCost:       n.HourlyCost,
+		Error:            n.Error,
+	}
+}
+
+// PublisherNotifier sends notifications as JSON events through a cloud
+// provider's event publisher
+type PublisherNotifier struct {
+	name      string
+	publisher common.EventPublisher
+}
+
+// NewPublisherNotifier creates a notifier that publishes through the given publisher
+func NewPublisherNotifier(name string, publisher common.EventPublisher) *PublisherNotifier {
+	return &PublisherNotifier{
+		name:      name,
+		publisher: publisher,
+	}
+}
+
+// Name returns the notifier name
+func (p *PublisherNotifier) Name() string {
+	return p.name
+}
+
+// Notify publishes the notification as a JSON event
+func (p *PublisherNotifier) Notify(n Notification) error {
+	payload, err := json.Marshal(NewEventMessage(n))
+	if err != nil {
+		return fmt.Errorf("failed to serialize event: %v", err)
+	}
+
+	return p.publisher.PublishEvent(string(n.Type), payload)
+}
@@ -0,0 +1,219 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultQueuePath is the default location of the notification queue
+	DefaultQueuePath = "/var/lib/cloudsnooze/notification-queue.json"
+
+	// retryBaseDelay is the delay before the first retry, doubling each attempt
+	retryBaseDelay = 30 * time.Second
+	// retryMaxDelay caps the delay between retries
+	retryMaxDelay = time.Hour
+	// retryMaxAge is how long delivery is attempted before giving up
+	retryMaxAge = 24 * time.Hour
+
+	// DefaultQueueMaxEntries is the default number of undelivered
+	// notifications kept
+	DefaultQueueMaxEntries = 500
+)
+
+// QueuedNotification is a delivery to a single notifier that has not
+// succeeded yet
+type QueuedNotification struct {
+	ID           string       `json:"id"`
+	Notifier     string       `json:"notifier"`
+	Notification Notification `json:"notification"`
+	Attempts     int          `json:"attempts"`
+	Queued       time.Time    `json:"queued"`
+	NextAttempt  time.Time    `json:"next_attempt"`
+	LastError    string       `json:"last_error,omitempty"`
+}
+
+// Queue persists undelivered notifications so they survive restarts. At
+// most maxEntries are kept; the oldest are dropped to make room.
+type Queue struct {
+	path       string
+	maxEntries int
+	entries    []QueuedNotification
+	nextID     int64
+	// inFlight holds the IDs of entries being delivered, which aren't due
+	// until the delivery finishes
+	inFlight map[string]bool
+	lock     sync.Mutex
+}
+
+// NewQueue creates a queue backed by the given file, loading any entries
+// left from a previous run. An empty path keeps the queue in memory only.
+func NewQueue(path string, maxEntries int) (*Queue, error) {
+	if maxEntries <= 0 {
+		maxEntries = DefaultQueueMaxEntries
+	}
+	queue := &Queue{path: path, maxEntries: maxEntries, inFlight: make(map[string]bool)}
+
+	if path == "" {
+		return queue, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return queue, nil
+	}
+	if err != nil {
+		return queue, fmt.Errorf("failed to read notification queue: %v", err)
+	}
+	if err := json.Unmarshal(data, &queue.entries); err != nil {
+		return queue, fmt.Errorf("failed to parse notification queue: %v", err)
+	}
+	queue.trim()
+
+	return queue, nil
+}
+
+// Add records a delivery before it is attempted and returns its ID. The
+// entry is in flight until Done or Failed is called for it.
+func (q *Queue) Add(notifier string, n Notification) (string, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.nextID++
+	now := time.Now()
+	entry := QueuedNotification{
+		ID:           fmt.Sprintf("%d-%d", now.UnixNano(), q.nextID),
+		Notifier:     notifier,
+		Notification: n,
+		Queued:       now,
+		NextAttempt:  now,
+	}
+	q.entries = append(q.entries, entry)
+	q.inFlight[entry.ID] = true
+	q.trim()
+
+	return entry.ID, q.save()
+}
+
+// Done removes a delivered entry
+func (q *Queue) Done(id string) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	delete(q.inFlight, id)
+	for i, entry := range q.entries {
+		if entry.ID == id {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return q.save()
+		}
+	}
+	return nil
+}
+
+// Failed schedules the next attempt with exponential backoff. Entries
+// older than the maximum age are dropped; it returns false in that case.
+func (q *Queue) Failed(id string, deliveryErr error) (bool, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	delete(q.inFlight, id)
+	now := time.Now()
+	for i := range q.entries {
+		entry := &q.entries[i]
+		if entry.ID != id {
+			continue
+		}
+
+		if now.Sub(entry.Queued) >= retryMaxAge {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return false, q.save()
+		}
+
+		entry.Attempts++
+		entry.LastError = deliveryErr.Error()
+		entry.NextAttempt = now.Add(retryDelay(entry.Attempts))
+		return true, q.save()
+	}
+	return false, nil
+}
+
+// Due returns the entries whose next attempt is at or before now, leaving
+// out those still being delivered
+func (q *Queue) Due(now time.Time) []QueuedNotification {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	var due []QueuedNotification
+	for _, entry := range q.entries {
+		if !entry.NextAttempt.After(now) && !q.inFlight[entry.ID] {
+			due = append(due, entry)
+		}
+	}
+	return due
+}
+
+// Len returns the number of undelivered notifications
+func (q *Queue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.entries)
+}
+
+// Usage returns the number of queued notifications and the most that are kept
+func (q *Queue) Usage() (int, int) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.entries), q.maxEntries
+}
+
+// trim drops the oldest entries beyond the limit
+func (q *Queue) trim() {
+	if dropped := len(q.entries) - q.maxEntries; dropped > 0 {
+		logger().Warn("Notification queue full, dropping oldest undelivered notifications", "dropped", dropped)
+		q.entries = append(q.entries[:0], q.entries[dropped:]...)
+	}
+}
+
+// retryDelay returns the backoff delay after the given number of attempts
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// save writes the queue file atomically; the caller must hold the lock
+func (q *Queue) save() error {
+	if q.path == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(q.path), 0700); err != nil {
+		return fmt.Errorf("failed to create notification queue directory: %v", err)
+	}
+
+	data, err := json.Marshal(q.entries)
+	if err != nil {
+		return fmt.Errorf("failed to serialize notification queue: %v", err)
+	}
+
+	tmpPath := q.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0600); err != nil {
+		return fmt.Errorf("failed to write notification queue: %v", err)
+	}
+	if err := os.Rename(tmpPath, q.path); err != nil {
+		return fmt.Errorf("failed to replace notification queue: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SessionNotifier warns users logged in to the instance before it stops,
+// using wall on Linux and a desktop notification on macOS
+type SessionNotifier struct {
+	platform string
+	run      func(stdin string, name string, args ...string) error
+}
+
+// NewSessionNotifier creates a notifier for logged-in sessions
+func NewSessionNotifier() *SessionNotifier {
+	return &SessionNotifier{
+		platform: runtime.GOOS,
+		run:      runCommand,
+	}
+}
+
+// Name returns the notifier name
+func (s *SessionNotifier) Name() string {
+	return "sessions"
+}
+
+// Notify broadcasts pending stops; other notifications are ignored
+func (s *SessionNotifier) Notify(n Notification) error {
+	if n.Type != NotificationPending {
+		return nil
+	}
+
+	message := fmt.Sprintf("This instance will be stopped in %s: %s\n%s",
+		n.Countdown.Round(time.Second), n.Event.Reason, n.Hint())
+	if n.CustomBody != "" {
+		message = n.CustomBody + "\n" + n.Hint()
+	}
+
+	switch s.platform {
+	case "linux":
+		return s.run("CloudSnooze: "+message+"\n", "wall")
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s sound name %s",
+			appleScriptString(message), appleScriptString("CloudSnooze"), appleScriptString("default"))
+		return s.run("", "osascript", "-e", script)
+	default:
+		return fmt.Errorf("unsupported platform: %s", s.platform)
+	}
+}
+
+// runCommand runs a command with the given standard input
+func runCommand(stdin string, name string, args ...string) error {
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s not found", name)
+	}
+
+	cmd := exec.Command(name, args...)
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to run %s: %v: %s", name, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// appleScriptString quotes a string for use in AppleScript
+func appleScriptString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,148 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// slackPostMessageURL is the Web API method used with bot tokens
+const slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// SlackConfig holds the Slack notifier settings. Either WebhookURL or
+// BotToken with Channel must be set.
+type SlackConfig struct {
+	WebhookURL string
+	BotToken   string
+	Channel    string
+	Username   string
+}
+
+// SlackNotifier posts notifications to Slack
+type SlackNotifier struct {
+	config SlackConfig
+	apiURL string
+	client *http.Client
+}
+
+// NewSlackNotifier creates a Slack notifier
+func NewSlackNotifier(config SlackConfig) (*SlackNotifier, error) {
+	if config.WebhookURL == "" && config.BotToken == "" {
+		return nil, fmt.Errorf("slack requires a webhook URL or bot token")
+	}
+	if config.WebhookURL == "" && config.Channel == "" {
+		return nil, fmt.Errorf("slack bot token requires a channel")
+	}
+
+	return &SlackNotifier{
+		config: config,
+		apiURL: slackPostMessageURL,
+		client: &http.Client{Timeout: DefaultTimeout},
+	}, nil
+}
+
+// Name returns the notifier name
+func (s *SlackNotifier) Name() string {
+	return "slack"
+}
+
+// slackMessage is the payload accepted by both webhooks and chat.postMessage
+type slackMessage struct {
+	Channel  string       `json:"channel,omitempty"`
+	Username string       `json:"username,omitempty"`
+	Text     string       `json:"text"`
+	Blocks   []slackBlock `json:"blocks,omitempty"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Notify posts the notification to Slack
+func (s *SlackNotifier) Notify(n Notification) error {
+	message := slackMessage{
+		Channel:  s.config.Channel,
+		Username: s.config.Username,
+		Text:     n.Title(),
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: n.Title()}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: slackDetails(n)}},
+		},
+	}
+
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to serialize slack message: %v", err)
+	}
+
+	url := s.config.WebhookURL
+	if url == "" {
+		url = s.apiURL
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create slack request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if s.config.WebhookURL == "" {
+		req.Header.Set("Authorization", "Bearer "+s.config.BotToken)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to slack: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	// The Web API reports errors in the body with a 200 status
+	if s.config.WebhookURL == "" {
+		var result struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(respBody, &result); err != nil {
+			return fmt.Errorf("failed to parse slack response: %v", err)
+		}
+		if !result.OK {
+			return fmt.Errorf("slack API error: %s", result.Error)
+		}
+	}
+
+	return nil
+}
+
+// slackDetails formats the notification fields as Slack mrkdwn
+func slackDetails(n Notification) string {
+	if n.CustomBody != "" {
+		return n.CustomBody
+	}
+
+	var b strings.Builder
+
+	for _, field := range n.Fields() {
+		fmt.Fprintf(&b, "*%s:* %s\n", field.Name, field.Value)
+	}
+	if hint := n.Hint(); hint != "" {
+		b.WriteString(hint)
+	}
+
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,107 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// TeamsNotifier posts notifications to a Microsoft Teams channel as
+// Adaptive Cards through an incoming webhook or workflow URL
+type TeamsNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewTeamsNotifier creates a Teams notifier
+func NewTeamsNotifier(webhookURL string) (*TeamsNotifier, error) {
+	if webhookURL == "" {
+		return nil, fmt.Errorf("teams requires a webhook URL")
+	}
+
+	return &TeamsNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: DefaultTimeout},
+	}, nil
+}
+
+// Name returns the notifier name
+func (t *TeamsNotifier) Name() string {
+	return "teams"
+}
+
+// Notify posts the notification as an Adaptive Card
+func (t *TeamsNotifier) Notify(n Notification) error {
+	body, err := json.Marshal(teamsCard(n))
+	if err != nil {
+		return fmt.Errorf("failed to serialize teams message: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create teams request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post to teams: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Incoming webhooks return 200, workflow triggers return 202
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("teams returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// teamsCard builds the webhook message wrapping an Adaptive Card
+func teamsCard(n Notification) map[string]interface{} {
+	color := "Good"
+	switch {
+	case n.IsError():
+		color = "Attention"
+	case n.Type == NotificationPending, n.Type == NotificationSnoozing:
+		color = "Warning"
+	}
+
+	facts := make([]map[string]string, 0)
+	for _, field := range n.Fields() {
+		facts = append(facts, map[string]string{"title": field.Name, "value": field.Value})
+	}
+
+	body := []map[string]interface{}{
+		{"type": "TextBlock", "text": n.Title(), "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+	}
+	if n.CustomBody != "" {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": n.CustomBody, "wrap": true})
+	} else {
+		body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+	}
+	if hint := n.Hint(); hint != "" {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": hint, "isSubtle": true, "wrap": true})
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]interface{}{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// Templates customizes notification text with Go templates. Templates are
+// executed against the Notification, so besides its fields they can use
+// .Instance, .Region, .Reason, .Metrics, .Idle, .Savings, and .Title (the
+// default title).
+type Templates struct {
+	title *template.Template
+	body  *template.Template
+}
+
+// templateFuncs are available in all notification templates
+var templateFuncs = template.FuncMap{
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// NewTemplates parses the title and body templates. Empty templates keep
+// the default text.
+func NewTemplates(title, body string) (*Templates, error) {
+	t := &Templates{}
+
+	if title != "" {
+		parsed, err := template.New("title").Funcs(templateFuncs).Parse(title)
+		if err != nil {
+			return nil, fmt.Errorf("invalid title template: %v", err)
+		}
+		t.title = parsed
+	}
+	if body != "" {
+		parsed, err := template.New("body").Funcs(templateFuncs).Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid body template: %v", err)
+		}
+		t.body = parsed
+	}
+
+	return t, nil
+}
+
+// Apply renders the templates into the notification's custom title and body
+func (t *Templates) Apply(n *Notification) error {
+	data := *n
+	data.CustomTitle = ""
+	data.CustomBody = ""
+
+	if t.title != nil {
+		var b bytes.Buffer
+		if err := t.title.Execute(&b, data); err != nil {
+			return fmt.Errorf("failed to render title template: %v", err)
+		}
+		n.CustomTitle = strings.TrimSpace(strings.ReplaceAll(b.String(), "\n", " "))
+	}
+	if t.body != nil {
+		var b bytes.Buffer
+		if err := t.body.Execute(&b, data); err != nil {
+			return fmt.Errorf("failed to render body template: %v", err)
+		}
+		n.CustomBody = strings.TrimSpace(b.String())
+	}
+
+	return nil
+}
+
+// Instance returns the instance ID
+func (n Notification) Instance() string {
+	return n.Event.InstanceID
+}
+
+// Region returns the instance region
+func (n Notification) Region() string {
+	return n.Event.Region
+}
+
+// Reason returns why the notification was sent
+func (n Notification) Reason() string {
+	return n.Event.Reason
+}
+
+// Metrics returns the system metrics at decision time
+func (n Notification) Metrics() common.SystemMetrics {
+	return n.Event.Metrics
+}
@@ -0,0 +1,160 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"path/filepath"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/policy"
+)
+
+// Like config verification, the organization's policy is set with flags
+// rather than settings, so editing the config can't loosen it
+var (
+	policyURL     = flag.String("policy-url", "", "URL of the organization's signed baseline policy, which local settings may tighten but not loosen (empty for none)")
+	policyKeyFile = flag.String("policy-key", "", "File holding the Ed25519 public key the policy is signed with")
+	policyCache   = flag.String("policy-cache", filepath.Join(dataDir, "policy.json"), "Where the last verified policy is kept for when -policy-url can't be reached")
+)
+
+// PolicyStatus is the organization's policy in effect, reported by STATUS
+type PolicyStatus struct {
+	URL      string        `json:"url"`
+	Source   string        `json:"source"` // "fetched", or "cached" when the URL couldn't be reached
+	Policy   policy.Policy `json:"policy"`
+	Adjusted []string      `json:"adjusted"` // Local settings the policy overrode
+}
+
+// The longest boot grace and input idle threshold a policy that forbids
+// disabling allows
+const (
+	maxForbidDisableBootGraceMinutes = 24 * 60
+	maxForbidDisableInputIdleSecs    = 24 * 60 * 60
+)
+
+// activePolicy is the policy applied at startup, or nil if there is none
+var activePolicy *PolicyStatus
+
+// loadOrgPolicy fetches the policy at -policy-url, saving it to
+// -policy-cache, or falls back to the cached copy. It returns nil without a
+// policy URL.
+func loadOrgPolicy(ctx context.Context) (*PolicyStatus, error) {
+	if *policyURL == "" {
+		return nil, nil
+	}
+	if *policyKeyFile == "" {
+		return nil, fmt.Errorf("-policy-url needs -policy-key")
+	}
+	key, err := policy.ReadPublicKey(*policyKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy key: %v", err)
+	}
+
+	p, data, signature, err := policy.Fetch(ctx, *policyURL, key)
+	if err == nil {
+		if err := policy.Save(*policyCache, data, signature); err != nil {
+			logger().Warn("Failed to cache policy", "path", *policyCache, "error", err)
+		}
+		return &PolicyStatus{URL: *policyURL, Source: "fetched", Policy: p}, nil
+	}
+	logger().Warn("Failed to fetch policy, using the cached copy", "url", *policyURL, "error", err)
+	p, cacheErr := policy.Load(*policyCache, key)
+	if cacheErr != nil {
+		return nil, fmt.Errorf("%v, and no cached policy: %v", err, cacheErr)
+	}
+	return &PolicyStatus{URL: *policyURL, Source: "cached", Policy: p}, nil
+}
+
+// applyOrgPolicy brings the settings the policy bounds within its bounds,
+// returning the settings it changed
+func applyOrgPolicy(config *Config, p policy.Policy) []string {
+	var adjusted []string
+	adjustInt := func(field string, value *int, bounded int) {
+		if *value != bounded {
+			adjusted = append(adjusted, fmt.Sprintf("%s %d changed to %d", field, *value, bounded))
+			*value = bounded
+		}
+	}
+	adjustFloat := func(field string, value *float64, bounded float64) {
+		if *value != bounded {
+			adjusted = append(adjusted, fmt.Sprintf("%s %g changed to %g", field, *value, bounded))
+			*value = bounded
+		}
+	}
+	remove := func(field string) {
+		adjusted = append(adjusted, field+" removed")
+	}
+
+	adjustInt("naptime_minutes", &config.NaptimeMinutes, p.Naptime(config.NaptimeMinutes))
+	// A weekend naptime of 0 uses the weekday one
+	if weekend := config.Schedule.Weekend.NaptimeMinutes; weekend > 0 {
+		adjustInt("schedule.weekend.naptime_minutes", &config.Schedule.Weekend.NaptimeMinutes, p.Naptime(weekend))
+	}
+	adjustFloat("leases.max_hours", &config.Leases.MaxHours, p.LeaseHours(config.Leases.MaxHours))
+
+	if p.ForbidDisable {
+		// Leases without a limit and thresholds of 0, which usage is
+		// never below, keep the instance from ever snoozing
+		defaults := DefaultConfig()
+		if config.Leases.MaxHours <= 0 {
+			adjustFloat("leases.max_hours", &config.Leases.MaxHours, defaults.Leases.MaxHours)
+		}
+		for _, threshold := range []struct {
+			field    string
+			value    *float64
+			fallback float64
+		}{
+			{"cpu_threshold_percent", &config.CPUThresholdPercent, defaults.CPUThresholdPercent},
+			{"memory_threshold_percent", &config.MemoryThresholdPercent, defaults.MemoryThresholdPercent},
+			{"network_threshold_kbps", &config.NetworkThresholdKBps, defaults.NetworkThresholdKBps},
+			{"disk_io_threshold_kbps", &config.DiskIOThresholdKBps, defaults.DiskIOThresholdKBps},
+		} {
+			if *threshold.value <= 0 {
+				adjustFloat(threshold.field, threshold.value, threshold.fallback)
+			}
+		}
+
+		// A long enough boot grace never ends, and a long enough input
+		// idle threshold never passes. A weekend threshold of 0 uses the
+		// weekday one.
+		if config.BootGraceMinutes > maxForbidDisableBootGraceMinutes {
+			adjustInt("boot_grace_minutes", &config.BootGraceMinutes, maxForbidDisableBootGraceMinutes)
+		}
+		if config.InputIdleThresholdSecs > maxForbidDisableInputIdleSecs {
+			adjustInt("input_idle_threshold_secs", &config.InputIdleThresholdSecs, maxForbidDisableInputIdleSecs)
+		}
+		if config.Schedule.Weekend.InputIdleThresholdSecs > maxForbidDisableInputIdleSecs {
+			adjustInt("schedule.weekend.input_idle_threshold_secs", &config.Schedule.Weekend.InputIdleThresholdSecs, maxForbidDisableInputIdleSecs)
+		}
+
+		// Checks, dependents, peers and calendar events, blackouts as
+		// much as force-active ones, can keep the instance running for as
+		// long as they like, and a script can stop it or do nothing
+		for i := range config.Hooks.Commands {
+			if config.Hooks.Commands[i].Check != "" {
+				remove(fmt.Sprintf("hooks.commands[%d].check", i))
+				config.Hooks.Commands[i].Check = ""
+			}
+		}
+		if len(config.Dependencies.Instances) > 0 || len(config.Dependencies.Tags) > 0 {
+			remove("dependencies")
+			config.Dependencies.Instances, config.Dependencies.Tags = []string{}, map[string]string{}
+		}
+		if len(config.Peers.Instances) > 0 || len(config.Peers.Tags) > 0 {
+			remove("peers")
+			config.Peers.Instances, config.Peers.Tags = []string{}, map[string]string{}
+		}
+		if config.Schedule.Calendar.Enabled {
+			remove("schedule.calendar")
+			config.Schedule.Calendar.Enabled = false
+		}
+		if config.StopAction == actionScript {
+			adjusted = append(adjusted, fmt.Sprintf("stop_action %s changed to %s", actionScript, actionStop))
+			config.StopAction = actionStop
+		}
+	}
+	return adjusted
+}
@@ -0,0 +1,125 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/policy"
+)
+
+func TestApplyOrgPolicyClamps(t *testing.T) {
+	bounds := policy.Policy{MinNaptimeMinutes: 15, MaxNaptimeMinutes: 120, MaxLeaseHours: 8}
+
+	tests := []struct {
+		name     string
+		mutate   func(*Config)
+		check    func(Config) bool
+		adjusted string
+	}{
+		{"naptime below the minimum", func(c *Config) { c.NaptimeMinutes = 5 },
+			func(c Config) bool { return c.NaptimeMinutes == 15 }, "naptime_minutes 5 changed to 15"},
+		{"naptime above the maximum", func(c *Config) { c.NaptimeMinutes = 600 },
+			func(c Config) bool { return c.NaptimeMinutes == 120 }, "naptime_minutes 600 changed to 120"},
+		{"weekend naptime above the maximum", func(c *Config) { c.Schedule.Weekend.NaptimeMinutes = 240 },
+			func(c Config) bool { return c.Schedule.Weekend.NaptimeMinutes == 120 }, "schedule.weekend.naptime_minutes 240 changed to 120"},
+		{"unlimited leases", func(c *Config) { c.Leases.MaxHours = 0 },
+			func(c Config) bool { return c.Leases.MaxHours == 8 }, "leases.max_hours 0 changed to 8"},
+		{"leases above the maximum", func(c *Config) { c.Leases.MaxHours = 72 },
+			func(c Config) bool { return c.Leases.MaxHours == 8 }, "leases.max_hours 72 changed to 8"},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.mutate(&config)
+		adjusted := applyOrgPolicy(&config, bounds)
+		if !tt.check(config) {
+			t.Errorf("%s: expected the setting to be brought within the policy", tt.name)
+		}
+		if !slices.Contains(adjusted, tt.adjusted) {
+			t.Errorf("%s: expected %q to be reported, got %v", tt.name, tt.adjusted, adjusted)
+		}
+	}
+
+	// Settings within the bounds, including a weekend naptime of 0 that
+	// uses the weekday one, are left alone
+	config := DefaultConfig()
+	config.NaptimeMinutes = 30
+	config.Schedule.Weekend.NaptimeMinutes = 0
+	config.Leases.MaxHours = 4
+	if adjusted := applyOrgPolicy(&config, bounds); len(adjusted) != 0 {
+		t.Errorf("Expected no changes within the bounds, got %v", adjusted)
+	}
+}
+
+func TestApplyOrgPolicyForbidDisable(t *testing.T) {
+	forbid := policy.Policy{ForbidDisable: true}
+	defaults := DefaultConfig()
+
+	tests := []struct {
+		name     string
+		mutate   func(*Config)
+		check    func(Config) bool
+		adjusted string
+	}{
+		{"unlimited leases", func(c *Config) { c.Leases.MaxHours = 0 },
+			func(c Config) bool { return c.Leases.MaxHours == defaults.Leases.MaxHours }, "leases.max_hours 0 changed to 24"},
+		{"CPU threshold of 0", func(c *Config) { c.CPUThresholdPercent = 0 },
+			func(c Config) bool { return c.CPUThresholdPercent == defaults.CPUThresholdPercent }, "cpu_threshold_percent 0 changed to 10"},
+		{"endless boot grace", func(c *Config) { c.BootGraceMinutes = 1000000 },
+			func(c Config) bool { return c.BootGraceMinutes == maxForbidDisableBootGraceMinutes }, "boot_grace_minutes 1000000 changed to 1440"},
+		{"endless input idle threshold", func(c *Config) { c.InputIdleThresholdSecs = 1 << 30 },
+			func(c Config) bool { return c.InputIdleThresholdSecs == maxForbidDisableInputIdleSecs }, "input_idle_threshold_secs 1073741824 changed to 86400"},
+		{"endless weekend input idle threshold", func(c *Config) { c.Schedule.Weekend.InputIdleThresholdSecs = 1 << 30 },
+			func(c Config) bool { return c.Schedule.Weekend.InputIdleThresholdSecs == maxForbidDisableInputIdleSecs },
+			"schedule.weekend.input_idle_threshold_secs 1073741824 changed to 86400"},
+		{"force-active calendar", func(c *Config) {
+			c.Schedule.Calendar.Enabled = true
+			c.Schedule.Calendar.URL = "https://calendar.example.com/ops.ics"
+			c.Schedule.Calendar.DefaultWindow = "force-active"
+		}, func(c Config) bool { return !c.Schedule.Calendar.Enabled }, "schedule.calendar removed"},
+		{"blackout calendar", func(c *Config) {
+			c.Schedule.Calendar.Enabled = true
+			c.Schedule.Calendar.URL = "https://calendar.example.com/ops.ics"
+		}, func(c Config) bool { return !c.Schedule.Calendar.Enabled }, "schedule.calendar removed"},
+		{"hook check", func(c *Config) {
+			c.Hooks.Commands = []HookConfig{{Name: "jobs", Check: "/usr/local/bin/veto", PreStop: "/usr/local/bin/save"}}
+		}, func(c Config) bool {
+			return c.Hooks.Commands[0].Check == "" && c.Hooks.Commands[0].PreStop == "/usr/local/bin/save"
+		}, "hooks.commands[0].check removed"},
+		{"dependencies", func(c *Config) { c.Dependencies.Instances = []string{"i-0dep"} },
+			func(c Config) bool { return len(c.Dependencies.Instances) == 0 }, "dependencies removed"},
+		{"peers", func(c *Config) {
+			c.Peers.Tags = map[string]string{"Service": "web"}
+			c.Peers.MinRunning = 10
+		}, func(c Config) bool { return len(c.Peers.Tags) == 0 }, "peers removed"},
+		{"stop script", func(c *Config) {
+			c.StopAction = actionScript
+			c.StopScript = "/usr/local/bin/park"
+		}, func(c Config) bool { return c.StopAction == actionStop }, "stop_action script changed to stop"},
+	}
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.mutate(&config)
+		adjusted := applyOrgPolicy(&config, forbid)
+		if !tt.check(config) {
+			t.Errorf("%s: expected the setting to be overridden", tt.name)
+		}
+		if !slices.Contains(adjusted, tt.adjusted) {
+			t.Errorf("%s: expected %q to be reported, got %v", tt.name, tt.adjusted, adjusted)
+		}
+		if err := validateConfig(config); err != nil {
+			t.Errorf("%s: expected the overridden config to be valid, got %v", tt.name, err)
+		}
+	}
+
+	// Without forbid_disable the same settings stand
+	config := DefaultConfig()
+	config.BootGraceMinutes = 1000000
+	config.InputIdleThresholdSecs = 1 << 30
+	config.Peers.Tags = map[string]string{"Service": "web"}
+	if adjusted := applyOrgPolicy(&config, policy.Policy{}); len(adjusted) != 0 {
+		t.Errorf("Expected no changes without forbid_disable, got %v", adjusted)
+	}
+}
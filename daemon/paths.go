@@ -0,0 +1,14 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package main
+
+// Default locations of the daemon's files
+const (
+	configDir  = "/etc/snooze"          // The config file, tokens and secrets
+	installDir = "/etc/cloudsnooze"     // Plugins and TLS certificates
+	dataDir    = "/var/lib/cloudsnooze" // State kept across restarts
+	logDir     = "/var/log"
+)
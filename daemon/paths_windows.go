@@ -0,0 +1,27 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Default locations of the daemon's files, all under
+// %ProgramData%\CloudSnooze where the installers put the config and logs
+var (
+	configDir  = programData()
+	installDir = programData()
+	dataDir    = filepath.Join(programData(), "data")
+	logDir     = filepath.Join(programData(), "logs")
+)
+
+// programData returns the CloudSnooze directory of %ProgramData%
+func programData() string {
+	root := os.Getenv("ProgramData")
+	if root == "" {
+		root = `C:\ProgramData`
+	}
+	return filepath.Join(root, "CloudSnooze")
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// peerChecker reads the states of the instances that are replicas of this
+// one, and keeps the instance running if snoozing it would leave too few
+// of them running. Each daemon's reports to the aggregation server are its
+// heartbeats; a peer whose reports are stale isn't counted as running.
+type peerChecker struct {
+	*instanceStates
+	self       func() string
+	minRunning int
+}
+
+// newPeerChecker creates a checker, or returns nil if no peers are
+// declared or their states can't be read
+func newPeerChecker(cloudProvider common.CloudProvider, config Config) *peerChecker {
+	peers := config.Peers
+	if len(peers.Instances) == 0 && len(peers.Tags) == 0 {
+		return nil
+	}
+	read, err := newInstanceStateReader(cloudProvider, config, peers.Source, peers.Instances, peers.Tags)
+	if err != nil {
+		logger().Error("Peers disabled", "error", err)
+		return nil
+	}
+	return &peerChecker{
+		instanceStates: &instanceStates{read: read, interval: time.Duration(peers.CheckSeconds) * time.Second},
+		self:           func() string { return currentInstanceID(cloudProvider) },
+		minRunning:     peers.MinRunning,
+	}
+}
+
+// Check reads the states of the peers again if the check interval has
+// passed. It returns why the instance must keep running, or "" if enough
+// peers would be left running without it.
+func (c *peerChecker) Check(ctx context.Context, now time.Time) string {
+	if c == nil {
+		return ""
+	}
+	states, err := c.Read(ctx, now, "peers")
+	if err != nil {
+		return fmt.Sprintf("Snooze suppressed as the states of peers can't be read: %v", err)
+	}
+	running := runningPeers(states, c.self())
+	if len(running) >= c.minRunning {
+		return ""
+	}
+	if len(running) == 0 {
+		return fmt.Sprintf("Snooze suppressed as no peer would be left running (%d needed)", c.minRunning)
+	}
+	return fmt.Sprintf("Snooze suppressed as only %d peers would be left running (%s), %d needed",
+		len(running), strings.Join(running, ", "), c.minRunning)
+}
+
+// runningPeers returns the IDs of the peers that will keep running. A peer
+// that is idle or counting down may snooze too, so only one of two idle
+// peers counts the other as running: the one with the lower instance ID,
+// which snoozes first. Paused peers keep running; peers whose state is
+// unknown aren't counted.
+func runningPeers(states []common.FleetMemberStatus, self string) []string {
+	var running []string
+	for _, state := range states {
+		switch state.State {
+		case common.FleetStateActive, common.FleetStatePaused:
+			running = append(running, state.InstanceID)
+		case common.FleetStateIdle, common.FleetStateCountdown:
+			if state.InstanceID > self {
+				running = append(running, state.InstanceID)
+			}
+		}
+	}
+	return running
+}
+
+// States returns the states of the peers last read
+func (c *peerChecker) States() []common.FleetMemberStatus {
+	if c == nil {
+		return nil
+	}
+	return c.instanceStates.States()
+}
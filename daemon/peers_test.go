@@ -0,0 +1,93 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+func TestRunningPeers(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		peer  string
+		want  bool
+	}{
+		{"active peer", common.FleetStateActive, "i-0aaa", true},
+		{"paused peer", common.FleetStatePaused, "i-0aaa", true},
+		{"idle peer with a higher ID", common.FleetStateIdle, "i-0ccc", true},
+		{"idle peer with a lower ID", common.FleetStateIdle, "i-0aaa", false},
+		{"counting down peer with a higher ID", common.FleetStateCountdown, "i-0ccc", true},
+		{"counting down peer with a lower ID", common.FleetStateCountdown, "i-0aaa", false},
+		{"stopped peer", common.FleetStateStopped, "i-0ccc", false},
+		{"unknown peer", common.FleetStateError, "i-0ccc", false},
+	}
+	for _, tt := range tests {
+		running := runningPeers([]common.FleetMemberStatus{{InstanceID: tt.peer, State: tt.state}}, "i-0bbb")
+		if got := slices.Contains(running, tt.peer); got != tt.want {
+			t.Errorf("%s: expected running=%v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRunningPeersLowerIDFirst(t *testing.T) {
+	// Two idle replicas, one needed: each sees the other's state, and only
+	// the lower ID may go, counting on the other to stay
+	low, high := "i-0aaa", "i-0bbb"
+	idle := func(id string) []common.FleetMemberStatus {
+		return []common.FleetMemberStatus{{InstanceID: id, State: common.FleetStateIdle}}
+	}
+	if running := runningPeers(idle(high), low); len(running) != 1 {
+		t.Errorf("Expected the lower ID to count the higher as running, got %v", running)
+	}
+	if running := runningPeers(idle(low), high); len(running) != 0 {
+		t.Errorf("Expected the higher ID not to count the lower as running, got %v", running)
+	}
+}
+
+func TestPeerCheck(t *testing.T) {
+	var peers []common.FleetMemberStatus
+	checker := &peerChecker{
+		instanceStates: &instanceStates{
+			read: func(ctx context.Context) ([]common.FleetMemberStatus, error) {
+				return peers, nil
+			},
+		},
+		self:       func() string { return "i-0bbb" },
+		minRunning: 2,
+	}
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	peers = []common.FleetMemberStatus{
+		{InstanceID: "i-0aaa", State: common.FleetStateActive},
+		{InstanceID: "i-0ccc", State: common.FleetStateIdle},
+	}
+	if reason := checker.Check(context.Background(), now); reason != "" {
+		t.Errorf("Expected enough peers left running, got %q", reason)
+	}
+
+	peers = []common.FleetMemberStatus{
+		{InstanceID: "i-0aaa", State: common.FleetStateIdle},
+		{InstanceID: "i-0ccc", State: common.FleetStateStopped},
+	}
+	if reason := checker.Check(context.Background(), now); !strings.Contains(reason, "no peer") {
+		t.Errorf("Expected the snooze to be suppressed with no peer running, got %q", reason)
+	}
+
+	peers = []common.FleetMemberStatus{{InstanceID: "i-0ccc", State: common.FleetStateActive}}
+	if reason := checker.Check(context.Background(), now); !strings.Contains(reason, "only 1 peers") {
+		t.Errorf("Expected the snooze to be suppressed with one peer running, got %q", reason)
+	}
+
+	var none *peerChecker
+	if reason := none.Check(context.Background(), now); reason != "" {
+		t.Errorf("Expected no peers to allow the snooze, got %q", reason)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/docker"
+	"github.com/scttfrdmn/cloudsnooze/daemon/hooks"
+	"github.com/scttfrdmn/cloudsnooze/daemon/kubernetes"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+	"github.com/scttfrdmn/cloudsnooze/daemon/notify"
+)
+
+// Steps of the snooze pipeline
+const (
+	stepNotify      = "notify"
+	stepHooks       = "hooks"
+	stepTraining    = "training"
+	stepDatabases   = "databases"
+	stepDrain       = "drain"
+	stepContainers  = "containers"
+	stepSnapshot    = "snapshot"
+	stepFilesystems = "filesystems"
+	stepCommand     = "command"
+	stepStop        = "stop"
+)
+
+// pipelineSteps lists the valid pipeline steps
+var pipelineSteps = []string{stepNotify, stepHooks, stepTraining, stepDatabases, stepDrain, stepContainers, stepSnapshot, stepFilesystems, stepCommand, stepStop}
+
+// What a pipeline does when a step fails
+const (
+	failureAbort    = "abort"
+	failureContinue = "continue"
+)
+
+// errNothingToDo is returned by a step with nothing configured, which is
+// left out of the results
+var errNothingToDo = errors.New("nothing to do")
+
+// defaultPipeline returns the steps run when no pipeline is configured:
+// everything that's configured, with only training checkpoints and node
+// drains able to abandon the snooze
+func defaultPipeline() []PipelineStepConfig {
+	return []PipelineStepConfig{
+		{Step: stepHooks, OnFailure: failureContinue},
+		{Step: stepTraining, OnFailure: failureAbort},
+		{Step: stepDatabases, OnFailure: failureContinue},
+		{Step: stepDrain, OnFailure: failureAbort},
+		{Step: stepContainers, OnFailure: failureContinue},
+		{Step: stepFilesystems, OnFailure: failureContinue},
+		{Step: stepStop, OnFailure: failureAbort},
+	}
+}
+
+// snoozePipeline carries out the steps of a snooze
+type snoozePipeline struct {
+	config     Config
+	provider   common.CloudProvider
+	notifier   *notify.Dispatcher
+	hookRunner *hooks.Runner
+	containers *docker.Stopper
+	drainer    *kubernetes.Drainer
+	action     idleAction
+	idle       time.Duration
+	ran        []string // Steps that were run, in order
+	unmounted  []string // Filesystems the filesystems step unmounted
+}
+
+// newSnoozePipeline returns the pipeline for config, ending in action
+func newSnoozePipeline(config Config, provider common.CloudProvider, notifier *notify.Dispatcher, action idleAction, idle time.Duration) *snoozePipeline {
+	return &snoozePipeline{
+		config:     config,
+		provider:   provider,
+		notifier:   notifier,
+		hookRunner: newHookRunner(config.Hooks),
+		containers: newContainerStopper(config.Docker),
+		drainer:    newNodeDrainer(config.Kubernetes),
+		action:     action,
+		idle:       idle,
+	}
+}
+
+// run runs the steps in order, adding their results to the event and
+// calling record after each one. A failed step set to abort skips the
+// rest, including the stop, and its error is returned.
+func (p *snoozePipeline) run(ctx context.Context, event *monitor.SnoozeEvent, record func()) error {
+	steps := p.config.Pipeline
+	if len(steps) == 0 {
+		steps = defaultPipeline()
+	}
+
+	var failed error
+	for _, step := range steps {
+		if failed != nil {
+			event.Steps = append(event.Steps, monitor.StepResult{Step: step.Step, Status: monitor.StepSkipped})
+			continue
+		}
+
+		start := time.Now()
+		err := p.runStep(ctx, step, *event)
+		if errors.Is(err, errNothingToDo) {
+			continue
+		}
+		// A failed step may have done part of its work
+		p.ran = append(p.ran, step.Step)
+		result := monitor.StepResult{
+			Step:         step.Step,
+			Status:       monitor.StepOK,
+			DurationSecs: time.Since(start).Round(time.Millisecond).Seconds(),
+		}
+		if err != nil {
+			result.Status = monitor.StepFailed
+			result.Error = err.Error()
+			logger().Warn("Snooze step failed", "step", step.Step, "on_failure", onFailure(step), "error", err)
+			if onFailure(step) == failureAbort {
+				failed = fmt.Errorf("%s step failed: %v", step.Step, err)
+			}
+		} else {
+			logger().Info("Snooze step done", "step", step.Step, "took", time.Since(start).Round(time.Millisecond).String())
+		}
+		event.Steps = append(event.Steps, result)
+		record()
+	}
+	if failed != nil {
+		record()
+	}
+	return failed
+}
+
+// rollback undoes the steps that were run, newest first, for an instance
+// that is still running: because a step failed, including the stop, or
+// because the action, such as suspend, leaves it running. Each step is
+// tried whatever happens to the others, and failures are logged.
+func (p *snoozePipeline) rollback(ctx context.Context) {
+	for i := len(p.ran) - 1; i >= 0; i-- {
+		step := p.ran[i]
+		var err error
+		switch step {
+		case stepHooks:
+			err = p.hookRunner.PostStart(ctx)
+		case stepDrain:
+			err = p.drainer.Uncordon(ctx)
+		case stepContainers:
+			err = p.containers.StartStopped(ctx)
+		case stepFilesystems:
+			err = remountFilesystems(ctx, p.unmounted)
+			p.unmounted = nil
+		case stepStop:
+			// The provider may have left load balancers before failing
+			rollbacker, ok := p.provider.(common.StopRollbacker)
+			if !ok {
+				continue
+			}
+			err = rollbacker.RollbackStop(ctx)
+		default:
+			continue
+		}
+		if err != nil {
+			logger().Warn("Failed to roll back snooze step", "step", step, "error", err)
+			continue
+		}
+		logger().Debug("Rolled back snooze step", "step", step)
+	}
+	p.ran = nil
+}
+
+// onFailure returns the step's failure policy
+func onFailure(step PipelineStepConfig) string {
+	if step.OnFailure == "" {
+		return failureAbort
+	}
+	return step.OnFailure
+}
+
+// runStep runs a step within its timeout
+func (p *snoozePipeline) runStep(ctx context.Context, step PipelineStepConfig, event monitor.SnoozeEvent) error {
+	if step.TimeoutSecs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(step.TimeoutSecs)*time.Second)
+		defer cancel()
+	}
+
+	switch step.Step {
+	case stepNotify:
+		// Let people object before anything is taken down
+		p.notifier.Notify(notify.Notification{
+			Type:         notify.NotificationSnoozing,
+			Event:        event,
+			IdleDuration: p.idle,
+			HourlyCost:   p.config.Notifications.HourlyCostUSD,
+		})
+		return nil
+	case stepHooks:
+		// Save state for the post_start hooks
+		if !p.hookRunner.Enabled() {
+			return errNothingToDo
+		}
+		return p.hookRunner.PreStop(ctx, event.Reason, event.Trigger, event.InstanceID)
+	case stepTraining:
+		if len(p.config.Training.Checkpoints) == 0 {
+			return errNothingToDo
+		}
+		return checkpointTraining(ctx, p.config.Training)
+	case stepDatabases:
+		if len(p.config.Databases.Checkpoints) == 0 {
+			return errNothingToDo
+		}
+		return checkpointDatabases(ctx, p.config.Databases)
+	case stepDrain:
+		if p.drainer == nil {
+			return errNothingToDo
+		}
+		return p.drainer.Drain(ctx)
+	case stepContainers:
+		// Let containers run their shutdown handlers
+		if p.containers == nil {
+			return errNothingToDo
+		}
+		return p.containers.StopAll(ctx)
+	case stepSnapshot:
+		snapshotter, ok := p.provider.(common.VolumeSnapshotter)
+		if !ok {
+			return errors.New("the cloud provider can't snapshot volumes")
+		}
+		_, err := snapshotter.SnapshotVolumes(ctx, "CloudSnooze: "+event.Reason, step.Keep)
+		return err
+	case stepFilesystems:
+		// Don't leave cached writes behind if the stop is abrupt
+		if !p.config.Filesystems.SyncBeforeStop && len(p.config.Filesystems.Unmount) == 0 {
+			return errNothingToDo
+		}
+		unmounted, err := prepareFilesystems(ctx, p.config.Filesystems)
+		p.unmounted = append(p.unmounted, unmounted...)
+		return err
+	case stepCommand:
+		if err := runSnoozeCommand(ctx, step.Command, event); err != nil {
+			return fmt.Errorf("%s failed: %v", step.Command, err)
+		}
+		return nil
+	case stepStop:
+		return p.action.Run(ctx, event)
+	}
+	return fmt.Errorf("unknown step %q", step.Step)
+}
@@ -0,0 +1,187 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+func TestPipelineRun(t *testing.T) {
+	dir := t.TempDir()
+	ok := writeScript(t, dir, "ok", "true")
+	fail := writeScript(t, dir, "fail", "exit 1")
+	provider := &fakeProvider{}
+
+	config := DefaultConfig()
+	config.Pipeline = []PipelineStepConfig{
+		{Step: stepHooks},
+		{Step: stepCommand, Command: ok},
+		{Step: stepCommand, Command: fail, OnFailure: failureContinue},
+		{Step: stepStop},
+	}
+	pipeline := newSnoozePipeline(config, provider, nil, cloudStopAction{provider: provider}, time.Hour)
+	event := monitor.SnoozeEvent{Reason: "idle"}
+	records := 0
+	if err := pipeline.run(context.Background(), &event, func() { records++ }); err != nil {
+		t.Fatalf("run returned error: %v", err)
+	}
+
+	// The hooks step has nothing to do, so isn't recorded
+	if len(event.Steps) != 3 || records != 3 {
+		t.Fatalf("Expected 3 recorded steps, got %+v after %d records", event.Steps, records)
+	}
+	for i, want := range []string{monitor.StepOK, monitor.StepFailed, monitor.StepOK} {
+		if event.Steps[i].Status != want {
+			t.Errorf("Step %d: expected %s, got %+v", i, want, event.Steps[i])
+		}
+	}
+	if len(provider.stops) != 1 {
+		t.Errorf("Expected a failed step set to continue to let the stop go ahead, got %d stops", len(provider.stops))
+	}
+}
+
+func TestPipelineAbort(t *testing.T) {
+	dir := t.TempDir()
+	fail := writeScript(t, dir, "fail", "exit 1")
+	provider := &fakeProvider{}
+
+	config := DefaultConfig()
+	config.Pipeline = []PipelineStepConfig{
+		{Step: stepCommand, Command: fail},
+		{Step: stepCommand, Command: writeScript(t, dir, "never", "touch "+filepath.Join(dir, "ran"))},
+		{Step: stepStop},
+	}
+	pipeline := newSnoozePipeline(config, provider, nil, cloudStopAction{provider: provider}, time.Hour)
+	event := monitor.SnoozeEvent{Reason: "idle"}
+	if err := pipeline.run(context.Background(), &event, func() {}); err == nil {
+		t.Fatal("Expected a failed step set to abort to fail the pipeline")
+	}
+
+	if len(event.Steps) != 3 || event.Steps[0].Status != monitor.StepFailed {
+		t.Fatalf("Expected the failed step and the skipped ones, got %+v", event.Steps)
+	}
+	for _, step := range event.Steps[1:] {
+		if step.Status != monitor.StepSkipped {
+			t.Errorf("Expected %s to be skipped, got %s", step.Step, step.Status)
+		}
+	}
+	if len(provider.stops) != 0 {
+		t.Errorf("Expected no stop after an aborted step, got %d", len(provider.stops))
+	}
+}
+
+func TestPipelineSlowStep(t *testing.T) {
+	dir := t.TempDir()
+	provider := &fakeProvider{}
+	config := DefaultConfig()
+	config.Pipeline = []PipelineStepConfig{
+		{Step: stepCommand, Command: writeScript(t, dir, "slow", "exec sleep 5"), TimeoutSecs: 1},
+		{Step: stepStop},
+	}
+	pipeline := newSnoozePipeline(config, provider, nil, cloudStopAction{provider: provider}, time.Hour)
+	event := monitor.SnoozeEvent{Reason: "idle"}
+	if err := pipeline.run(context.Background(), &event, func() {}); err == nil || len(provider.stops) != 0 {
+		t.Errorf("Expected a step past its timeout to abort the snooze, got %v", err)
+	}
+}
+
+func TestPipelineRollback(t *testing.T) {
+	dir := t.TempDir()
+	restored := filepath.Join(dir, "restored")
+	provider := &fakeProvider{stopErr: errors.New("InsufficientInstanceCapacity")}
+
+	config := DefaultConfig()
+	config.Hooks = HooksConfig{
+		Commands: []HookConfig{{
+			Name:      "layout",
+			PreStop:   writeScript(t, dir, "save", `echo "layout"`),
+			PostStart: writeScript(t, dir, "restore", "cat > "+restored),
+		}},
+		ContextPath: filepath.Join(dir, "hooks.json"),
+		TimeoutSecs: 5,
+	}
+	config.Pipeline = []PipelineStepConfig{
+		{Step: stepHooks},
+		{Step: stepCommand, Command: writeScript(t, dir, "ok", "true")},
+		{Step: stepStop},
+	}
+	pipeline := newSnoozePipeline(config, provider, nil, cloudStopAction{provider: provider}, time.Hour)
+	event := monitor.SnoozeEvent{Reason: "idle"}
+	if err := pipeline.run(context.Background(), &event, func() {}); err == nil {
+		t.Fatal("Expected the failed stop to fail the pipeline")
+	}
+	if len(pipeline.ran) != 3 {
+		t.Fatalf("Expected the failed stop to count as run, got %v", pipeline.ran)
+	}
+
+	pipeline.rollback(context.Background())
+	if data, err := os.ReadFile(restored); err != nil || string(data) != "layout\n" {
+		t.Errorf("Expected the post_start hook to restore the saved layout, got %q, %v", data, err)
+	}
+	if provider.rollbacks != 1 {
+		t.Errorf("Expected the provider to undo its stop, got %d rollbacks", provider.rollbacks)
+	}
+	if len(pipeline.ran) != 0 {
+		t.Errorf("Expected nothing left to roll back, got %v", pipeline.ran)
+	}
+
+	// A second rollback has nothing to undo
+	pipeline.rollback(context.Background())
+	if provider.rollbacks != 1 {
+		t.Errorf("Expected one rollback, got %d", provider.rollbacks)
+	}
+}
+
+func TestPipelineRollbackAbortedSteps(t *testing.T) {
+	dir := t.TempDir()
+	provider := &fakeProvider{}
+
+	// Steps after the one that failed weren't run, so aren't undone
+	config := DefaultConfig()
+	config.Pipeline = []PipelineStepConfig{
+		{Step: stepCommand, Command: writeScript(t, dir, "fail", "exit 1")},
+		{Step: stepStop},
+	}
+	pipeline := newSnoozePipeline(config, provider, nil, cloudStopAction{provider: provider}, time.Hour)
+	event := monitor.SnoozeEvent{Reason: "idle"}
+	pipeline.run(context.Background(), &event, func() {})
+	pipeline.rollback(context.Background())
+	if provider.rollbacks != 0 || len(provider.stops) != 0 {
+		t.Errorf("Expected the skipped stop not to be rolled back, got %d rollbacks", provider.rollbacks)
+	}
+}
+
+func TestPipelineRollbackRemounts(t *testing.T) {
+	fakeMeminfo(t, "Dirty: 0 kB\n")
+	calls := fakeFilesystemCalls(t, "/shared")
+	provider := &fakeProvider{stopErr: errors.New("InsufficientInstanceCapacity")}
+
+	config := DefaultConfig()
+	config.Filesystems = FilesystemsConfig{Unmount: []string{"/scratch", "/shared", "/data"}, FlushTimeoutSecs: 1}
+	config.Pipeline = []PipelineStepConfig{
+		{Step: stepFilesystems, OnFailure: failureContinue},
+		{Step: stepStop},
+	}
+	pipeline := newSnoozePipeline(config, provider, nil, cloudStopAction{provider: provider}, time.Hour)
+	event := monitor.SnoozeEvent{Reason: "idle"}
+	if err := pipeline.run(context.Background(), &event, func() {}); err == nil {
+		t.Fatal("Expected the failed stop to fail the pipeline")
+	}
+
+	// Only what was unmounted is mounted again
+	pipeline.rollback(context.Background())
+	if want := []string{"/data", "/scratch"}; !slices.Equal(calls.mounted, want) {
+		t.Errorf("Expected %v to be mounted again, got %v", want, calls.mounted)
+	}
+}
@@ -5,13 +5,11 @@ package aws
 
 import (
 	"errors"
-	"log"
-	"net/http"
 	"os"
-	"time"
 
 	"github.com/scttfrdmn/cloudsnooze/daemon/cloud/aws"
 	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
 	"github.com/scttfrdmn/cloudsnooze/daemon/plugin"
 	cloudplugin "github.com/scttfrdmn/cloudsnooze/daemon/plugin/cloud"
 )
@@ -91,24 +89,16 @@ func (p *AWSPlugin) Detect() (bool, error) {
 	// Check if we're in a CI environment
 	if os.Getenv("CI") == "true" || os.Getenv("GITHUB_ACTIONS") == "true" {
 		// Skip actual detection in CI environments to avoid failures
-		log.Println("AWS detection skipped in CI environment")
+		logging.Component("provider").Info("AWS detection skipped in CI environment", "provider", "aws")
 		return false, nil
 	}
 
 	// Check for AWS instance metadata service
 	if _, err := os.Stat("/sys/devices/virtual/dmi/id/product_uuid"); err == nil {
-		// Check if we can access the instance metadata service
-		client := &http.Client{Timeout: 2 * time.Second}
-		resp, err := client.Get("http://169.254.169.254/latest/meta-data")
-		if err == nil {
-			defer func() {
-				if closeErr := resp.Body.Close(); closeErr != nil {
-					log.Printf("Error closing response body: %v", closeErr)
-				}
-			}()
-			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-				return true, nil
-			}
+		// Check if we can access the instance metadata service; this uses
+		// IMDSv2 so instances that require session tokens are detected too
+		if aws.MetadataAvailable() {
+			return true, nil
 		}
 	}
 	
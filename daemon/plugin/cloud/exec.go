@@ -0,0 +1,123 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package cloud
+
+import (
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/plugin"
+)
+
+// Methods implemented by exec cloud provider plugins
+const (
+	MethodDetect            = "detect"
+	MethodVerifyPermissions = "verify_permissions"
+	MethodGetInstanceInfo   = "get_instance_info"
+	MethodStopInstance      = "stop_instance"
+	MethodStartInstance     = "start_instance"
+	MethodTagInstance       = "tag_instance"
+	MethodGetExternalTags   = "get_external_tags"
+)
+
+// ExecProviderPlugin exposes an out-of-process plugin as a cloud provider.
+// Detection is attempted if the manifest declares the "detect" capability.
+type ExecProviderPlugin struct {
+	*plugin.ExecPlugin
+}
+
+// CreateProvider sends the provider configuration to the plugin process
+func (p *ExecProviderPlugin) CreateProvider(config interface{}) (common.CloudProvider, error) {
+	if err := p.Init(config); err != nil {
+		return nil, err
+	}
+	if err := p.Start(); err != nil {
+		return nil, err
+	}
+	return &execProvider{plugin: p.ExecPlugin}, nil
+}
+
+// CanDetect returns true if the manifest declares the detect capability
+func (p *ExecProviderPlugin) CanDetect() bool {
+	return p.Info().Capabilities["detect"]
+}
+
+// Detect asks the plugin whether it is running on its cloud provider
+func (p *ExecProviderPlugin) Detect() (bool, error) {
+	if err := p.Start(); err != nil {
+		return false, err
+	}
+
+	var detected bool
+	err := p.Call(MethodDetect, nil, &detected)
+	return detected, err
+}
+
+// execInstanceInfo is the wire format of common.InstanceInfo
+type execInstanceInfo struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Region     string            `json:"region"`
+	Provider   string            `json:"provider"`
+	LaunchTime string            `json:"launch_time"`
+	Tags       map[string]string `json:"tags"`
+}
+
+// execProvider implements CloudProvider by calling the plugin process
+type execProvider struct {
+	plugin *plugin.ExecPlugin
+}
+
+// VerifyPermissions checks if the daemon has sufficient permissions
+func (p *execProvider) VerifyPermissions() (bool, error) {
+	var ok bool
+	err := p.plugin.Call(MethodVerifyPermissions, nil, &ok)
+	return ok, err
+}
+
+// GetInstanceInfo retrieves information about the current instance
+func (p *execProvider) GetInstanceInfo() (*common.InstanceInfo, error) {
+	var info execInstanceInfo
+	if err := p.plugin.Call(MethodGetInstanceInfo, nil, &info); err != nil {
+		return nil, err
+	}
+
+	return &common.InstanceInfo{
+		ID:         info.ID,
+		Type:       info.Type,
+		Region:     info.Region,
+		Provider:   info.Provider,
+		LaunchTime: info.LaunchTime,
+		Tags:       info.Tags,
+	}, nil
+}
+
+// StopInstance stops the current instance
+func (p *execProvider) StopInstance(reason string, metrics common.SystemMetrics) error {
+	return p.plugin.Call(MethodStopInstance, map[string]interface{}{
+		"reason":  reason,
+		"metrics": metrics,
+	}, nil)
+}
+
+// StartInstance starts another instance
+func (p *execProvider) StartInstance(instanceID string) error {
+	return p.plugin.Call(MethodStartInstance, map[string]interface{}{"instance_id": instanceID}, nil)
+}
+
+// TagInstance adds tags to the current instance
+func (p *execProvider) TagInstance(tags map[string]string) error {
+	return p.plugin.Call(MethodTagInstance, map[string]interface{}{"tags": tags}, nil)
+}
+
+// GetExternalTags checks for tags from external systems that might control this instance
+func (p *execProvider) GetExternalTags() (map[string]string, error) {
+	var tags map[string]string
+	err := p.plugin.Call(MethodGetExternalTags, nil, &tags)
+	return tags, err
+}
+
+func init() {
+	plugin.RegisterExecAdapter(plugin.TypeCloudProvider, func(p *plugin.ExecPlugin) plugin.Plugin {
+		return &ExecProviderPlugin{ExecPlugin: p}
+	})
+}
@@ -0,0 +1,429 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/health"
+)
+
+// Out-of-process plugins are standalone executables that the daemon starts
+// and talks to over stdin/stdout. Each request is a single line of JSON:
+//
+//	{"id": 1, "method": "stop_instance", "params": {...}}
+//
+// and the plugin answers with a single line carrying the same ID:
+//
+//	{"id": 1, "result": {...}}  or  {"id": 1, "error": "message"}
+//
+// Every plugin must handle "init" (params: the plugin configuration) and
+// "shutdown". Other methods depend on the plugin type. Anything the plugin
+// writes to stderr is copied to the daemon log.
+
+// Standard exec plugin methods
+const (
+	MethodInit     = "init"
+	MethodShutdown = "shutdown"
+)
+
+// DefaultCallTimeout bounds how long an exec plugin may take to answer
+const DefaultCallTimeout = 30 * time.Second
+
+// execRequest is a request sent to an exec plugin
+type execRequest struct {
+	ID     int64       `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params,omitempty"`
+}
+
+// execResponse is a response read from an exec plugin
+type execResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// ExecPlugin runs a plugin as a separate process
+type ExecPlugin struct {
+	info    PluginInfo
+	path    string
+	args    []string
+	timeout time.Duration
+
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	pending   map[int64]chan execResponse
+	nextID    int64
+	running   bool
+	stopping  bool
+	exited    chan struct{}
+	config    interface{}
+	limits    ResourceLimits
+	interval  time.Duration
+	group     *cgroup
+	violation string
+	lock      sync.Mutex
+	writeLock sync.Mutex
+}
+
+// NewExecPlugin creates an out-of-process plugin for the executable at path
+func NewExecPlugin(info PluginInfo, path string, args ...string) *ExecPlugin {
+	return &ExecPlugin{
+		info:     info,
+		path:     path,
+		args:     args,
+		timeout:  DefaultCallTimeout,
+		interval: DefaultLimitCheckInterval,
+	}
+}
+
+// SetLimits sets the resource limits applied when the plugin process starts.
+// On Linux they are enforced with cgroups where available; on all supported
+// platforms the daemon also samples usage and kills plugins that exceed
+// their memory limit or stay pinned at their CPU limit.
+func (p *ExecPlugin) SetLimits(limits ResourceLimits) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.limits = limits
+}
+
+// healthComponent names the plugin in health checks
+func (p *ExecPlugin) healthComponent() string {
+	return "plugin:" + p.info.ID
+}
+
+// Info returns plugin metadata from the manifest
+func (p *ExecPlugin) Info() PluginInfo {
+	return p.info
+}
+
+// Init stores the configuration sent to the process when it starts
+func (p *ExecPlugin) Init(config interface{}) error {
+	p.lock.Lock()
+	p.config = config
+	running := p.running
+	p.lock.Unlock()
+
+	if running {
+		return p.Call(MethodInit, config, nil)
+	}
+	return nil
+}
+
+// Start launches the plugin process and sends it the configuration
+func (p *ExecPlugin) Start() error {
+	p.lock.Lock()
+	if p.running {
+		p.lock.Unlock()
+		return nil
+	}
+
+	cmd := exec.Command(p.path, p.args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		p.lock.Unlock()
+		return fmt.Errorf("failed to create stdin for plugin %s: %v", p.info.ID, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		p.lock.Unlock()
+		return fmt.Errorf("failed to create stdout for plugin %s: %v", p.info.ID, err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		p.lock.Unlock()
+		return fmt.Errorf("failed to create stderr for plugin %s: %v", p.info.ID, err)
+	}
+	if err := cmd.Start(); err != nil {
+		p.lock.Unlock()
+		return fmt.Errorf("failed to start plugin %s: %v", p.info.ID, err)
+	}
+
+	// The process runs unconfined until it joins its cgroup, but it can't
+	// do much before it has read its configuration
+	group, err := newCgroup(p.info.ID, cmd.Process.Pid, p.limits)
+	if err != nil {
+		logger().Warn("Failed to apply cgroup limits", "plugin", p.info.ID, "error", err)
+	}
+
+	p.cmd = cmd
+	p.stdin = stdin
+	p.pending = make(map[int64]chan execResponse)
+	p.running = true
+	p.stopping = false
+	p.exited = make(chan struct{})
+	p.group = group
+	p.violation = ""
+	config := p.config
+	limits := p.limits
+	exited := p.exited
+	p.lock.Unlock()
+
+	go p.readResponses(stdout)
+	go p.logStderr(stderr)
+	go p.wait(cmd)
+	if limits.enabled() {
+		go p.watchResources(cmd, limits, exited)
+	}
+	health.Set(p.healthComponent(), health.OK, "running")
+
+	if err := p.Call(MethodInit, config, nil); err != nil {
+		p.Stop()
+		return fmt.Errorf("failed to initialize plugin %s: %v", p.info.ID, err)
+	}
+	return nil
+}
+
+// Stop asks the plugin to shut down, killing it if it doesn't exit in time
+func (p *ExecPlugin) Stop() error {
+	p.lock.Lock()
+	if !p.running {
+		p.lock.Unlock()
+		return nil
+	}
+	p.stopping = true
+	cmd := p.cmd
+	exited := p.exited
+	p.lock.Unlock()
+
+	// Ignore errors, the plugin may exit before answering
+	p.callWithTimeout(MethodShutdown, nil, nil, 5*time.Second)
+	p.stdin.Close()
+
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		logger().Warn("Plugin did not exit, killing it", "plugin", p.info.ID)
+		cmd.Process.Kill()
+		<-exited
+	}
+	return nil
+}
+
+// IsRunning returns true if the plugin process is running
+func (p *ExecPlugin) IsRunning() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.running
+}
+
+// Pid returns the process ID of the running plugin, or 0 if not running
+func (p *ExecPlugin) Pid() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if !p.running || p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+// Call invokes a method on the plugin and decodes the result into result,
+// which may be nil if the result is not needed
+func (p *ExecPlugin) Call(method string, params interface{}, result interface{}) error {
+	return p.callWithTimeout(method, params, result, p.timeout)
+}
+
+// callWithTimeout sends a request and waits for the matching response
+func (p *ExecPlugin) callWithTimeout(method string, params interface{}, result interface{}, timeout time.Duration) error {
+	p.lock.Lock()
+	if !p.running {
+		p.lock.Unlock()
+		return fmt.Errorf("plugin %s is not running", p.info.ID)
+	}
+	p.nextID++
+	id := p.nextID
+	responseChan := make(chan execResponse, 1)
+	p.pending[id] = responseChan
+	exited := p.exited
+	p.lock.Unlock()
+
+	defer func() {
+		p.lock.Lock()
+		delete(p.pending, id)
+		p.lock.Unlock()
+	}()
+
+	data, err := json.Marshal(execRequest{ID: id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("failed to serialize request: %v", err)
+	}
+
+	// Requests are written whole so lines from concurrent calls don't interleave
+	p.writeLock.Lock()
+	_, err = p.stdin.Write(append(data, '\n'))
+	p.writeLock.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to send request to plugin %s: %v", p.info.ID, err)
+	}
+
+	select {
+	case response := <-responseChan:
+		if response.Error != "" {
+			return fmt.Errorf("plugin %s: %s", p.info.ID, response.Error)
+		}
+		if result != nil && len(response.Result) > 0 {
+			if err := json.Unmarshal(response.Result, result); err != nil {
+				return fmt.Errorf("invalid response from plugin %s: %v", p.info.ID, err)
+			}
+		}
+		return nil
+	case <-exited:
+		return fmt.Errorf("plugin %s exited", p.info.ID)
+	case <-time.After(timeout):
+		return fmt.Errorf("plugin %s did not answer %s within %s", p.info.ID, method, timeout)
+	}
+}
+
+// readResponses routes response lines to waiting callers
+func (p *ExecPlugin) readResponses(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+
+	for scanner.Scan() {
+		var response execResponse
+		if err := json.Unmarshal(scanner.Bytes(), &response); err != nil {
+			logger().Warn("Invalid output from plugin", "plugin", p.info.ID, "error", err)
+			continue
+		}
+
+		p.lock.Lock()
+		responseChan, ok := p.pending[response.ID]
+		p.lock.Unlock()
+		if ok {
+			responseChan <- response
+		}
+	}
+}
+
+// logStderr copies the plugin's stderr to the daemon log
+func (p *ExecPlugin) logStderr(stderr io.Reader) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		logger().Info(scanner.Text(), "plugin", p.info.ID, "stream", "stderr")
+	}
+}
+
+// wait marks the plugin stopped when its process exits
+func (p *ExecPlugin) wait(cmd *exec.Cmd) {
+	err := cmd.Wait()
+
+	p.lock.Lock()
+	stopping := p.stopping
+	violation := p.violation
+	group := p.group
+	limits := p.limits
+	p.group = nil
+	p.running = false
+	close(p.exited)
+	p.lock.Unlock()
+
+	if group != nil {
+		if violation == "" && group.oomKilled() {
+			violation = fmt.Sprintf("killed by the kernel for exceeding its memory limit of %d MB", limits.MemoryMB)
+		}
+		group.remove()
+	}
+
+	switch {
+	case violation != "":
+		logger().Warn("Plugin stopped for exceeding its resource limits", "plugin", p.info.ID, "reason", violation)
+		health.Set(p.healthComponent(), health.Failed, violation)
+	case err != nil && !stopping:
+		logger().Warn("Plugin exited", "plugin", p.info.ID, "error", err)
+		health.Set(p.healthComponent(), health.Failed, fmt.Sprintf("exited: %v", err))
+	default:
+		health.Default.Remove(p.healthComponent())
+	}
+}
+
+// watchResources samples the plugin's resource usage and kills it if it
+// exceeds its memory limit or stays at its CPU limit
+func (p *ExecPlugin) watchResources(cmd *exec.Cmd, limits ResourceLimits, exited chan struct{}) {
+	last, err := readProcessUsage(cmd.Process.Pid)
+	if err != nil {
+		logger().Warn("Resource limits are not enforced", "plugin", p.info.ID, "error", err)
+		return
+	}
+	lastTime := time.Now()
+	busy := 0
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-exited:
+			return
+		case <-ticker.C:
+		}
+
+		usage, err := readProcessUsage(cmd.Process.Pid)
+		if err != nil {
+			// The process has most likely just exited
+			continue
+		}
+		now := time.Now()
+
+		var violation string
+		if limits.MemoryMB > 0 && usage.RSS > uint64(limits.MemoryMB)*1024*1024 {
+			violation = fmt.Sprintf("was killed for using %d MB of memory, above its limit of %d MB", usage.RSS/(1024*1024), limits.MemoryMB)
+		}
+
+		if limits.CPUPercent > 0 {
+			percent := float64(usage.CPU-last.CPU) / float64(now.Sub(lastTime)) * 100
+			if percent >= limits.CPUPercent*0.9 {
+				busy++
+			} else {
+				busy = 0
+			}
+			if busy >= runawaySamples && violation == "" {
+				violation = fmt.Sprintf("was killed for running at its CPU limit of %.0f%% for %s", limits.CPUPercent, time.Duration(busy)*p.interval)
+			}
+		}
+		last, lastTime = usage, now
+
+		if violation != "" {
+			p.lock.Lock()
+			p.violation = violation
+			p.lock.Unlock()
+			cmd.Process.Kill()
+			return
+		}
+	}
+}
+
+// ExecAdapter wraps an exec plugin in the interface for its plugin type
+type ExecAdapter func(p *ExecPlugin) Plugin
+
+var (
+	execAdapters    = make(map[string]ExecAdapter)
+	execAdapterLock sync.RWMutex
+)
+
+// RegisterExecAdapter registers how exec plugins of a given type are
+// exposed, so that e.g. an exec cloud provider implements CloudProviderPlugin
+func RegisterExecAdapter(pluginType string, adapter ExecAdapter) {
+	execAdapterLock.Lock()
+	defer execAdapterLock.Unlock()
+	execAdapters[pluginType] = adapter
+}
+
+// wrapExecPlugin applies the adapter registered for the plugin's type
+func wrapExecPlugin(p *ExecPlugin) (Plugin, error) {
+	execAdapterLock.RLock()
+	adapter, ok := execAdapters[p.info.Type]
+	execAdapterLock.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported plugin type %q for exec plugin %s", p.info.Type, p.info.ID)
+	}
+	return adapter(p), nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/health"
+)
+
+// ballast keeps memory allocated by the helper process alive
+var ballast []byte
+
+// TestHelperProcess is not a real test, it acts as an exec plugin when the
+// test binary is started by newHelperPlugin
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("CLOUDSNOOZE_HELPER_PLUGIN") != "1" {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			os.Exit(2)
+		}
+
+		response := map[string]interface{}{"id": request.ID}
+		switch request.Method {
+		case MethodInit:
+		case MethodShutdown:
+			json.NewEncoder(os.Stdout).Encode(response)
+			os.Exit(0)
+		case "echo":
+			response["result"] = request.Params
+		case "alloc":
+			var mb int
+			json.Unmarshal(request.Params, &mb)
+			ballast = make([]byte, mb*1024*1024)
+			for i := 0; i < len(ballast); i += 4096 {
+				ballast[i] = 1
+			}
+		default:
+			response["error"] = fmt.Sprintf("unknown method %s", request.Method)
+		}
+		json.NewEncoder(os.Stdout).Encode(response)
+	}
+	os.Exit(0)
+}
+
+func newHelperPlugin(t *testing.T, limits ResourceLimits) *ExecPlugin {
+	t.Setenv("CLOUDSNOOZE_HELPER_PLUGIN", "1")
+
+	// Never touch the host's real cgroups from tests
+	original := cgroupRoot
+	cgroupRoot = t.TempDir()
+	t.Cleanup(func() { cgroupRoot = original })
+
+	p := NewExecPlugin(PluginInfo{ID: "helper", Type: TypeNotifier}, os.Args[0], "-test.run=^TestHelperProcess$")
+	p.interval = 50 * time.Millisecond
+	p.SetLimits(limits)
+	if err := p.Start(); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	t.Cleanup(func() { p.Stop() })
+	return p
+}
+
+func TestExecPluginCall(t *testing.T) {
+	p := newHelperPlugin(t, ResourceLimits{})
+	if !p.IsRunning() || p.Pid() == 0 {
+		t.Fatal("Expected plugin process to be running")
+	}
+
+	var result map[string]string
+	if err := p.Call("echo", map[string]string{"hello": "world"}, &result); err != nil {
+		t.Fatalf("Call failed: %v", err)
+	}
+	if result["hello"] != "world" {
+		t.Errorf("Expected echoed params, got %v", result)
+	}
+
+	err := p.Call("missing", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown method missing") {
+		t.Errorf("Expected plugin error, got %v", err)
+	}
+
+	if err := p.Stop(); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	if p.IsRunning() {
+		t.Error("Expected plugin to be stopped")
+	}
+	if err := p.Call("echo", nil, nil); err == nil {
+		t.Error("Expected call to a stopped plugin to fail")
+	}
+}
+
+func TestExecPluginMemoryLimit(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("resource usage is not sampled on " + runtime.GOOS)
+	}
+
+	p := newHelperPlugin(t, ResourceLimits{MemoryMB: 32})
+	p.Call("alloc", 128, nil)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for p.IsRunning() && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if p.IsRunning() {
+		t.Fatal("Expected plugin over its memory limit to be killed")
+	}
+
+	var check *health.Check
+	for _, c := range health.Default.Checks() {
+		if c.Component == "plugin:helper" {
+			check = &c
+		}
+	}
+	if check == nil || check.State != health.Failed || !strings.Contains(check.Message, "memory") {
+		t.Errorf("Expected failed health check for memory limit, got %+v", check)
+	}
+}
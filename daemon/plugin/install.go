@@ -0,0 +1,199 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MaxInstallSize limits the size of a downloaded plugin archive
+const MaxInstallSize = 100 * 1024 * 1024
+
+// installClient downloads plugin archives
+var installClient = &http.Client{Timeout: 5 * time.Minute}
+
+// Install downloads a plugin archive (.tar.gz) over HTTPS and unpacks it into
+// dir/<id>, where id comes from the archive's manifest.json. The manifest may
+// be at the root of the archive or inside a single top-level directory. If
+// checksum is set, the archive's SHA-256 must match it. Returns the path of
+// the installed manifest.
+func Install(url, dir, checksum string) (string, error) {
+	if !strings.HasPrefix(url, "https://") {
+		return "", fmt.Errorf("plugin URLs must use https")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create plugin directory: %v", err)
+	}
+
+	archive, err := downloadArchive(url, dir, checksum)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(archive)
+
+	// Unpack next to the final location so the move is a rename
+	staging, err := os.MkdirTemp(dir, ".install-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %v", err)
+	}
+	defer os.RemoveAll(staging)
+
+	if err := extractArchive(archive, staging); err != nil {
+		return "", err
+	}
+
+	root, manifest, err := findManifest(staging)
+	if err != nil {
+		return "", err
+	}
+	if manifest.ID == "" || manifest.ID != filepath.Base(manifest.ID) || strings.HasPrefix(manifest.ID, ".") {
+		return "", fmt.Errorf("invalid plugin ID %q in manifest", manifest.ID)
+	}
+
+	target := filepath.Join(dir, manifest.ID)
+	if _, err := os.Stat(target); err == nil {
+		return "", fmt.Errorf("plugin %s is already installed", manifest.ID)
+	}
+	if err := os.Rename(root, target); err != nil {
+		return "", fmt.Errorf("failed to install plugin %s: %v", manifest.ID, err)
+	}
+	if err := os.Chmod(target, 0755); err != nil {
+		return "", fmt.Errorf("failed to install plugin %s: %v", manifest.ID, err)
+	}
+
+	return filepath.Join(target, "manifest.json"), nil
+}
+
+// downloadArchive saves the archive at url to a temporary file in dir and
+// verifies its checksum
+func downloadArchive(url, dir, checksum string) (string, error) {
+	resp, err := installClient.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("failed to download plugin: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download plugin: %s", resp.Status)
+	}
+
+	file, err := os.CreateTemp(dir, ".download-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create download file: %v", err)
+	}
+
+	hash := sha256.New()
+	written, err := io.Copy(io.MultiWriter(file, hash), io.LimitReader(resp.Body, MaxInstallSize+1))
+	file.Close()
+	if err == nil && written > MaxInstallSize {
+		err = fmt.Errorf("archive is larger than %d bytes", MaxInstallSize)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return "", fmt.Errorf("failed to download plugin: %v", err)
+	}
+
+	if checksum != "" {
+		sum := hex.EncodeToString(hash.Sum(nil))
+		if !strings.EqualFold(sum, checksum) {
+			os.Remove(file.Name())
+			return "", fmt.Errorf("checksum mismatch: expected %s, got %s", checksum, sum)
+		}
+	}
+
+	return file.Name(), nil
+}
+
+// extractArchive unpacks a gzipped tar archive into dest, rejecting entries
+// that would land outside it
+func extractArchive(path, dest string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %v", err)
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("invalid plugin archive: %v", err)
+	}
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid plugin archive: %v", err)
+		}
+
+		name := filepath.Clean(header.Name)
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("invalid path in plugin archive: %s", header.Name)
+		}
+		target := filepath.Join(dest, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to extract %s: %v", header.Name, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("failed to extract %s: %v", header.Name, err)
+			}
+			// Keep executable bits but never setuid/setgid or world-writable files
+			mode := os.FileMode(header.Mode) & 0755
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %v", header.Name, err)
+			}
+			_, err = io.Copy(out, reader)
+			out.Close()
+			if err != nil {
+				return fmt.Errorf("failed to extract %s: %v", header.Name, err)
+			}
+		default:
+			return fmt.Errorf("unsupported entry in plugin archive: %s", header.Name)
+		}
+	}
+}
+
+// findManifest locates and parses the manifest in an unpacked archive,
+// returning the plugin's root directory
+func findManifest(dir string) (string, PluginInfo, error) {
+	var manifest PluginInfo
+
+	root := dir
+	if _, err := os.Stat(filepath.Join(root, "manifest.json")); err != nil {
+		entries, err := os.ReadDir(dir)
+		if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+			return "", manifest, fmt.Errorf("plugin archive has no manifest.json")
+		}
+		root = filepath.Join(dir, entries[0].Name())
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "manifest.json"))
+	if err != nil {
+		return "", manifest, fmt.Errorf("plugin archive has no manifest.json")
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", manifest, fmt.Errorf("failed to parse plugin manifest: %v", err)
+	}
+
+	return root, manifest, nil
+}
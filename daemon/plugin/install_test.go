@@ -0,0 +1,112 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeArchive builds a .tar.gz holding the given files
+func makeArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// serveArchive serves data over HTTPS and points the installer at the server
+func serveArchive(t *testing.T, data []byte) string {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	original := installClient
+	installClient = server.Client()
+	t.Cleanup(func() { installClient = original })
+
+	return server.URL + "/plugin.tar.gz"
+}
+
+func TestInstall(t *testing.T) {
+	data := makeArchive(t, map[string]string{
+		"myplugin/manifest.json": `{"id": "myplugin", "type": "notifier", "exec": "run.sh"}`,
+		"myplugin/run.sh":        "#!/bin/sh\n",
+	})
+	url := serveArchive(t, data)
+	dir := t.TempDir()
+
+	sum := sha256.Sum256(data)
+	manifestPath, err := Install(url, dir, hex.EncodeToString(sum[:]))
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if manifestPath != filepath.Join(dir, "myplugin", "manifest.json") {
+		t.Errorf("Unexpected manifest path %s", manifestPath)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "myplugin", "run.sh"))
+	if err != nil {
+		t.Fatalf("Plugin executable not installed: %v", err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Error("Expected executable bit to be preserved")
+	}
+
+	// Only the plugin directory should be left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the plugin directory, got %d entries", len(entries))
+	}
+
+	if _, err := Install(url, dir, ""); err == nil || !strings.Contains(err.Error(), "already installed") {
+		t.Errorf("Expected already installed error, got %v", err)
+	}
+}
+
+func TestInstallRejectsBadArchives(t *testing.T) {
+	dir := t.TempDir()
+
+	if _, err := Install("http://example.com/plugin.tar.gz", dir, ""); err == nil {
+		t.Error("Expected plain HTTP URL to be rejected")
+	}
+
+	url := serveArchive(t, makeArchive(t, map[string]string{
+		"manifest.json":   `{"id": "evil"}`,
+		"../../escape.sh": "#!/bin/sh\n",
+	}))
+	if _, err := Install(url, dir, ""); err == nil || !strings.Contains(err.Error(), "invalid path") {
+		t.Errorf("Expected path traversal to be rejected, got %v", err)
+	}
+
+	if _, err := Install(url, dir, "0000"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch, got %v", err)
+	}
+
+	url = serveArchive(t, makeArchive(t, map[string]string{
+		"manifest.json": `{"id": "../evil"}`,
+	}))
+	if _, err := Install(url, dir, ""); err == nil || !strings.Contains(err.Error(), "invalid plugin ID") {
+		t.Errorf("Expected invalid ID to be rejected, got %v", err)
+	}
+}
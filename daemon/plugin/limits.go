@@ -0,0 +1,282 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResourceLimits bounds the resources an exec plugin may use. Zero disables a limit.
+type ResourceLimits struct {
+	CPUPercent float64 // Share of one CPU, in percent
+	MemoryMB   int     // Resident memory
+}
+
+// enabled returns true if any limit is set
+func (l ResourceLimits) enabled() bool {
+	return l.CPUPercent > 0 || l.MemoryMB > 0
+}
+
+const (
+	// DefaultLimitCheckInterval is how often plugin resource usage is sampled
+	DefaultLimitCheckInterval = 5 * time.Second
+
+	// runawaySamples is how many consecutive samples a plugin may spend at
+	// its CPU limit before it is considered runaway and killed
+	runawaySamples = 12
+
+	// cpuPeriod is the cgroup CPU accounting period in microseconds
+	cpuPeriod = 100000
+)
+
+// cgroupRoot is where the cgroup filesystem is mounted
+var cgroupRoot = "/sys/fs/cgroup"
+
+// processUsage is a sample of a process's resource consumption
+type processUsage struct {
+	CPU time.Duration // Total CPU time used
+	RSS uint64        // Resident memory in bytes
+}
+
+// readProcessUsage samples the resource usage of a process
+func readProcessUsage(pid int) (processUsage, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return readLinuxProcessUsage(pid)
+	case "darwin", "freebsd", "openbsd":
+		return readPSProcessUsage(pid)
+	default:
+		return processUsage{}, fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+}
+
+// readLinuxProcessUsage reads CPU time and RSS from /proc/<pid>/stat
+func readLinuxProcessUsage(pid int) (processUsage, error) {
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return processUsage{}, err
+	}
+
+	// Fields after the command name, which may itself contain spaces
+	end := strings.LastIndexByte(string(data), ')')
+	if end < 0 {
+		return processUsage{}, fmt.Errorf("unexpected format of /proc/%d/stat", pid)
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 22 {
+		return processUsage{}, fmt.Errorf("unexpected format of /proc/%d/stat", pid)
+	}
+
+	// utime and stime are in clock ticks, which are 1/100s on Linux
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	pages, _ := strconv.ParseUint(fields[21], 10, 64)
+
+	return processUsage{
+		CPU: time.Duration(utime+stime) * 10 * time.Millisecond,
+		RSS: pages * uint64(os.Getpagesize()),
+	}, nil
+}
+
+// readPSProcessUsage reads CPU time and RSS using ps, which takes the same
+// options on macOS and the BSDs
+func readPSProcessUsage(pid int) (processUsage, error) {
+	output, err := exec.Command("ps", "-o", "rss=,time=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return processUsage{}, fmt.Errorf("failed to run ps: %v", err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) != 2 {
+		return processUsage{}, fmt.Errorf("unexpected ps output: %s", output)
+	}
+	rssKB, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return processUsage{}, fmt.Errorf("failed to parse RSS: %v", err)
+	}
+	cpu, err := parseCPUTime(fields[1])
+	if err != nil {
+		return processUsage{}, err
+	}
+
+	return processUsage{CPU: cpu, RSS: rssKB * 1024}, nil
+}
+
+// parseCPUTime parses ps CPU time in [[dd-]hh:]mm:ss.ss format
+func parseCPUTime(value string) (time.Duration, error) {
+	var days float64
+	if i := strings.IndexByte(value, '-'); i >= 0 {
+		d, err := strconv.ParseFloat(value[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU time %q", value)
+		}
+		days = d
+		value = value[i+1:]
+	}
+
+	var seconds float64
+	for _, part := range strings.Split(value, ":") {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CPU time %q", value)
+		}
+		seconds = seconds*60 + v
+	}
+	seconds += days * 24 * 3600
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// cgroup confines a plugin process on Linux
+type cgroup struct {
+	dirs []string
+	v2   bool
+}
+
+// newCgroup places pid in a cgroup that enforces limits. Returns nil if
+// cgroups are not available on this host.
+func newCgroup(id string, pid int, limits ResourceLimits) (*cgroup, error) {
+	if runtime.GOOS != "linux" || !limits.enabled() {
+		return nil, nil
+	}
+
+	name := "plugin-" + id
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err == nil {
+		return newCgroupV2(name, pid, limits)
+	}
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "memory")); err == nil {
+		return newCgroupV1(name, pid, limits)
+	}
+	return nil, nil
+}
+
+// newCgroupV2 creates a cgroup under the unified hierarchy
+func newCgroupV2(name string, pid int, limits ResourceLimits) (*cgroup, error) {
+	parent := filepath.Join(cgroupRoot, "cloudsnooze")
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %v", err)
+	}
+
+	// Controllers must be enabled at each level above the plugin's cgroup
+	for _, dir := range []string{cgroupRoot, parent} {
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+cpu +memory"), 0644); err != nil {
+			return nil, fmt.Errorf("failed to enable cgroup controllers: %v", err)
+		}
+	}
+
+	dir := filepath.Join(parent, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %v", err)
+	}
+	group := &cgroup{dirs: []string{dir}, v2: true}
+
+	settings := map[string]string{}
+	if limits.MemoryMB > 0 {
+		settings["memory.max"] = strconv.Itoa(limits.MemoryMB * 1024 * 1024)
+	}
+	if limits.CPUPercent > 0 {
+		settings["cpu.max"] = fmt.Sprintf("%d %d", int(limits.CPUPercent*cpuPeriod/100), cpuPeriod)
+	}
+	settings["cgroup.procs"] = strconv.Itoa(pid)
+
+	if err := group.write(dir, settings, "memory.max", "cpu.max", "cgroup.procs"); err != nil {
+		group.remove()
+		return nil, err
+	}
+	return group, nil
+}
+
+// newCgroupV1 creates cgroups in the separate memory and cpu hierarchies
+func newCgroupV1(name string, pid int, limits ResourceLimits) (*cgroup, error) {
+	group := &cgroup{}
+
+	if limits.MemoryMB > 0 {
+		dir := filepath.Join(cgroupRoot, "memory", "cloudsnooze", name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cgroup: %v", err)
+		}
+		group.dirs = append(group.dirs, dir)
+
+		err := group.write(dir, map[string]string{
+			"memory.limit_in_bytes": strconv.Itoa(limits.MemoryMB * 1024 * 1024),
+			"cgroup.procs":          strconv.Itoa(pid),
+		}, "memory.limit_in_bytes", "cgroup.procs")
+		if err != nil {
+			group.remove()
+			return nil, err
+		}
+	}
+
+	if limits.CPUPercent > 0 {
+		dir := filepath.Join(cgroupRoot, "cpu", "cloudsnooze", name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			group.remove()
+			return nil, fmt.Errorf("failed to create cgroup: %v", err)
+		}
+		group.dirs = append(group.dirs, dir)
+
+		err := group.write(dir, map[string]string{
+			"cpu.cfs_period_us": strconv.Itoa(cpuPeriod),
+			"cpu.cfs_quota_us":  strconv.Itoa(int(limits.CPUPercent * cpuPeriod / 100)),
+			"cgroup.procs":      strconv.Itoa(pid),
+		}, "cpu.cfs_period_us", "cpu.cfs_quota_us", "cgroup.procs")
+		if err != nil {
+			group.remove()
+			return nil, err
+		}
+	}
+
+	return group, nil
+}
+
+// write sets cgroup files in order; the process must be added last so the
+// limits are in place when it joins
+func (g *cgroup) write(dir string, settings map[string]string, order ...string) error {
+	for _, file := range order {
+		value, ok := settings[file]
+		if !ok {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(value), 0644); err != nil {
+			return fmt.Errorf("failed to set %s: %v", file, err)
+		}
+	}
+	return nil
+}
+
+// oomKilled returns true if the kernel killed a process in the cgroup for
+// exceeding its memory limit
+func (g *cgroup) oomKilled() bool {
+	for _, dir := range g.dirs {
+		file := "memory.oom_control"
+		if g.v2 {
+			file = "memory.events"
+		}
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 2 && fields[0] == "oom_kill" && fields[1] != "0" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// remove deletes the cgroup once its process has exited
+func (g *cgroup) remove() {
+	for _, dir := range g.dirs {
+		os.Remove(dir)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCgroupV2Limits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("cgroups are only used on Linux")
+	}
+
+	original := cgroupRoot
+	cgroupRoot = t.TempDir()
+	defer func() { cgroupRoot = original }()
+	os.WriteFile(filepath.Join(cgroupRoot, "cgroup.controllers"), []byte("cpu memory"), 0644)
+
+	group, err := newCgroup("test", 1234, ResourceLimits{CPUPercent: 25, MemoryMB: 64})
+	if err != nil || group == nil {
+		t.Fatalf("newCgroup failed: %v", err)
+	}
+
+	dir := filepath.Join(cgroupRoot, "cloudsnooze", "plugin-test")
+	expected := map[string]string{
+		"memory.max":   "67108864",
+		"cpu.max":      "25000 100000",
+		"cgroup.procs": "1234",
+	}
+	for file, value := range expected {
+		data, _ := os.ReadFile(filepath.Join(dir, file))
+		if string(data) != value {
+			t.Errorf("Expected %s to be %q, got %q", file, value, data)
+		}
+	}
+
+	os.WriteFile(filepath.Join(dir, "memory.events"), []byte("oom 1\noom_kill 1\n"), 0644)
+	if !group.oomKilled() {
+		t.Error("Expected OOM kill to be detected")
+	}
+}
+
+func TestParseCPUTime(t *testing.T) {
+	tests := map[string]time.Duration{
+		"0:01.50":    1500 * time.Millisecond,
+		"2:03.00":    123 * time.Second,
+		"1:00:00":    time.Hour,
+		"1-00:00:01": 24*time.Hour + time.Second,
+	}
+	for value, expected := range tests {
+		got, err := parseCPUTime(value)
+		if err != nil || got != expected {
+			t.Errorf("parseCPUTime(%q) = %v, %v; expected %v", value, got, err, expected)
+		}
+	}
+}
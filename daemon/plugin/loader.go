@@ -64,7 +64,7 @@ func LoadPluginsFromDir(dir string) ([]Plugin, error) {
 	for _, match := range matches {
 		plugin, err := LoadPluginFromFile(match)
 		if err != nil {
-			fmt.Printf("Warning: Failed to load plugin %s: %v\n", match, err)
+			logger().Warn("Failed to load plugin", "path", match, "error", err)
 			continue
 		}
 
@@ -84,39 +84,64 @@ func LoadPluginsFromManifest(dir string) ([]Plugin, error) {
 
 	var plugins []Plugin
 	for _, manifestPath := range manifests {
-		// Read and parse manifest
-		data, err := os.ReadFile(manifestPath)
+		plugin, err := LoadPluginFromManifest(manifestPath)
 		if err != nil {
-			fmt.Printf("Warning: Failed to read manifest %s: %v\n", manifestPath, err)
+			logger().Warn("Failed to load plugin", "manifest", manifestPath, "error", err)
 			continue
 		}
 
-		var manifest PluginInfo
-		if err := json.Unmarshal(data, &manifest); err != nil {
-			fmt.Printf("Warning: Failed to parse manifest %s: %v\n", manifestPath, err)
-			continue
-		}
+		plugins = append(plugins, plugin)
+	}
 
-		// Find plugin binary in the same directory
-		pluginDir := filepath.Dir(manifestPath)
-		pluginPath := filepath.Join(pluginDir, manifest.ID+".so")
-		
-		if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
-			fmt.Printf("Warning: Plugin binary not found for manifest %s\n", manifestPath)
-			continue
-		}
+	return plugins, nil
+}
+
+// LoadPluginFromManifest loads the plugin described by a manifest file
+func LoadPluginFromManifest(manifestPath string) (Plugin, error) {
+	// Read and parse manifest
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %s: %v", manifestPath, err)
+	}
 
-		// Load the plugin
-		plugin, err := LoadPluginFromFile(pluginPath)
+	var manifest PluginInfo
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %v", manifestPath, err)
+	}
+
+	// Find plugin binary in the same directory
+	pluginDir := filepath.Dir(manifestPath)
+	
+	// Out-of-process plugins run as a separate executable
+	if manifest.Exec != "" {
+		execPath := manifest.Exec
+		if !filepath.IsAbs(execPath) {
+			execPath = filepath.Join(pluginDir, execPath)
+		}
+		if _, err := os.Stat(execPath); err != nil {
+			return nil, fmt.Errorf("plugin executable not found for manifest %s", manifestPath)
+		}
+		
+		plugin, err := wrapExecPlugin(NewExecPlugin(manifest, execPath))
 		if err != nil {
-			fmt.Printf("Warning: Failed to load plugin %s: %v\n", pluginPath, err)
-			continue
+			return nil, fmt.Errorf("failed to load plugin %s: %v", execPath, err)
 		}
+		return plugin, nil
+	}
+	
+	pluginPath := filepath.Join(pluginDir, manifest.ID+".so")
+	
+	if _, err := os.Stat(pluginPath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("plugin binary not found for manifest %s", manifestPath)
+	}
 
-		plugins = append(plugins, plugin.pluginImpl)
+	// Load the plugin
+	plugin, err := LoadPluginFromFile(pluginPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plugin %s: %v", pluginPath, err)
 	}
 
-	return plugins, nil
+	return plugin.pluginImpl, nil
 }
 
 // LoadExternalPlugins loads plugins from the specified directory and registers them
@@ -128,7 +153,7 @@ func LoadExternalPlugins(dir string) error {
 	// Try loading from manifests first
 	plugins, err := LoadPluginsFromManifest(dir)
 	if err != nil {
-		fmt.Printf("Warning: Failed to load plugins from manifests: %v\n", err)
+		logger().Warn("Failed to load plugins from manifests", "error", err)
 		// Fall back to direct .so loading
 		plugins, err = LoadPluginsFromDir(dir)
 		if err != nil {
@@ -139,7 +164,7 @@ func LoadExternalPlugins(dir string) error {
 	// Register loaded plugins
 	for _, p := range plugins {
 		if err := Registry.Register(p); err != nil {
-			fmt.Printf("Warning: Failed to register plugin %s: %v\n", p.Info().ID, err)
+			logger().Warn("Failed to register plugin", "plugin", p.Info().ID, "error", err)
 		}
 	}
 
@@ -0,0 +1,29 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notifier
+
+import (
+	"github.com/scttfrdmn/cloudsnooze/daemon/notify"
+	"github.com/scttfrdmn/cloudsnooze/daemon/plugin"
+)
+
+// MethodNotify delivers a notification to an exec notifier plugin. The
+// params are a notify.EventMessage.
+const MethodNotify = "notify"
+
+// ExecNotifierPlugin exposes an out-of-process plugin as a notifier
+type ExecNotifierPlugin struct {
+	*plugin.ExecPlugin
+}
+
+// Notify sends the notification to the plugin process
+func (p *ExecNotifierPlugin) Notify(notification notify.Notification) error {
+	return p.Call(MethodNotify, notify.NewEventMessage(notification), nil)
+}
+
+func init() {
+	plugin.RegisterExecAdapter(plugin.TypeNotifier, func(p *plugin.ExecPlugin) plugin.Plugin {
+		return &ExecNotifierPlugin{ExecPlugin: p}
+	})
+}
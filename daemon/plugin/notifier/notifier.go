@@ -0,0 +1,97 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notifier
+
+import (
+	"fmt"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/notify"
+	"github.com/scttfrdmn/cloudsnooze/daemon/plugin"
+)
+
+// NotifierPlugin extends the base Plugin interface for notification integrations
+type NotifierPlugin interface {
+	plugin.Plugin
+
+	// Notify delivers a snooze notification
+	Notify(notification notify.Notification) error
+}
+
+// NotifierRegistry provides access to notifier plugins
+type NotifierRegistry struct {
+	registry *plugin.PluginRegistry
+}
+
+// NewNotifierRegistry creates a new notifier registry
+func NewNotifierRegistry(registry *plugin.PluginRegistry) *NotifierRegistry {
+	return &NotifierRegistry{
+		registry: registry,
+	}
+}
+
+// GetNotifier gets a notifier plugin by ID
+func (r *NotifierRegistry) GetNotifier(id string) (NotifierPlugin, bool) {
+	p, exists := r.registry.Get(id)
+	if !exists {
+		return nil, false
+	}
+
+	np, ok := p.(NotifierPlugin)
+	return np, ok
+}
+
+// GetAllNotifiers gets all registered notifier plugins
+func (r *NotifierRegistry) GetAllNotifiers() []NotifierPlugin {
+	plugins := r.registry.GetByType(plugin.TypeNotifier)
+	result := make([]NotifierPlugin, 0, len(plugins))
+
+	for _, p := range plugins {
+		if np, ok := p.(NotifierPlugin); ok {
+			result = append(result, np)
+		}
+	}
+
+	return result
+}
+
+// AddTo initializes and starts the configured notifier plugins, adding
+// each to dispatcher. It returns why any plugin couldn't be added, by ID.
+func (r *NotifierRegistry) AddTo(dispatcher *notify.Dispatcher, configs map[string]map[string]interface{}) map[string]error {
+	failed := make(map[string]error)
+	for id, config := range configs {
+		p, ok := r.GetNotifier(id)
+		if !ok {
+			failed[id] = fmt.Errorf("notifier plugin not found")
+			continue
+		}
+		if err := p.Init(config); err != nil {
+			failed[id] = fmt.Errorf("failed to initialize: %v", err)
+			continue
+		}
+		if err := p.Start(); err != nil {
+			failed[id] = fmt.Errorf("failed to start: %v", err)
+			continue
+		}
+		dispatcher.Add(Adapter{Plugin: p})
+	}
+	return failed
+}
+
+// Adapter lets a notifier plugin receive notifications from the daemon's dispatcher
+type Adapter struct {
+	Plugin NotifierPlugin
+}
+
+// Name returns the plugin ID
+func (a Adapter) Name() string {
+	return a.Plugin.Info().ID
+}
+
+// Notify forwards the notification to the plugin
+func (a Adapter) Notify(notification notify.Notification) error {
+	return a.Plugin.Notify(notification)
+}
+
+// Global notifier registry instance
+var Registry = NewNotifierRegistry(plugin.Registry)
@@ -0,0 +1,120 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package notifier
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+	"github.com/scttfrdmn/cloudsnooze/daemon/notify"
+	"github.com/scttfrdmn/cloudsnooze/daemon/plugin"
+)
+
+// TestHelperNotifier is not a real test, it acts as an exec notifier plugin
+// when the test binary is started by newHelperNotifier. Each notification
+// is appended to the file named by CLOUDSNOOZE_HELPER_OUTPUT.
+func TestHelperNotifier(t *testing.T) {
+	output := os.Getenv("CLOUDSNOOZE_HELPER_OUTPUT")
+	if output == "" {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var request struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			os.Exit(2)
+		}
+
+		response := map[string]interface{}{"id": request.ID}
+		switch request.Method {
+		case plugin.MethodInit:
+		case plugin.MethodShutdown:
+			json.NewEncoder(os.Stdout).Encode(response)
+			os.Exit(0)
+		case MethodNotify:
+			file, err := os.OpenFile(output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				response["error"] = err.Error()
+				break
+			}
+			fmt.Fprintf(file, "%s\n", request.Params)
+			file.Close()
+		default:
+			response["error"] = fmt.Sprintf("unknown method %s", request.Method)
+		}
+		json.NewEncoder(os.Stdout).Encode(response)
+	}
+	os.Exit(0)
+}
+
+// newHelperNotifier returns a registry holding the test binary as an exec
+// notifier plugin, and the file it writes notifications to
+func newHelperNotifier(t *testing.T) (*NotifierRegistry, string) {
+	output := filepath.Join(t.TempDir(), "notifications")
+	t.Setenv("CLOUDSNOOZE_HELPER_OUTPUT", output)
+
+	registry := plugin.NewPluginRegistry()
+	p := &ExecNotifierPlugin{ExecPlugin: plugin.NewExecPlugin(plugin.PluginInfo{ID: "helper", Type: plugin.TypeNotifier}, os.Args[0], "-test.run=^TestHelperNotifier$")}
+	if err := registry.Register(p); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	t.Cleanup(func() { p.Stop() })
+	return NewNotifierRegistry(registry), output
+}
+
+func TestNotifierPluginDispatch(t *testing.T) {
+	registry, output := newHelperNotifier(t)
+	dispatcher := notify.NewDispatcher()
+
+	failed := registry.AddTo(dispatcher, map[string]map[string]interface{}{
+		"helper":  {"channel": "ops"},
+		"missing": {},
+	})
+	if len(failed) != 1 || failed["missing"] == nil {
+		t.Errorf("Expected only the missing plugin to fail, got %v", failed)
+	}
+	if dispatcher.Count() != 1 {
+		t.Fatalf("Expected the helper to be added to the dispatcher, got %d notifiers", dispatcher.Count())
+	}
+
+	dispatcher.Notify(notify.Notification{
+		Type:  notify.NotificationSnoozed,
+		Event: monitor.SnoozeEvent{InstanceID: "i-0abc", Reason: "idle"},
+	})
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Expected the plugin to receive the notification: %v", err)
+	}
+	var message notify.EventMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		t.Fatalf("Expected one event message, got %q: %v", data, err)
+	}
+	if message.Type != notify.NotificationSnoozed || message.Event.InstanceID != "i-0abc" || message.Title == "" {
+		t.Errorf("Unexpected message %+v", message)
+	}
+}
+
+func TestGetNotifier(t *testing.T) {
+	registry, _ := newHelperNotifier(t)
+	if _, ok := registry.GetNotifier("helper"); !ok {
+		t.Error("Expected the helper notifier to be found")
+	}
+	if _, ok := registry.GetNotifier("missing"); ok {
+		t.Error("Expected no notifier for an unknown ID")
+	}
+	if notifiers := registry.GetAllNotifiers(); len(notifiers) != 1 {
+		t.Errorf("Expected one notifier, got %d", len(notifiers))
+	}
+}
@@ -5,12 +5,22 @@ package plugin
 
 import (
 	"errors"
+	"log/slog"
+	"sort"
 	"sync"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
 )
 
+// logger returns the plugin component logger
+func logger() *slog.Logger {
+	return logging.Component("plugin")
+}
+
 // Plugin types
 const (
 	TypeCloudProvider = "cloud-provider"
+	TypeNotifier      = "notifier"
 	// Add more plugin types as needed
 )
 
@@ -24,6 +34,7 @@ type PluginInfo struct {
 	Author       string            // Plugin author
 	Website      string            // Plugin website or repository
 	Dependencies []string          // IDs of plugins this plugin depends on
+	Exec         string            // Executable for out-of-process plugins, relative to the manifest
 }
 
 // Plugin defines the base interface all plugins must implement
@@ -46,14 +57,16 @@ type Plugin interface {
 
 // PluginRegistry is the global registry of plugins
 type PluginRegistry struct {
-	plugins map[string]Plugin
-	lock    sync.RWMutex
+	plugins  map[string]Plugin
+	disabled map[string]bool
+	lock     sync.RWMutex
 }
 
 // NewPluginRegistry creates a new plugin registry
 func NewPluginRegistry() *PluginRegistry {
 	return &PluginRegistry{
-		plugins: make(map[string]Plugin),
+		plugins:  make(map[string]Plugin),
+		disabled: make(map[string]bool),
 	}
 }
 
@@ -71,23 +84,26 @@ func (r *PluginRegistry) Register(p Plugin) error {
 	return nil
 }
 
-// Get returns a plugin by ID
+// Get returns an enabled plugin by ID
 func (r *PluginRegistry) Get(id string) (Plugin, bool) {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
 	
+	if r.disabled[id] {
+		return nil, false
+	}
 	p, exists := r.plugins[id]
 	return p, exists
 }
 
-// GetByType returns all plugins of a specific type
+// GetByType returns all enabled plugins of a specific type
 func (r *PluginRegistry) GetByType(pluginType string) []Plugin {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
 	
 	var result []Plugin
-	for _, p := range r.plugins {
-		if p.Info().Type == pluginType {
+	for id, p := range r.plugins {
+		if p.Info().Type == pluginType && !r.disabled[id] {
 			result = append(result, p)
 		}
 	}
@@ -95,5 +111,54 @@ func (r *PluginRegistry) GetByType(pluginType string) []Plugin {
 	return result
 }
 
+// GetAll returns all registered plugins ordered by ID, including disabled ones
+func (r *PluginRegistry) GetAll() []Plugin {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	
+	result := make([]Plugin, 0, len(r.plugins))
+	for _, p := range r.plugins {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Info().ID < result[j].Info().ID
+	})
+	
+	return result
+}
+
+// SetEnabled enables or disables a plugin. Plugins may be disabled before
+// they are registered so that configuration can be applied before loading.
+func (r *PluginRegistry) SetEnabled(id string, enabled bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	
+	if enabled {
+		delete(r.disabled, id)
+	} else {
+		r.disabled[id] = true
+	}
+}
+
+// IsEnabled returns false if the plugin has been disabled
+func (r *PluginRegistry) IsEnabled(id string) bool {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return !r.disabled[id]
+}
+
+// Disabled returns the IDs of disabled plugins in sorted order
+func (r *PluginRegistry) Disabled() []string {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	
+	result := make([]string, 0, len(r.disabled))
+	for id := range r.disabled {
+		result = append(result, id)
+	}
+	sort.Strings(result)
+	return result
+}
+
 // Global registry instance
 var Registry = NewPluginRegistry()
\ No newline at end of file
@@ -0,0 +1,33 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package plugin
+
+import "testing"
+
+func TestRegistryDisabledPlugins(t *testing.T) {
+	registry := NewPluginRegistry()
+
+	// Plugins may be disabled before they are registered
+	registry.SetEnabled("exec", false)
+	registry.Register(NewExecPlugin(PluginInfo{ID: "exec", Type: TypeNotifier}, "/bin/true"))
+	registry.Register(NewExecPlugin(PluginInfo{ID: "other", Type: TypeNotifier}, "/bin/true"))
+
+	if _, ok := registry.Get("exec"); ok {
+		t.Error("Expected disabled plugin to be hidden from Get")
+	}
+	if got := registry.GetByType(TypeNotifier); len(got) != 1 || got[0].Info().ID != "other" {
+		t.Errorf("Expected only the enabled plugin from GetByType, got %d", len(got))
+	}
+	if got := registry.GetAll(); len(got) != 2 {
+		t.Errorf("Expected GetAll to include disabled plugins, got %d", len(got))
+	}
+	if got := registry.Disabled(); len(got) != 1 || got[0] != "exec" {
+		t.Errorf("Unexpected disabled list %v", got)
+	}
+
+	registry.SetEnabled("exec", true)
+	if _, ok := registry.Get("exec"); !ok || !registry.IsEnabled("exec") {
+		t.Error("Expected re-enabled plugin to be available")
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package policy fetches an organization's baseline policy, which bounds
+// the settings of every daemon on its accounts, from a central URL. The
+// policy is signed with Ed25519, so only the holder of the private key can
+// change it, and a verified copy is cached for when the URL can't be
+// reached.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fetchTimeout limits fetching the policy and its signature
+const fetchTimeout = 30 * time.Second
+
+// maxPolicyBytes limits the size of a policy document
+const maxPolicyBytes = 1 << 20
+
+// Policy is the organization's baseline. Zero values leave a setting
+// unbounded. Local settings within the bounds apply as they are.
+type Policy struct {
+	MinNaptimeMinutes int     `json:"min_naptime_minutes"` // Shortest naptime instances may use
+	MaxNaptimeMinutes int     `json:"max_naptime_minutes"` // Longest naptime instances may use
+	MaxLeaseHours     float64 `json:"max_lease_hours"`     // Longest lease clients may take
+	ForbidDisable     bool    `json:"forbid_disable"`      // Refuse settings that keep an instance from ever snoozing
+}
+
+// SignaturePath returns where the signature of the policy at path or URL
+// is kept
+func SignaturePath(path string) string {
+	return path + ".sig"
+}
+
+// ReadPublicKey reads a PEM-encoded Ed25519 public key, as written by
+// "openssl pkey -pubout"
+func ReadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key in %s: %v", path, err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key in %s is not an Ed25519 key", path)
+	}
+	return edKey, nil
+}
+
+// Verify checks the signature of a policy document and parses it. The
+// signature may be raw, as written by "openssl pkeyutl -sign -rawin", or
+// base64-encoded.
+func Verify(data, signature []byte, key ed25519.PublicKey) (Policy, error) {
+	if len(signature) != ed25519.SignatureSize {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+		if err != nil {
+			return Policy{}, fmt.Errorf("invalid policy signature: %v", err)
+		}
+		signature = decoded
+	}
+	if !ed25519.Verify(key, data, signature) {
+		return Policy{}, fmt.Errorf("policy does not match its signature")
+	}
+
+	// Refuse a policy bounding settings this daemon doesn't know, rather
+	// than ignore them
+	var policy Policy
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&policy); err != nil {
+		return Policy{}, fmt.Errorf("failed to parse policy: %v", err)
+	}
+	if policy.MaxNaptimeMinutes > 0 && policy.MaxNaptimeMinutes < policy.MinNaptimeMinutes {
+		return Policy{}, fmt.Errorf("policy max_naptime_minutes %d is below min_naptime_minutes %d", policy.MaxNaptimeMinutes, policy.MinNaptimeMinutes)
+	}
+	return policy, nil
+}
+
+// Naptime returns a naptime in minutes within the policy's bounds
+func (p Policy) Naptime(minutes int) int {
+	if minutes < p.MinNaptimeMinutes {
+		return p.MinNaptimeMinutes
+	}
+	if p.MaxNaptimeMinutes > 0 && minutes > p.MaxNaptimeMinutes {
+		return p.MaxNaptimeMinutes
+	}
+	return minutes
+}
+
+// LeaseHours returns the longest lease within the policy's bounds, where
+// 0 is no limit
+func (p Policy) LeaseHours(hours float64) float64 {
+	if p.MaxLeaseHours > 0 && (hours <= 0 || hours > p.MaxLeaseHours) {
+		return p.MaxLeaseHours
+	}
+	return hours
+}
+
+// Fetch downloads the policy at url and its signature at url.sig, and
+// verifies it. It returns the document and signature as well, to be saved.
+func Fetch(ctx context.Context, url string, key ed25519.PublicKey) (Policy, []byte, []byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	data, err := download(ctx, url)
+	if err != nil {
+		return Policy{}, nil, nil, err
+	}
+	signature, err := download(ctx, SignaturePath(url))
+	if err != nil {
+		return Policy{}, nil, nil, err
+	}
+	policy, err := Verify(data, signature, key)
+	if err != nil {
+		return Policy{}, nil, nil, err
+	}
+	return policy, data, signature, nil
+}
+
+// download returns the body of a GET request to url
+func download(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPolicyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %v", url, err)
+	}
+	if len(data) > maxPolicyBytes {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, maxPolicyBytes)
+	}
+	return data, nil
+}
+
+// Load reads a policy saved at path and verifies it
+func Load(path string, key ed25519.PublicKey) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Policy{}, err
+	}
+	signature, err := os.ReadFile(SignaturePath(path))
+	if err != nil {
+		return Policy{}, err
+	}
+	return Verify(data, signature, key)
+}
+
+// Save writes a verified policy document and its signature to path
+func Save(path string, data, signature []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(SignaturePath(path), signature, 0644); err != nil {
+		return err
+	}
+	// Write the document last, so a failure leaves the old pair or a
+	// pair that doesn't verify
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package policy
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testPolicy bounds naptime to between 15 minutes and 2 hours
+const testPolicy = `{"min_naptime_minutes": 15, "max_naptime_minutes": 120, "max_lease_hours": 8, "forbid_disable": true}`
+
+func TestVerify(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	data := []byte(testPolicy)
+	signature := ed25519.Sign(private, data)
+
+	// Raw and base64-encoded signatures are both accepted
+	for _, sig := range [][]byte{signature, []byte(base64.StdEncoding.EncodeToString(signature) + "\n")} {
+		policy, err := Verify(data, sig, public)
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if policy.MinNaptimeMinutes != 15 || policy.MaxNaptimeMinutes != 120 || policy.MaxLeaseHours != 8 || !policy.ForbidDisable {
+			t.Errorf("Unexpected policy %+v", policy)
+		}
+	}
+
+	if _, err := Verify([]byte(`{"min_naptime_minutes": 0}`), signature, public); err == nil {
+		t.Error("Expected a changed policy to fail verification")
+	}
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := Verify(data, signature, other); err == nil {
+		t.Error("Expected a policy signed with another key to fail verification")
+	}
+	unknown := []byte(`{"max_cpu_threshold_percent": 50}`)
+	if _, err := Verify(unknown, ed25519.Sign(private, unknown), public); err == nil {
+		t.Error("Expected a policy with unknown settings to be refused")
+	}
+}
+
+func TestBounds(t *testing.T) {
+	policy := Policy{MinNaptimeMinutes: 15, MaxNaptimeMinutes: 120, MaxLeaseHours: 8}
+	for minutes, want := range map[int]int{5: 15, 30: 30, 600: 120} {
+		if got := policy.Naptime(minutes); got != want {
+			t.Errorf("Naptime(%d) = %d, want %d", minutes, got, want)
+		}
+	}
+	for hours, want := range map[float64]float64{0: 8, 4: 4, 24: 8} {
+		if got := policy.LeaseHours(hours); got != want {
+			t.Errorf("LeaseHours(%g) = %g, want %g", hours, got, want)
+		}
+	}
+	if got := (Policy{}).LeaseHours(0); got != 0 {
+		t.Errorf("Expected no lease limit without a policy limit, got %g", got)
+	}
+}
+
+func TestFetchAndCache(t *testing.T) {
+	public, private, _ := ed25519.GenerateKey(rand.Reader)
+	data := []byte(testPolicy)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/policy.json":
+			w.Write(data)
+		case "/policy.json.sig":
+			w.Write(ed25519.Sign(private, data))
+		default:
+			http.NotFound(w, req)
+		}
+	}))
+	defer server.Close()
+
+	policy, document, signature, err := Fetch(context.Background(), server.URL+"/policy.json", public)
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if policy.MaxNaptimeMinutes != 120 {
+		t.Errorf("Unexpected policy %+v", policy)
+	}
+	if _, _, _, err := Fetch(context.Background(), server.URL+"/missing.json", public); err == nil {
+		t.Error("Expected fetching a missing policy to fail")
+	}
+
+	path := filepath.Join(t.TempDir(), "cache", "policy.json")
+	if err := Save(path, document, signature); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	cached, err := Load(path, public)
+	if err != nil || cached != policy {
+		t.Errorf("Expected the cached policy back, got %+v and %v", cached, err)
+	}
+	os.WriteFile(path, []byte(`{}`), 0644)
+	if _, err := Load(path, public); err == nil {
+		t.Error("Expected an edited cached policy to fail verification")
+	}
+}
+
+func TestReadPublicKey(t *testing.T) {
+	public, _, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "policy.pub")
+	os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644)
+
+	key, err := ReadPublicKey(path)
+	if err != nil || !key.Equal(public) {
+		t.Errorf("Expected the public key back, got %v", err)
+	}
+	os.WriteFile(path, []byte("not a key"), 0644)
+	if _, err := ReadPublicKey(path); err == nil {
+		t.Error("Expected a file without a key to be refused")
+	}
+}
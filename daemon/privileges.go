@@ -0,0 +1,91 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"strconv"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/api"
+)
+
+// sharedDirs are directories other software also writes to, which the
+// daemon's user is never given
+var sharedDirs = map[string]bool{
+	"/": true, "/etc": true, "/tmp": true, "/var": true, "/var/lib": true,
+	"/var/log": true, "/var/run": true, "/run": true, "/var/tmp": true,
+}
+
+// credentials are the user and groups the daemon switches to
+type credentials struct {
+	uid    int
+	gid    int
+	groups []int // Supplementary groups, e.g. for reading input devices
+}
+
+// lookupCredentials returns who to run as, with the user's supplementary
+// groups so access such as the tty group for wall is kept
+func lookupCredentials(config PrivilegesConfig) (credentials, error) {
+	var creds credentials
+	u, err := user.Lookup(config.User)
+	if err != nil {
+		if u, err = user.LookupId(config.User); err != nil {
+			return creds, fmt.Errorf("unknown user %q: %v", config.User, err)
+		}
+	}
+	if creds.uid, err = strconv.Atoi(u.Uid); err != nil {
+		return creds, fmt.Errorf("user %q has no numeric UID", config.User)
+	}
+	if creds.gid, err = strconv.Atoi(u.Gid); err != nil {
+		return creds, fmt.Errorf("user %q has no numeric GID", config.User)
+	}
+	if config.Group != "" {
+		if creds.gid, err = api.LookupGroupID(config.Group); err != nil {
+			return creds, err
+		}
+	}
+
+	ids, err := u.GroupIds()
+	if err != nil {
+		logger().Warn("Failed to look up supplementary groups", "user", config.User, "error", err)
+	}
+	creds.groups = []int{creds.gid}
+	for _, id := range ids {
+		if gid, err := strconv.Atoi(id); err == nil && gid != creds.gid {
+			creds.groups = append(creds.groups, gid)
+		}
+	}
+	return creds, nil
+}
+
+// writablePaths returns the files the daemon writes while running, which
+// its user is given along with their directories unless those are shared
+func writablePaths(config Config) []string {
+	var paths []string
+	for _, path := range []string{
+		config.StatePath,
+		config.HistoryFile,
+		config.PricingCachePath,
+		config.Schedule.Calendar.CachePath,
+		config.Schedule.BudgetStatePath,
+		config.Notifications.QueuePath,
+		config.Logging.LogFilePath,
+		config.Audit.LogPath,
+		config.Hooks.ContextPath,
+		config.Leases.StatePath,
+		config.Docker.StatePath,
+		config.Kubernetes.StatePath,
+	} {
+		if path == "" {
+			continue
+		}
+		paths = append(paths, path)
+		if dir := filepath.Dir(path); !sharedDirs[dir] {
+			paths = append(paths, dir)
+		}
+	}
+	return paths
+}
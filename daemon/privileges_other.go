@@ -0,0 +1,17 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux && !darwin && !freebsd && !openbsd
+
+package main
+
+import "fmt"
+
+// dropPrivileges isn't supported on this platform; run the service as the
+// intended account instead
+func dropPrivileges(config PrivilegesConfig, paths []string) error {
+	if config.User == "" {
+		return nil
+	}
+	return fmt.Errorf("switching to user %s is not supported on this platform", config.User)
+}
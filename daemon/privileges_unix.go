@@ -0,0 +1,59 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin || freebsd || openbsd
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// dropPrivileges switches the daemon from root to the configured user,
+// first giving it the files in paths. Finding the instance and stopping it
+// only take HTTPS requests, so no capabilities are kept; switching away
+// from root clears them all.
+func dropPrivileges(config PrivilegesConfig, paths []string) error {
+	if config.User == "" {
+		return nil
+	}
+	creds, err := lookupCredentials(config)
+	if err != nil {
+		return err
+	}
+	if os.Geteuid() != 0 {
+		if os.Geteuid() == creds.uid {
+			return nil
+		}
+		return fmt.Errorf("must be started as root to run as %s", config.User)
+	}
+
+	for _, path := range paths {
+		if err := os.Chown(path, creds.uid, creds.gid); err != nil && !os.IsNotExist(err) {
+			logger().Warn("Failed to give file to the daemon user", "path", path, "user", config.User, "error", err)
+		}
+	}
+
+	// Groups must change while still root, and the user last. Go applies
+	// these to every thread.
+	if err := syscall.Setgroups(creds.groups); err != nil {
+		return fmt.Errorf("failed to set groups: %v", err)
+	}
+	if err := syscall.Setgid(creds.gid); err != nil {
+		return fmt.Errorf("failed to set group: %v", err)
+	}
+	if err := syscall.Setuid(creds.uid); err != nil {
+		return fmt.Errorf("failed to set user: %v", err)
+	}
+
+	// Make sure root can't be regained
+	if syscall.Setuid(0) == nil {
+		return fmt.Errorf("root privileges could be regained after switching to %s", config.User)
+	}
+	if os.Getuid() != creds.uid || os.Geteuid() != creds.uid {
+		return fmt.Errorf("still running as UID %d after switching to %s", os.Geteuid(), config.User)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/api"
+	"github.com/scttfrdmn/cloudsnooze/daemon/cloud"
+	"github.com/scttfrdmn/cloudsnooze/daemon/cloud/aws"
+	"github.com/scttfrdmn/cloudsnooze/daemon/restarter"
+	"github.com/scttfrdmn/cloudsnooze/daemon/schedule"
+)
+
+var restarterMode = flag.Bool("restarter", false, "Start instances snoozed by other daemons, from an always-on host, instead of watching this instance")
+
+// runRestarter runs the restarter until a signal and returns the exit
+// code. The socket API lists snoozed instances and starts them, and the
+// webhook starts them for other services.
+func runRestarter(config Config) int {
+	// Only AWS can find and start snoozed instances
+	if config.ProviderType != "" && cloud.ProviderType(config.ProviderType) != cloud.AWS {
+		logger().Error("The restarter only supports AWS", "provider", config.ProviderType)
+		return 1
+	}
+	provider := aws.NewProvider(awsProviderConfig(config))
+	if err := provider.InitializeFleet(); err != nil {
+		logger().Error("Failed to set up the AWS provider", "error", err)
+		return 1
+	}
+
+	location, _, err := schedule.LoadLocation(config.Schedule.Timezone)
+	if err != nil {
+		logger().Error("Invalid time zone", "error", err)
+		return 1
+	}
+	schedules, err := restarterSchedules(config.Restarter.Schedules)
+	if err != nil {
+		logger().Error("Invalid restarter schedule", "error", err)
+		return 1
+	}
+	r := restarter.New(provider, schedules, location)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	provider.SetContext(ctx)
+
+	// Serve the API, with only the restarter's commands
+	admins, err := adminPolicy(config.Socket)
+	if err != nil {
+		logger().Error("Failed to read socket admin token", "error", err)
+		return 1
+	}
+	allowlists, err := clientAllowlists(config.ClientAllowlists)
+	if err != nil {
+		logger().Error("Failed to read client allowlist token", "error", err)
+		return 1
+	}
+	auditLog := newAuditLog(config)
+	defer auditLog.Close()
+	newServer := func() (*api.SocketServer, error) {
+		server, err := api.NewSocketServer(*socketPath, socketAccess(config.Socket))
+		if err != nil {
+			return nil, err
+		}
+		if err := server.SetAdminPolicy(admins); err != nil {
+			server.Stop()
+			return nil, err
+		}
+		if err := server.SetAllowlists(allowlists); err != nil {
+			server.Stop()
+			return nil, err
+		}
+		server.SetAuditLog(auditLog)
+		server.SetRateLimit(rateLimit(config.Socket.RateLimit))
+		registerRestarterHandlers(ctx, server, r)
+		return server, nil
+	}
+	server, err := newServer()
+	if err != nil {
+		logger().Error("Failed to create socket server", "error", err)
+		return 1
+	}
+
+	// Listen for the webhook before giving up root
+	var webhook *http.Server
+	if config.Restarter.WebhookAddr != "" {
+		if webhook, err = newRestarterWebhook(config.Restarter, r); err != nil {
+			logger().Error("Failed to start the restarter webhook", "error", err)
+			return 1
+		}
+	}
+	// Bind the wake-on-SSH listeners too, since they usually take port 22
+	sshListeners := make([]net.Listener, 0, len(config.Restarter.WakeOnSSH))
+	defer func() {
+		for _, listener := range sshListeners {
+			listener.Close()
+		}
+	}()
+	for _, w := range config.Restarter.WakeOnSSH {
+		listener, err := net.Listen("tcp", w.ListenAddr)
+		if err != nil {
+			logger().Error("Failed to listen for wake-on-SSH", "address", w.ListenAddr, "instance", w.InstanceID, "error", err)
+			return 1
+		}
+		sshListeners = append(sshListeners, listener)
+	}
+	if err := dropPrivileges(config.Privileges, writablePaths(config)); err != nil {
+		logger().Error("Failed to drop privileges", "user", config.Privileges.User, "error", err)
+		return 1
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- serveAPI(ctx, server, newServer)
+	}()
+	go r.Run(ctx, time.Duration(config.Restarter.PollSeconds)*time.Second)
+	for i, w := range config.Restarter.WakeOnSSH {
+		go func(listener net.Listener, w WakeOnSSHConfig) {
+			err := r.ServeWakeOnSSH(ctx, listener, restarter.WakeOnSSH{
+				InstanceID: w.InstanceID,
+				Target:     w.Target,
+				Timeout:    time.Duration(config.Restarter.WakeTimeoutSecs) * time.Second,
+			})
+			if err != nil {
+				logger().Error("Wake-on-SSH listener stopped", "address", w.ListenAddr, "instance", w.InstanceID, "error", err)
+			}
+		}(sshListeners[i], w)
+	}
+	logger().Info("Restarter running", "schedules", len(schedules), "webhook", config.Restarter.WebhookAddr,
+		"wake_on_ssh", len(sshListeners))
+
+	sigChan := make(chan os.Signal, 1)
+	notifyShutdown(sigChan)
+	exitCode := 0
+	select {
+	case sig := <-sigChan:
+		logger().Info("Received signal, shutting down", "signal", sig.String())
+	case err := <-serverErr:
+		logger().Error("API socket unavailable, shutting down", "error", err)
+		exitCode = 1
+	}
+
+	cancel()
+	if webhook != nil {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+		webhook.Shutdown(shutdownCtx)
+		cancelShutdown()
+	}
+	return exitCode
+}
+
+// restarterSchedules converts the configured schedules, which have been
+// validated
+func restarterSchedules(configs []RestarterScheduleConfig) ([]restarter.Schedule, error) {
+	schedules := make([]restarter.Schedule, 0, len(configs))
+	for _, c := range configs {
+		at, err := time.Parse("15:04", c.Time)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time %q", c.Time)
+		}
+		days, err := schedule.ParseWeekdays(c.Days)
+		if err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, restarter.Schedule{
+			Hour:      at.Hour(),
+			Minute:    at.Minute(),
+			Days:      days,
+			Instances: c.Instances,
+			Tags:      c.Tags,
+		})
+	}
+	return schedules, nil
+}
+
+// newRestarterWebhook starts serving the webhook, over HTTPS if a
+// certificate is configured
+func newRestarterWebhook(config RestarterConfig, r *restarter.Restarter) (*http.Server, error) {
+	data, err := os.ReadFile(config.WebhookTokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook token: %v", err)
+	}
+	token := strings.TrimSpace(string(data))
+	if token == "" {
+		return nil, fmt.Errorf("webhook token file %s is empty", config.WebhookTokenFile)
+	}
+
+	return startHTTPServer("Restarter webhook", config.WebhookAddr, r.WebhookHandler(token), config.WebhookCertFile, config.WebhookKeyFile)
+}
+
+// startHTTPServer serves handler on addr, over HTTPS if certFile is set,
+// logging why it stops unless it is shut down
+func startHTTPServer(name, addr string, handler http.Handler, certFile, keyFile string) (*http.Server, error) {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		var err error
+		if certFile != "" {
+			err = server.ServeTLS(listener, certFile, keyFile)
+		} else {
+			err = server.Serve(listener)
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			logger().Error(name+" stopped", "address", addr, "error", err)
+		}
+	}()
+	return server, nil
+}
+
+// registerRestarterHandlers registers the restarter's commands
+func registerRestarterHandlers(ctx context.Context, server *api.SocketServer, r *restarter.Restarter) {
+	// INSTANCES lists the snoozed instances
+	server.RegisterReadOnlyHandler("INSTANCES", func(params map[string]interface{}) (interface{}, error) {
+		return r.Instances(ctx)
+	})
+
+	// WAKE starts a snoozed instance
+	server.RegisterHandler("WAKE", func(params map[string]interface{}) (interface{}, error) {
+		instanceID, _ := params["instance_id"].(string)
+		if instanceID == "" {
+			return nil, fmt.Errorf("instance_id is required")
+		}
+		if err := r.Wake(ctx, instanceID, restarter.TriggerAPI); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"instance_id": instanceID, "starting": true}, nil
+	})
+}
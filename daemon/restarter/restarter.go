@@ -0,0 +1,223 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package restarter starts instances snoozed by CloudSnooze daemons again,
+// on request, on a schedule or from a webhook. It runs on an always-on
+// host, completing the stop/start lifecycle.
+package restarter
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+)
+
+// What started an instance, recorded on the instance
+const (
+	TriggerAPI      = "api"
+	TriggerSchedule = "schedule"
+	TriggerWebhook  = "webhook"
+	TriggerDeadline = "max_snooze"
+)
+
+// ErrNotSnoozed is returned when asked to start an instance that isn't
+// snoozed, so the restarter can't be used to start arbitrary instances
+var ErrNotSnoozed = errors.New("instance is not snoozed")
+
+// logger returns the restarter component logger
+func logger() *slog.Logger {
+	return logging.Component("restarter")
+}
+
+// Schedule starts snoozed instances at a time of day
+type Schedule struct {
+	Hour      int
+	Minute    int
+	Days      []time.Weekday    // Days to start instances on, empty for every day
+	Instances []string          // Instance IDs, empty for every snoozed instance
+	Tags      map[string]string // Tags the instances must have
+}
+
+// due reports whether the schedule's time falls after since and at or
+// before now
+func (s Schedule) due(since, now time.Time, location *time.Location) bool {
+	since, now = since.In(location), now.In(location)
+	day := time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, location)
+	for !day.After(now) {
+		at := time.Date(day.Year(), day.Month(), day.Day(), s.Hour, s.Minute, 0, 0, location)
+		if at.After(since) && !at.After(now) && (len(s.Days) == 0 || slices.Contains(s.Days, at.Weekday())) {
+			return true
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return false
+}
+
+// matches reports whether the schedule applies to instance
+func (s Schedule) matches(instance common.SnoozedInstance) bool {
+	if len(s.Instances) > 0 && !slices.Contains(s.Instances, instance.ID) {
+		return false
+	}
+	for key, value := range s.Tags {
+		if instance.Tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Restarter starts snoozed instances
+type Restarter struct {
+	fleet     common.Fleet
+	schedules []Schedule
+	location  *time.Location
+	starting  sync.Mutex // Held while starting, so one instance isn't started twice
+}
+
+// New creates a restarter for the instances of fleet. Schedule times are in
+// location.
+func New(fleet common.Fleet, schedules []Schedule, location *time.Location) *Restarter {
+	return &Restarter{
+		fleet:     fleet,
+		schedules: schedules,
+		location:  location,
+	}
+}
+
+// Instances returns the snoozed instances
+func (r *Restarter) Instances(ctx context.Context) ([]common.SnoozedInstance, error) {
+	return r.fleet.SnoozedInstances(ctx)
+}
+
+// Wake starts a snoozed instance, recording what triggered it
+func (r *Restarter) Wake(ctx context.Context, instanceID, trigger string) error {
+	r.starting.Lock()
+	defer r.starting.Unlock()
+
+	instances, err := r.fleet.SnoozedInstances(ctx)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if instance.ID == instanceID {
+			return r.start(ctx, instance, trigger)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotSnoozed, instanceID)
+}
+
+// start starts an instance and logs it
+func (r *Restarter) start(ctx context.Context, instance common.SnoozedInstance, trigger string) error {
+	if err := r.fleet.StartInstanceContext(ctx, instance.ID, trigger); err != nil {
+		logger().Error("Failed to start instance", "instance", instance.ID, "trigger", trigger, "error", err)
+		return err
+	}
+	logger().Info("Started snoozed instance", "instance", instance.ID, "name", instance.Name, "trigger", trigger,
+		"snoozed_for", time.Since(instance.StoppedAt).Round(time.Minute).String())
+	return nil
+}
+
+// Run checks the schedules and wake deadlines every interval until ctx is
+// cancelled
+func (r *Restarter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := r.checkDeadlines(ctx, now); err != nil {
+				logger().Warn("Failed to check wake deadlines", "error", err)
+			}
+			// A failed check is retried, so a schedule isn't missed
+			if err := r.checkSchedules(ctx, last, now); err != nil {
+				logger().Warn("Failed to check schedules", "error", err)
+				continue
+			}
+			last = now
+		}
+	}
+}
+
+// checkSchedules starts the instances of the schedules due after since and
+// at or before now
+func (r *Restarter) checkSchedules(ctx context.Context, since, now time.Time) error {
+	var due []Schedule
+	for _, schedule := range r.schedules {
+		if schedule.due(since, now, r.location) {
+			due = append(due, schedule)
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	r.starting.Lock()
+	defer r.starting.Unlock()
+	instances, err := r.fleet.SnoozedInstances(ctx)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if slices.ContainsFunc(due, func(s Schedule) bool { return s.matches(instance) }) {
+			// A failed start is logged; the others still go ahead
+			r.start(ctx, instance, TriggerSchedule)
+		}
+	}
+	return nil
+}
+
+// checkDeadlines starts the instances whose daemons limit how long they
+// stay snoozed, once the limit has passed
+func (r *Restarter) checkDeadlines(ctx context.Context, now time.Time) error {
+	r.starting.Lock()
+	defer r.starting.Unlock()
+	instances, err := r.fleet.SnoozedInstances(ctx)
+	if err != nil {
+		return err
+	}
+	for _, instance := range instances {
+		if instance.WakeAt != nil && !instance.WakeAt.After(now) {
+			r.start(ctx, instance, TriggerDeadline)
+		}
+	}
+	return nil
+}
+
+// WebhookHandler accepts POST /wake/INSTANCE_ID requests authorized with
+// "Authorization: Bearer TOKEN"
+func (r *Restarter) WebhookHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /wake/{id}", func(w http.ResponseWriter, req *http.Request) {
+		if token == "" || subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		instanceID := req.PathValue("id")
+		err := r.Wake(req.Context(), instanceID, TriggerWebhook)
+		switch {
+		case errors.Is(err, ErrNotSnoozed):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusBadGateway)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]interface{}{"instance_id": instanceID, "starting": true})
+		}
+	})
+	return mux
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+)
+
+// deadline is when i-ml must be started again
+var deadline = time.Date(2025, 5, 6, 18, 0, 0, 0, time.UTC)
+
+// fakeFleet has two snoozed instances and records starts
+type fakeFleet struct {
+	lock    sync.Mutex
+	started map[string]string
+	states  map[string]string // Instance states, which starting sets to running
+}
+
+func (f *fakeFleet) SnoozedInstances(ctx context.Context) ([]common.SnoozedInstance, error) {
+	return []common.SnoozedInstance{
+		{ID: "i-ml", Tags: map[string]string{"team": "ml"}, WakeAt: &deadline},
+		{ID: "i-web", Tags: map[string]string{"team": "web"}},
+	}, nil
+}
+
+func (f *fakeFleet) StartInstanceContext(ctx context.Context, instanceID, trigger string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.states != nil {
+		f.states[instanceID] = "running"
+	}
+	if f.started == nil {
+		f.started = make(map[string]string)
+	}
+	f.started[instanceID] = trigger
+	return nil
+}
+
+func (f *fakeFleet) Instance(ctx context.Context, instanceID string) (common.FleetInstance, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return common.FleetInstance{ID: instanceID, State: f.states[instanceID], PrivateIP: "127.0.0.1"}, nil
+}
+
+func (f *fakeFleet) trigger(instanceID string) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.started[instanceID]
+}
+
+func TestScheduleDue(t *testing.T) {
+	// Monday 8:00 in UTC
+	schedule := Schedule{Hour: 8, Days: []time.Weekday{time.Monday}}
+	monday := time.Date(2025, 5, 5, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		since, now time.Time
+		due        bool
+	}{
+		{"just passed", monday.Add(7*time.Hour + 59*time.Minute), monday.Add(8 * time.Hour), true},
+		{"already checked", monday.Add(8 * time.Hour), monday.Add(8*time.Hour + time.Minute), false},
+		{"not yet", monday.Add(7 * time.Hour), monday.Add(7*time.Hour + 59*time.Minute), false},
+		{"over the weekend", monday.Add(-48 * time.Hour), monday.Add(9 * time.Hour), true},
+		{"wrong day", monday.Add(31 * time.Hour), monday.Add(33 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		if due := schedule.due(tt.since, tt.now, time.UTC); due != tt.due {
+			t.Errorf("%s: expected due %v, got %v", tt.name, tt.due, due)
+		}
+	}
+}
+
+func TestCheckSchedules(t *testing.T) {
+	fleet := &fakeFleet{}
+	r := New(fleet, []Schedule{{Hour: 8, Tags: map[string]string{"team": "ml"}}}, time.UTC)
+
+	morning := time.Date(2025, 5, 5, 8, 0, 0, 0, time.UTC)
+	if err := r.checkSchedules(context.Background(), morning.Add(-time.Minute), morning); err != nil {
+		t.Fatalf("checkSchedules failed: %v", err)
+	}
+	if len(fleet.started) != 1 || fleet.started["i-ml"] != TriggerSchedule {
+		t.Errorf("Expected only i-ml to be started by the schedule, got %v", fleet.started)
+	}
+}
+
+func TestCheckDeadlines(t *testing.T) {
+	fleet := &fakeFleet{}
+	r := New(fleet, nil, time.UTC)
+
+	if err := r.checkDeadlines(context.Background(), deadline.Add(-time.Minute)); err != nil {
+		t.Fatalf("checkDeadlines failed: %v", err)
+	}
+	if len(fleet.started) != 0 {
+		t.Errorf("Expected nothing to be started before the deadline, got %v", fleet.started)
+	}
+	if err := r.checkDeadlines(context.Background(), deadline); err != nil {
+		t.Fatalf("checkDeadlines failed: %v", err)
+	}
+	if len(fleet.started) != 1 || fleet.started["i-ml"] != TriggerDeadline {
+		t.Errorf("Expected only i-ml to be started at its deadline, got %v", fleet.started)
+	}
+}
+
+func TestWake(t *testing.T) {
+	fleet := &fakeFleet{}
+	r := New(fleet, nil, time.UTC)
+
+	if err := r.Wake(context.Background(), "i-web", TriggerAPI); err != nil {
+		t.Fatalf("Wake failed: %v", err)
+	}
+	if fleet.started["i-web"] != TriggerAPI {
+		t.Errorf("Expected i-web to be started, got %v", fleet.started)
+	}
+	if err := r.Wake(context.Background(), "i-other", TriggerAPI); !errors.Is(err, ErrNotSnoozed) {
+		t.Errorf("Expected an instance that isn't snoozed to be refused, got %v", err)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	fleet := &fakeFleet{}
+	handler := New(fleet, nil, time.UTC).WebhookHandler("s3cret")
+
+	for _, tt := range []struct {
+		path, auth string
+		status     int
+	}{
+		{"/wake/i-ml", "", http.StatusUnauthorized},
+		{"/wake/i-ml", "Bearer wrong", http.StatusUnauthorized},
+		{"/wake/i-other", "Bearer s3cret", http.StatusNotFound},
+		{"/wake/i-ml", "Bearer s3cret", http.StatusAccepted},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		if recorder.Code != tt.status {
+			t.Errorf("%s with %q: expected status %d, got %d", tt.path, tt.auth, tt.status, recorder.Code)
+		}
+	}
+	if fleet.started["i-ml"] != TriggerWebhook {
+		t.Errorf("Expected i-ml to be started by the webhook, got %v", fleet.started)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// TriggerSSH records that an instance was started by a connection to its
+// wake-on-SSH listener
+const TriggerSSH = "ssh"
+
+// How often a starting instance is checked, and how long each attempt to
+// reach its SSH server waits
+var (
+	wakePollInterval = 5 * time.Second
+	wakeDialTimeout  = 5 * time.Second
+)
+
+// WakeOnSSH forwards connections to an instance's SSH server, starting the
+// instance first if it is snoozed
+type WakeOnSSH struct {
+	InstanceID string
+	Target     string        // host:port to forward to, empty for port 22 of the instance's private address
+	Timeout    time.Duration // How long a connection waits for the instance to start
+}
+
+// ServeWakeOnSSH forwards connections accepted by listener until ctx is
+// cancelled or the listener fails. Open sessions end when the process
+// exits.
+func (r *Restarter) ServeWakeOnSSH(ctx context.Context, listener net.Listener, w WakeOnSSH) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			r.forwardSSH(ctx, conn, w)
+		}()
+	}
+}
+
+// forwardSSH starts the instance if needed, then copies data between the
+// client and the instance until either side closes
+func (r *Restarter) forwardSSH(ctx context.Context, client net.Conn, w WakeOnSSH) {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, w.Timeout)
+	server, woke, err := r.connectSSH(ctx, w)
+	cancel()
+	if err != nil {
+		logger().Warn("Failed to forward SSH connection", "instance", w.InstanceID, "client", client.RemoteAddr().String(), "error", err)
+		return
+	}
+	defer server.Close()
+	if woke {
+		logger().Info("Forwarding SSH connection to woken instance", "instance", w.InstanceID,
+			"client", client.RemoteAddr().String(), "waited", time.Since(start).Round(time.Second).String())
+	}
+
+	done := make(chan struct{}, 2)
+	copyAndClose := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		// Pass the close on, so the other side sees the end of the session
+		if tcp, ok := dst.(interface{ CloseWrite() error }); ok {
+			tcp.CloseWrite()
+		} else {
+			dst.Close()
+		}
+		done <- struct{}{}
+	}
+	go copyAndClose(server, client)
+	go copyAndClose(client, server)
+	<-done
+	<-done
+}
+
+// connectSSH connects to the instance's SSH server, starting the instance
+// if it is snoozed and waiting for it to come up. It reports whether it
+// had to wait for the instance.
+func (r *Restarter) connectSSH(ctx context.Context, w WakeOnSSH) (net.Conn, bool, error) {
+	var dialer net.Dialer
+	woke, refused := false, false
+	for {
+		instance, err := r.fleet.Instance(ctx, w.InstanceID)
+		if err != nil {
+			return nil, woke, err
+		}
+
+		switch instance.State {
+		case "running":
+			target := w.Target
+			if target == "" {
+				address := instance.PrivateIP
+				if address == "" {
+					address = instance.PublicIP
+				}
+				target = net.JoinHostPort(address, "22")
+			}
+			dialCtx, cancel := context.WithTimeout(ctx, wakeDialTimeout)
+			conn, err := dialer.DialContext(dialCtx, "tcp", target)
+			cancel()
+			if err == nil {
+				return conn, woke, nil
+			}
+			// The SSH server may not be up yet
+			if !woke {
+				return nil, woke, err
+			}
+		case "stopped":
+			// Another connection may have started it already, in which
+			// case it is no longer snoozed
+			err := r.Wake(ctx, w.InstanceID, TriggerSSH)
+			if errors.Is(err, ErrNotSnoozed) && refused {
+				return nil, woke, err
+			}
+			if err != nil && !errors.Is(err, ErrNotSnoozed) {
+				return nil, woke, err
+			}
+			refused = err != nil
+			woke = true
+		case "pending", "stopping":
+			woke = true
+		default:
+			return nil, woke, fmt.Errorf("instance %s is %s", w.InstanceID, instance.State)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, woke, fmt.Errorf("instance %s did not start in time", w.InstanceID)
+		case <-time.After(wakePollInterval):
+		}
+	}
+}
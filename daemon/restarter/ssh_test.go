@@ -0,0 +1,88 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package restarter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// listenSSH serves a fake SSH server that sends a banner then echoes a line
+func listenSSH(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fmt.Fprint(conn, "SSH-2.0-fake\r\n")
+				line, _ := bufio.NewReader(conn).ReadString('\n')
+				fmt.Fprint(conn, line)
+			}()
+		}
+	}()
+	return listener
+}
+
+func TestWakeOnSSH(t *testing.T) {
+	wakePollInterval = 10 * time.Millisecond
+	server := listenSSH(t)
+	fleet := &fakeFleet{states: map[string]string{"i-ml": "stopped", "i-web": "stopped"}}
+	r := New(fleet, nil, time.UTC)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.ServeWakeOnSSH(ctx, listener, WakeOnSSH{InstanceID: "i-ml", Target: server.Addr().String(), Timeout: 5 * time.Second})
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	reader := bufio.NewReader(conn)
+	if banner, err := reader.ReadString('\n'); err != nil || banner != "SSH-2.0-fake\r\n" {
+		t.Fatalf("Expected the server's banner, got %q (%v)", banner, err)
+	}
+	fmt.Fprint(conn, "hello\n")
+	if echo, err := reader.ReadString('\n'); err != nil || echo != "hello\n" {
+		t.Fatalf("Expected the connection to be forwarded, got %q (%v)", echo, err)
+	}
+
+	if trigger := fleet.trigger("i-ml"); trigger != TriggerSSH {
+		t.Errorf("Expected i-ml to be started by SSH, got %q", trigger)
+	}
+	if trigger := fleet.trigger("i-web"); trigger != "" {
+		t.Errorf("Expected i-web to be left stopped, got %q", trigger)
+	}
+}
+
+func TestWakeOnSSHNotSnoozed(t *testing.T) {
+	wakePollInterval = 10 * time.Millisecond
+	fleet := &fakeFleet{states: map[string]string{"i-manual": "stopped"}}
+	r := New(fleet, nil, time.UTC)
+
+	_, _, err := r.connectSSH(context.Background(), WakeOnSSH{InstanceID: "i-manual", Timeout: time.Second})
+	if err == nil {
+		t.Fatal("Expected an instance that isn't snoozed to be refused")
+	}
+	if trigger := fleet.trigger("i-manual"); trigger != "" {
+		t.Errorf("Expected i-manual to be left stopped, got %q", trigger)
+	}
+}
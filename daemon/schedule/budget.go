@@ -0,0 +1,169 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BudgetStatus reports the consumption of the daily runtime budget
+type BudgetStatus struct {
+	Day              string  `json:"day"`
+	BudgetMinutes    float64 `json:"budget_minutes"`
+	UsedMinutes      float64 `json:"used_minutes"`
+	RemainingMinutes float64 `json:"remaining_minutes"`
+	Warned           bool    `json:"warned"`
+	Exceeded         bool    `json:"exceeded"`
+}
+
+// budgetState is the persisted form of the budget
+type budgetState struct {
+	Day         string  `json:"day"`
+	UsedSeconds float64 `json:"used_seconds"`
+	Warned      bool    `json:"warned"`
+}
+
+// Budget tracks how long the instance has been running on the current day
+// and whether the daily runtime limit has been reached
+type Budget struct {
+	limit     time.Duration
+	warning   time.Duration
+	location  *time.Location
+	statePath string
+
+	day    string
+	used   time.Duration
+	last   time.Time
+	warned bool
+	lock   sync.Mutex
+}
+
+// NewBudget creates a daily runtime budget. Days are evaluated in the given
+// location. If statePath is set, consumption survives daemon restarts.
+func NewBudget(limit, warning time.Duration, location *time.Location, statePath string) *Budget {
+	budget := &Budget{
+		limit:     limit,
+		warning:   warning,
+		location:  location,
+		statePath: statePath,
+	}
+
+	if statePath != "" {
+		if data, err := os.ReadFile(statePath); err == nil {
+			var state budgetState
+			if json.Unmarshal(data, &state) == nil {
+				budget.day = state.Day
+				budget.used = time.Duration(state.UsedSeconds * float64(time.Second))
+				budget.warned = state.Warned
+			}
+		}
+	}
+
+	return budget
+}
+
+// Record adds the running time elapsed since the previous call. Time during
+// which the daemon was not running (e.g. while the instance was stopped) is
+// not counted because the first call after startup only sets the baseline.
+func (b *Budget) Record(now time.Time) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now = now.In(b.location)
+	day := now.Format("2006-01-02")
+
+	var elapsed time.Duration
+	if !b.last.IsZero() && now.After(b.last) {
+		elapsed = now.Sub(b.last)
+	}
+	b.last = now
+
+	if day != b.day {
+		// New day: only the part of the interval after midnight counts
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, b.location)
+		if sinceMidnight := now.Sub(midnight); elapsed > sinceMidnight {
+			elapsed = sinceMidnight
+		}
+		b.day = day
+		b.used = 0
+		b.warned = false
+	}
+
+	b.used += elapsed
+	return b.save()
+}
+
+// NeedsWarning returns true once per day when the remaining budget drops to
+// the warning threshold
+func (b *Budget) NeedsWarning() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.warned || b.used < b.limit-b.warning {
+		return false
+	}
+
+	b.warned = true
+	if err := b.save(); err != nil {
+		logger().Warn("Failed to save runtime budget", "error", err)
+	}
+	return true
+}
+
+// Exceeded returns true when the budget is used up and the warning has been given
+func (b *Budget) Exceeded() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.warned && b.used >= b.limit
+}
+
+// Status returns the current budget consumption
+func (b *Budget) Status() BudgetStatus {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	remaining := b.limit - b.used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return BudgetStatus{
+		Day:              b.day,
+		BudgetMinutes:    b.limit.Minutes(),
+		UsedMinutes:      b.used.Minutes(),
+		RemainingMinutes: remaining.Minutes(),
+		Warned:           b.warned,
+		Exceeded:         b.used >= b.limit,
+	}
+}
+
+// save persists the budget state; the caller must hold the lock
+func (b *Budget) save() error {
+	if b.statePath == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.statePath), 0755); err != nil {
+		return fmt.Errorf("failed to create budget state directory: %v", err)
+	}
+
+	data, err := json.Marshal(budgetState{
+		Day:         b.day,
+		UsedSeconds: b.used.Seconds(),
+		Warned:      b.warned,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialize budget state: %v", err)
+	}
+
+	if err := os.WriteFile(b.statePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write budget state: %v", err)
+	}
+	return nil
+}
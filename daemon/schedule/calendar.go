@@ -0,0 +1,237 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package schedule
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WindowKind describes how a calendar window affects snoozing
+type WindowKind string
+
+const (
+	// WindowBlackout suppresses stopping while the window is active
+	WindowBlackout WindowKind = "blackout"
+	// WindowForceActive treats the system as active while the window is active
+	WindowForceActive WindowKind = "force-active"
+)
+
+// CalendarConfig holds the calendar integration configuration
+type CalendarConfig struct {
+	URL                 string
+	RefreshMinutes      int
+	CachePath           string
+	DefaultKind         WindowKind
+	BlackoutKeywords    []string
+	ForceActiveKeywords []string
+}
+
+// Window is a calendar event that is currently affecting the daemon
+type Window struct {
+	Kind    WindowKind `json:"kind"`
+	Summary string     `json:"summary"`
+	Start   time.Time  `json:"start"`
+	End     time.Time  `json:"end"`
+}
+
+// Calendar periodically fetches an iCal feed and answers whether a
+// maintenance window is active at a given time
+type Calendar struct {
+	config      CalendarConfig
+	location    *time.Location
+	client      *http.Client
+	events      []Event
+	lastRefresh time.Time
+	ticker      *time.Ticker
+	stopRefresh chan struct{}
+	lock        sync.RWMutex
+}
+
+// NewCalendar creates a new calendar for the given configuration
+func NewCalendar(config CalendarConfig) *Calendar {
+	if config.DefaultKind == "" {
+		config.DefaultKind = WindowBlackout
+	}
+	return &Calendar{
+		config:      config,
+		location:    time.Local,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		stopRefresh: make(chan struct{}),
+	}
+}
+
+// SetLocation sets the time zone used for floating event times. It must be
+// called before Initialize.
+func (c *Calendar) SetLocation(location *time.Location) {
+	c.lock.Lock()
+	c.location = location
+	c.lock.Unlock()
+}
+
+// Initialize loads the cached calendar, performs a first refresh and starts
+// the periodic refresh if configured
+func (c *Calendar) Initialize() error {
+	// Load the cached copy first so we have windows even when offline
+	if c.config.CachePath != "" {
+		if data, err := os.ReadFile(c.config.CachePath); err == nil {
+			if err := c.load(data); err != nil {
+				logger().Warn("Failed to parse cached calendar", "error", err)
+			}
+		}
+	}
+
+	err := c.Refresh()
+
+	if c.config.RefreshMinutes > 0 {
+		c.ticker = time.NewTicker(time.Duration(c.config.RefreshMinutes) * time.Minute)
+		go c.refreshLoop()
+	}
+
+	return err
+}
+
+// Refresh fetches the calendar from the configured URL and updates the cache
+func (c *Calendar) Refresh() error {
+	if c.config.URL == "" {
+		return fmt.Errorf("no calendar URL configured")
+	}
+
+	resp, err := c.client.Get(c.config.URL)
+	if err != nil {
+		return fmt.Errorf("failed to fetch calendar: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			logger().Error("Failed to close response body", "error", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch calendar, status: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read calendar: %v", err)
+	}
+
+	if err := c.load(data); err != nil {
+		return err
+	}
+
+	// Cache the raw feed for offline operation
+	if c.config.CachePath != "" {
+		if err := os.MkdirAll(filepath.Dir(c.config.CachePath), 0755); err != nil {
+			logger().Warn("Failed to create calendar cache directory", "error", err)
+		} else if err := os.WriteFile(c.config.CachePath, data, 0644); err != nil {
+			logger().Warn("Failed to write calendar cache", "error", err)
+		}
+	}
+
+	return nil
+}
+
+// load parses raw iCal data and replaces the current event list
+func (c *Calendar) load(data []byte) error {
+	c.lock.RLock()
+	loc := c.location
+	c.lock.RUnlock()
+
+	events, err := ParseICal(bytes.NewReader(data), loc)
+	if err != nil {
+		return fmt.Errorf("failed to parse calendar: %v", err)
+	}
+
+	c.lock.Lock()
+	c.events = events
+	c.lastRefresh = time.Now()
+	c.lock.Unlock()
+
+	return nil
+}
+
+// refreshLoop refreshes the calendar until Stop is called
+func (c *Calendar) refreshLoop() {
+	for {
+		select {
+		case <-c.ticker.C:
+			if err := c.Refresh(); err != nil {
+				logger().Warn("Calendar refresh failed, using cached events", "error", err)
+			}
+		case <-c.stopRefresh:
+			c.ticker.Stop()
+			return
+		}
+	}
+}
+
+// Stop stops the periodic refresh
+func (c *Calendar) Stop() {
+	if c.ticker != nil {
+		close(c.stopRefresh)
+	}
+}
+
+// ActiveWindow returns the window active at the given time, or nil if none.
+// Force-active windows take precedence over blackout windows.
+func (c *Calendar) ActiveWindow(now time.Time) *Window {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var active *Window
+	for _, event := range c.events {
+		start, end, ok := event.OccurrenceAt(now)
+		if !ok {
+			continue
+		}
+
+		window := &Window{
+			Kind:    c.classify(event),
+			Summary: event.Summary,
+			Start:   start,
+			End:     end,
+		}
+		if window.Kind == WindowForceActive {
+			return window
+		}
+		if active == nil {
+			active = window
+		}
+	}
+
+	return active
+}
+
+// LastRefresh returns the time the calendar was last loaded
+func (c *Calendar) LastRefresh() time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.lastRefresh
+}
+
+// classify determines the window kind of an event from its summary and categories
+func (c *Calendar) classify(event Event) WindowKind {
+	text := strings.ToLower(event.Summary + " " + strings.Join(event.Categories, " "))
+
+	for _, keyword := range c.config.ForceActiveKeywords {
+		if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			return WindowForceActive
+		}
+	}
+	for _, keyword := range c.config.BlackoutKeywords {
+		if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			return WindowBlackout
+		}
+	}
+
+	return c.config.DefaultKind
+}
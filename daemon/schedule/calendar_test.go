@@ -0,0 +1,289 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package schedule
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testFeed = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:release-1\r\n" +
+	"SUMMARY:Release night\r\n" +
+	"DTSTART:20250501T180000Z\r\n" +
+	"DTEND:20250501T230000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:standup\r\n" +
+	"SUMMARY:Daily demo environment\r\n" +
+	"CATEGORIES:keep-alive\r\n" +
+	"DTSTART:20250501T090000Z\r\n" +
+	"DURATION:PT1H\r\n" +
+	"RRULE:FREQ=DAILY;COUNT=5\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICal(t *testing.T) {
+	events, err := ParseICal(strings.NewReader(testFeed), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseICal returned error: %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+
+	if events[0].Summary != "Release night" {
+		t.Errorf("Expected summary 'Release night', got '%s'", events[0].Summary)
+	}
+
+	if events[1].End.Sub(events[1].Start) != time.Hour {
+		t.Errorf("Expected DURATION to produce a 1 hour event, got %v", events[1].End.Sub(events[1].Start))
+	}
+
+	if events[1].Recurrence == nil || events[1].Recurrence.Days != 1 || events[1].Recurrence.Count != 5 {
+		t.Errorf("Expected daily recurrence with count 5, got %+v", events[1].Recurrence)
+	}
+}
+
+func TestParseICalFoldedLinesAndTZID(t *testing.T) {
+	feed := "BEGIN:VEVENT\r\n" +
+		"SUMMARY:Long\r\n" +
+		" running freeze\r\n" +
+		"DTSTART;TZID=America/New_York:20250301T090000\r\n" +
+		"DTEND;TZID=America/New_York:20250301T100000\r\n" +
+		"END:VEVENT\r\n"
+
+	events, err := ParseICal(strings.NewReader(feed), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseICal returned error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+
+	if events[0].Summary != "Longrunning freeze" {
+		t.Errorf("Expected folded summary, got '%s'", events[0].Summary)
+	}
+
+	// 09:00 EST is 14:00 UTC
+	if events[0].Start.UTC().Hour() != 14 {
+		t.Errorf("Expected TZID to be honoured, got %v", events[0].Start.UTC())
+	}
+}
+
+func TestOccurrenceAtRecurring(t *testing.T) {
+	events, err := ParseICal(strings.NewReader(testFeed), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseICal returned error: %v", err)
+	}
+	daily := events[1]
+
+	inside := time.Date(2025, 5, 3, 9, 30, 0, 0, time.UTC)
+	if _, _, ok := daily.OccurrenceAt(inside); !ok {
+		t.Errorf("Expected occurrence on day 3")
+	}
+
+	outside := time.Date(2025, 5, 3, 10, 30, 0, 0, time.UTC)
+	if _, _, ok := daily.OccurrenceAt(outside); ok {
+		t.Errorf("Expected no occurrence after the event ends")
+	}
+
+	pastCount := time.Date(2025, 5, 7, 9, 30, 0, 0, time.UTC)
+	if _, _, ok := daily.OccurrenceAt(pastCount); ok {
+		t.Errorf("Expected no occurrence after COUNT is exhausted")
+	}
+}
+
+// weekdayFeed is a Monday to Friday series from Monday 5 May 2025, as
+// Outlook and Google write them, with Wednesday 7 May removed, Thursday 8
+// May moved to the afternoon and Friday 9 May cancelled
+const weekdayFeed = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:office-hours\r\n" +
+	"SUMMARY:Office hours\r\n" +
+	"DTSTART:20250505T090000Z\r\n" +
+	"DTEND:20250505T100000Z\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;WKST=MO\r\n" +
+	"EXDATE:20250507T090000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:office-hours\r\n" +
+	"RECURRENCE-ID:20250508T090000Z\r\n" +
+	"SUMMARY:Office hours (moved)\r\n" +
+	"DTSTART:20250508T140000Z\r\n" +
+	"DTEND:20250508T150000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:office-hours\r\n" +
+	"RECURRENCE-ID:20250509T090000Z\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART:20250509T090000Z\r\n" +
+	"DTEND:20250509T100000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestOccurrenceAtWeekdays(t *testing.T) {
+	events, err := ParseICal(strings.NewReader(weekdayFeed), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseICal returned error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected the series and the moved instance, got %d events", len(events))
+	}
+
+	active := func(now time.Time) bool {
+		for _, event := range events {
+			if _, _, ok := event.OccurrenceAt(now); ok {
+				return true
+			}
+		}
+		return false
+	}
+	at := func(day, hour int) time.Time {
+		return time.Date(2025, 5, day, hour, 30, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"Monday", at(5, 9), true},
+		{"Tuesday", at(6, 9), true},
+		{"Wednesday removed by EXDATE", at(7, 9), false},
+		{"Thursday's usual time after the instance moved", at(8, 9), false},
+		{"Thursday's moved instance", at(8, 14), true},
+		{"Friday's cancelled instance", at(9, 9), false},
+		{"Saturday", at(10, 9), false},
+		{"Sunday", at(11, 9), false},
+		{"Next Monday", at(12, 9), true},
+		{"Next Friday", at(16, 9), true},
+	}
+	for _, tt := range tests {
+		if got := active(tt.now); got != tt.want {
+			t.Errorf("%s: expected active %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestOccurrenceAtFortnightlyWeekdays(t *testing.T) {
+	feed := "BEGIN:VEVENT\r\n" +
+		"DTSTART:20250505T090000Z\r\n" +
+		"DURATION:PT1H\r\n" +
+		"RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=3\r\n" +
+		"EXDATE;VALUE=DATE:20250508\r\n" +
+		"END:VEVENT\r\n"
+	events, err := ParseICal(strings.NewReader(feed), time.UTC)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d: %v", len(events), err)
+	}
+
+	// Mon 5, Thu 8 (removed, but counted), Mon 19; then COUNT is used up
+	for day, want := range map[int]bool{5: true, 8: false, 12: false, 15: false, 19: true, 22: false} {
+		if _, _, ok := events[0].OccurrenceAt(time.Date(2025, 5, day, 9, 30, 0, 0, time.UTC)); ok != want {
+			t.Errorf("May %d: expected occurrence %v, got %v", day, want, ok)
+		}
+	}
+}
+
+func TestParseICalSkipsUnsupportedRecurrence(t *testing.T) {
+	rules := []string{
+		"RRULE:FREQ=MONTHLY;BYDAY=1MO",
+		"RRULE:FREQ=YEARLY",
+		"RRULE:FREQ=WEEKLY;BYMONTHDAY=1",
+		"RRULE:FREQ=WEEKLY;BYDAY=1MO",
+		"RDATE:20250601T090000Z",
+	}
+	for _, rule := range rules {
+		feed := "BEGIN:VEVENT\r\n" +
+			"DTSTART:20250505T090000Z\r\n" +
+			"DURATION:PT1H\r\n" +
+			rule + "\r\n" +
+			"END:VEVENT\r\n" +
+			"BEGIN:VEVENT\r\n" +
+			"DTSTART:20250506T090000Z\r\n" +
+			"DURATION:PT1H\r\n" +
+			"END:VEVENT\r\n"
+		events, err := ParseICal(strings.NewReader(feed), time.UTC)
+		if err != nil {
+			t.Fatalf("%s: ParseICal returned error: %v", rule, err)
+		}
+		if len(events) != 1 || !events[0].Start.Equal(time.Date(2025, 5, 6, 9, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: expected only the other event, got %+v", rule, events)
+		}
+	}
+}
+
+func TestParseICalCancelledEvent(t *testing.T) {
+	feed := "BEGIN:VEVENT\r\n" +
+		"STATUS:CANCELLED\r\n" +
+		"DTSTART:20250505T090000Z\r\n" +
+		"DURATION:PT1H\r\n" +
+		"RRULE:FREQ=DAILY\r\n" +
+		"END:VEVENT\r\n"
+	events, err := ParseICal(strings.NewReader(feed), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseICal returned error: %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected a cancelled series to be dropped, got %+v", events)
+	}
+}
+
+func TestCalendarActiveWindow(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testFeed)
+	}))
+	defer server.Close()
+
+	tempDir, err := os.MkdirTemp("", "calendar-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	cachePath := filepath.Join(tempDir, "calendar.ics")
+	calendar := NewCalendar(CalendarConfig{
+		URL:                 server.URL,
+		CachePath:           cachePath,
+		ForceActiveKeywords: []string{"keep-alive"},
+	})
+	if err := calendar.Initialize(); err != nil {
+		t.Fatalf("Initialize returned error: %v", err)
+	}
+	defer calendar.Stop()
+
+	window := calendar.ActiveWindow(time.Date(2025, 5, 1, 20, 0, 0, 0, time.UTC))
+	if window == nil || window.Kind != WindowBlackout {
+		t.Errorf("Expected blackout window during release night, got %+v", window)
+	}
+
+	window = calendar.ActiveWindow(time.Date(2025, 5, 2, 9, 15, 0, 0, time.UTC))
+	if window == nil || window.Kind != WindowForceActive {
+		t.Errorf("Expected force-active window during demo, got %+v", window)
+	}
+
+	if window := calendar.ActiveWindow(time.Date(2025, 5, 2, 12, 0, 0, 0, time.UTC)); window != nil {
+		t.Errorf("Expected no window, got %+v", window)
+	}
+
+	// A second calendar pointed at an unreachable URL should fall back to the cache
+	server.Close()
+	offline := NewCalendar(CalendarConfig{URL: server.URL, CachePath: cachePath})
+	if err := offline.Initialize(); err == nil {
+		t.Errorf("Expected refresh error when offline")
+	}
+	if window := offline.ActiveWindow(time.Date(2025, 5, 1, 20, 0, 0, 0, time.UTC)); window == nil {
+		t.Errorf("Expected cached window when offline")
+	}
+}
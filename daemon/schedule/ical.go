@@ -0,0 +1,453 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package schedule
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a single VEVENT parsed from an iCal feed
+type Event struct {
+	UID        string
+	Summary    string
+	Categories []string
+	Start      time.Time
+	End        time.Time
+	Recurrence *Recurrence
+}
+
+// Recurrence is the subset of RRULE supported for calendar windows:
+// FREQ=DAILY or FREQ=WEEKLY with optional INTERVAL, BYDAY, WKST, COUNT and
+// UNTIL, less the occurrences removed by EXDATE or moved by RECURRENCE-ID
+type Recurrence struct {
+	Days      int            // Days between occurrences, or between the weeks of a weekly rule with Weekdays
+	Weekdays  []time.Weekday // Days of the week occurrences fall on (empty for any day a daily rule lands on, or the start's for weekly)
+	WeekStart time.Weekday   // First day of the week, for weekly rules with Weekdays
+	Weekly    bool
+	Count     int // Maximum number of occurrences (0 for unlimited)
+	Until     time.Time
+
+	Except     []time.Time // Starts of occurrences that don't happen
+	ExceptDays []time.Time // Dates on which occurrences don't happen
+}
+
+// icalWeekdays maps BYDAY and WKST day names to weekdays
+var icalWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseICal parses the VEVENTs in an iCal feed. Floating times are
+// interpreted in the given location.
+func ParseICal(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var current *Event
+	var duration time.Duration
+
+	// State of the current event that isn't kept in it
+	var recurrenceID time.Time
+	var cancelled bool
+	var unsupported error
+	var exceptions []time.Time
+	var exceptionDays []time.Time
+
+	// Instances of recurring events that were moved or cancelled, by UID
+	moved := make(map[string][]time.Time)
+
+	for _, line := range lines {
+		name, params, value, ok := splitProperty(line)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &Event{}
+			duration = 0
+			recurrenceID, cancelled, unsupported = time.Time{}, false, nil
+			exceptions, exceptionDays = nil, nil
+		case name == "END" && value == "VEVENT":
+			if current == nil {
+				continue
+			}
+			event := *current
+			current = nil
+			if event.End.IsZero() {
+				event.End = event.Start.Add(duration)
+			}
+			if !recurrenceID.IsZero() {
+				// An instance of a recurring event moved, changed or
+				// cancelled: it replaces the occurrence it is for
+				moved[event.UID] = append(moved[event.UID], recurrenceID)
+				event.Recurrence = nil
+			} else if unsupported != nil {
+				logger().Warn("Skipping calendar event with unsupported recurrence", "summary", event.Summary, "uid", event.UID, "error", unsupported)
+				continue
+			}
+			if cancelled {
+				continue
+			}
+			if event.Recurrence != nil {
+				event.Recurrence.Except = exceptions
+				event.Recurrence.ExceptDays = exceptionDays
+			}
+			if !event.Start.IsZero() && event.End.After(event.Start) {
+				events = append(events, event)
+			}
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescapeText(value)
+		case name == "CATEGORIES":
+			for _, category := range strings.Split(value, ",") {
+				current.Categories = append(current.Categories, unescapeText(category))
+			}
+		case name == "DTSTART":
+			t, err := parseICalTime(value, params, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTSTART %q: %v", value, err)
+			}
+			current.Start = t
+		case name == "DTEND":
+			t, err := parseICalTime(value, params, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTEND %q: %v", value, err)
+			}
+			current.End = t
+		case name == "DURATION":
+			d, err := parseICalDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DURATION %q: %v", value, err)
+			}
+			duration = d
+		case name == "RRULE":
+			current.Recurrence, err = parseRecurrence(value, loc)
+			if err != nil {
+				unsupported = err
+			}
+		case name == "RDATE" || name == "EXRULE":
+			unsupported = fmt.Errorf("%s is not supported", name)
+		case name == "EXDATE":
+			for _, date := range strings.Split(value, ",") {
+				t, err := parseICalTime(date, params, loc)
+				if err != nil {
+					return nil, fmt.Errorf("invalid EXDATE %q: %v", date, err)
+				}
+				if params["VALUE"] == "DATE" || len(date) == 8 {
+					exceptionDays = append(exceptionDays, t)
+				} else {
+					exceptions = append(exceptions, t)
+				}
+			}
+		case name == "RECURRENCE-ID":
+			t, err := parseICalTime(value, params, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RECURRENCE-ID %q: %v", value, err)
+			}
+			recurrenceID = t
+		case name == "STATUS":
+			cancelled = strings.EqualFold(value, "CANCELLED")
+		}
+	}
+
+	// Moved and cancelled instances no longer happen when the series says
+	for i := range events {
+		if recurrence := events[i].Recurrence; recurrence != nil {
+			recurrence.Except = append(recurrence.Except, moved[events[i].UID]...)
+		}
+	}
+
+	return events, nil
+}
+
+// OccurrenceAt returns the occurrence of the event covering the given time
+func (e Event) OccurrenceAt(now time.Time) (time.Time, time.Time, bool) {
+	length := e.End.Sub(e.Start)
+
+	if e.Recurrence == nil {
+		if !now.Before(e.Start) && now.Before(e.End) {
+			return e.Start, e.End, true
+		}
+		return time.Time{}, time.Time{}, false
+	}
+
+	if now.Before(e.Start) || e.Recurrence.Days <= 0 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	// Only occurrences starting on the days from the event's length before
+	// now until now can cover it. A day either side allows for DST
+	// transitions (23 or 25 hour days).
+	last := int(now.Sub(e.Start).Hours() / 24)
+	first := int(now.Add(-length).Sub(e.Start).Hours()/24) - 1
+	for n := max(first, 0); n <= last+1; n++ {
+		if !e.Recurrence.occursOn(e.Start, n) {
+			continue
+		}
+		start := e.Start.AddDate(0, 0, n)
+		if !e.Recurrence.Until.IsZero() && start.After(e.Recurrence.Until) {
+			break
+		}
+		if e.Recurrence.Count > 0 && e.Recurrence.occurrencesBefore(e.Start, n) >= e.Recurrence.Count {
+			break
+		}
+		if e.Recurrence.excepted(start) {
+			continue
+		}
+
+		end := start.Add(length)
+		if !now.Before(start) && now.Before(end) {
+			return start, end, true
+		}
+	}
+
+	return time.Time{}, time.Time{}, false
+}
+
+// occursOn reports whether the rule has an occurrence n days after start,
+// before COUNT, UNTIL and exceptions are applied
+func (r *Recurrence) occursOn(start time.Time, n int) bool {
+	day := start.AddDate(0, 0, n)
+	if !r.Weekly || len(r.Weekdays) == 0 {
+		if n%r.Days != 0 {
+			return false
+		}
+		return len(r.Weekdays) == 0 || slices.Contains(r.Weekdays, day.Weekday())
+	}
+
+	// Weekly rules with BYDAY repeat every Days/7 weeks, counted from the
+	// week that starts on WeekStart and holds the start
+	if !slices.Contains(r.Weekdays, day.Weekday()) {
+		return false
+	}
+	offset := (int(start.Weekday()) - int(r.WeekStart) + 7) % 7
+	return ((n+offset)/7)%(r.Days/7) == 0
+}
+
+// occurrencesBefore counts the occurrences before the one n days after
+// start, which COUNT limits
+func (r *Recurrence) occurrencesBefore(start time.Time, n int) int {
+	count := 0
+	for i := 0; i < n && count < r.Count; i++ {
+		if r.occursOn(start, i) {
+			count++
+		}
+	}
+	return count
+}
+
+// excepted reports whether the occurrence at start was removed or moved
+func (r *Recurrence) excepted(start time.Time) bool {
+	for _, t := range r.Except {
+		if t.Equal(start) {
+			return true
+		}
+	}
+	for _, t := range r.ExceptDays {
+		local := start.In(t.Location())
+		if local.Year() == t.Year() && local.YearDay() == t.YearDay() {
+			return true
+		}
+	}
+	return false
+}
+
+// unfoldLines reads content lines, joining folded continuation lines
+func unfoldLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %v", err)
+	}
+	return lines, nil
+}
+
+// splitProperty splits a content line into name, parameters and value
+func splitProperty(line string) (string, map[string]string, string, bool) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", nil, "", false
+	}
+
+	head := strings.Split(line[:colon], ";")
+	params := make(map[string]string)
+	for _, param := range head[1:] {
+		if key, value, found := strings.Cut(param, "="); found {
+			params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+		}
+	}
+
+	return strings.ToUpper(head[0]), params, line[colon+1:], true
+}
+
+// parseICalTime parses DATE and DATE-TIME values
+func parseICalTime(value string, params map[string]string, loc *time.Location) (time.Time, error) {
+	if tzid, ok := params["TZID"]; ok {
+		if tz, err := time.LoadLocation(tzid); err == nil {
+			loc = tz
+		}
+	}
+
+	switch {
+	case params["VALUE"] == "DATE" || len(value) == 8:
+		return time.ParseInLocation("20060102", value, loc)
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	default:
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+}
+
+// parseICalDuration parses the common forms of an RFC 5545 duration
+// (e.g. PT2H, P1D, P1DT30M)
+func parseICalDuration(value string) (time.Duration, error) {
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimLeft(value, "+-")
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("duration must start with P")
+	}
+
+	var total time.Duration
+	var number string
+	inTime := false
+	for _, ch := range value[1:] {
+		switch {
+		case ch >= '0' && ch <= '9':
+			number += string(ch)
+			continue
+		case ch == 'T':
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number in duration")
+		}
+		number = ""
+
+		switch {
+		case ch == 'W':
+			total += time.Duration(n) * 7 * 24 * time.Hour
+		case ch == 'D':
+			total += time.Duration(n) * 24 * time.Hour
+		case ch == 'H' && inTime:
+			total += time.Duration(n) * time.Hour
+		case ch == 'M' && inTime:
+			total += time.Duration(n) * time.Minute
+		case ch == 'S' && inTime:
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("unexpected designator %q", ch)
+		}
+	}
+
+	if negative {
+		total = -total
+	}
+	return total, nil
+}
+
+// parseRecurrence parses an RRULE value, failing for rules that can't be
+// followed exactly, so their events aren't applied on the wrong days
+func parseRecurrence(value string, loc *time.Location) (*Recurrence, error) {
+	rule := make(map[string]string)
+	for _, part := range strings.Split(value, ";") {
+		if key, val, found := strings.Cut(part, "="); found {
+			rule[strings.ToUpper(key)] = strings.ToUpper(val)
+		}
+	}
+	for key := range rule {
+		switch key {
+		case "FREQ", "INTERVAL", "COUNT", "UNTIL", "BYDAY", "WKST":
+		default:
+			return nil, fmt.Errorf("RRULE %s is not supported", key)
+		}
+	}
+
+	interval := 1
+	if v, ok := rule["INTERVAL"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid RRULE INTERVAL %q", v)
+		}
+		interval = n
+	}
+
+	recurrence := &Recurrence{WeekStart: time.Monday}
+	switch rule["FREQ"] {
+	case "DAILY":
+		recurrence.Days = interval
+	case "WEEKLY":
+		recurrence.Days = 7 * interval
+		recurrence.Weekly = true
+	default:
+		return nil, fmt.Errorf("RRULE FREQ=%s is not supported", rule["FREQ"])
+	}
+
+	if days, ok := rule["BYDAY"]; ok {
+		for _, day := range strings.Split(days, ",") {
+			weekday, ok := icalWeekdays[day]
+			if !ok {
+				// Such as 1MO, which only monthly and yearly rules use
+				return nil, fmt.Errorf("RRULE BYDAY=%s is not supported", day)
+			}
+			recurrence.Weekdays = append(recurrence.Weekdays, weekday)
+		}
+	}
+	if day, ok := rule["WKST"]; ok {
+		weekday, ok := icalWeekdays[day]
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE WKST %q", day)
+		}
+		recurrence.WeekStart = weekday
+	}
+
+	if v, ok := rule["COUNT"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid RRULE COUNT %q", v)
+		}
+		recurrence.Count = n
+	}
+	if until, ok := rule["UNTIL"]; ok {
+		t, err := parseICalTime(until, nil, loc)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE UNTIL %q", until)
+		}
+		recurrence.Until = t
+	}
+
+	return recurrence, nil
+}
+
+// unescapeText reverses iCal TEXT escaping
+func unescapeText(value string) string {
+	replacer := strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`)
+	return strings.TrimSpace(replacer.Replace(value))
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package schedule
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+)
+
+// logger returns the schedule component logger
+func logger() *slog.Logger {
+	return logging.Component("schedule")
+}
+
+// Scheduler evaluates all time-based behavior in a single configured time zone
+type Scheduler struct {
+	location *time.Location
+	zoneName string
+	calendar *Calendar
+	budget   *Budget
+	weekend  map[time.Weekday]bool
+}
+
+// NewScheduler creates a scheduler for the given IANA time zone name.
+// An empty name selects the instance's local time zone.
+func NewScheduler(timezone string) (*Scheduler, error) {
+	location, zoneName, err := LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Scheduler{
+		location: location,
+		zoneName: zoneName,
+		weekend:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+	}, nil
+}
+
+// LoadLocation resolves an IANA time zone name, falling back to the
+// instance's local time zone when the name is empty. It also returns the
+// name of the resolved zone for reporting.
+func LoadLocation(timezone string) (*time.Location, string, error) {
+	if timezone == "" {
+		return time.Local, localZoneName(), nil
+	}
+
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid time zone %q: %v", timezone, err)
+	}
+	return location, location.String(), nil
+}
+
+// localZoneName determines the IANA name of the local time zone where possible
+func localZoneName() string {
+	if tz := os.Getenv("TZ"); tz != "" {
+		return strings.TrimPrefix(tz, ":")
+	}
+
+	// Most Linux distributions and macOS symlink /etc/localtime into the zoneinfo database
+	if target, err := filepath.EvalSymlinks("/etc/localtime"); err == nil {
+		if idx := strings.Index(target, "zoneinfo/"); idx >= 0 {
+			return target[idx+len("zoneinfo/"):]
+		}
+	}
+
+	return time.Local.String()
+}
+
+// Location returns the scheduler's time zone
+func (s *Scheduler) Location() *time.Location {
+	return s.location
+}
+
+// ZoneName returns the name of the active time zone
+func (s *Scheduler) ZoneName() string {
+	return s.zoneName
+}
+
+// Now returns the current time in the scheduler's time zone
+func (s *Scheduler) Now() time.Time {
+	return time.Now().In(s.location)
+}
+
+// SetWeekendDays sets which days of the week count as the weekend
+func (s *Scheduler) SetWeekendDays(days []time.Weekday) {
+	s.weekend = make(map[time.Weekday]bool, len(days))
+	for _, day := range days {
+		s.weekend[day] = true
+	}
+}
+
+// IsWeekend returns true if the given time falls on a weekend day in the
+// scheduler's time zone
+func (s *Scheduler) IsWeekend(t time.Time) bool {
+	return s.weekend[t.In(s.location).Weekday()]
+}
+
+// ParseWeekdays converts day names such as "saturday" or "sun" to weekdays
+func ParseWeekdays(names []string) ([]time.Weekday, error) {
+	days := make([]time.Weekday, 0, len(names))
+	for _, name := range names {
+		found := false
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			full := strings.ToLower(day.String())
+			if n := strings.ToLower(strings.TrimSpace(name)); n == full || n == full[:3] {
+				days = append(days, day)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid day of week %q", name)
+		}
+	}
+	return days, nil
+}
+
+// SetCalendar attaches a maintenance window calendar to the scheduler,
+// making floating calendar times evaluate in the scheduler's time zone
+func (s *Scheduler) SetCalendar(calendar *Calendar) {
+	calendar.SetLocation(s.location)
+	s.calendar = calendar
+}
+
+// Calendar returns the attached calendar, if any
+func (s *Scheduler) Calendar() *Calendar {
+	return s.calendar
+}
+
+// ActiveWindow returns the calendar window active now, or nil if none
+func (s *Scheduler) ActiveWindow() *Window {
+	if s.calendar == nil {
+		return nil
+	}
+	return s.calendar.ActiveWindow(s.Now())
+}
+
+// SetBudget attaches a daily runtime budget to the scheduler
+func (s *Scheduler) SetBudget(budget *Budget) {
+	s.budget = budget
+}
+
+// Budget returns the attached runtime budget, if any
+func (s *Scheduler) Budget() *Budget {
+	return s.budget
+}
+
+// Stop stops any background activity owned by the scheduler
+func (s *Scheduler) Stop() {
+	if s.calendar != nil {
+		s.calendar.Stop()
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package schedule
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSchedulerTimezone(t *testing.T) {
+	scheduler, err := NewScheduler("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("NewScheduler returned error: %v", err)
+	}
+
+	if scheduler.ZoneName() != "Europe/Berlin" {
+		t.Errorf("Expected zone Europe/Berlin, got %s", scheduler.ZoneName())
+	}
+
+	if scheduler.Now().Location().String() != "Europe/Berlin" {
+		t.Errorf("Expected Now() in Europe/Berlin, got %s", scheduler.Now().Location())
+	}
+}
+
+func TestNewSchedulerLocalFallback(t *testing.T) {
+	scheduler, err := NewScheduler("")
+	if err != nil {
+		t.Fatalf("NewScheduler returned error: %v", err)
+	}
+
+	if scheduler.Location() != time.Local {
+		t.Errorf("Expected local time zone when none is configured")
+	}
+
+	if scheduler.ZoneName() == "" {
+		t.Errorf("Expected a non-empty zone name")
+	}
+}
+
+func TestNewSchedulerInvalidTimezone(t *testing.T) {
+	if _, err := NewScheduler("Not/AZone"); err == nil {
+		t.Errorf("Expected error for invalid time zone")
+	}
+}
+
+func TestFloatingRecurrenceAcrossDST(t *testing.T) {
+	location, _, err := LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation returned error: %v", err)
+	}
+
+	// Daily 09:00-10:00 local window starting before the March 2025 DST change
+	feed := "BEGIN:VEVENT\r\n" +
+		"SUMMARY:Morning freeze\r\n" +
+		"DTSTART:20250305T090000\r\n" +
+		"DTEND:20250305T100000\r\n" +
+		"RRULE:FREQ=DAILY\r\n" +
+		"END:VEVENT\r\n"
+
+	events, err := ParseICal(strings.NewReader(feed), location)
+	if err != nil {
+		t.Fatalf("ParseICal returned error: %v", err)
+	}
+
+	// After DST starts on March 9th, 09:30 local is 13:30 UTC rather than 14:30 UTC
+	afterDST := time.Date(2025, 3, 12, 13, 30, 0, 0, time.UTC)
+	if _, _, ok := events[0].OccurrenceAt(afterDST); !ok {
+		t.Errorf("Expected window at 09:30 local time after DST change")
+	}
+
+	stale := time.Date(2025, 3, 12, 14, 30, 0, 0, time.UTC)
+	if _, _, ok := events[0].OccurrenceAt(stale); ok {
+		t.Errorf("Expected no window at 10:30 local time after DST change")
+	}
+}
+
+func TestBudgetRecordAndExceed(t *testing.T) {
+	budget := NewBudget(2*time.Hour, 30*time.Minute, time.UTC, "")
+
+	start := time.Date(2025, 5, 1, 8, 0, 0, 0, time.UTC)
+	budget.Record(start) // Baseline only
+
+	budget.Record(start.Add(time.Hour))
+	if budget.NeedsWarning() {
+		t.Errorf("Expected no warning after 1 hour of a 2 hour budget")
+	}
+
+	budget.Record(start.Add(90 * time.Minute))
+	if !budget.NeedsWarning() {
+		t.Errorf("Expected warning with 30 minutes remaining")
+	}
+	if budget.NeedsWarning() {
+		t.Errorf("Expected the warning to be given only once")
+	}
+	if budget.Exceeded() {
+		t.Errorf("Expected budget not to be exceeded yet")
+	}
+
+	budget.Record(start.Add(2 * time.Hour))
+	if !budget.Exceeded() {
+		t.Errorf("Expected budget to be exceeded after 2 hours")
+	}
+
+	status := budget.Status()
+	if status.UsedMinutes != 120 || status.RemainingMinutes != 0 {
+		t.Errorf("Unexpected budget status: %+v", status)
+	}
+}
+
+func TestBudgetResetsAtMidnight(t *testing.T) {
+	location, _, err := LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("LoadLocation returned error: %v", err)
+	}
+	budget := NewBudget(time.Hour, 0, location, "")
+
+	// 23:30 to 00:15 local time: only 15 minutes count towards the new day
+	budget.Record(time.Date(2025, 5, 1, 23, 30, 0, 0, location))
+	budget.Record(time.Date(2025, 5, 2, 0, 15, 0, 0, location))
+
+	status := budget.Status()
+	if status.Day != "2025-05-02" {
+		t.Errorf("Expected day 2025-05-02, got %s", status.Day)
+	}
+	if status.UsedMinutes != 15 {
+		t.Errorf("Expected 15 minutes used, got %.1f", status.UsedMinutes)
+	}
+}
+
+func TestBudgetPersistsAcrossRestarts(t *testing.T) {
+	location, _, err := LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("LoadLocation returned error: %v", err)
+	}
+	statePath := filepath.Join(t.TempDir(), "budget.json")
+
+	// Midday, so the records don't cross midnight and reset the budget
+	now := time.Date(2025, 5, 1, 12, 0, 0, 0, location)
+
+	budget := NewBudget(time.Hour, 0, location, statePath)
+	budget.Record(now)
+	budget.Record(now.Add(20 * time.Minute))
+
+	restored := NewBudget(time.Hour, 0, location, statePath)
+	restored.Record(now.Add(40 * time.Minute)) // Downtime is not counted
+
+	if used := restored.Status().UsedMinutes; used != 20 {
+		t.Errorf("Expected 20 minutes restored, got %.1f", used)
+	}
+}
+
+func TestWeekendDays(t *testing.T) {
+	scheduler, err := NewScheduler("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("NewScheduler returned error: %v", err)
+	}
+
+	// Friday 20:00 UTC is already Saturday morning in Tokyo
+	fridayUTC := time.Date(2025, 5, 2, 20, 0, 0, 0, time.UTC)
+	if !scheduler.IsWeekend(fridayUTC) {
+		t.Errorf("Expected Saturday in Tokyo to be a weekend day")
+	}
+
+	days, err := ParseWeekdays([]string{"Fri", "saturday"})
+	if err != nil {
+		t.Fatalf("ParseWeekdays returned error: %v", err)
+	}
+	scheduler.SetWeekendDays(days)
+
+	sunday := time.Date(2025, 5, 4, 12, 0, 0, 0, scheduler.Location())
+	if scheduler.IsWeekend(sunday) {
+		t.Errorf("Expected Sunday not to be a weekend day after override")
+	}
+
+	if _, err := ParseWeekdays([]string{"funday"}); err == nil {
+		t.Errorf("Expected error for invalid day name")
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/api"
+)
+
+// redactedValue replaces secrets in redacted output
+const redactedValue = "[REDACTED]"
+
+// redactedConfig returns config as JSON values with its secrets replaced.
+// Fields tagged `secret:"true"` are secret, as are the values in free-form
+// settings whose names suggest a secret.
+func redactedConfig(config Config) (map[string]interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	redactFields(reflect.TypeOf(config), values)
+	return values, nil
+}
+
+// redactFields redacts the secret fields of the struct type t in values
+func redactFields(t reflect.Type, values map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		value, ok := values[name]
+		if !ok {
+			continue
+		}
+
+		switch {
+		case field.Tag.Get("secret") == "true":
+			values[name] = redact(value)
+		case field.Type.Kind() == reflect.Struct:
+			if nested, ok := value.(map[string]interface{}); ok {
+				redactFields(field.Type, nested)
+			}
+		case field.Type.Kind() == reflect.Map:
+			redactSensitive(value)
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			items, _ := value.([]interface{})
+			for _, item := range items {
+				if nested, ok := item.(map[string]interface{}); ok {
+					redactFields(field.Type.Elem(), nested)
+				}
+			}
+		}
+	}
+}
+
+// redact replaces every value set in value
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return v
+		}
+		return redactedValue
+	case map[string]interface{}:
+		for key, nested := range v {
+			v[key] = redact(nested)
+		}
+		return v
+	case nil:
+		return nil
+	default:
+		return redactedValue
+	}
+}
+
+// redactSensitive redacts the values of free-form settings whose names
+// suggest a secret
+func redactSensitive(value interface{}) {
+	settings, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	for key, nested := range settings {
+		if api.IsSensitive(key) {
+			settings[key] = redact(nested)
+		} else {
+			redactSensitive(nested)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package secrets keeps integration credentials, such as webhook URLs with
+// embedded tokens, in a file sealed with a key only root can read, so they
+// aren't stored in the plain JSON config
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultStorePath is the default location of the sealed store
+	DefaultStorePath = "/etc/snooze/secrets.sealed"
+
+	// DefaultKeyPath is the default location of the store's key
+	DefaultKeyPath = "/etc/snooze/secrets.key"
+
+	// keySize is the size of an AES-256 key
+	keySize = 32
+
+	// formatVersion is the first byte of a sealed store
+	formatVersion = 1
+)
+
+// additionalData binds sealed stores to their purpose
+var additionalData = []byte("cloudsnooze-secrets")
+
+// Store holds named secrets, sealed on disk with AES-256-GCM
+type Store struct {
+	path   string
+	aead   cipher.AEAD
+	values map[string]string
+	lock   sync.RWMutex
+}
+
+// GenerateKey writes a new random key to path, readable only by its owner.
+// An existing key is never replaced, since it would lose the store.
+func GenerateKey(path string) error {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate key: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create key directory: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %v", err)
+	}
+	if _, err := file.WriteString(hex.EncodeToString(key) + "\n"); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write key file: %v", err)
+	}
+	return file.Close()
+}
+
+// ReadKey reads the key at path, refusing a key that others can read
+func ReadKey(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	// Windows doesn't have Unix permissions
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("key file %s must only be readable by its owner, has mode %#o", path, info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != keySize {
+		return nil, fmt.Errorf("key file %s must hold a %d-byte hex key", path, keySize)
+	}
+	return key, nil
+}
+
+// Open reads the store at path sealed with key. A missing file is an empty
+// store.
+func Open(path string, key []byte) (*Store, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, aead: aead, values: make(map[string]string)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret store: %v", err)
+	}
+	nonceSize := aead.NonceSize()
+	if len(data) < 1+nonceSize || data[0] != formatVersion {
+		return nil, fmt.Errorf("secret store %s is not a sealed store", path)
+	}
+	plaintext, err := aead.Open(nil, data[1:1+nonceSize], data[1+nonceSize:], additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal secret store %s: wrong key or modified file", path)
+	}
+	if err := json.Unmarshal(plaintext, &s.values); err != nil {
+		return nil, fmt.Errorf("failed to parse secret store: %v", err)
+	}
+	return s, nil
+}
+
+// Get returns the secret called name
+func (s *Store) Get(name string) (string, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	value, ok := s.values[name]
+	return value, ok
+}
+
+// Set stores a secret; call Save to write it
+func (s *Store) Set(name, value string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.values[name] = value
+}
+
+// Delete removes a secret, reporting whether it was stored
+func (s *Store) Delete(name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.values[name]
+	delete(s.values, name)
+	return ok
+}
+
+// Names returns the names of the stored secrets, sorted
+func (s *Store) Names() []string {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save seals the secrets and writes them to the store's file, readable
+// only by its owner
+func (s *Store) Save() error {
+	s.lock.RLock()
+	plaintext, err := json.Marshal(s.values)
+	s.lock.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	data := append([]byte{formatVersion}, nonce...)
+	data = s.aead.Seal(data, nonce, plaintext, additionalData)
+
+	// Write to a temporary file first so a failure can't lose the store
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create secret store directory: %v", err)
+	}
+	tmp := s.path + ".tmp"
+	os.Remove(tmp)
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write secret store: %v", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,93 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package secrets
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// Test sealing secrets and reading them back
+func TestStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "secrets.key")
+	storePath := filepath.Join(dir, "secrets.sealed")
+
+	if err := GenerateKey(keyPath); err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	if err := GenerateKey(keyPath); err == nil {
+		t.Error("Expected an existing key not to be replaced")
+	}
+	key, err := ReadKey(keyPath)
+	if err != nil {
+		t.Fatalf("ReadKey failed: %v", err)
+	}
+
+	store, err := Open(storePath, key)
+	if err != nil {
+		t.Fatalf("Open of a missing store failed: %v", err)
+	}
+	webhook := "https://hooks.slack.com/services/T000/B000/XXXXXXXX"
+	store.Set("slack_webhook", webhook)
+	store.Set("smtp_password", "hunter2")
+	if !store.Delete("smtp_password") || store.Delete("smtp_password") {
+		t.Error("Expected Delete to report whether the secret was stored")
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	data, err := os.ReadFile(storePath)
+	if err != nil {
+		t.Fatalf("Failed to read store: %v", err)
+	}
+	if bytes.Contains(data, []byte("hooks.slack.com")) {
+		t.Error("Expected the store not to contain the secret in plain text")
+	}
+
+	reopened, err := Open(storePath, key)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if value, ok := reopened.Get("slack_webhook"); !ok || value != webhook {
+		t.Errorf("Expected the webhook back, got %q", value)
+	}
+	if names := reopened.Names(); len(names) != 1 || names[0] != "slack_webhook" {
+		t.Errorf("Expected only slack_webhook, got %v", names)
+	}
+
+	// Another key or a modified file can't be opened
+	other := bytes.Repeat([]byte{1}, keySize)
+	if _, err := Open(storePath, other); err == nil {
+		t.Error("Expected the wrong key to fail")
+	}
+	data[len(data)-1] ^= 0xff
+	if err := os.WriteFile(storePath, data, 0600); err != nil {
+		t.Fatalf("Failed to modify store: %v", err)
+	}
+	if _, err := Open(storePath, key); err == nil {
+		t.Error("Expected a modified store to fail")
+	}
+}
+
+// Test that a key others can read is refused
+func TestReadKeyPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows doesn't have Unix permissions")
+	}
+	keyPath := filepath.Join(t.TempDir(), "secrets.key")
+	if err := GenerateKey(keyPath); err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	if err := os.Chmod(keyPath, 0644); err != nil {
+		t.Fatalf("Chmod failed: %v", err)
+	}
+	if _, err := ReadKey(keyPath); err == nil {
+		t.Error("Expected a world-readable key to be refused")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/secrets"
+)
+
+// secretReference is the prefix of secret settings stored in the sealed
+// store rather than the config, e.g. "secret:slack_webhook"
+const secretReference = "secret:"
+
+// The sealed store is managed as root with flags, like config signing
+var (
+	setSecret    = flag.String("set-secret", "", "Store the secret with this name, read from standard input, in the sealed store and exit")
+	deleteSecret = flag.String("delete-secret", "", "Remove the secret with this name from the sealed store and exit")
+	listSecrets  = flag.Bool("list-secrets", false, "List the names of the secrets in the sealed store and exit")
+)
+
+// resolveSecrets replaces references to the sealed store in fields tagged
+// `secret:"true"` with the stored secrets. The store is only opened if the
+// config refers to it.
+func resolveSecrets(config *Config) error {
+	var store *secrets.Store
+	lookup := func(field, value string) (string, error) {
+		if !strings.HasPrefix(value, secretReference) {
+			return value, nil
+		}
+		if store == nil {
+			var err error
+			if store, err = openSecretStore(config.Secrets, false); err != nil {
+				return "", err
+			}
+		}
+		name := strings.TrimPrefix(value, secretReference)
+		secret, ok := store.Get(name)
+		if !ok {
+			return "", fmt.Errorf("%s refers to secret %q, which isn't in %s", field, name, config.Secrets.StorePath)
+		}
+		return secret, nil
+	}
+	return resolveFields(reflect.ValueOf(config).Elem(), "", lookup)
+}
+
+// resolveFields resolves the secret fields of the struct v, whose fields
+// are named after prefix
+func resolveFields(v reflect.Value, prefix string, lookup func(field, value string) (string, error)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		name := prefix + strings.Split(field.Tag.Get("json"), ",")[0]
+
+		switch {
+		case field.Tag.Get("secret") == "true" && value.Kind() == reflect.String:
+			resolved, err := lookup(name, value.String())
+			if err != nil {
+				return err
+			}
+			value.SetString(resolved)
+		case field.Tag.Get("secret") == "true" && value.Kind() == reflect.Map && field.Type.Elem().Kind() == reflect.String:
+			for _, key := range value.MapKeys() {
+				resolved, err := lookup(name+"."+key.String(), value.MapIndex(key).String())
+				if err != nil {
+					return err
+				}
+				value.SetMapIndex(key, reflect.ValueOf(resolved))
+			}
+		case value.Kind() == reflect.Struct:
+			if err := resolveFields(value, name+".", lookup); err != nil {
+				return err
+			}
+		case value.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			for j := 0; j < value.Len(); j++ {
+				if err := resolveFields(value.Index(j), fmt.Sprintf("%s[%d].", name, j), lookup); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// openSecretStore opens the sealed store, generating its key first if
+// create is set and there isn't one
+func openSecretStore(config SecretsConfig, create bool) (*secrets.Store, error) {
+	if create {
+		if _, err := os.Stat(config.KeyFile); os.IsNotExist(err) {
+			if err := secrets.GenerateKey(config.KeyFile); err != nil {
+				return nil, err
+			}
+			fmt.Printf("Generated secret store key %s\n", config.KeyFile)
+		}
+	}
+	key, err := secrets.ReadKey(config.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secret store key: %v", err)
+	}
+	return secrets.Open(config.StorePath, key)
+}
+
+// manageSecrets carries out -set-secret, -delete-secret or -list-secrets.
+// Only the store's location is read from the config, since the rest may
+// refer to secrets that aren't stored yet.
+func manageSecrets(configPath string) error {
+	config := DefaultConfig()
+	if data, err := os.ReadFile(configPath); err == nil {
+		var settings struct {
+			Secrets *SecretsConfig `json:"secrets"`
+		}
+		settings.Secrets = &config.Secrets
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("failed to parse config file: %v", err)
+		}
+	}
+
+	store, err := openSecretStore(config.Secrets, *setSecret != "")
+	if err != nil {
+		return err
+	}
+	switch {
+	case *setSecret != "":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("failed to read secret: %v", err)
+		}
+		value := strings.TrimRight(string(data), "\r\n")
+		if value == "" {
+			return fmt.Errorf("no secret given on standard input")
+		}
+		store.Set(*setSecret, value)
+		if err := store.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Stored %s; use \"%s%s\" in the config\n", *setSecret, secretReference, *setSecret)
+	case *deleteSecret != "":
+		if !store.Delete(*deleteSecret) {
+			return fmt.Errorf("no secret named %s", *deleteSecret)
+		}
+		if err := store.Save(); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", *deleteSecret)
+	default:
+		for _, name := range store.Names() {
+			fmt.Println(name)
+		}
+	}
+	return nil
+}
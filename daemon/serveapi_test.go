@@ -0,0 +1,91 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/api"
+)
+
+// shortRestartDelay makes serveAPI listen again without waiting
+func shortRestartDelay(t *testing.T) {
+	previous := serverRestartDelay
+	serverRestartDelay = time.Millisecond
+	t.Cleanup(func() { serverRestartDelay = previous })
+}
+
+// failedServer returns a server whose StartContext fails straight away
+func failedServer(t *testing.T) *api.SocketServer {
+	server, err := api.NewSocketServer(filepath.Join(t.TempDir(), "failed.sock"), api.SocketAccess{})
+	if err != nil {
+		t.Fatalf("Failed to create socket server: %v", err)
+	}
+	server.Stop()
+	return server
+}
+
+func TestServeAPIRestarts(t *testing.T) {
+	shortRestartDelay(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The socket can't be created twice, then listens until cancelled
+	socketPath := filepath.Join(t.TempDir(), "test.sock")
+	calls := 0
+	newServer := func() (*api.SocketServer, error) {
+		calls++
+		if calls < maxServerRestarts {
+			return nil, errors.New("address in use")
+		}
+		server, err := api.NewSocketServer(socketPath, api.SocketAccess{})
+		if err == nil {
+			cancel()
+		}
+		return server, err
+	}
+
+	if err := serveAPI(ctx, failedServer(t), newServer); err != nil {
+		t.Errorf("Expected the restarted server to stop cleanly, got %v", err)
+	}
+	if calls != maxServerRestarts {
+		t.Errorf("Expected %d attempts to listen again, got %d", maxServerRestarts, calls)
+	}
+}
+
+func TestServeAPIGivesUp(t *testing.T) {
+	shortRestartDelay(t)
+
+	calls := 0
+	newServer := func() (*api.SocketServer, error) {
+		calls++
+		return nil, errors.New("address in use")
+	}
+
+	if err := serveAPI(context.Background(), failedServer(t), newServer); err == nil {
+		t.Error("Expected an error once the restarts run out")
+	}
+	if calls != maxServerRestarts {
+		t.Errorf("Expected %d attempts to listen again, got %d", maxServerRestarts, calls)
+	}
+
+	// A server that keeps failing once started counts against the same limit
+	calls = 0
+	newServer = func() (*api.SocketServer, error) {
+		calls++
+		return failedServer(t), nil
+	}
+	if err := serveAPI(context.Background(), failedServer(t), newServer); err == nil {
+		t.Error("Expected an error once the restarts run out")
+	}
+	if calls != maxServerRestarts {
+		t.Errorf("Expected %d restarts, got %d", maxServerRestarts, calls)
+	}
+}
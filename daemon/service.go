@@ -0,0 +1,12 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows
+
+package main
+
+// handleService does nothing outside Windows, where systemd or launchd
+// runs the daemon, and returns false
+func handleService(run func()) bool {
+	return false
+}
@@ -0,0 +1,185 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+var serviceAction = flag.String("service", "", "Manage the Windows service: install, uninstall, start or stop. Flags given with install are passed to the service.")
+
+const (
+	serviceName        = "CloudSnooze"
+	serviceDisplayName = "CloudSnooze Daemon"
+	serviceDescription = "Stops this instance when it has been idle for long enough"
+)
+
+// handleService runs -service, or runs the daemon as a service when the
+// service manager started it. It returns false if neither applies.
+func handleService(run func()) bool {
+	if *serviceAction != "" {
+		if err := controlService(*serviceAction); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to %s service: %v\n", *serviceAction, err)
+			os.Exit(1)
+		}
+		return true
+	}
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false
+	}
+	if err := svc.Run(serviceName, &service{run: run}); err != nil {
+		logger().Error("Service failed", "error", err)
+		os.Exit(1)
+	}
+	return true
+}
+
+// service runs the daemon for the service manager, shutting it down as
+// SIGTERM would when the service is stopped
+type service struct {
+	run func()
+}
+
+// Execute runs the daemon until it exits
+func (s *service) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.StartPending}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.run()
+	}()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case <-done:
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				status <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending, WaitHint: uint32(shutdownTimeout / time.Millisecond)}
+				requestShutdown(syscall.SIGTERM)
+			}
+		}
+	}
+}
+
+// controlService installs, uninstalls, starts or stops the service
+func controlService(action string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service manager: %v", err)
+	}
+	defer m.Disconnect()
+
+	if action == "install" {
+		return installService(m)
+	}
+	s, err := m.OpenService(serviceName)
+	if err != nil {
+		return fmt.Errorf("service %s is not installed", serviceName)
+	}
+	defer s.Close()
+
+	switch action {
+	case "uninstall":
+		if err := stopService(s); err != nil {
+			return err
+		}
+		if err := s.Delete(); err != nil {
+			return err
+		}
+		fmt.Printf("Removed service %s\n", serviceName)
+	case "start":
+		if err := s.Start(); err != nil {
+			return err
+		}
+		fmt.Printf("Started service %s\n", serviceName)
+	case "stop":
+		if err := stopService(s); err != nil {
+			return err
+		}
+		fmt.Printf("Stopped service %s\n", serviceName)
+	default:
+		return fmt.Errorf("unknown action %q, expected install, uninstall, start or stop", action)
+	}
+	return nil
+}
+
+// installService registers the daemon to start with Windows, running with
+// the flags given alongside -service, and to be restarted if it fails
+func installService(m *mgr.Mgr) error {
+	if s, err := m.OpenService(serviceName); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s is already installed", serviceName)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	config, err := filepath.Abs(*configFile)
+	if err != nil {
+		return err
+	}
+	args := []string{"-config=" + config}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "service" && f.Name != "config" {
+			args = append(args, fmt.Sprintf("-%s=%s", f.Name, f.Value))
+		}
+	})
+
+	s, err := m.CreateService(serviceName, exe, mgr.Config{
+		DisplayName: serviceDisplayName,
+		Description: serviceDescription,
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	recovery := []mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: 10 * time.Second}}
+	if err := s.SetRecoveryActions(recovery, uint32((24 * time.Hour).Seconds())); err != nil {
+		return fmt.Errorf("failed to set recovery actions: %v", err)
+	}
+	fmt.Printf("Installed service %s with config %s; start it with -service start\n", serviceName, config)
+	return nil
+}
+
+// stopService stops the service if it is running, waiting for it to stop
+func stopService(s *mgr.Service) error {
+	status, err := s.Query()
+	if err != nil {
+		return err
+	}
+	if status.State == svc.Stopped {
+		return nil
+	}
+	if status.State != svc.StopPending {
+		if _, err := s.Control(svc.Stop); err != nil {
+			return err
+		}
+	}
+	deadline := time.Now().Add(shutdownTimeout + 5*time.Second)
+	for time.Now().Before(deadline) {
+		time.Sleep(500 * time.Millisecond)
+		if status, err = s.Query(); err != nil {
+			return err
+		}
+		if status.State == svc.Stopped {
+			return nil
+		}
+	}
+	return fmt.Errorf("service %s did not stop within %s", serviceName, shutdownTimeout)
+}
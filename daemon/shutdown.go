@@ -0,0 +1,47 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// shutdownChannels are told when the service manager stops the daemon
+var (
+	shutdownLock      sync.Mutex
+	shutdownChannels  []chan<- os.Signal
+	shutdownRequested os.Signal // Set once a stop is requested, for channels added later
+)
+
+// notifyShutdown relays the requests to shut the daemon down to c: SIGINT
+// and SIGTERM, and stop requests from the Windows service manager
+func notifyShutdown(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
+	shutdownLock.Lock()
+	defer shutdownLock.Unlock()
+	shutdownChannels = append(shutdownChannels, c)
+	// A stop requested while the daemon was starting still shuts it down
+	if shutdownRequested != nil {
+		select {
+		case c <- shutdownRequested:
+		default:
+		}
+	}
+}
+
+// requestShutdown shuts the daemon down as if it had received sig
+func requestShutdown(sig os.Signal) {
+	shutdownLock.Lock()
+	defer shutdownLock.Unlock()
+	shutdownRequested = sig
+	for _, c := range shutdownChannels {
+		select {
+		case c <- sig:
+		default:
+		}
+	}
+}
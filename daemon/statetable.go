@@ -0,0 +1,142 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/api"
+	"github.com/scttfrdmn/cloudsnooze/daemon/common"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+)
+
+// stateRecordTimeout bounds a write to the state table, so a slow API can't
+// hold up a snooze
+const stateRecordTimeout = 10 * time.Second
+
+// stateRecords asks the state loop to record the state straight away
+var stateRecords = make(chan struct{}, 1)
+
+// recordStateNow records the state without waiting for the next interval,
+// such as when a snooze failed and the instance keeps running
+func recordStateNow() {
+	select {
+	case stateRecords <- struct{}{}:
+	default:
+	}
+}
+
+// recordStates records the daemon's state in the state table every
+// interval until ctx is cancelled
+func recordStates(ctx context.Context, recorder common.StateRecorder, relay *api.SocketServer, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failing := false
+	for {
+		err := writeState(ctx, recorder, newInstanceState(relay, time.Now()))
+		if ctx.Err() != nil {
+			return
+		}
+		// Log only changes so a missing permission doesn't flood the log
+		if err != nil && !failing {
+			logger().Warn("Failed to record state", "error", err)
+		} else if err == nil && failing {
+			logger().Info("Recording state again")
+		}
+		failing = err != nil
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-stateRecords:
+		}
+	}
+}
+
+// recordSnoozeState records the instance as stopped before it goes away.
+// If the snooze fails, the state loop records the actual state again.
+func recordSnoozeState(ctx context.Context, cloudProvider common.CloudProvider, config Config, event monitor.SnoozeEvent) {
+	recorder, ok := cloudProvider.(common.StateRecorder)
+	if config.StateTable == "" || !ok {
+		return
+	}
+	state := common.InstanceState{
+		InstanceID:   event.InstanceID,
+		InstanceType: event.InstanceType,
+		Region:       event.Region,
+		Version:      version,
+		State:        common.FleetStateStopped,
+		Reason:       event.Reason,
+		LastSnooze:   &common.FleetEvent{Time: event.Timestamp, Event: monitor.EventSnooze, Reason: event.Reason},
+		Updated:      time.Now(),
+	}
+	state.Hostname, _ = os.Hostname()
+	if err := writeState(ctx, recorder, state); err != nil {
+		logger().Warn("Failed to record snooze state", "error", err)
+	}
+}
+
+// writeState writes state, giving up after stateRecordTimeout
+func writeState(ctx context.Context, recorder common.StateRecorder, state common.InstanceState) error {
+	ctx, cancel := context.WithTimeout(ctx, stateRecordTimeout)
+	defer cancel()
+	return recorder.RecordState(ctx, state)
+}
+
+// newInstanceState returns the daemon's state from its STATUS and its
+// latest snooze
+func newInstanceState(relay *api.SocketServer, now time.Time) common.InstanceState {
+	state := common.InstanceState{Version: version, State: common.FleetStateActive, Updated: now}
+	state.Hostname, _ = os.Hostname()
+	state.InstanceID = state.Hostname
+
+	result, err := relay.Query("STATUS", nil)
+	if err != nil {
+		logger().Warn("Failed to get status for state table", "error", err)
+	}
+	status, _ := result.(map[string]interface{})
+	if info, _ := status["instance_info"].(*common.InstanceInfo); info != nil {
+		if info.ID != "" {
+			state.InstanceID = info.ID
+		}
+		state.InstanceType = info.Type
+		state.Region = info.Region
+	}
+	if value, _ := status["idle_since"].(string); value != "" {
+		if idleSince, err := time.Parse(time.RFC3339, value); err == nil {
+			state.IdleSince = &idleSince
+			state.State = common.FleetStateIdle
+			state.Reason, _ = status["snooze_reason"].(string)
+		}
+	}
+	if countdown, _ := status["countdown"].(*CountdownStatus); countdown != nil {
+		state.State = common.FleetStateCountdown
+		state.Reason = countdown.Reason
+	}
+	leases, _ := status["leases"].([]Lease)
+	for _, lease := range leases {
+		state.Leases = append(state.Leases, common.InstanceLease{
+			ID:        lease.ID,
+			Owner:     lease.Owner,
+			Reason:    lease.Reason,
+			ExpiresAt: lease.ExpiresAt,
+		})
+	}
+
+	// Starts and snoozes alternate, so the latest snooze is among the
+	// latest few events
+	history, _ := relay.Query("HISTORY", map[string]interface{}{"limit": float64(4)})
+	events, _ := history.([]monitor.SnoozeEvent)
+	for _, event := range events {
+		if !event.IsStart() {
+			state.LastSnooze = &common.FleetEvent{Time: event.Timestamp, Event: monitor.EventSnooze, Reason: event.Reason}
+			break
+		}
+	}
+	return state
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StatsDConfig defines where StatsD metrics are sent
+type StatsDConfig struct {
+	Host      string
+	Port      int
+	Prefix    string   // Prepended to every metric name, e.g. "cloudsnooze"
+	Tags      []string // Tags added to every metric, e.g. "env:prod"
+	DogStatsD bool     // Send tags in the DogStatsD format; otherwise they become part of the name
+}
+
+// StatsD sends the outcome of each check to a StatsD or DogStatsD agent
+type StatsD struct {
+	conn   net.Conn
+	config StatsDConfig
+}
+
+// NewStatsD creates a StatsD emitter. Metrics are sent over UDP, so an
+// unreachable agent never slows down the daemon.
+func NewStatsD(config StatsDConfig) (*StatsD, error) {
+	conn, err := net.Dial("udp", net.JoinHostPort(config.Host, strconv.Itoa(config.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to StatsD: %v", err)
+	}
+	return &StatsD{conn: conn, config: config}, nil
+}
+
+// Record sends the check as one packet of gauges and a decision counter
+func (s *StatsD) Record(_ context.Context, check Check) {
+	var lines []string
+	lines = append(lines, s.format("idle_seconds", check.Idle.Seconds(), "g", nil))
+	for _, name := range sortedKeys(check.Usage) {
+		lines = append(lines, s.format("resource.usage", check.Usage[name], "g", []string{"resource:" + name}))
+	}
+	for _, name := range sortedKeys(check.Thresholds) {
+		lines = append(lines, s.format("resource.threshold", check.Thresholds[name], "g", []string{"resource:" + name}))
+	}
+	lines = append(lines, s.format("decisions", 1, "c", []string{"outcome:" + check.Outcome}))
+
+	// Delivery is best effort; lost packets only leave gaps in graphs
+	s.conn.Write([]byte(strings.Join(lines, "\n")))
+}
+
+// format builds one metric line. Without DogStatsD, tag values are folded
+// into the name, e.g. prefix.resource.usage.cpu.
+func (s *StatsD) format(name string, value float64, kind string, tags []string) string {
+	if s.config.Prefix != "" {
+		name = s.config.Prefix + "." + name
+	}
+	if !s.config.DogStatsD {
+		for _, tag := range tags {
+			name += "." + tag[strings.IndexByte(tag, ':')+1:]
+		}
+	}
+
+	line := fmt.Sprintf("%s:%s|%s", name, strconv.FormatFloat(value, 'f', -1, 64), kind)
+	if s.config.DogStatsD {
+		if all := append(append([]string{}, s.config.Tags...), tags...); len(all) > 0 {
+			line += "|#" + strings.Join(all, ",")
+		}
+	}
+	return line
+}
+
+// Close closes the connection to the agent
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+// sortedKeys returns the keys of m in order, so packets are deterministic
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
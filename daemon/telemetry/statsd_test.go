@@ -0,0 +1,81 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package telemetry
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// receive starts a UDP listener and returns its port and a function that
+// reads one packet
+func receive(t *testing.T) (int, func() string) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn.LocalAddr().(*net.UDPAddr).Port, func() string {
+		buf := make([]byte, 4096)
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Failed to read packet: %v", err)
+		}
+		return string(buf[:n])
+	}
+}
+
+func TestStatsDDogStatsD(t *testing.T) {
+	port, read := receive(t)
+	statsd, err := NewStatsD(StatsDConfig{Host: "127.0.0.1", Port: port, Prefix: "cloudsnooze", Tags: []string{"env:test"}, DogStatsD: true})
+	if err != nil {
+		t.Fatalf("Failed to create emitter: %v", err)
+	}
+	defer statsd.Close()
+
+	statsd.Record(context.Background(), Check{
+		Idle:       2 * time.Minute,
+		Usage:      map[string]float64{"cpu": 4.5},
+		Thresholds: map[string]float64{"cpu": 10},
+		Outcome:    OutcomeIdle,
+	})
+
+	expected := []string{
+		"cloudsnooze.idle_seconds:120|g|#env:test",
+		"cloudsnooze.resource.usage:4.5|g|#env:test,resource:cpu",
+		"cloudsnooze.resource.threshold:10|g|#env:test,resource:cpu",
+		"cloudsnooze.decisions:1|c|#env:test,outcome:idle",
+	}
+	if packet := read(); packet != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected packet:\n%s", packet)
+	}
+}
+
+func TestStatsDPlain(t *testing.T) {
+	port, read := receive(t)
+	statsd, err := NewStatsD(StatsDConfig{Host: "127.0.0.1", Port: port, Tags: []string{"env:test"}})
+	if err != nil {
+		t.Fatalf("Failed to create emitter: %v", err)
+	}
+	defer statsd.Close()
+
+	statsd.Record(context.Background(), Check{
+		Usage:   map[string]float64{"memory": 20},
+		Outcome: OutcomeSnooze,
+	})
+
+	expected := []string{
+		"idle_seconds:0|g",
+		"resource.usage.memory:20|g",
+		"decisions.snooze:1|c",
+	}
+	if packet := read(); packet != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected packet:\n%s", packet)
+	}
+}
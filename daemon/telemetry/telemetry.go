@@ -0,0 +1,237 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package telemetry exports daemon metrics and traces of the stop pipeline
+// over OTLP, and metrics to StatsD, so CloudSnooze shows up in existing
+// observability backends. Until Setup is called or a recorder is added,
+// recording is a no-op and spans are discarded.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the daemon's meters and tracers
+const instrumentationName = "github.com/scttfrdmn/cloudsnooze/daemon"
+
+// DefaultExportInterval is how often metrics are pushed to the collector
+const DefaultExportInterval = 60 * time.Second
+
+// Decision outcomes of a monitoring check
+const (
+	OutcomeActive     = "active"     // The system is in use
+	OutcomeIdle       = "idle"       // The system is idle but the naptime hasn't passed
+	OutcomeSuppressed = "suppressed" // A calendar window prevents snoozing
+	OutcomePending    = "pending"    // A cancellable countdown is running
+	OutcomeSnooze     = "snooze"     // The instance is being stopped
+)
+
+// Config defines where telemetry is exported
+type Config struct {
+	Endpoint       string            // Collector host:port; empty uses the OTEL_EXPORTER_OTLP_* environment
+	Insecure       bool              // Use plain HTTP instead of HTTPS
+	Headers        map[string]string // Extra headers, e.g. for authentication
+	ServiceName    string            // service.name resource attribute
+	ExportInterval time.Duration     // How often metrics are pushed
+	Attributes     map[string]string // Extra resource attributes, e.g. the instance ID
+}
+
+// Check is the outcome of one monitoring check
+type Check struct {
+	Idle       time.Duration      // How long the system has been idle
+	Usage      map[string]float64 // Resource utilization by resource name
+	Thresholds map[string]float64 // Idle thresholds by resource name
+	Outcome    string             // One of the Outcome constants
+}
+
+// instruments are the metrics recorded for each check
+type instruments struct {
+	idle      metric.Float64Gauge
+	usage     metric.Float64Gauge
+	threshold metric.Float64Gauge
+	decisions metric.Int64Counter
+}
+
+// Recorder receives the outcome of every monitoring check
+type Recorder interface {
+	Record(ctx context.Context, check Check)
+}
+
+var (
+	current   *instruments
+	recorders []Recorder
+	lock      sync.RWMutex
+)
+
+// AddRecorder sends the outcome of every later check to r as well
+func AddRecorder(r Recorder) {
+	lock.Lock()
+	defer lock.Unlock()
+	recorders = append(recorders, r)
+}
+
+// Setup starts exporting metrics and traces and installs the global
+// providers. The returned function flushes and stops the exporters.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	metricOptions := []otlpmetrichttp.Option{}
+	traceOptions := []otlptracehttp.Option{}
+	if config.Endpoint != "" {
+		metricOptions = append(metricOptions, otlpmetrichttp.WithEndpoint(config.Endpoint))
+		traceOptions = append(traceOptions, otlptracehttp.WithEndpoint(config.Endpoint))
+	}
+	if config.Insecure {
+		metricOptions = append(metricOptions, otlpmetrichttp.WithInsecure())
+		traceOptions = append(traceOptions, otlptracehttp.WithInsecure())
+	}
+	if len(config.Headers) > 0 {
+		metricOptions = append(metricOptions, otlpmetrichttp.WithHeaders(config.Headers))
+		traceOptions = append(traceOptions, otlptracehttp.WithHeaders(config.Headers))
+	}
+
+	metricExporter, err := otlpmetrichttp.New(ctx, metricOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP metric exporter: %v", err)
+	}
+	traceExporter, err := otlptracehttp.New(ctx, traceOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %v", err)
+	}
+
+	interval := config.ExportInterval
+	if interval <= 0 {
+		interval = DefaultExportInterval
+	}
+	res := newResource(config)
+
+	meterProvider := sdkmetric.NewMeterProvider(
+		sdkmetric.WithResource(res),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metricExporter, sdkmetric.WithInterval(interval))),
+	)
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithResource(res),
+		sdktrace.WithBatcher(traceExporter),
+	)
+
+	inst, err := newInstruments(meterProvider)
+	if err != nil {
+		return nil, err
+	}
+	otel.SetMeterProvider(meterProvider)
+	otel.SetTracerProvider(tracerProvider)
+
+	lock.Lock()
+	current = inst
+	lock.Unlock()
+
+	return func(ctx context.Context) error {
+		lock.Lock()
+		current = nil
+		lock.Unlock()
+		return errors.Join(tracerProvider.Shutdown(ctx), meterProvider.Shutdown(ctx))
+	}, nil
+}
+
+// newResource describes the daemon to the backend
+func newResource(config Config) *resource.Resource {
+	name := config.ServiceName
+	if name == "" {
+		name = "cloudsnooze"
+	}
+	attrs := []attribute.KeyValue{attribute.String("service.name", name)}
+
+	keys := make([]string, 0, len(config.Attributes))
+	for key := range config.Attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		attrs = append(attrs, attribute.String(key, config.Attributes[key]))
+	}
+	return resource.NewSchemaless(attrs...)
+}
+
+// newInstruments creates the check metrics on a meter provider
+func newInstruments(provider metric.MeterProvider) (*instruments, error) {
+	meter := provider.Meter(instrumentationName)
+
+	idle, err := meter.Float64Gauge("cloudsnooze.idle.duration",
+		metric.WithDescription("How long the system has been idle"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create idle metric: %v", err)
+	}
+	usage, err := meter.Float64Gauge("cloudsnooze.resource.usage",
+		metric.WithDescription("Resource utilization measured by the last check"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create usage metric: %v", err)
+	}
+	threshold, err := meter.Float64Gauge("cloudsnooze.resource.threshold",
+		metric.WithDescription("Utilization below which a resource counts as idle"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create threshold metric: %v", err)
+	}
+	decisions, err := meter.Int64Counter("cloudsnooze.decisions",
+		metric.WithDescription("Monitoring checks by decision outcome"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create decisions metric: %v", err)
+	}
+
+	return &instruments{idle: idle, usage: usage, threshold: threshold, decisions: decisions}, nil
+}
+
+// Record records the outcome of a monitoring check for OTLP export and
+// every added recorder
+func Record(ctx context.Context, check Check) {
+	lock.RLock()
+	inst := current
+	others := recorders
+	lock.RUnlock()
+	if inst != nil {
+		inst.record(ctx, check)
+	}
+	for _, r := range others {
+		r.Record(ctx, check)
+	}
+}
+
+// record records a check on the instruments
+func (i *instruments) record(ctx context.Context, check Check) {
+	i.idle.Record(ctx, check.Idle.Seconds())
+	for name, value := range check.Usage {
+		i.usage.Record(ctx, value, metric.WithAttributes(attribute.String("resource", name)))
+	}
+	for name, value := range check.Thresholds {
+		i.threshold.Record(ctx, value, metric.WithAttributes(attribute.String("resource", name)))
+	}
+	i.decisions.Add(ctx, 1, metric.WithAttributes(attribute.String("outcome", check.Outcome)))
+}
+
+// Tracer returns the tracer used for the stop pipeline
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// EndSpan records err on the span, if any, and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
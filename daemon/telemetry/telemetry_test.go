@@ -0,0 +1,83 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package telemetry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestRecordCheck(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	inst, err := newInstruments(provider)
+	if err != nil {
+		t.Fatalf("Failed to create instruments: %v", err)
+	}
+
+	check := Check{
+		Idle:       90 * time.Second,
+		Usage:      map[string]float64{"cpu": 3.5},
+		Thresholds: map[string]float64{"cpu": 10},
+		Outcome:    OutcomeIdle,
+	}
+	inst.record(context.Background(), check)
+	inst.record(context.Background(), check)
+
+	var data metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &data); err != nil {
+		t.Fatalf("Failed to collect metrics: %v", err)
+	}
+
+	metrics := map[string]metricdata.Aggregation{}
+	for _, scope := range data.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m.Data
+		}
+	}
+
+	idle, ok := metrics["cloudsnooze.idle.duration"].(metricdata.Gauge[float64])
+	if !ok || len(idle.DataPoints) != 1 || idle.DataPoints[0].Value != 90 {
+		t.Errorf("Unexpected idle duration: %+v", metrics["cloudsnooze.idle.duration"])
+	}
+
+	usage, ok := metrics["cloudsnooze.resource.usage"].(metricdata.Gauge[float64])
+	if !ok || len(usage.DataPoints) != 1 || usage.DataPoints[0].Value != 3.5 {
+		t.Fatalf("Unexpected usage: %+v", metrics["cloudsnooze.resource.usage"])
+	}
+	if value, _ := usage.DataPoints[0].Attributes.Value(attribute.Key("resource")); value.AsString() != "cpu" {
+		t.Errorf("Expected resource attribute cpu, got %q", value.AsString())
+	}
+
+	decisions, ok := metrics["cloudsnooze.decisions"].(metricdata.Sum[int64])
+	if !ok || len(decisions.DataPoints) != 1 || decisions.DataPoints[0].Value != 2 {
+		t.Fatalf("Unexpected decisions: %+v", metrics["cloudsnooze.decisions"])
+	}
+	if value, _ := decisions.DataPoints[0].Attributes.Value(attribute.Key("outcome")); value.AsString() != OutcomeIdle {
+		t.Errorf("Expected outcome %s, got %q", OutcomeIdle, value.AsString())
+	}
+}
+
+func TestRecordWithoutSetup(t *testing.T) {
+	// Recording before Setup must be harmless
+	Record(context.Background(), Check{Outcome: OutcomeActive})
+}
+
+func TestNewResource(t *testing.T) {
+	res := newResource(Config{Attributes: map[string]string{"host.id": "i-123"}})
+
+	name, _ := res.Set().Value(attribute.Key("service.name"))
+	if name.AsString() != "cloudsnooze" {
+		t.Errorf("Expected default service name, got %q", name.AsString())
+	}
+	host, _ := res.Set().Value(attribute.Key("host.id"))
+	if host.AsString() != "i-123" {
+		t.Errorf("Expected host.id i-123, got %q", host.AsString())
+	}
+}
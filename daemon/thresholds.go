@@ -0,0 +1,70 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+	"github.com/scttfrdmn/cloudsnooze/daemon/schedule"
+)
+
+// Threshold profiles
+const (
+	profileWeekday = "weekday"
+	profileWeekend = "weekend"
+)
+
+// weekdayThresholds returns the top-level idle thresholds
+func weekdayThresholds(config Config) monitor.Thresholds {
+	return monitor.Thresholds{
+		CPUPercent:     config.CPUThresholdPercent,
+		MemoryPercent:  config.MemoryThresholdPercent,
+		NetworkKBps:    config.NetworkThresholdKBps,
+		DiskIOKBps:     config.DiskIOThresholdKBps,
+		GPUPercent:     config.GPUThresholdPercent,
+		InputIdleSecs:  config.InputIdleThresholdSecs,
+		NaptimeMinutes: config.NaptimeMinutes,
+	}
+}
+
+// weekendThresholds returns the weekday thresholds overridden by any
+// values set in the weekend configuration
+func weekendThresholds(config Config) monitor.Thresholds {
+	t := weekdayThresholds(config)
+	weekend := config.Schedule.Weekend
+
+	if weekend.CPUThresholdPercent > 0 {
+		t.CPUPercent = weekend.CPUThresholdPercent
+	}
+	if weekend.MemoryThresholdPercent > 0 {
+		t.MemoryPercent = weekend.MemoryThresholdPercent
+	}
+	if weekend.NetworkThresholdKBps > 0 {
+		t.NetworkKBps = weekend.NetworkThresholdKBps
+	}
+	if weekend.DiskIOThresholdKBps > 0 {
+		t.DiskIOKBps = weekend.DiskIOThresholdKBps
+	}
+	if weekend.GPUThresholdPercent > 0 {
+		t.GPUPercent = weekend.GPUThresholdPercent
+	}
+	if weekend.InputIdleThresholdSecs > 0 {
+		t.InputIdleSecs = weekend.InputIdleThresholdSecs
+	}
+	if weekend.NaptimeMinutes > 0 {
+		t.NaptimeMinutes = weekend.NaptimeMinutes
+	}
+
+	return t
+}
+
+// thresholdProfile returns the name and thresholds of the profile that
+// applies at the given time
+func thresholdProfile(config Config, scheduler *schedule.Scheduler, now time.Time) (string, monitor.Thresholds) {
+	if config.Schedule.Weekend.Enabled && scheduler.IsWeekend(now) {
+		return profileWeekend, weekendThresholds(config)
+	}
+	return profileWeekday, weekdayThresholds(config)
+}
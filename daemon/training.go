@@ -0,0 +1,36 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/training"
+)
+
+// checkpointTraining asks the configured training jobs to save a
+// checkpoint, returning an error if a required one didn't confirm it
+func checkpointTraining(ctx context.Context, config TrainingConfig) error {
+	if len(config.Checkpoints) == 0 {
+		return nil
+	}
+	trainers := make([]training.Trainer, len(config.Checkpoints))
+	for i, c := range config.Checkpoints {
+		trainers[i] = training.Trainer{
+			Name:     c.Name,
+			PIDFile:  c.PIDFile,
+			Process:  c.Process,
+			Signal:   c.Signal,
+			URL:      c.URL,
+			DoneFile: c.DoneFile,
+			Required: c.Required,
+		}
+	}
+	if err := training.CheckpointAll(ctx, trainers, time.Duration(config.TimeoutSecs)*time.Second); err != nil {
+		return fmt.Errorf("failed to checkpoint training: %v", err)
+	}
+	return nil
+}
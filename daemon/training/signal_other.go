@@ -0,0 +1,18 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !linux && !darwin && !freebsd && !openbsd
+
+package training
+
+import "fmt"
+
+// signal isn't supported on this platform; use a checkpoint endpoint
+func signal(pid int, name string) error {
+	return fmt.Errorf("signalling processes is not supported on this platform")
+}
+
+// alive can't tell on this platform, so processes are assumed gone
+func alive(pid int) bool {
+	return false
+}
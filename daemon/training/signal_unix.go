@@ -0,0 +1,39 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin || freebsd || openbsd
+
+package training
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// signals maps the names in Signals to signals
+var signals = map[string]syscall.Signal{
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// signal sends the named signal to the process, SIGUSR1 if name is empty
+func signal(pid int, name string) error {
+	if name == "" {
+		name = "SIGUSR1"
+	}
+	sig, ok := signals[name]
+	if !ok {
+		return fmt.Errorf("unknown signal %s", name)
+	}
+	return syscall.Kill(pid, sig)
+}
+
+// alive reports whether the process exists; one that can't be signalled
+// because it belongs to another user still counts
+func alive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package training asks training processes to write a checkpoint before
+// the instance stops, by signalling them or calling an HTTP endpoint, and
+// waits for them to confirm it's written.
+package training
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+)
+
+// Signals lists the signals a trainer may be sent
+var Signals = []string{"SIGUSR1", "SIGUSR2", "SIGHUP", "SIGINT", "SIGTERM"}
+
+// pollInterval is how often confirmation is checked for
+var pollInterval = time.Second
+
+// errNotRunning means there's no training to checkpoint
+var errNotRunning = errors.New("not running")
+
+// Trainer is a training process asked to checkpoint before a stop
+type Trainer struct {
+	Name     string // Label used in logs
+	PIDFile  string // File holding the process ID to signal
+	Process  string // Text matched against command lines to find processes to signal
+	Signal   string // Signal sent, SIGUSR1 if empty
+	URL      string // Endpoint POSTed to instead of sending a signal
+	DoneFile string // File the trainer writes once the checkpoint is saved
+	Required bool   // Abandon the snooze if the checkpoint isn't confirmed
+}
+
+// logger returns the training component logger
+func logger() *slog.Logger {
+	return logging.Component("training")
+}
+
+// Checkpoint asks the trainer to checkpoint and waits for confirmation:
+// the done file being written, or with no done file, the signalled
+// processes exiting or the endpoint answering
+func (t Trainer) Checkpoint(ctx context.Context) error {
+	// The done file must be newer than the request, allowing for coarse
+	// modification times
+	requested := time.Now().Truncate(time.Second)
+
+	var pids []int
+	if t.URL != "" {
+		if err := t.call(ctx); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if pids, err = t.processes(); err != nil {
+			return err
+		}
+		if len(pids) == 0 {
+			return errNotRunning
+		}
+		for _, pid := range pids {
+			if err := signal(pid, t.Signal); err != nil {
+				return fmt.Errorf("failed to signal process %d: %v", pid, err)
+			}
+		}
+		logger().Info("Asked trainer to checkpoint", "name", t.Name, "pids", pids, "signal", t.signalName())
+	}
+
+	switch {
+	case t.DoneFile != "":
+		return t.wait(ctx, func() bool {
+			info, err := os.Stat(t.DoneFile)
+			return err == nil && !info.ModTime().Before(requested)
+		})
+	case len(pids) > 0:
+		return t.wait(ctx, func() bool {
+			for _, pid := range pids {
+				if alive(pid) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	return nil
+}
+
+// call POSTs to the trainer's endpoint, which may answer once the
+// checkpoint is saved or accept the request and write the done file
+func (t Trainer) call(ctx context.Context) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader([]byte("{}")))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := http.DefaultClient.Do(request)
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return errNotRunning
+	}
+	if err != nil {
+		return fmt.Errorf("failed to call checkpoint endpoint: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 200))
+		return fmt.Errorf("checkpoint endpoint returned %s: %s", response.Status, strings.TrimSpace(string(body)))
+	}
+	logger().Info("Asked trainer to checkpoint", "name", t.Name, "status", response.StatusCode)
+	return nil
+}
+
+// processes returns the IDs of the processes to signal
+func (t Trainer) processes() ([]int, error) {
+	if t.PIDFile != "" {
+		data, err := os.ReadFile(t.PIDFile)
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PID file: %v", err)
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil || pid <= 0 {
+			return nil, fmt.Errorf("invalid PID file %s", t.PIDFile)
+		}
+		if !alive(pid) {
+			return nil, nil
+		}
+		return []int{pid}, nil
+	}
+	return matchProcesses(t.Process)
+}
+
+// matchProcesses returns the IDs of the processes whose command lines
+// contain text, other than this one
+func matchProcesses(text string) ([]int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list processes: %v", err)
+	}
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || pid == os.Getpid() {
+			continue
+		}
+		cmdline, err := os.ReadFile("/proc/" + entry.Name() + "/cmdline")
+		if err != nil {
+			continue
+		}
+		if strings.Contains(strings.ReplaceAll(string(cmdline), "\x00", " "), text) {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// wait polls until done reports the checkpoint is saved
+func (t Trainer) wait(ctx context.Context, done func() bool) error {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for !done() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("checkpoint not confirmed: %v", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// signalName returns the name of the signal sent
+func (t Trainer) signalName() string {
+	if t.Signal == "" {
+		return "SIGUSR1"
+	}
+	return t.Signal
+}
+
+// CheckpointAll checkpoints the trainers at the same time, giving each up
+// to timeout. Failures are logged, and those of required trainers returned.
+func CheckpointAll(ctx context.Context, trainers []Trainer, timeout time.Duration) error {
+	var (
+		errs []error
+		lock sync.Mutex
+		wg   sync.WaitGroup
+	)
+	for _, trainer := range trainers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := trainer.Checkpoint(ctx)
+			switch {
+			case errors.Is(err, errNotRunning):
+				logger().Debug("Trainer not running", "name", trainer.Name)
+			case err != nil:
+				logger().Warn("Failed to checkpoint training", "name", trainer.Name, "required", trainer.Required, "error", err)
+				if trainer.Required {
+					lock.Lock()
+					errs = append(errs, fmt.Errorf("%s: %v", trainer.Name, err))
+					lock.Unlock()
+				}
+			default:
+				logger().Info("Training checkpoint saved", "name", trainer.Name, "duration", time.Since(start).Round(time.Second))
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,142 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build linux || darwin
+
+package training
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func init() {
+	pollInterval = 20 * time.Millisecond
+}
+
+// startTrainer runs a shell loop that runs onSignal when sent SIGUSR1,
+// returning its process ID
+func startTrainer(t *testing.T, onSignal string) int {
+	script := "trap '" + onSignal + "' USR1; while :; do sleep 0.05; done"
+	cmd := exec.Command("sh", "-c", script, "trainer-"+t.Name())
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		<-exited
+	})
+	// Let the shell set its trap
+	time.Sleep(200 * time.Millisecond)
+	return cmd.Process.Pid
+}
+
+func TestCheckpointDoneFile(t *testing.T) {
+	dir := t.TempDir()
+	done := filepath.Join(dir, "checkpoint.done")
+	pid := startTrainer(t, "touch "+done)
+	pidFile := filepath.Join(dir, "train.pid")
+	if err := os.WriteFile(pidFile, []byte(strconv.Itoa(pid)+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	trainer := Trainer{Name: "pid", PIDFile: pidFile, DoneFile: done, Required: true}
+	if err := CheckpointAll(context.Background(), []Trainer{trainer}, 5*time.Second); err != nil {
+		t.Fatalf("CheckpointAll failed: %v", err)
+	}
+	if _, err := os.Stat(done); err != nil {
+		t.Errorf("Expected the trainer to write the done file: %v", err)
+	}
+}
+
+func TestCheckpointProcessExit(t *testing.T) {
+	pid := startTrainer(t, "exit 0")
+
+	trainer := Trainer{Name: "match", Process: "trainer-" + t.Name()}
+	pids, err := trainer.processes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pids) != 1 || pids[0] != pid {
+		t.Fatalf("Expected to match process %d, got %v", pid, pids)
+	}
+	if err := trainer.Checkpoint(context.Background()); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	if alive(pid) {
+		t.Error("Expected Checkpoint to wait for the trainer to exit")
+	}
+}
+
+func TestCheckpointEndpoint(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected a POST, got %s", r.Method)
+		}
+		if r.URL.Path == "/broken" {
+			http.Error(w, "checkpoint failed: disk full", http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	ok := Trainer{Name: "ok", URL: server.URL + "/checkpoint", Required: true}
+	if err := CheckpointAll(context.Background(), []Trainer{ok}, 5*time.Second); err != nil {
+		t.Fatalf("CheckpointAll failed: %v", err)
+	}
+
+	// Only required trainers abandon the snooze
+	optional := Trainer{Name: "optional", URL: server.URL + "/broken"}
+	if err := CheckpointAll(context.Background(), []Trainer{optional}, 5*time.Second); err != nil {
+		t.Errorf("Expected an optional failure to be ignored, got %v", err)
+	}
+	required := Trainer{Name: "required", URL: server.URL + "/broken", Required: true}
+	err := CheckpointAll(context.Background(), []Trainer{required}, 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Expected the endpoint's error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestCheckpointNotRunning(t *testing.T) {
+	dir := t.TempDir()
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	trainers := []Trainer{
+		{Name: "pid", PIDFile: filepath.Join(dir, "missing.pid"), Required: true},
+		{Name: "match", Process: "no-such-trainer-" + t.Name(), Required: true},
+		{Name: "endpoint", URL: url, Required: true},
+	}
+	if err := CheckpointAll(context.Background(), trainers, 5*time.Second); err != nil {
+		t.Errorf("Expected trainers that aren't running to be skipped, got %v", err)
+	}
+}
+
+func TestCheckpointTimeout(t *testing.T) {
+	dir := t.TempDir()
+	startTrainer(t, ":")
+
+	trainer := Trainer{Name: "silent", Process: "trainer-" + t.Name(), DoneFile: filepath.Join(dir, "never"), Required: true}
+	err := CheckpointAll(context.Background(), []Trainer{trainer}, 300*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "not confirmed") {
+		t.Errorf("Expected the checkpoint not to be confirmed, got %v", err)
+	}
+}
@@ -0,0 +1,473 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/scttfrdmn/cloudsnooze/daemon/api"
+	"github.com/scttfrdmn/cloudsnooze/daemon/cloud"
+	"github.com/scttfrdmn/cloudsnooze/daemon/database"
+	"github.com/scttfrdmn/cloudsnooze/daemon/hooks"
+	"github.com/scttfrdmn/cloudsnooze/daemon/logging"
+	"github.com/scttfrdmn/cloudsnooze/daemon/monitor"
+	"github.com/scttfrdmn/cloudsnooze/daemon/schedule"
+	"github.com/scttfrdmn/cloudsnooze/daemon/training"
+	snoozeerrors "github.com/scttfrdmn/cloudsnooze/pkg/errors"
+)
+
+// pluginIDPattern matches the IDs plugin providers register under
+var pluginIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// configProblems collects the invalid fields of a configuration
+type configProblems []string
+
+// add records a problem with a field
+func (p *configProblems) add(field, format string, args ...interface{}) {
+	*p = append(*p, field+" "+fmt.Sprintf(format, args...))
+}
+
+// atLeast checks that an integer field is no smaller than min
+func (p *configProblems) atLeast(field string, value, min int) {
+	if value < min {
+		p.add(field, "must be at least %d, got %d", min, value)
+	}
+}
+
+// percent checks that a threshold is a percentage
+func (p *configProblems) percent(field string, value float64) {
+	if value < 0 || value > 100 {
+		p.add(field, "must be between 0 and 100, got %g", value)
+	}
+}
+
+// nonNegative checks that a rate threshold isn't negative
+func (p *configProblems) nonNegative(field string, value float64) {
+	if value < 0 {
+		p.add(field, "must not be negative, got %g", value)
+	}
+}
+
+// instanceSource checks where the states of other instances are read from
+func (p *configProblems) instanceSource(field string, config Config, source string) {
+	switch instanceSource(config, source) {
+	case instanceSourceAggregator:
+		if config.Aggregation.ServerURL == "" {
+			p.add(field, "aggregator needs aggregation.server_url")
+		}
+	case instanceSourceCloud:
+	default:
+		p.add(field, "must be aggregator or cloud, got %q", source)
+	}
+}
+
+// validateConfig checks that settings are in range, so the daemon doesn't
+// run with values that make no sense. All invalid fields are reported in
+// one error.
+func validateConfig(config Config) error {
+	var problems configProblems
+
+	problems.atLeast("check_interval_seconds", config.CheckIntervalSeconds, 1)
+	problems.atLeast("naptime_minutes", config.NaptimeMinutes, 1)
+	problems.atLeast("countdown_seconds", config.CountdownSeconds, 0)
+	problems.atLeast("boot_grace_minutes", config.BootGraceMinutes, 0)
+	problems.atLeast("max_snooze_hours", config.MaxSnoozeHours, 0)
+	if config.MaxSnoozeHours > 0 && !config.EnableInstanceTags {
+		// The deadline is only recorded in the instance's tags
+		problems.add("max_snooze_hours", "requires enable_instance_tags")
+	}
+
+	problems.percent("cpu_threshold_percent", config.CPUThresholdPercent)
+	problems.percent("memory_threshold_percent", config.MemoryThresholdPercent)
+	problems.percent("gpu_threshold_percent", config.GPUThresholdPercent)
+	problems.nonNegative("network_threshold_kbps", config.NetworkThresholdKBps)
+	problems.nonNegative("disk_io_threshold_kbps", config.DiskIOThresholdKBps)
+	problems.atLeast("input_idle_threshold_secs", config.InputIdleThresholdSecs, 0)
+	switch monitor.FailurePolicy(config.CollectionFailurePolicy) {
+	case "", monitor.FailureBusy, monitor.FailureLastValue:
+	default:
+		problems.add("collection_failure_policy", "must be \"busy\" or \"last_value\", got %q", config.CollectionFailurePolicy)
+	}
+	problems.atLeast("collection_stale_secs", config.CollectionStaleSecs, 0)
+	problems.atLeast("collection_timeout_secs", config.CollectionTimeoutSecs, 0)
+	problems.atLeast("decision_log_size", config.DecisionLogSize, 0)
+	problems.atLeast("history_max_events", config.HistoryMaxEvents, 0)
+	problems.atLeast("audit.buffer_size", config.Audit.BufferSize, 0)
+	problems.atLeast("notifications.queue_max_entries", config.Notifications.QueueMaxEntries, 0)
+
+	weekend := config.Schedule.Weekend
+	problems.atLeast("schedule.weekend.naptime_minutes", weekend.NaptimeMinutes, 0)
+	problems.percent("schedule.weekend.cpu_threshold_percent", weekend.CPUThresholdPercent)
+	problems.percent("schedule.weekend.memory_threshold_percent", weekend.MemoryThresholdPercent)
+	problems.percent("schedule.weekend.gpu_threshold_percent", weekend.GPUThresholdPercent)
+	problems.nonNegative("schedule.weekend.network_threshold_kbps", weekend.NetworkThresholdKBps)
+	problems.nonNegative("schedule.weekend.disk_io_threshold_kbps", weekend.DiskIOThresholdKBps)
+	problems.atLeast("schedule.weekend.input_idle_threshold_secs", weekend.InputIdleThresholdSecs, 0)
+	problems.nonNegative("schedule.daily_runtime_budget_hours", config.Schedule.DailyRuntimeBudgetHours)
+
+	if config.TagPollingEnabled {
+		problems.atLeast("tag_polling_interval_secs", config.TagPollingIntervalSecs, 1)
+	}
+	problems.atLeast("elb_drain_timeout_secs", config.ELBDrainTimeoutSecs, 0)
+	problems.atLeast("aws_retry_attempts", config.AWSRetryAttempts, 0)
+	if config.Route53ZoneID != "" {
+		if config.Route53RecordName == "" {
+			problems.add("route53_record_name", "must be set when route53_zone_id is")
+		}
+		if ip := net.ParseIP(config.Route53ParkedIP); config.Route53ParkedIP != "" && (ip == nil || ip.To4() == nil) {
+			problems.add("route53_parked_ip", "must be an IPv4 address, got %q", config.Route53ParkedIP)
+		}
+		problems.atLeast("route53_ttl", config.Route53TTL, 1)
+	}
+	if config.StateTable != "" {
+		problems.atLeast("state_table_seconds", config.StateTableSeconds, 10)
+		problems.atLeast("state_table_ttl_hours", config.StateTableTTLHours, 0)
+		// Snoozed instances don't update their rows
+		if config.StateTableTTLHours > 0 && config.StateTableTTLHours < config.MaxSnoozeHours {
+			problems.add("state_table_ttl_hours", "must be at least max_snooze_hours (%d), got %d", config.MaxSnoozeHours, config.StateTableTTLHours)
+		}
+	}
+	problems.atLeast("notifications.alerting.permission_check_minutes", config.Notifications.Alerting.PermissionCheckMinutes, 0)
+	problems.atLeast("notifications.alerting.stop_failure_threshold", config.Notifications.Alerting.StopFailureThreshold, 0)
+	problems.atLeast("maintenance.guard_minutes", config.Maintenance.GuardMinutes, 0)
+	if adaptive := config.AdaptiveInterval; adaptive.Enabled {
+		problems.atLeast("adaptive_interval.min_seconds", adaptive.MinSeconds, 1)
+		if adaptive.MinSeconds > config.CheckIntervalSeconds {
+			problems.add("adaptive_interval.min_seconds", "must not be more than check_interval_seconds (%d), got %d", config.CheckIntervalSeconds, adaptive.MinSeconds)
+		}
+		if adaptive.MaxSeconds < config.CheckIntervalSeconds {
+			problems.add("adaptive_interval.max_seconds", "must be at least check_interval_seconds (%d), got %d", config.CheckIntervalSeconds, adaptive.MaxSeconds)
+		}
+	}
+
+	if config.Socket.Mode != "" {
+		if _, err := api.ParseSocketMode(config.Socket.Mode); err != nil {
+			problems.add("socket.mode", "must be octal permissions such as \"0660\", got %q", config.Socket.Mode)
+		}
+	}
+	if config.Socket.Group != "" {
+		if _, err := api.LookupGroupID(config.Socket.Group); err != nil {
+			problems.add("socket.group", "must be an existing group, got %q", config.Socket.Group)
+		}
+	}
+	if config.Socket.AdminGroup != "" {
+		if _, err := api.LookupGroupID(config.Socket.AdminGroup); err != nil {
+			problems.add("socket.admin_group", "must be an existing group, got %q", config.Socket.AdminGroup)
+		}
+	}
+	problems.nonNegative("socket.rate_limit.global_per_second", config.Socket.RateLimit.GlobalPerSecond)
+	problems.nonNegative("socket.rate_limit.client_per_second", config.Socket.RateLimit.ClientPerSecond)
+	problems.atLeast("socket.rate_limit.burst", config.Socket.RateLimit.Burst, 1)
+	if remote := config.RemoteAPI; remote.ListenAddr != "" {
+		if _, _, err := net.SplitHostPort(remote.ListenAddr); err != nil {
+			problems.add("remote_api.listen_addr", "must be host:port, got %q", remote.ListenAddr)
+		}
+		for _, file := range []struct{ field, path string }{
+			{"cert_file", remote.CertFile}, {"key_file", remote.KeyFile}, {"client_ca_file", remote.ClientCAFile},
+		} {
+			if file.path == "" {
+				problems.add("remote_api."+file.field, "must be set when remote_api.listen_addr is")
+			}
+		}
+	}
+	for i, list := range config.ClientAllowlists {
+		field := fmt.Sprintf("client_allowlists[%d]", i)
+		identities := 0
+		for _, identity := range []string{list.User, list.TokenFile, list.Certificate} {
+			if identity != "" {
+				identities++
+			}
+		}
+		if identities != 1 {
+			problems.add(field, "must set exactly one of user, token_file or certificate")
+		}
+		if list.User != "" {
+			if _, err := api.LookupUserID(list.User); err != nil {
+				problems.add(field+".user", "must be an existing user, got %q", list.User)
+			}
+		}
+		if len(list.Commands) == 0 {
+			problems.add(field+".commands", "must list at least one command")
+		}
+	}
+	if config.Privileges.User != "" {
+		if _, err := api.LookupUserID(config.Privileges.User); err != nil {
+			problems.add("privileges.user", "must be an existing user, got %q", config.Privileges.User)
+		}
+	} else if config.Privileges.Group != "" {
+		problems.add("privileges.group", "needs privileges.user to be set")
+	}
+	if config.Privileges.Group != "" {
+		if _, err := api.LookupGroupID(config.Privileges.Group); err != nil {
+			problems.add("privileges.group", "must be an existing group, got %q", config.Privileges.Group)
+		}
+	}
+	problems.atLeast("restarter.poll_seconds", config.Restarter.PollSeconds, 1)
+	for i, s := range config.Restarter.Schedules {
+		field := fmt.Sprintf("restarter.schedules[%d]", i)
+		if _, err := time.Parse("15:04", s.Time); err != nil {
+			problems.add(field+".time", "must be a time of day such as \"08:00\", got %q", s.Time)
+		}
+		if _, err := schedule.ParseWeekdays(s.Days); err != nil {
+			problems.add(field+".days", "%v", err)
+		}
+	}
+	if restarter := config.Restarter; restarter.WebhookAddr != "" {
+		if _, _, err := net.SplitHostPort(restarter.WebhookAddr); err != nil {
+			problems.add("restarter.webhook_addr", "must be host:port, got %q", restarter.WebhookAddr)
+		}
+		if restarter.WebhookTokenFile == "" {
+			problems.add("restarter.webhook_token_file", "must be set when restarter.webhook_addr is")
+		}
+		if (restarter.WebhookCertFile == "") != (restarter.WebhookKeyFile == "") {
+			problems.add("restarter.webhook_cert_file", "must be set along with restarter.webhook_key_file")
+		}
+	}
+	problems.atLeast("restarter.wake_timeout_secs", config.Restarter.WakeTimeoutSecs, 1)
+	problems.atLeast("controller.poll_seconds", config.Controller.PollSeconds, 60)
+	groupNames := make(map[string]bool)
+	for i, g := range config.Controller.Groups {
+		field := fmt.Sprintf("controller.groups[%d]", i)
+		if g.Name == "" {
+			problems.add(field+".name", "must be set")
+		} else if groupNames[g.Name] {
+			problems.add(field+".name", "must be unique, got %q twice", g.Name)
+		}
+		groupNames[g.Name] = true
+		// Without tags the group would take in every instance in the region
+		if len(g.Tags) == 0 && len(config.Controller.Tags) == 0 {
+			problems.add(field+".tags", "must be set when controller.tags aren't")
+		}
+		for key, value := range g.Tags {
+			if other, ok := config.Controller.Tags[key]; ok && other != value {
+				problems.add(field+".tags", "can't set %s to %q when controller.tags sets it to %q", key, value, other)
+			}
+		}
+		problems.atLeast(field+".naptime_minutes", g.NaptimeMinutes, 0)
+		problems.nonNegative(field+".cpu_threshold_percent", g.CPUThresholdPercent)
+		problems.nonNegative(field+".network_threshold_kbps", g.NetworkThresholdKBps)
+	}
+	if controller := config.Controller; controller.StatusAddr != "" {
+		if _, _, err := net.SplitHostPort(controller.StatusAddr); err != nil {
+			problems.add("controller.status_addr", "must be host:port, got %q", controller.StatusAddr)
+		}
+		if controller.StatusTokenFile == "" {
+			problems.add("controller.status_token_file", "must be set when controller.status_addr is")
+		}
+		if (controller.StatusCertFile == "") != (controller.StatusKeyFile == "") {
+			problems.add("controller.status_cert_file", "must be set along with controller.status_key_file")
+		}
+	}
+	if aggregation := config.Aggregation; aggregation.ServerURL != "" {
+		if u, err := url.Parse(aggregation.ServerURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			problems.add("aggregation.server_url", "must be an http or https URL, got %q", aggregation.ServerURL)
+		}
+		if aggregation.TokenFile == "" {
+			problems.add("aggregation.token_file", "must be set when aggregation.server_url is")
+		}
+		problems.atLeast("aggregation.push_seconds", aggregation.PushSeconds, 10)
+		problems.atLeast("aggregation.history_events", aggregation.HistoryEvents, 0)
+	}
+	if _, _, err := net.SplitHostPort(config.Aggregator.ListenAddr); err != nil {
+		problems.add("aggregator.listen_addr", "must be host:port, got %q", config.Aggregator.ListenAddr)
+	}
+	if (config.Aggregator.CertFile == "") != (config.Aggregator.KeyFile == "") {
+		problems.add("aggregator.cert_file", "must be set along with aggregator.key_file")
+	}
+	problems.atLeast("aggregator.stale_seconds", config.Aggregator.StaleSeconds, 1)
+	problems.atLeast("hooks.timeout_secs", config.Hooks.TimeoutSecs, 1)
+	problems.nonNegative("leases.max_hours", config.Leases.MaxHours)
+	if dependencies := config.Dependencies; len(dependencies.Instances) > 0 || len(dependencies.Tags) > 0 {
+		problems.instanceSource("dependencies.source", config, dependencies.Source)
+		problems.atLeast("dependencies.check_seconds", dependencies.CheckSeconds, 10)
+	}
+	if peers := config.Peers; len(peers.Instances) > 0 || len(peers.Tags) > 0 {
+		problems.instanceSource("peers.source", config, peers.Source)
+		problems.atLeast("peers.min_running", peers.MinRunning, 1)
+		problems.atLeast("peers.check_seconds", peers.CheckSeconds, 10)
+	}
+	if config.Docker.StopContainers {
+		problems.atLeast("docker.stop_timeout_secs", config.Docker.StopTimeoutSecs, 1)
+		if config.Docker.SocketPath == "" {
+			problems.add("docker.socket_path", "must be set when docker.stop_containers is")
+		}
+	}
+	if config.Container.Enabled {
+		if config.Container.HostProc == "" {
+			problems.add("container.host_proc", "must be set when container.enabled is")
+		}
+		if config.Container.HostSys == "" {
+			problems.add("container.host_sys", "must be set when container.enabled is")
+		}
+	}
+	if config.Kubernetes.DrainNode {
+		problems.atLeast("kubernetes.drain_timeout_secs", config.Kubernetes.DrainTimeoutSecs, 1)
+		if config.Kubernetes.Kubectl == "" {
+			problems.add("kubernetes.kubectl", "must be set when kubernetes.drain_node is")
+		}
+	}
+	problems.atLeast("training.timeout_secs", config.Training.TimeoutSecs, 1)
+	trainingNames := make(map[string]bool)
+	for i, checkpoint := range config.Training.Checkpoints {
+		field := fmt.Sprintf("training.checkpoints[%d]", i)
+		if checkpoint.Name == "" || trainingNames[checkpoint.Name] {
+			problems.add(field+".name", "must be set and unique, got %q", checkpoint.Name)
+		}
+		trainingNames[checkpoint.Name] = true
+		targets := 0
+		for _, target := range []string{checkpoint.PIDFile, checkpoint.Process, checkpoint.URL} {
+			if target != "" {
+				targets++
+			}
+		}
+		if targets != 1 {
+			problems.add(field, "must set exactly one of pid_file, process or url")
+		}
+		if checkpoint.Signal != "" && !slices.Contains(training.Signals, checkpoint.Signal) {
+			problems.add(field+".signal", "must be one of %s, got %q", strings.Join(training.Signals, ", "), checkpoint.Signal)
+		}
+		if checkpoint.URL != "" && !strings.HasPrefix(checkpoint.URL, "http://") && !strings.HasPrefix(checkpoint.URL, "https://") {
+			problems.add(field+".url", "must be an http:// or https:// URL")
+		}
+		if checkpoint.DoneFile != "" && !filepath.IsAbs(checkpoint.DoneFile) {
+			problems.add(field+".done_file", "must be an absolute path, got %q", checkpoint.DoneFile)
+		}
+	}
+	problems.atLeast("databases.timeout_secs", config.Databases.TimeoutSecs, 1)
+	databaseNames := make(map[string]bool)
+	for i, checkpoint := range config.Databases.Checkpoints {
+		field := fmt.Sprintf("databases.checkpoints[%d]", i)
+		if checkpoint.Name == "" || databaseNames[checkpoint.Name] {
+			problems.add(field+".name", "must be set and unique, got %q", checkpoint.Name)
+		}
+		databaseNames[checkpoint.Name] = true
+		if !slices.Contains(database.Engines, checkpoint.Engine) {
+			problems.add(field+".engine", "must be one of %s, got %q", strings.Join(database.Engines, ", "), checkpoint.Engine)
+		}
+		if checkpoint.Port < 0 || checkpoint.Port > 65535 {
+			problems.add(field+".port", "must be between 0 and 65535, got %d", checkpoint.Port)
+		}
+	}
+	problems.atLeast("filesystems.flush_timeout_secs", config.Filesystems.FlushTimeoutSecs, 1)
+	for i, mount := range config.Filesystems.Unmount {
+		if !filepath.IsAbs(mount) || filepath.Clean(mount) == "/" {
+			problems.add(fmt.Sprintf("filesystems.unmount[%d]", i), "must be an absolute path other than /, got %q", mount)
+		}
+	}
+	for i, step := range config.Pipeline {
+		field := fmt.Sprintf("pipeline[%d]", i)
+		if !slices.Contains(pipelineSteps, step.Step) {
+			problems.add(field+".step", "must be one of %s, got %q", strings.Join(pipelineSteps, ", "), step.Step)
+		}
+		if step.Step == stepStop && i != len(config.Pipeline)-1 {
+			problems.add(field+".step", "must be the last step when it is %q", stepStop)
+		}
+		if step.OnFailure != "" && step.OnFailure != failureAbort && step.OnFailure != failureContinue {
+			problems.add(field+".on_failure", "must be %q or %q, got %q", failureAbort, failureContinue, step.OnFailure)
+		}
+		problems.atLeast(field+".timeout_secs", step.TimeoutSecs, 0)
+		problems.atLeast(field+".keep", step.Keep, 0)
+		if step.Step == stepCommand && !filepath.IsAbs(step.Command) {
+			problems.add(field+".command", "must be an absolute path, got %q", step.Command)
+		}
+	}
+	if n := len(config.Pipeline); n > 0 && config.Pipeline[n-1].Step != stepStop {
+		problems.add("pipeline", "must end with a %q step", stepStop)
+	}
+	hookNames := make(map[string]bool)
+	for i, hook := range config.Hooks.Commands {
+		field := fmt.Sprintf("hooks.commands[%d]", i)
+		if hook.Name == "" || hookNames[hook.Name] {
+			problems.add(field+".name", "must be set and unique, got %q", hook.Name)
+		}
+		hookNames[hook.Name] = true
+		if hook.Check == "" && hook.PreStop == "" && hook.PostStart == "" {
+			problems.add(field, "must set check, pre_stop or post_start")
+		}
+		if hook.Check != "" && !filepath.IsAbs(hook.Check) {
+			problems.add(field+".check", "must be an absolute path, got %q", hook.Check)
+		}
+		if hook.PreStop != "" && !filepath.IsAbs(hook.PreStop) {
+			problems.add(field+".pre_stop", "must be an absolute path, got %q", hook.PreStop)
+		}
+		if hook.PostStart != "" && !filepath.IsAbs(hook.PostStart) {
+			problems.add(field+".post_start", "must be an absolute path, got %q", hook.PostStart)
+		}
+	}
+	for i, hook := range config.Hooks.Events {
+		field := fmt.Sprintf("hooks.events[%d]", i)
+		if hook.Name == "" {
+			problems.add(field+".name", "must be set")
+		}
+		if !filepath.IsAbs(hook.Command) {
+			problems.add(field+".command", "must be an absolute path, got %q", hook.Command)
+		}
+		if len(hook.Events) == 0 {
+			problems.add(field+".events", "must list at least one of %s", strings.Join(hooks.Events, ", "))
+		}
+		for _, event := range hook.Events {
+			if !slices.Contains(hooks.Events, event) {
+				problems.add(field+".events", "must be one of %s, got %q", strings.Join(hooks.Events, ", "), event)
+			}
+		}
+	}
+	if len(config.Hooks.Commands) > 0 && config.Hooks.ContextPath == "" {
+		problems.add("hooks.context_path", "must be set when hooks.commands are")
+	}
+	for i, w := range config.Restarter.WakeOnSSH {
+		field := fmt.Sprintf("restarter.wake_on_ssh[%d]", i)
+		if _, _, err := net.SplitHostPort(w.ListenAddr); err != nil {
+			problems.add(field+".listen_addr", "must be host:port, got %q", w.ListenAddr)
+		}
+		if w.InstanceID == "" {
+			problems.add(field+".instance_id", "must be set")
+		}
+		if w.Target != "" {
+			if _, _, err := net.SplitHostPort(w.Target); err != nil {
+				problems.add(field+".target", "must be host:port, got %q", w.Target)
+			}
+		}
+	}
+	if addr := config.Debug.PprofAddr; addr != "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			problems.add("debug.pprof_addr", "must be host:port, got %q", addr)
+		} else if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			problems.add("debug.pprof_addr", "must be a loopback address, got %q", addr)
+		}
+	}
+
+	if config.StopAction != "" && !slices.Contains(idleActions, config.StopAction) {
+		problems.add("stop_action", "must be one of %s, got %q", strings.Join(idleActions, ", "), config.StopAction)
+	}
+	if config.StopAction == actionScript {
+		if !filepath.IsAbs(config.StopScript) {
+			problems.add("stop_script", "must be an absolute path when stop_action is \"script\", got %q", config.StopScript)
+		}
+		problems.atLeast("stop_script_timeout_secs", config.StopScriptTimeoutSecs, 1)
+	}
+	if _, err := logging.ParseLevel(config.Logging.LogLevel); err != nil {
+		problems.add("logging.log_level", "must be debug, info, warn or error, got %q", config.Logging.LogLevel)
+	}
+
+	switch cloud.ProviderType(config.ProviderType) {
+	case "", cloud.AWS, cloud.GCP, cloud.Azure:
+	default:
+		if !config.PluginsEnabled || !pluginIDPattern.MatchString(config.ProviderType) {
+			problems.add("provider_type", "must be aws, gcp, azure, empty to detect, or the ID of a provider plugin, got %q", config.ProviderType)
+		}
+	}
+
+	if len(problems) > 0 {
+		return snoozeerrors.ConfigurationError("invalid configuration: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2025 Scott Friedman and CloudSnooze Contributors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestValidateConfigAccepts(t *testing.T) {
+	if err := validateConfig(DefaultConfig()); err != nil {
+		t.Errorf("Expected the default config to be valid, got %v", err)
+	}
+
+	// The example shipped with the packages is read over the defaults, as
+	// loadConfig does
+	data, err := os.ReadFile("../config/snooze.json")
+	if err != nil {
+		t.Fatalf("Failed to read example config: %v", err)
+	}
+	config := DefaultConfig()
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("Failed to parse example config: %v", err)
+	}
+	if err := validateConfig(config); err != nil {
+		t.Errorf("Expected config/snooze.json to be valid, got %v", err)
+	}
+}
+
+func TestValidateConfigRejects(t *testing.T) {
+	peered := func(config *Config) {
+		config.Peers.Tags = map[string]string{"Service": "web"}
+		config.Peers.Source = "cloud"
+		config.Peers.MinRunning = 1
+		config.Peers.CheckSeconds = 60
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		field  string
+	}{
+		// Ranges
+		{"zero check interval", func(c *Config) { c.CheckIntervalSeconds = 0 }, "check_interval_seconds"},
+		{"zero naptime", func(c *Config) { c.NaptimeMinutes = 0 }, "naptime_minutes"},
+		{"CPU threshold over 100%", func(c *Config) { c.CPUThresholdPercent = 150 }, "cpu_threshold_percent"},
+		{"negative network threshold", func(c *Config) { c.NetworkThresholdKBps = -1 }, "network_threshold_kbps"},
+		{"weekend threshold over 100%", func(c *Config) { c.Schedule.Weekend.MemoryThresholdPercent = 101 }, "schedule.weekend.memory_threshold_percent"},
+		{"negative runtime budget", func(c *Config) { c.Schedule.DailyRuntimeBudgetHours = -1 }, "schedule.daily_runtime_budget_hours"},
+		{"negative lease cap", func(c *Config) { c.Leases.MaxHours = -1 }, "leases.max_hours"},
+
+		// Unknown enums
+		{"unknown failure policy", func(c *Config) { c.CollectionFailurePolicy = "sometimes" }, "collection_failure_policy"},
+		{"unknown stop action", func(c *Config) { c.StopAction = "nap" }, "stop_action"},
+		{"unknown log level", func(c *Config) { c.Logging.LogLevel = "loud" }, "logging.log_level"},
+		{"unknown provider", func(c *Config) { c.ProviderType = "Not A Cloud" }, "provider_type"},
+		{"unknown restart day", func(c *Config) {
+			c.Restarter.Schedules = []RestarterScheduleConfig{{Time: "08:00", Days: []string{"someday"}}}
+		}, "restarter.schedules[0].days"},
+
+		// Conflicting settings
+		{"restart time of day", func(c *Config) {
+			c.Restarter.Schedules = []RestarterScheduleConfig{{Time: "8am"}}
+		}, "restarter.schedules[0].time"},
+		{"adaptive minimum above the interval", func(c *Config) {
+			c.AdaptiveInterval = AdaptiveIntervalConfig{Enabled: true, MinSeconds: 120, MaxSeconds: 300}
+		}, "adaptive_interval.min_seconds"},
+		{"adaptive maximum below the interval", func(c *Config) {
+			c.AdaptiveInterval = AdaptiveIntervalConfig{Enabled: true, MinSeconds: 10, MaxSeconds: 30}
+		}, "adaptive_interval.max_seconds"},
+		{"snooze cap without instance tags", func(c *Config) {
+			c.EnableInstanceTags = false
+			c.MaxSnoozeHours = 8
+		}, "max_snooze_hours"},
+		{"state rows expiring before the snooze cap", func(c *Config) {
+			c.EnableInstanceTags = true
+			c.MaxSnoozeHours = 48
+			c.StateTable = "cloudsnooze-state"
+			c.StateTableTTLHours = 24
+		}, "state_table_ttl_hours"},
+		{"peers read from an aggregator that isn't set", func(c *Config) {
+			peered(c)
+			c.Peers.Source = "aggregator"
+		}, "peers.source"},
+		{"peers from an unknown source", func(c *Config) {
+			peered(c)
+			c.Peers.Source = "gossip"
+		}, "peers.source"},
+		{"peers without a minimum", func(c *Config) {
+			peered(c)
+			c.Peers.MinRunning = 0
+		}, "peers.min_running"},
+		{"stop script that isn't absolute", func(c *Config) {
+			c.StopAction = "script"
+			c.StopScript = "park.sh"
+		}, "stop_script"},
+	}
+
+	for _, tt := range tests {
+		config := DefaultConfig()
+		tt.mutate(&config)
+		err := validateConfig(config)
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.field+" ") {
+			t.Errorf("%s: expected %s to be reported, got %v", tt.name, tt.field, err)
+		}
+	}
+}
@@ -66,6 +66,12 @@ cat > "${PACKAGE_DIR}/postinst" << 'EOF'
 #!/bin/sh
 set -e
 
+# Group that can be given access to the socket with socket.group
+getent group snooze >/dev/null || groupadd --system snooze
+
+# User the daemon can switch to after startup with privileges.user
+getent passwd snooze >/dev/null || useradd --system --gid snooze --no-create-home --shell /usr/sbin/nologin snooze
+
 # Enable and start the service
 systemctl daemon-reload
 systemctl enable snoozed.service
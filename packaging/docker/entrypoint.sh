@@ -0,0 +1,11 @@
+#!/bin/sh
+# Entrypoint of the CloudSnooze container image. Runs the daemon with the
+# config file at SNOOZED_CONFIG_FILE, /etc/snooze/snooze.json by default,
+# which is created with the default settings if it doesn't exist. Other
+# arguments are passed to the daemon.
+set -e
+
+config="${SNOOZED_CONFIG_FILE:-/etc/snooze/snooze.json}"
+unset SNOOZED_CONFIG_FILE
+
+exec /usr/local/bin/snoozed -config "$config" "$@"
@@ -63,6 +63,8 @@ cp %{_builddir}/README.md %{buildroot}/usr/share/doc/cloudsnooze/
 cp %{_builddir}/roadmap.md %{buildroot}/usr/share/doc/cloudsnooze/
 
 %post
+getent group snooze >/dev/null || groupadd --system snooze
+getent passwd snooze >/dev/null || useradd --system --gid snooze --no-create-home --shell /sbin/nologin snooze
 systemctl daemon-reload
 systemctl enable snoozed.service
 systemctl start snoozed.service || echo "Failed to start snoozed service"
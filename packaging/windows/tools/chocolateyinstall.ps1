@@ -67,7 +67,7 @@ $serviceExists = Get-Service -Name $serviceName -ErrorAction SilentlyContinue
 
 if (-not $serviceExists) {
     Write-Host "Installing CloudSnooze service..."
-    & sc.exe create $serviceName binPath= "$serviceExePath --config $configPath" DisplayName= "CloudSnooze Daemon" start= auto
+    & $serviceExePath -service install -config $configPath
     Write-Host "CloudSnooze service installed!"
     
     # Start the service
@@ -4,6 +4,7 @@
 package errors
 
 import (
+	stderrors "errors"
 	"fmt"
 	"runtime"
 	"strings"
@@ -27,6 +28,8 @@ const (
 	ErrorTypeNetwork
 	// ErrorTypeInternal represents internal errors
 	ErrorTypeInternal
+	// ErrorTypeThrottling represents requests rejected for exceeding a rate limit
+	ErrorTypeThrottling
 )
 
 // CloudSnoozeError is a custom error type with context
@@ -71,19 +74,19 @@ func (e *CloudSnoozeError) WithStack() *CloudSnoozeError {
 
 // New creates a new CloudSnoozeError
 func New(errorType ErrorType, message string) *CloudSnoozeError {
-	return &CloudSnoozeError{
+	return (&CloudSnoozeError{
 		Type:    errorType,
 		Message: message,
-	}.WithStack()
+	}).WithStack()
 }
 
 // Wrap wraps an existing error with additional context
 func Wrap(err error, errorType ErrorType, message string) *CloudSnoozeError {
-	return &CloudSnoozeError{
+	return (&CloudSnoozeError{
 		Type:    errorType,
 		Message: message,
 		Err:     err,
-	}.WithStack()
+	}).WithStack()
 }
 
 // ValidationError creates a new validation error
@@ -116,10 +119,38 @@ func InternalError(message string) *CloudSnoozeError {
 	return New(ErrorTypeInternal, message)
 }
 
-// IsType checks if an error is of a specific type
+// ThrottlingError creates a new throttling error
+func ThrottlingError(message string) *CloudSnoozeError {
+	return New(ErrorTypeThrottling, message)
+}
+
+// IsType checks if an error, or an error it wraps, is of a specific type
 func IsType(err error, errorType ErrorType) bool {
-	if csErr, ok := err.(*CloudSnoozeError); ok {
+	var csErr *CloudSnoozeError
+	if stderrors.As(err, &csErr) {
 		return csErr.Type == errorType
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// Recover stops a panic in the calling goroutine and passes it to handler
+// as an internal error carrying the stack of the panic. It does nothing if
+// the goroutine isn't panicking. It must be deferred directly:
+//
+//	defer errors.Recover(func(err *errors.CloudSnoozeError) { ... })
+func Recover(handler func(err *CloudSnoozeError)) {
+	value := recover()
+	if value == nil {
+		return
+	}
+
+	panicErr := &CloudSnoozeError{
+		Type:    ErrorTypeInternal,
+		Message: fmt.Sprintf("panic: %v", value),
+	}
+	if err, ok := value.(error); ok {
+		panicErr.Message = "panic"
+		panicErr.Err = err
+	}
+	handler(panicErr.WithStack())
+}
@@ -0,0 +1,3 @@
+module github.com/scttfrdmn/cloudsnooze/pkg
+
+go 1.24